@@ -18,10 +18,13 @@
 package accounts
 
 import (
+	"fmt"
 	"math/big"
 
 	"github.com/69th-byte/sdexchain/common"
 	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/crypto/eip712"
 	"github.com/69th-byte/sdexchain/event"
 	ethereum "github.com/tomochain/tomochain"
 )
@@ -113,6 +116,22 @@ type Wallet interface {
 	// the account in a keystore).
 	SignTx(account Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
 
+	// SignText requests the wallet to sign the hash of the given text, wrapped as
+	// an EIP-191 personal message (see TextHash). This is how the order and lending
+	// transactions of TomoX are signed, so hardware wallets can show the message
+	// to the user instead of blindly signing a hash.
+	//
+	// The signature is returned in the [R || S || V] format where V is 0 or 1. The
+	// wallet may require additional authentication as with SignHash.
+	SignText(account Account, text []byte) ([]byte, error)
+
+	// SignTypedData requests the wallet to sign the EIP-712 hash of the given typed
+	// data, such as the typed data of an order or lending transaction.
+	//
+	// The signature is returned in the [R || S || V] format where V is 0 or 1. The
+	// wallet may require additional authentication as with SignHash.
+	SignTypedData(account Account, data *eip712.TypedData) ([]byte, error)
+
 	// SignHashWithPassphrase requests the wallet to sign the given hash with the
 	// given passphrase as extra authentication information.
 	//
@@ -120,6 +139,11 @@ type Wallet interface {
 	// or optionally with the aid of any location metadata from the embedded URL field.
 	SignHashWithPassphrase(account Account, passphrase string, hash []byte) ([]byte, error)
 
+	// SignTypedDataWithPassphrase requests the wallet to sign the EIP-712 hash of
+	// the given typed data with the given passphrase as extra authentication
+	// information.
+	SignTypedDataWithPassphrase(account Account, passphrase string, data *eip712.TypedData) ([]byte, error)
+
 	// SignTxWithPassphrase requests the wallet to sign the given transaction, with the
 	// given passphrase as extra authentication information.
 	//
@@ -128,6 +152,16 @@ type Wallet interface {
 	SignTxWithPassphrase(account Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
 }
 
+// TextHash is a helper function that calculates the hash of the given message
+// the way personal messages are signed, giving it context and preventing it from
+// being a transaction:
+//
+//	keccak256("\x19Ethereum Signed Message:\n"${message length}${message}).
+func TextHash(data []byte) []byte {
+	msg := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(data), data)
+	return crypto.Keccak256([]byte(msg))
+}
+
 // Backend is a "wallet provider" that may contain a batch of accounts they can
 // sign transactions with and upon request, do so.
 type Backend interface {
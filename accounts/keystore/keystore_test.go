@@ -28,6 +28,7 @@ import (
 
 	"github.com/69th-byte/sdexchain/accounts"
 	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/crypto"
 	"github.com/69th-byte/sdexchain/event"
 )
 
@@ -85,6 +86,36 @@ func TestSign(t *testing.T) {
 	}
 }
 
+// Tests that personal messages signed by a keystore wallet recover to the account,
+// the way order and lending transactions are verified.
+func TestSignText(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	a1, err := ks.NewAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet := ks.Wallets()[0]
+	if _, err := wallet.SignText(a1, testSigData); err != ErrLocked {
+		t.Fatalf("locked signing error mismatch: have %v, want %v", err, ErrLocked)
+	}
+	if err := ks.Unlock(a1, ""); err != nil {
+		t.Fatal(err)
+	}
+	sig, err := wallet.SignText(a1, testSigData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubkey, err := crypto.SigToPub(accounts.TextHash(testSigData), sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer := crypto.PubkeyToAddress(*pubkey); signer != a1.Address {
+		t.Fatalf("signer mismatch: have %x, want %x", signer, a1.Address)
+	}
+}
+
 func TestSignWithPassphrase(t *testing.T) {
 	dir, ks := tmpKeyStore(t, true)
 	defer os.RemoveAll(dir)
@@ -21,6 +21,7 @@ import (
 
 	"github.com/69th-byte/sdexchain/accounts"
 	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/crypto/eip712"
 	ethereum "github.com/tomochain/tomochain"
 )
 
@@ -110,6 +111,22 @@ func (w *keystoreWallet) SignTx(account accounts.Account, tx *types.Transaction,
 	return w.keystore.SignTx(account, tx, chainID)
 }
 
+// SignText implements accounts.Wallet, attempting to sign the hash of the given
+// text as a personal message with the given account.
+func (w *keystoreWallet) SignText(account accounts.Account, text []byte) ([]byte, error) {
+	return w.SignHash(account, accounts.TextHash(text))
+}
+
+// SignTypedData implements accounts.Wallet, attempting to sign the EIP-712 hash
+// of the given typed data with the given account.
+func (w *keystoreWallet) SignTypedData(account accounts.Account, data *eip712.TypedData) ([]byte, error) {
+	hash, err := data.Hash()
+	if err != nil {
+		return nil, err
+	}
+	return w.SignHash(account, hash[:])
+}
+
 // SignHashWithPassphrase implements accounts.Wallet, attempting to sign the
 // given hash with the given account using passphrase as extra authentication.
 func (w *keystoreWallet) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
@@ -124,6 +141,17 @@ func (w *keystoreWallet) SignHashWithPassphrase(account accounts.Account, passph
 	return w.keystore.SignHashWithPassphrase(account, passphrase, hash)
 }
 
+// SignTypedDataWithPassphrase implements accounts.Wallet, attempting to sign the
+// EIP-712 hash of the given typed data with the given account using passphrase
+// as extra authentication.
+func (w *keystoreWallet) SignTypedDataWithPassphrase(account accounts.Account, passphrase string, data *eip712.TypedData) ([]byte, error) {
+	hash, err := data.Hash()
+	if err != nil {
+		return nil, err
+	}
+	return w.SignHashWithPassphrase(account, passphrase, hash[:])
+}
+
 // SignTxWithPassphrase implements accounts.Wallet, attempting to sign the given
 // transaction with the given account using passphrase as extra authentication.
 func (w *keystoreWallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
@@ -159,6 +159,15 @@ func (w *trezorDriver) SignTx(path accounts.DerivationPath, tx *types.Transactio
 	return w.trezorSign(path, tx, chainID)
 }
 
+// SignText implements usbwallet.driver, sending the personal message to the
+// Trezor and waiting for the user to confirm or deny signing it.
+func (w *trezorDriver) SignText(path accounts.DerivationPath, text []byte) ([]byte, error) {
+	if w.device == nil {
+		return nil, accounts.ErrWalletClosed
+	}
+	return w.trezorSignText(path, text)
+}
+
 // trezorDerive sends a derivation request to the Trezor device and returns the
 // Ethereum address located on that path.
 func (w *trezorDriver) trezorDerive(derivationPath []uint32) (common.Address, error) {
@@ -235,6 +244,24 @@ func (w *trezorDriver) trezorSign(derivationPath []uint32, tx *types.Transaction
 	return sender, signed, nil
 }
 
+// trezorSignText sends the personal message to the Trezor wallet, and waits for
+// the user to confirm or deny signing it.
+func (w *trezorDriver) trezorSignText(derivationPath []uint32, text []byte) ([]byte, error) {
+	response := new(trezor.EthereumMessageSignature)
+	if _, err := w.trezorExchange(&trezor.EthereumSignMessage{AddressN: derivationPath, Message: text}, response); err != nil {
+		return nil, err
+	}
+	// Extract the Ethereum signature and do a sanity validation
+	signature := response.GetSignature()
+	if len(signature) != 65 {
+		return nil, errors.New("reply lacks signature")
+	}
+	signature = common.CopyBytes(signature)
+	signature[64] -= 27
+
+	return signature, nil
+}
+
 // trezorExchange performs a data exchange with the Trezor wallet, sending it a
 // message and retrieving the response. If multiple responses are possible, the
 // method will also return the index of the destination object used.
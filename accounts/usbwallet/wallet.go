@@ -28,6 +28,8 @@ import (
 	"github.com/69th-byte/sdexchain/accounts"
 	"github.com/69th-byte/sdexchain/common"
 	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/crypto/eip712"
 	"github.com/69th-byte/sdexchain/log"
 	"github.com/karalabe/hid"
 	ethereum "github.com/tomochain/tomochain"
@@ -66,6 +68,11 @@ type driver interface {
 	// SignTx sends the transaction to the USB device and waits for the user to confirm
 	// or deny the transaction.
 	SignTx(path accounts.DerivationPath, tx *types.Transaction, chainID *big.Int) (common.Address, *types.Transaction, error)
+
+	// SignText sends the personal message to the USB device and waits for the user
+	// to confirm or deny signing it, returning the signature in [R || S || V]
+	// format where V is 0 or 1.
+	SignText(path accounts.DerivationPath, text []byte) ([]byte, error)
 }
 
 // wallet represents the common functionality shared by all USB hardware
@@ -521,6 +528,10 @@ func (w *wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID
 	if !ok {
 		return nil, accounts.ErrUnknownAccount
 	}
+	// Hardware wallets only understand the legacy transaction format
+	if tx.Type() != types.LegacyTxType {
+		return nil, types.ErrTxTypeNotSupported
+	}
 	// All infos gathered and metadata checks out, request signing
 	<-w.commsLock
 	defer func() { w.commsLock <- struct{}{} }()
@@ -547,6 +558,59 @@ func (w *wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID
 	return signed, nil
 }
 
+// SignText implements accounts.Wallet. It sends the personal message over to the
+// hardware wallet to request a confirmation from the user, so that order and
+// lending transactions can be signed without a hot key. It returns either the
+// signature or a failure if the user denied signing.
+func (w *wallet) SignText(account accounts.Account, text []byte) ([]byte, error) {
+	w.stateLock.RLock() // Comms have own mutex, this is for the state fields
+	defer w.stateLock.RUnlock()
+
+	// If the wallet is closed, abort
+	if w.device == nil {
+		return nil, accounts.ErrWalletClosed
+	}
+	// Make sure the requested account is contained within
+	path, ok := w.paths[account.Address]
+	if !ok {
+		return nil, accounts.ErrUnknownAccount
+	}
+	// All infos gathered and metadata checks out, request signing
+	<-w.commsLock
+	defer func() { w.commsLock <- struct{}{} }()
+
+	// Ensure the device isn't screwed with while user confirmation is pending
+	// TODO(karalabe): remove if hotplug lands on Windows
+	w.hub.commsLock.Lock()
+	w.hub.commsPend++
+	w.hub.commsLock.Unlock()
+
+	defer func() {
+		w.hub.commsLock.Lock()
+		w.hub.commsPend--
+		w.hub.commsLock.Unlock()
+	}()
+	// Sign the message and verify the signer to avoid hardware fault surprises
+	signature, err := w.driver.SignText(path, text)
+	if err != nil {
+		return nil, err
+	}
+	pubkey, err := crypto.SigToPub(accounts.TextHash(text), signature)
+	if err != nil {
+		return nil, err
+	}
+	if signer := crypto.PubkeyToAddress(*pubkey); signer != account.Address {
+		return nil, fmt.Errorf("signer mismatch: expected %s, got %s", account.Address.Hex(), signer.Hex())
+	}
+	return signature, nil
+}
+
+// SignTypedData implements accounts.Wallet, however signing typed data is not
+// supported for hardware wallets, so this method will always return an error.
+func (w *wallet) SignTypedData(account accounts.Account, data *eip712.TypedData) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
 // SignHashWithPassphrase implements accounts.Wallet, however signing arbitrary
 // data is not supported for Ledger wallets, so this method will always return
 // an error.
@@ -554,6 +618,13 @@ func (w *wallet) SignHashWithPassphrase(account accounts.Account, passphrase str
 	return w.SignHash(account, hash)
 }
 
+// SignTypedDataWithPassphrase implements accounts.Wallet, however signing typed
+// data is not supported for hardware wallets, so this method will always return
+// an error.
+func (w *wallet) SignTypedDataWithPassphrase(account accounts.Account, passphrase string, data *eip712.TypedData) ([]byte, error) {
+	return w.SignTypedData(account, data)
+}
+
 // SignTxWithPassphrase implements accounts.Wallet, attempting to sign the given
 // transaction with the given account using passphrase as extra authentication.
 // Since USB wallets don't rely on passphrases, these are silently ignored.
@@ -0,0 +1,306 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/p2p/discover"
+	"github.com/69th-byte/sdexchain/p2p/dnsdisc"
+	"github.com/69th-byte/sdexchain/p2p/enr"
+	"gopkg.in/urfave/cli.v1"
+)
+
+const (
+	nodesFile    = "nodes.json"
+	treeInfoFile = "enrtree-info.json"
+)
+
+var (
+	dnsCommand = cli.Command{
+		Name:  "dns",
+		Usage: "DNS node list (EIP-1459) management",
+		Subcommands: []cli.Command{
+			dnsSyncCommand,
+			dnsSignCommand,
+			dnsToZoneFileCommand,
+		},
+	}
+	dnsSyncCommand = cli.Command{
+		Name:      "sync",
+		Usage:     "Download a DNS node list",
+		ArgsUsage: "<url> [ <tree-directory> ]",
+		Action:    dnsSync,
+	}
+	dnsSignCommand = cli.Command{
+		Name:      "sign",
+		Usage:     "Sign a DNS node list",
+		ArgsUsage: "<tree-directory> <key-file>",
+		Description: `
+Sign the node list defined by the nodes.json file of the tree directory with the
+given private key. The nodes.json file maps node IDs to their node records, as
+gathered by a crawler or from the "enr" field of admin.nodeInfo. The domain,
+sequence number and links of the list are kept in enrtree-info.json, which is
+updated with the signature.
+`,
+		Flags:  []cli.Flag{domainFlag, seqFlag},
+		Action: dnsSign,
+	}
+	dnsToZoneFileCommand = cli.Command{
+		Name:      "to-zonefile",
+		Usage:     "Create a DNS zone file from a signed node list",
+		ArgsUsage: "<tree-directory>",
+		Flags:     []cli.Flag{ttlFlag},
+		Action:    dnsToZoneFile,
+	}
+)
+
+var (
+	domainFlag = cli.StringFlag{
+		Name:  "domain",
+		Usage: "domain name of the tree",
+	}
+	seqFlag = cli.UintFlag{
+		Name:  "seq",
+		Usage: "sequence number of the tree",
+	}
+	ttlFlag = cli.UintFlag{
+		Name:  "ttl",
+		Usage: "time to live of the DNS records in seconds",
+		Value: 3600,
+	}
+)
+
+// nodeJSON is an entry of a crawled node set.
+type nodeJSON struct {
+	Record *enr.Record `json:"record"`
+}
+
+// nodeSet is the content of nodes.json.
+type nodeSet map[discover.NodeID]nodeJSON
+
+// dnsDefinition is the content of a tree directory.
+type dnsDefinition struct {
+	Meta  dnsMetaJSON `json:"meta"`
+	Nodes nodeSet     `json:"nodes"`
+}
+
+// dnsMetaJSON is the content of enrtree-info.json.
+type dnsMetaJSON struct {
+	URL       string   `json:"url,omitempty"`
+	Domain    string   `json:"domain,omitempty"`
+	Seq       uint     `json:"seq"`
+	Signature string   `json:"signature,omitempty"`
+	Links     []string `json:"links"`
+}
+
+// dnsSync performs dnsSyncCommand.
+func dnsSync(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return fmt.Errorf("need tree URL as argument")
+	}
+	url, outdir := ctx.Args().Get(0), ctx.Args().Get(1)
+
+	client, err := dnsdisc.NewClient(dnsdisc.Config{})
+	if err != nil {
+		return err
+	}
+	t, err := client.SyncTree(url)
+	if err != nil {
+		return err
+	}
+	_, domain, _ := dnsdisc.ParseURL(url)
+	def := treeToDefinition(url, domain, t)
+	if outdir == "" {
+		return writeJSON(os.Stdout, def)
+	}
+	return writeTreeDefinition(outdir, def)
+}
+
+// dnsSign performs dnsSignCommand.
+func dnsSign(ctx *cli.Context) error {
+	if ctx.NArg() < 2 {
+		return fmt.Errorf("need tree definition directory and key file as arguments")
+	}
+	defdir, keyfile := ctx.Args().Get(0), ctx.Args().Get(1)
+
+	def, err := loadTreeDefinition(defdir)
+	if err != nil {
+		return err
+	}
+	key, err := crypto.LoadECDSA(keyfile)
+	if err != nil {
+		return fmt.Errorf("can't load key: %v", err)
+	}
+	if ctx.IsSet(domainFlag.Name) {
+		def.Meta.Domain = ctx.String(domainFlag.Name)
+	}
+	if def.Meta.Domain == "" {
+		return fmt.Errorf("missing domain, set it with --%s", domainFlag.Name)
+	}
+	if ctx.IsSet(seqFlag.Name) {
+		def.Meta.Seq = ctx.Uint(seqFlag.Name)
+	}
+	t, err := makeTree(def)
+	if err != nil {
+		return err
+	}
+	url, err := t.Sign(key, def.Meta.Domain)
+	if err != nil {
+		return fmt.Errorf("can't sign: %v", err)
+	}
+	def = treeToDefinition(url, def.Meta.Domain, t)
+	if err := writeTreeDefinition(defdir, def); err != nil {
+		return err
+	}
+	fmt.Println(url)
+	return nil
+}
+
+// dnsToZoneFile performs dnsToZoneFileCommand.
+func dnsToZoneFile(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return fmt.Errorf("need tree definition directory as argument")
+	}
+	def, err := loadTreeDefinition(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	t, err := signedTree(def)
+	if err != nil {
+		return err
+	}
+	return t.WriteZoneFile(os.Stdout, def.Meta.Domain, ctx.Uint(ttlFlag.Name))
+}
+
+// makeTree creates the (unsigned) tree of a definition.
+func makeTree(def *dnsDefinition) (*dnsdisc.Tree, error) {
+	records := make([]*enr.Record, 0, len(def.Nodes))
+	for id, n := range def.Nodes {
+		if n.Record == nil {
+			return nil, fmt.Errorf("node %x has no record", id[:8])
+		}
+		node, err := discover.NodeFromRecord(n.Record)
+		if err != nil {
+			return nil, fmt.Errorf("invalid record of node %x: %v", id[:8], err)
+		}
+		if node.ID != id {
+			return nil, fmt.Errorf("record of node %x has ID %x", id[:8], node.ID[:8])
+		}
+		records = append(records, n.Record)
+	}
+	return dnsdisc.MakeTree(def.Meta.Seq, records, def.Meta.Links)
+}
+
+// signedTree creates the tree of a definition and verifies its signature.
+func signedTree(def *dnsDefinition) (*dnsdisc.Tree, error) {
+	if def.Meta.URL == "" || def.Meta.Signature == "" {
+		return nil, fmt.Errorf("tree is not signed")
+	}
+	pubkey, domain, err := dnsdisc.ParseURL(def.Meta.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tree URL: %v", err)
+	}
+	if domain != def.Meta.Domain {
+		return nil, fmt.Errorf("tree URL domain %s doesn't match %s", domain, def.Meta.Domain)
+	}
+	t, err := makeTree(def)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.SetSignature(pubkey, def.Meta.Signature); err != nil {
+		return nil, fmt.Errorf("tree doesn't match its signature, sign it again: %v", err)
+	}
+	return t, nil
+}
+
+func treeToDefinition(url, domain string, t *dnsdisc.Tree) *dnsDefinition {
+	def := &dnsDefinition{
+		Meta: dnsMetaJSON{
+			URL:       url,
+			Domain:    domain,
+			Seq:       t.Seq(),
+			Signature: t.Signature(),
+			Links:     t.Links(),
+		},
+		Nodes: make(nodeSet),
+	}
+	for _, r := range t.Nodes() {
+		if n, err := discover.NodeFromRecord(r); err == nil {
+			def.Nodes[n.ID] = nodeJSON{Record: r}
+		}
+	}
+	return def
+}
+
+// loadTreeDefinition loads a directory containing nodes.json and, optionally,
+// enrtree-info.json.
+func loadTreeDefinition(directory string) (*dnsDefinition, error) {
+	def := &dnsDefinition{Nodes: make(nodeSet)}
+	if err := readJSON(filepath.Join(directory, nodesFile), &def.Nodes); err != nil {
+		return nil, err
+	}
+	err := readJSON(filepath.Join(directory, treeInfoFile), &def.Meta)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return def, nil
+}
+
+// writeTreeDefinition writes a tree definition to the given directory.
+func writeTreeDefinition(directory string, def *dnsDefinition) error {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return err
+	}
+	meta, err := json.MarshalIndent(def.Meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(directory, treeInfoFile), append(meta, '\n'), 0644); err != nil {
+		return err
+	}
+	nodes, err := json.MarshalIndent(def.Nodes, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(directory, nodesFile), append(nodes, '\n'), 0644)
+}
+
+func readJSON(file string, v interface{}) error {
+	blob, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(blob, v); err != nil {
+		return fmt.Errorf("invalid JSON in %s: %v", file, err)
+	}
+	return nil
+}
+
+func writeJSON(w *os.File, v interface{}) error {
+	blob, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", blob)
+	return err
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/p2p/discover"
+)
+
+func TestTreeDefinitionSignAndLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "devp2p-dns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Write a crawled node set
+	def := &dnsDefinition{Meta: dnsMetaJSON{Domain: "nodes.example.org", Seq: 1}, Nodes: make(nodeSet)}
+	for i := 0; i < 20; i++ {
+		key, _ := crypto.GenerateKey()
+		node := discover.NewNode(discover.PubkeyID(&key.PublicKey), net.IP{10, 0, 0, byte(i + 1)}, 30303, 30303)
+		r, err := discover.SignNodeRecord(node, key)
+		if err != nil {
+			t.Fatalf("failed to sign record: %v", err)
+		}
+		def.Nodes[node.ID] = nodeJSON{Record: r}
+	}
+	if err := writeTreeDefinition(dir, def); err != nil {
+		t.Fatalf("failed to write definition: %v", err)
+	}
+	// Unsigned trees can't be exported
+	loaded, err := loadTreeDefinition(dir)
+	if err != nil {
+		t.Fatalf("failed to load definition: %v", err)
+	}
+	if _, err := signedTree(loaded); err == nil {
+		t.Fatal("exported unsigned tree")
+	}
+	// Sign the tree and store the signature
+	tree, err := makeTree(loaded)
+	if err != nil {
+		t.Fatalf("failed to make tree: %v", err)
+	}
+	signer, _ := crypto.GenerateKey()
+	url, err := tree.Sign(signer, loaded.Meta.Domain)
+	if err != nil {
+		t.Fatalf("failed to sign tree: %v", err)
+	}
+	if err := writeTreeDefinition(dir, treeToDefinition(url, loaded.Meta.Domain, tree)); err != nil {
+		t.Fatalf("failed to write signed definition: %v", err)
+	}
+	// The signed tree can be restored from the directory
+	signed, err := loadTreeDefinition(dir)
+	if err != nil {
+		t.Fatalf("failed to load signed definition: %v", err)
+	}
+	if len(signed.Nodes) != len(def.Nodes) {
+		t.Fatalf("node count mismatch: got %d, want %d", len(signed.Nodes), len(def.Nodes))
+	}
+	restored, err := signedTree(signed)
+	if err != nil {
+		t.Fatalf("failed to restore signed tree: %v", err)
+	}
+	if restored.Signature() != tree.Signature() {
+		t.Error("restored signature mismatch")
+	}
+	// Changing the node set invalidates the signature
+	for id := range signed.Nodes {
+		delete(signed.Nodes, id)
+		break
+	}
+	if _, err := signedTree(signed); err == nil {
+		t.Error("restored signature of changed tree")
+	}
+}
@@ -257,6 +257,15 @@ func makeFullNode(ctx *cli.Context) (*node.Node, tomoConfig) {
 		}
 		utils.RegisterShhService(stack, &cfg.Shh)
 	}
+	// Add the relayer quote channel on top of Whisper if requested.
+	if ctx.GlobalBool(utils.TomoXRelayFlag.Name) {
+		utils.RegisterRelayService(stack)
+	}
+
+	// Mount the GraphQL endpoint on the HTTP-RPC server if requested.
+	if ctx.GlobalBool(utils.GraphQLEnabledFlag.Name) {
+		utils.RegisterGraphQLService(stack)
+	}
 
 	// Add the Ethereum Stats daemon if requested.
 	if cfg.Ethstats.URL != "" {
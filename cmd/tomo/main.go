@@ -58,6 +58,7 @@ var (
 		utils.BootnodesV4Flag,
 		utils.BootnodesV5Flag,
 		utils.DataDirFlag,
+		utils.AncientFlag,
 		utils.KeyStoreDirFlag,
 		//utils.NoUSBFlag,
 		//utils.EthashCacheDirFlag,
@@ -86,6 +87,10 @@ var (
 		utils.LightModeFlag,
 		utils.SyncModeFlag,
 		utils.GCModeFlag,
+		utils.FreezerFlag,
+		utils.FreezerThresholdFlag,
+		utils.SnapshotFlag,
+		utils.SnapshotCacheFlag,
 		//utils.LightServFlag,
 		//utils.LightPeersFlag,
 		//utils.LightKDFFlag,
@@ -95,6 +100,7 @@ var (
 		//utils.TrieCacheGenFlag,
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
+		utils.ValidatorPeersFlag,
 		utils.MaxPendingPeersFlag,
 		utils.EtherbaseFlag,
 		utils.GasPriceFlag,
@@ -103,6 +109,7 @@ var (
 		utils.TargetGasLimitFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
+		utils.DNSDiscoveryFlag,
 		//utils.DiscoveryV5Flag,
 		//utils.NetrestrictFlag,
 		utils.NodeKeyFileFlag,
@@ -117,8 +124,11 @@ var (
 		utils.NetworkIdFlag,
 		utils.RPCCORSDomainFlag,
 		utils.RPCVirtualHostsFlag,
+		utils.GraphQLEnabledFlag,
 		utils.EthStatsURLFlag,
 		utils.MetricsEnabledFlag,
+		utils.MetricsHTTPFlag,
+		utils.MetricsPortFlag,
 		//utils.FakePoWFlag,
 		//utils.NoCompactionFlag,
 		//utils.GpoBlocksFlag,
@@ -149,6 +159,7 @@ var (
 		utils.WhisperEnabledFlag,
 		utils.WhisperMaxMessageSizeFlag,
 		utils.WhisperMinPOWFlag,
+		utils.TomoXRelayFlag,
 	}
 )
 
@@ -175,6 +186,10 @@ func init() {
 		versionCommand,
 		// See config.go
 		dumpConfigCommand,
+		// See tokencmd.go
+		tokenCommand,
+		// See prunecmd.go
+		pruneStateCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
@@ -191,6 +206,7 @@ func init() {
 		}
 		// Start system runtime metrics collection
 		go metrics.CollectProcessMetrics(3 * time.Second)
+		utils.SetupMetrics(ctx)
 
 		utils.SetupNetwork(ctx)
 		return nil
@@ -0,0 +1,190 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+
+	"github.com/69th-byte/sdexchain/cmd/utils"
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/consensus/posv"
+	"github.com/69th-byte/sdexchain/core"
+	"github.com/69th-byte/sdexchain/core/rawdb"
+	"github.com/69th-byte/sdexchain/core/state/pruner"
+	"github.com/69th-byte/sdexchain/ethdb"
+	"github.com/69th-byte/sdexchain/log"
+	"github.com/69th-byte/sdexchain/tomox"
+	"github.com/69th-byte/sdexchain/tomoxlending"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	pruneBlocksFlag = cli.Uint64Flag{
+		Name:  "blocks",
+		Usage: "Number of recent blocks whose state, trading and lending tries are kept",
+		Value: 128,
+	}
+	pruneBloomSizeFlag = cli.Uint64Flag{
+		Name:  "bloomfilter.size",
+		Usage: "Megabytes of memory allocated to the bloom filter of the kept trie nodes",
+		Value: 2048,
+	}
+	pruneDryRunFlag = cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "Only report the number and size of the trie nodes which would be pruned",
+	}
+
+	pruneStateCommand = cli.Command{
+		Action:    utils.MigrateFlags(pruneState),
+		Name:      "prune-state",
+		Usage:     "Prune stale state, trading and lending trie nodes",
+		ArgsUsage: " ",
+		Category:  "BLOCKCHAIN COMMANDS",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.TomoXDataDirFlag,
+			utils.FreezerFlag,
+			utils.AncientFlag,
+			utils.TestnetFlag,
+			pruneBlocksFlag,
+			pruneBloomSizeFlag,
+			pruneDryRunFlag,
+		},
+		Description: `
+    tomo prune-state [--blocks <n>] [--dry-run]
+
+deletes the trie nodes which are not reachable from the last n blocks (default
+128) from the databases of a stopped node. The account state tries (along with
+their storage tries and contract codes) are kept in the chain database, the
+TomoX trading and lending tries in the TomoX database. The genesis state is
+always kept.
+
+The kept nodes are recorded in a bloom filter, a false positive only keeps a
+stale node. With --dry-run nothing is deleted, the command only reports the
+number and size of the stale nodes.`,
+	}
+)
+
+func pruneState(ctx *cli.Context) error {
+	stack, cfg := makeConfigNode(ctx)
+
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	var (
+		blocks    = ctx.Uint64(pruneBlocksFlag.Name)
+		bloomSize = ctx.Uint64(pruneBloomSizeFlag.Name)
+		dryRun    = ctx.Bool(pruneDryRunFlag.Name)
+	)
+	if blocks == 0 {
+		utils.Fatalf("At least the state of the head block must be kept")
+	}
+	headHash := core.GetHeadBlockHash(chainDb)
+	if headHash == (common.Hash{}) {
+		utils.Fatalf("No head block in the database")
+	}
+	head := core.GetBlockNumber(chainDb, headHash)
+	genesis := core.GetBlock(chainDb, core.GetCanonicalHash(chainDb, 0), 0)
+	if genesis == nil {
+		utils.Fatalf("No genesis block in the database")
+	}
+	config, err := core.GetChainConfig(chainDb, genesis.Hash())
+	if err != nil {
+		utils.Fatalf("Failed to load the chain config: %v", err)
+	}
+	var engine *posv.Posv
+	if config.Posv != nil {
+		engine = posv.New(config.Posv, chainDb)
+	}
+	// Collect the roots of the recent blocks, oldest first
+	first := uint64(0)
+	if head >= blocks {
+		first = head - blocks + 1
+	}
+	var stateRoots, tradingRoots, lendingRoots []common.Hash
+	for number := first; number <= head; number++ {
+		block := core.GetBlock(chainDb, core.GetCanonicalHash(chainDb, number), number)
+		if block == nil {
+			utils.Fatalf("Block #%d missing from the database", number)
+		}
+		stateRoots = append(stateRoots, block.Root())
+
+		if engine == nil || !config.IsTIPTomoX(block.Number()) || number <= config.Posv.Epoch {
+			continue
+		}
+		author, err := engine.Author(block.Header())
+		if err != nil {
+			utils.Fatalf("Failed to retrieve the author of block #%d: %v", number, err)
+		}
+		// The root lookups only read the block, no service state is needed
+		tradingRoot, _ := new(tomox.TomoX).GetTradingStateRoot(block, author)
+		lendingRoot, _ := new(tomoxlending.Lending).GetLendingStateRoot(block, author)
+		tradingRoots = append(tradingRoots, tradingRoot)
+		lendingRoots = append(lendingRoots, lendingRoot)
+	}
+	log.Info("Pruning stale trie nodes", "head", head, "kept", head-first+1, "dryrun", dryRun)
+
+	if err := pruneChainState(chainDb, stateRoots, genesis.Root(), bloomSize, dryRun); err != nil {
+		utils.Fatalf("Failed to prune the state tries: %v", err)
+	}
+	if len(tradingRoots) == 0 {
+		log.Info("No trading and lending state to prune")
+		return nil
+	}
+	if _, err := os.Stat(cfg.TomoX.DataDir); err != nil {
+		log.Info("No TomoX database to prune", "path", cfg.TomoX.DataDir)
+		return nil
+	}
+	tomoxDb, err := rawdb.NewLevelDBDatabase(cfg.TomoX.DataDir, 128, utils.MakeDatabaseHandles(), "")
+	if err != nil {
+		utils.Fatalf("Could not open the TomoX database: %v", err)
+	}
+	defer tomoxDb.Close()
+
+	if err := pruneTradingState(tomoxDb, tradingRoots, lendingRoots, bloomSize, dryRun); err != nil {
+		utils.Fatalf("Failed to prune the trading and lending tries: %v", err)
+	}
+	return nil
+}
+
+// pruneChainState deletes the account state trie nodes and contract codes of
+// the chain database which are not reachable from the given roots or from the
+// genesis state.
+func pruneChainState(db ethdb.Database, roots []common.Hash, genesis common.Hash, bloomSize uint64, dryRun bool) error {
+	p := pruner.NewPruner(db, bloomSize)
+	if err := p.MarkState(roots); err != nil {
+		return err
+	}
+	if err := p.MarkState([]common.Hash{genesis}); err != nil {
+		return err
+	}
+	_, _, err := p.Prune(dryRun)
+	return err
+}
+
+// pruneTradingState deletes the trading and lending trie nodes of the TomoX
+// database which are not reachable from the given roots.
+func pruneTradingState(db ethdb.Database, tradingRoots, lendingRoots []common.Hash, bloomSize uint64, dryRun bool) error {
+	p := pruner.NewPruner(db, bloomSize)
+	if err := p.MarkTries(tradingRoots); err != nil {
+		return err
+	}
+	if err := p.MarkTries(lendingRoots); err != nil {
+		return err
+	}
+	_, _, err := p.Prune(dryRun)
+	return err
+}
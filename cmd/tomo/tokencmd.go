@@ -0,0 +1,107 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/69th-byte/sdexchain/cmd/utils"
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/core"
+	"github.com/69th-byte/sdexchain/core/types"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	tokenCommand = cli.Command{
+		Name:     "token",
+		Usage:    "Inspect TRC21 tokens",
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+Inspect TRC21 tokens against the local chain.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "check",
+				Usage:     "Check whether a token can be applied to TomoX/TomoZ",
+				ArgsUsage: "<tokenAddress> [<blockHash> | <blockNum>]",
+				Action:    utils.MigrateFlags(checkToken),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.TestnetFlag,
+				},
+				Description: `
+    tomo token check <tokenAddress> [<blockHash> | <blockNum>]
+
+runs the checks of TomoX/TomoZ apply transactions against the token at the
+given block (default: the current block) without sending any transaction.
+It reports which storage slot layout assumption failed, the token decimals
+and the TRC21 fee capacity of the token.`,
+			},
+		},
+	}
+)
+
+func checkToken(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 || !common.IsHexAddress(ctx.Args().First()) {
+		utils.Fatalf("This command requires a token address as the first argument.")
+	}
+	token := common.HexToAddress(ctx.Args().First())
+
+	stack, _ := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	block := chain.CurrentBlock()
+	if len(ctx.Args()) > 1 {
+		arg := ctx.Args().Get(1)
+		if hashish(arg) {
+			block = chain.GetBlockByHash(common.HexToHash(arg))
+		} else {
+			num, err := strconv.ParseUint(arg, 10, 64)
+			if err != nil {
+				utils.Fatalf("Invalid block number %s: %v", arg, err)
+			}
+			block = chain.GetBlockByNumber(num)
+		}
+	}
+	if block == nil {
+		utils.Fatalf("block not found")
+	}
+	result, err := checkTokenAtBlock(chain, block, token)
+	if err != nil {
+		utils.Fatalf("Failed to check token %s: %v", token.Hex(), err)
+	}
+	out, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		utils.Fatalf("Failed to encode result: %v", err)
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+func checkTokenAtBlock(chain *core.BlockChain, block *types.Block, token common.Address) (*core.TokenCompatibility, error) {
+	statedb, err := chain.StateAt(block.Root())
+	if err != nil {
+		return nil, err
+	}
+	if len(statedb.GetCode(token)) == 0 {
+		return nil, fmt.Errorf("no contract code at block %d", block.NumberU64())
+	}
+	return core.CheckTokenCompatibility(chain, block.Header(), statedb, token)
+}
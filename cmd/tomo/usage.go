@@ -67,6 +67,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			configFileFlag,
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.KeyStoreDirFlag,
 			//utils.NoUSBFlag,
 			utils.NetworkIdFlag,
@@ -74,6 +75,10 @@ var AppHelpFlagGroups = []flagGroup{
 			//utils.RinkebyFlag,
 			utils.SyncModeFlag,
 			utils.GCModeFlag,
+			utils.FreezerFlag,
+			utils.FreezerThresholdFlag,
+			utils.SnapshotFlag,
+			utils.SnapshotCacheFlag,
 			utils.EthStatsURLFlag,
 			utils.IdentityFlag,
 			//utils.LightServFlag,
@@ -155,6 +160,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.IPCPathFlag,
 			utils.RPCCORSDomainFlag,
 			utils.RPCVirtualHostsFlag,
+			utils.GraphQLEnabledFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
@@ -168,9 +174,11 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.BootnodesV5Flag,
 			utils.ListenPortFlag,
 			utils.MaxPeersFlag,
+			utils.ValidatorPeersFlag,
 			utils.MaxPendingPeersFlag,
 			utils.NATFlag,
 			utils.NoDiscoverFlag,
+			utils.DNSDiscoveryFlag,
 			//utils.DiscoveryV5Flag,
 			//utils.NetrestrictFlag,
 			utils.NodeKeyFileFlag,
@@ -205,6 +213,8 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "LOGGING AND DEBUGGING",
 		Flags: append([]cli.Flag{
 			utils.MetricsEnabledFlag,
+			utils.MetricsHTTPFlag,
+			utils.MetricsPortFlag,
 			//utils.FakePoWFlag,
 			//utils.NoCompactionFlag,
 		}, debug.Flags...),
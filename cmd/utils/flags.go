@@ -44,6 +44,7 @@ import (
 	"github.com/69th-byte/sdexchain/ethdb"
 	"github.com/69th-byte/sdexchain/log"
 	"github.com/69th-byte/sdexchain/metrics"
+	"github.com/69th-byte/sdexchain/metrics/exp"
 	"github.com/69th-byte/sdexchain/node"
 	"github.com/69th-byte/sdexchain/p2p"
 	"github.com/69th-byte/sdexchain/p2p/discover"
@@ -129,6 +130,19 @@ var (
 		Usage: "Data directory for the databases and keystore",
 		Value: DirectoryString{node.DefaultDataDir()},
 	}
+	AncientFlag = DirectoryFlag{
+		Name:  "datadir.ancient",
+		Usage: "Data directory for ancient chain segments (default = inside chaindata)",
+	}
+	FreezerFlag = cli.BoolFlag{
+		Name:  "freezer",
+		Usage: "Move finalized blocks and receipts from the database into the ancient store",
+	}
+	FreezerThresholdFlag = cli.Uint64Flag{
+		Name:  "freezer.threshold",
+		Usage: "Number of recent blocks kept in the database when the freezer is enabled",
+		Value: params.ImmutabilityThreshold,
+	}
 	KeyStoreDirFlag = DirectoryFlag{
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
@@ -305,6 +319,15 @@ var (
 		Usage: "Percentage of cache memory allowance to use for trie pruning",
 		Value: 25,
 	}
+	SnapshotFlag = cli.BoolFlag{
+		Name:  "snapshot",
+		Usage: "Serve the account and storage reads from a flat state snapshot (generated in the background)",
+	}
+	SnapshotCacheFlag = cli.IntFlag{
+		Name:  "snapshot.cache",
+		Usage: "Megabytes of memory allocated to the state snapshot read cache",
+		Value: 256,
+	}
 	// Miner settings
 	StakingEnabledFlag = cli.BoolFlag{
 		Name:  "mine",
@@ -359,6 +382,16 @@ var (
 		Name:  metrics.MetricsEnabledFlag,
 		Usage: "Enable metrics collection and reporting",
 	}
+	MetricsHTTPFlag = cli.StringFlag{
+		Name:  "metrics.addr",
+		Usage: "Enable the metrics HTTP server (expvar and Prometheus formats) on the given listening interface",
+		Value: "",
+	}
+	MetricsPortFlag = cli.IntFlag{
+		Name:  "metrics.port",
+		Usage: "Metrics HTTP server listening port",
+		Value: 6061,
+	}
 	FakePoWFlag = cli.BoolFlag{
 		Name:  "fakepow",
 		Usage: "Disables proof-of-work verification",
@@ -397,6 +430,10 @@ var (
 		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
 		Value: strings.Join(node.DefaultConfig.HTTPVirtualHosts, ","),
 	}
+	GraphQLEnabledFlag = cli.BoolFlag{
+		Name:  "graphql",
+		Usage: "Enable the GraphQL endpoint at /graphql on the HTTP-RPC server",
+	}
 	RPCApiFlag = cli.StringFlag{
 		Name:  "rpcapi",
 		Usage: "API's offered over the HTTP-RPC interface",
@@ -449,6 +486,11 @@ var (
 		Usage: "Maximum number of network peers (network disabled if set to 0)",
 		Value: 25,
 	}
+	ValidatorPeersFlag = cli.IntFlag{
+		Name:  "validatorpeers",
+		Usage: "Number of peer slots reserved for masternodes on PoSV chains (at most half of maxpeers)",
+		Value: eth.DefaultConfig.ValidatorPeers,
+	}
 	MaxPendingPeersFlag = cli.IntFlag{
 		Name:  "maxpendpeers",
 		Usage: "Maximum number of pending connection attempts (defaults used if set to 0)",
@@ -499,6 +541,10 @@ var (
 		Name:  "netrestrict",
 		Usage: "Restricts network communication to the given IP networks (CIDR masks)",
 	}
+	DNSDiscoveryFlag = cli.StringFlag{
+		Name:  "discovery.dns",
+		Usage: "Comma separated enrtree:// URLs of DNS node lists to dial peers from",
+	}
 
 	// ATM the url is left to the user and deployment to
 	JSpathFlag = cli.StringFlag{
@@ -532,6 +578,10 @@ var (
 		Usage: "Minimum POW accepted",
 		Value: whisper.DefaultMinimumPoW,
 	}
+	TomoXRelayFlag = cli.BoolFlag{
+		Name:  "tomox.relay",
+		Usage: "Enable the Whisper channel for relayer quotes (implies --shh)",
+	}
 	TomoXDataDirFlag = DirectoryFlag{
 		Name:  "tomox.datadir",
 		Usage: "Data directory for the TomoX databases",
@@ -883,6 +933,10 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		cfg.DiscoveryV5 = true
 	}
 
+	if urls := ctx.GlobalString(DNSDiscoveryFlag.Name); urls != "" {
+		cfg.DNSDiscovery = strings.Split(urls, ",")
+	}
+
 	if netrestrict := ctx.GlobalString(NetrestrictFlag.Name); netrestrict != "" {
 		list, err := netutil.ParseNetlist(netrestrict)
 		if err != nil {
@@ -1111,11 +1165,21 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
+	if ctx.GlobalIsSet(ValidatorPeersFlag.Name) {
+		cfg.ValidatorPeers = ctx.GlobalInt(ValidatorPeersFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheDatabaseFlag.Name) {
 		cfg.DatabaseCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
 	}
 	cfg.DatabaseHandles = MakeDatabaseHandles()
+	if ctx.GlobalIsSet(AncientFlag.Name) {
+		cfg.DatabaseFreezer = ctx.GlobalString(AncientFlag.Name)
+	}
+	cfg.Freezer = ctx.GlobalBool(FreezerFlag.Name)
+	if ctx.GlobalIsSet(FreezerThresholdFlag.Name) {
+		cfg.FreezerThreshold = ctx.GlobalUint64(FreezerThresholdFlag.Name)
+	}
 
 	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
@@ -1125,6 +1189,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
+	if ctx.GlobalBool(SnapshotFlag.Name) {
+		cfg.SnapshotCache = ctx.GlobalInt(SnapshotCacheFlag.Name)
+	}
 	if ctx.GlobalIsSet(StakerThreadsFlag.Name) {
 		cfg.MinerThreads = ctx.GlobalInt(StakerThreadsFlag.Name)
 	}
@@ -1186,6 +1253,17 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	// TODO(fjl): move trie cache generations into config
 }
 
+// SetupMetrics starts the metrics HTTP server if requested, metrics collection
+// itself is enabled by the --metrics flag.
+func SetupMetrics(ctx *cli.Context) {
+	if !metrics.Enabled {
+		return
+	}
+	if addr := ctx.GlobalString(MetricsHTTPFlag.Name); addr != "" {
+		exp.Setup(fmt.Sprintf("%s:%d", addr, ctx.GlobalInt(MetricsPortFlag.Name)))
+	}
+}
+
 // SetupNetwork configures the system for either the main net or some test network.
 func SetupNetwork(ctx *cli.Context) {
 	// TODO(fjl): move target gas limit into config
@@ -1198,11 +1276,18 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) ethdb.Database {
 		cache   = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
 		handles = MakeDatabaseHandles()
 	)
-	name := "chaindata"
-	if ctx.GlobalBool(LightModeFlag.Name) {
-		name = "lightchaindata"
+	var (
+		chainDb ethdb.Database
+		err     error
+	)
+	switch {
+	case ctx.GlobalBool(LightModeFlag.Name):
+		chainDb, err = stack.OpenDatabase("lightchaindata", cache, handles, "")
+	case ctx.GlobalBool(FreezerFlag.Name):
+		chainDb, err = stack.OpenDatabaseWithFreezer("chaindata", cache, handles, ctx.GlobalString(AncientFlag.Name), "", ctx.GlobalUint64(FreezerThresholdFlag.Name))
+	default:
+		chainDb, err = stack.OpenDatabase("chaindata", cache, handles, "")
 	}
-	chainDb, err := stack.OpenDatabase(name, cache, handles, "")
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
 	}
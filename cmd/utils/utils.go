@@ -1,12 +1,16 @@
 package utils
 
 import (
+	"errors"
+
 	"github.com/69th-byte/sdexchain/eth"
 	"github.com/69th-byte/sdexchain/eth/downloader"
 	"github.com/69th-byte/sdexchain/ethstats"
+	"github.com/69th-byte/sdexchain/graphql"
 	"github.com/69th-byte/sdexchain/les"
 	"github.com/69th-byte/sdexchain/node"
 	"github.com/69th-byte/sdexchain/tomox"
+	"github.com/69th-byte/sdexchain/tomox/relay"
 	"github.com/69th-byte/sdexchain/tomoxlending"
 	whisper "github.com/69th-byte/sdexchain/whisper/whisperv6"
 )
@@ -46,6 +50,24 @@ func RegisterShhService(stack *node.Node, cfg *whisper.Config) {
 	}
 }
 
+// RegisterRelayService adds the relayer quote channel to the given node, on top
+// of its Whisper service and submitting accepted quotes to its order pool.
+func RegisterRelayService(stack *node.Node) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		var shhServ *whisper.Whisper
+		if err := ctx.Service(&shhServ); err != nil {
+			return nil, err
+		}
+		var ethServ *eth.Ethereum
+		if err := ctx.Service(&ethServ); err != nil {
+			return nil, errors.New("relay requires a full node")
+		}
+		return relay.New(shhServ, ethServ.OrderPool())
+	}); err != nil {
+		Fatalf("Failed to register the relay service: %v", err)
+	}
+}
+
 // RegisterEthStatsService configures the Ethereum Stats daemon and adds it to
 // th egiven node.
 func RegisterEthStatsService(stack *node.Node, url string) {
@@ -63,6 +85,24 @@ func RegisterEthStatsService(stack *node.Node, url string) {
 	}
 }
 
+// RegisterGraphQLService mounts the GraphQL endpoint on the HTTP-RPC server of
+// the given node, backed by the full or light Ethereum service.
+func RegisterGraphQLService(stack *node.Node) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		var ethServ *eth.Ethereum
+		if err := ctx.Service(&ethServ); err == nil {
+			return graphql.New(stack, ethServ.ApiBackend)
+		}
+		var lesServ *les.LightEthereum
+		if err := ctx.Service(&lesServ); err == nil {
+			return graphql.New(stack, lesServ.ApiBackend)
+		}
+		return nil, errors.New("no Ethereum service")
+	}); err != nil {
+		Fatalf("Failed to register the GraphQL service: %v", err)
+	}
+}
+
 func RegisterTomoXService(stack *node.Node, cfg *tomox.Config) {
 	tomoX := tomox.New(cfg)
 	if err := stack.Register(func(n *node.ServiceContext) (node.Service, error) {
@@ -25,6 +25,7 @@ const (
 	IgnoreSignerCheckBlock     = uint64(14458500)
 	OneYear                    = uint64(365 * 86400)
 	LiquidateLendingTradeBlock = uint64(100)
+	CollateralPriceTWAPWindow  = uint64(3600) // seconds, window of TomoX price oracle used to price collaterals
 )
 
 var Rewound = uint64(0)
@@ -37,6 +38,9 @@ var BlackListHFNumber = uint64(9349100)
 var TIPTomoX = big.NewInt(20581700)
 var TIPTomoXLending = big.NewInt(21430200)
 var TIPTomoXCancellationFee = big.NewInt(30915660)
+var TIPTomoXRoutedOrder = big.NewInt(9999999999) // not scheduled yet
+var TIPTomoXPriceOracle = big.NewInt(9999999999) // not scheduled yet
+var TIPTomoXTypedData = big.NewInt(9999999999)   // not scheduled yet
 var TIPTomoXTestnet = big.NewInt(0)
 var IsTestnet bool = false
 var StoreRewardFolder string
@@ -0,0 +1,102 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package misc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/params"
+)
+
+// eip1559Config returns a copy of the test chain config with EIP-1559 activated
+// at block 5.
+func eip1559Config() *params.ChainConfig {
+	config := *params.TestChainConfig
+	config.EIP1559Block = big.NewInt(5)
+	return &config
+}
+
+// TestCalcBaseFee assumes all blocks are EIP-1559 blocks.
+func TestCalcBaseFee(t *testing.T) {
+	initial := int64(params.InitialBaseFee)
+	tests := []struct {
+		parentBaseFee   int64
+		parentGasLimit  uint64
+		parentGasUsed   uint64
+		expectedBaseFee int64
+	}{
+		{2 * initial, 20000000, 10000000, 2 * initial},          // usage == target
+		{2 * initial, 20000000, 9000000, 2*initial - 6250000},   // usage below target
+		{2 * initial, 20000000, 11000000, 2*initial + 6250000},  // usage above target
+		{2 * initial, 20000000, 20000000, 2*initial + 62500000}, // full block
+		{initial, 20000000, 0, initial},                         // floored at the initial base fee
+	}
+	for i, test := range tests {
+		parent := &types.Header{
+			Number:   big.NewInt(32),
+			GasLimit: test.parentGasLimit,
+			GasUsed:  test.parentGasUsed,
+			BaseFee:  big.NewInt(test.parentBaseFee),
+		}
+		if have, want := CalcBaseFee(eip1559Config(), parent), big.NewInt(test.expectedBaseFee); have.Cmp(want) != 0 {
+			t.Errorf("test %d: have %d  want %d, ", i, have, want)
+		}
+	}
+}
+
+// TestBlockBaseFeeVerification checks that the base fee is only present after
+// the fork and follows the parent header.
+func TestBlockBaseFeeVerification(t *testing.T) {
+	config := eip1559Config()
+	initial := new(big.Int).SetUint64(params.InitialBaseFee)
+	tests := []struct {
+		parentNumber, number int64
+		parentBaseFee        *big.Int
+		baseFee              *big.Int
+		ok                   bool
+	}{
+		{3, 4, nil, nil, true},
+		{3, 4, nil, initial, false},
+		{4, 5, nil, nil, false},
+		{4, 5, nil, initial, true},
+		{4, 5, nil, big.NewInt(1), false},
+		{5, 6, initial, initial, true},
+		{5, 6, initial, new(big.Int).Add(initial, big.NewInt(1)), false},
+	}
+	for i, test := range tests {
+		parent := &types.Header{
+			Number:   big.NewInt(test.parentNumber),
+			GasLimit: 20000000,
+			GasUsed:  10000000,
+			BaseFee:  test.parentBaseFee,
+		}
+		header := &types.Header{
+			Number:   big.NewInt(test.number),
+			GasLimit: 20000000,
+			BaseFee:  test.baseFee,
+		}
+		err := VerifyEip1559Header(config, parent, header)
+		if test.ok && err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		}
+		if !test.ok && err == nil {
+			t.Errorf("test %d: invalid header verified", i)
+		}
+	}
+}
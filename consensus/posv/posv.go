@@ -46,6 +46,7 @@ import (
 	"github.com/69th-byte/sdexchain/crypto/sha3"
 	"github.com/69th-byte/sdexchain/ethdb"
 	"github.com/69th-byte/sdexchain/log"
+	"github.com/69th-byte/sdexchain/metrics"
 	"github.com/69th-byte/sdexchain/params"
 	"github.com/69th-byte/sdexchain/rlp"
 	"github.com/69th-byte/sdexchain/rpc"
@@ -63,6 +64,19 @@ type Masternode struct {
 	Stake   *big.Int
 }
 
+// SortMasternodes orders the masternode candidates by stake descending and caps
+// them at common.MaxMasternodes. The comparator, including how it orders equal
+// stakes, is part of consensus: the set is checked against checkpoint headers.
+func SortMasternodes(masternodes []Masternode) []Masternode {
+	sort.Slice(masternodes, func(i, j int) bool {
+		return masternodes[i].Stake.Cmp(masternodes[j].Stake) >= 0
+	})
+	if len(masternodes) > common.MaxMasternodes {
+		masternodes = masternodes[:common.MaxMasternodes]
+	}
+	return masternodes
+}
+
 type TradingService interface {
 	GetTradingStateRoot(block *types.Block, author common.Address) (common.Hash, error)
 	GetTradingState(block *types.Block, author common.Address) (*tradingstate.TradingStateDB, error)
@@ -108,6 +122,11 @@ var (
 	diffNoTurn = big.NewInt(1) // Block difficulty for out-of-turn signatures
 )
 
+var (
+	missedTurnsMeter = metrics.NewRegisteredMeter("posv/turns/missed", nil) // Masternodes skipped before a block was sealed
+	penaltiesGauge   = metrics.NewRegisteredGauge("posv/penalties", nil)    // Masternodes penalized at the last checkpoint
+)
+
 // Various error messages to mark blocks invalid. These should be private to
 // prevent engine specific errors from being referenced in the remainder of the
 // codebase, inherently breaking if the engine is swapped out. Please put common
@@ -193,7 +212,7 @@ var (
 func sigHash(header *types.Header) (hash common.Hash) {
 	hasher := sha3.NewKeccak256()
 
-	rlp.Encode(hasher, []interface{}{
+	enc := []interface{}{
 		header.ParentHash,
 		header.UncleHash,
 		header.Coinbase,
@@ -209,7 +228,11 @@ func sigHash(header *types.Header) (hash common.Hash) {
 		header.Extra[:len(header.Extra)-65], // Yes, this will panic if extra is too short
 		header.MixDigest,
 		header.Nonce,
-	})
+	}
+	if header.BaseFee != nil {
+		enc = append(enc, header.BaseFee)
+	}
+	rlp.Encode(hasher, enc)
 	hasher.Sum(hash[:0])
 	return hash
 }
@@ -259,15 +282,17 @@ type Posv struct {
 	signFn clique.SignerFn // Signer function to authorize hashes with
 	lock   sync.RWMutex    // Protects the signer fields
 
+	turnParent common.Hash // Last parent whose missed turns were recorded, protected by lock
+
 	BlockSigners               *lru.Cache
 	HookReward                 func(chain consensus.ChainReader, state *state.StateDB, parentState *state.StateDB, header *types.Header) (error, map[string]interface{})
 	HookPenalty                func(chain consensus.ChainReader, blockNumberEpoc uint64) ([]common.Address, error)
 	HookPenaltyTIPSigning      func(chain consensus.ChainReader, header *types.Header, candidate []common.Address) ([]common.Address, error)
 	HookValidator              func(header *types.Header, signers []common.Address) ([]byte, error)
-	HookVerifyMNs              func(header *types.Header, signers []common.Address) error
+	HookVerifyMNs              func(header, parent *types.Header, signers []common.Address) error
 	GetTomoXService            func() TradingService
 	GetLendingService          func() LendingService
-	HookGetSignersFromContract func(blockHash common.Hash) ([]common.Address, error)
+	HookGetSignersFromContract func(header *types.Header) ([]common.Address, error)
 }
 
 // New creates a PoSV proof-of-stake-voting consensus engine with the initial
@@ -431,6 +456,10 @@ func (c *Posv) verifyCascadingFields(chain consensus.ChainReader, header *types.
 	if parent.Time.Uint64()+c.config.Period > header.Time.Uint64() {
 		return ErrInvalidTimestamp
 	}
+	// Verify the base fee of the block
+	if err := misc.VerifyEip1559Header(chain.Config(), parent, header); err != nil {
+		return err
+	}
 
 	if number%c.config.Epoch != 0 {
 		return c.verifySeal(chain, header, parents, fullVerify)
@@ -447,16 +476,16 @@ func (c *Posv) verifyCascadingFields(chain consensus.ChainReader, header *types.
 	}
 
 	signers := snap.GetSigners()
-	err = c.checkSignersOnCheckpoint(chain, header, signers)
+	err = c.checkSignersOnCheckpoint(chain, header, parent, signers)
 	if err == nil {
 		return c.verifySeal(chain, header, parents, fullVerify)
 	}
 
-	signers, err = c.GetSignersFromContract(chain, header)
+	signers, err = c.GetSignersFromContract(chain, header, parents)
 	if err != nil {
 		return err
 	}
-	err = c.checkSignersOnCheckpoint(chain, header, signers)
+	err = c.checkSignersOnCheckpoint(chain, header, parent, signers)
 	if err == nil {
 		return c.verifySeal(chain, header, parents, fullVerify)
 	}
@@ -464,7 +493,7 @@ func (c *Posv) verifyCascadingFields(chain consensus.ChainReader, header *types.
 	return err
 }
 
-func (c *Posv) checkSignersOnCheckpoint(chain consensus.ChainReader, header *types.Header, signers []common.Address) error {
+func (c *Posv) checkSignersOnCheckpoint(chain consensus.ChainReader, header, parent *types.Header, signers []common.Address) error {
 	number := header.Number.Uint64()
 	// ignore signerCheck at checkpoint block 14458500 due to wrong snapshot at gap 14458495
 	if number == common.IgnoreSignerCheckBlock {
@@ -488,11 +517,20 @@ func (c *Posv) checkSignersOnCheckpoint(chain consensus.ChainReader, header *typ
 		if !bytes.Equal(header.Penalties, bytePenalties) {
 			return errInvalidCheckpointPenalties
 		}
+	} else {
+		// Without block bodies (light clients) the penalties can't be recomputed,
+		// take the ones claimed by the checkpoint: they can only shrink the set.
+		penPenalties = common.ExtractAddressFromBytes(header.Penalties)
 	}
+	penaltiesGauge.Update(int64(len(penPenalties)))
+
 	signers = common.RemoveItemFromArray(signers, penPenalties)
 	for i := 1; i <= common.LimitPenaltyEpoch; i++ {
 		if number > uint64(i)*c.config.Epoch {
-			signers = RemovePenaltiesFromBlock(chain, signers, number-uint64(i)*c.config.Epoch)
+			var err error
+			if signers, err = RemovePenaltiesFromBlock(chain, signers, number-uint64(i)*c.config.Epoch); err != nil {
+				return err
+			}
 		}
 	}
 	extraSuffix := len(header.Extra) - extraSeal
@@ -504,7 +542,7 @@ func (c *Posv) checkSignersOnCheckpoint(chain consensus.ChainReader, header *typ
 		return errInvalidCheckpointSigners
 	}
 	if c.HookVerifyMNs != nil {
-		err := c.HookVerifyMNs(header, signers)
+		err := c.HookVerifyMNs(header, parent, signers)
 		if err != nil {
 			return err
 		}
@@ -542,6 +580,18 @@ func (c *Posv) StoreSnapshot(snap *Snapshot) error {
 	return snap.store(c.db)
 }
 
+// SetTrustedSnapshot makes the given masternodes the signers of a block whose
+// ancestors are not available locally, like the trusted checkpoint a light
+// client starts syncing from. Headers on top of it are verified against it.
+func (c *Posv) SetTrustedSnapshot(number uint64, hash common.Hash, masternodes []common.Address) error {
+	snap := newSnapshot(c.config, c.signatures, number, hash, masternodes)
+	c.recents.Add(snap.Hash, snap)
+	if (number+c.config.Gap)%c.config.Epoch == 0 {
+		return snap.store(c.db)
+	}
+	return nil
+}
+
 func position(list []common.Address, x common.Address) int {
 	for i, item := range list {
 		if item == x {
@@ -607,6 +657,7 @@ func (c *Posv) YourTurn(chain consensus.ChainReader, parent *types.Header, signe
 			return 0, 0, 0, false, err
 		}
 		preIndex = position(masternodes, pre)
+		c.recordMissedTurns(chain, snap, parent, masternodes, preIndex)
 	}
 	curIndex := position(masternodes, signer)
 	if signer == c.signer {
@@ -621,6 +672,34 @@ func (c *Posv) YourTurn(chain consensus.ChainReader, parent *types.Header, signe
 	return len(masternodes), preIndex, curIndex, false, nil
 }
 
+// recordMissedTurns counts the masternodes that skipped their turn between the
+// grandparent and the parent, once per parent.
+func (c *Posv) recordMissedTurns(chain consensus.ChainReader, snap *Snapshot, parent *types.Header, masternodes []common.Address, preIndex int) {
+	number := parent.Number.Uint64()
+	if preIndex == -1 || number < 2 || number%c.config.Epoch == 0 {
+		return
+	}
+	c.lock.Lock()
+	if c.turnParent == parent.Hash() {
+		c.lock.Unlock()
+		return
+	}
+	c.turnParent = parent.Hash()
+	c.lock.Unlock()
+
+	grandParent := chain.GetHeader(parent.ParentHash, number-1)
+	if grandParent == nil {
+		return
+	}
+	creator, err := whoIsCreator(snap, grandParent)
+	if err != nil {
+		return
+	}
+	if index := position(masternodes, creator); index != -1 {
+		missedTurnsMeter.Mark(int64(Hop(len(masternodes), index, preIndex)))
+	}
+}
+
 // snapshot retrieves the authorization snapshot at a given point in time.
 func (c *Posv) snapshot(chain consensus.ChainReader, number uint64, hash common.Hash, parents []*types.Header) (*Snapshot, error) {
 	// Search for a snapshot in memory or on disk for checkpoints
@@ -905,7 +984,9 @@ func (c *Posv) Prepare(chain consensus.ChainReader, header *types.Header) error
 		// Prevent penalized masternode(s) within 4 recent epochs
 		for i := 1; i <= common.LimitPenaltyEpoch; i++ {
 			if number > uint64(i)*c.config.Epoch {
-				masternodes = RemovePenaltiesFromBlock(chain, masternodes, number-uint64(i)*c.config.Epoch)
+				if masternodes, err = RemovePenaltiesFromBlock(chain, masternodes, number-uint64(i)*c.config.Epoch); err != nil {
+					return err
+				}
 			}
 		}
 		for _, masternode := range masternodes {
@@ -1188,19 +1269,22 @@ func (c *Posv) GetDb() ethdb.Database {
 	return c.db
 }
 
-// Extract validators from byte array.
-func RemovePenaltiesFromBlock(chain consensus.ChainReader, masternodes []common.Address, epochNumber uint64) []common.Address {
+// RemovePenaltiesFromBlock drops the masternodes penalized by the checkpoint at
+// epochNumber. The checkpoint header must be available locally: skipping it
+// would keep penalized masternodes in the set, so a missing header is an error.
+func RemovePenaltiesFromBlock(chain consensus.ChainReader, masternodes []common.Address, epochNumber uint64) ([]common.Address, error) {
 	if epochNumber <= 0 {
-		return masternodes
+		return masternodes, nil
 	}
 	header := chain.GetHeaderByNumber(epochNumber)
-	block := chain.GetBlock(header.Hash(), epochNumber)
-	penalties := block.Penalties()
-	if penalties != nil {
+	if header == nil {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	if penalties := header.Penalties; penalties != nil {
 		prevPenalties := common.ExtractAddressFromBytes(penalties)
 		masternodes = common.RemoveItemFromArray(masternodes, prevPenalties)
 	}
-	return masternodes
+	return masternodes, nil
 }
 
 // Get masternodes address from checkpoint Header.
@@ -1305,6 +1389,7 @@ func (c *Posv) CheckMNTurn(chain consensus.ChainReader, parent *types.Header, si
 			return false
 		}
 		preIndex = position(masternodes, pre)
+		c.recordMissedTurns(chain, snap, parent, masternodes, preIndex)
 	}
 	curIndex := position(masternodes, signer)
 	if (preIndex)%len(masternodes) == curIndex {
@@ -1313,13 +1398,25 @@ func (c *Posv) CheckMNTurn(chain consensus.ChainReader, parent *types.Header, si
 	return false
 }
 
-func (c *Posv) GetSignersFromContract(chain consensus.ChainReader, checkpointHeader *types.Header) ([]common.Address, error) {
+func (c *Posv) GetSignersFromContract(chain consensus.ChainReader, checkpointHeader *types.Header, parents []*types.Header) ([]common.Address, error) {
 	startGapBlockHeader := checkpointHeader
 	number := checkpointHeader.Number.Uint64()
 	for step := uint64(1); step <= chain.Config().Posv.Gap; step++ {
-		startGapBlockHeader = chain.GetHeader(startGapBlockHeader.ParentHash, number-step)
+		// Prefer the explicit parents, they aren't part of the local chain yet
+		if len(parents) > 0 {
+			startGapBlockHeader = parents[len(parents)-1]
+			parents = parents[:len(parents)-1]
+		} else {
+			startGapBlockHeader = chain.GetHeader(startGapBlockHeader.ParentHash, number-step)
+		}
+		if startGapBlockHeader == nil || startGapBlockHeader.Number.Uint64() != number-step {
+			return []common.Address{}, consensus.ErrUnknownAncestor
+		}
+	}
+	if c.HookGetSignersFromContract == nil {
+		return []common.Address{}, errors.New("Can't get signers from Smart Contract without a contract reader")
 	}
-	signers, err := c.HookGetSignersFromContract(startGapBlockHeader.Hash())
+	signers, err := c.HookGetSignersFromContract(startGapBlockHeader)
 	if err != nil {
 		return []common.Address{}, fmt.Errorf("Can't get signers from Smart Contract . Err: %v", err)
 	}
@@ -3,9 +3,11 @@ package posv
 import (
 	"fmt"
 	"math/big"
+	"sort"
 	"testing"
 
 	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/core/rawdb"
 	"github.com/69th-byte/sdexchain/core/types"
 	"github.com/69th-byte/sdexchain/params"
 )
@@ -82,3 +84,55 @@ func TestCompareSignersLists(t *testing.T) {
 		t.Error("Failed with list has only one signer")
 	}
 }
+
+func TestSetTrustedSnapshot(t *testing.T) {
+	masternodes := []common.Address{
+		common.StringToAddress("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"),
+		common.StringToAddress("bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"),
+	}
+	c := New(&params.PosvConfig{Epoch: 900, Gap: 450}, rawdb.NewMemoryDatabase())
+
+	// The ancestors of a trusted head are unknown, the snapshot must not need them
+	head := &types.Header{Number: big.NewInt(3464050), ParentHash: common.HexToHash("0xdead")}
+	if err := c.SetTrustedSnapshot(head.Number.Uint64(), head.Hash(), masternodes); err != nil {
+		t.Fatalf("failed to set trusted snapshot: %v", err)
+	}
+	snap, err := c.GetSnapshot(nil, head)
+	if err != nil {
+		t.Fatalf("failed to get snapshot: %v", err)
+	}
+	if !compareSignersLists(snap.GetSigners(), masternodes) {
+		t.Errorf("signers mismatch: have %v, want %v", snap.GetSigners(), masternodes)
+	}
+}
+
+// Tests that candidates tied at the last masternode slot are picked the way the
+// full node has always picked them, as checkpoints are validated against it.
+func TestSortMasternodesTieBoundary(t *testing.T) {
+	candidates := func() []Masternode {
+		var ms []Masternode
+		for i := 0; i < common.MaxMasternodes+10; i++ {
+			stake := big.NewInt(1)
+			if i%2 == 0 && i < 2*(common.MaxMasternodes-5) {
+				stake = big.NewInt(2)
+			}
+			ms = append(ms, Masternode{Address: common.BigToAddress(big.NewInt(int64(i + 1))), Stake: stake})
+		}
+		return ms
+	}
+	want := candidates()
+	sort.Slice(want, func(i, j int) bool {
+		return want[i].Stake.Cmp(want[j].Stake) >= 0
+	})
+	want = want[:common.MaxMasternodes]
+
+	have := SortMasternodes(candidates())
+	if len(have) != len(want) {
+		t.Fatalf("masternode count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i := range want {
+		if have[i].Address != want[i].Address {
+			t.Fatalf("masternode %d mismatch: have %x, want %x", i, have[i].Address, want[i].Address)
+		}
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/69th-byte/sdexchain/core"
 	"github.com/69th-byte/sdexchain/core/types"
 	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/params"
 )
 
 var (
@@ -91,7 +92,7 @@ func TestSendTxRandomizeSecretAndOpening(t *testing.T) {
 		nonce := uint64(i)
 		switch i {
 		case 800:
-			tx, err := contracts.BuildTxSecretRandomize(nonce, randomizeAddr, epocNumber, randomizeKeyValue)
+			tx, err := contracts.BuildTxSecretRandomize(params.TestnetChainConfig, new(big.Int).SetUint64(nonce), nonce, randomizeAddr, epocNumber, randomizeKeyValue)
 			if err != nil {
 				t.Fatalf("Can't create tx randomize secret: %v", err)
 			}
@@ -105,7 +106,7 @@ func TestSendTxRandomizeSecretAndOpening(t *testing.T) {
 			}
 		case 850:
 			// Set opening.
-			tx, err := contracts.BuildTxOpeningRandomize(nonce, randomizeAddr, randomizeKeyValue)
+			tx, err := contracts.BuildTxOpeningRandomize(params.TestnetChainConfig, new(big.Int).SetUint64(nonce), nonce, randomizeAddr, randomizeKeyValue)
 			if err != nil {
 				t.Fatalf("Can't create tx randomize opening: %v", err)
 			}
@@ -44,6 +44,7 @@ import (
 	"github.com/69th-byte/sdexchain/core/types"
 	"github.com/69th-byte/sdexchain/ethdb"
 	"github.com/69th-byte/sdexchain/log"
+	"github.com/69th-byte/sdexchain/metrics"
 	"github.com/69th-byte/sdexchain/params"
 )
 
@@ -57,6 +58,10 @@ type rewardLog struct {
 	Reward *big.Int `json:"reward"`
 }
 
+// signTxTimer measures the time from a block's timestamp until the masternode's
+// signing transaction for it is added to the pool.
+var signTxTimer = metrics.NewRegisteredTimer("posv/signtx/latency", nil)
+
 var TxSignMu sync.RWMutex
 
 // Send tx sign for block number to smart contract blockSigner.
@@ -85,7 +90,7 @@ func CreateTransactionSign(chainConfig *params.ChainConfig, pool *core.TxPool, m
 
 		// Create and send tx to smart contract for sign validate block.
 		nonce := pool.State().GetNonce(account.Address)
-		tx := CreateTxSign(block.Number(), block.Hash(), nonce, common.HexToAddress(common.BlockSigners))
+		tx := CreateTxSign(chainConfig, block.Number(), block.Hash(), nonce, common.HexToAddress(common.BlockSigners))
 		txSigned, err := wallet.SignTx(account, tx, chainConfig.ChainId)
 		if err != nil {
 			log.Error("Fail to create tx sign", "error", err)
@@ -97,9 +102,11 @@ func CreateTransactionSign(chainConfig *params.ChainConfig, pool *core.TxPool, m
 			log.Error("Fail to add tx sign to local pool.", "error", err, "number", block.NumberU64(), "hash", block.Hash().Hex(), "from", account.Address, "nonce", nonce)
 			return err
 		}
+		signTxTimer.UpdateSince(time.Unix(block.Time().Int64(), 0))
 
 		// Create secret tx.
 		blockNumber := block.Number().Uint64()
+		pendingNumber := new(big.Int).Add(block.Number(), common.Big1)
 		checkNumber := blockNumber % chainConfig.Posv.Epoch
 		// Generate random private key and save into chaindb.
 		randomizeKeyName := []byte("randomizeKey")
@@ -110,7 +117,7 @@ func CreateTransactionSign(chainConfig *params.ChainConfig, pool *core.TxPool, m
 			// Only process when private key empty in state db.
 			// Save randomize key into state db.
 			randomizeKeyValue := RandStringByte(32)
-			tx, err := BuildTxSecretRandomize(nonce+1, common.HexToAddress(common.RandomizeSMC), chainConfig.Posv.Epoch, randomizeKeyValue)
+			tx, err := BuildTxSecretRandomize(chainConfig, pendingNumber, nonce+1, common.HexToAddress(common.RandomizeSMC), chainConfig.Posv.Epoch, randomizeKeyValue)
 			if err != nil {
 				log.Error("Fail to get tx opening for randomize", "error", err)
 				return err
@@ -139,7 +146,7 @@ func CreateTransactionSign(chainConfig *params.ChainConfig, pool *core.TxPool, m
 				return err
 			}
 
-			tx, err := BuildTxOpeningRandomize(nonce+1, common.HexToAddress(common.RandomizeSMC), randomizeKeyValue)
+			tx, err := BuildTxOpeningRandomize(chainConfig, pendingNumber, nonce+1, common.HexToAddress(common.RandomizeSMC), randomizeKeyValue)
 			if err != nil {
 				log.Error("Fail to get tx opening for randomize", "error", err)
 				return err
@@ -164,18 +171,19 @@ func CreateTransactionSign(chainConfig *params.ChainConfig, pool *core.TxPool, m
 	return nil
 }
 
-// Create tx sign.
-func CreateTxSign(blockNumber *big.Int, blockHash common.Hash, nonce uint64, blockSigner common.Address) *types.Transaction {
+// Create tx sign. The tx is built for the block following the signed one,
+// which is the earliest block it can be included in.
+func CreateTxSign(config *params.ChainConfig, blockNumber *big.Int, blockHash common.Hash, nonce uint64, blockSigner common.Address) *types.Transaction {
 	data := common.Hex2Bytes(common.HexSignMethod)
 	inputData := append(data, common.LeftPadBytes(blockNumber.Bytes(), 32)...)
 	inputData = append(inputData, common.LeftPadBytes(blockHash.Bytes(), 32)...)
-	tx := types.NewTransaction(nonce, blockSigner, big.NewInt(0), 200000, big.NewInt(0), inputData)
+	tx := types.NewSystemCall(config, new(big.Int).Add(blockNumber, common.Big1), nonce, blockSigner, 200000, inputData)
 
 	return tx
 }
 
 // Send secret key into randomize smartcontract.
-func BuildTxSecretRandomize(nonce uint64, randomizeAddr common.Address, epocNumber uint64, randomizeKey []byte) (*types.Transaction, error) {
+func BuildTxSecretRandomize(config *params.ChainConfig, number *big.Int, nonce uint64, randomizeAddr common.Address, epocNumber uint64, randomizeKey []byte) (*types.Transaction, error) {
 	data := common.Hex2Bytes(common.HexSetSecret)
 	rand.Seed(time.Now().UnixNano())
 	secretNumb := rand.Intn(int(epocNumber))
@@ -193,16 +201,16 @@ func BuildTxSecretRandomize(nonce uint64, randomizeAddr common.Address, epocNumb
 		encryptSecret := Encrypt(randomizeKey, new(big.Int).SetInt64(secret).String())
 		inputData = append(inputData, common.LeftPadBytes([]byte(encryptSecret), int(sizeOfArray))...)
 	}
-	tx := types.NewTransaction(nonce, randomizeAddr, big.NewInt(0), 200000, big.NewInt(0), inputData)
+	tx := types.NewSystemCall(config, number, nonce, randomizeAddr, 200000, inputData)
 
 	return tx, nil
 }
 
 // Send opening to randomize SMC.
-func BuildTxOpeningRandomize(nonce uint64, randomizeAddr common.Address, randomizeKey []byte) (*types.Transaction, error) {
+func BuildTxOpeningRandomize(config *params.ChainConfig, number *big.Int, nonce uint64, randomizeAddr common.Address, randomizeKey []byte) (*types.Transaction, error) {
 	data := common.Hex2Bytes(common.HexSetOpening)
 	inputData := append(data, randomizeKey...)
-	tx := types.NewTransaction(nonce, randomizeAddr, big.NewInt(0), 200000, big.NewInt(0), inputData)
+	tx := types.NewSystemCall(config, number, nonce, randomizeAddr, 200000, inputData)
 
 	return tx, nil
 }
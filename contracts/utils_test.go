@@ -27,6 +27,7 @@ import (
 	"github.com/69th-byte/sdexchain/core"
 	"github.com/69th-byte/sdexchain/core/types"
 	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/params"
 	"math/big"
 	"math/rand"
 	"testing"
@@ -70,7 +71,7 @@ func TestSendTxSign(t *testing.T) {
 	oldBlocks := make(map[common.Hash]common.Address)
 
 	signTx := func(ctx context.Context, backend *backends.SimulatedBackend, signer types.HomesteadSigner, nonces map[*ecdsa.PrivateKey]int, accKey *ecdsa.PrivateKey, blockNumber *big.Int, blockHash common.Hash) *types.Transaction {
-		tx, _ := types.SignTx(CreateTxSign(blockNumber, blockHash, uint64(nonces[accKey]), blockSignerAddr), signer, accKey)
+		tx, _ := types.SignTx(CreateTxSign(params.TestnetChainConfig, blockNumber, blockHash, uint64(nonces[accKey]), blockSignerAddr), signer, accKey)
 		backend.SendTransaction(ctx, tx)
 		backend.Commit()
 		nonces[accKey]++
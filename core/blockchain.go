@@ -23,7 +23,6 @@ import (
 	"io"
 	"math/big"
 	"os"
-	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -39,6 +38,7 @@ import (
 	"github.com/69th-byte/sdexchain/consensus/posv"
 	contractValidator "github.com/69th-byte/sdexchain/contracts/validator/contract"
 	"github.com/69th-byte/sdexchain/core/state"
+	"github.com/69th-byte/sdexchain/core/state/snapshot"
 	"github.com/69th-byte/sdexchain/core/types"
 	"github.com/69th-byte/sdexchain/core/vm"
 	"github.com/69th-byte/sdexchain/crypto"
@@ -58,6 +58,10 @@ var (
 	blockInsertTimer = metrics.NewRegisteredTimer("chain/inserts", nil)
 	CheckpointCh     = make(chan int)
 	ErrNoGenesis     = errors.New("Genesis not found in chain")
+
+	// Time between a block's timestamp and the end of its MongoDB sync, in milliseconds
+	tradingSyncLagGauge = metrics.NewRegisteredGauge("tomox/mongodb/lag", nil)
+	lendingSyncLagGauge = metrics.NewRegisteredGauge("lending/mongodb/lag", nil)
 )
 
 const (
@@ -81,6 +85,8 @@ type CacheConfig struct {
 	Disabled      bool          // Whether to disable trie write caching (archive node)
 	TrieNodeLimit int           // Memory limit (MB) at which to flush the current in-memory trie to disk
 	TrieTimeLimit time.Duration // Time limit after which to flush the current in-memory trie to disk
+	SnapshotLimit int           // Memory allowance (MB) to use for caching snapshot entries in memory, zero disables the snapshot
+	SnapshotWait  bool          // Wait for snapshot construction on startup
 }
 type ResultProcessBlock struct {
 	logs         []*types.Log
@@ -133,6 +139,7 @@ type BlockChain struct {
 	currentFastBlock atomic.Value // Current head of the fast-sync chain (may be above the block chain!)
 
 	stateCache state.Database // State database to reuse between imports (contains state cache)
+	snaps      *snapshot.Tree // Snapshot tree for fast trie leaf access
 
 	bodyCache        *lru.Cache    // Cache for the most recent block bodies
 	bodyRLPCache     *lru.Cache    // Cache for the most recent block bodies in RLP encoded format
@@ -165,6 +172,9 @@ type BlockChain struct {
 	resultLendingTrade  *lru.Cache
 	rejectedLendingItem *lru.Cache
 	finalizedTrade      *lru.Cache // include both trades which force update to closed/liquidated by the protocol
+
+	tradingSyncLag int64 // Delay of the last trading data synced to the SDK node (ms, atomic)
+	lendingSyncLag int64 // Delay of the last lending data synced to the SDK node (ms, atomic)
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -247,6 +257,10 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 			}
 		}
 	}
+	// Load any existing snapshot, regenerating it if loading failed
+	if bc.cacheConfig.SnapshotLimit > 0 {
+		bc.snaps = snapshot.New(bc.db, bc.stateCache.TrieDB(), bc.cacheConfig.SnapshotLimit, bc.CurrentBlock().Root(), !bc.cacheConfig.SnapshotWait)
+	}
 	// Take ownership of this particular state
 	go bc.update()
 	return bc, nil
@@ -420,6 +434,11 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	}
 	currentBlock := bc.CurrentBlock()
 	currentFastBlock := bc.CurrentFastBlock()
+
+	// Rebuild the snapshot if the head was rewound below its disk layer
+	if bc.snaps != nil && bc.snaps.Snapshot(currentBlock.Root()) == nil {
+		bc.snaps.Rebuild(currentBlock.Root())
+	}
 	if err := WriteHeadBlockHash(bc.db, currentBlock.Hash()); err != nil {
 		log.Crit("Failed to reset head full block", "err", err)
 	}
@@ -445,6 +464,11 @@ func (bc *BlockChain) FastSyncCommitHead(hash common.Hash) error {
 	bc.currentBlock.Store(block)
 	bc.mu.Unlock()
 
+	// Destroy any existing state snapshot and regenerate it in the background
+	if bc.snaps != nil {
+		bc.snaps.Rebuild(block.Root())
+	}
+
 	log.Info("Committed new head block", "number", block.Number(), "hash", hash)
 	return nil
 }
@@ -501,7 +525,7 @@ func (bc *BlockChain) State() (*state.StateDB, error) {
 
 // StateAt returns a new mutable state based on a particular point in time.
 func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
-	return state.New(root, bc.stateCache)
+	return state.NewWithSnapshot(root, bc.stateCache, bc.snaps)
 }
 
 // OrderStateAt returns a new mutable state based on a particular point in time.
@@ -731,8 +755,7 @@ func (bc *BlockChain) HasBlock(hash common.Hash, number uint64) bool {
 	if bc.blockCache.Contains(hash) {
 		return true
 	}
-	ok, _ := bc.db.Has(blockBodyKey(hash, number))
-	return ok
+	return HasBody(bc.db, hash, number)
 }
 
 // HasFullState checks if state trie is fully present in the database or not.
@@ -867,6 +890,16 @@ func (bc *BlockChain) SaveData() {
 	// Make sure no inconsistent state is leaked during insertion
 	bc.mu.Lock()
 	defer bc.mu.Unlock()
+
+	// Persist the in-memory diff layers of the snapshot, the disk layer state is
+	// needed to resume a pending generation on the next startup
+	var snapBase common.Hash
+	if bc.snaps != nil {
+		var err error
+		if snapBase, err = bc.snaps.Journal(bc.CurrentBlock().Root()); err != nil {
+			log.Error("Failed to journal state snapshot", "err", err)
+		}
+	}
 	// Ensure the state of a recent block is also stored to disk before exiting.
 	// We're writing three different states to catch different restart scenarios:
 	//  - HEAD:     So we don't need to reprocess any blocks in the general case
@@ -918,6 +951,12 @@ func (bc *BlockChain) SaveData() {
 				}
 			}
 		}
+		if snapBase != (common.Hash{}) {
+			log.Info("Writing snapshot state to disk", "root", snapBase)
+			if err := triedb.Commit(snapBase, true); err != nil {
+				log.Error("Failed to commit snapshot state trie", "err", err)
+			}
+		}
 		for !bc.triegc.Empty() {
 			triedb.Dereference(bc.triegc.PopItem().(common.Hash))
 		}
@@ -1521,7 +1560,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		} else {
 			parent = chain[i-1]
 		}
-		statedb, err := state.New(parent.Root(), bc.stateCache)
+		statedb, err := state.NewWithSnapshot(parent.Root(), bc.stateCache, bc.snaps)
 		if err != nil {
 			return i, events, coalescedLogs, err
 		}
@@ -1813,7 +1852,7 @@ func (bc *BlockChain) getResultBlock(block *types.Block, verifiedM2 bool) (*Resu
 	// Create a new statedb using the parent block and report an
 	// error if it fails.
 	var parent = bc.GetBlock(block.ParentHash(), block.NumberU64()-1)
-	statedb, err := state.New(parent.Root(), bc.stateCache)
+	statedb, err := state.NewWithSnapshot(parent.Root(), bc.stateCache, bc.snaps)
 	if err != nil {
 		return nil, err
 	}
@@ -2489,21 +2528,14 @@ func (bc *BlockChain) UpdateM1() error {
 		log.Error("No masternode found. Stopping node")
 		os.Exit(1)
 	} else {
-		sort.Slice(ms, func(i, j int) bool {
-			return ms[i].Stake.Cmp(ms[j].Stake) >= 0
-		})
+		ms = posv.SortMasternodes(ms)
 		log.Info("Ordered list of masternode candidates")
 		for _, m := range ms {
 			log.Info("", "address", m.Address.String(), "stake", m.Stake)
 		}
 		// update masternodes
 		log.Info("Updating new set of masternodes")
-		if len(ms) > common.MaxMasternodes {
-			err = engine.UpdateMasternodes(bc, bc.CurrentHeader(), ms[:common.MaxMasternodes])
-		} else {
-			err = engine.UpdateMasternodes(bc, bc.CurrentHeader(), ms)
-		}
-		if err != nil {
+		if err := engine.UpdateMasternodes(bc, bc.CurrentHeader(), ms); err != nil {
 			return err
 		}
 		log.Info("Masternodes are ready for the next epoch")
@@ -2573,6 +2605,17 @@ func (bc *BlockChain) logExchangeData(block *types.Block) {
 			}
 		}
 	}
+	lag := int64(time.Since(time.Unix(block.Time().Int64(), 0)) / time.Millisecond)
+	atomic.StoreInt64(&bc.tradingSyncLag, lag)
+	tradingSyncLagGauge.Update(lag)
+}
+
+// SDKSyncLag returns how long after their block time the last trading and
+// lending data were synced to the SDK node's database.
+func (bc *BlockChain) SDKSyncLag() (trading, lending time.Duration) {
+	trading = time.Duration(atomic.LoadInt64(&bc.tradingSyncLag)) * time.Millisecond
+	lending = time.Duration(atomic.LoadInt64(&bc.lendingSyncLag)) * time.Millisecond
+	return trading, lending
 }
 
 func (bc *BlockChain) reorgTxMatches(deletedTxs types.Transactions, newChain types.Blocks) {
@@ -2666,6 +2709,9 @@ func (bc *BlockChain) logLendingData(block *types.Block) {
 			}
 		}
 	}
+	lag := int64(time.Since(time.Unix(block.Time().Int64(), 0)) / time.Millisecond)
+	atomic.StoreInt64(&bc.lendingSyncLag, lag)
+	lendingSyncLagGauge.Update(lag)
 
 	// update finalizedTrades
 	if block.Number().Uint64()%bc.chainConfig.Posv.Epoch == common.LiquidateLendingTradeBlock {
@@ -1223,6 +1223,72 @@ func TestBlockchainHeaderchainReorgConsistency(t *testing.T) {
 	}
 }
 
+// Tests that after the EIP-1559 fork the base fee part of the transaction fees
+// is burned or paid to the foundation wallet, and only the tip is left for the
+// block producer.
+func TestEIP1559FeeDestination(t *testing.T) {
+	for _, burn := range []bool{false, true} {
+		testEIP1559FeeDestination(t, burn)
+	}
+}
+
+func testEIP1559FeeDestination(t *testing.T, burn bool) {
+	var (
+		db         = rawdb.NewMemoryDatabase()
+		key, _     = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address    = crypto.PubkeyToAddress(key.PublicKey)
+		foundation = common.HexToAddress("0x0000000000000000000000000000000000000068")
+		funds      = new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(10))
+		config     = &params.ChainConfig{
+			ChainId:        big.NewInt(1),
+			HomesteadBlock: new(big.Int),
+			EIP155Block:    new(big.Int),
+			EIP2718Block:   new(big.Int),
+			EIP1559Block:   new(big.Int),
+			Posv:           &params.PosvConfig{FoudationWalletAddr: foundation, BurnBaseFee: burn},
+		}
+		gspec   = &Genesis{Config: config, Alloc: GenesisAlloc{address: {Balance: funds}}}
+		genesis = gspec.MustCommit(db)
+		tip     = big.NewInt(params.Shannon / 10)
+	)
+	if genesis.BaseFee() == nil || genesis.BaseFee().Uint64() != params.InitialBaseFee {
+		t.Fatalf("genesis base fee mismatch: have %v, want %d", genesis.BaseFee(), params.InitialBaseFee)
+	}
+	signer := types.LatestSigner(config)
+	blocks, _ := GenerateChain(config, genesis, ethash.NewFaker(), db, 1, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTx(&types.DynamicFeeTx{
+			ChainID:   config.ChainId,
+			Nonce:     block.TxNonce(address),
+			GasTipCap: tip,
+			GasFeeCap: new(big.Int).Mul(genesis.BaseFee(), big.NewInt(2)),
+			Gas:       21000,
+			To:        &common.Address{0x01},
+			Value:     big.NewInt(0),
+		}), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.AddTx(tx)
+	})
+	statedb, err := state.New(blocks[0].Root(), state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("burn %v: failed to open state: %v", burn, err)
+	}
+
+	baseFee := blocks[0].BaseFee()
+	spent := new(big.Int).Mul(new(big.Int).Add(baseFee, tip), big.NewInt(21000))
+	if have, want := statedb.GetBalance(address), new(big.Int).Sub(funds, spent); have.Cmp(want) != 0 {
+		t.Errorf("burn %v: sender balance mismatch: have %v, want %v", burn, have, want)
+	}
+	want := new(big.Int).Mul(baseFee, big.NewInt(21000))
+	if burn {
+		want = new(big.Int)
+	}
+	if have := statedb.GetBalance(foundation); have.Cmp(want) != 0 {
+		t.Errorf("burn %v: foundation balance mismatch: have %v, want %v", burn, have, want)
+	}
+}
+
 // Tests that importing small side forks doesn't leave junk in the trie database
 // cache (which would eventually cause memory issues).
 func TestTrieForkGC(t *testing.T) {
@@ -1269,6 +1335,69 @@ func TestTrieForkGC(t *testing.T) {
 	}
 }
 
+// Tests that the state snapshot follows the imported chain across forks, stays
+// consistent with the state tries and is reloaded from its journal on restart.
+func TestSnapshotChain(t *testing.T) {
+	var (
+		engine  = ethash.NewFaker()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{address: {Balance: big.NewInt(1000000000)}},
+		}
+		db      = rawdb.NewMemoryDatabase()
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, engine, db, 2*triesInMemory, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{1})
+		tx, err := types.SignTx(types.NewTransaction(b.TxNonce(address), common.Address{byte(i)}, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		b.AddTx(tx)
+	})
+	forks, _ := GenerateChain(gspec.Config, blocks[len(blocks)-8], engine, db, 4, func(i int, b *BlockGen) { b.SetCoinbase(common.Address{2}) })
+
+	diskdb := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(diskdb)
+
+	cacheConfig := &CacheConfig{TrieNodeLimit: 256, TrieTimeLimit: 5 * time.Minute, SnapshotLimit: 16, SnapshotWait: true}
+	chain, err := NewBlockChain(diskdb, cacheConfig, gspec.Config, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if _, err := chain.InsertChain(forks); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	head := chain.CurrentBlock().Root()
+	if err := chain.snaps.Verify(head); err != nil {
+		t.Fatalf("snapshot verification failed: %v", err)
+	}
+	if err := chain.snaps.Verify(forks[len(forks)-1].Root()); err != nil {
+		t.Fatalf("fork snapshot verification failed: %v", err)
+	}
+	chain.Stop()
+
+	// Restart the chain, the snapshot must be loaded from the journal
+	chain, err = NewBlockChain(diskdb, cacheConfig, gspec.Config, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to recreate tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if chain.snaps.Snapshot(blocks[len(blocks)-2].Root()) == nil {
+		t.Fatalf("diff layers not loaded from the journal")
+	}
+	if err := chain.snaps.Verify(chain.CurrentBlock().Root()); err != nil {
+		t.Fatalf("snapshot verification failed after restart: %v", err)
+	}
+}
+
 // Tests that doing large reorgs works even if the state associated with the
 // forking point is not available any more.
 func TestLargeReorgTrieGC(t *testing.T) {
@@ -94,6 +94,9 @@ func encodeBlockNumber(number uint64) []byte {
 // GetCanonicalHash retrieves a hash assigned to a canonical block number.
 func GetCanonicalHash(db DatabaseReader, number uint64) common.Hash {
 	data, _ := db.Get(append(append(headerPrefix, encodeBlockNumber(number)...), numSuffix...))
+	if len(data) == 0 {
+		data = readAncient(db, rawdb.FreezerHashTable, common.Hash{}, number)
+	}
 	if len(data) == 0 {
 		return common.Hash{}
 	}
@@ -162,9 +165,20 @@ func GetTrieSyncProgress(db DatabaseReader) uint64 {
 // if the header's not found.
 func GetHeaderRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(headerKey(hash, number))
+	if len(data) == 0 {
+		data = readAncient(db, rawdb.FreezerHeaderTable, hash, number)
+	}
 	return data
 }
 
+// HasHeader verifies the existence of a block header corresponding to the hash.
+func HasHeader(db ethdb.Reader, hash common.Hash, number uint64) bool {
+	if has, err := db.Has(headerKey(hash, number)); err == nil && has {
+		return true
+	}
+	return len(readAncient(db, rawdb.FreezerHashTable, hash, number)) > 0
+}
+
 // GetHeader retrieves the block header corresponding to the hash, nil if none
 // found.
 func GetHeader(db DatabaseReader, hash common.Hash, number uint64) *types.Header {
@@ -183,6 +197,42 @@ func GetHeader(db DatabaseReader, hash common.Hash, number uint64) *types.Header
 // GetBodyRLP retrieves the block body (transactions and uncles) in RLP encoding.
 func GetBodyRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(blockBodyKey(hash, number))
+	if len(data) == 0 {
+		data = readAncient(db, rawdb.FreezerBodiesTable, hash, number)
+	}
+	return data
+}
+
+// HasBody verifies the existence of a block body corresponding to the hash.
+func HasBody(db ethdb.Reader, hash common.Hash, number uint64) bool {
+	if has, err := db.Has(blockBodyKey(hash, number)); err == nil && has {
+		return true
+	}
+	return len(readAncient(db, rawdb.FreezerHashTable, hash, number)) > 0
+}
+
+// readAncient retrieves an item of the given kind of a canonical block from the
+// chain freezer if the database has one, nil otherwise. Items other than the
+// canonical hash itself are only returned if the block with the given hash is
+// the frozen one.
+func readAncient(db DatabaseReader, kind string, hash common.Hash, number uint64) []byte {
+	ancients, ok := db.(ethdb.AncientReader)
+	if !ok {
+		return nil
+	}
+	if kind != rawdb.FreezerHashTable || hash != (common.Hash{}) {
+		frozen, err := ancients.Ancient(rawdb.FreezerHashTable, number)
+		if err != nil || common.BytesToHash(frozen) != hash {
+			return nil
+		}
+		if kind == rawdb.FreezerHashTable {
+			return frozen
+		}
+	}
+	data, err := ancients.Ancient(kind, number)
+	if err != nil {
+		return nil
+	}
 	return data
 }
 
@@ -213,6 +263,9 @@ func GetBody(db DatabaseReader, hash common.Hash, number uint64) *types.Body {
 // none found.
 func GetTd(db DatabaseReader, hash common.Hash, number uint64) *big.Int {
 	data, _ := db.Get(append(append(append(headerPrefix, encodeBlockNumber(number)...), hash[:]...), tdSuffix...))
+	if len(data) == 0 {
+		data = readAncient(db, rawdb.FreezerDifficultyTable, hash, number)
+	}
 	if len(data) == 0 {
 		return nil
 	}
@@ -248,6 +301,9 @@ func GetBlock(db DatabaseReader, hash common.Hash, number uint64) *types.Block {
 // in a block given by its hash.
 func GetBlockReceipts(db DatabaseReader, hash common.Hash, number uint64) types.Receipts {
 	data, _ := db.Get(append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash[:]...))
+	if len(data) == 0 {
+		data = readAncient(db, rawdb.FreezerReceiptTable, hash, number)
+	}
 	if len(data) == 0 {
 		return nil
 	}
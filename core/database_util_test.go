@@ -19,12 +19,16 @@ package core
 import (
 	"bytes"
 	"github.com/69th-byte/sdexchain/core/rawdb"
+	"io/ioutil"
 	"math/big"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/69th-byte/sdexchain/common"
 	"github.com/69th-byte/sdexchain/core/types"
 	"github.com/69th-byte/sdexchain/crypto/sha3"
+	"github.com/69th-byte/sdexchain/ethdb/memorydb"
 	"github.com/69th-byte/sdexchain/rlp"
 )
 
@@ -386,3 +390,86 @@ func TestBlockReceiptStorage(t *testing.T) {
 		t.Fatalf("deleted receipts returned: %v", rs)
 	}
 }
+
+// Tests that blocks moved into the chain freezer are still served by the
+// accessors, while they are wiped from the key-value store.
+func TestAncientStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	kvdb := memorydb.New()
+	var (
+		blocks   []*types.Block
+		parent   common.Hash
+		receipts = types.Receipts{&types.Receipt{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 1, Logs: []*types.Log{}}}
+	)
+	for i := 0; i < 10; i++ {
+		block := types.NewBlockWithHeader(&types.Header{
+			ParentHash: parent,
+			Number:     big.NewInt(int64(i)),
+			Extra:      []byte("test block"),
+		})
+		if err := WriteBlock(kvdb, block); err != nil {
+			t.Fatalf("failed to write block #%d: %v", i, err)
+		}
+		if err := WriteCanonicalHash(kvdb, block.Hash(), block.NumberU64()); err != nil {
+			t.Fatalf("failed to write canonical hash #%d: %v", i, err)
+		}
+		if err := WriteTd(kvdb, block.Hash(), block.NumberU64(), big.NewInt(int64(i+1))); err != nil {
+			t.Fatalf("failed to write td #%d: %v", i, err)
+		}
+		if err := WriteBlockReceipts(kvdb, block.Hash(), block.NumberU64(), receipts); err != nil {
+			t.Fatalf("failed to write receipts #%d: %v", i, err)
+		}
+		blocks = append(blocks, block)
+		parent = block.Hash()
+	}
+	if err := WriteHeadBlockHash(kvdb, parent); err != nil {
+		t.Fatalf("failed to write head block hash: %v", err)
+	}
+	// Freeze the blocks more than 4 blocks behind the head, i.e. blocks 0..4
+	db, err := rawdb.NewDatabaseWithFreezer(kvdb, dir, "", 4)
+	if err != nil {
+		t.Fatalf("failed to create freezer database: %v", err)
+	}
+	defer db.Close()
+
+	for i := 0; ; i++ {
+		if frozen, _ := db.Ancients(); frozen == 5 {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("blocks not frozen in time")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	for _, block := range blocks {
+		hash, number := block.Hash(), block.NumberU64()
+		if have := GetCanonicalHash(db, number); have != hash {
+			t.Errorf("block #%d: canonical hash mismatch: have %x, want %x", number, have, hash)
+		}
+		if have := GetBlock(db, hash, number); have == nil || have.Hash() != hash {
+			t.Errorf("block #%d: block not retrieved", number)
+		}
+		if !HasHeader(db, hash, number) || !HasBody(db, hash, number) {
+			t.Errorf("block #%d: header or body reported missing", number)
+		}
+		if have := GetTd(db, hash, number); have == nil || have.Int64() != int64(number+1) {
+			t.Errorf("block #%d: td mismatch: have %v, want %d", number, have, number+1)
+		}
+		if have := GetBlockReceipts(db, hash, number); len(have) != 1 {
+			t.Errorf("block #%d: receipts not retrieved", number)
+		}
+		frozen := number > 0 && number < 5
+		if has, _ := kvdb.Has(headerKey(hash, number)); has == frozen {
+			t.Errorf("block #%d: header in key-value store: have %v, want %v", number, has, !frozen)
+		}
+	}
+	// Items of other blocks at a frozen height must not be served
+	if have := GetHeaderRLP(db, common.Hash{0xff}, 3); len(have) != 0 {
+		t.Errorf("non-canonical header returned from the ancient store")
+	}
+}
@@ -34,6 +34,10 @@ func NewEVMContext(msg Message, header *types.Header, chain consensus.ChainConte
 	} else {
 		beneficiary = *author
 	}
+	var baseFee *big.Int
+	if header.BaseFee != nil {
+		baseFee = new(big.Int).Set(header.BaseFee)
+	}
 	return vm.Context{
 		CanTransfer: CanTransfer,
 		Transfer:    Transfer,
@@ -45,6 +49,7 @@ func NewEVMContext(msg Message, header *types.Header, chain consensus.ChainConte
 		Difficulty:  new(big.Int).Set(header.Difficulty),
 		GasLimit:    header.GasLimit,
 		GasPrice:    new(big.Int).Set(msg.GasPrice()),
+		BaseFee:     baseFee,
 	}
 }
 
@@ -34,6 +34,7 @@ import (
 	"github.com/69th-byte/sdexchain/core/types"
 	"github.com/69th-byte/sdexchain/event"
 	"github.com/69th-byte/sdexchain/log"
+	"github.com/69th-byte/sdexchain/metrics"
 	"github.com/69th-byte/sdexchain/params"
 	"gopkg.in/karalabe/cookiejar.v2/collections/prque"
 )
@@ -57,6 +58,25 @@ var (
 	LendingTypeMarket = "MO"
 )
 
+var (
+	// Metrics for the pending pool
+	lendingPendingDiscardCounter   = metrics.NewRegisteredCounter("lendingpool/pending/discard", nil)
+	lendingPendingReplaceCounter   = metrics.NewRegisteredCounter("lendingpool/pending/replace", nil)
+	lendingPendingRateLimitCounter = metrics.NewRegisteredCounter("lendingpool/pending/ratelimit", nil) // Dropped due to rate limiting
+
+	// Metrics for the queued pool
+	lendingQueuedDiscardCounter   = metrics.NewRegisteredCounter("lendingpool/queued/discard", nil)
+	lendingQueuedReplaceCounter   = metrics.NewRegisteredCounter("lendingpool/queued/replace", nil)
+	lendingQueuedRateLimitCounter = metrics.NewRegisteredCounter("lendingpool/queued/ratelimit", nil) // Dropped due to rate limiting
+	lendingQueuedEvictionCounter  = metrics.NewRegisteredCounter("lendingpool/queued/eviction", nil)  // Dropped due to lifetime
+
+	// General tx metrics
+	lendingInvalidTxCounter  = metrics.NewRegisteredCounter("lendingpool/invalid", nil)
+	lendingOverflowTxCounter = metrics.NewRegisteredCounter("lendingpool/overflow", nil) // Dropped due to a full pool
+	lendingPendingGauge      = metrics.NewRegisteredGauge("lendingpool/pending", nil)
+	lendingQueuedGauge       = metrics.NewRegisteredGauge("lendingpool/queued", nil)
+)
+
 // LendingPoolConfig are the configuration parameters of the order transaction pool.
 type LendingPoolConfig struct {
 	NoLocals  bool          // Whether local transaction handling should be disabled
@@ -158,7 +178,7 @@ func NewLendingPool(chainconfig *params.ChainConfig, chain blockChainLending) *L
 		config:      config,
 		chainconfig: chainconfig,
 		chain:       chain,
-		signer:      types.LendingTxSigner{},
+		signer:      types.NewLendingTypedSigner(chainconfig.ChainId),
 		pending:     make(map[common.Address]*lendingtxList),
 		queue:       make(map[common.Address]*lendingtxList),
 		beats:       make(map[common.Address]time.Time),
@@ -235,6 +255,8 @@ func (pool *LendingPool) loop() {
 			pool.mu.RLock()
 			pending, queued := pool.stats()
 			pool.mu.RUnlock()
+			lendingPendingGauge.Update(int64(pending))
+			lendingQueuedGauge.Update(int64(queued))
 			if pending != prevPending || queued != prevQueued {
 				log.Debug("Lending pool status report", "executable", pending, "queued", queued)
 				prevPending, prevQueued = pending, queued
@@ -250,9 +272,11 @@ func (pool *LendingPool) loop() {
 				}
 				// Any non-locals old enough should be removed
 				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
-					for _, tx := range pool.queue[addr].Flatten() {
+					list := pool.queue[addr].Flatten()
+					for _, tx := range list {
 						pool.removeTx(tx.Hash())
 					}
+					lendingQueuedEvictionCounter.Inc(int64(len(list)))
 				}
 			}
 			pool.mu.Unlock()
@@ -623,10 +647,6 @@ func (pool *LendingPool) validateLending(tx *types.LendingTransaction) error {
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *LendingPool) validateTx(tx *types.LendingTransaction, local bool) error {
 
-	// check if sender is in black list
-	if tx.From() != nil && common.Blacklist[*tx.From()] {
-		return fmt.Errorf("Reject transaction with sender in black-list: %v", tx.From().Hex())
-	}
 	// Heuristic limit, reject transactions over 32KB to prevent DOS attacks
 	if tx.Size() > 32*1024 {
 		return ErrOversizedData
@@ -637,6 +657,16 @@ func (pool *LendingPool) validateTx(tx *types.LendingTransaction, local bool) er
 	if err != nil {
 		return ErrInvalidSender
 	}
+	// Signatures of the EIP-712 typed data are only accepted after the fork
+	if !pool.chainconfig.IsTIPTomoXTypedData(pool.chain.CurrentBlock().Number()) {
+		if legacy, err := (types.LendingTxSigner{}).Sender(tx); err != nil || legacy != from {
+			return ErrInvalidSender
+		}
+	}
+	// check if sender is in black list, EIP-712 signers are only known once verified
+	if common.Blacklist[from] {
+		return fmt.Errorf("Reject transaction with sender in black-list: %v", from.Hex())
+	}
 	err = pool.validateLending(tx)
 	if err != nil {
 		return err
@@ -671,7 +701,7 @@ func (pool *LendingPool) add(tx *types.LendingTransaction, local bool) (bool, er
 	// If the transaction fails basic validation, discard it
 	if err := pool.validateTx(tx, local); err != nil {
 		log.Debug("Discarding invalid lending transaction", "hash", hash, "userAddress", tx.UserAddress, "status", tx.Status, "err", err)
-		invalidTxCounter.Inc(1)
+		lendingInvalidTxCounter.Inc(1)
 		return false, err
 	}
 	from, _ := types.LendingSender(pool.signer, tx) // already validated
@@ -679,18 +709,19 @@ func (pool *LendingPool) add(tx *types.LendingTransaction, local bool) (bool, er
 	// If the transaction pool is full, discard underpriced transactions
 	if uint64(len(pool.all)) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
 		log.Debug("Add lending transaction to pool full", "hash", hash, "nonce", tx.Nonce())
+		lendingOverflowTxCounter.Inc(1)
 		return false, ErrPoolOverflow
 	}
 	// If the transaction is replacing an already pending one, do directly
 	if list := pool.pending[from]; list != nil && list.Overlaps(tx) {
 		inserted, old := list.Add(tx)
 		if !inserted {
-			pendingDiscardCounter.Inc(1)
+			lendingPendingDiscardCounter.Inc(1)
 			return false, ErrPendingNonceTooLow
 		}
 		if old != nil {
 			delete(pool.all, old.Hash())
-			pendingReplaceCounter.Inc(1)
+			lendingPendingReplaceCounter.Inc(1)
 		}
 		pool.all[tx.Hash()] = tx
 		pool.journalTx(from, tx)
@@ -726,13 +757,13 @@ func (pool *LendingPool) enqueueTx(hash common.Hash, tx *types.LendingTransactio
 	inserted, old := pool.queue[from].Add(tx)
 	if !inserted {
 		// An older transaction was better, discard this
-		queuedDiscardCounter.Inc(1)
+		lendingQueuedDiscardCounter.Inc(1)
 		return false, ErrPendingNonceTooLow
 	}
 	// Discard any previous transaction and mark this
 	if old != nil {
 		delete(pool.all, old.Hash())
-		queuedReplaceCounter.Inc(1)
+		lendingQueuedReplaceCounter.Inc(1)
 	}
 	pool.all[hash] = tx
 	return old != nil, nil
@@ -764,13 +795,13 @@ func (pool *LendingPool) promoteTx(addr common.Address, hash common.Hash, tx *ty
 	if !inserted {
 		// An older transaction was better, discard this
 		delete(pool.all, hash)
-		pendingDiscardCounter.Inc(1)
+		lendingPendingDiscardCounter.Inc(1)
 		return
 	}
 	// Otherwise discard any previous transaction and mark this
 	if old != nil {
 		delete(pool.all, old.Hash())
-		pendingReplaceCounter.Inc(1)
+		lendingPendingReplaceCounter.Inc(1)
 	}
 	// Failsafe to work around direct pending inserts (tests)
 	if pool.all[hash] == nil {
@@ -980,7 +1011,7 @@ func (pool *LendingPool) promoteExecutables(accounts []common.Address) {
 				hash := tx.Hash()
 				delete(pool.all, hash)
 
-				queuedRateLimitCounter.Inc(1)
+				lendingQueuedRateLimitCounter.Inc(1)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
 		}
@@ -1056,7 +1087,7 @@ func (pool *LendingPool) promoteExecutables(accounts []common.Address) {
 				}
 			}
 		}
-		pendingRateLimitCounter.Inc(int64(pendingBeforeCap - pending))
+		lendingPendingRateLimitCounter.Inc(int64(pendingBeforeCap - pending))
 	}
 	// If we've queued more transactions than the hard limit, drop oldest ones
 	queued := uint64(0)
@@ -1086,7 +1117,7 @@ func (pool *LendingPool) promoteExecutables(accounts []common.Address) {
 					pool.removeTx(tx.Hash())
 				}
 				drop -= size
-				queuedRateLimitCounter.Inc(int64(size))
+				lendingQueuedRateLimitCounter.Inc(int64(size))
 				continue
 			}
 			// Otherwise drop only last few transactions
@@ -1094,7 +1125,7 @@ func (pool *LendingPool) promoteExecutables(accounts []common.Address) {
 			for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
 				pool.removeTx(txs[i].Hash())
 				drop--
-				queuedRateLimitCounter.Inc(1)
+				lendingQueuedRateLimitCounter.Inc(1)
 			}
 		}
 	}
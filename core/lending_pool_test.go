@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/core/rawdb"
+	"github.com/69th-byte/sdexchain/core/state"
 	"github.com/69th-byte/sdexchain/core/types"
 	"github.com/69th-byte/sdexchain/crypto"
 	"github.com/69th-byte/sdexchain/crypto/sha3"
 	"github.com/69th-byte/sdexchain/ethclient"
+	"github.com/69th-byte/sdexchain/params"
 	"github.com/69th-byte/sdexchain/rpc"
 	"github.com/69th-byte/sdexchain/tomoxlending/lendingstate"
 	"log"
@@ -285,3 +288,72 @@ func TestRecallLending(t *testing.T) {
 	testSendLending(key, nonce, USDAddress, common.HexToAddress(common.TomoNativeAddress), new(big.Int).Mul(_1E8, big.NewInt(1000)), interestRate, lendingstate.Borrowing, lendingstate.LendingStatusNew, true, 0, 0, common.Hash{}, "")
 	time.Sleep(2 * time.Second)
 }
+
+// testLendingChain is a chain whose head is all the lending pool validation needs.
+type testLendingChain struct {
+	blockChainLending
+	head *types.Block
+}
+
+func (bc *testLendingChain) CurrentBlock() *types.Block { return bc.head }
+
+// newTestLendingPool creates a lending pool on top of a head at the given number.
+func newTestLendingPool(t *testing.T, number int64) *LendingPool {
+	db := rawdb.NewMemoryDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	lendingState, err := lendingstate.New(common.Hash{}, lendingstate.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to create lending state: %v", err)
+	}
+	config := *params.TestChainConfig
+	return &LendingPool{
+		chainconfig:         &config,
+		chain:               &testLendingChain{head: types.NewBlockWithHeader(&types.Header{Number: big.NewInt(number)})},
+		signer:              types.NewLendingTypedSigner(config.ChainId),
+		currentRootState:    statedb,
+		currentLendingState: lendingState,
+		pendingState:        lendingstate.ManageState(lendingState),
+	}
+}
+
+// Tests that lendings signed over their EIP-712 typed data are only accepted
+// after the fork, and that their signers are checked against the black-list.
+func TestLendingPoolTypedSigning(t *testing.T) {
+	defer func(fork *big.Int) { common.TIPTomoXTypedData = fork }(common.TIPTomoXTypedData)
+	common.TIPTomoXTypedData = big.NewInt(10)
+
+	key, _ := crypto.GenerateKey()
+	user := crypto.PubkeyToAddress(key.PublicKey)
+	sign := func(signer types.LendingSigner) *types.LendingTransaction {
+		tx := types.NewLendingTransaction(0, _1E18, 10, 86400, common.HexToAddress("0x0D3ab14BBaD3D99F4203bd7a11aCB94882050E7e"), user, USDAddress, common.Address{}, false, lendingstate.LendingStatusNew, lendingstate.Investing, LendingTypeLimit, common.Hash{}, 0, 0, "")
+		signed, err := types.LendingSignTx(tx, signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign lending: %v", err)
+		}
+		return signed
+	}
+	pre, post := newTestLendingPool(t, 9), newTestLendingPool(t, 10)
+	typed := types.NewLendingTypedSigner(params.TestChainConfig.ChainId)
+
+	// The relayer isn't registered, so the lendings passing the signature checks
+	// are rejected by the lending validation
+	if err := pre.validateTx(sign(typed), false); err != ErrInvalidSender {
+		t.Errorf("typed lending error mismatch before the fork: have %v, want %v", err, ErrInvalidSender)
+	}
+	if err := pre.validateTx(sign(types.LendingTxSigner{}), false); err == nil || !strings.Contains(err.Error(), "invalid lending relayer") {
+		t.Errorf("legacy lending error mismatch before the fork: have %v", err)
+	}
+	tx := sign(typed)
+	if err := post.validateTx(tx, false); err == nil || !strings.Contains(err.Error(), "invalid lending relayer") {
+		t.Errorf("typed lending error mismatch after the fork: have %v", err)
+	}
+	if from := tx.From(); from == nil || *from != user {
+		t.Errorf("verified sender mismatch: have %x, want %x", from, user)
+	}
+	common.Blacklist[user] = true
+	defer delete(common.Blacklist, user)
+
+	if err := post.validateTx(sign(typed), false); err == nil || !strings.Contains(err.Error(), "black-list") {
+		t.Errorf("black-listed typed lending error mismatch: have %v", err)
+	}
+}
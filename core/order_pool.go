@@ -33,6 +33,7 @@ import (
 	"github.com/69th-byte/sdexchain/core/types"
 	"github.com/69th-byte/sdexchain/event"
 	"github.com/69th-byte/sdexchain/log"
+	"github.com/69th-byte/sdexchain/metrics"
 	"github.com/69th-byte/sdexchain/params"
 	"gopkg.in/karalabe/cookiejar.v2/collections/prque"
 )
@@ -52,12 +53,13 @@ var (
 )
 
 var (
-	OrderTypeLimit    = "LO"
-	OrderTypeMarket   = "MO"
-	OrderStatusNew    = "NEW"
-	OrderStatusCancle = "CANCELLED"
-	OrderSideBid      = "BUY"
-	OrderSideAsk      = "SELL"
+	OrderTypeLimit        = "LO"
+	OrderTypeMarket       = "MO"
+	OrderTypeRoutedMarket = "RMO"
+	OrderStatusNew        = "NEW"
+	OrderStatusCancle     = "CANCELLED"
+	OrderSideBid          = "BUY"
+	OrderSideAsk          = "SELL"
 )
 
 var (
@@ -65,6 +67,25 @@ var (
 	ErrPoolOverflow       = errors.New("Exceed pool size")
 )
 
+var (
+	// Metrics for the pending pool
+	orderPendingDiscardCounter   = metrics.NewRegisteredCounter("orderpool/pending/discard", nil)
+	orderPendingReplaceCounter   = metrics.NewRegisteredCounter("orderpool/pending/replace", nil)
+	orderPendingRateLimitCounter = metrics.NewRegisteredCounter("orderpool/pending/ratelimit", nil) // Dropped due to rate limiting
+
+	// Metrics for the queued pool
+	orderQueuedDiscardCounter   = metrics.NewRegisteredCounter("orderpool/queued/discard", nil)
+	orderQueuedReplaceCounter   = metrics.NewRegisteredCounter("orderpool/queued/replace", nil)
+	orderQueuedRateLimitCounter = metrics.NewRegisteredCounter("orderpool/queued/ratelimit", nil) // Dropped due to rate limiting
+	orderQueuedEvictionCounter  = metrics.NewRegisteredCounter("orderpool/queued/eviction", nil)  // Dropped due to lifetime
+
+	// General tx metrics
+	orderInvalidTxCounter  = metrics.NewRegisteredCounter("orderpool/invalid", nil)
+	orderOverflowTxCounter = metrics.NewRegisteredCounter("orderpool/overflow", nil) // Dropped due to a full pool
+	orderPendingGauge      = metrics.NewRegisteredGauge("orderpool/pending", nil)
+	orderQueuedGauge       = metrics.NewRegisteredGauge("orderpool/queued", nil)
+)
+
 // OrderPoolConfig are the configuration parameters of the order transaction pool.
 type OrderPoolConfig struct {
 	NoLocals  bool          // Whether local transaction handling should be disabled
@@ -166,7 +187,7 @@ func NewOrderPool(chainconfig *params.ChainConfig, chain blockChainTomox) *Order
 		config:      config,
 		chainconfig: chainconfig,
 		chain:       chain,
-		signer:      types.OrderTxSigner{},
+		signer:      types.NewOrderTypedSigner(chainconfig.ChainId),
 		pending:     make(map[common.Address]*ordertxList),
 		queue:       make(map[common.Address]*ordertxList),
 		beats:       make(map[common.Address]time.Time),
@@ -242,6 +263,8 @@ func (pool *OrderPool) loop() {
 			pool.mu.RLock()
 			pending, queued := pool.stats()
 			pool.mu.RUnlock()
+			orderPendingGauge.Update(int64(pending))
+			orderQueuedGauge.Update(int64(queued))
 
 			log.Debug("Order pool status report", "executable", pending, "queued", queued)
 
@@ -255,9 +278,11 @@ func (pool *OrderPool) loop() {
 				}
 				// Any non-locals old enough should be removed
 				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
-					for _, tx := range pool.queue[addr].Flatten() {
+					list := pool.queue[addr].Flatten()
+					for _, tx := range list {
 						pool.removeTx(tx.Hash())
 					}
+					orderQueuedEvictionCounter.Inc(int64(len(list)))
 				}
 			}
 			pool.mu.Unlock()
@@ -446,7 +471,7 @@ func (pool *OrderPool) validateOrder(tx *types.OrderTransaction) error {
 		if quantity == nil || quantity.Cmp(big.NewInt(0)) <= 0 {
 			return ErrInvalidOrderQuantity
 		}
-		if orderType != OrderTypeMarket {
+		if orderType != OrderTypeMarket && orderType != OrderTypeRoutedMarket {
 			if price == nil || price.Cmp(big.NewInt(0)) <= 0 {
 				return ErrInvalidOrderPrice
 			}
@@ -455,7 +480,14 @@ func (pool *OrderPool) validateOrder(tx *types.OrderTransaction) error {
 		if orderSide != OrderSideAsk && orderSide != OrderSideBid {
 			return ErrInvalidOrderSide
 		}
-		if orderType != OrderTypeLimit && orderType != OrderTypeMarket {
+		if orderType == OrderTypeRoutedMarket {
+			if !pool.chainconfig.IsTIPTomoXRoutedOrder(pool.chain.CurrentBlock().Number()) {
+				return ErrInvalidOrderType
+			}
+			if err := pool.validateRoute(cloneStateDb, tx); err != nil {
+				return err
+			}
+		} else if orderType != OrderTypeLimit && orderType != OrderTypeMarket {
 			return ErrInvalidOrderType
 		}
 		if err := tradingstate.VerifyPair(cloneStateDb, tx.ExchangeAddress(), tx.BaseToken(), tx.QuoteToken()); err != nil {
@@ -489,6 +521,8 @@ func (pool *OrderPool) validateOrder(tx *types.OrderTransaction) error {
 	if orderStatus != OrderStatusNew && orderStatus != OrderStatusCancle {
 		return ErrInvalidOrderStatus
 	}
+	// Orders are identified by their legacy hash whichever scheme they are signed
+	// with, the EIP-712 hash of the typed signer is only what the user signs
 	var signer = types.OrderTxSigner{}
 
 	if !tx.IsCancelledOrder() {
@@ -505,7 +539,7 @@ func (pool *OrderPool) validateOrder(tx *types.OrderTransaction) error {
 			return ErrInvalidCancelledOrder
 		}
 		originOrder := cloneTomoXStateDb.GetOrder(tradingstate.GetTradingOrderBookHash(tx.BaseToken(), tx.QuoteToken()), common.BigToHash(new(big.Int).SetUint64(tx.OrderID())))
-		if originOrder == tradingstate.EmptyOrder {
+		if tradingstate.IsEmptyOrder(originOrder) {
 			log.Debug("Order not found ", "OrderId", tx.OrderID(), "BaseToken", tx.BaseToken().Hex(), "QuoteToken", tx.QuoteToken().Hex())
 			return ErrInvalidCancelledOrder
 		}
@@ -527,14 +561,25 @@ func (pool *OrderPool) validateOrder(tx *types.OrderTransaction) error {
 	return nil
 }
 
+// validateRoute checks the route of a routed market order, every hop must be a pair listed by the exchange
+func (pool *OrderPool) validateRoute(statedb *state.StateDB, tx *types.OrderTransaction) error {
+	route := tx.Route()
+	if err := tradingstate.VerifyRoute(route, tx.BaseToken(), tx.QuoteToken(), tx.Side()); err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(route); i++ {
+		if tradingstate.VerifyPair(statedb, tx.ExchangeAddress(), route[i], route[i+1]) != nil &&
+			tradingstate.VerifyPair(statedb, tx.ExchangeAddress(), route[i+1], route[i]) != nil {
+			return fmt.Errorf("invalid route: pair of %s and %s is not listed by exchange %s", route[i].Hex(), route[i+1].Hex(), tx.ExchangeAddress().Hex())
+		}
+	}
+	return nil
+}
+
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *OrderPool) validateTx(tx *types.OrderTransaction, local bool) error {
 
-	// check if sender is in black list
-	if tx.From() != nil && common.Blacklist[*tx.From()] {
-		return fmt.Errorf("Reject transaction with sender in black-list: %v", tx.From().Hex())
-	}
 	// Heuristic limit, reject transactions over 32KB to prevent DOS attacks
 	if tx.Size() > 32*1024 {
 		return ErrOversizedData
@@ -545,6 +590,16 @@ func (pool *OrderPool) validateTx(tx *types.OrderTransaction, local bool) error
 	if err != nil {
 		return ErrInvalidSender
 	}
+	// Signatures of the EIP-712 typed data are only accepted after the fork
+	if !pool.chainconfig.IsTIPTomoXTypedData(pool.chain.CurrentBlock().Number()) {
+		if legacy, err := (types.OrderTxSigner{}).Sender(tx); err != nil || legacy != from {
+			return ErrInvalidSender
+		}
+	}
+	// check if sender is in black list, EIP-712 signers are only known once verified
+	if common.Blacklist[from] {
+		return fmt.Errorf("Reject transaction with sender in black-list: %v", from.Hex())
+	}
 	err = pool.validateOrder(tx)
 	if err != nil {
 		return err
@@ -579,7 +634,7 @@ func (pool *OrderPool) add(tx *types.OrderTransaction, local bool) (bool, error)
 	// If the transaction fails basic validation, discard it
 	if err := pool.validateTx(tx, local); err != nil {
 		log.Debug("Discarding invalid order transaction", "hash", hash, "userAddress", tx.UserAddress().Hex(), "status", tx.Status, "err", err)
-		invalidTxCounter.Inc(1)
+		orderInvalidTxCounter.Inc(1)
 		return false, err
 	}
 	from, _ := types.OrderSender(pool.signer, tx) // already validated
@@ -587,18 +642,19 @@ func (pool *OrderPool) add(tx *types.OrderTransaction, local bool) (bool, error)
 	// If the transaction pool is full, discard underpriced transactions
 	if uint64(len(pool.all)) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
 		log.Debug("Add order transaction to pool full", "hash", hash, "nonce", tx.Nonce())
+		orderOverflowTxCounter.Inc(1)
 		return false, ErrPoolOverflow
 	}
 	// If the transaction is replacing an already pending one, do directly
 	if list := pool.pending[from]; list != nil && list.Overlaps(tx) {
 		inserted, old := list.Add(tx)
 		if !inserted {
-			pendingDiscardCounter.Inc(1)
+			orderPendingDiscardCounter.Inc(1)
 			return false, ErrPendingNonceTooLow
 		}
 		if old != nil {
 			delete(pool.all, old.Hash())
-			pendingReplaceCounter.Inc(1)
+			orderPendingReplaceCounter.Inc(1)
 		}
 		pool.all[tx.Hash()] = tx
 		pool.journalTx(from, tx)
@@ -636,13 +692,13 @@ func (pool *OrderPool) enqueueTx(hash common.Hash, tx *types.OrderTransaction) (
 	inserted, old := pool.queue[from].Add(tx)
 	if !inserted {
 		// An older transaction was better, discard this
-		queuedDiscardCounter.Inc(1)
+		orderQueuedDiscardCounter.Inc(1)
 		return false, ErrPendingNonceTooLow
 	}
 	// Discard any previous transaction and mark this
 	if old != nil {
 		delete(pool.all, old.Hash())
-		queuedReplaceCounter.Inc(1)
+		orderQueuedReplaceCounter.Inc(1)
 	}
 	pool.all[hash] = tx
 	return old != nil, nil
@@ -675,13 +731,13 @@ func (pool *OrderPool) promoteTx(addr common.Address, hash common.Hash, tx *type
 	if !inserted {
 		// An older transaction was better, discard this
 		delete(pool.all, hash)
-		pendingDiscardCounter.Inc(1)
+		orderPendingDiscardCounter.Inc(1)
 		return
 	}
 	// Otherwise discard any previous transaction and mark this
 	if old != nil {
 		delete(pool.all, old.Hash())
-		pendingReplaceCounter.Inc(1)
+		orderPendingReplaceCounter.Inc(1)
 	}
 	// Failsafe to work around direct pending inserts (tests)
 	if pool.all[hash] == nil {
@@ -894,7 +950,7 @@ func (pool *OrderPool) promoteExecutables(accounts []common.Address) {
 				hash := tx.Hash()
 				delete(pool.all, hash)
 
-				queuedRateLimitCounter.Inc(1)
+				orderQueuedRateLimitCounter.Inc(1)
 				log.Debug("Removed cap-exceeding queued transaction", "addr", tx.UserAddress().Hex(), "nonce", tx.Nonce(), "ohash", tx.OrderHash().Hex(), "status", tx.Status(), "orderid", tx.OrderID())
 			}
 		}
@@ -971,7 +1027,7 @@ func (pool *OrderPool) promoteExecutables(accounts []common.Address) {
 				}
 			}
 		}
-		pendingRateLimitCounter.Inc(int64(pendingBeforeCap - pending))
+		orderPendingRateLimitCounter.Inc(int64(pendingBeforeCap - pending))
 	}
 	// If we've queued more transactions than the hard limit, drop oldest ones
 	queued := uint64(0)
@@ -1001,7 +1057,7 @@ func (pool *OrderPool) promoteExecutables(accounts []common.Address) {
 					pool.removeTx(tx.Hash())
 				}
 				drop -= size
-				queuedRateLimitCounter.Inc(int64(size))
+				orderQueuedRateLimitCounter.Inc(int64(size))
 				continue
 			}
 			// Otherwise drop only last few transactions
@@ -1009,7 +1065,7 @@ func (pool *OrderPool) promoteExecutables(accounts []common.Address) {
 			for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
 				pool.removeTx(txs[i].Hash())
 				drop--
-				queuedRateLimitCounter.Inc(1)
+				orderQueuedRateLimitCounter.Inc(1)
 			}
 		}
 	}
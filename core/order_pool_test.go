@@ -3,10 +3,14 @@ package core
 import (
 	"context"
 	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/core/rawdb"
+	"github.com/69th-byte/sdexchain/core/state"
 	"github.com/69th-byte/sdexchain/core/types"
 	"github.com/69th-byte/sdexchain/crypto"
 	"github.com/69th-byte/sdexchain/ethclient"
+	"github.com/69th-byte/sdexchain/params"
 	"github.com/69th-byte/sdexchain/rpc"
+	"github.com/69th-byte/sdexchain/tomox/tradingstate"
 	"log"
 	"math/big"
 	"strconv"
@@ -312,3 +316,89 @@ func TestCancelOrder(t *testing.T) {
 	time.Sleep(5 * time.Second)
 	//testSendOrder(t, new(big.Int).SetUint64(48), new(big.Int).SetUint64(15), "SELL", "NEW", 0)
 }
+
+// testOrderChain is a chain whose head is all the order pool validation needs.
+type testOrderChain struct {
+	blockChainTomox
+	head *types.Block
+}
+
+func (bc *testOrderChain) CurrentBlock() *types.Block { return bc.head }
+
+// newTestOrderPool creates an order pool on top of a head at the given number,
+// with a relayer listing the base/quote pair of the test orders.
+func newTestOrderPool(t *testing.T, number int64) *OrderPool {
+	db := rawdb.NewMemoryDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	orderstate, err := tradingstate.New(common.Hash{}, tradingstate.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to create trading state: %v", err)
+	}
+	var (
+		contract = common.HexToAddress(common.RelayerRegistrationSMC)
+		loc      = tradingstate.GetLocMappingAtKey(testRelayer.Hash(), tradingstate.RelayerMappingSlot["RELAYER_LIST"])
+		field    = func(name string) common.Hash {
+			return common.BigToHash(new(big.Int).Add(loc, tradingstate.RelayerStructMappingSlot[name]))
+		}
+	)
+	statedb.SetState(contract, field("_deposit"), common.BigToHash(new(big.Int).Mul(common.BasePrice, new(big.Int).Add(common.RelayerLockedFund, common.Big1))))
+	statedb.SetState(contract, field("_fromTokens"), common.BigToHash(common.Big1))
+	statedb.SetState(contract, field("_toTokens"), common.BigToHash(common.Big1))
+	statedb.SetState(contract, state.GetLocDynamicArrAtElement(field("_fromTokens"), 0, 1), BTCAddress.Hash())
+	statedb.SetState(contract, state.GetLocDynamicArrAtElement(field("_toTokens"), 0, 1), USDAddress.Hash())
+
+	config := *params.TestChainConfig
+	return &OrderPool{
+		chainconfig:       &config,
+		chain:             &testOrderChain{head: types.NewBlockWithHeader(&types.Header{Number: big.NewInt(number)})},
+		signer:            types.NewOrderTypedSigner(config.ChainId),
+		currentRootState:  statedb,
+		currentOrderState: orderstate,
+		pendingState:      tradingstate.ManageState(orderstate),
+	}
+}
+
+var testRelayer = common.HexToAddress("0x0D3ab14BBaD3D99F4203bd7a11aCB94882050E7e")
+
+// Tests that orders signed over their EIP-712 typed data are only accepted after
+// the fork, and that their signers are checked against the black-list.
+func TestOrderPoolTypedSigning(t *testing.T) {
+	defer func(fork *big.Int) { common.TIPTomoXTypedData = fork }(common.TIPTomoXTypedData)
+	common.TIPTomoXTypedData = big.NewInt(10)
+
+	key, _ := crypto.GenerateKey()
+	user := crypto.PubkeyToAddress(key.PublicKey)
+	sign := func(signer types.OrderSigner) *types.OrderTransaction {
+		tx := types.NewOrderTransaction(0, _1E18, _1E8, testRelayer, user, BTCAddress, USDAddress, OrderStatusNew, OrderSideBid, OrderTypeMarket, common.Hash{}, 0)
+		signed, err := types.OrderSignTx(tx, signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign order: %v", err)
+		}
+		return signed
+	}
+	pre, post := newTestOrderPool(t, 9), newTestOrderPool(t, 10)
+	typed := types.NewOrderTypedSigner(params.TestChainConfig.ChainId)
+
+	if err := pre.validateTx(sign(types.OrderTxSigner{}), false); err != nil {
+		t.Errorf("legacy order rejected before the fork: %v", err)
+	}
+	if err := pre.validateTx(sign(typed), false); err != ErrInvalidSender {
+		t.Errorf("typed order error mismatch before the fork: have %v, want %v", err, ErrInvalidSender)
+	}
+	if err := post.validateTx(sign(types.OrderTxSigner{}), false); err != nil {
+		t.Errorf("legacy order rejected after the fork: %v", err)
+	}
+	tx := sign(typed)
+	if err := post.validateTx(tx, false); err != nil {
+		t.Errorf("typed order rejected after the fork: %v", err)
+	}
+	if from := tx.From(); from == nil || *from != user {
+		t.Errorf("verified sender mismatch: have %x, want %x", from, user)
+	}
+	common.Blacklist[user] = true
+	defer delete(common.Blacklist, user)
+
+	if err := post.validateTx(sign(typed), false); err == nil || !strings.Contains(err.Error(), "black-list") {
+		t.Errorf("black-listed typed order error mismatch: have %v", err)
+	}
+}
@@ -17,7 +17,11 @@
 package rawdb
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+
+	"github.com/69th-byte/sdexchain/common"
 	"github.com/69th-byte/sdexchain/ethdb"
 	"github.com/69th-byte/sdexchain/ethdb/leveldb"
 	"github.com/69th-byte/sdexchain/ethdb/memorydb"
@@ -93,6 +97,54 @@ func NewDatabase(db ethdb.KeyValueStore) ethdb.Database {
 	}
 }
 
+// NewDatabaseWithFreezer creates a high level database on top of a given key-
+// value data store with a freezer moving immutable chain segments older than
+// threshold blocks into cold storage.
+func NewDatabaseWithFreezer(db ethdb.KeyValueStore, freezer string, namespace string, threshold uint64) (ethdb.Database, error) {
+	// Create the idle freezer instance
+	frdb, err := newFreezer(freezer, namespace, threshold)
+	if err != nil {
+		return nil, err
+	}
+	// Since the freezer can be stored separately from the user's key-value database,
+	// there's a fairly high probability that the user requests invalid combinations
+	// of the freezer and database. Ensure that we don't shoot ourselves in the foot
+	// by serving up conflicting data, leading to both datastores getting corrupted.
+	if kvgenesis, _ := db.Get(headerHashKey(0)); len(kvgenesis) > 0 {
+		if frozen, _ := frdb.Ancients(); frozen > 0 {
+			// If the freezer already contains something, ensure that the genesis blocks
+			// match, otherwise we might mix up freezers across chains and destroy both
+			// the freezer and the key-value store.
+			if frgenesis, _ := frdb.Ancient(FreezerHashTable, 0); !bytes.Equal(kvgenesis, frgenesis) {
+				frdb.Close()
+				return nil, fmt.Errorf("genesis mismatch: %#x (leveldb) != %#x (ancients)", kvgenesis, frgenesis)
+			}
+			// Key-value store and freezer belong to the same network. Ensure that they
+			// are contiguous, otherwise we might end up with a non-functional freezer.
+			if kvhash := readCanonicalHash(db, frozen); kvhash == (common.Hash{}) {
+				// Subsequent header after the freezer limit is missing from the database.
+				// Reject startup is the database has a more recent head.
+				if head := readHeaderNumber(db, readHeadBlockHash(db)); head != nil && *head > frozen-1 {
+					frdb.Close()
+					return nil, fmt.Errorf("gap (#%d) in the chain between ancients and leveldb", frozen)
+				}
+			}
+		}
+	} else if frozen, _ := frdb.Ancients(); frozen > 0 {
+		// The key-value store is empty, refuse to run a half-populated freezer on it
+		frdb.Close()
+		return nil, errors.New("ancient chain segments already extracted, please set --datadir.ancient to the correct path")
+	}
+	// Freezer is consistent with the key-value database, permit combining the two
+	frdb.wg.Add(1)
+	go frdb.freeze(db)
+
+	return &freezerdb{
+		KeyValueStore: db,
+		AncientStore:  frdb,
+	}, nil
+}
+
 // NewMemoryDatabase creates an ephemeral in-memory key-value database without a
 // freezer moving immutable chain segments into cold storage.
 func NewMemoryDatabase() ethdb.Database {
@@ -108,3 +160,18 @@ func NewLevelDBDatabase(file string, cache int, handles int, namespace string) (
 	}
 	return NewDatabase(db), nil
 }
+
+// NewLevelDBDatabaseWithFreezer creates a persistent key-value database with a
+// freezer moving immutable chain segments into cold storage.
+func NewLevelDBDatabaseWithFreezer(file string, cache int, handles int, freezer string, namespace string, threshold uint64) (ethdb.Database, error) {
+	kvdb, err := leveldb.New(file, cache, handles, namespace)
+	if err != nil {
+		return nil, err
+	}
+	frdb, err := NewDatabaseWithFreezer(kvdb, freezer, namespace, threshold)
+	if err != nil {
+		kvdb.Close()
+		return nil, err
+	}
+	return frdb, nil
+}
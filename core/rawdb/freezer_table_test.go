@@ -0,0 +1,197 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/metrics"
+)
+
+// getChunk returns a chunk of data, filled with the given byte.
+func getChunk(size int, b int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(b)
+	}
+	return data
+}
+
+func newTestTable(t *testing.T, dir string, name string, maxFilesize uint32, noCompression bool) *freezerTable {
+	rm, wm := metrics.NewMeter(), metrics.NewMeter()
+	f, err := newCustomTable(dir, name, rm, wm, maxFilesize, noCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// Tests that items are appended and retrieved across several data files, both
+// with and without compression, and survive reopening the table.
+func TestFreezerBasics(t *testing.T) {
+	for _, noCompression := range []bool{true, false} {
+		dir, err := ioutil.TempDir("", "freezer")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		// set cutoff at 50 bytes
+		f := newTestTable(t, dir, "test", 50, noCompression)
+		// Write 15 bytes 255 times, results in 85 files
+		for x := 0; x < 255; x++ {
+			if err := f.Append(uint64(x), getChunk(15, x)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		check := func(f *freezerTable) {
+			for y := 0; y < 255; y++ {
+				got, err := f.Retrieve(uint64(y))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, getChunk(15, y)) {
+					t.Fatalf("item %d: data mismatch: have %x", y, got)
+				}
+			}
+			if _, err := f.Retrieve(255); err == nil {
+				t.Fatal("expected error for item beyond the table")
+			}
+		}
+		check(f)
+		if err := f.Append(300, getChunk(15, 0)); err == nil {
+			t.Fatal("expected error for out of order append")
+		}
+		f.Close()
+
+		f = newTestTable(t, dir, "test", 50, noCompression)
+		if f.items != 255 {
+			t.Fatalf("reopened table: item count mismatch: have %d, want %d", f.items, 255)
+		}
+		check(f)
+		f.Close()
+	}
+}
+
+// Tests that a table with a data file longer than the index, or an index
+// longer than the data files, is repaired to the common length on open.
+func TestFreezerRepair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := newTestTable(t, dir, "test", 50, true)
+	for x := 0; x < 10; x++ {
+		if err := f.Append(uint64(x), getChunk(15, x)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+
+	// Chop the last data file in half, the last item must be dropped
+	data := filepath.Join(dir, fmt.Sprintf("test.%04d.rdat", 3))
+	stat, err := os.Stat(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(data, stat.Size()-7); err != nil {
+		t.Fatal(err)
+	}
+	f = newTestTable(t, dir, "test", 50, true)
+	if f.items != 9 {
+		t.Fatalf("item count mismatch after data loss: have %d, want %d", f.items, 9)
+	}
+	if _, err := f.Retrieve(9); err == nil {
+		t.Fatal("expected error for dropped item")
+	}
+	// The table must accept the dropped item again
+	if err := f.Append(9, getChunk(15, 9)); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// Chop a partial entry off the index, the last item must be dropped
+	index := filepath.Join(dir, "test.ridx")
+	stat, err = os.Stat(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(index, stat.Size()-2); err != nil {
+		t.Fatal(err)
+	}
+	f = newTestTable(t, dir, "test", 50, true)
+	defer f.Close()
+	if f.items != 9 {
+		t.Fatalf("item count mismatch after index loss: have %d, want %d", f.items, 9)
+	}
+	for y := 0; y < 9; y++ {
+		got, err := f.Retrieve(uint64(y))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, getChunk(15, y)) {
+			t.Fatalf("item %d: data mismatch: have %x", y, got)
+		}
+	}
+}
+
+// Tests that truncating a table drops the items above the limit, including
+// whole data files, and that appending resumes from the new head.
+func TestFreezerTruncate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := newTestTable(t, dir, "test", 50, true)
+	defer f.Close()
+	for x := 0; x < 30; x++ {
+		if err := f.Append(uint64(x), getChunk(15, x)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.truncate(10); err != nil {
+		t.Fatal(err)
+	}
+	if f.items != 10 {
+		t.Fatalf("item count mismatch: have %d, want %d", f.items, 10)
+	}
+	if _, err := f.Retrieve(10); err == nil {
+		t.Fatal("expected error for truncated item")
+	}
+	if _, err := os.Stat(filepath.Join(dir, fmt.Sprintf("test.%04d.rdat", 9))); !os.IsNotExist(err) {
+		t.Fatalf("data file above the head not removed: %v", err)
+	}
+	if err := f.Append(10, getChunk(15, 0xff)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.Retrieve(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, getChunk(15, 0xff)) {
+		t.Fatalf("data mismatch after truncation: have %x", got)
+	}
+}
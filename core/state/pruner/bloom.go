@@ -0,0 +1,66 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"encoding/binary"
+
+	"github.com/69th-byte/sdexchain/common"
+)
+
+// stateBloom is a bloom filter used during the state pruning to record all
+// the kept trie nodes and contract codes.
+//
+// All the keys are 32 byte keccak hashes, so instead of hashing them again the
+// filter uses four independent 8 byte slices of the key as its hash functions.
+// A false positive only means a dangling node is kept, it never causes a
+// reachable node to be deleted.
+type stateBloom struct {
+	bits []uint64
+	size uint64 // Number of bits in the filter
+}
+
+// newStateBloom creates a bloom filter of the given size in megabytes.
+func newStateBloom(megabytes uint64) *stateBloom {
+	if megabytes == 0 {
+		megabytes = 1
+	}
+	size := megabytes * 1024 * 1024 * 8
+	return &stateBloom{
+		bits: make([]uint64, size/64),
+		size: size,
+	}
+}
+
+// add marks the given hash as kept.
+func (b *stateBloom) add(hash common.Hash) {
+	for i := 0; i < common.HashLength; i += 8 {
+		bit := binary.BigEndian.Uint64(hash[i:]) % b.size
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// contains reports whether the given hash may have been marked as kept.
+func (b *stateBloom) contains(hash common.Hash) bool {
+	for i := 0; i < common.HashLength; i += 8 {
+		bit := binary.BigEndian.Uint64(hash[i:]) % b.size
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,316 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package pruner implements the offline pruning of the trie nodes which are no
+// longer reachable from the recent state roots.
+package pruner
+
+import (
+	"errors"
+	"time"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/core/state"
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/ethdb"
+	"github.com/69th-byte/sdexchain/log"
+	"github.com/69th-byte/sdexchain/rlp"
+	"github.com/69th-byte/sdexchain/trie"
+)
+
+var (
+	// errNoRoots is returned if none of the roots to keep is present in the
+	// database, pruning would delete every trie node.
+	errNoRoots = errors.New("none of the trie roots to keep is available")
+
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	// emptyCode is the known hash of the empty EVM bytecode.
+	emptyCode = crypto.Keccak256Hash(nil)
+)
+
+// logInterval is the interval of the progress reports while marking and
+// sweeping the database.
+const logInterval = 8 * time.Second
+
+// Pruner removes the trie nodes of a database which are not reachable from a
+// set of kept roots. It works in two phases: the kept tries are marked into a
+// bloom filter first, then every trie node of the database missing from the
+// filter is swept. The database must not be used by a running node meanwhile.
+type Pruner struct {
+	db     ethdb.Database
+	triedb *trie.Database
+	bloom  *stateBloom
+	marked map[common.Hash]struct{} // Roots of the tries already marked in full
+
+	nodes  uint64    // Number of nodes marked so far
+	start  time.Time // Time the marking started
+	logged time.Time // Time of the last progress report
+}
+
+// NewPruner creates a pruner for the given database, recording the kept nodes
+// in a bloom filter of bloomSize megabytes.
+func NewPruner(db ethdb.Database, bloomSize uint64) *Pruner {
+	return &Pruner{
+		db:     db,
+		triedb: trie.NewDatabase(db),
+		bloom:  newStateBloom(bloomSize),
+		marked: make(map[common.Hash]struct{}),
+		start:  time.Now(),
+		logged: time.Now(),
+	}
+}
+
+// MarkState marks the account tries of the given state roots as kept, along
+// with the storage tries and the contract codes of their accounts. The roots
+// should be ordered by block number, every trie is only walked where it differs
+// from the previous one.
+//
+// A node only persists the state of some blocks, the roots missing from the
+// database are skipped. It is an error if none of them is present though, as
+// nothing would be kept.
+func (p *Pruner) MarkState(roots []common.Hash) error {
+	roots, err := p.availableRoots(roots)
+	if err != nil {
+		return err
+	}
+	return p.markTries(roots, p.markAccount)
+}
+
+// MarkTries marks the tries of the given roots as kept, together with every
+// nested trie referenced from their leaves. It is used for the TomoX trading
+// and lending tries, which store the roots of their order book, order list and
+// liquidation tries as 32 byte strings inside the leaf objects. The roots
+// should be ordered by block number, missing ones are skipped as by MarkState.
+func (p *Pruner) MarkTries(roots []common.Hash) error {
+	roots, err := p.availableRoots(roots)
+	if err != nil {
+		return err
+	}
+	return p.markTries(roots, p.markNested)
+}
+
+// Prune deletes every trie node and contract code of the database which was not
+// marked as kept, returning the number and the total size of the deleted
+// entries. In dry-run mode nothing is deleted, only the counters are reported.
+func (p *Pruner) Prune(dryRun bool) (uint64, common.StorageSize, error) {
+	var (
+		count  uint64
+		size   common.StorageSize
+		start  = time.Now()
+		logged = time.Now()
+		batch  = p.db.NewBatch()
+		it     = p.db.NewIterator(nil, nil)
+	)
+	defer it.Release()
+
+	log.Info("Sweeping unreachable trie nodes", "marked", p.nodes, "dryrun", dryRun)
+	for it.Next() {
+		key := it.Key()
+		if len(key) != common.HashLength {
+			continue
+		}
+		hash := common.BytesToHash(key)
+		if p.bloom.contains(hash) {
+			continue
+		}
+		// Only content addressed entries (trie nodes and codes) are pruned
+		if crypto.Keccak256Hash(it.Value()) != hash {
+			continue
+		}
+		count++
+		size += common.StorageSize(len(key) + len(it.Value()))
+
+		if !dryRun {
+			if err := batch.Delete(key); err != nil {
+				return count, size, err
+			}
+			if batch.ValueSize() >= ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					return count, size, err
+				}
+				batch.Reset()
+			}
+		}
+		if time.Since(logged) > logInterval {
+			log.Info("Sweeping unreachable trie nodes", "nodes", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return count, size, err
+	}
+	if dryRun {
+		log.Info("Found unreachable trie nodes", "nodes", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+		return count, size, nil
+	}
+	if err := batch.Write(); err != nil {
+		return count, size, err
+	}
+	log.Info("Pruned unreachable trie nodes", "nodes", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	if count > 0 {
+		cstart := time.Now()
+		log.Info("Compacting database")
+		if err := p.db.Compact(nil, nil); err != nil {
+			return count, size, err
+		}
+		log.Info("Compacted database", "elapsed", common.PrettyDuration(time.Since(cstart)))
+	}
+	return count, size, nil
+}
+
+// availableRoots filters the non-empty roots present in the database, failing
+// if there are non-empty roots but none of them is present.
+func (p *Pruner) availableRoots(roots []common.Hash) ([]common.Hash, error) {
+	var (
+		available []common.Hash
+		missing   int
+	)
+	for _, root := range roots {
+		if root == emptyRoot || root == (common.Hash{}) {
+			continue
+		}
+		if has, _ := p.db.Has(root[:]); !has {
+			missing++
+			continue
+		}
+		available = append(available, root)
+	}
+	if missing > 0 {
+		log.Warn("Skipping unavailable trie roots", "missing", missing, "available", len(available))
+		if len(available) == 0 {
+			return nil, errNoRoots
+		}
+	}
+	return available, nil
+}
+
+// markTries marks the nodes of the tries of the given roots, calling onLeaf for
+// every leaf reached. Each trie is only walked where it differs from the last
+// trie marked in full, the unchanged parts and their leaves are kept already.
+func (p *Pruner) markTries(roots []common.Hash, onLeaf func(blob []byte) error) error {
+	prev, err := trie.New(common.Hash{}, p.triedb)
+	if err != nil {
+		return err
+	}
+	for _, root := range roots {
+		if _, ok := p.marked[root]; ok || root == emptyRoot || root == (common.Hash{}) {
+			continue
+		}
+		t, err := trie.New(root, p.triedb)
+		if err != nil {
+			return err
+		}
+		it, _ := trie.NewDifferenceIterator(prev.NodeIterator(nil), t.NodeIterator(nil))
+		for it.Next(true) {
+			if hash := it.Hash(); hash != (common.Hash{}) {
+				p.mark(hash)
+			}
+			if it.Leaf() && onLeaf != nil {
+				if err := onLeaf(it.LeafBlob()); err != nil {
+					return err
+				}
+			}
+		}
+		if err := it.Error(); err != nil {
+			return err
+		}
+		p.marked[root] = struct{}{}
+		prev = t
+	}
+	return nil
+}
+
+// markAccount marks the storage trie and the code of an account leaf.
+func (p *Pruner) markAccount(blob []byte) error {
+	var account state.Account
+	if err := rlp.DecodeBytes(blob, &account); err != nil {
+		return err
+	}
+	if hash := common.BytesToHash(account.CodeHash); hash != emptyCode {
+		p.mark(hash)
+	}
+	return p.markTries([]common.Hash{account.Root}, nil)
+}
+
+// markNested marks every trie whose root is referenced from the given leaf.
+func (p *Pruner) markNested(blob []byte) error {
+	for _, root := range nestedRoots(blob) {
+		if _, ok := p.marked[root]; ok || !p.isTrieNode(root) {
+			continue
+		}
+		if err := p.markTries([]common.Hash{root}, p.markNested); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isTrieNode reports whether the given hash is the key of a trie node in the
+// database, as opposed to any other 32 byte value stored in a leaf.
+func (p *Pruner) isTrieNode(hash common.Hash) bool {
+	enc, err := p.db.Get(hash[:])
+	if err != nil || crypto.Keccak256Hash(enc) != hash {
+		return false
+	}
+	kind, content, _, err := rlp.Split(enc)
+	if err != nil || kind != rlp.List {
+		return false
+	}
+	n, err := rlp.CountValues(content)
+	return err == nil && (n == 2 || n == 17)
+}
+
+// mark records a node as kept, reporting the progress from time to time.
+func (p *Pruner) mark(hash common.Hash) {
+	p.bloom.add(hash)
+	p.nodes++
+
+	if time.Since(p.logged) > logInterval {
+		log.Info("Marking reachable trie nodes", "nodes", p.nodes, "tries", len(p.marked), "elapsed", common.PrettyDuration(time.Since(p.start)))
+		p.logged = time.Now()
+	}
+}
+
+// nestedRoots returns every 32 byte string found in an RLP encoded leaf,
+// descending into lists and into strings which are RLP encoded themselves.
+func nestedRoots(blob []byte) []common.Hash {
+	var roots []common.Hash
+	for len(blob) > 0 {
+		kind, content, rest, err := rlp.Split(blob)
+		if err != nil {
+			return roots
+		}
+		switch {
+		case kind == rlp.List:
+			roots = append(roots, nestedRoots(content)...)
+		case len(content) == common.HashLength:
+			roots = append(roots, common.BytesToHash(content))
+		case len(content) > common.HashLength && isRLP(content):
+			roots = append(roots, nestedRoots(content)...)
+		}
+		blob = rest
+	}
+	return roots
+}
+
+// isRLP reports whether the given bytes are a single complete RLP value.
+func isRLP(blob []byte) bool {
+	_, _, rest, err := rlp.Split(blob)
+	return err == nil && len(rest) == 0
+}
@@ -0,0 +1,196 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/core/rawdb"
+	"github.com/69th-byte/sdexchain/core/state"
+	"github.com/69th-byte/sdexchain/ethdb"
+	"github.com/69th-byte/sdexchain/rlp"
+	"github.com/69th-byte/sdexchain/trie"
+)
+
+// stateNodes returns the hashes of all the trie nodes and codes of a state.
+func stateNodes(t *testing.T, db ethdb.Database, root common.Hash) map[common.Hash]struct{} {
+	statedb, err := state.New(root, state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to open state %x: %v", root, err)
+	}
+	nodes := make(map[common.Hash]struct{})
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+		if it.Hash != (common.Hash{}) {
+			nodes[it.Hash] = struct{}{}
+		}
+	}
+	if it.Error != nil {
+		t.Fatalf("failed to iterate state %x: %v", root, it.Error)
+	}
+	return nodes
+}
+
+// makeStates commits three generations of a state, each modifying some of the
+// accounts and storage slots of the previous one.
+func makeStates(t *testing.T, db ethdb.Database) []common.Hash {
+	sdb := state.NewDatabase(db)
+
+	var (
+		roots []common.Hash
+		root  common.Hash
+	)
+	for gen := 0; gen < 3; gen++ {
+		statedb, _ := state.New(root, sdb)
+		for i := byte(0); i < 64; i++ {
+			addr := common.BytesToAddress([]byte{i})
+			if gen > 0 && i%(byte(gen)+1) != 0 {
+				continue
+			}
+			statedb.AddBalance(addr, big.NewInt(int64(i)+1))
+			if i%4 == 0 {
+				statedb.SetState(addr, common.Hash{byte(gen)}, common.Hash{i, byte(gen) + 1})
+			}
+			if gen == 0 && i%8 == 0 {
+				statedb.SetCode(addr, []byte{i, i, i})
+			}
+		}
+		var err error
+		if root, err = statedb.Commit(false); err != nil {
+			t.Fatalf("failed to commit state: %v", err)
+		}
+		if err := sdb.TrieDB().Commit(root, false); err != nil {
+			t.Fatalf("failed to flush state: %v", err)
+		}
+		roots = append(roots, root)
+	}
+	return roots
+}
+
+// Tests that pruning keeps every node of the marked states while deleting the
+// nodes only referenced by older ones, and that a dry run deletes nothing.
+func TestPruneState(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	roots := makeStates(t, db)
+
+	kept := stateNodes(t, db, roots[1])
+	for hash := range stateNodes(t, db, roots[2]) {
+		kept[hash] = struct{}{}
+	}
+	var stale []common.Hash
+	for hash := range stateNodes(t, db, roots[0]) {
+		if _, ok := kept[hash]; !ok {
+			stale = append(stale, hash)
+		}
+	}
+	if len(stale) == 0 {
+		t.Fatalf("no stale nodes in the test states")
+	}
+	// A dry run only reports the stale nodes
+	pruner := NewPruner(db, 1)
+	if err := pruner.MarkState(roots[1:]); err != nil {
+		t.Fatalf("failed to mark state: %v", err)
+	}
+	count, _, err := pruner.Prune(true)
+	if err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	if count != uint64(len(stale)) {
+		t.Errorf("dry run count mismatch: have %d, want %d", count, len(stale))
+	}
+	for _, hash := range stale {
+		if has, _ := db.Has(hash[:]); !has {
+			t.Fatalf("stale node %x deleted in dry run", hash)
+		}
+	}
+	// A real run deletes them, keeping the marked states intact
+	count, _, err = pruner.Prune(false)
+	if err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	if count != uint64(len(stale)) {
+		t.Errorf("pruned count mismatch: have %d, want %d", count, len(stale))
+	}
+	for _, hash := range stale {
+		if has, _ := db.Has(hash[:]); has {
+			t.Errorf("stale node %x not pruned", hash)
+		}
+	}
+	for _, root := range roots[1:] {
+		stateNodes(t, db, root)
+	}
+}
+
+// Tests that the tries referenced from the leaves of a marked trie are kept,
+// the way the TomoX trading state references its order book tries.
+func TestPruneNestedTries(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	triedb := trie.NewDatabase(db)
+
+	commit := func(tr *trie.Trie) common.Hash {
+		root, err := tr.Commit(nil)
+		if err != nil {
+			t.Fatalf("failed to commit trie: %v", err)
+		}
+		if err := triedb.Commit(root, false); err != nil {
+			t.Fatalf("failed to flush trie: %v", err)
+		}
+		return root
+	}
+	var outerRoots, innerRoots []common.Hash
+	for gen := byte(0); gen < 2; gen++ {
+		inner, _ := trie.New(common.Hash{}, triedb)
+		for i := byte(0); i < 32; i++ {
+			inner.Update(common.Hash{i, gen}.Bytes(), common.Hash{gen, i}.Bytes())
+		}
+		innerRoot := commit(inner)
+
+		outer, _ := trie.New(common.Hash{}, triedb)
+		for i := byte(0); i < 16; i++ {
+			leaf, _ := rlp.EncodeToBytes([]interface{}{big.NewInt(int64(i)), innerRoot, common.Hash{0xff, i}})
+			outer.Update(common.Hash{i}.Bytes(), leaf)
+		}
+		outerRoots = append(outerRoots, commit(outer))
+		innerRoots = append(innerRoots, innerRoot)
+	}
+	pruner := NewPruner(db, 1)
+	if err := pruner.MarkTries(outerRoots[1:]); err != nil {
+		t.Fatalf("failed to mark tries: %v", err)
+	}
+	if _, _, err := pruner.Prune(false); err != nil {
+		t.Fatalf("failed to prune tries: %v", err)
+	}
+	for i, root := range []common.Hash{outerRoots[0], innerRoots[0]} {
+		if has, _ := db.Has(root[:]); has {
+			t.Errorf("stale root #%d not pruned", i)
+		}
+	}
+	for i, root := range []common.Hash{outerRoots[1], innerRoots[1]} {
+		tr, err := trie.New(root, trie.NewDatabase(db))
+		if err != nil {
+			t.Fatalf("kept root #%d missing: %v", i, err)
+		}
+		it := tr.NodeIterator(nil)
+		for it.Next(true) {
+		}
+		if err := it.Error(); err != nil {
+			t.Errorf("kept trie #%d incomplete: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/core/rawdb"
+	"github.com/69th-byte/sdexchain/ethdb"
+	"github.com/69th-byte/sdexchain/log"
+	"github.com/69th-byte/sdexchain/rlp"
+	"github.com/69th-byte/sdexchain/trie"
+	"github.com/VictoriaMetrics/fastcache"
+)
+
+var (
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+)
+
+// logInterval is the interval of the generation progress reports.
+const logInterval = 8 * time.Second
+
+// generatorStats is a collection of statistics gathered by the snapshot generator
+// for logging purposes.
+type generatorStats struct {
+	start    time.Time          // Timestamp when generation started
+	accounts uint64             // Number of accounts indexed
+	slots    uint64             // Number of storage slots indexed
+	storage  common.StorageSize // Account and storage slot size
+}
+
+// Log creates a contextual log with the given message and the context pulled
+// from the internally maintained statistics.
+func (gs *generatorStats) Log(msg string, root common.Hash, marker []byte) {
+	ctx := []interface{}{"root", root}
+	if len(marker) > 0 {
+		ctx = append(ctx, "at", common.BytesToHash(marker))
+	}
+	ctx = append(ctx, []interface{}{
+		"accounts", gs.accounts,
+		"slots", gs.slots,
+		"storage", gs.storage,
+		"elapsed", common.PrettyDuration(time.Since(gs.start)),
+	}...)
+	log.Info(msg, ctx...)
+}
+
+// generateSnapshot regenerates a brand new snapshot based on an existing state
+// database and head block asynchronously. The snapshot is returned immediately
+// and generation is continued in the background until done.
+func generateSnapshot(diskdb ethdb.KeyValueStore, triedb *trie.Database, cache int, root common.Hash) *diskLayer {
+	// Wipe any previously existing snapshot from the database
+	if err := wipeSnapshot(diskdb); err != nil {
+		log.Crit("Failed to wipe old snapshot", "err", err)
+	}
+	// Create a new disk layer with an initialized state marker at zero
+	batch := diskdb.NewBatch()
+	rawdb.WriteSnapshotRoot(batch, root)
+	journalProgress(batch, []byte{}, nil)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write initialized state marker", "err", err)
+	}
+	base := &diskLayer{
+		diskdb:     diskdb,
+		triedb:     triedb,
+		root:       root,
+		cache:      fastcache.New(cache * 1024 * 1024),
+		genMarker:  []byte{}, // Initialized but empty!
+		genPending: make(chan struct{}),
+		genAbort:   make(chan chan *generatorStats),
+	}
+	go base.generate(&generatorStats{start: time.Now()})
+	return base
+}
+
+// wipeSnapshot deletes all the account and storage snapshot entries from the
+// database, along with the snapshot root, journal and generator markers.
+func wipeSnapshot(db ethdb.KeyValueStore) error {
+	batch := db.NewBatch()
+
+	rawdb.DeleteSnapshotRoot(batch)
+	rawdb.DeleteSnapshotJournal(batch)
+	rawdb.DeleteSnapshotGenerator(batch)
+
+	for _, wipe := range []struct {
+		prefix []byte
+		keylen int
+	}{
+		{rawdb.SnapshotAccountPrefix, len(rawdb.SnapshotAccountPrefix) + common.HashLength},
+		{rawdb.SnapshotStoragePrefix, len(rawdb.SnapshotStoragePrefix) + 2*common.HashLength},
+	} {
+		it := db.NewIterator(wipe.prefix, nil)
+		for it.Next() {
+			// Skip any keys with the correct prefix but wrong length (trie nodes)
+			key := it.Key()
+			if len(key) != wipe.keylen {
+				continue
+			}
+			if err := batch.Delete(key); err != nil {
+				it.Release()
+				return err
+			}
+			if batch.ValueSize() > ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					it.Release()
+					return err
+				}
+				batch.Reset()
+			}
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
+// journalProgress persists the generator stats into the database to resume later.
+func journalProgress(db ethdb.KeyValueWriter, marker []byte, stats *generatorStats) {
+	// Write out the generator marker. Note it's a standalone disk layer generator
+	// which is not mixed with journal. It's ok if the generator is persisted while
+	// journal is not.
+	entry := journalGenerator{
+		Done:   marker == nil,
+		Marker: marker,
+	}
+	if stats != nil {
+		entry.Accounts = stats.accounts
+		entry.Slots = stats.slots
+		entry.Storage = uint64(stats.storage)
+	}
+	blob, err := rlp.EncodeToBytes(entry)
+	if err != nil {
+		panic(err) // Cannot happen, here to catch dev errors
+	}
+	rawdb.WriteSnapshotGenerator(db, blob)
+}
+
+// flush writes the accumulated snapshot entries to disk along with the given
+// generation marker, and only then extends the range of the layer served from
+// disk up to the marker.
+func (dl *diskLayer) flush(batch ethdb.Batch, marker []byte, stats *generatorStats) {
+	journalProgress(batch, marker, stats)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write snapshot", "err", err)
+	}
+	batch.Reset()
+
+	dl.lock.Lock()
+	dl.genMarker = marker
+	dl.lock.Unlock()
+}
+
+// pause waits until the generator is aborted, keeping the progress made so far.
+// It is used once the generation is done, or if the tries of the disk layer are
+// not available (any more) to generate from. In the latter case generation is
+// continued when the next disk layer is created.
+func (dl *diskLayer) pause(stats *generatorStats) {
+	abort := <-dl.genAbort
+	abort <- stats
+}
+
+// generate is a background thread that iterates over the state and storage tries,
+// constructing the state snapshot. All the arguments are purely for statistics
+// gathering and logging, since the method surfs the blocks as they arrive, often
+// being restarted.
+func (dl *diskLayer) generate(stats *generatorStats) {
+	// Create an account and state iterator pointing to the current generator marker
+	accTrie, err := trie.New(dl.root, dl.triedb)
+	if err != nil {
+		// The account trie is missing (GC), surf the chain until one becomes available
+		stats.Log("Trie missing, state snapshotting paused", dl.root, dl.genMarker)
+		dl.pause(stats)
+		return
+	}
+	stats.Log("Resuming state snapshot generation", dl.root, dl.genMarker)
+
+	var (
+		marker = dl.genMarker // Last account fully generated into the batch
+		batch  = dl.diskdb.NewBatch()
+		logged = time.Now()
+	)
+	accIt := trie.NewIterator(accTrie.NodeIterator(marker))
+	for accIt.Next() {
+		// The iteration starts at the marker itself, which is generated already
+		if bytes.Equal(accIt.Key, marker) {
+			continue
+		}
+		// Retrieve the current account and flatten it into the internal format
+		accountHash := common.BytesToHash(accIt.Key)
+
+		var acc Account
+		if err := rlp.DecodeBytes(accIt.Value, &acc); err != nil {
+			log.Crit("Invalid account encountered during snapshot creation", "err", err)
+		}
+		// Drop any slots left over by an interrupted run before regenerating
+		it := rawdb.IterateStorageSnapshots(dl.diskdb, accountHash)
+		for it.Next() {
+			if key := it.Key(); len(key) == len(rawdb.SnapshotStoragePrefix)+2*common.HashLength {
+				batch.Delete(key)
+			}
+		}
+		it.Release()
+
+		rawdb.WriteAccountSnapshot(batch, accountHash, accIt.Value)
+		stats.storage += common.StorageSize(1 + common.HashLength + len(accIt.Value))
+		stats.accounts++
+
+		// Generate the storage slots of contract accounts
+		if acc.Root != emptyRoot {
+			storeTrie, err := trie.New(acc.Root, dl.triedb)
+			if err != nil {
+				log.Error("Generator failed to access storage trie", "root", dl.root, "account", accountHash, "stroot", acc.Root, "err", err)
+				dl.flush(batch, marker, stats)
+				dl.pause(stats)
+				return
+			}
+			storeIt := trie.NewIterator(storeTrie.NodeIterator(nil))
+			for storeIt.Next() {
+				rawdb.WriteStorageSnapshot(batch, accountHash, common.BytesToHash(storeIt.Key), storeIt.Value)
+				stats.storage += common.StorageSize(1 + 2*common.HashLength + len(storeIt.Value))
+				stats.slots++
+
+				// Large contracts are flushed in chunks, the marker stays at the
+				// previous account until all the slots are written
+				if batch.ValueSize() > ethdb.IdealBatchSize {
+					dl.flush(batch, marker, stats)
+
+					select {
+					case abort := <-dl.genAbort:
+						abort <- stats
+						return
+					default:
+					}
+				}
+			}
+			if storeIt.Err != nil {
+				log.Error("Generator failed to iterate storage trie", "root", dl.root, "account", accountHash, "stroot", acc.Root, "err", storeIt.Err)
+				dl.flush(batch, marker, stats)
+				dl.pause(stats)
+				return
+			}
+		}
+		marker = accountHash.Bytes()
+
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			dl.flush(batch, marker, stats)
+		}
+		// Persist the progress and bail out if generation was aborted
+		select {
+		case abort := <-dl.genAbort:
+			dl.flush(batch, marker, stats)
+			abort <- stats
+			return
+		default:
+		}
+		if time.Since(logged) > logInterval {
+			stats.Log("Generating state snapshot", dl.root, marker)
+			logged = time.Now()
+		}
+	}
+	if accIt.Err != nil {
+		log.Error("Generator failed to iterate account trie", "root", dl.root, "err", accIt.Err)
+		dl.flush(batch, marker, stats)
+		dl.pause(stats)
+		return
+	}
+	// Snapshot fully generated, set the marker to nil
+	dl.flush(batch, nil, stats)
+
+	log.Info("Generated state snapshot", "accounts", stats.accounts, "slots", stats.slots,
+		"storage", stats.storage, "elapsed", common.PrettyDuration(time.Since(stats.start)))
+
+	close(dl.genPending)
+
+	// Someone will be looking for us, wait it out
+	dl.pause(stats)
+}
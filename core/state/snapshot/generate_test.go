@@ -0,0 +1,110 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/core/rawdb"
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/ethdb"
+	"github.com/69th-byte/sdexchain/trie"
+)
+
+// Tests that a snapshot generated from the state tries contains every account
+// and storage slot, and verifies against the state root.
+func TestGeneration(t *testing.T) {
+	state := makeTestState(64)
+	db, _, snaps, root := makeTestTree(t, state)
+
+	checkSnapshot(t, snaps.Snapshot(root), state, nil)
+	if err := snaps.Verify(root); err != nil {
+		t.Fatalf("verification failed: %v", err)
+	}
+	accounts, slots := 0, 0
+	for hash, account := range state {
+		if len(rawdb.ReadAccountSnapshot(db, hash)) > 0 {
+			accounts++
+		}
+		for slot := range account.storage {
+			if len(rawdb.ReadStorageSnapshot(db, hash, slot)) > 0 {
+				slots++
+			}
+		}
+	}
+	if accounts != 64 || slots != 22*8 {
+		t.Fatalf("persisted entries mismatch: have %d accounts and %d slots, want %d and %d", accounts, slots, 64, 22*8)
+	}
+}
+
+// Tests that verification detects flat entries diverging from the state tries.
+func TestVerifyCorruption(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(db ethdb.KeyValueWriter)
+	}{
+		{"storage", func(db ethdb.KeyValueWriter) {
+			rawdb.WriteStorageSnapshot(db, crypto.Keccak256Hash([]byte{0}), crypto.Keccak256Hash([]byte{0, 0}), encodeSlot(0xff))
+		}},
+		{"extra slot", func(db ethdb.KeyValueWriter) {
+			rawdb.WriteStorageSnapshot(db, crypto.Keccak256Hash([]byte{3}), common.Hash{0x01}, encodeSlot(1))
+		}},
+		{"missing account", func(db ethdb.KeyValueWriter) {
+			rawdb.DeleteAccountSnapshot(db, crypto.Keccak256Hash([]byte{1}))
+		}},
+	}
+	for _, tt := range tests {
+		state := makeTestState(16)
+		db, _, snaps, root := makeTestTree(t, state)
+
+		tt.corrupt(db)
+		if err := snaps.Verify(root); err == nil {
+			t.Errorf("%s: corrupted snapshot verified", tt.name)
+		}
+	}
+}
+
+// Tests that a generator interrupted by the flattening of a diff layer resumes
+// on the new disk layer and produces a consistent snapshot.
+func TestGenerationInterrupted(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	triedb := trie.NewDatabase(db)
+
+	state0 := makeTestState(128)
+	root0, _ := state0.commit(t, triedb)
+
+	state1 := state0.copy()
+	state1[crypto.Keccak256Hash([]byte{0})].balance = 1000
+	delete(state1, crypto.Keccak256Hash([]byte{3}))
+	state1[crypto.Keccak256Hash([]byte{6})].storage[common.Hash{0x06}] = encodeSlot(6)
+	root1, diff := diffStates(t, triedb, state0, state1)
+
+	snaps := New(db, triedb, 16, root0, true)
+	if err := snaps.Update(root1, root0, diff.destructs, diff.accounts, diff.storage); err != nil {
+		t.Fatalf("failed to update snapshot tree: %v", err)
+	}
+	if err := snaps.Cap(root1, 0); err != nil {
+		t.Fatalf("failed to cap snapshot tree: %v", err)
+	}
+	snaps.waitBuild()
+
+	checkSnapshot(t, snaps.Snapshot(root1), state1, []common.Hash{crypto.Keccak256Hash([]byte{3})})
+	if err := snaps.Verify(root1); err != nil {
+		t.Fatalf("verification failed: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/crypto"
+)
+
+// Tests that the diff layers are persisted into the journal and loaded back on
+// the next startup, and that a journal not matching the head is discarded.
+func TestJournal(t *testing.T) {
+	state0 := makeTestState(32)
+	db, triedb, snaps, root0 := makeTestTree(t, state0)
+
+	state1 := state0.copy()
+	state1[crypto.Keccak256Hash([]byte{0})].balance = 1000
+	delete(state1, crypto.Keccak256Hash([]byte{3}))
+	root1, diff1 := diffStates(t, triedb, state0, state1)
+
+	state2 := state1.copy()
+	delete(state2[crypto.Keccak256Hash([]byte{6})].storage, crypto.Keccak256Hash([]byte{6, 0}))
+	root2, diff2 := diffStates(t, triedb, state1, state2)
+
+	if err := snaps.Update(root1, root0, diff1.destructs, diff1.accounts, diff1.storage); err != nil {
+		t.Fatalf("failed to update snapshot tree: %v", err)
+	}
+	if err := snaps.Update(root2, root1, diff2.destructs, diff2.accounts, diff2.storage); err != nil {
+		t.Fatalf("failed to update snapshot tree: %v", err)
+	}
+	if _, err := snaps.Journal(root2); err != nil {
+		t.Fatalf("failed to journal snapshot tree: %v", err)
+	}
+	// Journalling twice (as done on shutdown) must not fail
+	if _, err := snaps.Journal(root2); err != nil {
+		t.Fatalf("failed to journal snapshot tree twice: %v", err)
+	}
+	loaded := New(db, triedb, 16, root2, false)
+	for _, root := range []common.Hash{root0, root1, root2} {
+		if loaded.Snapshot(root) == nil {
+			t.Fatalf("layer %x missing after reload", root)
+		}
+	}
+	checkSnapshot(t, loaded.Snapshot(root1), state1, nil)
+	checkSnapshot(t, loaded.Snapshot(root2), state2, nil)
+	if err := loaded.Verify(root2); err != nil {
+		t.Fatalf("verification failed after reload: %v", err)
+	}
+	// Loading the journal for a different head must rebuild the snapshot
+	rebuilt := New(db, triedb, 16, root1, false)
+	if rebuilt.Snapshot(root2) != nil {
+		t.Fatalf("mismatching journal loaded")
+	}
+	checkSnapshot(t, rebuilt.Snapshot(root1), state1, nil)
+	if err := rebuilt.Verify(root1); err != nil {
+		t.Fatalf("verification failed after rebuild: %v", err)
+	}
+}
@@ -0,0 +1,331 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/core/rawdb"
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/ethdb"
+	"github.com/69th-byte/sdexchain/rlp"
+	"github.com/69th-byte/sdexchain/trie"
+)
+
+// testAccount is the content of an account of a test state.
+type testAccount struct {
+	balance int64
+	storage map[common.Hash][]byte // Encoded slot values keyed by slot hash
+}
+
+// testState is a test state keyed by account hash.
+type testState map[common.Hash]*testAccount
+
+// copy returns a deep copy of the state.
+func (s testState) copy() testState {
+	cpy := make(testState)
+	for hash, account := range s {
+		storage := make(map[common.Hash][]byte)
+		for slot, blob := range account.storage {
+			storage[slot] = blob
+		}
+		cpy[hash] = &testAccount{balance: account.balance, storage: storage}
+	}
+	return cpy
+}
+
+// encodeSlot encodes a storage slot value the way the storage tries do.
+func encodeSlot(value byte) []byte {
+	blob, _ := rlp.EncodeToBytes([]byte{value})
+	return blob
+}
+
+// makeTestState creates a state of a number of accounts, every third of them
+// having a few storage slots.
+func makeTestState(accounts int) testState {
+	state := make(testState)
+	for i := 0; i < accounts; i++ {
+		account := &testAccount{balance: int64(i + 1), storage: make(map[common.Hash][]byte)}
+		if i%3 == 0 {
+			for j := 0; j < 8; j++ {
+				account.storage[crypto.Keccak256Hash([]byte{byte(i), byte(j)})] = encodeSlot(byte(j + 1))
+			}
+		}
+		state[crypto.Keccak256Hash([]byte{byte(i)})] = account
+	}
+	return state
+}
+
+// commit writes the account and storage tries of the state into the trie
+// database, returning the state root and the encoded accounts.
+func (s testState) commit(t *testing.T, triedb *trie.Database) (common.Hash, map[common.Hash][]byte) {
+	accTrie, _ := trie.New(common.Hash{}, triedb)
+	blobs := make(map[common.Hash][]byte)
+	for hash, account := range s {
+		stTrie, _ := trie.New(common.Hash{}, triedb)
+		for slot, blob := range account.storage {
+			stTrie.Update(slot[:], blob)
+		}
+		stRoot, err := stTrie.Commit(nil)
+		if err != nil {
+			t.Fatalf("failed to commit storage trie: %v", err)
+		}
+		blob, _ := rlp.EncodeToBytes(Account{
+			Balance:  big.NewInt(account.balance),
+			Root:     stRoot,
+			CodeHash: crypto.Keccak256(nil),
+		})
+		accTrie.Update(hash[:], blob)
+		blobs[hash] = blob
+	}
+	root, err := accTrie.Commit(nil)
+	if err != nil {
+		t.Fatalf("failed to commit account trie: %v", err)
+	}
+	return root, blobs
+}
+
+// testDiff is the set of changes between two test states, as pushed into the
+// snapshot tree.
+type testDiff struct {
+	destructs map[common.Hash]struct{}
+	accounts  map[common.Hash][]byte
+	storage   map[common.Hash]map[common.Hash][]byte
+}
+
+// diffStates collects the changes from the old state to the new one. The tries
+// of both states are committed into the trie database.
+func diffStates(t *testing.T, triedb *trie.Database, old, new testState) (common.Hash, *testDiff) {
+	_, oldBlobs := old.commit(t, triedb)
+	root, newBlobs := new.commit(t, triedb)
+
+	diff := &testDiff{
+		destructs: make(map[common.Hash]struct{}),
+		accounts:  make(map[common.Hash][]byte),
+		storage:   make(map[common.Hash]map[common.Hash][]byte),
+	}
+	for hash := range old {
+		if _, ok := new[hash]; !ok {
+			diff.destructs[hash] = struct{}{}
+		}
+	}
+	for hash, account := range new {
+		if !bytes.Equal(oldBlobs[hash], newBlobs[hash]) {
+			diff.accounts[hash] = newBlobs[hash]
+		}
+		slots := make(map[common.Hash][]byte)
+		if prev, ok := old[hash]; ok {
+			for slot := range prev.storage {
+				if _, ok := account.storage[slot]; !ok {
+					slots[slot] = nil
+				}
+			}
+		}
+		for slot, blob := range account.storage {
+			if prev, ok := old[hash]; !ok || !bytes.Equal(prev.storage[slot], blob) {
+				slots[slot] = blob
+			}
+		}
+		if len(slots) > 0 {
+			diff.storage[hash] = slots
+		}
+	}
+	return root, diff
+}
+
+// checkSnapshot checks that the snapshot serves the accounts and storage slots
+// of the given state.
+func checkSnapshot(t *testing.T, snap Snapshot, state testState, removed []common.Hash) {
+	t.Helper()
+
+	for hash, account := range state {
+		acc, err := snap.Account(hash)
+		if err != nil {
+			t.Fatalf("account %x: failed to retrieve: %v", hash, err)
+		}
+		if acc == nil || acc.Balance.Int64() != account.balance {
+			t.Fatalf("account %x: balance mismatch: have %v, want %d", hash, acc, account.balance)
+		}
+		for slot, blob := range account.storage {
+			have, err := snap.Storage(hash, slot)
+			if err != nil {
+				t.Fatalf("account %x slot %x: failed to retrieve: %v", hash, slot, err)
+			}
+			if !bytes.Equal(have, blob) {
+				t.Fatalf("account %x slot %x: value mismatch: have %x, want %x", hash, slot, have, blob)
+			}
+		}
+	}
+	for _, hash := range removed {
+		if acc, err := snap.Account(hash); err != nil || acc != nil {
+			t.Fatalf("removed account %x: have %v, %v", hash, acc, err)
+		}
+	}
+}
+
+// makeTestTree creates a snapshot tree, with a fully generated disk layer for
+// the given state, on top of a fresh database.
+func makeTestTree(t *testing.T, state testState) (ethdb.Database, *trie.Database, *Tree, common.Hash) {
+	db := rawdb.NewMemoryDatabase()
+	triedb := trie.NewDatabase(db)
+
+	root, _ := state.commit(t, triedb)
+	return db, triedb, New(db, triedb, 16, root, false), root
+}
+
+// Tests that the snapshot tree serves the changes of the diff layers on top of
+// the disk layer, and that they are flattened into the disk layer when capped.
+func TestDiffLayers(t *testing.T) {
+	state0 := makeTestState(32)
+	db, triedb, snaps, root0 := makeTestTree(t, state0)
+
+	// Modify an account, destruct an account with storage, change some slots and
+	// recreate an account with a fresh storage
+	var (
+		modified  = crypto.Keccak256Hash([]byte{1})
+		destroyed = crypto.Keccak256Hash([]byte{3})
+		changed   = crypto.Keccak256Hash([]byte{6})
+		recreated = crypto.Keccak256Hash([]byte{9})
+		created   = crypto.Keccak256Hash([]byte{100})
+	)
+	state1 := state0.copy()
+	state1[modified].balance = 1000
+	delete(state1, destroyed)
+	state1[changed].storage[crypto.Keccak256Hash([]byte{6, 0})] = encodeSlot(0xff)
+	delete(state1[changed].storage, crypto.Keccak256Hash([]byte{6, 1}))
+	state1[created] = &testAccount{balance: 1, storage: map[common.Hash][]byte{{0x01}: encodeSlot(1)}}
+	root1, diff1 := diffStates(t, triedb, state0, state1)
+
+	state2 := state1.copy()
+	state2[recreated] = &testAccount{balance: 5, storage: map[common.Hash][]byte{{0x02}: encodeSlot(2)}}
+	state2[created].storage[common.Hash{0x03}] = encodeSlot(3)
+	root2, diff2 := diffStates(t, triedb, state1, state2)
+	diff2.destructs[recreated] = struct{}{}
+	diff2.storage[recreated] = state2[recreated].storage
+
+	if err := snaps.Update(root1, root0, diff1.destructs, diff1.accounts, diff1.storage); err != nil {
+		t.Fatalf("failed to update snapshot tree: %v", err)
+	}
+	if err := snaps.Update(root2, root1, diff2.destructs, diff2.accounts, diff2.storage); err != nil {
+		t.Fatalf("failed to update snapshot tree: %v", err)
+	}
+	if err := snaps.Update(root1, root0, nil, nil, nil); err != nil {
+		t.Fatalf("failed to update snapshot tree with an existing layer: %v", err)
+	}
+	checkSnapshot(t, snaps.Snapshot(root0), state0, nil)
+	checkSnapshot(t, snaps.Snapshot(root1), state1, []common.Hash{destroyed})
+	checkSnapshot(t, snaps.Snapshot(root2), state2, []common.Hash{destroyed})
+
+	if blob, _ := snaps.Snapshot(root1).Storage(changed, crypto.Keccak256Hash([]byte{6, 1})); len(blob) != 0 {
+		t.Fatalf("deleted slot served: %x", blob)
+	}
+	if blob, _ := snaps.Snapshot(root2).Storage(recreated, crypto.Keccak256Hash([]byte{9, 0})); len(blob) != 0 {
+		t.Fatalf("slot of recreated account served: %x", blob)
+	}
+	for _, root := range []common.Hash{root0, root1, root2} {
+		if err := snaps.Verify(root); err != nil {
+			t.Fatalf("snapshot %x: verification failed: %v", root, err)
+		}
+	}
+	// Flatten the bottom layer, the original disk layer must become stale
+	disk := snaps.Snapshot(root0)
+	if err := snaps.Cap(root2, 1); err != nil {
+		t.Fatalf("failed to cap snapshot tree: %v", err)
+	}
+	if snaps.Snapshot(root0) != nil {
+		t.Fatalf("flattened disk layer still available")
+	}
+	if _, err := disk.Account(modified); err != ErrSnapshotStale {
+		t.Fatalf("stale disk layer read: have %v, want %v", err, ErrSnapshotStale)
+	}
+	if have := rawdb.ReadSnapshotRoot(db); have != root1 {
+		t.Fatalf("disk layer root mismatch: have %x, want %x", have, root1)
+	}
+	checkSnapshot(t, snaps.Snapshot(root1), state1, []common.Hash{destroyed})
+	checkSnapshot(t, snaps.Snapshot(root2), state2, []common.Hash{destroyed})
+	if err := snaps.Verify(root2); err != nil {
+		t.Fatalf("verification failed after cap: %v", err)
+	}
+	// Flatten everything, the disk layer must hold the entire head state
+	if err := snaps.Cap(root2, 0); err != nil {
+		t.Fatalf("failed to cap snapshot tree: %v", err)
+	}
+	if _, ok := snaps.Snapshot(root2).(*diskLayer); !ok {
+		t.Fatalf("head not flattened into the disk layer")
+	}
+	if snaps.Snapshot(root1) != nil {
+		t.Fatalf("flattened diff layer still available")
+	}
+	if blob := rawdb.ReadAccountSnapshot(db, destroyed); len(blob) != 0 {
+		t.Fatalf("destructed account still on disk")
+	}
+	if blob := rawdb.ReadStorageSnapshot(db, destroyed, crypto.Keccak256Hash([]byte{3, 0})); len(blob) != 0 {
+		t.Fatalf("slot of destructed account still on disk")
+	}
+	checkSnapshot(t, snaps.Snapshot(root2), state2, []common.Hash{destroyed})
+	if err := snaps.Verify(root2); err != nil {
+		t.Fatalf("verification failed after flattening: %v", err)
+	}
+}
+
+// Tests that flattening a diff layer drops the forks built on its siblings,
+// while keeping the ones built on top of itself.
+func TestDiffLayerForks(t *testing.T) {
+	state0 := makeTestState(16)
+	_, triedb, snaps, root0 := makeTestTree(t, state0)
+
+	fork := func(parent testState, balance int64) (testState, common.Hash, *testDiff) {
+		state := parent.copy()
+		state[crypto.Keccak256Hash([]byte{0})].balance = balance
+		root, diff := diffStates(t, triedb, parent, state)
+		return state, root, diff
+	}
+	stateA, rootA, diffA := fork(state0, 100)
+	stateB, rootB, diffB := fork(state0, 200)
+	stateA1, rootA1, diffA1 := fork(stateA, 300)
+	stateA2, rootA2, diffA2 := fork(stateA, 400)
+	_, rootB1, diffB1 := fork(stateB, 500)
+
+	for _, update := range []struct {
+		root, parent common.Hash
+		diff         *testDiff
+	}{
+		{rootA, root0, diffA}, {rootB, root0, diffB},
+		{rootA1, rootA, diffA1}, {rootA2, rootA, diffA2}, {rootB1, rootB, diffB1},
+	} {
+		if err := snaps.Update(update.root, update.parent, update.diff.destructs, update.diff.accounts, update.diff.storage); err != nil {
+			t.Fatalf("failed to update snapshot tree: %v", err)
+		}
+	}
+	staleB := snaps.Snapshot(rootB1)
+	if err := snaps.Cap(rootA1, 1); err != nil {
+		t.Fatalf("failed to cap snapshot tree: %v", err)
+	}
+	for _, root := range []common.Hash{root0, rootB, rootB1} {
+		if snaps.Snapshot(root) != nil {
+			t.Errorf("layer %x of a dropped fork still available", root)
+		}
+	}
+	if _, err := staleB.Account(crypto.Keccak256Hash([]byte{0})); err != ErrSnapshotStale {
+		t.Errorf("dropped fork read: have %v, want %v", err, ErrSnapshotStale)
+	}
+	checkSnapshot(t, snaps.Snapshot(rootA1), stateA1, nil)
+	checkSnapshot(t, snaps.Snapshot(rootA2), stateA2, nil)
+}
@@ -0,0 +1,180 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/core/rawdb"
+	"github.com/69th-byte/sdexchain/ethdb/memorydb"
+	"github.com/69th-byte/sdexchain/rlp"
+	"github.com/69th-byte/sdexchain/trie"
+)
+
+// errNotGenerated is returned when verifying a snapshot whose disk layer is
+// still being generated.
+var errNotGenerated = errors.New("snapshot not fully generated yet")
+
+// Verify checks the flat accounts and storage slots of the snapshot of the given
+// root against the state tries: the tries rebuilt from the snapshot entries must
+// hash to the storage roots of the accounts and to the root itself. The disk
+// layer of the snapshot must be fully generated.
+//
+// The tries are rebuilt in memory, verification is meant to be an offline check
+// of the snapshot consistency.
+func (t *Tree) Verify(root common.Hash) error {
+	snap, ok := t.Snapshot(root).(snapshot)
+	if !ok {
+		return fmt.Errorf("snapshot [%#x] missing", root)
+	}
+	// Collect the diff layers on top of the disk layer
+	var diffs []*diffLayer
+	layer := snap
+	for {
+		diff, ok := layer.(*diffLayer)
+		if !ok {
+			break
+		}
+		diffs = append(diffs, diff)
+		layer = diff.Parent()
+	}
+	base := layer.(*diskLayer)
+
+	base.lock.RLock()
+	stale, generating := base.stale, base.genMarker != nil
+	base.lock.RUnlock()
+
+	if stale {
+		return ErrSnapshotStale
+	}
+	if generating {
+		return errNotGenerated
+	}
+	// Merge the diff layers bottom-up into a single set of overrides
+	var (
+		destructs = make(map[common.Hash]struct{})
+		accounts  = make(map[common.Hash][]byte)
+		storage   = make(map[common.Hash]map[common.Hash][]byte)
+	)
+	for i := len(diffs) - 1; i >= 0; i-- {
+		diff := diffs[i]
+
+		diff.lock.RLock()
+		for hash := range diff.destructSet {
+			destructs[hash] = struct{}{}
+			accounts[hash] = nil
+			delete(storage, hash)
+		}
+		for hash, blob := range diff.accountData {
+			accounts[hash] = blob
+		}
+		for hash, slots := range diff.storageData {
+			if storage[hash] == nil {
+				storage[hash] = make(map[common.Hash][]byte)
+			}
+			for slot, blob := range slots {
+				storage[hash][slot] = blob
+			}
+		}
+		diff.lock.RUnlock()
+	}
+	// Rebuild the account trie, checking the storage of every account
+	accTrie, _ := trie.New(common.Hash{}, trie.NewDatabase(memorydb.New()))
+
+	addAccount := func(hash common.Hash, blob []byte) error {
+		var account Account
+		if err := rlp.DecodeBytes(blob, &account); err != nil {
+			return fmt.Errorf("invalid account %#x: %v", hash, err)
+		}
+		_, destructed := destructs[hash]
+		storageRoot, err := base.storageRoot(hash, destructed, storage[hash])
+		if err != nil {
+			return err
+		}
+		if storageRoot != account.Root {
+			return fmt.Errorf("storage root mismatch for account %#x: have %#x, want %#x", hash, storageRoot, account.Root)
+		}
+		accTrie.Update(hash[:], blob)
+		return nil
+	}
+	it := base.diskdb.NewIterator(rawdb.SnapshotAccountPrefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(rawdb.SnapshotAccountPrefix)+common.HashLength {
+			continue
+		}
+		hash := common.BytesToHash(key[len(rawdb.SnapshotAccountPrefix):])
+		if _, ok := accounts[hash]; ok {
+			continue
+		}
+		if err := addAccount(hash, common.CopyBytes(it.Value())); err != nil {
+			return err
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	for hash, blob := range accounts {
+		if len(blob) == 0 {
+			continue
+		}
+		if err := addAccount(hash, blob); err != nil {
+			return err
+		}
+	}
+	if have := accTrie.Hash(); have != root {
+		return fmt.Errorf("state root mismatch: have %#x, want %#x", have, root)
+	}
+	return nil
+}
+
+// storageRoot rebuilds the storage trie of an account from the persisted slots
+// overridden by the given ones, returning its root hash. The persisted slots are
+// ignored if the account was destructed in a diff layer.
+func (dl *diskLayer) storageRoot(accountHash common.Hash, destructed bool, overrides map[common.Hash][]byte) (common.Hash, error) {
+	tr, _ := trie.New(common.Hash{}, trie.NewDatabase(memorydb.New()))
+
+	if !destructed {
+		it := rawdb.IterateStorageSnapshots(dl.diskdb, accountHash)
+		defer it.Release()
+
+		prefix := len(rawdb.SnapshotStoragePrefix) + common.HashLength
+		for it.Next() {
+			key := it.Key()
+			if len(key) != prefix+common.HashLength {
+				continue
+			}
+			if _, ok := overrides[common.BytesToHash(key[prefix:])]; ok {
+				continue
+			}
+			tr.Update(key[prefix:], common.CopyBytes(it.Value()))
+		}
+		if err := it.Error(); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	for slot, blob := range overrides {
+		if len(blob) > 0 {
+			tr.Update(slot[:], blob)
+		}
+	}
+	return tr.Hash(), nil
+}
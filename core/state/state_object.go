@@ -88,6 +88,12 @@ type stateObject struct {
 	touched   bool
 	deleted   bool
 	onDirty   func(addr common.Address) // Callback method to mark a state object newly dirty
+
+	// Snapshot flags.
+	// The storage of an object created in this state can't be read from the
+	// snapshot, which holds the storage of the account it replaced (if any).
+	created       bool // true if the object was created in this state
+	snapRecreated bool // true if the recreation was recorded for the snapshot
 }
 
 // empty returns whether the account is considered empty.
@@ -168,10 +174,38 @@ func (c *stateObject) getTrie(db Database) Trie {
 	return c.trie
 }
 
+// snapSlot retrieves the encoded value of a storage slot from the state snapshot.
+// It reports false if the snapshot can't serve the slot: it is unavailable, not
+// generated that far yet or the slot was modified since.
+func (self *stateObject) snapSlot(key common.Hash) ([]byte, bool) {
+	db := self.db
+	if db.snap == nil || self.created {
+		return nil, false
+	}
+	if _, ok := db.snapDestructs[self.addrHash]; ok {
+		return nil, false
+	}
+	slot := crypto.Keccak256Hash(key[:])
+	if _, ok := db.snapStorage[self.addrHash][slot]; ok {
+		return nil, false
+	}
+	enc, err := db.snap.Storage(self.addrHash, slot)
+	return enc, err == nil
+}
+
+// readStorage retrieves the encoded value of a storage slot, from the state
+// snapshot if it can serve it, or from the storage trie otherwise.
+func (self *stateObject) readStorage(db Database, key common.Hash) ([]byte, error) {
+	if enc, ok := self.snapSlot(key); ok {
+		return enc, nil
+	}
+	return self.getTrie(db).TryGet(key[:])
+}
+
 func (self *stateObject) GetCommittedState(db Database, key common.Hash) common.Hash {
 	value := common.Hash{}
 	// Load from DB in case it is missing.
-	enc, err := self.getTrie(db).TryGet(key[:])
+	enc, err := self.readStorage(db, key)
 	if err != nil {
 		self.setError(err)
 		return common.Hash{}
@@ -192,7 +226,7 @@ func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
 		return value
 	}
 	// Load from DB in case it is missing.
-	enc, err := self.getTrie(db).TryGet(key[:])
+	enc, err := self.readStorage(db, key)
 	if err != nil {
 		self.setError(err)
 		return common.Hash{}
@@ -233,15 +267,31 @@ func (self *stateObject) setState(key, value common.Hash) {
 // updateTrie writes cached storage modifications into the object's storage trie.
 func (self *stateObject) updateTrie(db Database) Trie {
 	tr := self.getTrie(db)
+
+	// Track the written slots for the snapshot
+	var storage map[common.Hash][]byte
+	if self.db.snap != nil && len(self.dirtyStorage) > 0 {
+		self.db.snapRecreate(self)
+		if storage = self.db.snapStorage[self.addrHash]; storage == nil {
+			storage = make(map[common.Hash][]byte)
+			self.db.snapStorage[self.addrHash] = storage
+		}
+	}
 	for key, value := range self.dirtyStorage {
 		delete(self.dirtyStorage, key)
 		if (value == common.Hash{}) {
 			self.setError(tr.TryDelete(key[:]))
+			if storage != nil {
+				storage[crypto.Keccak256Hash(key[:])] = nil
+			}
 			continue
 		}
 		// Encoding []byte cannot fail, ok to ignore the error.
 		v, _ := rlp.EncodeToBytes(bytes.TrimLeft(value[:], "\x00"))
 		self.setError(tr.TryUpdate(key[:], v))
+		if storage != nil {
+			storage[crypto.Keccak256Hash(key[:])] = v
+		}
 	}
 	return tr
 }
@@ -320,6 +370,8 @@ func (self *stateObject) deepCopy(db *StateDB, onDirty func(addr common.Address)
 	stateObject.suicided = self.suicided
 	stateObject.dirtyCode = self.dirtyCode
 	stateObject.deleted = self.deleted
+	stateObject.created = self.created
+	stateObject.snapRecreated = self.snapRecreated
 	return stateObject
 }
 
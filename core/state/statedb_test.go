@@ -32,6 +32,7 @@ import (
 	check "gopkg.in/check.v1"
 
 	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/core/state/snapshot"
 	"github.com/69th-byte/sdexchain/core/types"
 )
 
@@ -427,3 +428,116 @@ func (s *StateSuite) TestTouchDelete(c *check.C) {
 		c.Fatal("expected no dirty state object")
 	}
 }
+
+// Tests that the state is read through the snapshot tree, and that committing a
+// state opened with a snapshot keeps the snapshot consistent with the trie.
+func TestSnapshotReads(t *testing.T) {
+	diskdb := rawdb.NewMemoryDatabase()
+	db := NewDatabase(diskdb)
+
+	state, _ := New(common.Hash{}, db)
+	for i := byte(0); i < 16; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		state.AddBalance(addr, big.NewInt(int64(i)+1))
+		state.SetState(addr, common.Hash{i}, common.Hash{i + 1})
+	}
+	root, _ := state.Commit(false)
+	snaps := snapshot.New(diskdb, db.TrieDB(), 16, root, false)
+
+	state, _ = NewWithSnapshot(root, db, snaps)
+	for i := byte(0); i < 16; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		if have := state.GetBalance(addr); have.Int64() != int64(i)+1 {
+			t.Fatalf("account %d: balance mismatch: have %v, want %d", i, have, i+1)
+		}
+		if have := state.GetState(addr, common.Hash{i}); have != (common.Hash{i + 1}) {
+			t.Fatalf("account %d: slot mismatch: have %x, want %x", i, have, common.Hash{i + 1})
+		}
+	}
+	// Destruct, recreate and modify accounts, the snapshot must not serve stale data
+	state.Suicide(common.BytesToAddress([]byte{1}))
+	state.CreateAccount(common.BytesToAddress([]byte{2}))
+	state.SetState(common.BytesToAddress([]byte{3}), common.Hash{0xff}, common.Hash{0x01})
+	state.SetState(common.BytesToAddress([]byte{4}), common.Hash{4}, common.Hash{})
+
+	if have := state.GetState(common.BytesToAddress([]byte{2}), common.Hash{2}); have != (common.Hash{}) {
+		t.Fatalf("slot of recreated account served: %x", have)
+	}
+	cpy := state.Copy()
+	if have := cpy.GetState(common.BytesToAddress([]byte{2}), common.Hash{2}); have != (common.Hash{}) {
+		t.Fatalf("slot of recreated account served by copy: %x", have)
+	}
+	root, err := state.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if snaps.Snapshot(root) == nil {
+		t.Fatalf("snapshot of committed state missing")
+	}
+	if err := snaps.Verify(root); err != nil {
+		t.Fatalf("snapshot verification failed: %v", err)
+	}
+	// Reads through the snapshot must match the ones from the trie
+	snapState, _ := NewWithSnapshot(root, db, snaps)
+	trieState, _ := New(root, db)
+	for i := byte(0); i < 16; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		if have, want := snapState.Exist(addr), trieState.Exist(addr); have != want {
+			t.Fatalf("account %d: existence mismatch: have %v, want %v", i, have, want)
+		}
+		if have, want := snapState.GetBalance(addr), trieState.GetBalance(addr); have.Cmp(want) != 0 {
+			t.Fatalf("account %d: balance mismatch: have %v, want %v", i, have, want)
+		}
+		for _, key := range []common.Hash{{i}, {0xff}} {
+			if have, want := snapState.GetState(addr, key), trieState.GetState(addr, key); have != want {
+				t.Fatalf("account %d slot %x: mismatch: have %x, want %x", i, key, have, want)
+			}
+		}
+	}
+}
+
+// Tests that the changes of the access list are journaled and rolled back with
+// the state, and that the list is reset for the next transaction.
+func TestAccessList(t *testing.T) {
+	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()))
+
+	var (
+		sender = common.HexToAddress("0x01")
+		dest   = common.HexToAddress("0x02")
+		listed = common.HexToAddress("0x03")
+		slot   = common.HexToHash("0x01")
+	)
+	state.PrepareAccessList(sender, &dest, nil, types.AccessList{{Address: listed, StorageKeys: []common.Hash{slot}}})
+	if !state.AddressInAccessList(sender) || !state.AddressInAccessList(dest) {
+		t.Fatal("sender and destination not warmed")
+	}
+	if addrOk, slotOk := state.SlotInAccessList(listed, slot); !addrOk || !slotOk {
+		t.Fatalf("listed slot not warmed: address %v, slot %v", addrOk, slotOk)
+	}
+	snap := state.Snapshot()
+
+	other := common.HexToAddress("0x04")
+	state.AddSlotToAccessList(other, slot)
+	state.AddSlotToAccessList(listed, common.HexToHash("0x02"))
+	if addrOk, slotOk := state.SlotInAccessList(other, slot); !addrOk || !slotOk {
+		t.Fatalf("added slot missing: address %v, slot %v", addrOk, slotOk)
+	}
+	state.RevertToSnapshot(snap)
+
+	if state.AddressInAccessList(other) {
+		t.Fatal("reverted address still warm")
+	}
+	if _, slotOk := state.SlotInAccessList(listed, common.HexToHash("0x02")); slotOk {
+		t.Fatal("reverted slot still warm")
+	}
+	if _, slotOk := state.SlotInAccessList(listed, slot); !slotOk {
+		t.Fatal("listed slot reverted")
+	}
+	if cpy := state.Copy(); !cpy.AddressInAccessList(listed) {
+		t.Fatal("access list not copied")
+	}
+	state.PrepareAccessList(dest, nil, nil, nil)
+	if state.AddressInAccessList(sender) || state.AddressInAccessList(listed) {
+		t.Fatal("access list not reset for the next transaction")
+	}
+}
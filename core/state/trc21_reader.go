@@ -3,6 +3,7 @@ package state
 import (
 	"bytes"
 	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/core/types"
 	"github.com/hashicorp/golang-lru"
 	"math/big"
 )
@@ -139,6 +140,16 @@ func ValidateTRC21Tx(statedb *StateDB, from common.Address, token common.Address
 	return false
 }
 
+// TRC21FeeUsed returns the fee taken from the issuer capacity of a TRC21 token
+// for gas spent by a transaction paying its fee in that token.
+func TRC21FeeUsed(gas uint64, number *big.Int, baseFee *big.Int) *big.Int {
+	fee := new(big.Int).SetUint64(gas)
+	if number.Cmp(common.TIPTRC21Fee) > 0 {
+		fee = fee.Mul(fee, types.TRC21GasPrice(number, baseFee))
+	}
+	return fee
+}
+
 func UpdateTRC21Fee(statedb *StateDB, newBalance map[common.Address]*big.Int, totalFeeUsed *big.Int) {
 	if statedb == nil || len(newBalance) == 0 {
 		return
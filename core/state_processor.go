@@ -119,10 +119,7 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, tra
 		receipts = append(receipts, receipt)
 		allLogs = append(allLogs, receipt.Logs...)
 		if tokenFeeUsed {
-			fee := new(big.Int).SetUint64(gas)
-			if block.Header().Number.Cmp(common.TIPTRC21Fee) > 0 {
-				fee = fee.Mul(fee, common.TRC21GasPrice)
-			}
+			fee := state.TRC21FeeUsed(gas, block.Header().Number, block.Header().BaseFee)
 			balanceFee[*tx.To()] = new(big.Int).Sub(balanceFee[*tx.To()], fee)
 			balanceUpdated[*tx.To()] = balanceFee[*tx.To()]
 			totalFeeUsed = totalFeeUsed.Add(totalFeeUsed, fee)
@@ -200,10 +197,7 @@ func (p *StateProcessor) ProcessBlockNoValidator(cBlock *CalculatedBlock, stated
 		receipts[i] = receipt
 		allLogs = append(allLogs, receipt.Logs...)
 		if tokenFeeUsed {
-			fee := new(big.Int).SetUint64(gas)
-			if block.Header().Number.Cmp(common.TIPTRC21Fee) > 0 {
-				fee = fee.Mul(fee, common.TRC21GasPrice)
-			}
+			fee := state.TRC21FeeUsed(gas, block.Header().Number, block.Header().BaseFee)
 			balanceFee[*tx.To()] = new(big.Int).Sub(balanceFee[*tx.To()], fee)
 			balanceUpdated[*tx.To()] = balanceFee[*tx.To()]
 			totalFeeUsed = totalFeeUsed.Add(totalFeeUsed, fee)
@@ -220,6 +214,9 @@ func (p *StateProcessor) ProcessBlockNoValidator(cBlock *CalculatedBlock, stated
 // for the transaction, gas used and an error if the transaction failed,
 // indicating the block was invalid.
 func ApplyTransaction(config *params.ChainConfig, tokensFee map[common.Address]*big.Int, bc *BlockChain, author *common.Address, gp *GasPool, statedb *state.StateDB, tomoxState *tradingstate.TradingStateDB, header *types.Header, tx *types.Transaction, usedGas *uint64, cfg vm.Config) (*types.Receipt, uint64, error, bool) {
+	if err := ValidateTxType(tx, config.IsEIP2718(header.Number), config.IsEIP1559(header.Number)); err != nil {
+		return nil, 0, err, false
+	}
 	if tx.To() != nil && tx.To().String() == common.BlockSigners && config.IsTIPSigning(header.Number) {
 		return ApplySignTransaction(config, statedb, header, tx, usedGas)
 	}
@@ -243,7 +240,7 @@ func ApplyTransaction(config *params.ChainConfig, tokensFee map[common.Address]*
 			balanceFee = value
 		}
 	}
-	msg, err := tx.AsMessage(types.MakeSigner(config, header.Number), balanceFee, header.Number)
+	msg, err := tx.AsMessage(types.MakeSigner(config, header.Number), balanceFee, header.Number, header.BaseFee)
 	if err != nil {
 		return nil, 0, err, false
 	}
@@ -425,6 +422,7 @@ func ApplyTransaction(config *params.ChainConfig, tokensFee map[common.Address]*
 	// Create a new receipt for the transaction, storing the intermediate root and gas used by the tx
 	// based on the eip phase, we're passing wether the root touch-delete accounts.
 	receipt := types.NewReceipt(root, failed, *usedGas)
+	receipt.Type = tx.Type()
 	receipt.TxHash = tx.Hash()
 	receipt.GasUsed = gas
 	// if the transaction created a contract, store the creation address in the receipt.
@@ -462,6 +460,7 @@ func ApplySignTransaction(config *params.ChainConfig, statedb *state.StateDB, he
 	// Create a new receipt for the transaction, storing the intermediate root and gas used by the tx
 	// based on the eip phase, we're passing wether the root touch-delete accounts.
 	receipt := types.NewReceipt(root, false, *usedGas)
+	receipt.Type = tx.Type()
 	receipt.TxHash = tx.Hash()
 	receipt.GasUsed = 0
 	// if the transaction created a contract, store the creation address in the receipt.
@@ -486,6 +485,7 @@ func ApplyEmptyTransaction(config *params.ChainConfig, statedb *state.StateDB, h
 	// Create a new receipt for the transaction, storing the intermediate root and gas used by the tx
 	// based on the eip phase, we're passing wether the root touch-delete accounts.
 	receipt := types.NewReceipt(root, false, *usedGas)
+	receipt.Type = tx.Type()
 	receipt.TxHash = tx.Hash()
 	receipt.GasUsed = 0
 	// if the transaction created a contract, store the creation address in the receipt.
@@ -499,6 +499,26 @@ func ApplyEmptyTransaction(config *params.ChainConfig, statedb *state.StateDB, h
 	return receipt, 0, nil, false
 }
 
+// ValidateTxType checks that the transaction type is activated, and after the
+// EIP-2718 fork, that the system contracts are only called by system transactions
+// and that system transactions only call system contracts.
+func ValidateTxType(tx *types.Transaction, eip2718 bool, eip1559 bool) error {
+	if !eip2718 {
+		if tx.Type() != types.LegacyTxType {
+			return ErrTxTypeNotSupported
+		}
+		return nil
+	}
+	if !eip1559 && tx.Type() == types.DynamicFeeTxType {
+		return ErrTxTypeNotSupported
+	}
+	system := tx.To() != nil && types.IsSystemAddress(*tx.To())
+	if system != tx.IsSystemTransaction() {
+		return ErrSystemTxMisuse
+	}
+	return nil
+}
+
 func InitSignerInTransactions(config *params.ChainConfig, header *types.Header, txs types.Transactions) {
 	nWorker := runtime.NumCPU()
 	signer := types.MakeSigner(config, header.Number)
@@ -0,0 +1,64 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/consensus/ethash"
+	"github.com/69th-byte/sdexchain/core/rawdb"
+	"github.com/69th-byte/sdexchain/core/state"
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/core/vm"
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/params"
+)
+
+// Tests that blocks with dynamic fee transactions of black-listed senders are
+// rejected, the same as with legacy transactions.
+func TestProcessDynamicFeeBlacklist(t *testing.T) {
+	config := *params.TestChainConfig
+	config.EIP1559Block = big.NewInt(0)
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	tx, err := types.SignTx(types.NewTx(&types.DynamicFeeTx{
+		ChainID:   config.ChainId,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(1),
+		Gas:       params.TxGas,
+		To:        &common.Address{0x01},
+		Value:     big.NewInt(0),
+	}), types.LatestSigner(&config), key)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	header := &types.Header{Number: new(big.Int).SetUint64(common.BlackListHFNumber), GasLimit: params.TxGas}
+	block := types.NewBlock(header, types.Transactions{tx}, nil, nil)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+
+	common.Blacklist[from] = true
+	defer delete(common.Blacklist, from)
+
+	processor := NewStateProcessor(&config, nil, ethash.NewFaker())
+	if _, _, _, err := processor.Process(block, statedb, nil, vm.Config{}, nil); err == nil || !strings.Contains(err.Error(), "black-list") {
+		t.Errorf("black-listed sender error mismatch: have %v", err)
+	}
+}
@@ -22,6 +22,8 @@ import (
 	"math/big"
 
 	"github.com/69th-byte/sdexchain/common"
+	cmath "github.com/69th-byte/sdexchain/common/math"
+	"github.com/69th-byte/sdexchain/core/types"
 	"github.com/69th-byte/sdexchain/core/vm"
 	"github.com/69th-byte/sdexchain/log"
 	"github.com/69th-byte/sdexchain/params"
@@ -67,17 +69,20 @@ type Message interface {
 	To() *common.Address
 
 	GasPrice() *big.Int
+	GasFeeCap() *big.Int
+	GasTipCap() *big.Int
 	Gas() uint64
 	Value() *big.Int
 
 	Nonce() uint64
 	CheckNonce() bool
 	Data() []byte
+	AccessList() types.AccessList
 	BalanceTokenFee() *big.Int
 }
 
 // IntrinsicGas computes the 'intrinsic gas' for a message with the given data.
-func IntrinsicGas(data []byte, contractCreation, homestead bool) (uint64, error) {
+func IntrinsicGas(data []byte, accessList types.AccessList, contractCreation, homestead bool) (uint64, error) {
 	// Set the starting gas for the raw transaction
 	var gas uint64
 	if contractCreation && homestead {
@@ -106,6 +111,10 @@ func IntrinsicGas(data []byte, contractCreation, homestead bool) (uint64, error)
 		}
 		gas += z * params.TxDataZeroGas
 	}
+	if accessList != nil {
+		gas += uint64(len(accessList)) * params.TxAccessListAddressGas
+		gas += uint64(accessList.StorageKeys()) * params.TxAccessListStorageKeyGas
+	}
 	return gas, nil
 }
 
@@ -178,7 +187,13 @@ func (st *StateTransition) buyGas() error {
 	)
 	mgval := new(big.Int).Mul(new(big.Int).SetUint64(st.msg.Gas()), st.gasPrice)
 	if balanceTokenFee == nil {
-		if state.GetBalance(from.Address()).Cmp(mgval) < 0 {
+		// The sender must be able to afford the fee cap, although only the
+		// effective gas price is charged.
+		balanceCheck := mgval
+		if st.evm.BaseFee != nil {
+			balanceCheck = new(big.Int).Mul(new(big.Int).SetUint64(st.msg.Gas()), st.msg.GasFeeCap())
+		}
+		if state.GetBalance(from.Address()).Cmp(balanceCheck) < 0 {
 			return errInsufficientBalanceForGas
 		}
 	} else if balanceTokenFee.Cmp(mgval) < 0 {
@@ -209,6 +224,18 @@ func (st *StateTransition) preCheck() error {
 			return ErrNonceTooLow
 		}
 	}
+	// Make sure that transaction gasFeeCap is greater than the baseFee (post EIP-1559).
+	// Calls and TRC21 token-paid transactions are priced by the node, and system
+	// transactions pay no gas at all.
+	if st.evm.BaseFee != nil && msg.CheckNonce() && st.balanceTokenFee() == nil &&
+		(msg.To() == nil || !types.IsSystemAddress(*msg.To())) {
+		if msg.GasFeeCap().Cmp(msg.GasTipCap()) < 0 {
+			return ErrTipAboveFeeCap
+		}
+		if msg.GasFeeCap().Cmp(st.evm.BaseFee) < 0 {
+			return ErrFeeCapTooLow
+		}
+	}
 	return st.buyGas()
 }
 
@@ -226,13 +253,18 @@ func (st *StateTransition) TransitionDb(owner common.Address) (ret []byte, usedG
 	contractCreation := msg.To() == nil
 
 	// Pay intrinsic gas
-	gas, err := IntrinsicGas(st.data, contractCreation, homestead)
+	gas, err := IntrinsicGas(st.data, msg.AccessList(), contractCreation, homestead)
 	if err != nil {
 		return nil, 0, false, err
 	}
 	if err = st.useGas(gas); err != nil {
 		return nil, 0, false, err
 	}
+	// Warm up the sender, the destination, the precompiles and the access list
+	// the intrinsic gas paid for (EIP-2929)
+	if rules := st.evm.ChainRules(); rules.IsEIP2718 {
+		st.state.PrepareAccessList(msg.From(), msg.To(), vm.ActivePrecompileAddresses(rules), msg.AccessList())
+	}
 
 	var (
 		evm = st.evm
@@ -266,17 +298,33 @@ func (st *StateTransition) TransitionDb(owner common.Address) (ret []byte, usedG
 	}
 	st.refundGas()
 
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), st.gasPrice)
+	if st.evm.BaseFee != nil {
+		fee.Sub(fee, st.payBaseFee())
+	}
 	if st.evm.BlockNumber.Cmp(common.TIPTRC21Fee) > 0 {
 		if (owner != common.Address{}) {
-			st.state.AddBalance(owner, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), st.gasPrice))
+			st.state.AddBalance(owner, fee)
 		}
 	} else {
-		st.state.AddBalance(st.evm.Coinbase, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), st.gasPrice))
+		st.state.AddBalance(st.evm.Coinbase, fee)
 	}
 
 	return ret, st.gasUsed(), vmerr != nil, err
 }
 
+// payBaseFee takes the base fee part of the gas used out of the block reward,
+// and either burns it or pays it to the foundation wallet depending on the Posv
+// configuration. It returns the amount taken.
+func (st *StateTransition) payBaseFee() *big.Int {
+	baseFee := cmath.BigMin(st.evm.BaseFee, st.gasPrice)
+	amount := new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), baseFee)
+	if posv := st.evm.ChainConfig().Posv; posv != nil && !posv.BurnBaseFee {
+		st.state.AddBalance(posv.FoudationWalletAddr, amount)
+	}
+	return amount
+}
+
 func (st *StateTransition) refundGas() {
 	// Apply refund counter, capped to half of the used gas.
 	refund := st.gasUsed() / 2
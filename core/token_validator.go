@@ -22,6 +22,7 @@ import (
 	"github.com/69th-byte/sdexchain/consensus"
 	"github.com/69th-byte/sdexchain/contracts/tomox/contract"
 	"github.com/69th-byte/sdexchain/core/state"
+	"github.com/69th-byte/sdexchain/core/types"
 	"github.com/69th-byte/sdexchain/core/vm"
 	"github.com/69th-byte/sdexchain/log"
 	ethereum "github.com/tomochain/tomochain"
@@ -41,15 +42,18 @@ type callmsg struct {
 	ethereum.CallMsg
 }
 
-func (m callmsg) From() common.Address      { return m.CallMsg.From }
-func (m callmsg) Nonce() uint64             { return 0 }
-func (m callmsg) CheckNonce() bool          { return false }
-func (m callmsg) To() *common.Address       { return m.CallMsg.To }
-func (m callmsg) GasPrice() *big.Int        { return m.CallMsg.GasPrice }
-func (m callmsg) Gas() uint64               { return m.CallMsg.Gas }
-func (m callmsg) Value() *big.Int           { return m.CallMsg.Value }
-func (m callmsg) Data() []byte              { return m.CallMsg.Data }
-func (m callmsg) BalanceTokenFee() *big.Int { return m.CallMsg.BalanceTokenFee }
+func (m callmsg) From() common.Address         { return m.CallMsg.From }
+func (m callmsg) Nonce() uint64                { return 0 }
+func (m callmsg) CheckNonce() bool             { return false }
+func (m callmsg) To() *common.Address          { return m.CallMsg.To }
+func (m callmsg) GasPrice() *big.Int           { return m.CallMsg.GasPrice }
+func (m callmsg) GasFeeCap() *big.Int          { return m.CallMsg.GasPrice }
+func (m callmsg) GasTipCap() *big.Int          { return m.CallMsg.GasPrice }
+func (m callmsg) Gas() uint64                  { return m.CallMsg.Gas }
+func (m callmsg) Value() *big.Int              { return m.CallMsg.Value }
+func (m callmsg) Data() []byte                 { return m.CallMsg.Data }
+func (m callmsg) BalanceTokenFee() *big.Int    { return m.CallMsg.BalanceTokenFee }
+func (m callmsg) AccessList() types.AccessList { return m.CallMsg.AccessList }
 
 type SimulatedBackend interface {
 	CallContractWithState(call ethereum.CallMsg, chain consensus.ChainContext, statedb *state.StateDB) ([]byte, error)
@@ -85,7 +89,7 @@ func RunContract(chain consensus.ChainContext, statedb *state.StateDB, contractA
 	return unpackResult, nil
 }
 
-//FIXME: please use copyState for this function
+// FIXME: please use copyState for this function
 // CallContractWithState executes a contract call at the given state.
 func CallContractWithState(call ethereum.CallMsg, chain consensus.ChainContext, statedb *state.StateDB) ([]byte, error) {
 	// Ensure message is initialized properly.
@@ -217,3 +221,76 @@ func ValidateTokenDecimal(chain consensus.ChainContext, copyState *state.StateDB
 	}
 	return nil
 }
+
+// TokenSlotCheck is the result of validating one storage slot layout assumption of a TRC21 token
+type TokenSlotCheck struct {
+	Slot   uint64 `json:"slot"`
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// TokenCompatibility is the result of a dry-run of TomoX/TomoZ apply transaction checks against a token
+type TokenCompatibility struct {
+	Token           common.Address `json:"token"`
+	BlockNumber     uint64         `json:"blockNumber"`
+	Enforced        bool           `json:"enforced"` // false if the block is before TIPTomoX, apply transactions are not validated
+	BalanceSlot     TokenSlotCheck `json:"balanceSlot"`
+	MinFeeSlot      TokenSlotCheck `json:"minFeeSlot"`
+	Decimals        *uint8         `json:"decimals"`
+	DecimalsError   string         `json:"decimalsError,omitempty"`
+	IsTRC21         bool           `json:"isTRC21"`
+	FeeCapacity     *big.Int       `json:"feeCapacity"`
+	TomoXCompatible bool           `json:"tomoXCompatible"`
+	TomoZCompatible bool           `json:"tomoZCompatible"`
+}
+
+// tokenCheckChain pins the current header of chain to the block which tokens are checked against
+type tokenCheckChain struct {
+	consensus.ChainContext
+	header *types.Header
+}
+
+func (c *tokenCheckChain) CurrentHeader() *types.Header {
+	return c.header
+}
+
+// CheckTokenCompatibility runs the same checks as ValidateTomoXApplyTransaction and ValidateTomoZApplyTransaction
+// at the given block without changing statedb, and reports every failed slot layout assumption
+func CheckTokenCompatibility(chain consensus.ChainContext, header *types.Header, statedb *state.StateDB, tokenAddr common.Address) (*TokenCompatibility, error) {
+	contractABI, err := GetTokenAbi(contract.TRC21ABI)
+	if err != nil {
+		return nil, fmt.Errorf("CheckTokenCompatibility: cannot parse ABI. Err: %v", err)
+	}
+	chain = &tokenCheckChain{ChainContext: chain, header: header}
+	result := &TokenCompatibility{
+		Token:       tokenAddr,
+		BlockNumber: header.Number.Uint64(),
+		Enforced:    chain.Config().IsTIPTomoX(header.Number),
+		BalanceSlot: TokenSlotCheck{Slot: state.SlotTRC21Token["balances"], Passed: true},
+		MinFeeSlot:  TokenSlotCheck{Slot: state.SlotTRC21Token["minFee"], Passed: true},
+		FeeCapacity: new(big.Int),
+	}
+	// every check pokes random values into its own copy of state
+	if err := ValidateBalanceSlot(chain, statedb.Copy(), tokenAddr, contractABI); err != nil {
+		result.BalanceSlot.Passed = false
+		result.BalanceSlot.Error = err.Error()
+	}
+	if err := ValidateMinFeeSlot(chain, statedb.Copy(), tokenAddr, contractABI); err != nil {
+		result.MinFeeSlot.Passed = false
+		result.MinFeeSlot.Error = err.Error()
+	}
+	if err := ValidateTokenDecimal(chain, statedb.Copy(), tokenAddr, contractABI); err != nil {
+		result.DecimalsError = err.Error()
+	} else if decimals, err := RunContract(chain, statedb.Copy(), tokenAddr, contractABI, getDecimalFunction); err == nil {
+		if value, ok := decimals.(uint8); ok {
+			result.Decimals = &value
+		}
+	}
+	if capacity, ok := state.GetTRC21FeeCapacityFromState(statedb)[tokenAddr]; ok {
+		result.IsTRC21 = true
+		result.FeeCapacity = capacity
+	}
+	result.TomoXCompatible = result.BalanceSlot.Passed && result.DecimalsError == ""
+	result.TomoZCompatible = result.BalanceSlot.Passed && result.MinFeeSlot.Passed
+	return result, nil
+}
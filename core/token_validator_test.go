@@ -0,0 +1,88 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/consensus/ethash"
+	"github.com/69th-byte/sdexchain/core/rawdb"
+	"github.com/69th-byte/sdexchain/core/state"
+	"github.com/69th-byte/sdexchain/core/vm"
+	"github.com/69th-byte/sdexchain/params"
+)
+
+// balanceOnlyTokenCode returns sload(keccak256(calldata[4:36] . 0)) for every call,
+// so that balanceOf(addr) reads balances at slot 0 but minFee() doesn't read slot 1
+var balanceOnlyTokenCode = common.Hex2Bytes("600435600052600060205260406000205460005260206000f3")
+
+func TestCheckTokenCompatibility(t *testing.T) {
+	var (
+		token    = common.HexToAddress("0x0000000000000000000000000000000000000abc")
+		capacity = big.NewInt(1000000)
+		db       = rawdb.NewMemoryDatabase()
+	)
+	// register the token in TRC21 issuer with its fee capacity
+	tokensSlot := common.BigToHash(new(big.Int).SetUint64(state.SlotTRC21Issuer["tokens"]))
+	issuerStorage := map[common.Hash]common.Hash{
+		tokensSlot: common.BigToHash(big.NewInt(1)),
+		state.GetLocDynamicArrAtElement(tokensSlot, 0, 1):                                              token.Hash(),
+		common.BigToHash(state.GetLocMappingAtKey(token.Hash(), state.SlotTRC21Issuer["tokensState"])): common.BigToHash(capacity),
+	}
+	gspec := &Genesis{
+		Config: params.TestChainConfig,
+		Alloc: GenesisAlloc{
+			token:                 {Code: balanceOnlyTokenCode, Balance: new(big.Int)},
+			common.TRC21IssuerSMC: {Storage: issuerStorage, Balance: new(big.Int)},
+		},
+	}
+	gspec.MustCommit(db)
+	chain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+	statedb, err := chain.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := statedb.IntermediateRoot(false)
+
+	result, err := CheckTokenCompatibility(chain, chain.CurrentHeader(), statedb, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.BalanceSlot.Passed {
+		t.Errorf("balance slot check failed: %s", result.BalanceSlot.Error)
+	}
+	if result.MinFeeSlot.Passed || result.MinFeeSlot.Slot != state.SlotTRC21Token["minFee"] {
+		t.Errorf("minFee slot check should fail at slot %d, got %+v", state.SlotTRC21Token["minFee"], result.MinFeeSlot)
+	}
+	if result.Decimals == nil || *result.Decimals != 0 {
+		t.Errorf("invalid decimals %v, err %s", result.Decimals, result.DecimalsError)
+	}
+	if !result.IsTRC21 || result.FeeCapacity.Cmp(capacity) != 0 {
+		t.Errorf("invalid fee capacity: isTRC21 %v, capacity %v, want %v", result.IsTRC21, result.FeeCapacity, capacity)
+	}
+	if !result.TomoXCompatible || result.TomoZCompatible {
+		t.Errorf("invalid compatibility: tomox %v, tomoz %v", result.TomoXCompatible, result.TomoZCompatible)
+	}
+	if statedb.IntermediateRoot(false) != root {
+		t.Error("CheckTokenCompatibility changed the given state")
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/69th-byte/sdexchain/consensus"
+	"github.com/69th-byte/sdexchain/consensus/misc"
 	"math"
 	"math/big"
 	"sort"
@@ -218,6 +219,8 @@ type TxPool struct {
 	currentState  *state.StateDB      // Current state in the blockchain head
 	pendingState  *state.ManagedState // Pending state tracking virtual nonces
 	currentMaxGas uint64              // Current gas limit for transaction caps
+	pendingNumber *big.Int            // Number of the block the pending transactions go into
+	pendingFee    *big.Int            // Base fee of the pending block, nil before EIP-1559
 
 	locals  *accountSet // Set of local transaction to exempt from eviction rules
 	journal *txJournal  // Journal of local transaction to back up to disk
@@ -231,6 +234,8 @@ type TxPool struct {
 	wg sync.WaitGroup // for shutdown sync
 
 	homestead        bool
+	eip2718          bool // Fork indicator whether we are using EIP-2718 typed transactions
+	eip1559          bool // Fork indicator whether we are using EIP-1559 dynamic fee transactions
 	IsSigner         func(address common.Address) bool
 	trc21FeeCapacity map[common.Address]*big.Int
 }
@@ -246,7 +251,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		config:           config,
 		chainconfig:      chainconfig,
 		chain:            chain,
-		signer:           types.NewEIP155Signer(chainconfig.ChainId),
+		signer:           types.LatestSigner(chainconfig),
 		pending:          make(map[common.Address]*txList),
 		queue:            make(map[common.Address]*txList),
 		beats:            make(map[common.Address]time.Time),
@@ -434,6 +439,13 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.trc21FeeCapacity = state.GetTRC21FeeCapacityFromStateWithCache(newHead.Root, statedb)
 	pool.pendingState = state.ManageState(statedb)
 	pool.currentMaxGas = newHead.GasLimit
+	next := new(big.Int).Add(newHead.Number, big.NewInt(1))
+	pool.eip2718 = pool.chainconfig.IsEIP2718(next)
+	pool.eip1559 = pool.chainconfig.IsEIP1559(next)
+	pool.pendingNumber, pool.pendingFee = next, nil
+	if pool.eip1559 {
+		pool.pendingFee = misc.CalcBaseFee(pool.chainconfig, newHead)
+	}
 
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
@@ -595,6 +607,14 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		return fmt.Errorf("Reject transaction with receiver in black-list: %v", tx.To().Hex())
 	}
 
+	// Reject transaction types not activated yet, and misused system transactions
+	if err := ValidateTxType(tx, pool.eip2718, pool.eip1559); err != nil {
+		return err
+	}
+	// Ensure gasFeeCap is greater than or equal to gasTipCap.
+	if tx.GasFeeCap().Cmp(tx.GasTipCap()) < 0 {
+		return ErrTipAboveFeeCap
+	}
 	// Heuristic limit, reject transactions over 32KB to prevent DOS attacks
 	if tx.Size() > 32*1024 {
 		return ErrOversizedData
@@ -640,7 +660,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 			if !state.ValidateTRC21Tx(pool.pendingState.StateDB, from, *tx.To(), tx.Data()) {
 				return ErrInsufficientFunds
 			}
-			cost = tx.TRC21Cost()
+			cost = tx.TRC21Cost(pool.pendingNumber, pool.pendingFee)
 			minGasPrice = common.TRC21GasPrice
 		}
 	}
@@ -649,7 +669,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	}
 
 	if tx.To() == nil || (tx.To() != nil && !tx.IsSpecialTransaction()) {
-		intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, pool.homestead)
+		intrGas, err := IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, pool.homestead)
 		if err != nil {
 			return err
 		}
@@ -1065,7 +1085,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			pool.priced.Removed()
 		}
 		// Drop all transactions that are too costly (low balance or out of gas)
-		drops, _ := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas, pool.trc21FeeCapacity)
+		drops, _ := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas, pool.trc21FeeCapacity, pool.pendingNumber, pool.pendingFee)
 		for _, tx := range drops {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable queued transaction", "hash", hash)
@@ -1223,7 +1243,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			pool.priced.Removed()
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
-		drops, invalids := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas, pool.trc21FeeCapacity)
+		drops, invalids := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas, pool.trc21FeeCapacity, pool.pendingNumber, pool.pendingFee)
 		for _, tx := range drops {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
@@ -25,6 +25,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -95,12 +96,16 @@ func pricedTransaction(nonce uint64, gaslimit uint64, gasprice *big.Int, key *ec
 }
 
 func setupTxPool() (*TxPool, *ecdsa.PrivateKey) {
+	return setupTxPoolWithConfig(params.TestChainConfig)
+}
+
+func setupTxPoolWithConfig(config *params.ChainConfig) (*TxPool, *ecdsa.PrivateKey) {
 	diskdb := rawdb.NewMemoryDatabase()
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(diskdb))
 	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
 
 	key, _ := crypto.GenerateKey()
-	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	pool := NewTxPool(testTxPoolConfig, config, blockchain)
 
 	return pool, key
 }
@@ -979,6 +984,126 @@ func TestTransactionPendingLimiting(t *testing.T) {
 	}
 }
 
+// Tests that typed transactions are only accepted once EIP-2718 is activated,
+// and that afterwards the system contracts can only be called by system
+// transactions.
+func TestTransactionTypeActivation(t *testing.T) {
+	t.Parallel()
+
+	config := *params.TestChainConfig
+	config.EIP2718Block = nil
+
+	pool, key := setupTxPoolWithConfig(&config)
+	defer pool.Stop()
+
+	signer := types.LatestSignerForChainID(config.ChainId)
+	to := common.Address{0x01}
+	accessTx, _ := types.SignTx(types.NewTx(&types.AccessListTx{
+		ChainID:    config.ChainId,
+		GasPrice:   big.NewInt(common.DefaultMinGasPrice),
+		Gas:        100000,
+		To:         &to,
+		Value:      big.NewInt(0),
+		AccessList: types.AccessList{{Address: to, StorageKeys: []common.Hash{{0x01}}}},
+	}), signer, key)
+	from, _ := types.Sender(signer, accessTx)
+	balance := new(big.Int).Mul(accessTx.GasPrice(), big.NewInt(1000000))
+	pool.currentState.AddBalance(from, balance)
+
+	if err := pool.AddRemote(accessTx); err != ErrTxTypeNotSupported {
+		t.Errorf("pre-fork typed transaction error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+
+	pool, _ = setupTxPoolWithConfig(params.TestChainConfig)
+	defer pool.Stop()
+	pool.currentState.AddBalance(from, balance)
+
+	legacy, _ := types.SignTx(types.NewTransaction(0, common.HexToAddress(common.BlockSigners), big.NewInt(0), 100000, big.NewInt(0), nil), signer, key)
+	if err := pool.AddRemote(legacy); err != ErrSystemTxMisuse {
+		t.Errorf("legacy system call error mismatch: have %v, want %v", err, ErrSystemTxMisuse)
+	}
+	misused, _ := types.SignTx(types.NewSystemTransaction(config.ChainId, 0, to, 100000, nil), signer, key)
+	if err := pool.AddRemote(misused); err != ErrSystemTxMisuse {
+		t.Errorf("system transaction to a regular account error mismatch: have %v, want %v", err, ErrSystemTxMisuse)
+	}
+	if err := pool.AddRemote(accessTx); err != nil {
+		t.Errorf("failed to add access list transaction: %v", err)
+	}
+}
+
+// Tests that dynamic fee transactions are only accepted after the EIP-1559 fork,
+// and only with a tip within their fee cap.
+func TestDynamicFeeTransactionActivation(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	config := *params.TestChainConfig
+	config.EIP1559Block = big.NewInt(0)
+	signer := types.LatestSigner(&config)
+
+	dynamicTx := func(tip, feeCap int64) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTx(&types.DynamicFeeTx{
+			ChainID:   config.ChainId,
+			GasTipCap: big.NewInt(tip),
+			GasFeeCap: big.NewInt(feeCap),
+			Gas:       100000,
+			To:        &common.Address{0x01},
+			Value:     big.NewInt(0),
+		}), signer, key)
+		return tx
+	}
+	price := int64(common.DefaultMinGasPrice)
+	valid := dynamicTx(price, 2*price)
+	from, _ := types.Sender(signer, valid)
+	balance := new(big.Int).Mul(valid.GasFeeCap(), big.NewInt(1000000))
+	pool.currentState.AddBalance(from, balance)
+
+	if err := pool.AddRemote(valid); err != ErrTxTypeNotSupported {
+		t.Errorf("pre-fork dynamic fee transaction error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+
+	pool, _ = setupTxPoolWithConfig(&config)
+	defer pool.Stop()
+	pool.currentState.AddBalance(from, balance)
+
+	if err := pool.AddRemote(dynamicTx(2*price, price)); err != ErrTipAboveFeeCap {
+		t.Errorf("tip above fee cap error mismatch: have %v, want %v", err, ErrTipAboveFeeCap)
+	}
+	if err := pool.AddRemote(valid); err != nil {
+		t.Errorf("failed to add dynamic fee transaction: %v", err)
+	}
+}
+
+// Tests that the black-list applies to the senders of dynamic fee transactions.
+func TestDynamicFeeTransactionBlacklist(t *testing.T) {
+	config := *params.TestChainConfig
+	config.EIP1559Block = big.NewInt(0)
+
+	pool, key := setupTxPoolWithConfig(&config)
+	defer pool.Stop()
+
+	price := big.NewInt(int64(common.DefaultMinGasPrice))
+	tx, _ := types.SignTx(types.NewTx(&types.DynamicFeeTx{
+		ChainID:   config.ChainId,
+		GasTipCap: price,
+		GasFeeCap: price,
+		Gas:       100000,
+		To:        &common.Address{0x01},
+		Value:     big.NewInt(0),
+	}), types.LatestSigner(&config), key)
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(from, new(big.Int).Mul(price, big.NewInt(1000000)))
+
+	common.Blacklist[from] = true
+	defer delete(common.Blacklist, from)
+
+	if err := pool.AddRemote(tx); err == nil || !strings.Contains(err.Error(), "black-list") {
+		t.Errorf("black-listed sender error mismatch: have %v", err)
+	}
+}
+
 // Tests that the transaction limits are enforced the same way irrelevant whether
 // the transactions are added one by one or in batches.
 func TestTransactionQueueLimitingEquivalency(t *testing.T)   { testTransactionLimitingEquivalency(t, 1) }
@@ -59,3 +59,50 @@ func TestBlockEncoding(t *testing.T) {
 		t.Errorf("encoded block mismatch:\ngot:  %x\nwant: %x", ourBlockEnc, blockEnc)
 	}
 }
+
+// Tests that the base fee is an optional trailing header field, leaving the
+// encoding and hash of pre-EIP-1559 headers untouched.
+func TestHeaderBaseFeeEncoding(t *testing.T) {
+	header := &Header{
+		ParentHash: common.Hash{0x01},
+		Number:     big.NewInt(10),
+		GasLimit:   84000000,
+		Time:       big.NewInt(1500000000),
+		Difficulty: big.NewInt(1),
+		Extra:      []byte("test"),
+	}
+	legacy, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		t.Fatal("encode error: ", err)
+	}
+	var decoded Header
+	if err := rlp.DecodeBytes(legacy, &decoded); err != nil {
+		t.Fatal("decode error: ", err)
+	}
+	if decoded.BaseFee != nil {
+		t.Errorf("legacy header decoded with base fee %v", decoded.BaseFee)
+	}
+	if decoded.Hash() != header.Hash() {
+		t.Errorf("legacy header hash changed: have %x, want %x", decoded.Hash(), header.Hash())
+	}
+
+	withFee := CopyHeader(header)
+	withFee.BaseFee = big.NewInt(250000000)
+	enc, err := rlp.EncodeToBytes(withFee)
+	if err != nil {
+		t.Fatal("encode error: ", err)
+	}
+	if bytes.Equal(enc, legacy) || withFee.Hash() == header.Hash() {
+		t.Errorf("base fee not covered by the header encoding")
+	}
+	decoded = Header{}
+	if err := rlp.DecodeBytes(enc, &decoded); err != nil {
+		t.Fatal("decode error: ", err)
+	}
+	if decoded.BaseFee == nil || decoded.BaseFee.Cmp(withFee.BaseFee) != 0 {
+		t.Errorf("base fee mismatch: have %v, want %v", decoded.BaseFee, withFee.BaseFee)
+	}
+	if decoded.Hash() != withFee.Hash() {
+		t.Errorf("hash mismatch: have %x, want %x", decoded.Hash(), withFee.Hash())
+	}
+}
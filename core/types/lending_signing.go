@@ -78,7 +78,10 @@ func LendingSignTx(tx *LendingTransaction, s LendingSigner, prv *ecdsa.PrivateKe
 		[]byte("\x19Ethereum Signed Message:\n32"),
 		h.Bytes(),
 	)
-
+	// EIP-712 typed data hashes are signed as they are
+	if _, ok := s.(LendingTypedSigner); ok {
+		message = h.Bytes()
+	}
 	sig, err := crypto.Sign(message[:], prv)
 	if err != nil {
 		return nil, err
@@ -91,7 +94,7 @@ type LendingTxSigner struct{}
 
 // Equal compare two signer
 func (lendingsign LendingTxSigner) Equal(s2 LendingSigner) bool {
-	_, ok := s2.(LendingSigner)
+	_, ok := s2.(LendingTxSigner)
 	return ok
 }
 
@@ -205,9 +205,15 @@ func (tx *LendingTransaction) LendingTradeId() uint64 { return tx.data.LendingTr
 // SetLendingHash set hash of lending transaction hash
 func (tx *LendingTransaction) SetLendingHash(h common.Hash) { tx.data.Hash = h }
 
-// From get transaction from
+// From get transaction from. If the transaction was verified already, this is
+// the sender derived by the signer it was verified with, otherwise the legacy
+// signature scheme is assumed and EIP-712 signatures need LendingSender.
 func (tx *LendingTransaction) From() *common.Address {
 	if tx.data.V != nil {
+		if sc := tx.from.Load(); sc != nil {
+			from := sc.(lendingsigCache).from
+			return &from
+		}
 		signer := LendingTxSigner{}
 		if f, err := LendingSender(signer, tx); err != nil {
 			return nil
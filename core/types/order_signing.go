@@ -78,7 +78,10 @@ func OrderSignTx(tx *OrderTransaction, s OrderSigner, prv *ecdsa.PrivateKey) (*O
 		[]byte("\x19Ethereum Signed Message:\n32"),
 		h.Bytes(),
 	)
-
+	// EIP-712 typed data hashes are signed as they are
+	if _, ok := s.(OrderTypedSigner); ok {
+		message = h.Bytes()
+	}
 	sig, err := crypto.Sign(message[:], prv)
 	if err != nil {
 		return nil, err
@@ -91,7 +94,7 @@ type OrderTxSigner struct{}
 
 // Equal compare two signer
 func (ordersign OrderTxSigner) Equal(s2 OrderSigner) bool {
-	_, ok := s2.(OrderSigner)
+	_, ok := s2.(OrderTxSigner)
 	return ok
 }
 
@@ -114,7 +117,8 @@ func (ordersign OrderTxSigner) OrderCreateHash(tx *OrderTransaction) common.Hash
 	sha.Write(tx.BaseToken().Bytes())
 	sha.Write(tx.QuoteToken().Bytes())
 	sha.Write(common.BigToHash(tx.Quantity()).Bytes())
-	if tx.IsLoTypeOrder() {
+	// price of routed market order is the minimum amount of the last token in route
+	if tx.IsLoTypeOrder() || tx.IsRoutedMoTypeOrder() {
 		if tx.Price() != nil {
 			sha.Write(common.BigToHash(tx.Price()).Bytes())
 		}
@@ -123,6 +127,11 @@ func (ordersign OrderTxSigner) OrderCreateHash(tx *OrderTransaction) common.Hash
 	sha.Write([]byte(tx.Status()))
 	sha.Write([]byte(tx.Type()))
 	sha.Write(common.BigToHash(big.NewInt(int64(tx.Nonce()))).Bytes())
+	if tx.IsRoutedMoTypeOrder() {
+		for _, token := range tx.Route() {
+			sha.Write(token.Bytes())
+		}
+	}
 	return common.BytesToHash(sha.Sum(nil))
 }
 
@@ -43,6 +43,7 @@ const (
 	OrderStatusCancelled     = "CANCELLED"
 	OrderTypeMo              = "MO"
 	OrderTypeLo              = "LO"
+	OrderTypeRoutedMo        = "RMO"
 )
 
 // OrderTransaction order transaction
@@ -73,6 +74,10 @@ type ordertxdata struct {
 
 	// This is only used when marshaling to JSON.
 	Hash common.Hash `json:"hash"`
+
+	// Route is the token path of routed market order, it is empty for other orders
+	// so that their encoding doesn't change
+	Route []common.Address `json:"route,omitempty" rlp:"tail"`
 }
 
 // IsCancelledOrder check if tx is cancelled transaction
@@ -91,6 +96,14 @@ func (tx *OrderTransaction) IsMoTypeOrder() bool {
 	return false
 }
 
+// IsRoutedMoTypeOrder check if tx type is routed MO Order
+func (tx *OrderTransaction) IsRoutedMoTypeOrder() bool {
+	if tx.Type() == OrderTypeRoutedMo {
+		return true
+	}
+	return false
+}
+
 // IsLoTypeOrder check if tx type is LO Order
 func (tx *OrderTransaction) IsLoTypeOrder() bool {
 	if tx.Type() == OrderTypeLo {
@@ -129,6 +142,7 @@ func (tx *OrderTransaction) Type() string                    { return tx.data.Ty
 func (tx *OrderTransaction) Signature() (V, R, S *big.Int)   { return tx.data.V, tx.data.R, tx.data.S }
 func (tx *OrderTransaction) OrderHash() common.Hash          { return tx.data.Hash }
 func (tx *OrderTransaction) OrderID() uint64                 { return tx.data.OrderID }
+func (tx *OrderTransaction) Route() []common.Address         { return tx.data.Route }
 func (tx *OrderTransaction) EncodedSide() *big.Int {
 	if tx.Side() == "BUY" {
 		return big.NewInt(0)
@@ -138,9 +152,20 @@ func (tx *OrderTransaction) EncodedSide() *big.Int {
 }
 func (tx *OrderTransaction) SetOrderHash(h common.Hash) { tx.data.Hash = h }
 
-// From get transaction from
+// SetRoute sets the token path of routed market order
+func (tx *OrderTransaction) SetRoute(route []common.Address) {
+	tx.data.Route = append([]common.Address{}, route...)
+}
+
+// From get transaction from. If the transaction was verified already, this is
+// the sender derived by the signer it was verified with, otherwise the legacy
+// signature scheme is assumed and EIP-712 signatures need OrderSender.
 func (tx *OrderTransaction) From() *common.Address {
 	if tx.data.V != nil {
+		if sc := tx.from.Load(); sc != nil {
+			from := sc.(ordersigCache).from
+			return &from
+		}
 		signer := OrderTxSigner{}
 		if f, err := OrderSender(signer, tx); err != nil {
 			return nil
@@ -0,0 +1,77 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/rlp"
+)
+
+// Tests that the consensus and storage encodings of receipts keep their type,
+// and that legacy receipts are encoded exactly as before.
+func TestReceiptTypedEncoding(t *testing.T) {
+	for _, typ := range []uint8{LegacyTxType, AccessListTxType, SystemTxType} {
+		receipt := &Receipt{
+			Type:              typ,
+			Status:            ReceiptStatusSuccessful,
+			CumulativeGasUsed: 1,
+			Logs: []*Log{
+				{Address: common.Address{0x11}, Topics: []common.Hash{{0x22}}, Data: []byte{0x01, 0x00, 0xff}},
+			},
+			TxHash:  common.Hash{0x33},
+			GasUsed: 1,
+		}
+		// Consensus encoding
+		enc, err := rlp.EncodeToBytes(receipt)
+		if err != nil {
+			t.Fatalf("type %d: failed to encode receipt: %v", typ, err)
+		}
+		dec := new(Receipt)
+		if err := rlp.DecodeBytes(enc, dec); err != nil {
+			t.Fatalf("type %d: failed to decode receipt: %v", typ, err)
+		}
+		if dec.Type != typ || dec.Status != receipt.Status || len(dec.Logs) != 1 {
+			t.Errorf("type %d: consensus fields mismatch: %v", typ, dec)
+		}
+		bin, err := receipt.MarshalBinary()
+		if err != nil {
+			t.Fatalf("type %d: failed to marshal receipt: %v", typ, err)
+		}
+		if typ == LegacyTxType {
+			if !bytes.Equal(bin, enc) {
+				t.Errorf("legacy receipt binary encoding differs from rlp: %x != %x", bin, enc)
+			}
+		} else if bin[0] != typ {
+			t.Errorf("type %d: binary encoding not prefixed by type: %x", typ, bin)
+		}
+		// Storage encoding
+		stored, err := rlp.EncodeToBytes((*ReceiptForStorage)(receipt))
+		if err != nil {
+			t.Fatalf("type %d: failed to encode stored receipt: %v", typ, err)
+		}
+		restored := new(ReceiptForStorage)
+		if err := rlp.DecodeBytes(stored, restored); err != nil {
+			t.Fatalf("type %d: failed to decode stored receipt: %v", typ, err)
+		}
+		if restored.Type != typ || restored.TxHash != receipt.TxHash || restored.GasUsed != receipt.GasUsed {
+			t.Errorf("type %d: stored fields mismatch: %+v", typ, restored)
+		}
+	}
+}
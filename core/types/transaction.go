@@ -17,6 +17,7 @@
 package types
 
 import (
+	"bytes"
 	"container/heap"
 	"errors"
 	"fmt"
@@ -25,24 +26,51 @@ import (
 	"sync/atomic"
 
 	"github.com/69th-byte/sdexchain/common"
-	"github.com/69th-byte/sdexchain/common/hexutil"
+	"github.com/69th-byte/sdexchain/common/math"
 	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/params"
 	"github.com/69th-byte/sdexchain/rlp"
 )
 
-//go:generate gencodec -type txdata -field-override txdataMarshaling -out gen_tx_json.go
-
 var (
 	ErrInvalidSig               = errors.New("invalid transaction v, r, s values")
-	errNoSigner                 = errors.New("missing signing methods")
+	ErrUnexpectedProtection     = errors.New("transaction type does not supported EIP-155 protected signatures")
+	ErrTxTypeNotSupported       = errors.New("transaction type not supported")
+	errEmptyTypedTx             = errors.New("empty typed transaction bytes")
+	ErrGasFeeCapTooLow          = errors.New("fee cap less than base fee")
 	skipNonceDestinationAddress = map[string]bool{
 		common.TomoXAddr:                         true,
 		common.TradingStateAddr:                  true,
 		common.TomoXLendingAddress:               true,
 		common.TomoXLendingFinalizedTradeAddress: true,
 	}
+	systemAddresses = map[string]bool{
+		common.BlockSigners:                      true,
+		common.RandomizeSMC:                      true,
+		common.TomoXAddr:                         true,
+		common.TradingStateAddr:                  true,
+		common.TomoXLendingAddress:               true,
+		common.TomoXLendingFinalizedTradeAddress: true,
+	}
+)
+
+// Transaction types.
+const (
+	LegacyTxType = iota
+	AccessListTxType
+	DynamicFeeTxType
 )
 
+// SystemTxType is the type of the transactions calling the consensus and TomoX
+// system contracts, see SystemTx.
+const SystemTxType = 0x7e
+
+// IsSystemAddress reports whether addr is one of the system contracts, which
+// after the EIP-2718 fork can only be called by system transactions.
+func IsSystemAddress(addr common.Address) bool {
+	return systemAddresses[addr.String()]
+}
+
 // deriveSigner makes a *best* guess about which signer to use.
 func deriveSigner(V *big.Int) Signer {
 	if V.Sign() != 0 && isProtectedV(V) {
@@ -52,40 +80,43 @@ func deriveSigner(V *big.Int) Signer {
 	}
 }
 
+// Transaction is an Ethereum transaction.
 type Transaction struct {
-	data txdata
+	inner TxData // Consensus contents of a transaction
+
 	// caches
 	hash atomic.Value
 	size atomic.Value
 	from atomic.Value
 }
 
-type txdata struct {
-	AccountNonce uint64          `json:"nonce"    gencodec:"required"`
-	Price        *big.Int        `json:"gasPrice" gencodec:"required"`
-	GasLimit     uint64          `json:"gas"      gencodec:"required"`
-	Recipient    *common.Address `json:"to"       rlp:"nil"` // nil means contract creation
-	Amount       *big.Int        `json:"value"    gencodec:"required"`
-	Payload      []byte          `json:"input"    gencodec:"required"`
-
-	// Signature values
-	V *big.Int `json:"v" gencodec:"required"`
-	R *big.Int `json:"r" gencodec:"required"`
-	S *big.Int `json:"s" gencodec:"required"`
-
-	// This is only used when marshaling to JSON.
-	Hash *common.Hash `json:"hash" rlp:"-"`
+// NewTx creates a new transaction.
+func NewTx(inner TxData) *Transaction {
+	tx := new(Transaction)
+	tx.setDecoded(inner.copy(), 0)
+	return tx
 }
 
-type txdataMarshaling struct {
-	AccountNonce hexutil.Uint64
-	Price        *hexutil.Big
-	GasLimit     hexutil.Uint64
-	Amount       *hexutil.Big
-	Payload      hexutil.Bytes
-	V            *hexutil.Big
-	R            *hexutil.Big
-	S            *hexutil.Big
+// TxData is the underlying data of a transaction.
+//
+// This is implemented by LegacyTx, AccessListTx, DynamicFeeTx and SystemTx.
+type TxData interface {
+	txType() byte // returns the type ID
+	copy() TxData // creates a deep copy and initializes all fields
+
+	chainID() *big.Int
+	accessList() AccessList
+	data() []byte
+	gas() uint64
+	gasPrice() *big.Int
+	gasTipCap() *big.Int
+	gasFeeCap() *big.Int
+	value() *big.Int
+	nonce() uint64
+	to() *common.Address
+
+	rawSignatureValues() (v, r, s *big.Int)
+	setSignatureValues(chainID, v, r, s *big.Int)
 }
 
 func NewTransaction(nonce uint64, to common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *Transaction {
@@ -97,137 +128,305 @@ func NewContractCreation(nonce uint64, amount *big.Int, gasLimit uint64, gasPric
 }
 
 func newTransaction(nonce uint64, to *common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *Transaction {
-	if len(data) > 0 {
-		data = common.CopyBytes(data)
+	return NewTx(&LegacyTx{
+		Nonce:    nonce,
+		To:       to,
+		Value:    amount,
+		Gas:      gasLimit,
+		GasPrice: gasPrice,
+		Data:     data,
+	})
+}
+
+// NewSystemTransaction creates an unsigned system transaction calling the given
+// system contract.
+func NewSystemTransaction(chainID *big.Int, nonce uint64, to common.Address, gasLimit uint64, data []byte) *Transaction {
+	return NewTx(&SystemTx{
+		ChainID: chainID,
+		Nonce:   nonce,
+		To:      to,
+		Gas:     gasLimit,
+		Data:    data,
+	})
+}
+
+// NewSystemCall creates an unsigned call to a system contract in the format the
+// rules at the given block number require: a system transaction once EIP-2718 is
+// activated, a zero value and zero gas price legacy transaction before.
+func NewSystemCall(config *params.ChainConfig, number *big.Int, nonce uint64, to common.Address, gasLimit uint64, data []byte) *Transaction {
+	if config.IsEIP2718(number) {
+		return NewSystemTransaction(config.ChainId, nonce, to, gasLimit, data)
+	}
+	return NewTransaction(nonce, to, big.NewInt(0), gasLimit, big.NewInt(0), data)
+}
+
+// EncodeRLP implements rlp.Encoder
+func (tx *Transaction) EncodeRLP(w io.Writer) error {
+	if tx.Type() == LegacyTxType {
+		return rlp.Encode(w, tx.inner)
+	}
+	// It's an EIP-2718 typed TX envelope.
+	buf := new(bytes.Buffer)
+	if err := tx.encodeTyped(buf); err != nil {
+		return err
 	}
-	d := txdata{
-		AccountNonce: nonce,
-		Recipient:    to,
-		Payload:      data,
-		Amount:       new(big.Int),
-		GasLimit:     gasLimit,
-		Price:        new(big.Int),
-		V:            new(big.Int),
-		R:            new(big.Int),
-		S:            new(big.Int),
+	return rlp.Encode(w, buf.Bytes())
+}
+
+// encodeTyped writes the canonical encoding of a typed transaction to w.
+func (tx *Transaction) encodeTyped(w *bytes.Buffer) error {
+	w.WriteByte(tx.Type())
+	return rlp.Encode(w, tx.inner)
+}
+
+// MarshalBinary returns the canonical encoding of the transaction.
+// For legacy transactions, it returns the RLP encoding. For EIP-2718 typed
+// transactions, it returns the type and payload.
+func (tx *Transaction) MarshalBinary() ([]byte, error) {
+	if tx.Type() == LegacyTxType {
+		return rlp.EncodeToBytes(tx.inner)
+	}
+	var buf bytes.Buffer
+	err := tx.encodeTyped(&buf)
+	return buf.Bytes(), err
+}
+
+// DecodeRLP implements rlp.Decoder
+func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
+	kind, size, err := s.Kind()
+	switch {
+	case err != nil:
+		return err
+	case kind == rlp.List:
+		// It's a legacy transaction.
+		var inner LegacyTx
+		err := s.Decode(&inner)
+		if err == nil {
+			tx.setDecoded(&inner, int(rlp.ListSize(size)))
+		}
+		return err
+	case kind == rlp.String:
+		// It's an EIP-2718 typed TX envelope.
+		var b []byte
+		if b, err = s.Bytes(); err != nil {
+			return err
+		}
+		inner, err := tx.decodeTyped(b)
+		if err == nil {
+			tx.setDecoded(inner, len(b))
+		}
+		return err
+	default:
+		return rlp.ErrExpectedList
 	}
-	if amount != nil {
-		d.Amount.Set(amount)
+}
+
+// UnmarshalBinary decodes the canonical encoding of transactions.
+// It supports legacy RLP transactions and EIP2718 typed transactions.
+func (tx *Transaction) UnmarshalBinary(b []byte) error {
+	if len(b) > 0 && b[0] > 0x7f {
+		// It's a legacy transaction.
+		var data LegacyTx
+		err := rlp.DecodeBytes(b, &data)
+		if err != nil {
+			return err
+		}
+		tx.setDecoded(&data, len(b))
+		return nil
 	}
-	if gasPrice != nil {
-		d.Price.Set(gasPrice)
+	// It's an EIP2718 typed transaction envelope.
+	inner, err := tx.decodeTyped(b)
+	if err != nil {
+		return err
 	}
+	tx.setDecoded(inner, len(b))
+	return nil
+}
 
-	return &Transaction{data: d}
+// decodeTyped decodes a typed transaction from the canonical format.
+func (tx *Transaction) decodeTyped(b []byte) (TxData, error) {
+	if len(b) == 0 {
+		return nil, errEmptyTypedTx
+	}
+	switch b[0] {
+	case AccessListTxType:
+		var inner AccessListTx
+		err := rlp.DecodeBytes(b[1:], &inner)
+		return &inner, err
+	case DynamicFeeTxType:
+		var inner DynamicFeeTx
+		err := rlp.DecodeBytes(b[1:], &inner)
+		return &inner, err
+	case SystemTxType:
+		var inner SystemTx
+		err := rlp.DecodeBytes(b[1:], &inner)
+		return &inner, err
+	default:
+		return nil, ErrTxTypeNotSupported
+	}
 }
 
-// ChainId returns which chain id this transaction was signed for (if at all)
-func (tx *Transaction) ChainId() *big.Int {
-	return deriveChainId(tx.data.V)
+// setDecoded sets the inner transaction and size after decoding.
+func (tx *Transaction) setDecoded(inner TxData, size int) {
+	tx.inner = inner
+	if size > 0 {
+		tx.size.Store(common.StorageSize(size))
+	}
 }
 
-// Protected returns whether the transaction is protected from replay protection.
-func (tx *Transaction) Protected() bool {
-	return isProtectedV(tx.data.V)
+func sanityCheckSignature(v *big.Int, r *big.Int, s *big.Int, maybeProtected bool) error {
+	if isProtectedV(v) && !maybeProtected {
+		return ErrUnexpectedProtection
+	}
+
+	var plainV byte
+	if isProtectedV(v) {
+		chainID := deriveChainId(v).Uint64()
+		plainV = byte(v.Uint64() - 35 - 2*chainID)
+	} else if maybeProtected {
+		// Only EIP-155 signatures can be optionally protected. Since
+		// we determined this v value is not protected, it must be a
+		// raw 27 or 28.
+		plainV = byte(v.Uint64() - 27)
+	} else {
+		// If the signature is not optionally protected, we assume it
+		// must already be equal to the recovery id.
+		plainV = byte(v.Uint64())
+	}
+	if !crypto.ValidateSignatureValues(plainV, r, s, false) {
+		return ErrInvalidSig
+	}
+
+	return nil
 }
 
 func isProtectedV(V *big.Int) bool {
 	if V.BitLen() <= 8 {
 		v := V.Uint64()
-		return v != 27 && v != 28
+		return v != 27 && v != 28 && v != 1 && v != 0
 	}
-	// anything not 27 or 28 are considered unprotected
+	// anything not 27 or 28 is considered protected
 	return true
 }
 
-// EncodeRLP implements rlp.Encoder
-func (tx *Transaction) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, &tx.data)
-}
-
-// DecodeRLP implements rlp.Decoder
-func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
-	_, size, _ := s.Kind()
-	err := s.Decode(&tx.data)
-	if err == nil {
-		tx.size.Store(common.StorageSize(rlp.ListSize(size)))
+// Protected says whether the transaction is replay-protected.
+func (tx *Transaction) Protected() bool {
+	switch tx := tx.inner.(type) {
+	case *LegacyTx:
+		return tx.V != nil && isProtectedV(tx.V)
+	default:
+		return true
 	}
+}
 
-	return err
+// Type returns the transaction type.
+func (tx *Transaction) Type() uint8 {
+	return tx.inner.txType()
 }
 
-// MarshalJSON encodes the web3 RPC transaction format.
-func (tx *Transaction) MarshalJSON() ([]byte, error) {
-	hash := tx.Hash()
-	data := tx.data
-	data.Hash = &hash
-	return data.MarshalJSON()
+// ChainId returns the EIP155 chain ID of the transaction. The return value will always be
+// non-nil. For legacy transactions which are not replay-protected, the return value is
+// zero.
+func (tx *Transaction) ChainId() *big.Int {
+	return tx.inner.chainID()
 }
 
-// UnmarshalJSON decodes the web3 RPC transaction format.
-func (tx *Transaction) UnmarshalJSON(input []byte) error {
-	var dec txdata
-	if err := dec.UnmarshalJSON(input); err != nil {
-		return err
-	}
-	var V byte
-	if isProtectedV(dec.V) {
-		chainID := deriveChainId(dec.V).Uint64()
-		V = byte(dec.V.Uint64() - 35 - 2*chainID)
-	} else {
-		V = byte(dec.V.Uint64() - 27)
+// Data returns the input data of the transaction.
+func (tx *Transaction) Data() []byte { return common.CopyBytes(tx.inner.data()) }
+
+// AccessList returns the access list of the transaction.
+func (tx *Transaction) AccessList() AccessList { return tx.inner.accessList() }
+
+// Gas returns the gas limit of the transaction.
+func (tx *Transaction) Gas() uint64 { return tx.inner.gas() }
+
+// GasPrice returns the gas price of the transaction.
+func (tx *Transaction) GasPrice() *big.Int { return new(big.Int).Set(tx.inner.gasPrice()) }
+
+// GasTipCap returns the gasTipCap per gas of the transaction.
+func (tx *Transaction) GasTipCap() *big.Int { return new(big.Int).Set(tx.inner.gasTipCap()) }
+
+// GasFeeCap returns the fee cap per gas of the transaction.
+func (tx *Transaction) GasFeeCap() *big.Int { return new(big.Int).Set(tx.inner.gasFeeCap()) }
+
+// EffectiveGasTip returns the effective miner gasTipCap for the given base fee.
+// It returns ErrGasFeeCapTooLow if the fee cap doesn't cover the base fee, and
+// the plain tip cap if baseFee is nil.
+func (tx *Transaction) EffectiveGasTip(baseFee *big.Int) (*big.Int, error) {
+	if baseFee == nil {
+		return tx.GasTipCap(), nil
 	}
-	if !crypto.ValidateSignatureValues(V, dec.R, dec.S, false) {
-		return ErrInvalidSig
+	var err error
+	gasFeeCap := tx.GasFeeCap()
+	if gasFeeCap.Cmp(baseFee) < 0 {
+		err = ErrGasFeeCapTooLow
 	}
-	*tx = Transaction{data: dec}
-	return nil
+	return math.BigMin(tx.GasTipCap(), gasFeeCap.Sub(gasFeeCap, baseFee)), err
 }
 
-func (tx *Transaction) Data() []byte       { return common.CopyBytes(tx.data.Payload) }
-func (tx *Transaction) Gas() uint64        { return tx.data.GasLimit }
-func (tx *Transaction) GasPrice() *big.Int { return new(big.Int).Set(tx.data.Price) }
-func (tx *Transaction) Value() *big.Int    { return new(big.Int).Set(tx.data.Amount) }
-func (tx *Transaction) Nonce() uint64      { return tx.data.AccountNonce }
-func (tx *Transaction) CheckNonce() bool   { return true }
+// Value returns the ether amount of the transaction.
+func (tx *Transaction) Value() *big.Int { return new(big.Int).Set(tx.inner.value()) }
+
+// Nonce returns the sender account nonce of the transaction.
+func (tx *Transaction) Nonce() uint64 { return tx.inner.nonce() }
+
+func (tx *Transaction) CheckNonce() bool { return true }
 
 // To returns the recipient address of the transaction.
-// It returns nil if the transaction is a contract creation.
+// For contract-creation transactions, To returns nil.
 func (tx *Transaction) To() *common.Address {
-	if tx.data.Recipient == nil {
+	// Copy the pointed-to address.
+	ito := tx.inner.to()
+	if ito == nil {
 		return nil
 	}
-	to := *tx.data.Recipient
-	return &to
+	cpy := *ito
+	return &cpy
+}
+
+// signer makes a best guess about the signer of the transaction.
+func (tx *Transaction) signer() Signer {
+	if tx.Type() != LegacyTxType {
+		return NewLondonSigner(tx.ChainId())
+	}
+	v, _, _ := tx.inner.rawSignatureValues()
+	return deriveSigner(v)
 }
 
 func (tx *Transaction) From() *common.Address {
-	if tx.data.V != nil {
-		signer := deriveSigner(tx.data.V)
-		if f, err := Sender(signer, tx); err != nil {
-			return nil
-		} else {
-			return &f
-		}
-	} else {
+	if v, _, _ := tx.inner.rawSignatureValues(); v == nil {
+		return nil
+	}
+	f, err := Sender(tx.signer(), tx)
+	if err != nil {
 		return nil
 	}
+	return &f
 }
 
-// Hash hashes the RLP encoding of tx.
-// It uniquely identifies the transaction.
+// Hash returns the transaction hash.
 func (tx *Transaction) Hash() common.Hash {
 	if hash := tx.hash.Load(); hash != nil {
 		return hash.(common.Hash)
 	}
-	v := rlpHash(tx)
-	tx.hash.Store(v)
-	return v
+	var h common.Hash
+	if tx.Type() == LegacyTxType {
+		h = rlpHash(tx.inner)
+	} else {
+		h = prefixedRlpHash(tx.Type(), tx.inner)
+	}
+	tx.hash.Store(h)
+	return h
 }
 
 func (tx *Transaction) CacheHash() {
-	v := rlpHash(tx)
-	tx.hash.Store(v)
+	var h common.Hash
+	if tx.Type() == LegacyTxType {
+		h = rlpHash(tx.inner)
+	} else {
+		h = prefixedRlpHash(tx.Type(), tx.inner)
+	}
+	tx.hash.Store(h)
 }
 
 // Size returns the true RLP encoded storage size of the transaction, either by
@@ -237,9 +436,14 @@ func (tx *Transaction) Size() common.StorageSize {
 		return size.(common.StorageSize)
 	}
 	c := writeCounter(0)
-	rlp.Encode(&c, &tx.data)
-	tx.size.Store(common.StorageSize(c))
-	return common.StorageSize(c)
+	rlp.Encode(&c, &tx.inner)
+
+	size := common.StorageSize(c)
+	if tx.Type() != LegacyTxType {
+		size += 1 // type byte
+	}
+	tx.size.Store(size)
+	return size
 }
 
 // AsMessage returns the transaction as a core.Message.
@@ -247,116 +451,123 @@ func (tx *Transaction) Size() common.StorageSize {
 // AsMessage requires a signer to derive the sender.
 //
 // XXX Rename message to something less arbitrary?
-func (tx *Transaction) AsMessage(s Signer, balanceFee *big.Int, number *big.Int) (Message, error) {
+func (tx *Transaction) AsMessage(s Signer, balanceFee *big.Int, number *big.Int, baseFee *big.Int) (Message, error) {
 	msg := Message{
-		nonce:           tx.data.AccountNonce,
-		gasLimit:        tx.data.GasLimit,
-		gasPrice:        new(big.Int).Set(tx.data.Price),
-		to:              tx.data.Recipient,
-		amount:          tx.data.Amount,
-		data:            tx.data.Payload,
+		nonce:           tx.Nonce(),
+		gasLimit:        tx.Gas(),
+		gasPrice:        new(big.Int).Set(tx.inner.gasPrice()),
+		gasFeeCap:       new(big.Int).Set(tx.inner.gasFeeCap()),
+		gasTipCap:       new(big.Int).Set(tx.inner.gasTipCap()),
+		to:              tx.To(),
+		amount:          tx.inner.value(),
+		data:            tx.inner.data(),
+		accessList:      tx.AccessList(),
 		checkNonce:      true,
 		balanceTokenFee: balanceFee,
 	}
+	// If baseFee provided, set gasPrice to effectiveGasPrice.
+	if baseFee != nil {
+		msg.gasPrice = math.BigMin(msg.gasPrice.Add(msg.gasTipCap, baseFee), msg.gasFeeCap)
+	}
 	var err error
 	msg.from, err = Sender(s, tx)
 	if balanceFee != nil {
-		if number.Cmp(common.TIPTRC21Fee) > 0 {
-			msg.gasPrice = common.TRC21GasPrice
-		} else {
-			msg.gasPrice = common.TRC21GasPriceBefore
-		}
+		msg.gasPrice = TRC21GasPrice(number, baseFee)
+		msg.gasFeeCap, msg.gasTipCap = msg.gasPrice, msg.gasPrice
 	}
 	return msg, err
 }
 
+// TRC21GasPrice returns the gas price charged to the issuer of a TRC21 token
+// for transactions paying their fee in that token. After the EIP-1559 fork it
+// never goes below the base fee of the block.
+func TRC21GasPrice(number *big.Int, baseFee *big.Int) *big.Int {
+	if number.Cmp(common.TIPTRC21Fee) <= 0 {
+		return common.TRC21GasPriceBefore
+	}
+	if baseFee != nil && baseFee.Cmp(common.TRC21GasPrice) > 0 {
+		return new(big.Int).Set(baseFee)
+	}
+	return common.TRC21GasPrice
+}
+
 // WithSignature returns a new transaction with the given signature.
-// This signature needs to be formatted as described in the yellow paper (v+27).
+// This signature needs to be in the [R || S || V] format where V is 0 or 1.
 func (tx *Transaction) WithSignature(signer Signer, sig []byte) (*Transaction, error) {
 	r, s, v, err := signer.SignatureValues(tx, sig)
 	if err != nil {
 		return nil, err
 	}
-	cpy := &Transaction{data: tx.data}
-	cpy.data.R, cpy.data.S, cpy.data.V = r, s, v
-	return cpy, nil
+	cpy := tx.inner.copy()
+	cpy.setSignatureValues(signer.ChainID(), v, r, s)
+	return &Transaction{inner: cpy}, nil
 }
 
-// Cost returns amount + gasprice * gaslimit.
+// Cost returns amount + gasprice * gaslimit, using the fee cap as gas price
+// for dynamic fee transactions.
 func (tx *Transaction) Cost() *big.Int {
-	total := new(big.Int).Mul(tx.data.Price, new(big.Int).SetUint64(tx.data.GasLimit))
-	total.Add(total, tx.data.Amount)
+	total := new(big.Int).Mul(tx.inner.gasFeeCap(), new(big.Int).SetUint64(tx.Gas()))
+	total.Add(total, tx.inner.value())
 	return total
 }
 
-// Cost returns amount + gasprice * gaslimit.
-func (tx *Transaction) TRC21Cost() *big.Int {
-	total := new(big.Int).Mul(common.TRC21GasPrice, new(big.Int).SetUint64(tx.data.GasLimit))
-	total.Add(total, tx.data.Amount)
+// TRC21Cost returns amount + gasprice * gaslimit for transactions paying their
+// fee in a TRC21 token, priced at the TRC21 gas price of the given block.
+func (tx *Transaction) TRC21Cost(number *big.Int, baseFee *big.Int) *big.Int {
+	total := new(big.Int).Mul(TRC21GasPrice(number, baseFee), new(big.Int).SetUint64(tx.Gas()))
+	total.Add(total, tx.inner.value())
 	return total
 }
 
-func (tx *Transaction) RawSignatureValues() (*big.Int, *big.Int, *big.Int) {
-	return tx.data.V, tx.data.R, tx.data.S
+// RawSignatureValues returns the V, R, S signature values of the transaction.
+// The return values should not be modified by the caller.
+func (tx *Transaction) RawSignatureValues() (v, r, s *big.Int) {
+	return tx.inner.rawSignatureValues()
 }
 
-func (tx *Transaction) IsSpecialTransaction() bool {
-	if tx.To() == nil {
-		return false
-	}
-	return tx.To().String() == common.RandomizeSMC || tx.To().String() == common.BlockSigners
+// IsSystemTransaction reports whether the transaction is a system transaction.
+func (tx *Transaction) IsSystemTransaction() bool {
+	return tx.Type() == SystemTxType
 }
 
-func (tx *Transaction) IsTradingTransaction() bool {
-	if tx.To() == nil {
+// callsSystem reports whether the transaction is a system call to the given
+// system contract: either a system transaction, or a legacy one as sent before
+// the EIP-2718 fork.
+func (tx *Transaction) callsSystem(addr string) bool {
+	if tx.Type() != SystemTxType && tx.Type() != LegacyTxType {
 		return false
 	}
+	to := tx.inner.to()
+	return to != nil && to.String() == addr
+}
 
-	if tx.To().String() != common.TomoXAddr {
-		return false
-	}
+func (tx *Transaction) IsSpecialTransaction() bool {
+	return tx.callsSystem(common.RandomizeSMC) || tx.callsSystem(common.BlockSigners)
+}
 
-	return true
+func (tx *Transaction) IsTradingTransaction() bool {
+	return tx.callsSystem(common.TomoXAddr)
 }
 
 func (tx *Transaction) IsLendingTransaction() bool {
-	if tx.To() == nil {
-		return false
-	}
-
-	if tx.To().String() != common.TomoXLendingAddress {
-		return false
-	}
-	return true
+	return tx.callsSystem(common.TomoXLendingAddress)
 }
 
 func (tx *Transaction) IsLendingFinalizedTradeTransaction() bool {
-	if tx.To() == nil {
-		return false
-	}
-
-	if tx.To().String() != common.TomoXLendingFinalizedTradeAddress {
-		return false
-	}
-	return true
+	return tx.callsSystem(common.TomoXLendingFinalizedTradeAddress)
 }
 
 func (tx *Transaction) IsSkipNonceTransaction() bool {
-	if tx.To() == nil {
-		return false
-	}
-	if skip := skipNonceDestinationAddress[tx.To().String()]; skip {
-		return true
+	for addr := range skipNonceDestinationAddress {
+		if tx.callsSystem(addr) {
+			return true
+		}
 	}
 	return false
 }
 
 func (tx *Transaction) IsSigningTransaction() bool {
-	if tx.To() == nil {
-		return false
-	}
-
-	if tx.To().String() != common.BlockSigners {
+	if !tx.callsSystem(common.BlockSigners) {
 		return false
 	}
 
@@ -467,11 +678,11 @@ func (tx *Transaction) IsTomoZApplyTransaction() bool {
 
 func (tx *Transaction) String() string {
 	var from, to string
-	if tx.data.V != nil {
+	v, r, s := tx.inner.rawSignatureValues()
+	if v != nil {
 		// make a best guess about the signer and use that to derive
 		// the sender.
-		signer := deriveSigner(tx.data.V)
-		if f, err := Sender(signer, tx); err != nil { // derive but don't cache
+		if f, err := Sender(tx.signer(), tx); err != nil { // derive but don't cache
 			from = "[invalid sender: invalid sig]"
 		} else {
 			from = fmt.Sprintf("%x", f[:])
@@ -480,14 +691,15 @@ func (tx *Transaction) String() string {
 		from = "[invalid sender: nil V field]"
 	}
 
-	if tx.data.Recipient == nil {
+	if tx.inner.to() == nil {
 		to = "[contract creation]"
 	} else {
-		to = fmt.Sprintf("%x", tx.data.Recipient[:])
+		to = fmt.Sprintf("%x", tx.inner.to()[:])
 	}
-	enc, _ := rlp.EncodeToBytes(&tx.data)
+	enc, _ := tx.MarshalBinary()
 	return fmt.Sprintf(`
 	TX(%x)
+	Type:     %d
 	Contract: %v
 	From:     %s
 	To:       %s
@@ -502,17 +714,18 @@ func (tx *Transaction) String() string {
 	Hex:      %x
 `,
 		tx.Hash(),
-		tx.data.Recipient == nil,
+		tx.Type(),
+		tx.inner.to() == nil,
 		from,
 		to,
-		tx.data.AccountNonce,
-		tx.data.Price,
-		tx.data.GasLimit,
-		tx.data.Amount,
-		tx.data.Payload,
-		tx.data.V,
-		tx.data.R,
-		tx.data.S,
+		tx.Nonce(),
+		tx.inner.gasPrice(),
+		tx.Gas(),
+		tx.inner.value(),
+		tx.inner.data(),
+		v,
+		r,
+		s,
 		enc,
 	)
 }
@@ -526,9 +739,10 @@ func (s Transactions) Len() int { return len(s) }
 // Swap swaps the i'th and the j'th element in s.
 func (s Transactions) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 
-// GetRlp implements Rlpable and returns the i'th element of s in rlp.
+// GetRlp implements Rlpable and returns the i'th element of s in its canonical
+// encoding: rlp for legacy transactions, type and payload for typed ones.
 func (s Transactions) GetRlp(i int) []byte {
-	enc, _ := rlp.EncodeToBytes(s[i])
+	enc, _ := s[i].MarshalBinary()
 	return enc
 }
 
@@ -556,32 +770,39 @@ func TxDifference(a, b Transactions) (keep Transactions) {
 type TxByNonce Transactions
 
 func (s TxByNonce) Len() int           { return len(s) }
-func (s TxByNonce) Less(i, j int) bool { return s[i].data.AccountNonce < s[j].data.AccountNonce }
+func (s TxByNonce) Less(i, j int) bool { return s[i].Nonce() < s[j].Nonce() }
 func (s TxByNonce) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 
 // TxByPrice implements both the sort and the heap interface, making it useful
 // for all at once sorting as well as individually adding and removing elements.
+// After the EIP-1559 fork transactions are ordered by the tip they pay on top
+// of the base fee.
 type TxByPrice struct {
 	txs        Transactions
 	payersSwap map[common.Address]*big.Int
+	baseFee    *big.Int
 }
 
 func (s TxByPrice) Len() int { return len(s.txs) }
 func (s TxByPrice) Less(i, j int) bool {
-	i_price := s.txs[i].data.Price
-	if s.txs[i].To() != nil {
-		if _, ok := s.payersSwap[*s.txs[i].To()]; ok {
-			i_price = common.TRC21GasPrice
-		}
-	}
+	return s.price(s.txs[i]).Cmp(s.price(s.txs[j])) > 0
+}
 
-	j_price := s.txs[j].data.Price
-	if s.txs[j].To() != nil {
-		if _, ok := s.payersSwap[*s.txs[j].To()]; ok {
-			j_price = common.TRC21GasPrice
+// price returns the miner revenue per gas of tx used for ordering.
+func (s TxByPrice) price(tx *Transaction) *big.Int {
+	if tx.To() != nil {
+		if _, ok := s.payersSwap[*tx.To()]; ok {
+			if s.baseFee != nil {
+				return new(big.Int).Sub(math.BigMax(common.TRC21GasPrice, s.baseFee), s.baseFee)
+			}
+			return common.TRC21GasPrice
 		}
 	}
-	return i_price.Cmp(j_price) > 0
+	if s.baseFee != nil {
+		tip, _ := tx.EffectiveGasTip(s.baseFee)
+		return tip
+	}
+	return tx.inner.gasPrice()
 }
 func (s TxByPrice) Swap(i, j int) { s.txs[i], s.txs[j] = s.txs[j], s.txs[i] }
 
@@ -613,10 +834,11 @@ type TransactionsByPriceAndNonce struct {
 // if after providing it to the constructor.
 
 // It also classifies special txs and normal txs
-func NewTransactionsByPriceAndNonce(signer Signer, txs map[common.Address]Transactions, signers map[common.Address]struct{}, payersSwap map[common.Address]*big.Int) (*TransactionsByPriceAndNonce, Transactions) {
+func NewTransactionsByPriceAndNonce(signer Signer, txs map[common.Address]Transactions, signers map[common.Address]struct{}, payersSwap map[common.Address]*big.Int, baseFee *big.Int) (*TransactionsByPriceAndNonce, Transactions) {
 	// Initialize a price based heap with the head transactions
 	heads := TxByPrice{}
 	heads.payersSwap = payersSwap
+	heads.baseFee = baseFee
 	specialTxs := Transactions{}
 	for _, accTxs := range txs {
 		from, _ := Sender(signer, accTxs[0])
@@ -691,12 +913,15 @@ type Message struct {
 	amount          *big.Int
 	gasLimit        uint64
 	gasPrice        *big.Int
+	gasFeeCap       *big.Int
+	gasTipCap       *big.Int
 	data            []byte
+	accessList      AccessList
 	checkNonce      bool
 	balanceTokenFee *big.Int
 }
 
-func NewMessage(from common.Address, to *common.Address, nonce uint64, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, checkNonce bool, balanceTokenFee *big.Int) Message {
+func NewMessage(from common.Address, to *common.Address, nonce uint64, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, accessList AccessList, checkNonce bool, balanceTokenFee *big.Int) Message {
 	if balanceTokenFee != nil {
 		gasPrice = common.TRC21GasPrice
 	}
@@ -707,7 +932,10 @@ func NewMessage(from common.Address, to *common.Address, nonce uint64, amount *b
 		amount:          amount,
 		gasLimit:        gasLimit,
 		gasPrice:        gasPrice,
+		gasFeeCap:       gasPrice,
+		gasTipCap:       gasPrice,
 		data:            data,
+		accessList:      accessList,
 		checkNonce:      checkNonce,
 		balanceTokenFee: balanceTokenFee,
 	}
@@ -717,8 +945,11 @@ func (m Message) From() common.Address      { return m.from }
 func (m Message) BalanceTokenFee() *big.Int { return m.balanceTokenFee }
 func (m Message) To() *common.Address       { return m.to }
 func (m Message) GasPrice() *big.Int        { return m.gasPrice }
+func (m Message) GasFeeCap() *big.Int       { return m.gasFeeCap }
+func (m Message) GasTipCap() *big.Int       { return m.gasTipCap }
 func (m Message) Value() *big.Int           { return m.amount }
 func (m Message) Gas() uint64               { return m.gasLimit }
 func (m Message) Nonce() uint64             { return m.nonce }
 func (m Message) Data() []byte              { return m.data }
+func (m Message) AccessList() AccessList    { return m.accessList }
 func (m Message) CheckNonce() bool          { return m.checkNonce }
@@ -144,7 +144,7 @@ func TestTransactionPriceNonceSort(t *testing.T) {
 		}
 	}
 	// Sort the transactions and cross check the nonce ordering
-	txset, _ := NewTransactionsByPriceAndNonce(signer, groups, nil, map[common.Address]*big.Int{})
+	txset, _ := NewTransactionsByPriceAndNonce(signer, groups, nil, map[common.Address]*big.Int{}, nil)
 
 	txs := Transactions{}
 	for tx := txset.Peek(); tx != nil; tx = txset.Peek() {
@@ -198,15 +198,28 @@ func TestTransactionJSON(t *testing.T) {
 	if err != nil {
 		t.Fatalf("could not generate key: %v", err)
 	}
-	signer := NewEIP155Signer(common.Big1)
+	signer := NewEIP2930Signer(common.Big1)
 
 	for i := uint64(0); i < 25; i++ {
 		var tx *Transaction
-		switch i % 2 {
+		switch i % 4 {
 		case 0:
 			tx = NewTransaction(i, common.Address{1}, common.Big0, 1, common.Big2, []byte("abcdef"))
 		case 1:
 			tx = NewContractCreation(i, common.Big0, 1, common.Big2, []byte("abcdef"))
+		case 2:
+			tx = NewTx(&AccessListTx{
+				ChainID:    common.Big1,
+				Nonce:      i,
+				GasPrice:   common.Big2,
+				Gas:        1,
+				To:         &common.Address{1},
+				Value:      common.Big0,
+				Data:       []byte("abcdef"),
+				AccessList: AccessList{{Address: common.Address{2}, StorageKeys: []common.Hash{{3}}}},
+			})
+		case 3:
+			tx = NewSystemTransaction(common.Big1, i, common.HexToAddress(common.TomoXAddr), 1, []byte("abcdef"))
 		}
 
 		tx, err := SignTx(tx, signer, key)
@@ -228,8 +241,276 @@ func TestTransactionJSON(t *testing.T) {
 		if tx.Hash() != parsedTx.Hash() {
 			t.Errorf("parsed tx differs from original tx, want %v, got %v", tx, parsedTx)
 		}
+		if tx.Type() != parsedTx.Type() {
+			t.Errorf("invalid type, want %d, got %d", tx.Type(), parsedTx.Type())
+		}
 		if tx.ChainId().Cmp(parsedTx.ChainId()) != 0 {
 			t.Errorf("invalid chain id, want %d, got %d", tx.ChainId(), parsedTx.ChainId())
 		}
 	}
 }
+
+// Tests that typed transactions survive both the consensus RLP envelope and
+// the canonical binary encoding, and that their sender can be recovered.
+func TestTypedTransactionCoding(t *testing.T) {
+	key, addr := defaultTestKey()
+	signer := NewEIP2930Signer(big.NewInt(88))
+
+	to := common.Address{0x01}
+	txs := []*Transaction{
+		NewTransaction(0, to, big.NewInt(1), 21000, big.NewInt(2), nil),
+		NewTx(&AccessListTx{
+			ChainID:  big.NewInt(88),
+			Nonce:    1,
+			GasPrice: big.NewInt(2),
+			Gas:      30000,
+			To:       &to,
+			Value:    big.NewInt(1),
+			AccessList: AccessList{
+				{Address: to, StorageKeys: []common.Hash{{0x01}, {0x02}}},
+			},
+		}),
+		NewTx(&AccessListTx{ChainID: big.NewInt(88), Nonce: 2, GasPrice: big.NewInt(2), Gas: 60000, Value: big.NewInt(0), Data: []byte{0x60}}),
+		NewSystemTransaction(big.NewInt(88), 3, common.HexToAddress(common.BlockSigners), 200000, []byte{0xe3, 0x41}),
+	}
+	for i, tx := range txs {
+		signed, err := SignTx(tx, signer, key)
+		if err != nil {
+			t.Fatalf("tx %d: failed to sign: %v", i, err)
+		}
+		// Round trip through the RLP envelope used in blocks and on the wire
+		enc, err := rlp.EncodeToBytes(signed)
+		if err != nil {
+			t.Fatalf("tx %d: failed to encode: %v", i, err)
+		}
+		decoded, err := decodeTx(enc)
+		if err != nil {
+			t.Fatalf("tx %d: failed to decode: %v", i, err)
+		}
+		if decoded.Hash() != signed.Hash() {
+			t.Errorf("tx %d: hash mismatch after rlp round trip: have %x, want %x", i, decoded.Hash(), signed.Hash())
+		}
+		if decoded.Type() != tx.Type() {
+			t.Errorf("tx %d: type mismatch: have %d, want %d", i, decoded.Type(), tx.Type())
+		}
+		// Round trip through the canonical encoding
+		bin, err := signed.MarshalBinary()
+		if err != nil {
+			t.Fatalf("tx %d: failed to marshal: %v", i, err)
+		}
+		parsed := new(Transaction)
+		if err := parsed.UnmarshalBinary(bin); err != nil {
+			t.Fatalf("tx %d: failed to unmarshal: %v", i, err)
+		}
+		if parsed.Hash() != signed.Hash() {
+			t.Errorf("tx %d: hash mismatch after binary round trip: have %x, want %x", i, parsed.Hash(), signed.Hash())
+		}
+		from, err := Sender(signer, parsed)
+		if err != nil {
+			t.Fatalf("tx %d: failed to derive sender: %v", i, err)
+		}
+		if from != addr {
+			t.Errorf("tx %d: sender mismatch: have %x, want %x", i, from, addr)
+		}
+	}
+	// Typed transactions must be rejected by the pre-2718 signers
+	if _, err := Sender(NewEIP155Signer(big.NewInt(88)), txs[1]); err != ErrTxTypeNotSupported {
+		t.Errorf("eip155 signer accepted a typed transaction: %v", err)
+	}
+	// A transaction signed for another chain must not be accepted
+	signed, _ := SignTx(txs[3], signer, key)
+	if _, err := Sender(NewEIP2930Signer(big.NewInt(89)), signed); err == nil {
+		t.Errorf("sender derived for a transaction of another chain")
+	}
+}
+
+// Tests that only system and legacy transactions are classified as calls to
+// the system contracts.
+func TestSystemTransactionClassification(t *testing.T) {
+	tomox := common.HexToAddress(common.TomoXAddr)
+
+	system := NewSystemTransaction(big.NewInt(88), 0, tomox, 100000, nil)
+	if !system.IsSystemTransaction() || !system.IsTradingTransaction() || !system.IsSkipNonceTransaction() {
+		t.Errorf("system transaction not classified as trading transaction")
+	}
+	signing := NewSystemTransaction(big.NewInt(88), 0, common.HexToAddress(common.BlockSigners), 100000, nil)
+	if !signing.IsSpecialTransaction() {
+		t.Errorf("system transaction to the block signers not classified as special")
+	}
+	if system.GasPrice().Sign() != 0 || system.Value().Sign() != 0 {
+		t.Errorf("system transaction carries a price or value: %v %v", system.GasPrice(), system.Value())
+	}
+	legacy := NewTransaction(0, tomox, big.NewInt(0), 100000, big.NewInt(0), nil)
+	if legacy.IsSystemTransaction() || !legacy.IsTradingTransaction() {
+		t.Errorf("legacy transaction misclassified")
+	}
+	accessList := NewTx(&AccessListTx{ChainID: big.NewInt(88), To: &tomox, GasPrice: big.NewInt(0), Value: big.NewInt(0)})
+	if accessList.IsTradingTransaction() || accessList.IsSkipNonceTransaction() {
+		t.Errorf("access list transaction classified as a system call")
+	}
+	if !IsSystemAddress(tomox) || IsSystemAddress(common.Address{0x01}) {
+		t.Errorf("system address set mismatch")
+	}
+}
+
+// Tests the encoding, signing and fee accessors of EIP-1559 dynamic fee
+// transactions.
+func TestDynamicFeeTransaction(t *testing.T) {
+	key, addr := defaultTestKey()
+	signer := NewLondonSigner(big.NewInt(88))
+
+	to := common.Address{0x01}
+	tx, err := SignTx(NewTx(&DynamicFeeTx{
+		ChainID:    big.NewInt(88),
+		Nonce:      5,
+		GasTipCap:  big.NewInt(10),
+		GasFeeCap:  big.NewInt(100),
+		Gas:        30000,
+		To:         &to,
+		Value:      big.NewInt(1),
+		AccessList: AccessList{{Address: to, StorageKeys: []common.Hash{{0x01}}}},
+	}), signer, key)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	// Round trip through the canonical and the JSON encodings
+	bin, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	if bin[0] != DynamicFeeTxType {
+		t.Errorf("wrong type byte: have %d, want %d", bin[0], DynamicFeeTxType)
+	}
+	parsed := new(Transaction)
+	if err := parsed.UnmarshalBinary(bin); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	js, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatalf("failed to json marshal: %v", err)
+	}
+	fromJSON := new(Transaction)
+	if err := json.Unmarshal(js, fromJSON); err != nil {
+		t.Fatalf("failed to json unmarshal: %v", err)
+	}
+	for name, have := range map[string]*Transaction{"binary": parsed, "json": fromJSON} {
+		if have.Hash() != tx.Hash() {
+			t.Errorf("%s: hash mismatch: have %x, want %x", name, have.Hash(), tx.Hash())
+		}
+		if have.GasTipCap().Cmp(big.NewInt(10)) != 0 || have.GasFeeCap().Cmp(big.NewInt(100)) != 0 {
+			t.Errorf("%s: fee mismatch: tip %v, fee cap %v", name, have.GasTipCap(), have.GasFeeCap())
+		}
+		if from, err := Sender(signer, have); err != nil || from != addr {
+			t.Errorf("%s: sender mismatch: have %x (%v), want %x", name, from, err, addr)
+		}
+	}
+	// The best guess of the signer recovers the sender too, as the black-list needs
+	if from := tx.From(); from == nil || *from != addr {
+		t.Errorf("guessed sender mismatch: have %x, want %x", from, addr)
+	}
+	// Dynamic fee transactions are unknown to the pre-1559 signers
+	if _, err := Sender(NewEIP2930Signer(big.NewInt(88)), parsed); err != ErrTxTypeNotSupported {
+		t.Errorf("eip2930 signer accepted a dynamic fee transaction: %v", err)
+	}
+	// The cost is paid at the fee cap
+	if have, want := tx.Cost(), big.NewInt(30000*100+1); have.Cmp(want) != 0 {
+		t.Errorf("cost mismatch: have %v, want %v", have, want)
+	}
+	// The tip is capped by what remains of the fee cap above the base fee
+	for _, test := range []struct {
+		baseFee int64
+		tip     int64
+		err     error
+	}{
+		{50, 10, nil},
+		{95, 5, nil},
+		{100, 0, nil},
+		{110, -10, ErrGasFeeCapTooLow},
+	} {
+		tip, err := tx.EffectiveGasTip(big.NewInt(test.baseFee))
+		if tip.Int64() != test.tip || err != test.err {
+			t.Errorf("base fee %d: have tip %v (%v), want %d (%v)", test.baseFee, tip, err, test.tip, test.err)
+		}
+	}
+	// Legacy transactions pay their whole gas price
+	legacy := NewTransaction(0, to, big.NewInt(0), 21000, big.NewInt(70), nil)
+	if tip, _ := legacy.EffectiveGasTip(big.NewInt(50)); tip.Int64() != 20 {
+		t.Errorf("legacy tip mismatch: have %v, want 20", tip)
+	}
+}
+
+// Tests that the gas price of TRC21 token-paid transactions follows the base fee
+// once it rises above the fixed TRC21 price.
+func TestTRC21GasPrice(t *testing.T) {
+	after := new(big.Int).Add(common.TIPTRC21Fee, common.Big1)
+	if have := TRC21GasPrice(common.TIPTRC21Fee, nil); have.Cmp(common.TRC21GasPriceBefore) != 0 {
+		t.Errorf("before TIPTRC21Fee: have %v, want %v", have, common.TRC21GasPriceBefore)
+	}
+	if have := TRC21GasPrice(after, nil); have.Cmp(common.TRC21GasPrice) != 0 {
+		t.Errorf("without base fee: have %v, want %v", have, common.TRC21GasPrice)
+	}
+	low := new(big.Int).Sub(common.TRC21GasPrice, common.Big1)
+	if have := TRC21GasPrice(after, low); have.Cmp(common.TRC21GasPrice) != 0 {
+		t.Errorf("low base fee: have %v, want %v", have, common.TRC21GasPrice)
+	}
+	high := new(big.Int).Mul(common.TRC21GasPrice, common.Big2)
+	if have := TRC21GasPrice(after, high); have.Cmp(high) != 0 {
+		t.Errorf("high base fee: have %v, want %v", have, high)
+	}
+}
+
+// Tests that the cost of TRC21 token-paid transactions is priced like their
+// execution, following the base fee above the fixed TRC21 price.
+func TestTRC21Cost(t *testing.T) {
+	var (
+		after = new(big.Int).Add(common.TIPTRC21Fee, common.Big1)
+		high  = new(big.Int).Mul(common.TRC21GasPrice, common.Big2)
+		tx    = NewTransaction(0, common.Address{}, big.NewInt(10), 21000, common.TRC21GasPrice, nil)
+	)
+	want := new(big.Int).Add(new(big.Int).Mul(common.TRC21GasPrice, big.NewInt(21000)), big.NewInt(10))
+	if have := tx.TRC21Cost(after, nil); have.Cmp(want) != 0 {
+		t.Errorf("without base fee: have %v, want %v", have, want)
+	}
+	want = new(big.Int).Add(new(big.Int).Mul(high, big.NewInt(21000)), big.NewInt(10))
+	if have := tx.TRC21Cost(after, high); have.Cmp(want) != 0 {
+		t.Errorf("high base fee: have %v, want %v", have, want)
+	}
+}
+
+// Tests that after the EIP-1559 fork transactions are sorted by the tip they
+// pay over the base fee, not by their fee cap.
+func TestTransactionTipSort(t *testing.T) {
+	signer := NewLondonSigner(big.NewInt(88))
+	baseFee := big.NewInt(100)
+
+	var (
+		keys   = make([]*ecdsa.PrivateKey, 3)
+		groups = map[common.Address]Transactions{}
+	)
+	// The first account has the highest fee cap but the lowest effective tip
+	fees := []struct{ tip, feeCap int64 }{{50, 110}, {20, 300}, {30, 200}}
+	for i, fee := range fees {
+		keys[i], _ = crypto.GenerateKey()
+		tx, _ := SignTx(NewTx(&DynamicFeeTx{
+			ChainID:   big.NewInt(88),
+			GasTipCap: big.NewInt(fee.tip),
+			GasFeeCap: big.NewInt(fee.feeCap),
+			Gas:       21000,
+			To:        &common.Address{},
+			Value:     big.NewInt(0),
+		}), signer, keys[i])
+		addr := crypto.PubkeyToAddress(keys[i].PublicKey)
+		groups[addr] = append(groups[addr], tx)
+	}
+	txset, _ := NewTransactionsByPriceAndNonce(signer, groups, nil, map[common.Address]*big.Int{}, baseFee)
+
+	var tips []int64
+	for tx := txset.Peek(); tx != nil; tx = txset.Peek() {
+		tip, _ := tx.EffectiveGasTip(baseFee)
+		tips = append(tips, tip.Int64())
+		txset.Shift()
+	}
+	if len(tips) != 3 || tips[0] != 30 || tips[1] != 20 || tips[2] != 10 {
+		t.Errorf("wrong tip order: have %v, want [30 20 10]", tips)
+	}
+}
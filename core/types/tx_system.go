@@ -0,0 +1,86 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/69th-byte/sdexchain/common"
+)
+
+// SystemTx is the data of the system transactions the masternodes send to the
+// consensus and TomoX contracts: block signing, randomization and the trading
+// and lending matching results. System transactions always have a recipient,
+// carry no value and pay no gas.
+type SystemTx struct {
+	ChainID *big.Int       // destination chain ID
+	Nonce   uint64         // nonce of sender account
+	Gas     uint64         // gas limit
+	To      common.Address // system contract called
+	Data    []byte         // contract invocation input data
+	V, R, S *big.Int       // signature values
+}
+
+// copy creates a deep copy of the transaction data and initializes all fields.
+func (tx *SystemTx) copy() TxData {
+	cpy := &SystemTx{
+		Nonce: tx.Nonce,
+		To:    tx.To,
+		Data:  common.CopyBytes(tx.Data),
+		Gas:   tx.Gas,
+		// These are copied below.
+		ChainID: new(big.Int),
+		V:       new(big.Int),
+		R:       new(big.Int),
+		S:       new(big.Int),
+	}
+	if tx.ChainID != nil {
+		cpy.ChainID.Set(tx.ChainID)
+	}
+	if tx.V != nil {
+		cpy.V.Set(tx.V)
+	}
+	if tx.R != nil {
+		cpy.R.Set(tx.R)
+	}
+	if tx.S != nil {
+		cpy.S.Set(tx.S)
+	}
+	return cpy
+}
+
+// accessors for innerTx.
+
+func (tx *SystemTx) txType() byte           { return SystemTxType }
+func (tx *SystemTx) chainID() *big.Int      { return tx.ChainID }
+func (tx *SystemTx) accessList() AccessList { return nil }
+func (tx *SystemTx) data() []byte           { return tx.Data }
+func (tx *SystemTx) gas() uint64            { return tx.Gas }
+func (tx *SystemTx) gasFeeCap() *big.Int    { return new(big.Int) }
+func (tx *SystemTx) gasTipCap() *big.Int    { return new(big.Int) }
+func (tx *SystemTx) gasPrice() *big.Int     { return new(big.Int) }
+func (tx *SystemTx) value() *big.Int        { return new(big.Int) }
+func (tx *SystemTx) nonce() uint64          { return tx.Nonce }
+func (tx *SystemTx) to() *common.Address    { return &tx.To }
+
+func (tx *SystemTx) rawSignatureValues() (v, r, s *big.Int) {
+	return tx.V, tx.R, tx.S
+}
+
+func (tx *SystemTx) setSignatureValues(chainID, v, r, s *big.Int) {
+	tx.ChainID, tx.V, tx.R, tx.S = chainID, v, r, s
+}
@@ -0,0 +1,349 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/common/math"
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/crypto/eip712"
+	"github.com/69th-byte/sdexchain/params"
+)
+
+// Domain of the EIP-712 typed data of order and lending transactions.
+const (
+	TypedDataName    = "TomoX"
+	TypedDataVersion = "1"
+)
+
+var errUnknownLendingType = errors.New("unknown lending transaction type")
+
+// OrderTypes are the EIP-712 struct types of order transactions.
+var OrderTypes = eip712.Types{
+	"Order": {
+		{Name: "exchangeAddress", Type: "address"},
+		{Name: "userAddress", Type: "address"},
+		{Name: "baseToken", Type: "address"},
+		{Name: "quoteToken", Type: "address"},
+		{Name: "quantity", Type: "uint256"},
+		{Name: "price", Type: "uint256"},
+		{Name: "side", Type: "string"},
+		{Name: "status", Type: "string"},
+		{Name: "type", Type: "string"},
+		{Name: "nonce", Type: "uint256"},
+		{Name: "route", Type: "address[]"},
+	},
+	"OrderCancel": {
+		{Name: "orderHash", Type: "bytes32"},
+		{Name: "nonce", Type: "uint256"},
+		{Name: "userAddress", Type: "address"},
+		{Name: "orderId", Type: "uint256"},
+		{Name: "status", Type: "string"},
+		{Name: "exchangeAddress", Type: "address"},
+		{Name: "baseToken", Type: "address"},
+		{Name: "quoteToken", Type: "address"},
+	},
+}
+
+// LendingTypes are the EIP-712 struct types of lending transactions.
+var LendingTypes = eip712.Types{
+	"Lending": {
+		{Name: "relayerAddress", Type: "address"},
+		{Name: "userAddress", Type: "address"},
+		{Name: "collateralToken", Type: "address"},
+		{Name: "lendingToken", Type: "address"},
+		{Name: "quantity", Type: "uint256"},
+		{Name: "term", Type: "uint256"},
+		{Name: "interest", Type: "uint256"},
+		{Name: "side", Type: "string"},
+		{Name: "status", Type: "string"},
+		{Name: "type", Type: "string"},
+		{Name: "nonce", Type: "uint256"},
+		{Name: "autoTopUp", Type: "bool"},
+	},
+	"LendingCancel": {
+		{Name: "nonce", Type: "uint256"},
+		{Name: "status", Type: "string"},
+		{Name: "relayerAddress", Type: "address"},
+		{Name: "userAddress", Type: "address"},
+		{Name: "lendingToken", Type: "address"},
+		{Name: "term", Type: "uint256"},
+		{Name: "lendingId", Type: "uint256"},
+	},
+	"LendingRepay": {
+		{Name: "nonce", Type: "uint256"},
+		{Name: "status", Type: "string"},
+		{Name: "relayerAddress", Type: "address"},
+		{Name: "userAddress", Type: "address"},
+		{Name: "lendingToken", Type: "address"},
+		{Name: "term", Type: "uint256"},
+		{Name: "tradeId", Type: "uint256"},
+		{Name: "type", Type: "string"},
+	},
+	"LendingTopUp": {
+		{Name: "nonce", Type: "uint256"},
+		{Name: "status", Type: "string"},
+		{Name: "relayerAddress", Type: "address"},
+		{Name: "userAddress", Type: "address"},
+		{Name: "lendingToken", Type: "address"},
+		{Name: "term", Type: "uint256"},
+		{Name: "tradeId", Type: "uint256"},
+		{Name: "quantity", Type: "uint256"},
+		{Name: "type", Type: "string"},
+	},
+}
+
+// TypedDataDomain returns the EIP-712 domain order and lending transactions are
+// signed in on the given chain, bound to the TomoX listing contract.
+func TypedDataDomain(chainID *big.Int) eip712.Domain {
+	contract := common.TomoXListingSMC
+	if common.IsTestnet {
+		contract = common.TomoXListingSMCTestNet
+	}
+	if chainID == nil {
+		chainID = new(big.Int)
+	}
+	return eip712.Domain{
+		Name:              TypedDataName,
+		Version:           TypedDataVersion,
+		ChainId:           (*math.HexOrDecimal256)(new(big.Int).Set(chainID)),
+		VerifyingContract: &contract,
+	}
+}
+
+// OrderTypedData returns the EIP-712 typed data of an order transaction, the
+// message browser wallets show to the user when signing it.
+func OrderTypedData(tx *OrderTransaction, chainID *big.Int) *eip712.TypedData {
+	td := &eip712.TypedData{Types: OrderTypes, Domain: TypedDataDomain(chainID)}
+	if tx.IsCancelledOrder() {
+		td.PrimaryType = "OrderCancel"
+		td.Message = map[string]interface{}{
+			"orderHash":       tx.OrderHash(),
+			"nonce":           tx.Nonce(),
+			"userAddress":     tx.UserAddress(),
+			"orderId":         tx.OrderID(),
+			"status":          tx.Status(),
+			"exchangeAddress": tx.ExchangeAddress(),
+			"baseToken":       tx.BaseToken(),
+			"quoteToken":      tx.QuoteToken(),
+		}
+		return td
+	}
+	route := tx.Route()
+	if route == nil {
+		route = []common.Address{}
+	}
+	td.PrimaryType = "Order"
+	td.Message = map[string]interface{}{
+		"exchangeAddress": tx.ExchangeAddress(),
+		"userAddress":     tx.UserAddress(),
+		"baseToken":       tx.BaseToken(),
+		"quoteToken":      tx.QuoteToken(),
+		"quantity":        tx.Quantity(),
+		"price":           tx.Price(),
+		"side":            tx.Side(),
+		"status":          tx.Status(),
+		"type":            tx.Type(),
+		"nonce":           tx.Nonce(),
+		"route":           route,
+	}
+	return td
+}
+
+// LendingTypedData returns the EIP-712 typed data of a lending transaction, the
+// message browser wallets show to the user when signing it.
+func LendingTypedData(tx *LendingTransaction, chainID *big.Int) (*eip712.TypedData, error) {
+	td := &eip712.TypedData{Types: LendingTypes, Domain: TypedDataDomain(chainID)}
+	switch {
+	case tx.IsCancelledLending():
+		td.PrimaryType = "LendingCancel"
+		td.Message = map[string]interface{}{
+			"nonce":          tx.Nonce(),
+			"status":         tx.Status(),
+			"relayerAddress": tx.RelayerAddress(),
+			"userAddress":    tx.UserAddress(),
+			"lendingToken":   tx.LendingToken(),
+			"term":           tx.Term(),
+			"lendingId":      tx.LendingId(),
+		}
+	case tx.IsCreatedLending():
+		td.PrimaryType = "Lending"
+		td.Message = map[string]interface{}{
+			"relayerAddress":  tx.RelayerAddress(),
+			"userAddress":     tx.UserAddress(),
+			"collateralToken": tx.CollateralToken(),
+			"lendingToken":    tx.LendingToken(),
+			"quantity":        tx.Quantity(),
+			"term":            tx.Term(),
+			"interest":        tx.Interest(),
+			"side":            tx.Side(),
+			"status":          tx.Status(),
+			"type":            tx.Type(),
+			"nonce":           tx.Nonce(),
+			"autoTopUp":       tx.AutoTopUp(),
+		}
+	case tx.IsRepayLending():
+		td.PrimaryType = "LendingRepay"
+		td.Message = map[string]interface{}{
+			"nonce":          tx.Nonce(),
+			"status":         tx.Status(),
+			"relayerAddress": tx.RelayerAddress(),
+			"userAddress":    tx.UserAddress(),
+			"lendingToken":   tx.LendingToken(),
+			"term":           tx.Term(),
+			"tradeId":        tx.LendingTradeId(),
+			"type":           tx.Type(),
+		}
+	case tx.IsTopupLending():
+		td.PrimaryType = "LendingTopUp"
+		td.Message = map[string]interface{}{
+			"nonce":          tx.Nonce(),
+			"status":         tx.Status(),
+			"relayerAddress": tx.RelayerAddress(),
+			"userAddress":    tx.UserAddress(),
+			"lendingToken":   tx.LendingToken(),
+			"term":           tx.Term(),
+			"tradeId":        tx.LendingTradeId(),
+			"quantity":       tx.Quantity(),
+			"type":           tx.Type(),
+		}
+	default:
+		return nil, errUnknownLendingType
+	}
+	return td, nil
+}
+
+// MakeOrderSigner returns the order signer accepted at the given block number.
+func MakeOrderSigner(config *params.ChainConfig, blockNumber *big.Int) OrderSigner {
+	if config.IsTIPTomoXTypedData(blockNumber) {
+		return NewOrderTypedSigner(config.ChainId)
+	}
+	return OrderTxSigner{}
+}
+
+// MakeLendingSigner returns the lending signer accepted at the given block number.
+func MakeLendingSigner(config *params.ChainConfig, blockNumber *big.Int) LendingSigner {
+	if config.IsTIPTomoXTypedData(blockNumber) {
+		return NewLendingTypedSigner(config.ChainId)
+	}
+	return LendingTxSigner{}
+}
+
+// OrderTypedSigner signs order transactions over their EIP-712 typed data. It
+// still accepts the signatures of the legacy hash, so that the orders signed
+// before the fork remain valid.
+type OrderTypedSigner struct {
+	OrderTxSigner
+	chainID *big.Int
+}
+
+// NewOrderTypedSigner returns an EIP-712 order signer for the given chain.
+func NewOrderTypedSigner(chainID *big.Int) OrderTypedSigner {
+	if chainID == nil {
+		chainID = new(big.Int)
+	}
+	return OrderTypedSigner{chainID: chainID}
+}
+
+// Equal returns true if the given signer is an EIP-712 signer of the same chain.
+func (s OrderTypedSigner) Equal(s2 OrderSigner) bool {
+	typed, ok := s2.(OrderTypedSigner)
+	return ok && typed.chainID.Cmp(s.chainID) == 0
+}
+
+// Hash returns the EIP-712 hash of the typed data of the order to be signed.
+func (s OrderTypedSigner) Hash(tx *OrderTransaction) common.Hash {
+	hash, err := OrderTypedData(tx, s.chainID).Hash()
+	if err != nil {
+		return common.Hash{}
+	}
+	return hash
+}
+
+// Sender returns the user address if the order is signed by it with either the
+// legacy or the EIP-712 scheme, otherwise the signer of the typed data.
+func (s OrderTypedSigner) Sender(tx *OrderTransaction) (common.Address, error) {
+	if from, err := s.OrderTxSigner.Sender(tx); err == nil && from == tx.UserAddress() {
+		return from, nil
+	}
+	V, R, S := tx.Signature()
+	return recoverTypedSigner(s.Hash(tx), V, R, S)
+}
+
+// LendingTypedSigner signs lending transactions over their EIP-712 typed data.
+// It still accepts the signatures of the legacy hash, so that the lendings signed
+// before the fork remain valid.
+type LendingTypedSigner struct {
+	LendingTxSigner
+	chainID *big.Int
+}
+
+// NewLendingTypedSigner returns an EIP-712 lending signer for the given chain.
+func NewLendingTypedSigner(chainID *big.Int) LendingTypedSigner {
+	if chainID == nil {
+		chainID = new(big.Int)
+	}
+	return LendingTypedSigner{chainID: chainID}
+}
+
+// Equal returns true if the given signer is an EIP-712 signer of the same chain.
+func (s LendingTypedSigner) Equal(s2 LendingSigner) bool {
+	typed, ok := s2.(LendingTypedSigner)
+	return ok && typed.chainID.Cmp(s.chainID) == 0
+}
+
+// Hash returns the EIP-712 hash of the typed data of the lending to be signed.
+func (s LendingTypedSigner) Hash(tx *LendingTransaction) common.Hash {
+	td, err := LendingTypedData(tx, s.chainID)
+	if err != nil {
+		return common.Hash{}
+	}
+	hash, err := td.Hash()
+	if err != nil {
+		return common.Hash{}
+	}
+	return hash
+}
+
+// Sender returns the user address if the lending is signed by it with either the
+// legacy or the EIP-712 scheme, otherwise the signer of the typed data.
+func (s LendingTypedSigner) Sender(tx *LendingTransaction) (common.Address, error) {
+	if from, err := s.LendingTxSigner.Sender(tx); err == nil && from == tx.UserAddress() {
+		return from, nil
+	}
+	V, R, S := tx.Signature()
+	return recoverTypedSigner(s.Hash(tx), V, R, S)
+}
+
+// recoverTypedSigner returns the address that signed an EIP-712 hash.
+func recoverTypedSigner(hash common.Hash, V, R, S *big.Int) (common.Address, error) {
+	if hash == (common.Hash{}) {
+		return common.Address{}, ErrInvalidOrderSig
+	}
+	sig, err := MarshalSignature(R, S, V)
+	if err != nil {
+		return common.Address{}, err
+	}
+	pubKey, err := crypto.SigToPub(hash[:], sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/crypto"
+)
+
+// Tests that orders signed over their EIP-712 typed data are only accepted by
+// the typed signer, while legacy signatures remain valid with it.
+func TestOrderTypedSigning(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	newOrder := func() *OrderTransaction {
+		return NewOrderTransaction(1, big.NewInt(100), big.NewInt(5), common.HexToAddress("0x1"), addr, common.HexToAddress("0x2"), common.HexToAddress("0x3"), OrderStatusNew, "BUY", OrderTypeLo, common.Hash{}, 0)
+	}
+	signer := NewOrderTypedSigner(big.NewInt(88))
+
+	tx, err := OrderSignTx(newOrder(), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, err := OrderSender(signer, tx); err != nil || from != addr {
+		t.Fatalf("typed sender mismatch: have %x (%v), want %x", from, err, addr)
+	}
+	if from, _ := OrderSender(OrderTxSigner{}, tx); from == addr {
+		t.Fatal("typed signature accepted by the legacy signer")
+	}
+	if from, _ := OrderSender(NewOrderTypedSigner(big.NewInt(89)), tx); from == addr {
+		t.Fatal("typed signature accepted on another chain")
+	}
+
+	legacy, err := OrderSignTx(newOrder(), OrderTxSigner{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, err := OrderSender(signer, legacy); err != nil || from != addr {
+		t.Fatalf("legacy sender mismatch: have %x (%v), want %x", from, err, addr)
+	}
+}
+
+// Tests that lendings signed over their EIP-712 typed data are only accepted by
+// the typed signer, while legacy signatures remain valid with it.
+func TestLendingTypedSigning(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	newLending := func() *LendingTransaction {
+		return NewLendingTransaction(1, big.NewInt(100), 10, 86400, common.HexToAddress("0x1"), addr, common.HexToAddress("0x2"), common.HexToAddress("0x3"), true, LendingStatusNew, LendingSideInvest, LendingTypeLo, common.Hash{}, 0, 0, "")
+	}
+	signer := NewLendingTypedSigner(big.NewInt(88))
+
+	tx, err := LendingSignTx(newLending(), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, err := LendingSender(signer, tx); err != nil || from != addr {
+		t.Fatalf("typed sender mismatch: have %x (%v), want %x", from, err, addr)
+	}
+	if from, _ := LendingSender(LendingTxSigner{}, tx); from == addr {
+		t.Fatal("typed signature accepted by the legacy signer")
+	}
+
+	legacy, err := LendingSignTx(newLending(), LendingTxSigner{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, err := LendingSender(signer, legacy); err != nil || from != addr {
+		t.Fatalf("legacy sender mismatch: have %x (%v), want %x", from, err, addr)
+	}
+}
+
+// Tests that all the orders of users signing the typed data are processed in
+// nonce order, next to the orders of users signing the legacy hash.
+func TestOrderTypedByNonce(t *testing.T) {
+	signer := NewOrderTypedSigner(big.NewInt(88))
+	typedKey, _ := crypto.GenerateKey()
+	legacyKey, _ := crypto.GenerateKey()
+
+	pending := make(map[common.Address]OrderTransactions)
+	for _, user := range []struct {
+		key    *ecdsa.PrivateKey
+		signer OrderSigner
+	}{{typedKey, signer}, {legacyKey, OrderTxSigner{}}} {
+		addr := crypto.PubkeyToAddress(user.key.PublicKey)
+		for nonce := uint64(0); nonce < 10; nonce++ {
+			tx := NewOrderTransaction(nonce, big.NewInt(100), big.NewInt(5), common.HexToAddress("0x1"), addr, common.HexToAddress("0x2"), common.HexToAddress("0x3"), OrderStatusNew, "BUY", OrderTypeLo, common.Hash{}, 0)
+			signed, err := OrderSignTx(tx, user.signer, user.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pending[addr] = append(pending[addr], signed)
+		}
+	}
+	next := make(map[common.Address]uint64)
+	txs := NewOrderTransactionByNonce(signer, pending)
+	for tx := txs.Peek(); tx != nil; tx = txs.Peek() {
+		if from, err := OrderSender(signer, tx); err != nil || from != tx.UserAddress() {
+			t.Fatalf("sender mismatch: have %x (%v), want %x", from, err, tx.UserAddress())
+		}
+		if tx.Nonce() != next[tx.UserAddress()] {
+			t.Fatalf("nonce mismatch for %x: have %d, want %d", tx.UserAddress(), tx.Nonce(), next[tx.UserAddress()])
+		}
+		next[tx.UserAddress()]++
+		txs.Shift()
+	}
+	for _, key := range []*ecdsa.PrivateKey{typedKey, legacyKey} {
+		if addr := crypto.PubkeyToAddress(key.PublicKey); next[addr] != 10 {
+			t.Errorf("processed order count mismatch for %x: have %d, want 10", addr, next[addr])
+		}
+	}
+}
+
+// Tests that all the lendings of users signing the typed data are processed in
+// nonce order, next to the lendings of users signing the legacy hash.
+func TestLendingTypedByNonce(t *testing.T) {
+	signer := NewLendingTypedSigner(big.NewInt(88))
+	typedKey, _ := crypto.GenerateKey()
+	legacyKey, _ := crypto.GenerateKey()
+
+	pending := make(map[common.Address]LendingTransactions)
+	for _, user := range []struct {
+		key    *ecdsa.PrivateKey
+		signer LendingSigner
+	}{{typedKey, signer}, {legacyKey, LendingTxSigner{}}} {
+		addr := crypto.PubkeyToAddress(user.key.PublicKey)
+		for nonce := uint64(0); nonce < 10; nonce++ {
+			tx := NewLendingTransaction(nonce, big.NewInt(100), 10, 86400, common.HexToAddress("0x1"), addr, common.HexToAddress("0x2"), common.HexToAddress("0x3"), true, LendingStatusNew, LendingSideInvest, LendingTypeLo, common.Hash{}, 0, 0, "")
+			signed, err := LendingSignTx(tx, user.signer, user.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pending[addr] = append(pending[addr], signed)
+		}
+	}
+	next := make(map[common.Address]uint64)
+	txs := NewLendingTransactionByNonce(signer, pending)
+	for tx := txs.Peek(); tx != nil; tx = txs.Peek() {
+		if from, err := LendingSender(signer, tx); err != nil || from != tx.UserAddress() {
+			t.Fatalf("sender mismatch: have %x (%v), want %x", from, err, tx.UserAddress())
+		}
+		if tx.Nonce() != next[tx.UserAddress()] {
+			t.Fatalf("nonce mismatch for %x: have %d, want %d", tx.UserAddress(), tx.Nonce(), next[tx.UserAddress()])
+		}
+		next[tx.UserAddress()]++
+		txs.Shift()
+	}
+	for _, key := range []*ecdsa.PrivateKey{typedKey, legacyKey} {
+		if addr := crypto.PubkeyToAddress(key.PublicKey); next[addr] != 10 {
+			t.Errorf("processed lending count mismatch for %x: have %d, want 10", addr, next[addr])
+		}
+	}
+}
@@ -86,6 +86,51 @@ var PrecompiledContractsIstanbul = map[common.Address]PrecompiledContract{
 	common.BytesToAddress([]byte{42}): &tomoxEpochPrice{},
 }
 
+// PrecompiledContractsTomoXPriceOracle contains the default set of pre-compiled contracts
+// used since the TomoX price oracle fork.
+var PrecompiledContractsTomoXPriceOracle = map[common.Address]PrecompiledContract{
+	common.BytesToAddress([]byte{1}):  &ecrecover{},
+	common.BytesToAddress([]byte{2}):  &sha256hash{},
+	common.BytesToAddress([]byte{3}):  &ripemd160hash{},
+	common.BytesToAddress([]byte{4}):  &dataCopy{},
+	common.BytesToAddress([]byte{5}):  &bigModExp{},
+	common.BytesToAddress([]byte{6}):  &bn256AddIstanbul{},
+	common.BytesToAddress([]byte{7}):  &bn256ScalarMulIstanbul{},
+	common.BytesToAddress([]byte{8}):  &bn256PairingIstanbul{},
+	common.BytesToAddress([]byte{9}):  &blake2F{},
+	common.BytesToAddress([]byte{30}): &ringSignatureVerifier{},
+	common.BytesToAddress([]byte{40}): &bulletproofVerifier{},
+	common.BytesToAddress([]byte{41}): &tomoxLastPrice{},
+	common.BytesToAddress([]byte{42}): &tomoxEpochPrice{},
+	common.BytesToAddress([]byte{43}): &tomoxTWAPPrice{},
+}
+
+// ActivePrecompiles returns the set of pre-compiled contracts enabled by the
+// given chain rules.
+func ActivePrecompiles(rules params.Rules) map[common.Address]PrecompiledContract {
+	switch {
+	case rules.IsTIPTomoXPriceOracle:
+		return PrecompiledContractsTomoXPriceOracle
+	case rules.IsIstanbul:
+		return PrecompiledContractsIstanbul
+	case rules.IsByzantium:
+		return PrecompiledContractsByzantium
+	default:
+		return PrecompiledContractsHomestead
+	}
+}
+
+// ActivePrecompileAddresses returns the addresses of the pre-compiled contracts
+// enabled by the given chain rules.
+func ActivePrecompileAddresses(rules params.Rules) []common.Address {
+	precompiles := ActivePrecompiles(rules)
+	addrs := make([]common.Address, 0, len(precompiles))
+	for addr := range precompiles {
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
 // RunPrecompiledContract runs and evaluates the output of a precompiled contract.
 func RunPrecompiledContract(p PrecompiledContract, input []byte, contract *Contract) (ret []byte, err error) {
 	gas := p.RequiredGas(input)
@@ -427,6 +427,33 @@ var tomoxEpochPriceWithEmptyTradingStateTests = []precompiledTest{
 	},
 }
 
+func tomoxTWAPPriceInput(base, quote string, window int64) string {
+	input := append(common.Hex2BytesFixed(base, 32), common.Hex2BytesFixed(quote, 32)...)
+	return common.Bytes2Hex(append(input, common.LeftPadBytes(big.NewInt(window).Bytes(), 32)...))
+}
+
+var tomoxTWAPPriceTests = []precompiledTest{
+	{
+		input: tomoxTWAPPriceInput(BTCAddress, USDTAddress, 200),
+		// (BTCUSDTEpochPrice * 100 + BTCUSDTLastPrice * 100) / 200, volume 2, 1 trade, window 200
+		expected: common.Bytes2Hex(common.LeftPadBytes(big.NewInt(8900000000).Bytes(), 32)) +
+			common.Bytes2Hex(common.LeftPadBytes(big.NewInt(2).Bytes(), 32)) +
+			common.Bytes2Hex(common.LeftPadBytes(big.NewInt(1).Bytes(), 32)) +
+			common.Bytes2Hex(common.LeftPadBytes(big.NewInt(200).Bytes(), 32)),
+		name: "BTCUSDT",
+	},
+	{
+		input:    common.Bytes2Hex(append(common.Hex2BytesFixed(BTCAddress, 32), common.Hex2BytesFixed(USDTAddress, 32)...)),
+		expected: common.Bytes2Hex(common.LeftPadBytes(common.Big0.Bytes(), TomoXTWAPNumberOfBytesReturn)),
+		name:     "BTCUSDT_invalid_input_length",
+	},
+	{
+		input:    tomoxTWAPPriceInput(USDTAddress, BTCAddress, 200),
+		expected: common.Bytes2Hex(common.LeftPadBytes(common.Big0.Bytes(), TomoXTWAPNumberOfBytesReturn)),
+		name:     "USDTBTC",
+	},
+}
+
 // EIP-152 test vectors
 var blake2FMalformedInputTests = []precompiledFailureTest{
 	{
@@ -522,6 +549,30 @@ func testTomoxPrecompiled(addr string, test precompiledTest, t *testing.T) {
 	})
 }
 
+func testTomoxTWAPPrecompiled(addr string, test precompiledTest, t *testing.T) {
+	tradingStateDB, _ := tradingstate.New(common.Hash{}, tradingstate.NewDatabase(rawdb.NewMemoryDatabase()))
+	orderBook := tradingstate.GetTradingOrderBookHash(common.HexToAddress(BTCAddress), common.HexToAddress(USDTAddress))
+	tradingStateDB.ObservePrice(orderBook, 1, 1000, BTCUSDTEpochPrice, big.NewInt(1))
+	tradingStateDB.ObservePrice(orderBook, 2, 1100, BTCUSDTLastPrice, big.NewInt(2))
+
+	defer func(fork *big.Int) { common.TIPTomoXPriceOracle = fork }(common.TIPTomoXPriceOracle)
+	common.TIPTomoXPriceOracle = common.Big0
+	evm := NewEVM(Context{BlockNumber: common.Big3, Time: big.NewInt(1200)}, nil, tradingStateDB, &params.ChainConfig{ByzantiumBlock: common.Big0}, Config{})
+	contractAddr := common.HexToAddress(addr)
+	p := PrecompiledContractsTomoXPriceOracle[contractAddr]
+	in := common.Hex2Bytes(test.input)
+	contract := NewContract(AccountRef(common.HexToAddress("1337")),
+		nil, new(big.Int), p.RequiredGas(in))
+	contract.SetCallCode(&contractAddr, common.Hash{}, []byte{})
+	t.Run(fmt.Sprintf("%s-Gas=%d", test.name, contract.Gas), func(t *testing.T) {
+		if res, err := run(evm, contract, in, false); err != nil {
+			t.Error(err)
+		} else if common.Bytes2Hex(res) != test.expected {
+			t.Errorf("Expected %v, got %v", test.expected, common.Bytes2Hex(res))
+		}
+	})
+}
+
 func testPrecompiledWithEmptyTradingState(addr string, test precompiledTest, t *testing.T) {
 	evm := NewEVM(Context{BlockNumber: common.Big1}, nil, nil, &params.ChainConfig{ByzantiumBlock: common.Big0}, Config{})
 
@@ -723,6 +774,13 @@ func TestPrecompiledTomoXEpochPrice(t *testing.T) {
 	}
 }
 
+// Tests GetTomoXTWAPPrice
+func TestPrecompiledTomoXTWAPPrice(t *testing.T) {
+	for _, test := range tomoxTWAPPriceTests {
+		testTomoxTWAPPrecompiled("2B", test, t)
+	}
+}
+
 // Tests GetTomoXLastPrice
 func TestPrecompiledTomoXLastPriceWithEmptyTradingState(t *testing.T) {
 	for _, test := range tomoxLastPriceWithEmptyTradingStateTests {
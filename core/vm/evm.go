@@ -45,19 +45,15 @@ type (
 // run runs the given contract and takes care of running precompiles with a fallback to the byte code interpreter.
 func run(evm *EVM, contract *Contract, input []byte, readOnly bool) ([]byte, error) {
 	if contract.CodeAddr != nil {
-		precompiles := PrecompiledContractsHomestead
-		if evm.chainRules.IsByzantium {
-			precompiles = PrecompiledContractsByzantium
-		}
-		if evm.chainRules.IsIstanbul {
-			precompiles = PrecompiledContractsIstanbul
-		}
+		precompiles := ActivePrecompiles(evm.chainRules)
 		if p := precompiles[*contract.CodeAddr]; p != nil {
 			switch p.(type) {
 			case *tomoxEpochPrice:
 				p.(*tomoxEpochPrice).SetTradingState(evm.tradingStateDB)
 			case *tomoxLastPrice:
 				p.(*tomoxLastPrice).SetTradingState(evm.tradingStateDB)
+			case *tomoxTWAPPrice:
+				p.(*tomoxTWAPPrice).SetTradingState(evm.tradingStateDB, evm.Time)
 			}
 			return RunPrecompiledContract(p, input, contract)
 		}
@@ -99,6 +95,7 @@ type Context struct {
 	BlockNumber *big.Int       // Provides information for NUMBER
 	Time        *big.Int       // Provides information for TIME
 	Difficulty  *big.Int       // Provides information for DIFFICULTY
+	BaseFee     *big.Int       // Provides the EIP-1559 base fee, nil before the fork
 }
 
 // EVM is the Ethereum Virtual Machine base object and provides
@@ -199,13 +196,7 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 		snapshot = evm.StateDB.Snapshot()
 	)
 	if !evm.StateDB.Exist(addr) {
-		precompiles := PrecompiledContractsHomestead
-		if evm.chainRules.IsByzantium {
-			precompiles = PrecompiledContractsByzantium
-		}
-		if evm.chainRules.IsIstanbul {
-			precompiles = PrecompiledContractsIstanbul
-		}
+		precompiles := ActivePrecompiles(evm.chainRules)
 		if precompiles[addr] == nil && evm.chainRules.IsEIP158 && value.Sign() == 0 {
 			// Calling a non existing account, don't do anything, but ping the tracer
 			if evm.vmConfig.Debug && evm.depth == 0 {
@@ -383,6 +374,11 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	}
 	nonce := evm.StateDB.GetNonce(caller.Address())
 	evm.StateDB.SetNonce(caller.Address(), nonce+1)
+	// We add this to the access list _before_ taking a snapshot. Even if the creation fails,
+	// the access-list change should not be rolled back
+	if evm.chainRules.IsEIP2718 {
+		evm.StateDB.AddAddressToAccessList(address)
+	}
 
 	// Ensure there's no existing contract already at the designated address
 	contractHash := evm.StateDB.GetCodeHash(address)
@@ -464,5 +460,8 @@ func (evm *EVM) Create2(caller ContractRef, code []byte, gas uint64, endowment *
 	return evm.create(caller, codeAndHash, gas, endowment, contractAddr)
 }
 
+// ChainRules returns the fork rules of the environment's block.
+func (evm *EVM) ChainRules() params.Rules { return evm.chainRules }
+
 // ChainConfig returns the environment's chain configuration
 func (evm *EVM) ChainConfig() *params.ChainConfig { return evm.chainConfig }
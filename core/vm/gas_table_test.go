@@ -25,6 +25,7 @@ import (
 	"github.com/69th-byte/sdexchain/common"
 	"github.com/69th-byte/sdexchain/common/hexutil"
 	"github.com/69th-byte/sdexchain/core/state"
+	"github.com/69th-byte/sdexchain/core/types"
 	"github.com/69th-byte/sdexchain/params"
 )
 
@@ -105,3 +106,57 @@ func TestEIP2200(t *testing.T) {
 		}
 	}
 }
+
+var eip2929Tests = []struct {
+	warm bool
+	code string
+	used uint64
+}{
+	{false, "0x600154506001545000", 2210}, // cold then warm SLOAD
+	{true, "0x600154506001545000", 210},   // SLOAD of a listed slot
+	{false, "0x60023b5000", 2605},         // cold EXTCODESIZE
+	{true, "0x60023b5000", 105},           // EXTCODESIZE of a listed address
+}
+
+// Tests that state accesses are priced cold or warm by the access list of the
+// transaction after the typed transaction fork (EIP-2929).
+func TestEIP2929(t *testing.T) {
+	config := *params.AllEthashProtocolChanges
+	config.EIP2718Block = big.NewInt(0)
+
+	for i, tt := range eip2929Tests {
+		var (
+			caller  = common.Address{}
+			address = common.BytesToAddress([]byte("contract"))
+			other   = common.BytesToAddress([]byte{0x02})
+		)
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+		statedb.CreateAccount(address)
+		statedb.SetCode(address, hexutil.MustDecode(tt.code))
+		statedb.Finalise(true)
+
+		var list types.AccessList
+		if tt.warm {
+			list = types.AccessList{
+				{Address: address, StorageKeys: []common.Hash{common.BigToHash(big.NewInt(1))}},
+				{Address: other},
+			}
+		}
+		statedb.PrepareAccessList(caller, &address, nil, list)
+
+		vmctx := Context{
+			BlockNumber: big.NewInt(0),
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		}
+		vmenv := NewEVM(vmctx, statedb, nil, &config, Config{})
+
+		_, gas, err := vmenv.Call(AccountRef(caller), address, nil, math.MaxUint64, new(big.Int))
+		if err != nil {
+			t.Errorf("test %d: call failed: %v", i, err)
+		}
+		if used := math.MaxUint64 - gas; used != tt.used {
+			t.Errorf("test %d: gas used mismatch: have %v, want %v", i, used, tt.used)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package vm
 
 import (
+	"math/big"
+
 	"github.com/69th-byte/sdexchain/common"
 	"github.com/69th-byte/sdexchain/log"
 	"github.com/69th-byte/sdexchain/params"
@@ -9,6 +11,9 @@ import (
 
 const TomoXPriceNumberOfBytesReturn = 32
 
+// TomoXTWAPNumberOfBytesReturn is the length of twap, volume, number of trades and window returned by tomoxTWAPPrice
+const TomoXTWAPNumberOfBytesReturn = 4 * 32
+
 // tomoxPrice implements a pre-compile contract to get token price in tomox
 
 type tomoxLastPrice struct {
@@ -17,6 +22,10 @@ type tomoxLastPrice struct {
 type tomoxEpochPrice struct {
 	tradingStateDB *tradingstate.TradingStateDB
 }
+type tomoxTWAPPrice struct {
+	tradingStateDB *tradingstate.TradingStateDB
+	time           uint64
+}
 
 func (t *tomoxLastPrice) RequiredGas(input []byte) uint64 {
 	return params.TomoXPriceGas
@@ -69,3 +78,44 @@ func (t *tomoxEpochPrice) SetTradingState(tradingStateDB *tradingstate.TradingSt
 		t.tradingStateDB = nil
 	}
 }
+
+func (t *tomoxTWAPPrice) RequiredGas(input []byte) uint64 {
+	return params.TomoXTWAPPriceGas
+}
+
+func (t *tomoxTWAPPrice) Run(input []byte) ([]byte, error) {
+	// input includes baseTokenAddress, quoteTokenAddress, window in seconds
+	// output includes twap, volume, number of trades, window in seconds which is shorter than the input
+	// if the price oracle doesn't cover it
+	if t.tradingStateDB != nil && len(input) == 96 {
+		base := common.BytesToAddress(input[12:32])  // 20 bytes from 13-32
+		quote := common.BytesToAddress(input[44:64]) // 20 bytes from 45-64
+		window := new(big.Int).SetBytes(input[64:])
+		if window.IsUint64() {
+			oracle := t.tradingStateDB.GetPriceOracle(tradingstate.GetTradingOrderBookHash(base, quote))
+			result, err := oracle.GetTWAP(t.time, window.Uint64())
+			if err == nil {
+				log.Debug("Run GetTWAPPrice", "base", base.Hex(), "quote", quote.Hex(), "window", result.Window, "twap", result.TWAP)
+				output := make([]byte, 0, TomoXTWAPNumberOfBytesReturn)
+				output = append(output, common.LeftPadBytes(result.TWAP.Bytes(), 32)...)
+				output = append(output, common.LeftPadBytes(result.Volume.Bytes(), 32)...)
+				output = append(output, common.LeftPadBytes(new(big.Int).SetUint64(result.Trades).Bytes(), 32)...)
+				output = append(output, common.LeftPadBytes(new(big.Int).SetUint64(result.Window).Bytes(), 32)...)
+				return output, nil
+			}
+		}
+	}
+	return common.LeftPadBytes([]byte{}, TomoXTWAPNumberOfBytesReturn), nil
+}
+
+func (t *tomoxTWAPPrice) SetTradingState(tradingStateDB *tradingstate.TradingStateDB, time *big.Int) {
+	if tradingStateDB != nil {
+		t.tradingStateDB = tradingStateDB.Copy()
+	} else {
+		t.tradingStateDB = nil
+	}
+	t.time = 0
+	if time != nil {
+		t.time = time.Uint64()
+	}
+}
@@ -0,0 +1,396 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package eip712 implements the hashing of EIP-712 typed structured data, which
+// lets wallets show users the fields of the messages they sign rather than an
+// opaque hash.
+package eip712
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/common/hexutil"
+	"github.com/69th-byte/sdexchain/common/math"
+	"github.com/69th-byte/sdexchain/crypto"
+)
+
+// DomainType is the name of the type of the domain separator.
+const DomainType = "EIP712Domain"
+
+var (
+	// typedArray matches array types, capturing the element type.
+	typedArray = regexp.MustCompile(`^(.+)\[[0-9]*\]$`)
+	// typedInteger matches integer types, capturing the signedness and bit size.
+	typedInteger = regexp.MustCompile(`^(u?)int([0-9]*)$`)
+	// typedBytes matches fixed size byte types, capturing the size.
+	typedBytes = regexp.MustCompile(`^bytes([0-9]+)$`)
+)
+
+// Type is a field of a struct type.
+type Type struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Types are the struct types of typed data, by name.
+type Types map[string][]Type
+
+// Domain is the domain separator of typed data, binding signatures to an
+// application and a chain.
+type Domain struct {
+	Name              string                `json:"name,omitempty"`
+	Version           string                `json:"version,omitempty"`
+	ChainId           *math.HexOrDecimal256 `json:"chainId,omitempty"`
+	VerifyingContract *common.Address       `json:"verifyingContract,omitempty"`
+	Salt              *common.Hash          `json:"salt,omitempty"`
+}
+
+// types returns the fields of the domain type, the ones set in the domain.
+func (d *Domain) types() []Type {
+	var fields []Type
+	if d.Name != "" {
+		fields = append(fields, Type{Name: "name", Type: "string"})
+	}
+	if d.Version != "" {
+		fields = append(fields, Type{Name: "version", Type: "string"})
+	}
+	if d.ChainId != nil {
+		fields = append(fields, Type{Name: "chainId", Type: "uint256"})
+	}
+	if d.VerifyingContract != nil {
+		fields = append(fields, Type{Name: "verifyingContract", Type: "address"})
+	}
+	if d.Salt != nil {
+		fields = append(fields, Type{Name: "salt", Type: "bytes32"})
+	}
+	return fields
+}
+
+// message returns the values of the domain fields.
+func (d *Domain) message() map[string]interface{} {
+	values := make(map[string]interface{})
+	if d.Name != "" {
+		values["name"] = d.Name
+	}
+	if d.Version != "" {
+		values["version"] = d.Version
+	}
+	if d.ChainId != nil {
+		values["chainId"] = (*big.Int)(d.ChainId)
+	}
+	if d.VerifyingContract != nil {
+		values["verifyingContract"] = *d.VerifyingContract
+	}
+	if d.Salt != nil {
+		values["salt"] = *d.Salt
+	}
+	return values
+}
+
+// TypedData is a message of a struct type along with the definitions of the
+// types and the domain it is signed in, as taken by eth_signTypedData.
+type TypedData struct {
+	Types       Types                  `json:"types"`
+	PrimaryType string                 `json:"primaryType"`
+	Domain      Domain                 `json:"domain"`
+	Message     map[string]interface{} `json:"message"`
+}
+
+// Hash returns the hash to be signed for the typed data:
+//
+//	keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message))
+func (td *TypedData) Hash() (common.Hash, error) {
+	separator, err := td.DomainSeparator()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	message, err := td.HashStruct(td.PrimaryType, td.Message)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash([]byte("\x19\x01"), separator[:], message[:]), nil
+}
+
+// DomainSeparator returns the hash of the domain of the typed data. If the
+// types lack the domain type, it's made of the fields set in the domain.
+func (td *TypedData) DomainSeparator() (common.Hash, error) {
+	types := td.Types
+	if _, ok := types[DomainType]; !ok {
+		types = make(Types, len(td.Types)+1)
+		for name, fields := range td.Types {
+			types[name] = fields
+		}
+		types[DomainType] = td.Domain.types()
+	}
+	return (&TypedData{Types: types}).HashStruct(DomainType, td.Domain.message())
+}
+
+// HashStruct returns the hash of a value of a struct type.
+func (td *TypedData) HashStruct(primaryType string, data map[string]interface{}) (common.Hash, error) {
+	encoded, err := td.EncodeData(primaryType, data, 1)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+// TypeHash returns the hash of the encoding of a struct type.
+func (td *TypedData) TypeHash(primaryType string) common.Hash {
+	return crypto.Keccak256Hash(td.EncodeType(primaryType))
+}
+
+// EncodeType returns the encoding of a struct type along with the types it
+// references, such as "Mail(Person from,Person to,string contents)Person(string
+// name,address wallet)".
+func (td *TypedData) EncodeType(primaryType string) []byte {
+	deps := td.dependencies(primaryType, nil)
+	if len(deps) > 0 {
+		sort.Strings(deps[1:])
+	}
+	var buffer bytes.Buffer
+	for _, dep := range deps {
+		buffer.WriteString(dep)
+		buffer.WriteString("(")
+		for i, field := range td.Types[dep] {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString(field.Type)
+			buffer.WriteString(" ")
+			buffer.WriteString(field.Name)
+		}
+		buffer.WriteString(")")
+	}
+	return buffer.Bytes()
+}
+
+// dependencies returns the struct types a type references, itself first.
+func (td *TypedData) dependencies(primaryType string, found []string) []string {
+	if match := typedArray.FindStringSubmatch(primaryType); match != nil {
+		primaryType = match[1]
+	}
+	for _, dep := range found {
+		if dep == primaryType {
+			return found
+		}
+	}
+	if td.Types[primaryType] == nil {
+		return found
+	}
+	found = append(found, primaryType)
+	for _, field := range td.Types[primaryType] {
+		found = td.dependencies(field.Type, found)
+	}
+	return found
+}
+
+// EncodeData returns the encoding of a value of a struct type: its type hash
+// followed by the 32 byte encoding of each field.
+func (td *TypedData) EncodeData(primaryType string, data map[string]interface{}, depth int) ([]byte, error) {
+	fields, ok := td.Types[primaryType]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %q", primaryType)
+	}
+	if depth > 32 {
+		return nil, fmt.Errorf("type %q nested too deeply", primaryType)
+	}
+	buffer := bytes.NewBuffer(td.TypeHash(primaryType).Bytes())
+	for _, field := range fields {
+		value, ok := data[field.Name]
+		if !ok {
+			return nil, fmt.Errorf("missing field %q of %s", field.Name, primaryType)
+		}
+		encoded, err := td.encodeValue(field.Type, value, depth)
+		if err != nil {
+			return nil, fmt.Errorf("field %q of %s: %v", field.Name, primaryType, err)
+		}
+		buffer.Write(encoded)
+	}
+	return buffer.Bytes(), nil
+}
+
+// encodeValue returns the 32 byte encoding of a value of any type.
+func (td *TypedData) encodeValue(typ string, value interface{}, depth int) ([]byte, error) {
+	// Arrays are encoded as the hash of their concatenated elements
+	if match := typedArray.FindStringSubmatch(typ); match != nil {
+		items := reflect.ValueOf(value)
+		if items.Kind() != reflect.Slice && items.Kind() != reflect.Array {
+			return nil, fmt.Errorf("invalid %s value %v", typ, value)
+		}
+		var buffer bytes.Buffer
+		for i := 0; i < items.Len(); i++ {
+			encoded, err := td.encodeValue(match[1], items.Index(i).Interface(), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			buffer.Write(encoded)
+		}
+		return crypto.Keccak256(buffer.Bytes()), nil
+	}
+	// Structs are encoded as their hash
+	if _, ok := td.Types[typ]; ok {
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid %s value %v", typ, value)
+		}
+		encoded, err := td.EncodeData(typ, data, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(encoded), nil
+	}
+	return encodePrimitive(typ, value)
+}
+
+// encodePrimitive returns the 32 byte encoding of a value of an atomic type or
+// a dynamic bytes or string type.
+func encodePrimitive(typ string, value interface{}) ([]byte, error) {
+	switch typ {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid string value %v", value)
+		}
+		return crypto.Keccak256([]byte(str)), nil
+
+	case "bytes":
+		blob, err := toBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(blob), nil
+
+	case "bool":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("invalid bool value %v", value)
+		}
+		if b {
+			return common.LeftPadBytes([]byte{1}, 32), nil
+		}
+		return make([]byte, 32), nil
+
+	case "address":
+		switch v := value.(type) {
+		case common.Address:
+			return common.LeftPadBytes(v[:], 32), nil
+		case *common.Address:
+			return common.LeftPadBytes(v[:], 32), nil
+		case string:
+			if !common.IsHexAddress(v) {
+				return nil, fmt.Errorf("invalid address value %q", v)
+			}
+			return common.LeftPadBytes(common.HexToAddress(v).Bytes(), 32), nil
+		}
+		return nil, fmt.Errorf("invalid address value %v", value)
+	}
+	if match := typedBytes.FindStringSubmatch(typ); match != nil {
+		size, _ := strconv.Atoi(match[1])
+		if size < 1 || size > 32 {
+			return nil, fmt.Errorf("invalid type %q", typ)
+		}
+		blob, err := toBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(blob) != size {
+			return nil, fmt.Errorf("invalid %s value of %d bytes", typ, len(blob))
+		}
+		return common.RightPadBytes(blob, 32), nil
+	}
+	if match := typedInteger.FindStringSubmatch(typ); match != nil {
+		bits := 256
+		if match[2] != "" {
+			bits, _ = strconv.Atoi(match[2])
+		}
+		if bits < 8 || bits > 256 || bits%8 != 0 {
+			return nil, fmt.Errorf("invalid type %q", typ)
+		}
+		n, err := toBigInt(value)
+		if err != nil {
+			return nil, err
+		}
+		if match[1] == "u" {
+			if n.Sign() < 0 || n.BitLen() > bits {
+				return nil, fmt.Errorf("%s overflow: %v", typ, n)
+			}
+		} else {
+			limit := new(big.Int).Lsh(common.Big1, uint(bits-1))
+			if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+				return nil, fmt.Errorf("%s overflow: %v", typ, n)
+			}
+		}
+		return math.PaddedBigBytes(math.U256(new(big.Int).Set(n)), 32), nil
+	}
+	return nil, fmt.Errorf("unknown type %q", typ)
+}
+
+// toBytes converts a byte slice, a hash or a hex string to bytes.
+func toBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case hexutil.Bytes:
+		return v, nil
+	case common.Hash:
+		return v[:], nil
+	case string:
+		return hexutil.Decode(v)
+	}
+	return nil, fmt.Errorf("invalid bytes value %v", value)
+}
+
+// toBigInt converts a Go integer, a JSON number or a hex or decimal string to a
+// big integer.
+func toBigInt(value interface{}) (*big.Int, error) {
+	switch v := value.(type) {
+	case *big.Int:
+		if v == nil {
+			return new(big.Int), nil
+		}
+		return v, nil
+	case *math.HexOrDecimal256:
+		return (*big.Int)(v), nil
+	case *hexutil.Big:
+		return (*big.Int)(v), nil
+	case int:
+		return big.NewInt(int64(v)), nil
+	case int64:
+		return big.NewInt(v), nil
+	case uint64:
+		return new(big.Int).SetUint64(v), nil
+	case float64:
+		// JSON numbers, only exact integers are accepted
+		if v != float64(int64(v)) {
+			return nil, fmt.Errorf("invalid integer value %v", v)
+		}
+		return big.NewInt(int64(v)), nil
+	case string:
+		n, ok := new(big.Int).SetString(v, 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer value %q", v)
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("invalid integer value %v", value)
+}
@@ -0,0 +1,168 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eip712
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/common/math"
+	"github.com/69th-byte/sdexchain/crypto"
+)
+
+// mailTypedData is the example message of the EIP-712 specification.
+const mailTypedData = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": "1",
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+// Tests the hashing of the example message against the values given by the
+// specification, and its signature by the example key.
+func TestMailTypedData(t *testing.T) {
+	var td TypedData
+	if err := json.Unmarshal([]byte(mailTypedData), &td); err != nil {
+		t.Fatalf("failed to decode typed data: %v", err)
+	}
+	if have, want := string(td.EncodeType("Mail")), "Mail(Person from,Person to,string contents)Person(string name,address wallet)"; have != want {
+		t.Errorf("type encoding mismatch: have %q, want %q", have, want)
+	}
+	if have, want := td.TypeHash("Mail"), common.HexToHash("0xa0cedeb2dc280ba39b857546d74f5549c3a1d7bdc2dd96bf881f76108e23dac2"); have != want {
+		t.Errorf("type hash mismatch: have %x, want %x", have, want)
+	}
+	separator, err := td.DomainSeparator()
+	if err != nil {
+		t.Fatalf("failed to hash domain: %v", err)
+	}
+	if want := common.HexToHash("0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"); separator != want {
+		t.Errorf("domain separator mismatch: have %x, want %x", separator, want)
+	}
+	message, err := td.HashStruct("Mail", td.Message)
+	if err != nil {
+		t.Fatalf("failed to hash message: %v", err)
+	}
+	if want := common.HexToHash("0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e"); message != want {
+		t.Errorf("message hash mismatch: have %x, want %x", message, want)
+	}
+	hash, err := td.Hash()
+	if err != nil {
+		t.Fatalf("failed to hash typed data: %v", err)
+	}
+	if want := common.HexToHash("0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"); hash != want {
+		t.Errorf("typed data hash mismatch: have %x, want %x", hash, want)
+	}
+	key, _ := crypto.HexToECDSA(common.Bytes2Hex(crypto.Keccak256([]byte("cow"))))
+	sig, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if have, want := common.Bytes2Hex(sig[:64]), "4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b91562"; have != want {
+		t.Errorf("signature mismatch: have %s, want %s", have, want)
+	}
+}
+
+// Tests that the domain type is derived from the domain fields if missing, and
+// that Go values hash like their JSON representation.
+func TestDerivedDomain(t *testing.T) {
+	var td TypedData
+	if err := json.Unmarshal([]byte(mailTypedData), &td); err != nil {
+		t.Fatalf("failed to decode typed data: %v", err)
+	}
+	want, _ := td.Hash()
+
+	contract := common.HexToAddress("0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC")
+	derived := &TypedData{
+		Types:       Types{"Person": td.Types["Person"], "Mail": td.Types["Mail"]},
+		PrimaryType: "Mail",
+		Domain: Domain{
+			Name:              "Ether Mail",
+			Version:           "1",
+			ChainId:           (*math.HexOrDecimal256)(big.NewInt(1)),
+			VerifyingContract: &contract,
+		},
+		Message: map[string]interface{}{
+			"from":     map[string]interface{}{"name": "Cow", "wallet": common.HexToAddress("0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826")},
+			"to":       map[string]interface{}{"name": "Bob", "wallet": common.HexToAddress("0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB")},
+			"contents": "Hello, Bob!",
+		},
+	}
+	if have, err := derived.Hash(); err != nil || have != want {
+		t.Fatalf("hash mismatch: have %x (%v), want %x", have, err, want)
+	}
+}
+
+// Tests that invalid values are rejected rather than silently encoded.
+func TestEncodeInvalid(t *testing.T) {
+	tests := []struct {
+		typ   string
+		value interface{}
+	}{
+		{"uint8", 256},
+		{"uint256", -1},
+		{"int8", 128},
+		{"int8", "0xzz"},
+		{"bool", "true"},
+		{"address", "0x1234"},
+		{"bytes4", "0x1234"},
+		{"bytes33", "0x12"},
+		{"uint7", 1},
+		{"unknown", 1},
+		{"string[]", "not an array"},
+	}
+	td := &TypedData{Types: Types{}}
+	for i, tt := range tests {
+		if _, err := td.encodeValue(tt.typ, tt.value, 1); err == nil {
+			t.Errorf("test %d: %s value %v accepted", i, tt.typ, tt.value)
+		}
+	}
+	// Negative signed integers are encoded in two's complement
+	encoded, err := td.encodeValue("int8", -1, 1)
+	if err != nil {
+		t.Fatalf("failed to encode negative integer: %v", err)
+	}
+	if have := common.BytesToHash(encoded); have != common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff") {
+		t.Errorf("negative integer encoding mismatch: have %x", have)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/69th-byte/sdexchain/tomox/tradingstate"
+	"github.com/69th-byte/sdexchain/tomoxlending/lendingstate"
 	"io/ioutil"
 	"math/big"
 	"runtime"
@@ -204,10 +205,14 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 							balacne = value
 						}
 					}
-					msg, _ := tx.AsMessage(signer, balacne, task.block.Number())
+					msg, _ := tx.AsMessage(signer, balacne, task.block.Number(), task.block.Header().BaseFee)
 					vmctx := core.NewEVMContext(msg, task.block.Header(), api.eth.blockchain, nil)
 
-					res, err := api.traceTx(ctx, msg, vmctx, task.statedb, config)
+					reexec := defaultTraceReexec
+					if config != nil && config.Reexec != nil {
+						reexec = *config.Reexec
+					}
+					res, err := api.traceTx(ctx, msg, vmctx, task.statedb, config, api.matchingReplay(task.block, tx, reexec))
 					if err != nil {
 						task.results[i] = &txTraceResult{Error: err.Error()}
 						log.Warn("Tracing failed", "hash", tx.Hash(), "block", task.block.NumberU64(), "err", err)
@@ -444,10 +449,10 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 						balacne = value
 					}
 				}
-				msg, _ := txs[task.index].AsMessage(signer, balacne, block.Number())
+				msg, _ := txs[task.index].AsMessage(signer, balacne, block.Number(), block.Header().BaseFee)
 				vmctx := core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
 
-				res, err := api.traceTx(ctx, msg, vmctx, task.statedb, config)
+				res, err := api.traceTx(ctx, msg, vmctx, task.statedb, config, api.matchingReplay(block, txs[task.index], reexec))
 				if err != nil {
 					results[task.index] = &txTraceResult{Error: err.Error()}
 					continue
@@ -469,7 +474,7 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 			}
 		}
 		// Generate the next state snapshot fast without tracing
-		msg, _ := tx.AsMessage(signer, balacne, block.Number())
+		msg, _ := tx.AsMessage(signer, balacne, block.Number(), block.Header().BaseFee)
 		vmctx := core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
 
 		vmenv := vm.NewEVM(vmctx, statedb, tomoxState, api.config, vm.Config{})
@@ -586,20 +591,121 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Ha
 	if err != nil {
 		return nil, err
 	}
+	block := api.eth.blockchain.GetBlockByHash(blockHash)
+	if block == nil {
+		return nil, fmt.Errorf("block %x not found", blockHash)
+	}
 	// Trace the transaction and return
-	return api.traceTx(ctx, msg, vmctx, statedb, config)
+	return api.traceTx(ctx, msg, vmctx, statedb, config, api.matchingReplay(block, tx, reexec))
+}
+
+// matchingReplay is a callback replaying the matching engines over a trading or
+// lending transaction with the given tracer attached.
+type matchingReplay func(tracer tradingstate.MatchingTracer) error
+
+// matchingReplay returns the callback replaying the matching of a transaction,
+// nil if it is neither a trading nor a lending transaction.
+func (api *PrivateDebugAPI) matchingReplay(block *types.Block, tx *types.Transaction, reexec uint64) matchingReplay {
+	if !tx.IsTradingTransaction() && !tx.IsLendingTransaction() {
+		return nil
+	}
+	return func(tracer tradingstate.MatchingTracer) error {
+		return api.traceMatching(block, tx, reexec, tracer)
+	}
+}
+
+// traceMatching reruns the matching engines over the orders carried by a trading
+// or lending transaction, reporting every step to the tracer. Matching runs on
+// the parent state ahead of the transactions of the block, trading batches
+// first, so the batches preceding the traced one are replayed untraced.
+func (api *PrivateDebugAPI) traceMatching(block *types.Block, tx *types.Transaction, reexec uint64, tracer tradingstate.MatchingTracer) error {
+	if api.eth.TomoX == nil || api.eth.Lending == nil {
+		return errors.New("tomox service not available")
+	}
+	if api.config.Posv == nil || block.NumberU64()%api.config.Posv.Epoch == 0 {
+		return nil
+	}
+	parent := api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return fmt.Errorf("parent %x not found", block.ParentHash())
+	}
+	statedb, _, err := api.computeStateDB(parent, reexec)
+	if err != nil {
+		return err
+	}
+	tradingState, err := api.eth.blockchain.OrderStateAt(parent)
+	if err != nil {
+		return err
+	}
+	lendingState, err := api.eth.blockchain.LendingStateAt(parent)
+	if err != nil {
+		return err
+	}
+	author, err := api.eth.engine.Author(block.Header())
+	if err != nil {
+		return err
+	}
+	header := block.Header()
+
+	txMatchBatches, err := core.ExtractTradingTransactions(block.Transactions())
+	if err != nil {
+		return err
+	}
+	for _, batch := range txMatchBatches {
+		traced := batch.TxHash == tx.Hash()
+		if traced {
+			tradingState.SetMatchingTracer(tracer)
+		}
+		for _, txMatch := range batch.Data {
+			order, err := txMatch.DecodeOrder()
+			if err != nil {
+				continue
+			}
+			orderBook := tradingstate.GetTradingOrderBookHash(order.BaseToken, order.QuoteToken)
+			if _, _, err := api.eth.TomoX.ApplyOrder(header, author, api.eth.blockchain, statedb, tradingState, orderBook, order); err != nil {
+				return err
+			}
+		}
+		if traced {
+			return nil
+		}
+	}
+	lendingBatches, err := core.ExtractLendingTransactions(block.Transactions())
+	if err != nil {
+		return err
+	}
+	for _, batch := range lendingBatches {
+		traced := batch.TxHash == tx.Hash()
+		if traced {
+			tradingState.SetMatchingTracer(tracer)
+		}
+		for _, item := range batch.Data {
+			lendingBook := lendingstate.GetLendingOrderBookHash(item.LendingToken, item.Term)
+			if _, _, err := api.eth.Lending.ApplyOrder(header, author, api.eth.blockchain, statedb, lendingState, tradingState, lendingBook, item); err != nil {
+				return err
+			}
+		}
+		if traced {
+			return nil
+		}
+	}
+	return nil
 }
 
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
-// be tracer dependent.
-func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, vmctx vm.Context, statedb *state.StateDB, config *TraceConfig) (interface{}, error) {
+// be tracer dependent. If replay is set and the tracer follows the matching
+// engines, the matching of the transaction is replayed to it first.
+func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, vmctx vm.Context, statedb *state.StateDB, config *TraceConfig, replay matchingReplay) (interface{}, error) {
 	// Assemble the structured logger or the JavaScript tracer
 	var (
 		tracer vm.Tracer
 		err    error
 	)
 	switch {
+	case config != nil && config.Tracer != nil && *config.Tracer == tracers.MatchingTracerName:
+		tracer = tracers.NewMatchingLogger()
+
 	case config != nil && config.Tracer != nil:
 		// Define a meaningful timeout of a single transaction trace
 		timeout := defaultTraceTimeout
@@ -612,6 +718,7 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 		if tracer, err = tracers.New(*config.Tracer); err != nil {
 			return nil, err
 		}
+		tracer.(*tracers.Tracer).SetChainRules(api.config.Rules(vmctx.BlockNumber))
 		// Handle timeouts and RPC cancellations
 		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 		go func() {
@@ -626,6 +733,23 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 	default:
 		tracer = vm.NewStructLogger(config.LogConfig)
 	}
+	// Replay the matching engines for trading and lending transactions
+	if replay != nil {
+		var matchingTracer tradingstate.MatchingTracer
+		switch tracer := tracer.(type) {
+		case *tracers.MatchingLogger:
+			matchingTracer = tracer
+		case *tracers.Tracer:
+			if tracer.TracesMatching() {
+				matchingTracer = tracer
+			}
+		}
+		if matchingTracer != nil {
+			if err := replay(matchingTracer); err != nil {
+				return nil, fmt.Errorf("tracing matching failed: %v", err)
+			}
+		}
+	}
 	// Run the transaction with tracing enabled.
 	vmenv := vm.NewEVM(vmctx, statedb, nil, api.config, vm.Config{Debug: true, Tracer: tracer})
 
@@ -647,6 +771,9 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 	case *tracers.Tracer:
 		return tracer.GetResult()
 
+	case *tracers.MatchingLogger:
+		return tracer.GetResult()
+
 	default:
 		panic(fmt.Sprintf("bad tracer type %T", tracer))
 	}
@@ -687,7 +814,7 @@ func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int, ree
 					balanceFee = value
 				}
 			}
-			msg, err := tx.AsMessage(types.MakeSigner(api.config, block.Header().Number), balanceFee, block.Number())
+			msg, err := tx.AsMessage(types.MakeSigner(api.config, block.Header().Number), balanceFee, block.Number(), block.Header().BaseFee)
 			if err != nil {
 				return nil, vm.Context{}, nil, fmt.Errorf("tx %x failed: %v", tx.Hash(), err)
 			}
@@ -700,10 +827,7 @@ func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int, ree
 		}
 
 		if tokenFeeUsed {
-			fee := new(big.Int).SetUint64(gas)
-			if block.Header().Number.Cmp(common.TIPTRC21Fee) > 0 {
-				fee = fee.Mul(fee, common.TRC21GasPrice)
-			}
+			fee := state.TRC21FeeUsed(gas, block.Header().Number, block.Header().BaseFee)
 			feeCapacity[*tx.To()] = new(big.Int).Sub(feeCapacity[*tx.To()], fee)
 			balanceUpdated[*tx.To()] = feeCapacity[*tx.To()]
 			totalFeeUsed = totalFeeUsed.Add(totalFeeUsed, fee)
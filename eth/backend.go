@@ -22,7 +22,6 @@ import (
 	"fmt"
 	"math/big"
 	"runtime"
-	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -168,7 +167,7 @@ func New(ctx *node.ServiceContext, config *Config, tomoXServ *tomox.TomoX, lendi
 	}
 	var (
 		vmConfig    = vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
-		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout}
+		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout, SnapshotLimit: config.SnapshotCache}
 	)
 	if eth.chainConfig.Posv != nil {
 		c := eth.engine.(*posv.Posv)
@@ -216,9 +215,10 @@ func New(ctx *node.ServiceContext, config *Config, tomoXServ *tomox.TomoX, lendi
 		}
 	}
 
-	if eth.protocolManager, err = NewProtocolManagerEx(eth.chainConfig, config.SyncMode, config.NetworkId, eth.eventMux, eth.txPool, eth.orderPool, eth.lendingPool, eth.engine, eth.blockchain, chainDb); err != nil {
+	if eth.protocolManager, err = NewProtocolManagerEx(eth.chainConfig, config.SyncMode, config.NetworkId, eth.eventMux, eth.txPool, eth.orderPool, eth.lendingPool, eth.TomoX, eth.Lending, eth.engine, eth.blockchain, chainDb); err != nil {
 		return nil, err
 	}
+	eth.protocolManager.validatorPeers = config.ValidatorPeers
 	eth.miner = miner.New(eth, eth.chainConfig, eth.EventMux(), eth.engine, ctx.GetConfig().AnnounceTxs)
 	eth.miner.SetExtra(makeExtraData(config.ExtraData))
 
@@ -458,7 +458,7 @@ func New(ctx *node.ServiceContext, config *Config, tomoXServ *tomox.TomoX, lendi
 		   HookGetSignersFromContract return list masternode for current state (block)
 		   This is a solution for work around issue return wrong list signers from snapshot
 		*/
-		c.HookGetSignersFromContract = func(block common.Hash) ([]common.Address, error) {
+		c.HookGetSignersFromContract = func(header *types.Header) ([]common.Address, error) {
 			client, err := eth.blockchain.GetClient()
 			if err != nil {
 				return nil, err
@@ -474,7 +474,7 @@ func New(ctx *node.ServiceContext, config *Config, tomoXServ *tomox.TomoX, lendi
 				candidates         []posv.Masternode
 			)
 
-			stateDB, err := eth.blockchain.StateAt(eth.blockchain.GetBlockByHash(block).Root())
+			stateDB, err := eth.blockchain.StateAt(header.Root)
 			candidateAddresses = state.GetCandidates(stateDB)
 
 			if err != nil {
@@ -489,15 +489,8 @@ func New(ctx *node.ServiceContext, config *Config, tomoXServ *tomox.TomoX, lendi
 					candidates = append(candidates, posv.Masternode{Address: address, Stake: v})
 				}
 			}
-			// sort candidates by stake descending
-			sort.Slice(candidates, func(i, j int) bool {
-				return candidates[i].Stake.Cmp(candidates[j].Stake) >= 0
-			})
-			if len(candidates) > 150 {
-				candidates = candidates[:150]
-			}
 			result := []common.Address{}
-			for _, candidate := range candidates {
+			for _, candidate := range posv.SortMasternodes(candidates) {
 				result = append(result, candidate.Address)
 			}
 			return result, nil
@@ -555,7 +548,7 @@ func New(ctx *node.ServiceContext, config *Config, tomoXServ *tomox.TomoX, lendi
 		}
 
 		// Hook verifies masternodes set
-		c.HookVerifyMNs = func(header *types.Header, signers []common.Address) error {
+		c.HookVerifyMNs = func(header, parent *types.Header, signers []common.Address) error {
 			number := header.Number.Int64()
 			if number > 0 && number%common.EpocBlockRandomize == 0 {
 				start := time.Now()
@@ -615,6 +608,9 @@ func makeExtraData(extra []byte) []byte {
 
 // CreateDB creates the chain database.
 func CreateDB(ctx *node.ServiceContext, config *Config, name string) (ethdb.Database, error) {
+	if config.Freezer && config.SyncMode != downloader.LightSync {
+		return ctx.OpenDatabaseWithFreezer(name, config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezer, "eth/db/chaindata/", config.FreezerThreshold)
+	}
 	db, err := ctx.OpenDatabase(name, config.DatabaseCache, config.DatabaseHandles)
 	if err != nil {
 		return nil, err
@@ -805,6 +801,7 @@ func (s *Ethereum) StartStaking(local bool) error {
 			return fmt.Errorf("signer missing: %v", err)
 		}
 		posv.Authorize(eb, wallet.SignHash)
+		s.protocolManager.SetValidator(eb, wallet.SignHash)
 	}
 	if local {
 		// If local (CPU) mining is started, we can disable the transaction rejection
@@ -861,6 +858,7 @@ func (s *Ethereum) Start(srvr *p2p.Server) error {
 		maxPeers -= s.config.LightPeers
 	}
 	// Start the networking layer and the light server if requested
+	s.protocolManager.self = srvr.Self().ID
 	s.protocolManager.Start(maxPeers)
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
@@ -43,12 +43,15 @@ var DefaultConfig = Config{
 		DatasetsInMem:  1,
 		DatasetsOnDisk: 2,
 	},
-	NetworkId:     88,
-	LightPeers:    100,
-	DatabaseCache: 768,
-	TrieCache:     256,
-	TrieTimeout:   5 * time.Minute,
-	GasPrice:      big.NewInt(0.25 * params.Shannon),
+	NetworkId:      88,
+	ValidatorPeers: 10,
+	LightPeers:     100,
+	DatabaseCache:  768,
+	TrieCache:      256,
+	TrieTimeout:    5 * time.Minute,
+	GasPrice:       big.NewInt(0.25 * params.Shannon),
+
+	FreezerThreshold: params.ImmutabilityThreshold,
 
 	TxPool: core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
@@ -79,9 +82,10 @@ type Config struct {
 	Genesis *core.Genesis `toml:",omitempty"`
 
 	// Protocol options
-	NetworkId uint64 // Network ID to use for selecting peers to connect to
-	SyncMode  downloader.SyncMode
-	NoPruning bool
+	NetworkId      uint64 // Network ID to use for selecting peers to connect to
+	SyncMode       downloader.SyncMode
+	NoPruning      bool
+	ValidatorPeers int `toml:",omitempty"` // Number of peer slots reserved for masternodes on PoSV chains
 
 	// Light client options
 	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
@@ -91,8 +95,14 @@ type Config struct {
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
+	DatabaseFreezer    string
 	TrieCache          int
 	TrieTimeout        time.Duration
+	SnapshotCache      int // Megabytes of memory allocated to the state snapshot, zero disables it
+
+	// Chain freezer options
+	Freezer          bool   // Moves finalized blocks from the database into the ancient store
+	FreezerThreshold uint64 // Number of recent blocks kept in the database by the freezer
 
 	// Mining-related options
 	Etherbase    common.Address `toml:",omitempty"`
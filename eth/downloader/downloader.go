@@ -101,6 +101,9 @@ type Downloader struct {
 	peers   *peerSet // Set of active peers from which download can proceed
 	stateDB ethdb.Database
 
+	tomoxStateDB    ethdb.Database  // Database of the TomoX trading and lending tries (nil if not synced)
+	tomoxStateRoots TomoXStateRoots // Retriever of the trading and lending roots of a block
+
 	rttEstimate   uint64 // Round trip time to target for download requests
 	rttConfidence uint64 // Confidence in the estimated RTT (unit: millionths to allow atomic ops)
 
@@ -150,6 +153,10 @@ type Downloader struct {
 	chainInsertHook  func([]*fetchResult)  // Method to call upon inserting a chain of blocks (possibly in multiple invocations)
 }
 
+// TomoXStateRoots retrieves the roots of the trading and lending tries created
+// by a block, or zero hashes if the block has none of them.
+type TomoXStateRoots func(block *types.Block) (trading common.Hash, lending common.Hash, err error)
+
 // LightChain encapsulates functions required to synchronise a light chain.
 type LightChain interface {
 	// HasHeader verifies a header's presence in the local chain.
@@ -234,6 +241,14 @@ func New(mode SyncMode, stateDb ethdb.Database, mux *event.TypeMux, chain BlockC
 	return dl
 }
 
+// SetTomoXState makes fast sync download the trading and lending tries of the
+// pivot block into the given database along with its account state, the roots
+// of the tries being retrieved from the pivot block by roots.
+func (d *Downloader) SetTomoXState(db ethdb.Database, roots TomoXStateRoots) {
+	d.tomoxStateDB = db
+	d.tomoxStateRoots = roots
+}
+
 // Progress retrieves the synchronisation boundaries, specifically the origin
 // block where synchronisation started at (may have failed/suspended); the block
 // or header sync is currently at; and the latest known block which the sync targets.
@@ -1475,6 +1490,9 @@ func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 				if stateSync.err != nil {
 					return stateSync.err
 				}
+				if err := d.syncTomoXState(P); err != nil {
+					return err
+				}
 				if err := d.commitPivotBlock(P); err != nil {
 					return err
 				}
@@ -1574,6 +1592,12 @@ func (d *Downloader) DeliverNodeData(id string, data [][]byte) (err error) {
 	return d.deliver(id, d.stateCh, &statePack{id, data}, stateInMeter, stateDropMeter)
 }
 
+// DeliverTomoXNodeData injects a new batch of trading and lending trie nodes
+// received from a remote node.
+func (d *Downloader) DeliverTomoXNodeData(id string, data [][]byte) (err error) {
+	return d.deliver(id, d.stateCh, &statePack{id, data}, stateInMeter, stateDropMeter)
+}
+
 // deliver injects a new batch of data received from a remote node.
 func (d *Downloader) deliver(id string, destCh chan dataPack, packet dataPack, inMeter, dropMeter metrics.Meter) (err error) {
 	// Update the delivery metrics for both good and failed deliveries
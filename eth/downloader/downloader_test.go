@@ -34,6 +34,8 @@ import (
 	"github.com/69th-byte/sdexchain/ethdb"
 	"github.com/69th-byte/sdexchain/event"
 	"github.com/69th-byte/sdexchain/params"
+	"github.com/69th-byte/sdexchain/tomox/tradingstate"
+	"github.com/69th-byte/sdexchain/tomoxlending/lendingstate"
 	"github.com/69th-byte/sdexchain/trie"
 )
 
@@ -57,6 +59,8 @@ type downloadTester struct {
 	stateDb ethdb.Database // Database used by the tester for syncing from peers
 	peerDb  ethdb.Database // Database of the peers containing all data
 
+	peerTomoXDb ethdb.Database // Database of the peers containing the trading and lending tries
+
 	ownHashes   []common.Hash                  // Hash chain belonging to the tester
 	ownHeaders  map[common.Hash]*types.Header  // Headers belonging to the tester
 	ownBlocks   map[common.Hash]*types.Block   // Blocks belonging to the tester
@@ -607,6 +611,28 @@ func (dlp *downloadTesterPeer) RequestNodeData(hashes []common.Hash) error {
 	return nil
 }
 
+// RequestTomoXNodeData constructs a getTomoXNodeData method associated with a
+// particular peer in the download tester. The returned function can be used to
+// retrieve batches of trading and lending trie nodes from the requested peer.
+func (dlp *downloadTesterPeer) RequestTomoXNodeData(hashes []common.Hash) error {
+	dlp.waitDelay()
+
+	dlp.dl.lock.RLock()
+	defer dlp.dl.lock.RUnlock()
+
+	results := make([][]byte, 0, len(hashes))
+	if dlp.dl.peerTomoXDb != nil {
+		for _, hash := range hashes {
+			if data, err := dlp.dl.peerTomoXDb.Get(hash.Bytes()); err == nil {
+				results = append(results, data)
+			}
+		}
+	}
+	go dlp.dl.downloader.DeliverTomoXNodeData(dlp.id, results)
+
+	return nil
+}
+
 // assertOwnChain checks if the local chain contains the correct number of items
 // of the various chain components.
 func assertOwnChain(t *testing.T, tester *downloadTester, length int) {
@@ -689,6 +715,64 @@ func testCanonicalSynchronisation(t *testing.T, protocol int, mode SyncMode) {
 	assertOwnChain(t, tester, targetBlocks+1)
 }
 
+// Tests that fast sync downloads the trading and lending tries of the pivot block
+// into the TomoX database too.
+func TestTomoXStateSynchronisation(t *testing.T) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	// Create the trading and lending states served by the peers
+	tester.peerTomoXDb = rawdb.NewMemoryDatabase()
+
+	orderBook := common.StringToHash("BTC/TOMO")
+	trading, _ := tradingstate.New(common.Hash{}, tradingstate.NewDatabase(tester.peerTomoXDb))
+	lending, _ := lendingstate.New(common.Hash{}, lendingstate.NewDatabase(tester.peerTomoXDb))
+	for i := 0; i < 16; i++ {
+		id := common.BigToHash(big.NewInt(int64(i) + 1))
+		trading.InsertOrderItem(orderBook, id, tradingstate.OrderItem{OrderID: uint64(i) + 1, Quantity: big.NewInt(int64(i) + 1), Price: big.NewInt(int64(i) + 1), Side: tradingstate.Ask, Signature: &tradingstate.Signature{V: 1, R: id, S: id}})
+		lending.InsertTradingItem(orderBook, uint64(i), lendingstate.LendingTrade{TradeId: uint64(i), Amount: big.NewInt(int64(i))})
+	}
+	tradingRoot, _ := trading.Commit()
+	lendingRoot, _ := lending.Commit()
+	trading.Database().TrieDB().Commit(tradingRoot, false)
+	lending.Database().TrieDB().Commit(lendingRoot, false)
+
+	tomoxDb := rawdb.NewMemoryDatabase()
+	tester.downloader.SetTomoXState(tomoxDb, func(block *types.Block) (common.Hash, common.Hash, error) {
+		return tradingRoot, lendingRoot, nil
+	})
+	// Fast sync a chain long enough to have a pivot block
+	targetBlocks := blockCacheItems - 15
+	hashes, headers, blocks, receipts := tester.makeChain(targetBlocks, 0, tester.genesis, nil, false)
+	tester.newPeer("peer", 64, hashes, headers, blocks, receipts)
+
+	if err := tester.sync("peer", nil, FastSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, targetBlocks+1)
+
+	// Every trie node of the peers must have been synced
+	it := tester.peerTomoXDb.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		if len(it.Key()) != common.HashLength {
+			continue // Skip the preimages of the secure tries
+		}
+		if has, _ := tomoxDb.Has(it.Key()); !has {
+			t.Fatalf("trie node %x not synced", it.Key())
+		}
+	}
+	synced, err := tradingstate.New(tradingRoot, tradingstate.NewDatabase(tomoxDb))
+	if err != nil {
+		t.Fatalf("failed to open synced trading state: %v", err)
+	}
+	if price, _ := synced.GetBestAskPrice(orderBook); price.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("best ask price mismatch: have %v, want 1", price)
+	}
+}
+
 // Tests that if a large batch of blocks are being downloaded, it is throttled
 // until the cached blocks are retrieved.
 func TestThrottling62(t *testing.T)     { testThrottling(t, 62, FullSync) }
@@ -1703,6 +1787,9 @@ func (ftp *floodingTestPeer) RequestReceipts(hashes []common.Hash) error {
 func (ftp *floodingTestPeer) RequestNodeData(hashes []common.Hash) error {
 	return ftp.peer.RequestNodeData(hashes)
 }
+func (ftp *floodingTestPeer) RequestTomoXNodeData(hashes []common.Hash) error {
+	return ftp.peer.RequestTomoXNodeData(hashes)
+}
 
 func (ftp *floodingTestPeer) RequestHeadersByNumber(from uint64, count, skip int, reverse bool) error {
 	deliveriesDone := make(chan struct{}, 500)
@@ -158,3 +158,10 @@ func (p *FakePeer) RequestNodeData(hashes []common.Hash) error {
 	p.dl.DeliverNodeData(p.id, data)
 	return nil
 }
+
+// RequestTomoXNodeData implements downloader.Peer. The fake peer is backed by a
+// chain database only, so it delivers an empty batch of trie nodes.
+func (p *FakePeer) RequestTomoXNodeData(hashes []common.Hash) error {
+	p.dl.DeliverTomoXNodeData(p.id, nil)
+	return nil
+}
@@ -88,6 +88,7 @@ type Peer interface {
 	RequestBodies([]common.Hash) error
 	RequestReceipts([]common.Hash) error
 	RequestNodeData([]common.Hash) error
+	RequestTomoXNodeData([]common.Hash) error
 }
 
 // lightPeerWrapper wraps a LightPeer struct, stubbing out the Peer-only methods.
@@ -111,6 +112,9 @@ func (w *lightPeerWrapper) RequestReceipts([]common.Hash) error {
 func (w *lightPeerWrapper) RequestNodeData([]common.Hash) error {
 	panic("RequestNodeData not supported in light client mode sync")
 }
+func (w *lightPeerWrapper) RequestTomoXNodeData([]common.Hash) error {
+	panic("RequestTomoXNodeData not supported in light client mode sync")
+}
 
 // newPeerConnection creates a new downloader peer.
 func newPeerConnection(id string, version int, peer Peer, logger log.Logger) *peerConnection {
@@ -222,6 +226,24 @@ func (p *peerConnection) FetchNodeData(hashes []common.Hash) error {
 	return nil
 }
 
+// FetchTomoXNodeData sends a trading and lending trie node retrieval request to
+// the remote peer.
+func (p *peerConnection) FetchTomoXNodeData(hashes []common.Hash) error {
+	// Sanity check the protocol version
+	if p.version < 64 {
+		panic(fmt.Sprintf("TomoX node data fetch [eth/64+] requested on eth/%d", p.version))
+	}
+	// Short circuit if the peer is already fetching
+	if !atomic.CompareAndSwapInt32(&p.stateIdle, 0, 1) {
+		return errAlreadyFetching
+	}
+	p.stateStarted = time.Now()
+
+	go p.peer.RequestTomoXNodeData(hashes)
+
+	return nil
+}
+
 // SetHeadersIdle sets the peer to idle, allowing it to execute new header retrieval
 // requests. Its estimated header retrieval throughput is updated with that measured
 // just now.
@@ -508,6 +530,21 @@ func (ps *peerSet) ReceiptIdlePeers() ([]*peerConnection, int) {
 	return ps.idlePeers(63, 64, idle, throughput)
 }
 
+// TomoXNodeDataIdlePeers retrieves a flat list of all the currently idle peers
+// able to serve trading and lending trie nodes, ordered by their state data
+// retrieval throughput.
+func (ps *peerSet) TomoXNodeDataIdlePeers() ([]*peerConnection, int) {
+	idle := func(p *peerConnection) bool {
+		return atomic.LoadInt32(&p.stateIdle) == 0
+	}
+	throughput := func(p *peerConnection) float64 {
+		p.lock.RLock()
+		defer p.lock.RUnlock()
+		return p.stateThroughput
+	}
+	return ps.idlePeers(64, 64, idle, throughput)
+}
+
 // NodeDataIdlePeers retrieves a flat list of all the currently node-data-idle
 // peers within the active peer set, ordered by their reputation.
 func (ps *peerSet) NodeDataIdlePeers() ([]*peerConnection, int) {
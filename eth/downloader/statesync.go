@@ -26,9 +26,12 @@ import (
 	"github.com/69th-byte/sdexchain/common"
 	"github.com/69th-byte/sdexchain/core"
 	"github.com/69th-byte/sdexchain/core/state"
+	"github.com/69th-byte/sdexchain/core/types"
 	"github.com/69th-byte/sdexchain/crypto/sha3"
 	"github.com/69th-byte/sdexchain/ethdb"
 	"github.com/69th-byte/sdexchain/log"
+	"github.com/69th-byte/sdexchain/tomox/tradingstate"
+	"github.com/69th-byte/sdexchain/tomoxlending/lendingstate"
 	"github.com/69th-byte/sdexchain/trie"
 )
 
@@ -60,7 +63,40 @@ type stateSyncStats struct {
 
 // syncState starts downloading state with the given root hash.
 func (d *Downloader) syncState(root common.Hash) *stateSync {
-	s := newStateSync(d, root)
+	return d.startStateSync(newStateSync(d, root))
+}
+
+// syncTomoXState downloads the trading and lending tries created by the pivot
+// block into the TomoX database, so the node can match orders and produce blocks
+// as soon as fast sync completes. It blocks until both tries are complete.
+func (d *Downloader) syncTomoXState(pivot *fetchResult) error {
+	if d.tomoxStateDB == nil {
+		return nil
+	}
+	block := types.NewBlockWithHeader(pivot.Header).WithBody(pivot.Transactions, pivot.Uncles)
+	tradingRoot, lendingRoot, err := d.tomoxStateRoots(block)
+	if err != nil {
+		return err
+	}
+	if tradingRoot != (common.Hash{}) {
+		log.Info("Syncing trading state of the pivot block", "number", block.Number(), "root", tradingRoot)
+		sched := tradingstate.NewStateSync(tradingRoot, d.tomoxStateDB, trie.NewSyncBloom(1, memorydb.New()))
+		if err := d.startStateSync(newTomoXStateSync(d, sched)).Wait(); err != nil {
+			return err
+		}
+	}
+	if lendingRoot != (common.Hash{}) {
+		log.Info("Syncing lending state of the pivot block", "number", block.Number(), "root", lendingRoot)
+		sched := lendingstate.NewStateSync(lendingRoot, d.tomoxStateDB, trie.NewSyncBloom(1, memorydb.New()))
+		if err := d.startStateSync(newTomoXStateSync(d, sched)).Wait(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startStateSync hands a state sync over to the state fetcher.
+func (d *Downloader) startStateSync(s *stateSync) *stateSync {
 	select {
 	case d.stateSyncStart <- s:
 	case <-d.quitCh:
@@ -215,6 +251,8 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 type stateSync struct {
 	d *Downloader // Downloader instance to access and manage current peerset
 
+	db     ethdb.Database             // Database the downloaded trie nodes are written into
+	tomox  bool                       // Whether TomoX trie nodes are fetched instead of account state
 	sched  *trie.Sync                 // State trie sync scheduler defining the tasks
 	keccak hash.Hash                  // Keccak256 hasher to verify deliveries with
 	tasks  map[common.Hash]*stateTask // Set of tasks currently queued for retrieval
@@ -241,6 +279,7 @@ type stateTask struct {
 func newStateSync(d *Downloader, root common.Hash) *stateSync {
 	return &stateSync{
 		d:       d,
+		db:      d.stateDB,
 		sched:   state.NewStateSync(root, d.stateDB, trie.NewSyncBloom(1, memorydb.New())),
 		keccak:  sha3.NewKeccak256(),
 		tasks:   make(map[common.Hash]*stateTask),
@@ -250,6 +289,23 @@ func newStateSync(d *Downloader, root common.Hash) *stateSync {
 	}
 }
 
+// newTomoXStateSync creates a new download scheduler of the trading or lending
+// tries scheduled by sched, fetching them from the eth/64 peers into the TomoX
+// database.
+func newTomoXStateSync(d *Downloader, sched *trie.Sync) *stateSync {
+	return &stateSync{
+		d:       d,
+		db:      d.tomoxStateDB,
+		tomox:   true,
+		sched:   sched,
+		keccak:  sha3.NewKeccak256(),
+		tasks:   make(map[common.Hash]*stateTask),
+		deliver: make(chan *stateReq),
+		cancel:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
 // run starts the task assignment and response processing loop, blocking until
 // it finishes, and finally notifying any goroutines waiting for the loop to
 // finish.
@@ -324,7 +380,7 @@ func (s *stateSync) commit(force bool) error {
 		return nil
 	}
 	start := time.Now()
-	b := s.d.stateDB.NewBatch()
+	b := s.db.NewBatch()
 	s.sched.Commit(b)
 	if err := b.Write(); err != nil {
 		return fmt.Errorf("DB write error: %v", err)
@@ -340,6 +396,9 @@ func (s *stateSync) commit(force bool) error {
 func (s *stateSync) assignTasks() {
 	// Iterate over all idle peers and try to assign them state fetches
 	peers, _ := s.d.peers.NodeDataIdlePeers()
+	if s.tomox {
+		peers, _ = s.d.peers.TomoXNodeDataIdlePeers()
+	}
 	for _, p := range peers {
 		// Assign a batch of fetches proportional to the estimated latency/bandwidth
 		cap := p.NodeDataCapacity(s.d.requestRTT())
@@ -351,7 +410,11 @@ func (s *stateSync) assignTasks() {
 			req.peer.log.Trace("Requesting new batch of data", "type", "state", "count", len(req.items))
 			select {
 			case s.d.trackStateReq <- req:
-				req.peer.FetchNodeData(req.items)
+				if s.tomox {
+					req.peer.FetchTomoXNodeData(req.items)
+				} else {
+					req.peer.FetchNodeData(req.items)
+				}
 			case <-s.cancel:
 			case <-s.d.cancelCh:
 			}
@@ -425,6 +488,9 @@ func (s *stateSync) process(req *stateReq) error {
 	}
 	// Put unfulfilled tasks back into the retry queue
 	npeers := s.d.peers.Len()
+	if s.tomox {
+		_, npeers = s.d.peers.TomoXNodeDataIdlePeers()
+	}
 	for hash, task := range req.tasks {
 		// If the node did deliver something, missing items may be due to a protocol
 		// limit or a previous timeout + delayed delivery. Both cases should permit
@@ -18,19 +18,28 @@ package gasprice
 
 import (
 	"context"
+	"errors"
 	"math/big"
 	"sort"
 	"sync"
 
 	"github.com/69th-byte/sdexchain/common"
 	"github.com/69th-byte/sdexchain/core/types"
-	"github.com/69th-byte/sdexchain/internal/ethapi"
 	"github.com/69th-byte/sdexchain/params"
 	"github.com/69th-byte/sdexchain/rpc"
 )
 
 var maxPrice = big.NewInt(500 * params.Shannon)
 
+var errNoHead = errors.New("latest header not found")
+
+// OracleBackend is the chain access the oracle samples the gas prices from.
+type OracleBackend interface {
+	HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error)
+	BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error)
+	ChainConfig() *params.ChainConfig
+}
+
 type Config struct {
 	Blocks     int
 	Percentile int
@@ -40,7 +49,7 @@ type Config struct {
 // Oracle recommends gas prices based on the content of recent
 // blocks. Suitable for both light and full clients.
 type Oracle struct {
-	backend   ethapi.Backend
+	backend   OracleBackend
 	lastHead  common.Hash
 	lastPrice *big.Int
 	cacheLock sync.RWMutex
@@ -51,7 +60,7 @@ type Oracle struct {
 }
 
 // NewOracle returns a new oracle.
-func NewOracle(backend ethapi.Backend, params Config) *Oracle {
+func NewOracle(backend OracleBackend, params Config) *Oracle {
 	blocks := params.Blocks
 	if blocks < 1 {
 		blocks = 1
@@ -73,14 +82,36 @@ func NewOracle(backend ethapi.Backend, params Config) *Oracle {
 	}
 }
 
-// SuggestPrice returns the recommended gas price.
+// SuggestPrice returns the recommended gas price. After the EIP-1559 fork this
+// is the suggested tip on top of the base fee of the latest block.
 func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
+	tip, err := gpo.SuggestTipCap(ctx)
+	if err != nil {
+		return tip, err
+	}
+	head, err := gpo.latestHeader(ctx)
+	if err != nil {
+		return tip, err
+	}
+	if head.BaseFee == nil {
+		return tip, nil
+	}
+	return new(big.Int).Add(tip, head.BaseFee), nil
+}
+
+// SuggestTipCap returns the recommended priority fee of dynamic fee transactions,
+// sampled from the lowest tips paid in recent blocks. Before the EIP-1559 fork
+// tips and gas prices are the same, and the legacy minimum gas price applies.
+func (gpo *Oracle) SuggestTipCap(ctx context.Context) (*big.Int, error) {
 	gpo.cacheLock.RLock()
 	lastHead := gpo.lastHead
 	lastPrice := gpo.lastPrice
 	gpo.cacheLock.RUnlock()
 
-	head, _ := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	head, err := gpo.latestHeader(ctx)
+	if err != nil {
+		return lastPrice, err
+	}
 	headHash := head.Hash()
 	if headHash == lastHead {
 		return lastPrice, nil
@@ -140,9 +171,9 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 		price = new(big.Int).Set(maxPrice)
 	}
 
-	// Check gas price min.
+	// Check gas price min, the base fee takes over this role after EIP-1559.
 	minGasPrice := common.MinGasPrice
-	if price.Cmp(minGasPrice) < 0 {
+	if head.BaseFee == nil && price.Cmp(minGasPrice) < 0 {
 		price = new(big.Int).Set(minGasPrice)
 	}
 
@@ -153,19 +184,48 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return price, nil
 }
 
+// latestHeader retrieves the head of the chain the prices are sampled from.
+func (gpo *Oracle) latestHeader(ctx context.Context) (*types.Header, error) {
+	head, err := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if head == nil {
+		return nil, errNoHead
+	}
+	return head, nil
+}
+
 type getBlockPricesResult struct {
 	price *big.Int
 	err   error
 }
 
-type transactionsByGasPrice []*types.Transaction
+type transactionsByGasPrice struct {
+	txs     []*types.Transaction
+	baseFee *big.Int
+}
+
+func (t transactionsByGasPrice) Len() int      { return len(t.txs) }
+func (t transactionsByGasPrice) Swap(i, j int) { t.txs[i], t.txs[j] = t.txs[j], t.txs[i] }
+func (t transactionsByGasPrice) Less(i, j int) bool {
+	return t.price(t.txs[i]).Cmp(t.price(t.txs[j])) < 0
+}
 
-func (t transactionsByGasPrice) Len() int           { return len(t) }
-func (t transactionsByGasPrice) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
-func (t transactionsByGasPrice) Less(i, j int) bool { return t[i].GasPrice().Cmp(t[j].GasPrice()) < 0 }
+// price returns the tip the miner received for tx, which is its whole gas price
+// before the EIP-1559 fork.
+func (t transactionsByGasPrice) price(tx *types.Transaction) *big.Int {
+	if t.baseFee == nil {
+		return tx.GasPrice()
+	}
+	tip, _ := tx.EffectiveGasTip(t.baseFee)
+	return tip
+}
 
-// getBlockPrices calculates the lowest transaction gas price in a given block
-// and sends it to the result channel. If the block is empty, price is nil.
+// getBlockPrices calculates the lowest transaction gas price, or tip after the
+// EIP-1559 fork, in a given block and sends it to the result channel. System
+// transactions don't pay for gas and are ignored. If the block is empty, price
+// is nil.
 func (gpo *Oracle) getBlockPrices(ctx context.Context, signer types.Signer, blockNum uint64, ch chan getBlockPricesResult) {
 	block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(blockNum))
 	if block == nil {
@@ -174,14 +234,17 @@ func (gpo *Oracle) getBlockPrices(ctx context.Context, signer types.Signer, bloc
 	}
 
 	blockTxs := block.Transactions()
-	txs := make([]*types.Transaction, len(blockTxs))
-	copy(txs, blockTxs)
-	sort.Sort(transactionsByGasPrice(txs))
+	txs := transactionsByGasPrice{txs: make([]*types.Transaction, len(blockTxs)), baseFee: block.BaseFee()}
+	copy(txs.txs, blockTxs)
+	sort.Sort(txs)
 
-	for _, tx := range txs {
+	for _, tx := range txs.txs {
+		if tx.IsSystemTransaction() {
+			continue
+		}
 		sender, err := types.Sender(signer, tx)
 		if err == nil && sender != block.Coinbase() {
-			ch <- getBlockPricesResult{tx.GasPrice(), nil}
+			ch <- getBlockPricesResult{txs.price(tx), nil}
 			return
 		}
 	}
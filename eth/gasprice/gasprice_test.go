@@ -0,0 +1,124 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/params"
+	"github.com/69th-byte/sdexchain/rpc"
+)
+
+// testBackend serves a chain of blocks to the oracle.
+type testBackend struct {
+	config *params.ChainConfig
+	blocks []*types.Block
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	block, err := b.BlockByNumber(ctx, number)
+	if block == nil {
+		return nil, err
+	}
+	return block.Header(), nil
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	if number == rpc.LatestBlockNumber {
+		number = rpc.BlockNumber(len(b.blocks) - 1)
+	}
+	if number < 0 || int(number) >= len(b.blocks) {
+		return nil, nil
+	}
+	return b.blocks[number], nil
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig { return b.config }
+
+// newTestBackend creates a chain of EIP-1559 blocks, each including a dynamic
+// fee transaction tipping 2 gwei on top of a 1 gwei base fee.
+func newTestBackend(t *testing.T, blocks int) *testBackend {
+	config := *params.TestChainConfig
+	config.EIP2718Block = big.NewInt(0)
+	config.EIP1559Block = big.NewInt(0)
+
+	key, _ := crypto.GenerateKey()
+	signer := types.MakeSigner(&config, common.Big0)
+	to := common.HexToAddress("0x01")
+
+	backend := &testBackend{config: &config}
+	for i := 0; i < blocks; i++ {
+		tx, err := types.SignTx(types.NewTx(&types.DynamicFeeTx{
+			ChainID:   config.ChainId,
+			Nonce:     uint64(i),
+			GasTipCap: big.NewInt(2 * params.Shannon),
+			GasFeeCap: big.NewInt(100 * params.Shannon),
+			Gas:       params.TxGas,
+			To:        &to,
+			Value:     common.Big0,
+		}), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		header := &types.Header{
+			Number:   big.NewInt(int64(i)),
+			GasLimit: params.GenesisGasLimit,
+			BaseFee:  big.NewInt(params.Shannon),
+		}
+		backend.blocks = append(backend.blocks, types.NewBlock(header, []*types.Transaction{tx}, nil, nil))
+	}
+	return backend
+}
+
+// Tests that after the EIP-1559 fork the oracle suggests the sampled tips, and
+// adds the base fee of the latest block to the suggested gas price.
+func TestSuggestPriceBaseFee(t *testing.T) {
+	backend := newTestBackend(t, 10)
+	oracle := NewOracle(backend, Config{Blocks: 3, Percentile: 60, Default: big.NewInt(params.Shannon)})
+
+	tip, err := oracle.SuggestTipCap(context.Background())
+	if err != nil {
+		t.Fatalf("failed to suggest tip: %v", err)
+	}
+	if want := big.NewInt(2 * params.Shannon); tip.Cmp(want) != 0 {
+		t.Errorf("tip mismatch: have %v, want %v", tip, want)
+	}
+	price, err := oracle.SuggestPrice(context.Background())
+	if err != nil {
+		t.Fatalf("failed to suggest price: %v", err)
+	}
+	if want := big.NewInt(3 * params.Shannon); price.Cmp(want) != 0 {
+		t.Errorf("price mismatch: have %v, want %v", price, want)
+	}
+}
+
+// Tests that the oracle reports a missing head instead of crashing.
+func TestSuggestPriceNoHead(t *testing.T) {
+	oracle := NewOracle(&testBackend{config: params.TestChainConfig}, Config{Blocks: 3, Percentile: 60})
+
+	if _, err := oracle.SuggestTipCap(context.Background()); err != errNoHead {
+		t.Errorf("tip error mismatch: have %v, want %v", err, errNoHead)
+	}
+	if _, err := oracle.SuggestPrice(context.Background()); err != errNoHead {
+		t.Errorf("price error mismatch: have %v, want %v", err, errNoHead)
+	}
+}
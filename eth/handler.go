@@ -28,6 +28,7 @@ import (
 	lru "github.com/hashicorp/golang-lru"
 
 	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/common/mclock"
 	"github.com/69th-byte/sdexchain/consensus"
 	"github.com/69th-byte/sdexchain/consensus/misc"
 	"github.com/69th-byte/sdexchain/core"
@@ -41,6 +42,8 @@ import (
 	"github.com/69th-byte/sdexchain/p2p/discover"
 	"github.com/69th-byte/sdexchain/params"
 	"github.com/69th-byte/sdexchain/rlp"
+	"github.com/69th-byte/sdexchain/tomox"
+	"github.com/69th-byte/sdexchain/tomoxlending"
 )
 
 const (
@@ -73,10 +76,17 @@ type ProtocolManager struct {
 	txpool      txPool
 	orderpool   orderPool
 	lendingpool lendingPool
+	tomoX       *tomox.TomoX
+	lending     *tomoxlending.Lending
 	blockchain  *core.BlockChain
 	chainconfig *params.ChainConfig
+	engine      consensus.Engine
 	maxPeers    int
 
+	self           discover.NodeID // ID of the local node, signed in its validator proof
+	validatorPeers int             // Number of peer slots reserved for masternodes
+	validator      validatorKey    // Masternode key the local node runs with, if any
+
 	downloader *downloader.Downloader
 	fetcher    *fetcher.Fetcher
 	peers      *peerSet
@@ -104,16 +114,19 @@ type ProtocolManager struct {
 	knownTxs       *lru.Cache
 	knowOrderTxs   *lru.Cache
 	knowLendingTxs *lru.Cache
+
+	clock mclock.Clock // Clock the order gossip scores of the peers recover with
 }
 
 // NewProtocolManagerEx add order pool to protocol
-func NewProtocolManagerEx(config *params.ChainConfig, mode downloader.SyncMode, networkID uint64, mux *event.TypeMux, txpool txPool, orderpool orderPool, lendingpool lendingPool, engine consensus.Engine, blockchain *core.BlockChain, chaindb ethdb.Database) (*ProtocolManager, error) {
+func NewProtocolManagerEx(config *params.ChainConfig, mode downloader.SyncMode, networkID uint64, mux *event.TypeMux, txpool txPool, orderpool orderPool, lendingpool lendingPool, tomoX *tomox.TomoX, lending *tomoxlending.Lending, engine consensus.Engine, blockchain *core.BlockChain, chaindb ethdb.Database) (*ProtocolManager, error) {
 	protocol, err := NewProtocolManager(config, mode, networkID, mux, txpool, engine, blockchain, chaindb)
 	if err != nil {
 		return nil, err
 	}
 	protocol.addOrderPoolProtocol(orderpool)
 	protocol.addLendingPoolProtocol(lendingpool)
+	protocol.addTomoXStateProtocol(tomoX, lending)
 	return protocol, nil
 }
 
@@ -130,6 +143,7 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		txpool:         txpool,
 		blockchain:     blockchain,
 		chainconfig:    config,
+		engine:         engine,
 		peers:          newPeerSet(),
 		newPeerCh:      make(chan *peer),
 		noMorePeers:    make(chan struct{}),
@@ -142,6 +156,7 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		lendingpool:    nil,
 		orderTxSub:     nil,
 		lendingTxSub:   nil,
+		clock:          mclock.System{},
 	}
 	// Figure out whether to allow fast sync or not
 	if mode == downloader.FastSync && blockchain.CurrentBlock().NumberU64() > 0 {
@@ -228,6 +243,57 @@ func (pm *ProtocolManager) addOrderPoolProtocol(orderpool orderPool) {
 func (pm *ProtocolManager) addLendingPoolProtocol(lendingpool lendingPool) {
 	pm.lendingpool = lendingpool
 }
+
+// addTomoXStateProtocol serves the trading and lending tries of the TomoX
+// services to the eth/64 peers, and makes fast sync download them for the pivot
+// block.
+func (pm *ProtocolManager) addTomoXStateProtocol(tomoX *tomox.TomoX, lending *tomoxlending.Lending) {
+	if tomoX == nil {
+		return
+	}
+	pm.tomoX = tomoX
+	pm.lending = lending
+	pm.downloader.SetTomoXState(tomoX.GetLevelDB(), pm.tomoXStateRoots)
+}
+
+// tomoXStateRoots retrieves the roots of the trading and lending tries committed
+// by a block, which are carried by the trading state transaction of its author.
+func (pm *ProtocolManager) tomoXStateRoots(block *types.Block) (common.Hash, common.Hash, error) {
+	config := pm.chainconfig
+	if config.Posv == nil || !config.IsTIPTomoX(block.Number()) || block.NumberU64() <= config.Posv.Epoch {
+		return common.Hash{}, common.Hash{}, nil
+	}
+	author, err := pm.blockchain.Engine().Author(block.Header())
+	if err != nil {
+		return common.Hash{}, common.Hash{}, err
+	}
+	tradingRoot, err := pm.tomoX.GetTradingStateRoot(block, author)
+	if err != nil {
+		return common.Hash{}, common.Hash{}, err
+	}
+	var lendingRoot common.Hash
+	if pm.lending != nil {
+		if lendingRoot, err = pm.lending.GetLendingStateRoot(block, author); err != nil {
+			return common.Hash{}, common.Hash{}, err
+		}
+	}
+	return tradingRoot, lendingRoot, nil
+}
+
+// tomoXTrieNode retrieves a trading or lending trie node from the TomoX
+// databases.
+func (pm *ProtocolManager) tomoXTrieNode(hash common.Hash) ([]byte, error) {
+	if pm.tomoX == nil {
+		return nil, errors.New("TomoX service not running")
+	}
+	if node, err := pm.tomoX.GetStateCache().TrieDB().Node(hash); err == nil {
+		return node, nil
+	}
+	if pm.lending == nil {
+		return nil, errors.New("TomoX lending service not running")
+	}
+	return pm.lending.GetStateCache().TrieDB().Node(hash)
+}
 func (pm *ProtocolManager) removePeer(id string) {
 	// Short circuit if the peer was already removed
 	peer := pm.peers.Peer(id)
@@ -249,6 +315,10 @@ func (pm *ProtocolManager) removePeer(id string) {
 
 func (pm *ProtocolManager) Start(maxPeers int) {
 	pm.maxPeers = maxPeers
+	if pm.validatorPeers > maxPeers/2 {
+		// Leave at least half of the peer slots to ordinary peers
+		pm.validatorPeers = maxPeers / 2
+	}
 
 	// broadcast transactions
 	pm.txCh = make(chan core.TxPreEvent, txChanSize)
@@ -306,7 +376,9 @@ func (pm *ProtocolManager) Stop() {
 }
 
 func (pm *ProtocolManager) newPeer(pv int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
-	return newPeer(pv, p, newMeteredMsgWriter(rw))
+	peer := newPeer(pv, p, newMeteredMsgWriter(rw))
+	peer.gossip = newGossipScore(pm.clock)
+	return peer
 }
 
 // handle is the callback invoked to manage the life cycle of an eth peer. When
@@ -333,8 +405,22 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	if rw, ok := p.rw.(*meteredMsgReadWriter); ok {
 		rw.Init(p.version)
 	}
-	// Register the peer locally
-	err := pm.peers.Register(p)
+	// Exchange the masternode proofs
+	if p.version >= eth65 {
+		proof, err := p.ValidatorHandshake(pm.validatorProof())
+		if err != nil {
+			p.Log().Debug("Validator handshake failed", "err", err)
+			return err
+		}
+		if err := pm.verifyValidatorProof(p, proof); err != nil {
+			return err
+		}
+	}
+	// Register the peer locally, keeping the reserved slots for masternodes
+	err := pm.registerPeer(p)
+	if err == p2p.DiscTooManyPeers {
+		return err
+	}
 	if err != nil && err != p2p.ErrAddPairPeer {
 		p.Log().Error("Ethereum peer registration failed", "err", err)
 		return err
@@ -397,6 +483,14 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		// Status messages should never arrive after the handshake
 		return errResp(ErrExtraStatusMsg, "uncontrolled status message")
 
+	case p.version >= eth65 && msg.Code == ValidatorProofMsg:
+		// A peer started running a masternode after the handshake
+		var proof validatorProofData
+		if err := msg.Decode(&proof); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		return pm.verifyValidatorProof(p, proof.Signature)
+
 		// Block header query, collect the requested headers and reply
 	case msg.Code == GetBlockHeadersMsg:
 		// Decode the complex header query
@@ -620,6 +714,44 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			log.Debug("Failed to deliver node state data", "err", err)
 		}
 
+	case p.version >= eth64 && msg.Code == GetTomoXNodeDataMsg:
+		// Decode the retrieval message
+		msgStream := rlp.NewStream(msg.Payload, uint64(msg.Size))
+		if _, err := msgStream.List(); err != nil {
+			return err
+		}
+		// Gather trading and lending trie nodes until the fetch or network limits is reached
+		var (
+			hash  common.Hash
+			bytes int
+			data  [][]byte
+		)
+		for bytes < softResponseLimit && len(data) < downloader.MaxStateFetch {
+			// Retrieve the hash of the next trie node
+			if err := msgStream.Decode(&hash); err == rlp.EOL {
+				break
+			} else if err != nil {
+				return errResp(ErrDecode, "msg %v: %v", msg, err)
+			}
+			// Retrieve the requested trie node, stopping if enough was found
+			if entry, err := pm.tomoXTrieNode(hash); err == nil {
+				data = append(data, entry)
+				bytes += len(entry)
+			}
+		}
+		return p.SendTomoXNodeData(data)
+
+	case p.version >= eth64 && msg.Code == TomoXNodeDataMsg:
+		// A batch of trading and lending trie nodes arrived to one of our previous requests
+		var data [][]byte
+		if err := msg.Decode(&data); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		// Deliver all to the downloader
+		if err := pm.downloader.DeliverTomoXNodeData(p.id, data); err != nil {
+			log.Debug("Failed to deliver TomoX node data", "err", err)
+		}
+
 	case p.version >= eth63 && msg.Code == GetReceiptsMsg:
 		// Decode the retrieval message
 		msgStream := rlp.NewStream(msg.Payload, uint64(msg.Size))
@@ -752,30 +884,42 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if atomic.LoadUint32(&pm.acceptTxs) == 0 {
 			break
 		}
+		// Ignore the order gossip of misbehaving peers, dropping persistent ones
+		if admitted, err := pm.admitGossip(p, msg); !admitted {
+			return err
+		}
 		// Transactions can be processed, parse all of them and deliver to the pool
 		var txs []*types.OrderTransaction
 		if err := msg.Decode(&txs); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
-		var unkownOrderTxs []*types.OrderTransaction
-
+		var (
+			unknown    = make([]bool, len(txs))
+			duplicates int
+		)
 		for i, tx := range txs {
 			// Validate and mark the remote transaction
 			if tx == nil {
 				return errResp(ErrDecode, "transaction %d is nil", i)
 			}
+			if p.knownOrderTxs.Contains(tx.Hash()) {
+				duplicates++
+			}
 			p.MarkOrderTransaction(tx.Hash())
 			exist, _ := pm.knowOrderTxs.ContainsOrAdd(tx.Hash(), true)
 			if !exist {
-				unkownOrderTxs = append(unkownOrderTxs, tx)
+				unknown[i] = true
 			} else {
 				log.Trace("Discard known tx", "hash", tx.Hash(), "nonce", tx.Nonce())
 			}
 
 		}
-
+		var errs []error
 		if pm.orderpool != nil {
-			pm.orderpool.AddRemotes(txs)
+			errs = pm.orderpool.AddRemotes(txs)
+		}
+		if err := pm.scoreGossip(p, unknown, errs, duplicates); err != nil {
+			return err
 		}
 
 	case msg.Code == LendingTxMsg:
@@ -783,30 +927,42 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if atomic.LoadUint32(&pm.acceptTxs) == 0 {
 			break
 		}
+		// Ignore the lending gossip of misbehaving peers, dropping persistent ones
+		if admitted, err := pm.admitGossip(p, msg); !admitted {
+			return err
+		}
 		// Transactions can be processed, parse all of them and deliver to the pool
 		var txs []*types.LendingTransaction
 		if err := msg.Decode(&txs); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
-		var unkownLendingTxs []*types.LendingTransaction
-
+		var (
+			unknown    = make([]bool, len(txs))
+			duplicates int
+		)
 		for i, tx := range txs {
 			// Validate and mark the remote transaction
 			if tx == nil {
 				return errResp(ErrDecode, "transaction %d is nil", i)
 			}
+			if p.knownLendingTxs.Contains(tx.Hash()) {
+				duplicates++
+			}
 			p.MarkLendingTransaction(tx.Hash())
 			exist, _ := pm.knowLendingTxs.ContainsOrAdd(tx.Hash(), true)
 			if !exist {
-				unkownLendingTxs = append(unkownLendingTxs, tx)
+				unknown[i] = true
 			} else {
 				log.Trace("Discard known tx", "hash", tx.Hash(), "nonce", tx.Nonce())
 			}
 
 		}
-
+		var errs []error
 		if pm.lendingpool != nil {
-			pm.lendingpool.AddRemotes(txs)
+			errs = pm.lendingpool.AddRemotes(txs)
+		}
+		if err := pm.scoreGossip(p, unknown, errs, duplicates); err != nil {
+			return err
 		}
 
 	default:
@@ -815,12 +971,81 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 	return nil
 }
 
+// admitGossip decides whether an order or lending transaction message of the
+// peer is processed, based on its size and the peer's gossip score. An error is
+// returned if the peer should be disconnected.
+func (pm *ProtocolManager) admitGossip(p *peer, msg p2p.Msg) (bool, error) {
+	admitted, score := p.gossip.admit(msg.Size)
+	if admitted {
+		return true, nil
+	}
+	gossipIgnoredMeter.Mark(1)
+	if score < gossipScoreDrop {
+		gossipDroppedMeter.Mark(1)
+		return false, errResp(ErrMisbehavingPeer, "order gossip score %d", score)
+	}
+	p.Log().Trace("Ignoring order gossip", "size", msg.Size, "score", score)
+	return false, nil
+}
+
+// gossipInvalidErrors are the pool errors of malformed or wrongly signed order
+// and lending transactions, penalizing the peer relaying them. Other errors
+// depend on the local pool or chain state, like nonces or the pool capacity, and
+// honest peers relay such transactions too.
+var gossipInvalidErrors = map[error]bool{
+	core.ErrInvalidSender:             true,
+	core.ErrOversizedData:             true,
+	core.ErrInvalidOrderFormat:        true,
+	core.ErrInvalidOrderContent:       true,
+	core.ErrInvalidOrderSide:          true,
+	core.ErrInvalidOrderType:          true,
+	core.ErrInvalidOrderStatus:        true,
+	core.ErrInvalidOrderUserAddress:   true,
+	core.ErrInvalidOrderQuantity:      true,
+	core.ErrInvalidOrderPrice:         true,
+	core.ErrInvalidOrderHash:          true,
+	core.ErrInvalidLendingSide:        true,
+	core.ErrInvalidLendingType:        true,
+	core.ErrInvalidLendingStatus:      true,
+	core.ErrInvalidLendingUserAddress: true,
+	core.ErrInvalidLendingQuantity:    true,
+	core.ErrInvalidLendingInterest:    true,
+	core.ErrInvalidLendingHash:        true,
+}
+
+// scoreGossip accounts the transactions of an order or lending transaction
+// message in the peer's gossip score. Transactions other peers relayed first,
+// and transactions rejected for reasons depending on the local state, are
+// neither rewarded nor penalized. An error is returned if the peer should be
+// disconnected.
+func (pm *ProtocolManager) scoreGossip(p *peer, unknown []bool, errs []error, duplicates int) error {
+	var valid, invalid int
+	for i, isNew := range unknown {
+		switch {
+		case !isNew || i >= len(errs):
+			continue
+		case errs[i] == nil:
+			valid++
+		case gossipInvalidErrors[errs[i]]:
+			invalid++
+		}
+	}
+	if score := p.gossip.record(valid, invalid, duplicates); score < gossipScoreDrop {
+		gossipDroppedMeter.Mark(1)
+		return errResp(ErrMisbehavingPeer, "order gossip score %d", score)
+	}
+	return nil
+}
+
 // BroadcastBlock will either propagate a block to a subset of it's peers, or
 // will only announce it's availability (depending what's requested).
 func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 	hash := block.Hash()
 	peers := pm.peers.PeersWithoutBlock(hash)
 
+	// Masternodes go first, the one in turn for the next block leading
+	validators, peers := pm.prioritizeValidators(peers, block.Header())
+
 	// If propagation is requested, send to a subset of the peer
 	if propagate {
 		// Calculate the TD of the block (it's not imported yet, so block.Td is not valid)
@@ -832,18 +1057,28 @@ func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 			return
 		}
 		// Send the block to a subset of our peers
+		for _, peer := range validators {
+			peer.SendNewBlock(block, td)
+		}
 		for _, peer := range peers {
 			peer.SendNewBlock(block, td)
 		}
-		log.Trace("Propagated block", "hash", hash, "recipients", len(peers), "duration", common.PrettyDuration(time.Since(block.ReceivedAt)))
+		log.Trace("Propagated block", "hash", hash, "recipients", len(validators)+len(peers), "validators", len(validators), "duration", common.PrettyDuration(time.Since(block.ReceivedAt)))
 		return
 	}
-	// Otherwise if the block is indeed in out own chain, announce it
+	// Otherwise if the block is indeed in out own chain, push it to the
+	// masternodes still missing it and announce it to the others
 	if pm.blockchain.HasBlock(hash, block.NumberU64()) {
+		if len(validators) > 0 {
+			td := pm.blockchain.GetTd(hash, block.NumberU64())
+			for _, peer := range validators {
+				peer.SendNewBlock(block, td)
+			}
+		}
 		for _, peer := range peers {
 			peer.SendNewBlockHashes([]common.Hash{hash}, []uint64{block.NumberU64()})
 		}
-		log.Trace("Announced block", "hash", hash, "recipients", len(peers), "duration", common.PrettyDuration(time.Since(block.ReceivedAt)))
+		log.Trace("Announced block", "hash", hash, "recipients", len(peers), "validators", len(validators), "duration", common.PrettyDuration(time.Since(block.ReceivedAt)))
 	}
 }
 
@@ -852,6 +1087,11 @@ func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 func (pm *ProtocolManager) BroadcastTx(hash common.Hash, tx *types.Transaction) {
 	// Broadcast transaction to a batch of peers not knowing about it
 	peers := pm.peers.PeersWithoutTx(hash)
+	if tx.IsSigningTransaction() {
+		// Masternodes need block signing transactions first
+		validators, others := pm.prioritizeValidators(peers, nil)
+		peers = append(validators, others...)
+	}
 	//FIXME include this again: peers = peers[:int(math.Sqrt(float64(len(peers))))]
 	for _, peer := range peers {
 		peer.SendTransactions(types.Transactions{tx})
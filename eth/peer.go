@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/common/mclock"
 	"github.com/69th-byte/sdexchain/core/types"
 	"github.com/69th-byte/sdexchain/p2p"
 	"github.com/69th-byte/sdexchain/rlp"
@@ -47,9 +48,11 @@ const (
 // PeerInfo represents a short summary of the Ethereum sub-protocol metadata known
 // about a connected peer.
 type PeerInfo struct {
-	Version    int      `json:"version"`    // Ethereum protocol version negotiated
-	Difficulty *big.Int `json:"difficulty"` // Total difficulty of the peer's blockchain
-	Head       string   `json:"head"`       // SHA3 hash of the peer's best owned block
+	Version     int              `json:"version"`     // Ethereum protocol version negotiated
+	Difficulty  *big.Int         `json:"difficulty"`  // Total difficulty of the peer's blockchain
+	Head        string           `json:"head"`        // SHA3 hash of the peer's best owned block
+	OrderGossip *GossipScoreInfo `json:"orderGossip"` // Score of the peer's order and lending gossip
+	Validator   *common.Address  `json:"validator"`   // Masternode the peer proved to run, if any
 }
 
 type peer struct {
@@ -62,14 +65,17 @@ type peer struct {
 	version  int         // Protocol version negotiated
 	forkDrop *time.Timer // Timed connection dropper if forks aren't validated in time
 
-	head common.Hash
-	td   *big.Int
-	lock sync.RWMutex
+	head      common.Hash
+	td        *big.Int
+	validator common.Address // Masternode the peer proved to run, zero if none
+	lock      sync.RWMutex
 
 	knownTxs        mapset.Set // Set of transaction hashes known to be known by this peer
 	knownBlocks     mapset.Set // Set of block hashes known to be known by this peer
 	knownOrderTxs   mapset.Set // Set of order transaction hashes known to be known by this peer
 	knownLendingTxs mapset.Set // Set of lending transaction hashes known to be known by this peer
+
+	gossip *gossipScore // Score of the order and lending transactions relayed by the peer
 }
 
 func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
@@ -84,6 +90,7 @@ func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
 		knownBlocks:     mapset.NewSet(),
 		knownOrderTxs:   mapset.NewSet(),
 		knownLendingTxs: mapset.NewSet(),
+		gossip:          newGossipScore(mclock.System{}),
 	}
 }
 
@@ -91,11 +98,16 @@ func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
 func (p *peer) Info() *PeerInfo {
 	hash, td := p.Head()
 
-	return &PeerInfo{
-		Version:    p.version,
-		Difficulty: td,
-		Head:       hash.Hex(),
+	info := &PeerInfo{
+		Version:     p.version,
+		Difficulty:  td,
+		Head:        hash.Hex(),
+		OrderGossip: p.gossip.info(),
+	}
+	if validator := p.Validator(); validator != (common.Address{}) {
+		info.Validator = &validator
 	}
+	return info
 }
 
 // Head retrieves a copy of the current head hash and total difficulty of the
@@ -117,6 +129,23 @@ func (p *peer) SetHead(hash common.Hash, td *big.Int) {
 	p.td.Set(td)
 }
 
+// Validator retrieves the masternode the peer proved to run, or the zero address
+// if it didn't.
+func (p *peer) Validator() common.Address {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.validator
+}
+
+// SetValidator records the masternode the peer proved to run.
+func (p *peer) SetValidator(validator common.Address) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.validator = validator
+}
+
 // MarkBlock marks a block as known for the peer, ensuring that the block will
 // never be propagated to this particular peer.
 func (p *peer) MarkBlock(hash common.Hash) {
@@ -246,6 +275,16 @@ func (p *peer) SendNodeData(data [][]byte) error {
 	}
 }
 
+// SendTomoXNodeData sends a batch of trading and lending trie nodes, corresponding
+// to the hashes requested.
+func (p *peer) SendTomoXNodeData(data [][]byte) error {
+	if p.pairRw != nil {
+		return p2p.Send(p.pairRw, TomoXNodeDataMsg, data)
+	} else {
+		return p2p.Send(p.rw, TomoXNodeDataMsg, data)
+	}
+}
+
 // SendReceiptsRLP sends a batch of transaction receipts, corresponding to the
 // ones requested from an already RLP encoded format.
 func (p *peer) SendReceiptsRLP(receipts []rlp.RawValue) error {
@@ -311,6 +350,17 @@ func (p *peer) RequestNodeData(hashes []common.Hash) error {
 	}
 }
 
+// RequestTomoXNodeData fetches a batch of trading and lending trie nodes from a
+// node's TomoX database, corresponding to the specified hashes.
+func (p *peer) RequestTomoXNodeData(hashes []common.Hash) error {
+	p.Log().Debug("Fetching batch of TomoX state data", "count", len(hashes))
+	if p.pairRw != nil {
+		return p2p.Send(p.pairRw, GetTomoXNodeDataMsg, hashes)
+	} else {
+		return p2p.Send(p.rw, GetTomoXNodeDataMsg, hashes)
+	}
+}
+
 // RequestReceipts fetches a batch of transaction receipts from a remote node.
 func (p *peer) RequestReceipts(hashes []common.Hash) error {
 	p.Log().Debug("Fetching batch of receipts", "count", len(hashes))
@@ -383,6 +433,57 @@ func (p *peer) readStatus(network uint64, status *statusData, genesis common.Has
 	return nil
 }
 
+// SendValidatorProof sends the proof that the local node runs a masternode,
+// empty if it doesn't.
+func (p *peer) SendValidatorProof(proof []byte) error {
+	return p2p.Send(p.rw, ValidatorProofMsg, &validatorProofData{Signature: proof})
+}
+
+// ValidatorHandshake exchanges the masternode proofs of the local node and the
+// remote peer, returning the one of the peer.
+func (p *peer) ValidatorHandshake(proof []byte) ([]byte, error) {
+	// Send out own proof in a new thread
+	errc := make(chan error, 2)
+	var remote validatorProofData // safe to read after two values have been received from errc
+
+	go func() {
+		errc <- p.SendValidatorProof(proof)
+	}()
+	go func() {
+		errc <- p.readValidatorProof(&remote)
+	}()
+	timeout := time.NewTimer(handshakeTimeout)
+	defer timeout.Stop()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errc:
+			if err != nil {
+				return nil, err
+			}
+		case <-timeout.C:
+			return nil, p2p.DiscReadTimeout
+		}
+	}
+	return remote.Signature, nil
+}
+
+func (p *peer) readValidatorProof(proof *validatorProofData) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Code != ValidatorProofMsg {
+		return errResp(ErrNoValidatorProofMsg, "second msg has code %x (!= %x)", msg.Code, ValidatorProofMsg)
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	if err := msg.Decode(proof); err != nil {
+		return errResp(ErrDecode, "msg %v: %v", msg, err)
+	}
+	return nil
+}
+
 // String implements fmt.Stringer.
 func (p *peer) String() string {
 	return fmt.Sprintf("Peer %s [%s]", p.id,
@@ -411,6 +512,34 @@ func (ps *peerSet) Register(p *peer) error {
 	ps.lock.Lock()
 	defer ps.lock.Unlock()
 
+	return ps.register(p)
+}
+
+// RegisterReserved injects a new peer into the working set like Register, unless
+// it is an ordinary peer and limit ordinary peers are already registered. The
+// peers are counted and registered under the same lock, so that concurrent
+// handshakes can't take more slots than there are.
+func (ps *peerSet) RegisterReserved(p *peer, reserved func(*peer) bool, limit int) error {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if _, ok := ps.peers[p.id]; !ok && !reserved(p) {
+		var ordinary int
+		for _, peer := range ps.peers {
+			if !reserved(peer) {
+				ordinary++
+			}
+		}
+		if ordinary >= limit {
+			return p2p.DiscTooManyPeers
+		}
+	}
+	return ps.register(p)
+}
+
+// register injects a new peer into the working set. The caller must hold the
+// lock.
+func (ps *peerSet) register(p *peer) error {
 	if ps.closed {
 		return errClosed
 	}
@@ -456,6 +585,18 @@ func (ps *peerSet) Len() int {
 	return len(ps.peers)
 }
 
+// Peers retrieves a list of all the registered peers.
+func (ps *peerSet) Peers() []*peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	list := make([]*peer, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		list = append(list, p)
+	}
+	return list
+}
+
 // PeersWithoutBlock retrieves a list of peers that do not have a given block in
 // their set of known hashes.
 func (ps *peerSet) PeersWithoutBlock(hash common.Hash) []*peer {
@@ -32,16 +32,18 @@ import (
 const (
 	eth62 = 62
 	eth63 = 63
+	eth64 = 64
+	eth65 = 65
 )
 
 // Official short name of the protocol used during capability negotiation.
 var ProtocolName = "eth"
 
 // Supported versions of the eth protocol (first is primary).
-var ProtocolVersions = []uint{eth63, eth62}
+var ProtocolVersions = []uint{eth65, eth64, eth63, eth62}
 
 // Number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{17, 8}
+var ProtocolLengths = []uint64{20, 19, 17, 8}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
@@ -63,6 +65,11 @@ const (
 	NodeDataMsg    = 0x0e
 	GetReceiptsMsg = 0x0f
 	ReceiptsMsg    = 0x10
+	// Protocol messages belonging to eth/64
+	GetTomoXNodeDataMsg = 0x11
+	TomoXNodeDataMsg    = 0x12
+	// Protocol messages belonging to eth/65
+	ValidatorProofMsg = 0x13
 )
 
 type errCode int
@@ -77,6 +84,8 @@ const (
 	ErrNoStatusMsg
 	ErrExtraStatusMsg
 	ErrSuspendedPeer
+	ErrMisbehavingPeer
+	ErrNoValidatorProofMsg
 )
 
 func (e errCode) String() string {
@@ -94,6 +103,8 @@ var errorToString = map[int]string{
 	ErrNoStatusMsg:             "No status message",
 	ErrExtraStatusMsg:          "Extra status message",
 	ErrSuspendedPeer:           "Suspended peer",
+	ErrMisbehavingPeer:         "Misbehaving peer",
+	ErrNoValidatorProofMsg:     "No validator proof message",
 }
 
 type txPool interface {
@@ -144,6 +155,12 @@ type statusData struct {
 	GenesisBlock    common.Hash
 }
 
+// validatorProofData is the network packet proving that the sender runs a
+// masternode. The signature is empty if it doesn't.
+type validatorProofData struct {
+	Signature []byte // Signature of the sender's node ID by the masternode key
+}
+
 // newBlockHashesData is the network packet for the block announcements.
 type newBlockHashesData []struct {
 	Hash   common.Hash // Hash of one particular block being announced
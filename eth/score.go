@@ -0,0 +1,138 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/69th-byte/sdexchain/common/mclock"
+)
+
+// Order gossip scoring. Every peer starts with a neutral score, which is raised
+// by the order and lending transactions it relays that the pools accept, and
+// lowered by malformed, duplicate and oversized ones. Peers falling below the
+// throttle threshold have their order gossip ignored, which costs them further
+// points, until they drop below the disconnect threshold. Negative scores
+// recover over time, so a peer that stops misbehaving is eventually served again.
+const (
+	gossipScoreValid     = 1    // Reward for a new transaction accepted by the pool
+	gossipScoreDuplicate = -1   // Penalty for a transaction the peer already sent
+	gossipScoreInvalid   = -10  // Penalty for a transaction rejected as malformed by the pool
+	gossipScoreOversized = -50  // Penalty for a message above maxGossipMsgSize
+	gossipScoreIgnored   = -5   // Penalty for a message sent while throttled
+	gossipScoreMax       = 100  // Upper bound of the score
+	gossipScoreThrottle  = -100 // Below this score the peer's order gossip is ignored
+	gossipScoreDrop      = -300 // Below this score the peer is disconnected
+	gossipScoreRecovery  = 2    // Points a negative score recovers every second
+
+	// maxGossipMsgSize is the maximum size of an order or lending transaction
+	// message. A batch of honest relays stays well below it.
+	maxGossipMsgSize = 512 * 1024
+)
+
+// GossipScoreInfo is the order gossip score of a peer as reported by admin_peers.
+type GossipScoreInfo struct {
+	Score     int64  `json:"score"`     // Current score of the peer
+	Throttled bool   `json:"throttled"` // Whether the peer's order gossip is ignored
+	Valid     uint64 `json:"valid"`     // Transactions accepted by the pools
+	Invalid   uint64 `json:"invalid"`   // Transactions rejected as malformed by the pools
+	Duplicate uint64 `json:"duplicate"` // Transactions the peer sent more than once
+	Oversized uint64 `json:"oversized"` // Messages above the size limit
+	Ignored   uint64 `json:"ignored"`   // Messages ignored while throttled
+}
+
+// gossipScore tracks the order gossip score of a peer.
+type gossipScore struct {
+	clock   mclock.Clock
+	lock    sync.Mutex
+	score   int64
+	updated mclock.AbsTime // Time the recovery was last applied
+	stats   GossipScoreInfo
+}
+
+func newGossipScore(clock mclock.Clock) *gossipScore {
+	return &gossipScore{clock: clock, updated: clock.Now()}
+}
+
+// recover raises a negative score by the recovery accumulated since the last
+// update. The caller must hold the lock.
+func (s *gossipScore) recover() {
+	now := s.clock.Now()
+	if s.score >= 0 {
+		s.updated = now
+		return
+	}
+	seconds := int64(now.Sub(s.updated) / time.Second)
+	s.score += seconds * gossipScoreRecovery
+	if s.score > 0 {
+		s.score = 0
+	}
+	s.updated = s.updated.Add(time.Duration(seconds) * time.Second)
+}
+
+// adjust applies the recovery and the given delta, returning the new score.
+// The caller must hold the lock.
+func (s *gossipScore) adjust(delta int64) int64 {
+	s.recover()
+	s.score += delta
+	if s.score > gossipScoreMax {
+		s.score = gossipScoreMax
+	}
+	return s.score
+}
+
+// admit decides whether a gossip message of the given size is processed. It
+// penalizes oversized messages and messages received while throttled, and
+// returns the resulting score.
+func (s *gossipScore) admit(size uint32) (bool, int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if size > maxGossipMsgSize {
+		s.stats.Oversized++
+		return false, s.adjust(gossipScoreOversized)
+	}
+	if score := s.adjust(0); score < gossipScoreThrottle {
+		s.stats.Ignored++
+		return false, s.adjust(gossipScoreIgnored)
+	}
+	return true, s.score
+}
+
+// record accounts the outcome of a processed gossip message and returns the
+// resulting score.
+func (s *gossipScore) record(valid, invalid, duplicate int) int64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.stats.Valid += uint64(valid)
+	s.stats.Invalid += uint64(invalid)
+	s.stats.Duplicate += uint64(duplicate)
+	return s.adjust(int64(valid*gossipScoreValid + invalid*gossipScoreInvalid + duplicate*gossipScoreDuplicate))
+}
+
+// info returns the current score and statistics.
+func (s *gossipScore) info() *GossipScoreInfo {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	info := s.stats
+	info.Score = s.adjust(0)
+	info.Throttled = info.Score < gossipScoreThrottle
+	return &info
+}
@@ -0,0 +1,358 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/common/mclock"
+	"github.com/69th-byte/sdexchain/core"
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/eth/downloader"
+	"github.com/69th-byte/sdexchain/event"
+	"github.com/69th-byte/sdexchain/node"
+	"github.com/69th-byte/sdexchain/p2p"
+	"github.com/69th-byte/sdexchain/p2p/discover"
+	"github.com/69th-byte/sdexchain/p2p/simulations"
+	"github.com/69th-byte/sdexchain/p2p/simulations/adapters"
+	"github.com/69th-byte/sdexchain/rpc"
+)
+
+// Tests that a gossip score throttles a peer sending invalid transactions,
+// disconnects it if it keeps sending and recovers once it stops.
+func TestGossipScore(t *testing.T) {
+	clock := new(mclock.Simulated)
+	score := newGossipScore(clock)
+
+	// Valid transactions raise the score, duplicates and invalid ones lower it
+	if s := score.record(3, 0, 0); s != 3 {
+		t.Fatalf("score mismatch: have %d, want %d", s, 3)
+	}
+	if s := score.record(0, 0, 2); s != 1 {
+		t.Fatalf("score mismatch: have %d, want %d", s, 1)
+	}
+	// Oversized messages are never admitted
+	if admitted, s := score.admit(maxGossipMsgSize + 1); admitted || s != 1+gossipScoreOversized {
+		t.Fatalf("oversized message: admitted %v, score %d", admitted, s)
+	}
+	// Invalid transactions throttle the peer
+	for i := 0; i < 6; i++ {
+		if admitted, _ := score.admit(100); !admitted {
+			t.Fatalf("message %d not admitted before throttling", i)
+		}
+		score.record(0, 1, 0)
+	}
+	if info := score.info(); !info.Throttled || info.Score != -109 {
+		t.Fatalf("throttle mismatch: have %v/%d, want %v/%d", info.Throttled, info.Score, true, -109)
+	}
+	// Throttled messages are ignored and penalized until the peer is dropped
+	var ignored int
+	for {
+		admitted, s := score.admit(100)
+		if admitted {
+			t.Fatalf("message admitted while throttled")
+		}
+		ignored++
+		if s < gossipScoreDrop {
+			break
+		}
+	}
+	if ignored != 39 {
+		t.Fatalf("ignored messages mismatch: have %d, want %d", ignored, 39)
+	}
+	// Negative scores recover over time, but never above zero
+	clock.Run(30 * time.Second)
+	if info := score.info(); info.Score != -304+30*gossipScoreRecovery {
+		t.Fatalf("recovered score mismatch: have %d, want %d", info.Score, -304+30*gossipScoreRecovery)
+	}
+	clock.Run(time.Hour)
+	info := score.info()
+	if info.Score != 0 || info.Throttled {
+		t.Fatalf("fully recovered score mismatch: have %d/%v, want 0/false", info.Score, info.Throttled)
+	}
+	want := GossipScoreInfo{Valid: 3, Invalid: 6, Duplicate: 2, Oversized: 1, Ignored: 39}
+	if *info != want {
+		t.Fatalf("statistics mismatch: have %+v, want %+v", *info, want)
+	}
+}
+
+// testOrderPool is a fake order pool rejecting orders without a quantity, and
+// orders whose nonce was already used by their user.
+type testOrderPool struct {
+	txFeed event.Feed
+	pool   []*types.OrderTransaction // Collection of all added transactions
+	nonces map[common.Address]uint64 // Next nonce of each user
+	lock   sync.RWMutex              // Protects the transaction pool
+}
+
+func (p *testOrderPool) AddRemotes(txs []*types.OrderTransaction) []error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.nonces == nil {
+		p.nonces = make(map[common.Address]uint64)
+	}
+	errs := make([]error, len(txs))
+	for i, tx := range txs {
+		p.pool = append(p.pool, tx)
+		switch {
+		case tx.Quantity().Sign() == 0:
+			errs[i] = core.ErrInvalidOrderQuantity
+		case tx.Nonce() < p.nonces[tx.UserAddress()]:
+			errs[i] = core.ErrNonceTooLow
+		default:
+			p.nonces[tx.UserAddress()] = tx.Nonce() + 1
+		}
+	}
+	return errs
+}
+
+func (p *testOrderPool) Pending() (map[common.Address]types.OrderTransactions, error) {
+	return nil, nil
+}
+
+func (p *testOrderPool) SubscribeTxPreEvent(ch chan<- core.OrderTxPreEvent) event.Subscription {
+	return p.txFeed.Subscribe(ch)
+}
+
+func (p *testOrderPool) added(user common.Address) (valid, invalid int) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	for _, tx := range p.pool {
+		if tx.UserAddress() != user {
+			continue
+		}
+		if tx.Quantity().Sign() == 0 {
+			invalid++
+		} else {
+			valid++
+		}
+	}
+	return valid, invalid
+}
+
+// testEthService runs a test protocol manager as a simulation node service.
+type testEthService struct {
+	pm *ProtocolManager
+}
+
+func (s *testEthService) Protocols() []p2p.Protocol   { return s.pm.SubProtocols }
+func (s *testEthService) APIs() []rpc.API             { return nil }
+func (s *testEthService) Start(srv *p2p.Server) error { return nil }
+func (s *testEthService) SaveData()                   {}
+func (s *testEthService) Stop() error                 { s.pm.Stop(); return nil }
+
+// testGossiper is a simulation node service that completes the eth handshake
+// and then relays a scripted sequence of order transaction messages.
+type testGossiper struct {
+	msgs [][]*types.OrderTransaction
+}
+
+func (g *testGossiper) Protocols() []p2p.Protocol {
+	return []p2p.Protocol{{Name: ProtocolName, Version: eth63, Length: ProtocolLengths[2], Run: g.run}}
+}
+func (g *testGossiper) APIs() []rpc.API             { return nil }
+func (g *testGossiper) Start(srv *p2p.Server) error { return nil }
+func (g *testGossiper) SaveData()                   {}
+func (g *testGossiper) Stop() error                 { return nil }
+
+func (g *testGossiper) run(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+	// Echo the status of the remote node to pass its handshake
+	msg, err := rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	var status statusData
+	if err := msg.Decode(&status); err != nil {
+		return err
+	}
+	if err := p2p.Send(rw, StatusMsg, &status); err != nil {
+		return err
+	}
+	// Discard anything else the node sends and relay the scripted gossip
+	errc := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := rw.ReadMsg()
+			if err != nil {
+				errc <- err
+				return
+			}
+			msg.Discard()
+		}
+	}()
+	for _, txs := range g.msgs {
+		if err := p2p.Send(rw, OrderTxMsg, txs); err != nil {
+			return err
+		}
+	}
+	return <-errc
+}
+
+// newTestOrder creates an order transaction of the given user, which the test
+// order pool rejects if the quantity is zero.
+func newTestOrder(user common.Address, nonce uint64, quantity int64) *types.OrderTransaction {
+	return types.NewOrderTransaction(nonce, big.NewInt(quantity), big.NewInt(1), common.Address{}, user, common.Address{}, common.Address{}, types.OrderStatusNew, "BUY", types.OrderTypeLo, common.Hash{}, 0)
+}
+
+// Tests that the order gossip of peers is scored in a simulated network: an
+// honest peer stays connected with its score visible in the peer info, and so
+// does a peer relaying orders of already used nonces, while a peer spamming
+// invalid orders is throttled and then disconnected.
+func TestOrderGossipScoring(t *testing.T) {
+	var (
+		clock   = new(mclock.Simulated) // Never advanced, so scores don't recover
+		pool    = new(testOrderPool)
+		honest  = common.HexToAddress("0x01")
+		spammer = common.HexToAddress("0x02")
+		stale   = common.HexToAddress("0x03")
+	)
+	// The honest peer relays valid orders, one of them twice
+	var honestMsgs [][]*types.OrderTransaction
+	for i := uint64(0); i < 5; i++ {
+		honestMsgs = append(honestMsgs, []*types.OrderTransaction{newTestOrder(honest, i, 1)})
+	}
+	honestMsgs = append(honestMsgs, honestMsgs[0])
+
+	// The spammer relays invalid orders until it's disconnected
+	var spamMsgs [][]*types.OrderTransaction
+	for i := uint64(0); i < 100; i++ {
+		spamMsgs = append(spamMsgs, []*types.OrderTransaction{newTestOrder(spammer, i, 0)})
+	}
+	// The stale peer relays distinct orders all using the same nonce, only the
+	// first one is accepted but the others aren't malformed
+	var staleMsgs [][]*types.OrderTransaction
+	for i := int64(1); i <= 100; i++ {
+		staleMsgs = append(staleMsgs, []*types.OrderTransaction{newTestOrder(stale, 0, i)})
+	}
+	adapter := adapters.NewSimAdapter(adapters.Services{
+		"eth": func(ctx *adapters.ServiceContext) (node.Service, error) {
+			pm, _, err := newTestProtocolManager(downloader.FullSync, 0, nil, nil)
+			if err != nil {
+				return nil, err
+			}
+			pm.clock = clock
+			pm.orderpool = pool
+			pm.acceptTxs = 1
+			return &testEthService{pm: pm}, nil
+		},
+		"honest": func(ctx *adapters.ServiceContext) (node.Service, error) {
+			return &testGossiper{msgs: honestMsgs}, nil
+		},
+		"spammer": func(ctx *adapters.ServiceContext) (node.Service, error) {
+			return &testGossiper{msgs: spamMsgs}, nil
+		},
+		"stale": func(ctx *adapters.ServiceContext) (node.Service, error) {
+			return &testGossiper{msgs: staleMsgs}, nil
+		},
+	})
+	network := simulations.NewNetwork(adapter, &simulations.NetworkConfig{DefaultService: "eth"})
+	defer network.Shutdown()
+
+	ids := make(map[string]discover.NodeID)
+	for _, service := range []string{"eth", "honest", "spammer", "stale"} {
+		conf := adapters.RandomNodeConfig()
+		conf.Name = service
+		conf.Services = []string{service}
+		node, err := network.NewNodeWithConfig(conf)
+		if err != nil {
+			t.Fatalf("failed to create %s node: %v", service, err)
+		}
+		if err := network.Start(node.ID()); err != nil {
+			t.Fatalf("failed to start %s node: %v", service, err)
+		}
+		ids[service] = node.ID()
+	}
+	server := network.GetNode(ids["eth"]).Node.(*adapters.SimNode)
+
+	events := make(chan *p2p.PeerEvent, 16)
+	sub := server.SubscribeEvents(events)
+	defer sub.Unsubscribe()
+
+	// The simulation adapter accepts a single dial per node, so the node under
+	// test dials the gossipers
+	if err := network.Connect(ids["eth"], ids["honest"]); err != nil {
+		t.Fatalf("failed to connect honest peer: %v", err)
+	}
+	if err := network.Connect(ids["eth"], ids["spammer"]); err != nil {
+		t.Fatalf("failed to connect spammer: %v", err)
+	}
+	if err := network.Connect(ids["eth"], ids["stale"]); err != nil {
+		t.Fatalf("failed to connect stale peer: %v", err)
+	}
+	// Wait for the spammer to be disconnected for misbehaving
+	timeout := time.After(10 * time.Second)
+	for dropped := false; !dropped; {
+		select {
+		case ev := <-events:
+			if ev.Type != p2p.PeerEventTypeDrop {
+				continue
+			}
+			if ev.Peer != ids["spammer"] {
+				t.Fatalf("unexpected peer dropped: %v (%s)", ev.Peer, ev.Error)
+			}
+			dropped = true
+		case <-timeout:
+			t.Fatalf("spammer not disconnected")
+		}
+	}
+	// Stop listening, peer events block the message handling of the node
+	sub.Unsubscribe()
+
+	// Only the invalid orders relayed before throttling should reach the pool
+	if valid, invalid := pool.added(spammer); valid != 0 || invalid != 11 {
+		t.Errorf("spammer orders mismatch: have %d/%d, want %d/%d", valid, invalid, 0, 11)
+	}
+	// Wait for the honest and stale gossip to be processed and check the
+	// reported scores
+	for valid, _ := pool.added(stale); valid < len(staleMsgs); valid, _ = pool.added(stale) {
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-timeout:
+			t.Fatalf("stale orders not processed: have %d, want %d", valid, len(staleMsgs))
+		}
+	}
+	for service, want := range map[string]GossipScoreInfo{
+		"honest": {Score: 4, Valid: 5, Duplicate: 1},
+		"stale":  {Score: 1, Valid: 1},
+	} {
+		for {
+			var info *GossipScoreInfo
+			for _, peer := range server.Server().PeersInfo() {
+				if peer.ID == ids[service].String() {
+					info = peer.Protocols[ProtocolName].(*PeerInfo).OrderGossip
+				}
+			}
+			if info == nil {
+				t.Fatalf("%s peer disconnected", service)
+			}
+			if *info == want {
+				break
+			}
+			select {
+			case <-time.After(10 * time.Millisecond):
+			case <-timeout:
+				t.Fatalf("%s peer score mismatch: have %+v, want %+v", service, *info, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/common/hexutil"
+	"github.com/69th-byte/sdexchain/core/vm"
+	"github.com/69th-byte/sdexchain/tomox/tradingstate"
+)
+
+// MatchingTracerName is the name the built-in matching engine tracer is
+// selected by in the trace config.
+const MatchingTracerName = "matchingTracer"
+
+// Operations of the matching steps.
+const (
+	MatchingOpOrder   = "ORDER"
+	MatchingOpLevel   = "LEVEL"
+	MatchingOpTrade   = "TRADE"
+	MatchingOpBalance = "BALANCE"
+	MatchingOpFee     = "FEE"
+	MatchingOpReject  = "REJECT"
+	MatchingOpEnd     = "END"
+)
+
+// MatchingStep is a single step taken by the trading or lending matching engine.
+// Only the fields relevant to the operation are set:
+//   - ORDER: engine, orderBook, hash, account (user), side, type, price, quantity
+//   - LEVEL: side, price, quantity (still to trade)
+//   - TRADE: hash (maker order), account (maker), price, quantity
+//   - BALANCE: account, token, amount (negative if debited)
+//   - FEE: account (payer), receiver, token, amount
+//   - REJECT: hash, reason
+//   - END: error
+type MatchingStep struct {
+	Op        string          `json:"op"`
+	Engine    string          `json:"engine,omitempty"`
+	OrderBook *common.Hash    `json:"orderBook,omitempty"`
+	Hash      *common.Hash    `json:"hash,omitempty"`
+	Account   *common.Address `json:"account,omitempty"`
+	Receiver  *common.Address `json:"receiver,omitempty"`
+	Token     *common.Address `json:"token,omitempty"`
+	Side      string          `json:"side,omitempty"`
+	Type      string          `json:"type,omitempty"`
+	Price     *hexutil.Big    `json:"price,omitempty"`
+	Quantity  *hexutil.Big    `json:"quantity,omitempty"`
+	Amount    *hexutil.Big    `json:"amount,omitempty"`
+	Reason    string          `json:"reason,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// matchingHook adapts a step callback to the tradingstate.MatchingTracer interface.
+type matchingHook func(step *MatchingStep)
+
+func toBig(n *big.Int) *hexutil.Big {
+	if n == nil {
+		return nil
+	}
+	return (*hexutil.Big)(new(big.Int).Set(n))
+}
+
+func (hook matchingHook) CaptureOrderStart(engine string, orderBook, hash common.Hash, user common.Address, side, orderType string, price, quantity *big.Int) {
+	hook(&MatchingStep{Op: MatchingOpOrder, Engine: engine, OrderBook: &orderBook, Hash: &hash, Account: &user, Side: side, Type: orderType, Price: toBig(price), Quantity: toBig(quantity)})
+}
+
+func (hook matchingHook) CaptureLevel(side string, price, remaining *big.Int) {
+	hook(&MatchingStep{Op: MatchingOpLevel, Side: side, Price: toBig(price), Quantity: toBig(remaining)})
+}
+
+func (hook matchingHook) CaptureTrade(makerHash common.Hash, maker common.Address, price, quantity *big.Int) {
+	hook(&MatchingStep{Op: MatchingOpTrade, Hash: &makerHash, Account: &maker, Price: toBig(price), Quantity: toBig(quantity)})
+}
+
+func (hook matchingHook) CaptureBalance(account, token common.Address, delta *big.Int) {
+	hook(&MatchingStep{Op: MatchingOpBalance, Account: &account, Token: &token, Amount: toBig(delta)})
+}
+
+func (hook matchingHook) CaptureFee(payer, receiver, token common.Address, amount *big.Int) {
+	hook(&MatchingStep{Op: MatchingOpFee, Account: &payer, Receiver: &receiver, Token: &token, Amount: toBig(amount)})
+}
+
+func (hook matchingHook) CaptureReject(hash common.Hash, reason string) {
+	hook(&MatchingStep{Op: MatchingOpReject, Hash: &hash, Reason: reason})
+}
+
+func (hook matchingHook) CaptureOrderEnd(err error) {
+	step := &MatchingStep{Op: MatchingOpEnd}
+	if err != nil {
+		step.Error = err.Error()
+	}
+	hook(step)
+}
+
+// MatchingLogger is a built-in tracer which ignores the EVM execution and
+// collects the steps taken by the matching engines while applying the orders
+// of a trading or lending transaction.
+type MatchingLogger struct {
+	tradingstate.MatchingTracer
+
+	steps []*MatchingStep
+}
+
+// NewMatchingLogger returns a new matching engine tracer.
+func NewMatchingLogger() *MatchingLogger {
+	logger := &MatchingLogger{steps: []*MatchingStep{}}
+	logger.MatchingTracer = matchingHook(func(step *MatchingStep) {
+		logger.steps = append(logger.steps, step)
+	})
+	return logger
+}
+
+// CaptureStart implements the vm.Tracer interface, it is a no-op.
+func (l *MatchingLogger) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+// CaptureState implements the vm.Tracer interface, it is a no-op.
+func (l *MatchingLogger) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureFault implements the vm.Tracer interface, it is a no-op.
+func (l *MatchingLogger) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements the vm.Tracer interface, it is a no-op.
+func (l *MatchingLogger) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error {
+	return nil
+}
+
+// Steps returns the matching steps captured so far.
+func (l *MatchingLogger) Steps() []*MatchingStep {
+	return l.steps
+}
+
+// GetResult returns the captured matching steps as JSON.
+func (l *MatchingLogger) GetResult() (json.RawMessage, error) {
+	return json.Marshal(l.steps)
+}
@@ -0,0 +1,107 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/tomox/tradingstate"
+)
+
+// feedMatching reports a limit order matching a single maker order.
+func feedMatching(tracer tradingstate.MatchingTracer) {
+	var (
+		taker = common.HexToAddress("0x01")
+		maker = common.HexToAddress("0x02")
+		base  = common.HexToAddress("0x03")
+		quote = common.HexToAddress(common.TomoNativeAddress)
+	)
+	tracer.CaptureOrderStart(tradingstate.TradingEngine, common.HexToHash("0xb00c"), common.HexToHash("0x0a"), taker, tradingstate.Bid, tradingstate.Limit, big.NewInt(100), big.NewInt(5))
+	tracer.CaptureLevel(tradingstate.Ask, big.NewInt(90), big.NewInt(5))
+	tracer.CaptureTrade(common.HexToHash("0x0b"), maker, big.NewInt(90), big.NewInt(5))
+	tracer.CaptureBalance(taker, base, big.NewInt(5))
+	tracer.CaptureBalance(taker, quote, big.NewInt(-450))
+	tracer.CaptureFee(taker, common.HexToAddress("0x04"), quote, big.NewInt(1))
+	tracer.CaptureReject(common.HexToHash("0x0c"), tradingstate.RejectReasonInsufficientBalance)
+	tracer.CaptureOrderEnd(errors.New("boom"))
+}
+
+func TestMatchingLogger(t *testing.T) {
+	logger := NewMatchingLogger()
+	feedMatching(logger)
+
+	steps := logger.Steps()
+	want := []string{MatchingOpOrder, MatchingOpLevel, MatchingOpTrade, MatchingOpBalance, MatchingOpBalance, MatchingOpFee, MatchingOpReject, MatchingOpEnd}
+	if len(steps) != len(want) {
+		t.Fatalf("step count mismatch: have %d, want %d", len(steps), len(want))
+	}
+	for i, step := range steps {
+		if step.Op != want[i] {
+			t.Errorf("step %d: op mismatch: have %s, want %s", i, step.Op, want[i])
+		}
+	}
+	if steps[0].Engine != tradingstate.TradingEngine || steps[0].Price.ToInt().Int64() != 100 {
+		t.Errorf("order step mismatch: %+v", steps[0])
+	}
+	if steps[4].Amount.ToInt().Int64() != -450 {
+		t.Errorf("balance delta mismatch: have %v, want -450", steps[4].Amount.ToInt())
+	}
+	if steps[7].Error != "boom" {
+		t.Errorf("end error mismatch: have %q, want %q", steps[7].Error, "boom")
+	}
+	if _, err := logger.GetResult(); err != nil {
+		t.Fatalf("failed to encode result: %v", err)
+	}
+}
+
+func TestMatchingJSTracer(t *testing.T) {
+	tracer, err := New(`{ops: [], step: function() {}, fault: function() {}, matching: function(step) { this.ops.push(step.op + (step.reason ? ":" + step.reason : "")); }, result: function() { return this.ops.join(","); }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tracer.TracesMatching() {
+		t.Fatal("tracer with a matching function not detected")
+	}
+	feedMatching(tracer)
+
+	ret, err := runTrace(tracer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `"ORDER,LEVEL,TRADE,BALANCE,BALANCE,FEE,REJECT:INSUFFICIENT_BALANCE,END"`
+	if string(ret) != want {
+		t.Errorf("result mismatch: have %s, want %s", ret, want)
+	}
+}
+
+func TestMatchingJSTracerOptional(t *testing.T) {
+	tracer, err := New("{step: function() {}, fault: function() {}, result: function() { return 0; }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tracer.TracesMatching() {
+		t.Fatal("tracer without a matching function detected as matching tracer")
+	}
+	// Steps must be ignored silently
+	feedMatching(tracer)
+	if _, err := runTrace(tracer); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -30,6 +30,8 @@ import (
 	"github.com/69th-byte/sdexchain/core/vm"
 	"github.com/69th-byte/sdexchain/crypto"
 	"github.com/69th-byte/sdexchain/log"
+	"github.com/69th-byte/sdexchain/params"
+	"github.com/69th-byte/sdexchain/tomox/tradingstate"
 	duktape "gopkg.in/olebedev/go-duktape.v3"
 )
 
@@ -286,7 +288,10 @@ func (cw *contractWrapper) pushObject(vm *duktape.Context) {
 // Tracer provides an implementation of Tracer that evaluates a Javascript
 // function for each VM execution step.
 type Tracer struct {
-	inited bool // Flag whether the context was already inited from the EVM
+	tradingstate.MatchingTracer // Hook feeding the matching engine steps to the 'matching' function
+
+	inited        bool // Flag whether the context was already inited from the EVM
+	traceMatching bool // Flag whether the tracer exposes a 'matching' function
 
 	vm *duktape.Context // Javascript VM instance
 
@@ -309,13 +314,17 @@ type Tracer struct {
 	ctx map[string]interface{} // Transaction context gathered throughout execution
 	err error                  // Error, if one has occurred
 
+	activePrecompiles map[common.Address]vm.PrecompiledContract // Pre-compiled contracts of the traced block
+
 	interrupt uint32 // Atomic flag to signal execution interruption
 	reason    error  // Textual reason for the interruption
 }
 
 // New instantiates a new tracer instance. code specifies a Javascript snippet,
 // which must evaluate to an expression returning an object with 'step', 'fault'
-// and 'result' functions.
+// and 'result' functions. An optional 'matching' function receives the steps of
+// the matching engines for trading and lending transactions, see MatchingStep.
+// Its big numbers are hex strings.
 func New(code string) (*Tracer, error) {
 	// Resolve any tracers by name and assemble the tracer object
 	if tracer, ok := tracer(code); ok {
@@ -334,6 +343,8 @@ func New(code string) (*Tracer, error) {
 		costValue:       new(uint),
 		depthValue:      new(uint),
 		refundValue:     new(uint),
+
+		activePrecompiles: vm.PrecompiledContractsTomoXPriceOracle,
 	}
 	// Set up builtins for this environment
 	tracer.vm.PushGlobalGoFunction("toHex", func(ctx *duktape.Context) int {
@@ -399,7 +410,7 @@ func New(code string) (*Tracer, error) {
 		return 1
 	})
 	tracer.vm.PushGlobalGoFunction("isPrecompiled", func(ctx *duktape.Context) int {
-		_, ok := vm.PrecompiledContractsIstanbul[common.BytesToAddress(popSlice(ctx))]
+		_, ok := tracer.activePrecompiles[common.BytesToAddress(popSlice(ctx))]
 		ctx.PushBoolean(ok)
 		return 1
 	})
@@ -442,6 +453,12 @@ func New(code string) (*Tracer, error) {
 	}
 	tracer.vm.Pop()
 
+	// The matching hook is optional, it is only called for trading and lending
+	// transactions
+	tracer.traceMatching = tracer.vm.GetPropString(tracer.tracerObject, "matching")
+	tracer.vm.Pop()
+	tracer.MatchingTracer = matchingHook(tracer.captureMatching)
+
 	// Tracer is valid, inject the big int library to access large numbers
 	tracer.vm.EvalString(bigIntegerJS)
 	tracer.vm.PutGlobalString("bigInt")
@@ -525,6 +542,12 @@ func wrapError(context string, err error) error {
 	return fmt.Errorf("%v    in server-side tracer function '%v'", err, context)
 }
 
+// SetChainRules selects the pre-compiled contracts reported by isPrecompiled by
+// the fork rules of the traced block. Without it the latest set is reported.
+func (jst *Tracer) SetChainRules(rules params.Rules) {
+	jst.activePrecompiles = vm.ActivePrecompiles(rules)
+}
+
 // CaptureStart implements the Tracer interface to initialize the tracing operation.
 func (jst *Tracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
 	jst.ctx["type"] = "CALL"
@@ -606,6 +629,36 @@ func (jst *Tracer) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, er
 	return nil
 }
 
+// TracesMatching reports whether the tracer exposes a 'matching' function.
+func (jst *Tracer) TracesMatching() bool {
+	return jst.traceMatching
+}
+
+// captureMatching feeds a single matching engine step, encoded as a plain
+// object, to the Javascript 'matching' function.
+func (jst *Tracer) captureMatching(step *MatchingStep) {
+	if jst.err != nil || !jst.traceMatching {
+		return
+	}
+	// If tracing was interrupted, set the error and stop
+	if atomic.LoadUint32(&jst.interrupt) > 0 {
+		jst.err = jst.reason
+		return
+	}
+	blob, err := json.Marshal(step)
+	if err != nil {
+		jst.err = wrapError("matching", err)
+		return
+	}
+	jst.vm.PushString(string(blob))
+	jst.vm.JsonDecode(-1)
+	jst.vm.PutPropString(jst.stateObject, "matchingStep")
+
+	if _, err := jst.call("matching", "matchingStep"); err != nil {
+		jst.err = wrapError("matching", err)
+	}
+}
+
 // GetResult calls the Javascript 'result' function and returns its value, or any accumulated error
 func (jst *Tracer) GetResult() (json.RawMessage, error) {
 	// Transform the context into a JavaScript object and inject into the state
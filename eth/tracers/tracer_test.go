@@ -177,3 +177,26 @@ func TestHaltBetweenSteps(t *testing.T) {
 		t.Errorf("Expected timeout error, got %v", err)
 	}
 }
+
+// Tests that isPrecompiled reports the pre-compiled contracts of the fork rules
+// of the traced block.
+func TestIsPrecompiled(t *testing.T) {
+	code := "{step: function() {}, fault: function() {}, result: function() { return isPrecompiled(toAddress('0x000000000000000000000000000000000000002b')); }}"
+
+	tracer, err := New(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer.SetChainRules(params.Rules{IsByzantium: true, IsIstanbul: true})
+	if res, err := runTrace(tracer); err != nil || string(res) != "false" {
+		t.Fatalf("pre-fork precompile mismatch: have %s (%v), want false", res, err)
+	}
+	tracer, err = New(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer.SetChainRules(params.Rules{IsByzantium: true, IsIstanbul: true, IsTIPTomoXPriceOracle: true})
+	if res, err := runTrace(tracer); err != nil || string(res) != "true" {
+		t.Fatalf("post-fork precompile mismatch: have %s (%v), want true", res, err)
+	}
+}
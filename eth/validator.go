@@ -0,0 +1,210 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"sync"
+
+	"github.com/69th-byte/sdexchain/accounts"
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/consensus"
+	"github.com/69th-byte/sdexchain/consensus/clique"
+	"github.com/69th-byte/sdexchain/consensus/posv"
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/log"
+	"github.com/69th-byte/sdexchain/p2p/discover"
+)
+
+// Masternode-priority peering. A node run by a masternode proves it to its eth/65
+// peers by signing their view of its node ID with the masternode key. Peers that
+// are masternodes of the current PoSV snapshot may use the peer slots reserved
+// for them, and are the first to receive new blocks and block signing
+// transactions, the masternode in turn for the next block leading, so that it
+// has the parent block in time.
+
+// validatorProofPrefix is hashed together with the node ID signed by the
+// masternode, so that the proof can't be mistaken for any other signature.
+var validatorProofPrefix = []byte("tomochain validator peer")
+
+// validatorProofHash returns the hash a masternode signs to prove that it runs
+// the node with the given ID.
+func validatorProofHash(id discover.NodeID) []byte {
+	return crypto.Keccak256(validatorProofPrefix, id[:])
+}
+
+// masternodeEngine is the part of the PoSV consensus engine needed to find the
+// masternodes among the peers.
+type masternodeEngine interface {
+	GetSnapshot(chain consensus.ChainReader, header *types.Header) (*posv.Snapshot, error)
+	GetMasternodes(chain consensus.ChainReader, header *types.Header) []common.Address
+}
+
+// validatorKey is the masternode key the local node proves to run with.
+type validatorKey struct {
+	address common.Address
+	signFn  clique.SignerFn
+	proof   []byte // Cached proof of the local node
+	lock    sync.Mutex
+}
+
+// SetValidator sets the masternode key the local node proves to run with, and
+// sends the proof to the connected peers.
+func (pm *ProtocolManager) SetValidator(address common.Address, signFn clique.SignerFn) {
+	pm.validator.lock.Lock()
+	pm.validator.address, pm.validator.signFn, pm.validator.proof = address, signFn, nil
+	pm.validator.lock.Unlock()
+
+	proof := pm.validatorProof()
+	if proof == nil {
+		return
+	}
+	for _, p := range pm.peers.Peers() {
+		if p.version >= eth65 {
+			p.SendValidatorProof(proof)
+		}
+	}
+}
+
+// validatorProof returns the proof that the local node runs a masternode, or
+// nil if it isn't configured to.
+func (pm *ProtocolManager) validatorProof() []byte {
+	pm.validator.lock.Lock()
+	defer pm.validator.lock.Unlock()
+
+	if pm.validator.proof != nil || pm.validator.signFn == nil || pm.self == (discover.NodeID{}) {
+		return pm.validator.proof
+	}
+	proof, err := pm.validator.signFn(accounts.Account{Address: pm.validator.address}, validatorProofHash(pm.self))
+	if err != nil {
+		log.Warn("Failed to sign validator proof", "address", pm.validator.address, "err", err)
+		return nil
+	}
+	pm.validator.proof = proof
+	return proof
+}
+
+// verifyValidatorProof records the masternode a peer proved to run. An empty
+// proof is accepted, an invalid one is an error.
+func (pm *ProtocolManager) verifyValidatorProof(p *peer, proof []byte) error {
+	if len(proof) == 0 {
+		return nil
+	}
+	pubkey, err := crypto.SigToPub(validatorProofHash(p.ID()), proof)
+	if err != nil {
+		return errResp(ErrDecode, "invalid validator proof: %v", err)
+	}
+	validator := crypto.PubkeyToAddress(*pubkey)
+	p.SetValidator(validator)
+	p.Log().Debug("Peer proved to run a masternode", "address", validator)
+	return nil
+}
+
+// masternodes returns the masternodes of the current PoSV snapshot, nil if the
+// chain doesn't run PoSV.
+func (pm *ProtocolManager) masternodes() map[common.Address]struct{} {
+	engine, ok := pm.engine.(masternodeEngine)
+	if !ok {
+		return nil
+	}
+	snap, err := engine.GetSnapshot(pm.blockchain, pm.blockchain.CurrentHeader())
+	if err != nil {
+		log.Debug("Failed to retrieve masternodes", "err", err)
+		return nil
+	}
+	return snap.Signers
+}
+
+// isValidatorPeer reports whether the peer runs one of the given masternodes.
+func isValidatorPeer(p *peer, masternodes map[common.Address]struct{}) bool {
+	validator := p.Validator()
+	if validator == (common.Address{}) {
+		return false
+	}
+	_, ok := masternodes[validator]
+	return ok
+}
+
+// registerPeer registers the peer in the peer set, keeping untrusted ordinary
+// peers out of the slots reserved for masternodes. Only PoSV chains have
+// masternodes to reserve peer slots for.
+func (pm *ProtocolManager) registerPeer(p *peer) error {
+	if p.Peer.Info().Network.Trusted {
+		return pm.peers.Register(p)
+	}
+	limit := pm.maxPeers
+	if _, ok := pm.engine.(masternodeEngine); ok {
+		limit -= pm.validatorPeers
+	}
+	masternodes := pm.masternodes()
+	reserved := func(p *peer) bool {
+		return isValidatorPeer(p, masternodes)
+	}
+	return pm.peers.RegisterReserved(p, reserved, limit)
+}
+
+// prioritizeValidators splits the peers into the masternodes and the others. If
+// a header is given, the masternode in turn to create its child leads.
+func (pm *ProtocolManager) prioritizeValidators(peers []*peer, header *types.Header) (validators, others []*peer) {
+	masternodes := pm.masternodes()
+	if len(masternodes) == 0 {
+		return nil, peers
+	}
+	var next common.Address
+	if header != nil {
+		next = pm.nextInTurn(header)
+	}
+	for _, p := range peers {
+		switch {
+		case !isValidatorPeer(p, masternodes):
+			others = append(others, p)
+		case p.Validator() == next:
+			validators = append([]*peer{p}, validators...)
+		default:
+			validators = append(validators, p)
+		}
+	}
+	return validators, others
+}
+
+// nextInTurn returns the masternode in turn to create the child of the given
+// header, following Posv.YourTurn, or the zero address if unknown.
+func (pm *ProtocolManager) nextInTurn(header *types.Header) common.Address {
+	engine, ok := pm.engine.(masternodeEngine)
+	if !ok {
+		return common.Address{}
+	}
+	masternodes := engine.GetMasternodes(pm.blockchain, header)
+	if len(masternodes) == 0 {
+		return common.Address{}
+	}
+	// The first masternode is in turn after the genesis or an unknown creator
+	index := -1
+	if header.Number.Uint64() != 0 {
+		creator, err := pm.engine.Author(header)
+		if err != nil {
+			return common.Address{}
+		}
+		for i, masternode := range masternodes {
+			if masternode == creator {
+				index = i
+				break
+			}
+		}
+	}
+	return masternodes[(index+1)%len(masternodes)]
+}
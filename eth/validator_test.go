@@ -0,0 +1,272 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"crypto/ecdsa"
+	"testing"
+	"time"
+
+	"github.com/69th-byte/sdexchain/accounts"
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/consensus"
+	"github.com/69th-byte/sdexchain/consensus/posv"
+	"github.com/69th-byte/sdexchain/core"
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/eth/downloader"
+	"github.com/69th-byte/sdexchain/p2p"
+	"github.com/69th-byte/sdexchain/p2p/discover"
+)
+
+// testMasternodeEngine is a consensus engine with a fixed set of masternodes.
+type testMasternodeEngine struct {
+	consensus.Engine
+	masternodes []common.Address
+}
+
+func (e *testMasternodeEngine) GetSnapshot(chain consensus.ChainReader, header *types.Header) (*posv.Snapshot, error) {
+	signers := make(map[common.Address]struct{})
+	for _, masternode := range e.masternodes {
+		signers[masternode] = struct{}{}
+	}
+	return &posv.Snapshot{Signers: signers}, nil
+}
+
+func (e *testMasternodeEngine) GetMasternodes(chain consensus.ChainReader, header *types.Header) []common.Address {
+	return e.masternodes
+}
+
+// newTestMasternodes creates the keys of the given number of masternodes and a
+// protocol manager running PoSV with them.
+func newTestMasternodes(t *testing.T, count int, generator func(int, *core.BlockGen)) (*ProtocolManager, []*ecdsa.PrivateKey) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 1, generator, nil)
+
+	keys := make([]*ecdsa.PrivateKey, count)
+	engine := &testMasternodeEngine{Engine: pm.engine}
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		engine.masternodes = append(engine.masternodes, crypto.PubkeyToAddress(keys[i].PublicKey))
+	}
+	pm.engine = engine
+	return pm, keys
+}
+
+// newTestValidatorPeer creates an eth/65 peer completing the handshakes, proving
+// to run the masternode of the given key if it's not nil.
+func newTestValidatorPeer(t *testing.T, name string, pm *ProtocolManager, key *ecdsa.PrivateKey) (*testPeer, <-chan error) {
+	p, errc := newTestPeer(name, eth65, pm, true)
+	if err := p2p.ExpectMsg(p.app, ValidatorProofMsg, &validatorProofData{Signature: pm.validatorProof()}); err != nil {
+		t.Fatalf("%s: validator proof recv: %v", name, err)
+	}
+	var proof []byte
+	if key != nil {
+		proof, _ = crypto.Sign(validatorProofHash(p.ID()), key)
+	}
+	if err := p2p.Send(p.app, ValidatorProofMsg, &validatorProofData{Signature: proof}); err != nil {
+		t.Fatalf("%s: validator proof send: %v", name, err)
+	}
+	return p, errc
+}
+
+// expectRegistered checks that the protocol manager serves the peer, meaning
+// that it was admitted and registered.
+func expectRegistered(t *testing.T, pm *ProtocolManager, p *testPeer) {
+	genesis := pm.blockchain.Genesis().Header()
+	if err := p2p.Send(p.app, GetBlockHeadersMsg, &getBlockHeadersData{Origin: hashOrNumber{Number: 0}, Amount: 1}); err != nil {
+		t.Fatalf("%s: header request: %v", p.Name(), err)
+	}
+	if err := p2p.ExpectMsg(p.app, BlockHeadersMsg, []*types.Header{genesis}); err != nil {
+		t.Fatalf("%s: header response: %v", p.Name(), err)
+	}
+}
+
+// Tests that masternodes prove to run a masternode in the eth/65 handshake, and
+// that the local node does once it's configured with a masternode key.
+func TestValidatorHandshake(t *testing.T) {
+	pm, keys := newTestMasternodes(t, 2, nil)
+	defer pm.Stop()
+
+	// A masternode peer proves its masternode
+	p, _ := newTestValidatorPeer(t, "masternode", pm, keys[0])
+	defer p.close()
+	expectRegistered(t, pm, p)
+
+	info := pm.peers.Peer(p.id).Info()
+	if want := crypto.PubkeyToAddress(keys[0].PublicKey); info.Validator == nil || *info.Validator != want {
+		t.Errorf("validator mismatch: have %v, want %x", info.Validator, want)
+	}
+	// The local node proves its own masternode once configured
+	rand, _ := crypto.GenerateKey()
+	pm.self = discover.PubkeyID(&rand.PublicKey)
+	go pm.SetValidator(crypto.PubkeyToAddress(keys[1].PublicKey), func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, keys[1])
+	})
+	proof, _ := crypto.Sign(validatorProofHash(pm.self), keys[1])
+	if err := p2p.ExpectMsg(p.app, ValidatorProofMsg, &validatorProofData{Signature: proof}); err != nil {
+		t.Fatalf("validator proof broadcast: %v", err)
+	}
+	// New peers receive the proof in the handshake
+	q, _ := newTestValidatorPeer(t, "ordinary", pm, nil)
+	defer q.close()
+	expectRegistered(t, pm, q)
+
+	if info := pm.peers.Peer(q.id).Info(); info.Validator != nil {
+		t.Errorf("ordinary peer reported as validator %x", *info.Validator)
+	}
+}
+
+// Tests that ordinary peers can't take the peer slots reserved for masternodes.
+func TestValidatorPeerSlots(t *testing.T) {
+	pm, keys := newTestMasternodes(t, 1, nil)
+	defer pm.Stop()
+
+	pm.maxPeers, pm.validatorPeers = 4, 2
+
+	// Ordinary peers, including masternode candidates, fill the unreserved slots
+	candidate, _ := crypto.GenerateKey()
+	for i, key := range []*ecdsa.PrivateKey{nil, candidate} {
+		p, _ := newTestValidatorPeer(t, "ordinary", pm, key)
+		defer p.close()
+		expectRegistered(t, pm, p)
+		if pm.peers.Len() != i+1 {
+			t.Fatalf("peer count mismatch: have %d, want %d", pm.peers.Len(), i+1)
+		}
+	}
+	// Further ordinary peers are rejected
+	p, errc := newTestValidatorPeer(t, "rejected", pm, nil)
+	defer p.close()
+	select {
+	case err := <-errc:
+		if err != p2p.DiscTooManyPeers {
+			t.Errorf("rejection mismatch: have %v, want %v", err, p2p.DiscTooManyPeers)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("ordinary peer not rejected")
+	}
+	// A masternode takes a reserved slot
+	p, _ = newTestValidatorPeer(t, "masternode", pm, keys[0])
+	defer p.close()
+	expectRegistered(t, pm, p)
+}
+
+// Tests that concurrent registrations of ordinary peers can't take the peer
+// slots reserved for masternodes.
+func TestValidatorPeerSlotsConcurrent(t *testing.T) {
+	var (
+		ps       = newPeerSet()
+		reserved = func(p *peer) bool { return false }
+		errs     = make(chan error)
+	)
+	for i := 0; i < 16; i++ {
+		key, _ := crypto.GenerateKey()
+		p := newPeer(eth65, p2p.NewPeer(discover.PubkeyID(&key.PublicKey), "ordinary", nil), nil)
+		go func() { errs <- ps.RegisterReserved(p, reserved, 4) }()
+	}
+	var rejected int
+	for i := 0; i < 16; i++ {
+		switch err := <-errs; err {
+		case nil:
+		case p2p.DiscTooManyPeers:
+			rejected++
+		default:
+			t.Fatalf("registration failed: %v", err)
+		}
+	}
+	if ps.Len() != 4 || rejected != 12 {
+		t.Errorf("registration mismatch: have %d registered, %d rejected, want 4, 12", ps.Len(), rejected)
+	}
+}
+
+// Tests that no peer slots are reserved on chains without masternodes.
+func TestValidatorPeerSlotsNoPoSV(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 1, nil, nil)
+	defer pm.Stop()
+
+	pm.maxPeers, pm.validatorPeers = 3, 1
+	for i := 0; i < 3; i++ {
+		p, _ := newTestValidatorPeer(t, "ordinary", pm, nil)
+		defer p.close()
+		expectRegistered(t, pm, p)
+	}
+	if pm.peers.Len() != 3 {
+		t.Fatalf("peer count mismatch: have %d, want %d", pm.peers.Len(), 3)
+	}
+}
+
+// Tests that new blocks are pushed to the masternodes first, led by the one in
+// turn for the next block, and that masternodes get the full block even when
+// the others only get it announced.
+func TestValidatorBlockPriority(t *testing.T) {
+	var creator common.Address
+	generator := func(i int, block *core.BlockGen) { block.SetCoinbase(creator) }
+
+	// The second of three masternodes created the block, so the third is in turn
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	creator = crypto.PubkeyToAddress(keys[1].PublicKey)
+
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 1, generator, nil)
+	defer pm.Stop()
+
+	engine := &testMasternodeEngine{Engine: pm.engine}
+	for _, key := range keys {
+		engine.masternodes = append(engine.masternodes, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	pm.engine = engine
+
+	ordinary, _ := newTestValidatorPeer(t, "ordinary", pm, nil)
+	defer ordinary.close()
+	expectRegistered(t, pm, ordinary)
+
+	var masternodes []*testPeer
+	for _, key := range keys {
+		p, _ := newTestValidatorPeer(t, "masternode", pm, key)
+		defer p.close()
+		expectRegistered(t, pm, p)
+		masternodes = append(masternodes, p)
+	}
+	block := pm.blockchain.CurrentBlock()
+	td := pm.blockchain.GetTd(block.Hash(), block.NumberU64())
+
+	// Deliveries block until read, so reading out of order would time out
+	expect := func(code uint64, data interface{}, peers ...*testPeer) {
+		errc := make(chan error, len(peers))
+		for _, p := range peers {
+			go func(p *testPeer) { errc <- p2p.ExpectMsg(p.app, code, data) }(p)
+		}
+		timeout := time.After(time.Second)
+		for range peers {
+			select {
+			case err := <-errc:
+				if err != nil {
+					t.Fatalf("block delivery: %v", err)
+				}
+			case <-timeout:
+				t.Fatalf("block not delivered in order")
+			}
+		}
+	}
+	newBlock := &newBlockData{Block: block, TD: td}
+
+	go pm.BroadcastBlock(block, false)
+	expect(NewBlockMsg, newBlock, masternodes[2])
+	expect(NewBlockMsg, newBlock, masternodes[:2]...)
+	expect(NewBlockHashesMsg, newBlockHashesData{{Hash: block.Hash(), Number: block.NumberU64()}}, ordinary)
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/69th-byte/sdexchain/common"
 	"github.com/69th-byte/sdexchain/common/mclock"
 	"github.com/69th-byte/sdexchain/consensus"
+	"github.com/69th-byte/sdexchain/consensus/posv"
 	"github.com/69th-byte/sdexchain/core"
 	"github.com/69th-byte/sdexchain/core/types"
 	"github.com/69th-byte/sdexchain/eth"
@@ -80,6 +81,8 @@ type Service struct {
 
 	pongCh chan struct{} // Pong notifications are fed into this channel
 	histCh chan []uint64 // History request block numbers are fed into this channel
+
+	production epochProduction // Block production of the local node, only used by the reporting loop
 }
 
 // New returns a monitoring service ready for stats reporting.
@@ -258,6 +261,7 @@ func (s *Service) loop() {
 					log.Warn("Requested history report failed", "err", err)
 				}
 			case head := <-headCh:
+				s.updateProduction(head.Header())
 				if err = s.reportBlock(conn, head); err != nil {
 					log.Warn("Block stats report failed", "err", err)
 				}
@@ -428,6 +432,9 @@ func (s *Service) report(conn *websocket.Conn) error {
 	if err := s.reportStats(conn); err != nil {
 		return err
 	}
+	if err := s.reportMasternode(conn); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -717,3 +724,188 @@ func (s *Service) reportStats(conn *websocket.Conn) error {
 	}
 	return websocket.JSON.Send(conn, report)
 }
+
+// PoSV roles of the local node reported to the stats server.
+const (
+	roleMasternode = "masternode"
+	roleStandby    = "standby"
+	rolePenalized  = "penalized"
+)
+
+// masternodeStats is the information to report about the PoSV and TomoX duties
+// of the local node.
+type masternodeStats struct {
+	Role           string   `json:"role"`
+	BlocksCreated  int      `json:"blocksCreated"`
+	BlocksExpected int      `json:"blocksExpected"`
+	SignTxs        int      `json:"signTxs"`
+	Penalties      []uint64 `json:"penalties"`
+	TomoX          bool     `json:"tomox"`
+	Lending        bool     `json:"lending"`
+	PendingOrders  int      `json:"pendingOrders"`
+	QueuedOrders   int      `json:"queuedOrders"`
+	PendingLending int      `json:"pendingLending"`
+	QueuedLending  int      `json:"queuedLending"`
+	TradingSyncLag int64    `json:"tradingSyncLag"`
+	LendingSyncLag int64    `json:"lendingSyncLag"`
+}
+
+// updateProduction counts the blocks produced by the local node up to the given
+// head, so that masternode reports don't go through the whole epoch.
+func (s *Service) updateProduction(head *types.Header) {
+	engine, ok := s.engine.(*posv.Posv)
+	if s.eth == nil || !ok {
+		return
+	}
+	etherbase, err := s.eth.Etherbase()
+	if err != nil {
+		return
+	}
+	chain := s.eth.BlockChain()
+	s.production.update(chain, engine, etherbase, chain.Config().Posv.Epoch, head)
+}
+
+// reportMasternode retrieves the PoSV role, recent penalties and block production
+// of the local node in the current epoch, along with the state of the TomoX order
+// and lending pools, and reports it to the stats server. Light nodes and non-PoSV
+// chains don't report anything.
+func (s *Service) reportMasternode(conn *websocket.Conn) error {
+	engine, ok := s.engine.(*posv.Posv)
+	if s.eth == nil || !ok {
+		return nil
+	}
+	var (
+		chain  = s.eth.BlockChain()
+		config = chain.Config()
+		head   = chain.CurrentHeader()
+		stats  = new(masternodeStats)
+	)
+	etherbase, err := s.eth.Etherbase()
+	if err != nil {
+		return nil
+	}
+	// Figure out the role of the local node in the current snapshot
+	snap, err := engine.GetSnapshot(chain, head)
+	if err != nil {
+		log.Debug("Failed to retrieve PoSV snapshot", "number", head.Number, "err", err)
+		return nil
+	}
+	number := head.Number.Uint64()
+	checkpoint := number - number%config.Posv.Epoch
+
+	stats.Penalties = penaltyHistory(chain, etherbase, checkpoint, config.Posv.Epoch)
+	stats.Role = roleStandby
+	if _, ok := snap.Signers[etherbase]; ok {
+		stats.Role = roleMasternode
+	} else if len(stats.Penalties) > 0 {
+		stats.Role = rolePenalized
+	}
+	// Count the blocks and block signing transactions of the local node since
+	// the start of the epoch
+	s.updateProduction(head)
+	stats.BlocksCreated, stats.SignTxs = s.production.created, s.production.signTxs
+
+	if stats.Role == roleMasternode {
+		if masternodes := engine.GetMasternodes(chain, head); len(masternodes) > 0 {
+			stats.BlocksExpected = int(number-checkpoint+1) / len(masternodes)
+		}
+	}
+	// Gather the TomoX and lending details
+	stats.TomoX = s.eth.GetTomoX() != nil && config.IsTIPTomoX(head.Number)
+	stats.Lending = s.eth.GetTomoXLending() != nil && config.IsTIPTomoXLending(head.Number)
+	if pool := s.eth.OrderPool(); pool != nil {
+		stats.PendingOrders, stats.QueuedOrders = pool.Stats()
+	}
+	if pool := s.eth.LendingPool(); pool != nil {
+		stats.PendingLending, stats.QueuedLending = pool.Stats()
+	}
+	if tomox := s.eth.GetTomoX(); tomox != nil && tomox.IsSDKNode() {
+		trading, lending := chain.SDKSyncLag()
+		stats.TradingSyncLag = int64(trading / time.Millisecond)
+		stats.LendingSyncLag = int64(lending / time.Millisecond)
+	}
+	// Assemble the masternode stats and send it to the server
+	log.Trace("Sending masternode stats to ethstats", "role", stats.Role, "created", stats.BlocksCreated, "expected", stats.BlocksExpected)
+
+	report := map[string][]interface{}{
+		"emit": {"masternode", map[string]interface{}{
+			"id":    s.node,
+			"stats": stats,
+		}},
+	}
+	return websocket.JSON.Send(conn, report)
+}
+
+// productionChain is the part of the chain the masternode stats are read from.
+type productionChain interface {
+	GetHeaderByNumber(number uint64) *types.Header
+	GetBlockByNumber(number uint64) *types.Block
+}
+
+// epochProduction counts the blocks and block signing transactions of a signer
+// in the current epoch. Updates only go through the blocks imported since the
+// previous one, the epoch is recounted on a new epoch, a reorg or a new signer.
+type epochProduction struct {
+	signer     common.Address
+	checkpoint uint64      // First block of the counted epoch
+	number     uint64      // Last counted block
+	hash       common.Hash // Hash of the last counted block, to detect reorgs
+	counted    bool        // Whether any block of the epoch was counted
+
+	created int // Blocks sealed by the signer
+	signTxs int // Block signing transactions sent by the signer
+}
+
+// update counts the blocks of the signer up to the given head.
+func (p *epochProduction) update(chain productionChain, engine *posv.Posv, signer common.Address, epoch uint64, head *types.Header) {
+	number := head.Number.Uint64()
+	checkpoint := number - number%epoch
+
+	from := checkpoint
+	if p.counted && p.signer == signer && p.checkpoint == checkpoint && p.number <= number {
+		if header := chain.GetHeaderByNumber(p.number); header != nil && header.Hash() == p.hash {
+			from = p.number + 1
+		}
+	}
+	if from == checkpoint {
+		*p = epochProduction{signer: signer, checkpoint: checkpoint}
+	}
+	for n := from; n <= number; n++ {
+		block := chain.GetBlockByNumber(n)
+		if block == nil {
+			break
+		}
+		if creator, err := engine.Author(block.Header()); err == nil && creator == signer {
+			p.created++
+		}
+		signData, ok := engine.BlockSigners.Get(block.Hash())
+		if !ok {
+			signData = engine.CacheSigner(block.Hash(), block.Transactions())
+		}
+		for _, tx := range signData.([]*types.Transaction) {
+			if from := tx.From(); from != nil && *from == signer {
+				p.signTxs++
+			}
+		}
+		p.number, p.hash, p.counted = n, block.Hash(), true
+	}
+}
+
+// penaltyHistory returns the checkpoints, among the given one and the previous
+// ones still keeping penalized masternodes out of the set, penalizing the signer.
+func penaltyHistory(chain productionChain, signer common.Address, checkpoint, epoch uint64) []uint64 {
+	var history []uint64
+	for i := uint64(0); i <= common.LimitPenaltyEpoch && checkpoint >= i*epoch; i++ {
+		header := chain.GetHeaderByNumber(checkpoint - i*epoch)
+		if header == nil {
+			break
+		}
+		for _, penalty := range common.ExtractAddressFromBytes(header.Penalties) {
+			if penalty == signer {
+				history = append(history, header.Number.Uint64())
+				break
+			}
+		}
+	}
+	return history
+}
@@ -0,0 +1,154 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethstats
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/consensus/posv"
+	"github.com/69th-byte/sdexchain/core/rawdb"
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/params"
+)
+
+var (
+	keyA, _ = crypto.GenerateKey()
+	keyB, _ = crypto.GenerateKey()
+	addrA   = crypto.PubkeyToAddress(keyA.PublicKey)
+	addrB   = crypto.PubkeyToAddress(keyB.PublicKey)
+)
+
+// testChain is a canonical chain counting its block retrievals.
+type testChain struct {
+	blocks map[uint64]*types.Block
+	reads  int
+}
+
+func (c *testChain) GetHeaderByNumber(number uint64) *types.Header {
+	if block := c.blocks[number]; block != nil {
+		return block.Header()
+	}
+	return nil
+}
+
+func (c *testChain) GetBlockByNumber(number uint64) *types.Block {
+	c.reads++
+	return c.blocks[number]
+}
+
+// seal adds a block on top of the given number, sealed with the given key and
+// sending a block signing transaction from each signing key.
+func (c *testChain) seal(t *testing.T, number uint64, key *ecdsa.PrivateKey, signers ...*ecdsa.PrivateKey) {
+	var txs []*types.Transaction
+	for i, signer := range signers {
+		data := append(common.FromHex(common.SignMethod), make([]byte, 64)...)
+		tx := types.NewTransaction(uint64(i), common.HexToAddress(common.BlockSigners), new(big.Int), 100000, new(big.Int), data)
+		tx, err := types.SignTx(tx, types.HomesteadSigner{}, signer)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		txs = append(txs, tx)
+	}
+	header := &types.Header{Number: new(big.Int).SetUint64(number), Difficulty: big.NewInt(1), Extra: make([]byte, 32+65)}
+	if parent := c.blocks[number-1]; number > 0 && parent != nil {
+		header.ParentHash = parent.Hash()
+	}
+	header = types.NewBlock(header, txs, nil, nil).Header()
+	sig, err := crypto.Sign(posv.SigHash(header).Bytes(), key)
+	if err != nil {
+		t.Fatalf("failed to seal block: %v", err)
+	}
+	copy(header.Extra[32:], sig)
+	c.blocks[number] = types.NewBlock(header, txs, nil, nil)
+}
+
+func TestEpochProduction(t *testing.T) {
+	var (
+		engine     = posv.New(&params.PosvConfig{Epoch: 10}, rawdb.NewMemoryDatabase())
+		chain      = &testChain{blocks: make(map[uint64]*types.Block)}
+		production epochProduction
+	)
+	check := func(head uint64, created, signTxs, reads int) {
+		t.Helper()
+		chain.reads = 0
+		production.update(chain, engine, addrA, 10, chain.blocks[head].Header())
+		if production.created != created || production.signTxs != signTxs {
+			t.Errorf("head %d: production mismatch: have %d/%d, want %d/%d", head, production.created, production.signTxs, created, signTxs)
+		}
+		if chain.reads != reads {
+			t.Errorf("head %d: block reads mismatch: have %d, want %d", head, chain.reads, reads)
+		}
+	}
+	for n := uint64(0); n < 10; n++ {
+		chain.seal(t, n, keyA)
+	}
+	chain.seal(t, 10, keyA)
+	chain.seal(t, 11, keyB, keyA, keyB)
+	chain.seal(t, 12, keyA, keyB)
+	check(12, 2, 1, 3)
+
+	// Only the new blocks are counted
+	chain.seal(t, 13, keyA, keyA)
+	check(13, 3, 2, 1)
+	check(13, 3, 2, 0)
+
+	// A reorg recounts the epoch
+	chain.seal(t, 13, keyB)
+	chain.seal(t, 14, keyB)
+	check(14, 2, 1, 5)
+
+	// So does a new epoch
+	for n := uint64(15); n <= 20; n++ {
+		chain.seal(t, n, keyB)
+	}
+	check(20, 0, 0, 1)
+}
+
+func TestPenaltyHistory(t *testing.T) {
+	chain := &testChain{blocks: make(map[uint64]*types.Block)}
+	for n := uint64(0); n <= 60; n += 10 {
+		header := &types.Header{Number: new(big.Int).SetUint64(n)}
+		switch n {
+		case 10, 30:
+			header.Penalties = common.ExtractAddressToBytes([]common.Address{addrB, addrA})
+		case 50:
+			header.Penalties = common.ExtractAddressToBytes([]common.Address{addrB})
+		}
+		chain.blocks[n] = types.NewBlockWithHeader(header)
+	}
+	tests := []struct {
+		signer     common.Address
+		checkpoint uint64
+		want       []uint64
+	}{
+		{addrA, 60, []uint64{30}},
+		{addrA, 50, []uint64{30, 10}},
+		{addrA, 30, []uint64{30, 10}},
+		{addrB, 60, []uint64{50, 30}},
+		{addrA, 0, nil},
+	}
+	for i, tt := range tests {
+		if have := penaltyHistory(chain, tt.signer, tt.checkpoint, 10); !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: penalty history mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
@@ -0,0 +1,1031 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package graphql provides a GraphQL interface to Ethereum node data.
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/common/hexutil"
+	"github.com/69th-byte/sdexchain/common/math"
+	"github.com/69th-byte/sdexchain/core"
+	"github.com/69th-byte/sdexchain/core/state"
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/internal/ethapi"
+	"github.com/69th-byte/sdexchain/rpc"
+	"github.com/69th-byte/sdexchain/tomox/tradingstate"
+	"github.com/69th-byte/sdexchain/tomoxlending/lendingstate"
+)
+
+// maxBlocksRange is the maximum number of blocks a single blocks query may span.
+const maxBlocksRange = 1024
+
+var (
+	errBlockInvariant = errors.New("block objects must be instantiated with at least one of num or hash")
+	errStateNotFound  = errors.New("state not found")
+	errBlocksRange    = fmt.Errorf("blocks range exceeds %d blocks", maxBlocksRange)
+)
+
+// Account represents an Ethereum account at a particular block.
+type Account struct {
+	backend     ethapi.Backend
+	address     common.Address
+	blockNumber rpc.BlockNumber
+}
+
+// getState fetches the StateDB object for an account.
+func (a *Account) getState(ctx context.Context) (*state.StateDB, error) {
+	state, _, err := a.backend.StateAndHeaderByNumber(ctx, a.blockNumber)
+	if err == nil && state == nil {
+		err = errStateNotFound
+	}
+	return state, err
+}
+
+func (a *Account) Address(ctx context.Context) (common.Address, error) {
+	return a.address, nil
+}
+
+func (a *Account) Balance(ctx context.Context) (hexutil.Big, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*state.GetBalance(a.address)), nil
+}
+
+func (a *Account) TransactionCount(ctx context.Context) (hexutil.Uint64, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(state.GetNonce(a.address)), nil
+}
+
+func (a *Account) Code(ctx context.Context) (hexutil.Bytes, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(state.GetCode(a.address)), nil
+}
+
+func (a *Account) Storage(ctx context.Context, args struct{ Slot common.Hash }) (common.Hash, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return state.GetState(a.address, args.Slot), nil
+}
+
+// Log represents an individual log message. All arguments are mandatory.
+type Log struct {
+	backend     ethapi.Backend
+	transaction *Transaction
+	log         *types.Log
+}
+
+func (l *Log) Transaction(ctx context.Context) *Transaction {
+	return l.transaction
+}
+
+func (l *Log) Account(ctx context.Context, args BlockNumberArgs) *Account {
+	return &Account{
+		backend:     l.backend,
+		address:     l.log.Address,
+		blockNumber: args.Number(),
+	}
+}
+
+func (l *Log) Index(ctx context.Context) int32 {
+	return int32(l.log.Index)
+}
+
+func (l *Log) Topics(ctx context.Context) []common.Hash {
+	return l.log.Topics
+}
+
+func (l *Log) Data(ctx context.Context) hexutil.Bytes {
+	return hexutil.Bytes(l.log.Data)
+}
+
+// Transaction represents an Ethereum transaction.
+// backend and hash are mandatory; all others will be fetched when required.
+type Transaction struct {
+	backend ethapi.Backend
+	hash    common.Hash
+	tx      *types.Transaction
+	block   *Block
+	index   uint64
+}
+
+// resolve returns the internal transaction object, fetching it if needed.
+func (t *Transaction) resolve(ctx context.Context) (*types.Transaction, error) {
+	if t.tx == nil {
+		tx, blockHash, _, index := core.GetTransaction(t.backend.ChainDb(), t.hash)
+		if tx != nil {
+			t.tx = tx
+			t.block = &Block{
+				backend: t.backend,
+				hash:    blockHash,
+			}
+			t.index = index
+		} else {
+			t.tx = t.backend.GetPoolTransaction(t.hash)
+		}
+	}
+	return t.tx, nil
+}
+
+func (t *Transaction) Hash(ctx context.Context) common.Hash {
+	return t.hash
+}
+
+func (t *Transaction) InputData(ctx context.Context) (hexutil.Bytes, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(tx.Data()), nil
+}
+
+func (t *Transaction) Gas(ctx context.Context) (hexutil.Uint64, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return 0, err
+	}
+	return hexutil.Uint64(tx.Gas()), nil
+}
+
+func (t *Transaction) GasPrice(ctx context.Context) (hexutil.Big, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*tx.GasPrice()), nil
+}
+
+func (t *Transaction) MaxFeePerGas(ctx context.Context) (*hexutil.Big, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil || tx.Type() != types.DynamicFeeTxType {
+		return nil, err
+	}
+	return (*hexutil.Big)(tx.GasFeeCap()), nil
+}
+
+func (t *Transaction) MaxPriorityFeePerGas(ctx context.Context) (*hexutil.Big, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil || tx.Type() != types.DynamicFeeTxType {
+		return nil, err
+	}
+	return (*hexutil.Big)(tx.GasTipCap()), nil
+}
+
+func (t *Transaction) EffectiveGasPrice(ctx context.Context) (*hexutil.Big, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil || t.block == nil {
+		return nil, err
+	}
+	block, err := t.block.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	baseFee := block.BaseFee()
+	if baseFee == nil || tx.Type() != types.DynamicFeeTxType {
+		return (*hexutil.Big)(tx.GasPrice()), nil
+	}
+	return (*hexutil.Big)(math.BigMin(new(big.Int).Add(tx.GasTipCap(), baseFee), tx.GasFeeCap())), nil
+}
+
+func (t *Transaction) Value(ctx context.Context) (hexutil.Big, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*tx.Value()), nil
+}
+
+func (t *Transaction) Nonce(ctx context.Context) (hexutil.Uint64, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return 0, err
+	}
+	return hexutil.Uint64(tx.Nonce()), nil
+}
+
+func (t *Transaction) Type(ctx context.Context) (int32, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return 0, err
+	}
+	return int32(tx.Type()), nil
+}
+
+func (t *Transaction) To(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	to := tx.To()
+	if to == nil {
+		return nil, nil
+	}
+	return &Account{
+		backend:     t.backend,
+		address:     *to,
+		blockNumber: args.Number(),
+	}, nil
+}
+
+func (t *Transaction) From(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	var signer types.Signer = types.FrontierSigner{}
+	if tx.Protected() {
+		signer = types.LatestSignerForChainID(tx.ChainId())
+	}
+	from, _ := types.Sender(signer, tx)
+
+	return &Account{
+		backend:     t.backend,
+		address:     from,
+		blockNumber: args.Number(),
+	}, nil
+}
+
+func (t *Transaction) Block(ctx context.Context) (*Block, error) {
+	if _, err := t.resolve(ctx); err != nil {
+		return nil, err
+	}
+	return t.block, nil
+}
+
+func (t *Transaction) Index(ctx context.Context) (*int32, error) {
+	if _, err := t.resolve(ctx); err != nil {
+		return nil, err
+	}
+	if t.block == nil {
+		return nil, nil
+	}
+	index := int32(t.index)
+	return &index, nil
+}
+
+// getReceipt returns the receipt associated with this transaction, if any.
+func (t *Transaction) getReceipt(ctx context.Context) (*types.Receipt, error) {
+	if _, err := t.resolve(ctx); err != nil {
+		return nil, err
+	}
+	if t.block == nil {
+		return nil, nil
+	}
+	receipts, err := t.block.resolveReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if t.index >= uint64(len(receipts)) {
+		return nil, nil
+	}
+	return receipts[t.index], nil
+}
+
+func (t *Transaction) Status(ctx context.Context) (*hexutil.Uint64, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	ret := hexutil.Uint64(receipt.Status)
+	return &ret, nil
+}
+
+func (t *Transaction) GasUsed(ctx context.Context) (*hexutil.Uint64, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	ret := hexutil.Uint64(receipt.GasUsed)
+	return &ret, nil
+}
+
+func (t *Transaction) CumulativeGasUsed(ctx context.Context) (*hexutil.Uint64, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	ret := hexutil.Uint64(receipt.CumulativeGasUsed)
+	return &ret, nil
+}
+
+func (t *Transaction) CreatedContract(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil || receipt.ContractAddress == (common.Address{}) {
+		return nil, err
+	}
+	return &Account{
+		backend:     t.backend,
+		address:     receipt.ContractAddress,
+		blockNumber: args.Number(),
+	}, nil
+}
+
+func (t *Transaction) Logs(ctx context.Context) (*[]*Log, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	ret := make([]*Log, 0, len(receipt.Logs))
+	for _, log := range receipt.Logs {
+		ret = append(ret, &Log{
+			backend:     t.backend,
+			transaction: t,
+			log:         log,
+		})
+	}
+	return &ret, nil
+}
+
+func (t *Transaction) OrderMatches(ctx context.Context) ([]*Order, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil || !tx.IsTradingTransaction() {
+		return []*Order{}, err
+	}
+	return orderMatches(ctx, t.backend, t.hash)
+}
+
+func (t *Transaction) LendingMatches(ctx context.Context) ([]*LendingItem, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil || !tx.IsLendingTransaction() {
+		return []*LendingItem{}, err
+	}
+	return lendingMatches(ctx, t.backend, t.hash)
+}
+
+// Block represents an Ethereum block.
+// backend, and either num or hash are mandatory. All other fields are lazily fetched
+// when required.
+type Block struct {
+	backend  ethapi.Backend
+	num      *rpc.BlockNumber
+	hash     common.Hash
+	block    *types.Block
+	receipts []*types.Receipt
+}
+
+// resolve returns the internal Block object representing this block, fetching
+// it if necessary.
+func (b *Block) resolve(ctx context.Context) (*types.Block, error) {
+	if b.block != nil {
+		return b.block, nil
+	}
+	var err error
+	if b.hash != (common.Hash{}) {
+		b.block, err = b.backend.GetBlock(ctx, b.hash)
+	} else if b.num != nil {
+		b.block, err = b.backend.BlockByNumber(ctx, *b.num)
+	} else {
+		return nil, errBlockInvariant
+	}
+	if b.block != nil {
+		b.hash = b.block.Hash()
+	}
+	return b.block, err
+}
+
+// resolveReceipts returns the list of receipts for this block, fetching them
+// if necessary.
+func (b *Block) resolveReceipts(ctx context.Context) ([]*types.Receipt, error) {
+	if b.receipts == nil {
+		block, err := b.resolve(ctx)
+		if err != nil || block == nil {
+			return nil, err
+		}
+		receipts, err := b.backend.GetReceipts(ctx, block.Hash())
+		if err != nil {
+			return nil, err
+		}
+		b.receipts = []*types.Receipt(receipts)
+	}
+	return b.receipts, nil
+}
+
+// header resolves the block and returns its header, failing if the block is unknown.
+func (b *Block) header(ctx context.Context) (*types.Header, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, errors.New("block not found")
+	}
+	return block.Header(), nil
+}
+
+func (b *Block) Number(ctx context.Context) (hexutil.Uint64, error) {
+	header, err := b.header(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(header.Number.Uint64()), nil
+}
+
+func (b *Block) Hash(ctx context.Context) (common.Hash, error) {
+	if _, err := b.header(ctx); err != nil {
+		return common.Hash{}, err
+	}
+	return b.hash, nil
+}
+
+func (b *Block) GasLimit(ctx context.Context) (hexutil.Uint64, error) {
+	header, err := b.header(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(header.GasLimit), nil
+}
+
+func (b *Block) GasUsed(ctx context.Context) (hexutil.Uint64, error) {
+	header, err := b.header(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(header.GasUsed), nil
+}
+
+func (b *Block) BaseFeePerGas(ctx context.Context) (*hexutil.Big, error) {
+	header, err := b.header(ctx)
+	if err != nil || header.BaseFee == nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(header.BaseFee), nil
+}
+
+func (b *Block) Parent(ctx context.Context) (*Block, error) {
+	header, err := b.header(ctx)
+	if err != nil || header.Number.Sign() == 0 {
+		return nil, err
+	}
+	return &Block{
+		backend: b.backend,
+		hash:    header.ParentHash,
+	}, nil
+}
+
+func (b *Block) Difficulty(ctx context.Context) (hexutil.Big, error) {
+	header, err := b.header(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*header.Difficulty), nil
+}
+
+func (b *Block) Timestamp(ctx context.Context) (hexutil.Big, error) {
+	header, err := b.header(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*header.Time), nil
+}
+
+func (b *Block) Nonce(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.header(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(header.Nonce[:]), nil
+}
+
+func (b *Block) MixHash(ctx context.Context) (common.Hash, error) {
+	header, err := b.header(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.MixDigest, nil
+}
+
+func (b *Block) TransactionsRoot(ctx context.Context) (common.Hash, error) {
+	header, err := b.header(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.TxHash, nil
+}
+
+func (b *Block) StateRoot(ctx context.Context) (common.Hash, error) {
+	header, err := b.header(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.Root, nil
+}
+
+func (b *Block) ReceiptsRoot(ctx context.Context) (common.Hash, error) {
+	header, err := b.header(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.ReceiptHash, nil
+}
+
+func (b *Block) ExtraData(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.header(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(header.Extra), nil
+}
+
+func (b *Block) LogsBloom(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.header(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(header.Bloom.Bytes()), nil
+}
+
+func (b *Block) TotalDifficulty(ctx context.Context) (hexutil.Big, error) {
+	if _, err := b.header(ctx); err != nil {
+		return hexutil.Big{}, err
+	}
+	td := b.backend.GetTd(b.hash)
+	if td == nil {
+		return hexutil.Big{}, errors.New("total difficulty not found")
+	}
+	return hexutil.Big(*td), nil
+}
+
+func (b *Block) Miner(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	header, err := b.header(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// The coinbase of PoSV blocks is not the signer, report the actual author.
+	author, err := b.backend.GetEngine().Author(header)
+	if err != nil {
+		author = header.Coinbase
+	}
+	return &Account{
+		backend:     b.backend,
+		address:     author,
+		blockNumber: args.Number(),
+	}, nil
+}
+
+func (b *Block) TransactionCount(ctx context.Context) (int32, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return 0, err
+	}
+	return int32(len(block.Transactions())), nil
+}
+
+func (b *Block) Transactions(ctx context.Context) (*[]*Transaction, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	ret := make([]*Transaction, 0, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		ret = append(ret, &Transaction{
+			backend: b.backend,
+			hash:    tx.Hash(),
+			tx:      tx,
+			block:   b,
+			index:   uint64(i),
+		})
+	}
+	return &ret, nil
+}
+
+func (b *Block) TransactionAt(ctx context.Context, args struct{ Index int32 }) (*Transaction, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if args.Index < 0 || int(args.Index) >= len(txs) {
+		return nil, nil
+	}
+	tx := txs[args.Index]
+	return &Transaction{
+		backend: b.backend,
+		hash:    tx.Hash(),
+		tx:      tx,
+		block:   b,
+		index:   uint64(args.Index),
+	}, nil
+}
+
+func (b *Block) Account(ctx context.Context, args struct{ Address common.Address }) (*Account, error) {
+	header, err := b.header(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{
+		backend:     b.backend,
+		address:     args.Address,
+		blockNumber: rpc.BlockNumber(header.Number.Int64()),
+	}, nil
+}
+
+func (b *Block) Signers(ctx context.Context) ([]common.Address, error) {
+	if _, err := b.header(ctx); err != nil {
+		return nil, err
+	}
+	return ethapi.NewPublicBlockChainAPI(b.backend).GetBlockSignersByHash(ctx, b.hash)
+}
+
+func (b *Block) Finality(ctx context.Context) (int32, error) {
+	if _, err := b.header(ctx); err != nil {
+		return 0, err
+	}
+	finality, err := ethapi.NewPublicBlockChainAPI(b.backend).GetBlockFinalityByHash(ctx, b.hash)
+	return int32(finality), err
+}
+
+func (b *Block) Trades(ctx context.Context) ([]*Order, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return []*Order{}, err
+	}
+	trades := []*Order{}
+	for _, tx := range block.Transactions() {
+		if !tx.IsTradingTransaction() {
+			continue
+		}
+		orders, err := orderMatches(ctx, b.backend, tx.Hash())
+		if err != nil {
+			return nil, err
+		}
+		trades = append(trades, orders...)
+	}
+	return trades, nil
+}
+
+func (b *Block) LendingTrades(ctx context.Context) ([]*LendingItem, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return []*LendingItem{}, err
+	}
+	trades := []*LendingItem{}
+	for _, tx := range block.Transactions() {
+		if !tx.IsLendingTransaction() {
+			continue
+		}
+		items, err := lendingMatches(ctx, b.backend, tx.Hash())
+		if err != nil {
+			return nil, err
+		}
+		trades = append(trades, items...)
+	}
+	return trades, nil
+}
+
+// BlockNumberArgs encapsulates arguments to accessors that specify a block number.
+type BlockNumberArgs struct {
+	Block *hexutil.Uint64
+}
+
+// Number returns the provided block number, or rpc.LatestBlockNumber if none
+// was provided.
+func (a BlockNumberArgs) Number() rpc.BlockNumber {
+	if a.Block != nil {
+		return rpc.BlockNumber(*a.Block)
+	}
+	return rpc.LatestBlockNumber
+}
+
+// Order represents an order processed by the TomoX matching engine.
+type Order struct {
+	item *tradingstate.OrderItem
+}
+
+func (o *Order) Hash() common.Hash           { return o.item.Hash }
+func (o *Order) TxHash() common.Hash         { return o.item.TxHash }
+func (o *Order) UserAddress() common.Address { return o.item.UserAddress }
+func (o *Order) ExchangeAddress() common.Address {
+	return o.item.ExchangeAddress
+}
+func (o *Order) BaseToken() common.Address  { return o.item.BaseToken }
+func (o *Order) QuoteToken() common.Address { return o.item.QuoteToken }
+func (o *Order) Side() string               { return o.item.Side }
+func (o *Order) Type() string               { return o.item.Type }
+func (o *Order) Status() string             { return o.item.Status }
+func (o *Order) Price() *hexutil.Big        { return (*hexutil.Big)(o.item.Price) }
+func (o *Order) Quantity() *hexutil.Big     { return (*hexutil.Big)(o.item.Quantity) }
+func (o *Order) FilledAmount() *hexutil.Big { return (*hexutil.Big)(o.item.FilledAmount) }
+func (o *Order) OrderId() hexutil.Uint64    { return hexutil.Uint64(o.item.OrderID) }
+func (o *Order) RejectReason() *string      { return optionalString(o.item.RejectReason) }
+
+// LendingItem represents a lending order processed by the TomoX lending engine.
+type LendingItem struct {
+	item *lendingstate.LendingItem
+}
+
+func (l *LendingItem) Hash() common.Hash            { return l.item.Hash }
+func (l *LendingItem) TxHash() common.Hash          { return l.item.TxHash }
+func (l *LendingItem) UserAddress() common.Address  { return l.item.UserAddress }
+func (l *LendingItem) Relayer() common.Address      { return l.item.Relayer }
+func (l *LendingItem) LendingToken() common.Address { return l.item.LendingToken }
+func (l *LendingItem) CollateralToken() common.Address {
+	return l.item.CollateralToken
+}
+func (l *LendingItem) Term() hexutil.Uint64       { return hexutil.Uint64(l.item.Term) }
+func (l *LendingItem) Interest() *hexutil.Big     { return (*hexutil.Big)(l.item.Interest) }
+func (l *LendingItem) Quantity() *hexutil.Big     { return (*hexutil.Big)(l.item.Quantity) }
+func (l *LendingItem) FilledAmount() *hexutil.Big { return (*hexutil.Big)(l.item.FilledAmount) }
+func (l *LendingItem) Side() string               { return l.item.Side }
+func (l *LendingItem) Type() string               { return l.item.Type }
+func (l *LendingItem) Status() string             { return l.item.Status }
+func (l *LendingItem) LendingId() hexutil.Uint64  { return hexutil.Uint64(l.item.LendingId) }
+func (l *LendingItem) RejectReason() *string      { return optionalString(l.item.RejectReason) }
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// orderMatches returns the orders processed by the trading transaction of the given hash.
+func orderMatches(ctx context.Context, backend ethapi.Backend, hash common.Hash) ([]*Order, error) {
+	items, err := ethapi.NewPublicTomoXTransactionPoolAPI(backend, nil).GetOrderTxMatchByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	orders := make([]*Order, 0, len(items))
+	for _, item := range items {
+		orders = append(orders, &Order{item})
+	}
+	return orders, nil
+}
+
+// lendingMatches returns the lending items processed by the lending transaction of the given hash.
+func lendingMatches(ctx context.Context, backend ethapi.Backend, hash common.Hash) ([]*LendingItem, error) {
+	items, err := ethapi.NewPublicTomoXTransactionPoolAPI(backend, nil).GetLendingTxMatchByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	lendings := make([]*LendingItem, 0, len(items))
+	for _, item := range items {
+		lendings = append(lendings, &LendingItem{item})
+	}
+	return lendings, nil
+}
+
+// OrderBookLevel is the aggregated volume of an order book at a price.
+type OrderBookLevel struct {
+	price  *big.Int
+	volume *big.Int
+}
+
+func (l *OrderBookLevel) Price() hexutil.Big  { return hexutil.Big(*l.price) }
+func (l *OrderBookLevel) Volume() hexutil.Big { return hexutil.Big(*l.volume) }
+
+// LendingBookLevel is the aggregated volume of a lending book at an interest rate.
+type LendingBookLevel struct {
+	interest *big.Int
+	volume   *big.Int
+}
+
+func (l *LendingBookLevel) Interest() hexutil.Big { return hexutil.Big(*l.interest) }
+func (l *LendingBookLevel) Volume() hexutil.Big   { return hexutil.Big(*l.volume) }
+
+// sortedLevels flattens a price to volume mapping of the TomoX state into a
+// list sorted by price, descending if desc is set.
+func sortedLevels(levels map[*big.Int]*big.Int, desc bool) [][2]*big.Int {
+	sorted := make([][2]*big.Int, 0, len(levels))
+	for price, volume := range levels {
+		sorted = append(sorted, [2]*big.Int{price, volume})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if desc {
+			return sorted[i][0].Cmp(sorted[j][0]) > 0
+		}
+		return sorted[i][0].Cmp(sorted[j][0]) < 0
+	})
+	return sorted
+}
+
+// Pair represents a TomoX trading pair, resolved against the latest block.
+type Pair struct {
+	backend    ethapi.Backend
+	baseToken  common.Address
+	quoteToken common.Address
+}
+
+func (p *Pair) BaseToken() common.Address  { return p.baseToken }
+func (p *Pair) QuoteToken() common.Address { return p.quoteToken }
+
+func (p *Pair) Price(ctx context.Context) (*hexutil.Big, error) {
+	price, err := ethapi.NewPublicTomoXTransactionPoolAPI(p.backend, nil).GetPrice(ctx, p.baseToken, p.quoteToken)
+	if err != nil {
+		// A zero price is reported if the pair has not been traded yet
+		if price != nil && price.Sign() == 0 {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return (*hexutil.Big)(price), nil
+}
+
+func (p *Pair) Bids(ctx context.Context) ([]*OrderBookLevel, error) {
+	bids, err := ethapi.NewPublicTomoXTransactionPoolAPI(p.backend, nil).GetBids(ctx, p.baseToken, p.quoteToken)
+	if err != nil {
+		return nil, err
+	}
+	return orderBookLevels(sortedLevels(bids, true)), nil
+}
+
+func (p *Pair) Asks(ctx context.Context) ([]*OrderBookLevel, error) {
+	asks, err := ethapi.NewPublicTomoXTransactionPoolAPI(p.backend, nil).GetAsks(ctx, p.baseToken, p.quoteToken)
+	if err != nil {
+		return nil, err
+	}
+	return orderBookLevels(sortedLevels(asks, false)), nil
+}
+
+func orderBookLevels(sorted [][2]*big.Int) []*OrderBookLevel {
+	levels := make([]*OrderBookLevel, 0, len(sorted))
+	for _, level := range sorted {
+		levels = append(levels, &OrderBookLevel{price: level[0], volume: level[1]})
+	}
+	return levels
+}
+
+// LendingBook represents a TomoX lending book, resolved against the latest block.
+type LendingBook struct {
+	backend      ethapi.Backend
+	lendingToken common.Address
+	term         uint64
+}
+
+func (l *LendingBook) LendingToken() common.Address { return l.lendingToken }
+func (l *LendingBook) Term() hexutil.Uint64         { return hexutil.Uint64(l.term) }
+
+func (l *LendingBook) Invests(ctx context.Context) ([]*LendingBookLevel, error) {
+	invests, err := ethapi.NewPublicTomoXTransactionPoolAPI(l.backend, nil).GetInvests(ctx, l.lendingToken, l.term)
+	if err != nil {
+		return nil, err
+	}
+	// Investors offering the lowest interest are matched first
+	return lendingBookLevels(sortedLevels(invests, false)), nil
+}
+
+func (l *LendingBook) Borrows(ctx context.Context) ([]*LendingBookLevel, error) {
+	borrows, err := ethapi.NewPublicTomoXTransactionPoolAPI(l.backend, nil).GetBorrows(ctx, l.lendingToken, l.term)
+	if err != nil {
+		return nil, err
+	}
+	// Borrowers offering the highest interest are matched first
+	return lendingBookLevels(sortedLevels(borrows, true)), nil
+}
+
+func lendingBookLevels(sorted [][2]*big.Int) []*LendingBookLevel {
+	levels := make([]*LendingBookLevel, 0, len(sorted))
+	for _, level := range sorted {
+		levels = append(levels, &LendingBookLevel{interest: level[0], volume: level[1]})
+	}
+	return levels
+}
+
+// Resolver is the top-level object in the GraphQL hierarchy.
+type Resolver struct {
+	backend ethapi.Backend
+}
+
+func (r *Resolver) Block(ctx context.Context, args struct {
+	Number *hexutil.Uint64
+	Hash   *common.Hash
+}) (*Block, error) {
+	var block *Block
+	if args.Number != nil {
+		number := rpc.BlockNumber(uint64(*args.Number))
+		block = &Block{
+			backend: r.backend,
+			num:     &number,
+		}
+	} else if args.Hash != nil {
+		block = &Block{
+			backend: r.backend,
+			hash:    *args.Hash,
+		}
+	} else {
+		number := rpc.LatestBlockNumber
+		block = &Block{
+			backend: r.backend,
+			num:     &number,
+		}
+	}
+	// Resolve the block, return nil if it doesn't exist
+	b, err := block.resolve(ctx)
+	if err != nil || b == nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+func (r *Resolver) Blocks(ctx context.Context, args struct {
+	From hexutil.Uint64
+	To   *hexutil.Uint64
+}) ([]*Block, error) {
+	from := rpc.BlockNumber(args.From)
+
+	var to rpc.BlockNumber
+	if args.To != nil {
+		to = rpc.BlockNumber(*args.To)
+	} else {
+		to = rpc.BlockNumber(r.backend.CurrentBlock().Number().Int64())
+	}
+	if to < from {
+		return []*Block{}, nil
+	}
+	if to-from >= maxBlocksRange {
+		return nil, errBlocksRange
+	}
+	ret := make([]*Block, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		num := i
+		ret = append(ret, &Block{
+			backend: r.backend,
+			num:     &num,
+		})
+	}
+	return ret, nil
+}
+
+func (r *Resolver) Transaction(ctx context.Context, args struct{ Hash common.Hash }) (*Transaction, error) {
+	tx := &Transaction{
+		backend: r.backend,
+		hash:    args.Hash,
+	}
+	// Resolve the transaction; if it doesn't exist, return nil.
+	t, err := tx.resolve(ctx)
+	if err != nil || t == nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (r *Resolver) GasPrice(ctx context.Context) (hexutil.Big, error) {
+	price, err := r.backend.SuggestPrice(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*price), nil
+}
+
+func (r *Resolver) MaxPriorityFeePerGas(ctx context.Context) (hexutil.Big, error) {
+	tip, err := r.backend.SuggestTipCap(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*tip), nil
+}
+
+func (r *Resolver) ProtocolVersion(ctx context.Context) (int32, error) {
+	return int32(r.backend.ProtocolVersion()), nil
+}
+
+func (r *Resolver) Pair(ctx context.Context, args struct{ BaseToken, QuoteToken common.Address }) *Pair {
+	return &Pair{
+		backend:    r.backend,
+		baseToken:  args.BaseToken,
+		quoteToken: args.QuoteToken,
+	}
+}
+
+func (r *Resolver) LendingBook(ctx context.Context, args struct {
+	LendingToken common.Address
+	Term         hexutil.Uint64
+}) *LendingBook {
+	return &LendingBook{
+		backend:      r.backend,
+		lendingToken: args.LendingToken,
+		term:         uint64(args.Term),
+	}
+}
+
+func (r *Resolver) SendRawTransaction(ctx context.Context, args struct{ Data hexutil.Bytes }) (common.Hash, error) {
+	return ethapi.NewPublicTransactionPoolAPI(r.backend, new(ethapi.AddrLocker)).SendRawTransaction(ctx, args.Data)
+}
@@ -0,0 +1,242 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/common/hexutil"
+	"github.com/69th-byte/sdexchain/consensus"
+	"github.com/69th-byte/sdexchain/consensus/ethash"
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/internal/ethapi"
+	"github.com/69th-byte/sdexchain/tomox"
+	"github.com/69th-byte/sdexchain/tomox/tradingstate"
+	"github.com/69th-byte/sdexchain/tomoxlending"
+	"github.com/69th-byte/sdexchain/tomoxlending/lendingstate"
+)
+
+// tomoxBackend is an API backend serving the TomoX and lending states committed
+// by the author of its current block. Only the methods the TomoX resolvers need
+// are implemented.
+type tomoxBackend struct {
+	ethapi.Backend
+	block   *types.Block
+	tomox   *tomox.TomoX
+	lending *tomoxlending.Lending
+}
+
+func (b *tomoxBackend) CurrentBlock() *types.Block            { return b.block }
+func (b *tomoxBackend) TomoxService() *tomox.TomoX            { return b.tomox }
+func (b *tomoxBackend) LendingService() *tomoxlending.Lending { return b.lending }
+func (b *tomoxBackend) GetEngine() consensus.Engine           { return ethash.NewFaker() }
+
+// newTomoXBackend commits a small order book and lending book and returns a
+// backend whose current block references their state roots.
+func newTomoXBackend(t *testing.T, dir string, baseToken, quoteToken, lendingToken common.Address, term uint64) *tomoxBackend {
+	tomoX := tomox.New(&tomox.Config{DataDir: dir})
+	lending := tomoxlending.New(tomoX)
+
+	trading, _ := tradingstate.New(tradingstate.EmptyRoot, tomoX.StateCache)
+	orderBook := tradingstate.GetTradingOrderBookHash(baseToken, quoteToken)
+	for i, order := range []tradingstate.OrderItem{
+		{Side: tradingstate.Bid, Price: big.NewInt(90), Quantity: big.NewInt(1)},
+		{Side: tradingstate.Bid, Price: big.NewInt(95), Quantity: big.NewInt(2)},
+		{Side: tradingstate.Ask, Price: big.NewInt(110), Quantity: big.NewInt(3)},
+		{Side: tradingstate.Ask, Price: big.NewInt(105), Quantity: big.NewInt(4)},
+	} {
+		trading.InsertOrderItem(orderBook, common.BigToHash(big.NewInt(int64(i+1))), order)
+	}
+	trading.SetLastPrice(orderBook, big.NewInt(100))
+	tradingRoot, err := trading.Commit()
+	if err != nil {
+		t.Fatalf("failed to commit trading state: %v", err)
+	}
+	if err := tomoX.StateCache.TrieDB().Commit(tradingRoot, false); err != nil {
+		t.Fatalf("failed to flush trading state: %v", err)
+	}
+
+	books, _ := lendingstate.New(lendingstate.EmptyRoot, lending.StateCache)
+	lendingBook := lendingstate.GetLendingOrderBookHash(lendingToken, term)
+	for i, item := range []lendingstate.LendingItem{
+		{Side: lendingstate.Investing, Interest: big.NewInt(7), Quantity: big.NewInt(5)},
+		{Side: lendingstate.Investing, Interest: big.NewInt(6), Quantity: big.NewInt(6)},
+		{Side: lendingstate.Borrowing, Interest: big.NewInt(4), Quantity: big.NewInt(7)},
+		{Side: lendingstate.Borrowing, Interest: big.NewInt(5), Quantity: big.NewInt(8)},
+	} {
+		books.InsertLendingItem(lendingBook, common.BigToHash(big.NewInt(int64(i+1))), item)
+	}
+	lendingRoot, err := books.Commit()
+	if err != nil {
+		t.Fatalf("failed to commit lending state: %v", err)
+	}
+	if err := lending.StateCache.TrieDB().Commit(lendingRoot, false); err != nil {
+		t.Fatalf("failed to flush lending state: %v", err)
+	}
+
+	// The block author publishes both roots in a transaction to the state address
+	key, _ := crypto.GenerateKey()
+	author := crypto.PubkeyToAddress(key.PublicKey)
+	data := append(tradingRoot.Bytes(), lendingRoot.Bytes()...)
+	tx, err := types.SignTx(types.NewTransaction(0, common.HexToAddress(common.TradingStateAddr), common.Big0, 0, common.Big0, data), types.HomesteadSigner{}, key)
+	if err != nil {
+		t.Fatalf("failed to sign state root transaction: %v", err)
+	}
+	header := &types.Header{Number: big.NewInt(1), Coinbase: author}
+	return &tomoxBackend{
+		block:   types.NewBlock(header, []*types.Transaction{tx}, nil, nil),
+		tomox:   tomoX,
+		lending: lending,
+	}
+}
+
+// Tests that the schema parses and every field is backed by a resolver.
+func TestBuildSchema(t *testing.T) {
+	if _, err := newHandler(nil); err != nil {
+		t.Fatalf("could not create graphql handler: %v", err)
+	}
+}
+
+// Tests that the scalar arguments are decoded and passed through to the resolvers.
+func TestPairQuery(t *testing.T) {
+	handler, err := newHandler(nil)
+	if err != nil {
+		t.Fatalf("could not create graphql handler: %v", err)
+	}
+	query := `{ pair(baseToken: "0x0000000000000000000000000000000000000001", quoteToken: "0x0000000000000000000000000000000000000002") { baseToken quoteToken } }`
+	res := handler.Schema.Exec(context.Background(), query, "", nil)
+	if len(res.Errors) > 0 {
+		t.Fatalf("query failed: %v", res.Errors)
+	}
+	want := `{"pair":{"baseToken":"0x0000000000000000000000000000000000000001","quoteToken":"0x0000000000000000000000000000000000000002"}}`
+	if string(res.Data) != want {
+		t.Errorf("result mismatch: have %s, want %s", res.Data, want)
+	}
+}
+
+// Tests that order book levels are sorted best price first.
+func TestSortedLevels(t *testing.T) {
+	levels := map[*big.Int]*big.Int{
+		big.NewInt(2): big.NewInt(20),
+		big.NewInt(1): big.NewInt(10),
+		big.NewInt(3): big.NewInt(30),
+	}
+	prices := func(sorted [][2]*big.Int) []int64 {
+		var ret []int64
+		for _, level := range sorted {
+			ret = append(ret, level[0].Int64())
+		}
+		return ret
+	}
+	if have, want := prices(sortedLevels(levels, true)), []int64{3, 2, 1}; !reflect.DeepEqual(have, want) {
+		t.Errorf("descending order mismatch: have %v, want %v", have, want)
+	}
+	if have, want := prices(sortedLevels(levels, false)), []int64{1, 2, 3}; !reflect.DeepEqual(have, want) {
+		t.Errorf("ascending order mismatch: have %v, want %v", have, want)
+	}
+	blob, _ := json.Marshal(orderBookLevels(sortedLevels(levels, true))[0].Volume())
+	if have, want := string(blob), `"`+hexutil.EncodeBig(big.NewInt(30))+`"`; have != want {
+		t.Errorf("volume mismatch: have %s, want %s", have, want)
+	}
+}
+
+// Tests that the TomoX resolvers serve the order and lending books of the
+// current block, best levels first.
+func TestTomoXResolvers(t *testing.T) {
+	var (
+		baseToken    = common.HexToAddress("0x0000000000000000000000000000000000000001")
+		quoteToken   = common.HexToAddress("0x0000000000000000000000000000000000000002")
+		lendingToken = common.HexToAddress("0x0000000000000000000000000000000000000003")
+	)
+	dir, err := ioutil.TempDir("", "graphql-tomox")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	handler, err := newHandler(newTomoXBackend(t, dir, baseToken, quoteToken, lendingToken, 86400))
+	if err != nil {
+		t.Fatalf("could not create graphql handler: %v", err)
+	}
+	query := `{
+		pair(baseToken: "0x0000000000000000000000000000000000000001", quoteToken: "0x0000000000000000000000000000000000000002") {
+			price
+			bids { price volume }
+			asks { price volume }
+		}
+		lendingBook(lendingToken: "0x0000000000000000000000000000000000000003", term: 86400) {
+			invests { interest volume }
+			borrows { interest volume }
+		}
+	}`
+	res := handler.Schema.Exec(context.Background(), query, "", nil)
+	if len(res.Errors) > 0 {
+		t.Fatalf("query failed: %v", res.Errors)
+	}
+	want := `{"pair":{"price":"0x64",` +
+		`"bids":[{"price":"0x5f","volume":"0x2"},{"price":"0x5a","volume":"0x1"}],` +
+		`"asks":[{"price":"0x69","volume":"0x4"},{"price":"0x6e","volume":"0x3"}]},` +
+		`"lendingBook":{` +
+		`"invests":[{"interest":"0x6","volume":"0x6"},{"interest":"0x7","volume":"0x5"}],` +
+		`"borrows":[{"interest":"0x5","volume":"0x8"},{"interest":"0x4","volume":"0x7"}]}}`
+	if string(res.Data) != want {
+		t.Errorf("result mismatch:\nhave %s\nwant %s", res.Data, want)
+	}
+}
+
+// Tests that a pair without any trade reports a null price instead of an error.
+func TestTomoXResolversNoPrice(t *testing.T) {
+	dir, err := ioutil.TempDir("", "graphql-tomox")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	handler, err := newHandler(newTomoXBackend(t, dir, common.Address{1}, common.Address{2}, common.Address{3}, 86400))
+	if err != nil {
+		t.Fatalf("could not create graphql handler: %v", err)
+	}
+	query := `{ pair(baseToken: "0x0000000000000000000000000000000000000004", quoteToken: "0x0000000000000000000000000000000000000005") { price } }`
+	res := handler.Schema.Exec(context.Background(), query, "", nil)
+	if len(res.Errors) > 0 {
+		t.Fatalf("query failed: %v", res.Errors)
+	}
+	if have, want := string(res.Data), `{"pair":{"price":null}}`; have != want {
+		t.Errorf("result mismatch: have %s, want %s", have, want)
+	}
+}
+
+// Tests that block ranges wider than the allowed maximum are rejected.
+func TestBlocksRange(t *testing.T) {
+	handler, err := newHandler(nil)
+	if err != nil {
+		t.Fatalf("could not create graphql handler: %v", err)
+	}
+	res := handler.Schema.Exec(context.Background(), `{ blocks(from: 0, to: 1024) { number } }`, "", nil)
+	if len(res.Errors) == 0 || !strings.Contains(res.Errors[0].Message, errBlocksRange.Error()) {
+		t.Fatalf("expected range error, have %v", res.Errors)
+	}
+}
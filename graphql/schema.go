@@ -0,0 +1,294 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+const schema string = `
+    # Bytes32 is a 32 byte binary string, represented as 0x-prefixed hexadecimal.
+    scalar Bytes32
+    # Address is a 20 byte Ethereum address, represented as 0x-prefixed hexadecimal.
+    scalar Address
+    # Bytes is an arbitrary length binary string, represented as 0x-prefixed hexadecimal.
+    # An empty byte string is represented as '0x'. Byte strings must have an even number of hexadecimal nybbles.
+    scalar Bytes
+    # BigInt is a large integer. Input is accepted as either a JSON number or as a string.
+    # Strings may be either decimal or 0x-prefixed hexadecimal. Output values are all
+    # 0x-prefixed hexadecimal.
+    scalar BigInt
+    # Long is a 64 bit unsigned integer.
+    scalar Long
+
+    schema {
+        query: Query
+        mutation: Mutation
+    }
+
+    # Account is an Ethereum account at a particular block.
+    type Account {
+        # Address is the address owning the account.
+        address: Address!
+        # Balance is the balance of the account, in wei.
+        balance: BigInt!
+        # TransactionCount is the number of transactions sent from this account,
+        # or in the case of a contract, the number of contracts created. Otherwise
+        # known as the nonce.
+        transactionCount: Long!
+        # Code contains the smart contract code for this account, if the account
+        # is a (non-self-destructed) contract.
+        code: Bytes!
+        # Storage provides access to the storage of a contract account, indexed
+        # by its 32 byte slot identifier.
+        storage(slot: Bytes32!): Bytes32!
+    }
+
+    # Log is an Ethereum event log.
+    type Log {
+        # Index is the index of this log in the block.
+        index: Int!
+        # Account is the account which generated this log - this will always
+        # be a contract account.
+        account(block: Long): Account!
+        # Topics is a list of 0-4 indexed topics for the log.
+        topics: [Bytes32!]!
+        # Data is unindexed data for this log.
+        data: Bytes!
+        # Transaction is the transaction that generated this log entry.
+        transaction: Transaction!
+    }
+
+    # Transaction is an Ethereum transaction.
+    type Transaction {
+        # Hash is the hash of this transaction.
+        hash: Bytes32!
+        # Nonce is the nonce of the account this transaction was generated with.
+        nonce: Long!
+        # Index is the index of this transaction in the parent block. This will
+        # be null if the transaction has not yet been mined.
+        index: Int
+        # From is the account that sent this transaction - this will always be
+        # an externally owned account.
+        from(block: Long): Account!
+        # To is the account the transaction was sent to. This is null for
+        # contract-creating transactions.
+        to(block: Long): Account
+        # Value is the value, in wei, sent along with this transaction.
+        value: BigInt!
+        # GasPrice is the price offered to miners for gas, in wei per unit.
+        gasPrice: BigInt!
+        # MaxFeePerGas is the maximum fee per gas offered to include a dynamic
+        # fee transaction, in wei. It is null for other transaction types.
+        maxFeePerGas: BigInt
+        # MaxPriorityFeePerGas is the maximum miner tip per gas offered to include
+        # a dynamic fee transaction, in wei. It is null for other transaction types.
+        maxPriorityFeePerGas: BigInt
+        # EffectiveGasPrice is the price of gas actually paid by the transaction,
+        # in wei. It is null if the transaction has not yet been mined.
+        effectiveGasPrice: BigInt
+        # Gas is the maximum amount of gas this transaction can consume.
+        gas: Long!
+        # InputData is the data supplied to the target of the transaction.
+        inputData: Bytes!
+        # Block is the block this transaction was mined in. This will be null if
+        # the transaction has not yet been mined.
+        block: Block
+        # Status is the return status of the transaction. This will be 1 if the
+        # transaction succeeded, or 0 if it failed (due to a revert, or due to
+        # running out of gas). If the transaction has not yet been mined, this
+        # field will be null.
+        status: Long
+        # GasUsed is the amount of gas that was used processing this transaction.
+        # If the transaction has not yet been mined, this field will be null.
+        gasUsed: Long
+        # CumulativeGasUsed is the total gas used in the block up to and including
+        # this transaction. If the transaction has not yet been mined, this field
+        # will be null.
+        cumulativeGasUsed: Long
+        # CreatedContract is the account that was created by a contract creation
+        # transaction. If the transaction was not a contract creation transaction,
+        # or it has not yet been mined, this field will be null.
+        createdContract(block: Long): Account
+        # Logs is a list of log entries emitted by this transaction. If the
+        # transaction has not yet been mined, this field will be null.
+        logs: [Log!]
+        # Type is the EIP-2718 type of the transaction.
+        type: Int!
+        # OrderMatches lists the orders processed by the matching engine in this
+        # transaction. It is empty unless this is a TomoX trading transaction.
+        orderMatches: [Order!]!
+        # LendingMatches lists the lending items processed by the lending engine
+        # in this transaction. It is empty unless this is a TomoX lending transaction.
+        lendingMatches: [LendingItem!]!
+    }
+
+    # Block is an Ethereum block.
+    type Block {
+        # Number is the number of this block, starting at 0 for the genesis block.
+        number: Long!
+        # Hash is the block hash of this block.
+        hash: Bytes32!
+        # Parent is the parent block of this block.
+        parent: Block
+        # Nonce is the block nonce, an 8 byte sequence determined by the miner.
+        nonce: Bytes!
+        # TransactionsRoot is the keccak256 hash of the root of the trie of transactions in this block.
+        transactionsRoot: Bytes32!
+        # TransactionCount is the number of transactions in this block.
+        transactionCount: Int!
+        # StateRoot is the keccak256 hash of the state trie after this block was processed.
+        stateRoot: Bytes32!
+        # ReceiptsRoot is the keccak256 hash of the trie of transaction receipts in this block.
+        receiptsRoot: Bytes32!
+        # Miner is the account that mined this block.
+        miner(block: Long): Account!
+        # ExtraData is an arbitrary data field supplied by the miner.
+        extraData: Bytes!
+        # GasLimit is the maximum amount of gas that was available to transactions in this block.
+        gasLimit: Long!
+        # GasUsed is the amount of gas that was used executing transactions in this block.
+        gasUsed: Long!
+        # BaseFeePerGas is the fee per unit of gas burned by the protocol in
+        # this block. It is null for blocks before the EIP-1559 fork.
+        baseFeePerGas: BigInt
+        # Timestamp is the unix timestamp at which this block was mined.
+        timestamp: BigInt!
+        # LogsBloom is a bloom filter that can be used to check if a block may
+        # contain log entries matching a filter.
+        logsBloom: Bytes!
+        # MixHash is the hash that was used as an input to the PoW process.
+        mixHash: Bytes32!
+        # Difficulty is a measure of the difficulty of mining this block.
+        difficulty: BigInt!
+        # TotalDifficulty is the sum of all difficulty values up to and including
+        # this block.
+        totalDifficulty: BigInt!
+        # Transactions is a list of transactions associated with this block.
+        transactions: [Transaction!]
+        # TransactionAt returns the transaction at the specified index.
+        transactionAt(index: Int!): Transaction
+        # Account fetches an Ethereum account at the current block's state.
+        account(address: Address!): Account!
+        # Signers lists the masternodes which signed this block.
+        signers: [Address!]!
+        # Finality is the percentage of masternodes which signed this block.
+        finality: Int!
+        # Trades lists the orders matched by the trading transactions of this block.
+        trades: [Order!]!
+        # LendingTrades lists the lending items matched by the lending
+        # transactions of this block.
+        lendingTrades: [LendingItem!]!
+    }
+
+    # OrderBookLevel is the aggregated volume of an order book at a price.
+    type OrderBookLevel {
+        price: BigInt!
+        volume: BigInt!
+    }
+
+    # Pair is a TomoX trading pair, resolved against the latest block.
+    type Pair {
+        baseToken: Address!
+        quoteToken: Address!
+        # Price is the last matched price of the pair, null if nothing was traded.
+        price: BigInt
+        # Bids lists the buy side of the order book, best price first.
+        bids: [OrderBookLevel!]!
+        # Asks lists the sell side of the order book, best price first.
+        asks: [OrderBookLevel!]!
+    }
+
+    # Order is a TomoX order as processed by the matching engine.
+    type Order {
+        hash: Bytes32!
+        txHash: Bytes32!
+        userAddress: Address!
+        exchangeAddress: Address!
+        baseToken: Address!
+        quoteToken: Address!
+        side: String!
+        type: String!
+        status: String!
+        price: BigInt
+        quantity: BigInt
+        filledAmount: BigInt
+        orderId: Long!
+        # RejectReason is only known by SDK nodes.
+        rejectReason: String
+    }
+
+    # LendingBookLevel is the aggregated volume of a lending book at an interest rate.
+    type LendingBookLevel {
+        interest: BigInt!
+        volume: BigInt!
+    }
+
+    # LendingBook is a TomoX lending book, resolved against the latest block.
+    type LendingBook {
+        lendingToken: Address!
+        term: Long!
+        # Invests lists the investing side of the book, best rate first.
+        invests: [LendingBookLevel!]!
+        # Borrows lists the borrowing side of the book, best rate first.
+        borrows: [LendingBookLevel!]!
+    }
+
+    # LendingItem is a TomoX lending order as processed by the lending engine.
+    type LendingItem {
+        hash: Bytes32!
+        txHash: Bytes32!
+        userAddress: Address!
+        relayer: Address!
+        lendingToken: Address!
+        collateralToken: Address!
+        term: Long!
+        interest: BigInt
+        quantity: BigInt
+        filledAmount: BigInt
+        side: String!
+        type: String!
+        status: String!
+        lendingId: Long!
+        # RejectReason is only known by SDK nodes.
+        rejectReason: String
+    }
+
+    type Query {
+        # Block fetches an Ethereum block by number or by hash. If neither is
+        # supplied, the most recent known block is returned.
+        block(number: Long, hash: Bytes32): Block
+        # Blocks returns all the blocks between two numbers, inclusive. If
+        # to is not supplied, it defaults to the most recent known block.
+        blocks(from: Long!, to: Long): [Block!]!
+        # Transaction returns a transaction specified by its hash.
+        transaction(hash: Bytes32!): Transaction
+        # GasPrice returns the node's estimate of a gas price sufficient to
+        # ensure a transaction is mined in a timely fashion.
+        gasPrice: BigInt!
+        # MaxPriorityFeePerGas returns the node's estimate of a miner tip
+        # sufficient to ensure a transaction is mined in a timely fashion.
+        maxPriorityFeePerGas: BigInt!
+        # ProtocolVersion returns the current wire protocol version number.
+        protocolVersion: Int!
+        # Pair returns the TomoX trading pair of the given tokens.
+        pair(baseToken: Address!, quoteToken: Address!): Pair!
+        # LendingBook returns the TomoX lending book of the given token and term.
+        lendingBook(lendingToken: Address!, term: Long!): LendingBook!
+    }
+
+    type Mutation {
+        # SendRawTransaction sends an RLP-encoded transaction to the network.
+        sendRawTransaction(data: Bytes!): Bytes32!
+    }
+`
@@ -0,0 +1,68 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"github.com/69th-byte/sdexchain/internal/ethapi"
+	"github.com/69th-byte/sdexchain/node"
+	"github.com/69th-byte/sdexchain/p2p"
+	"github.com/69th-byte/sdexchain/rpc"
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+)
+
+// Service is the GraphQL node service. The endpoint itself is served by the
+// HTTP RPC server of the node, the service only ties it to the node's stack.
+type Service struct{}
+
+// New mounts a GraphQL endpoint backed by the given API backend on the HTTP
+// RPC server of the node, under the /graphql path.
+func New(stack *node.Node, backend ethapi.Backend) (*Service, error) {
+	handler, err := newHandler(backend)
+	if err != nil {
+		return nil, err
+	}
+	stack.RegisterHandler("GraphQL", "/graphql", handler)
+	return &Service{}, nil
+}
+
+// newHandler parses the schema against the resolvers of the given backend and
+// returns the HTTP handler executing the queries.
+func newHandler(backend ethapi.Backend) (*relay.Handler, error) {
+	s, err := graphqlgo.ParseSchema(schema, &Resolver{backend})
+	if err != nil {
+		return nil, err
+	}
+	return &relay.Handler{Schema: s}, nil
+}
+
+// Protocols implements node.Service, returning the P2P network protocols used
+// by the GraphQL service (nil as it doesn't use the devp2p overlay network).
+func (s *Service) Protocols() []p2p.Protocol { return nil }
+
+// APIs implements node.Service, returning the RPC API endpoints provided by the
+// GraphQL service (nil as it provides no RPC APIs).
+func (s *Service) APIs() []rpc.API { return nil }
+
+// Start implements node.Service, the endpoint is served by the node itself.
+func (s *Service) Start(server *p2p.Server) error { return nil }
+
+// SaveData implements node.Service, the GraphQL service has no state to persist.
+func (s *Service) SaveData() {}
+
+// Stop implements node.Service, the endpoint is closed with the node's HTTP server.
+func (s *Service) Stop() error { return nil }
@@ -27,6 +27,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/69th-byte/sdexchain/tomox"
 	"github.com/69th-byte/sdexchain/tomox/tradingstate"
 
 	"github.com/69th-byte/sdexchain/accounts"
@@ -35,6 +36,7 @@ import (
 	"github.com/69th-byte/sdexchain/common"
 	"github.com/69th-byte/sdexchain/common/hexutil"
 	"github.com/69th-byte/sdexchain/common/math"
+	"github.com/69th-byte/sdexchain/consensus"
 	"github.com/69th-byte/sdexchain/consensus/ethash"
 	"github.com/69th-byte/sdexchain/consensus/posv"
 	contractValidator "github.com/69th-byte/sdexchain/contracts/validator/contract"
@@ -43,6 +45,7 @@ import (
 	"github.com/69th-byte/sdexchain/core/types"
 	"github.com/69th-byte/sdexchain/core/vm"
 	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/crypto/eip712"
 	"github.com/69th-byte/sdexchain/log"
 	"github.com/69th-byte/sdexchain/p2p"
 	"github.com/69th-byte/sdexchain/params"
@@ -78,11 +81,21 @@ func NewPublicEthereumAPI(b Backend) *PublicEthereumAPI {
 	return &PublicEthereumAPI{b}
 }
 
-// GasPrice returns a suggestion for a gas price.
+// GasPrice returns a suggestion for a gas price. After the EIP-1559 fork this
+// covers the base fee of the latest block plus the suggested tip.
 func (s *PublicEthereumAPI) GasPrice(ctx context.Context) (*big.Int, error) {
 	return s.b.SuggestPrice(ctx)
 }
 
+// MaxPriorityFeePerGas returns a suggestion for a gas tip cap for dynamic fee transactions.
+func (s *PublicEthereumAPI) MaxPriorityFeePerGas(ctx context.Context) (*hexutil.Big, error) {
+	tipcap, err := s.b.SuggestTipCap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(tipcap), err
+}
+
 // ProtocolVersion returns the current Ethereum protocol version this node supports
 func (s *PublicEthereumAPI) ProtocolVersion() hexutil.Uint {
 	return hexutil.Uint(s.b.ProtocolVersion())
@@ -428,8 +441,7 @@ func (s *PrivateAccountAPI) SignTransaction(ctx context.Context, args SendTxArgs
 //
 // This gives context to the signed message and prevents signing of transactions.
 func signHash(data []byte) []byte {
-	msg := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(data), data)
-	return crypto.Keccak256([]byte(msg))
+	return accounts.TextHash(data)
 }
 
 // Sign calculates an Ethereum ECDSA signature for:
@@ -458,6 +470,29 @@ func (s *PrivateAccountAPI) Sign(ctx context.Context, data hexutil.Bytes, addr c
 	return signature, nil
 }
 
+// SignTypedData calculates an Ethereum ECDSA signature of the EIP-712 hash of the
+// given typed data, such as the typed data of an order or lending transaction:
+//
+//	keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message))
+//
+// The key used to calculate the signature is decrypted with the given password.
+func (s *PrivateAccountAPI) SignTypedData(ctx context.Context, data eip712.TypedData, addr common.Address, passwd string) (hexutil.Bytes, error) {
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	// Assemble sign the typed data with the wallet
+	signature, err := wallet.SignTypedDataWithPassphrase(account, passwd, &data)
+	if err != nil {
+		return nil, err
+	}
+	signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	return signature, nil
+}
+
 // EcRecover returns the address for the account that was used to create the signature.
 // Note, this function is compatible with eth_sign and personal_sign. As such it recovers
 // the address of:
@@ -1023,6 +1058,8 @@ type CallArgs struct {
 	GasPrice hexutil.Big     `json:"gasPrice"`
 	Value    hexutil.Big     `json:"value"`
 	Data     hexutil.Bytes   `json:"data"`
+
+	AccessList *types.AccessList `json:"accessList,omitempty"`
 }
 
 func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, bool, error) {
@@ -1032,10 +1069,27 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	if statedb == nil || err != nil {
 		return nil, 0, false, err
 	}
+	block, err := s.b.BlockByNumber(ctx, blockNr)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	author, err := s.b.GetEngine().Author(block.Header())
+	if err != nil {
+		return nil, 0, false, err
+	}
+	tomoxState, err := s.b.TomoxService().GetTradingState(block, author)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	return applyCall(ctx, s.b, args, statedb, tomoxState, header, vmCfg, timeout)
+}
+
+// applyCall executes a call message on top of the given states.
+func applyCall(ctx context.Context, b Backend, args CallArgs, statedb *state.StateDB, tomoxState *tradingstate.TradingStateDB, header *types.Header, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, bool, error) {
 	// Set sender address or use a default if none specified
 	addr := args.From
 	if addr == (common.Address{}) {
-		if wallets := s.b.AccountManager().Wallets(); len(wallets) > 0 {
+		if wallets := b.AccountManager().Wallets(); len(wallets) > 0 {
 			if accounts := wallets[0].Accounts(); len(accounts) > 0 {
 				addr = accounts[0].Address
 			}
@@ -1051,8 +1105,12 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	}
 	balanceTokenFee := big.NewInt(0).SetUint64(gas)
 	balanceTokenFee = balanceTokenFee.Mul(balanceTokenFee, gasPrice)
+	var accessList types.AccessList
+	if args.AccessList != nil {
+		accessList = *args.AccessList
+	}
 	// Create new call message
-	msg := types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false, balanceTokenFee)
+	msg := types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, accessList, false, balanceTokenFee)
 
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
@@ -1066,20 +1124,8 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	// this makes sure resources are cleaned up.
 	defer cancel()
 
-	block, err := s.b.BlockByNumber(ctx, blockNr)
-	if err != nil {
-		return nil, 0, false, err
-	}
-	author, err := s.b.GetEngine().Author(block.Header())
-	if err != nil {
-		return nil, 0, false, err
-	}
-	tomoxState, err := s.b.TomoxService().GetTradingState(block, author)
-	if err != nil {
-		return nil, 0, false, err
-	}
 	// Get a new instance of the EVM.
-	evm, vmError, err := s.b.GetEVM(ctx, msg, statedb, tomoxState, header, vmCfg)
+	evm, vmError, err := b.GetEVM(ctx, msg, statedb, tomoxState, header, vmCfg)
 	if err != nil {
 		return nil, 0, false, err
 	}
@@ -1246,6 +1292,9 @@ func (s *PublicBlockChainAPI) rpcOutputBlock(b *types.Block, inclTx bool, fullTx
 		"validator":        hexutil.Bytes(head.Validator),
 		"penalties":        hexutil.Bytes(head.Penalties),
 	}
+	if head.BaseFee != nil {
+		fields["baseFeePerGas"] = (*hexutil.Big)(head.BaseFee)
+	}
 
 	if inclTx {
 		formatTx := func(tx *types.Transaction) (interface{}, error) {
@@ -1442,14 +1491,22 @@ type RPCTransaction struct {
 	V                *hexutil.Big    `json:"v"`
 	R                *hexutil.Big    `json:"r"`
 	S                *hexutil.Big    `json:"s"`
+	Type             hexutil.Uint64  `json:"type"`
+
+	ChainID   *hexutil.Big      `json:"chainId,omitempty"`
+	Accesses  *types.AccessList `json:"accessList,omitempty"`
+	GasFeeCap *hexutil.Big      `json:"maxFeePerGas,omitempty"`
+	GasTipCap *hexutil.Big      `json:"maxPriorityFeePerGas,omitempty"`
 }
 
 // newRPCTransaction returns a transaction that will serialize to the RPC
-// representation, with the given location metadata set (if available).
-func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber uint64, index uint64) *RPCTransaction {
+// representation, with the given location metadata set (if available). The
+// gas price of dynamic fee transactions included in a block with the given base
+// fee is the effective one.
+func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber uint64, index uint64, baseFee *big.Int) *RPCTransaction {
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
-		signer = types.NewEIP155Signer(tx.ChainId())
+		signer = types.LatestSignerForChainID(tx.ChainId())
 	}
 	from, _ := types.Sender(signer, tx)
 	v, r, s := tx.RawSignatureValues()
@@ -1466,6 +1523,25 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		V:        (*hexutil.Big)(v),
 		R:        (*hexutil.Big)(r),
 		S:        (*hexutil.Big)(s),
+		Type:     hexutil.Uint64(tx.Type()),
+	}
+	switch tx.Type() {
+	case types.AccessListTxType:
+		al := tx.AccessList()
+		result.Accesses = &al
+		result.ChainID = (*hexutil.Big)(tx.ChainId())
+	case types.DynamicFeeTxType:
+		al := tx.AccessList()
+		result.Accesses = &al
+		result.ChainID = (*hexutil.Big)(tx.ChainId())
+		result.GasFeeCap = (*hexutil.Big)(tx.GasFeeCap())
+		result.GasTipCap = (*hexutil.Big)(tx.GasTipCap())
+		// Pending transactions report their fee cap as gas price.
+		if baseFee != nil && blockHash != (common.Hash{}) {
+			result.GasPrice = (*hexutil.Big)(effectiveGasPrice(tx, baseFee))
+		}
+	case types.SystemTxType:
+		result.ChainID = (*hexutil.Big)(tx.ChainId())
 	}
 	if blockHash != (common.Hash{}) {
 		result.BlockHash = blockHash
@@ -1477,7 +1553,16 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 
 // newRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
 func newRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
-	return newRPCTransaction(tx, common.Hash{}, 0, 0)
+	return newRPCTransaction(tx, common.Hash{}, 0, 0, nil)
+}
+
+// effectiveGasPrice returns the gas price paid by tx in a block with the given
+// base fee.
+func effectiveGasPrice(tx *types.Transaction, baseFee *big.Int) *big.Int {
+	if baseFee == nil || tx.Type() != types.DynamicFeeTxType {
+		return tx.GasPrice()
+	}
+	return math.BigMin(new(big.Int).Add(tx.GasTipCap(), baseFee), tx.GasFeeCap())
 }
 
 // newRPCTransactionFromBlockIndex returns a transaction that will serialize to the RPC representation.
@@ -1486,7 +1571,7 @@ func newRPCTransactionFromBlockIndex(b *types.Block, index uint64) *RPCTransacti
 	if index >= uint64(len(txs)) {
 		return nil
 	}
-	return newRPCTransaction(txs[index], b.Hash(), b.NumberU64(), index)
+	return newRPCTransaction(txs[index], b.Hash(), b.NumberU64(), index, b.BaseFee())
 }
 
 // newRPCRawTransactionFromBlockIndex returns the bytes of a transaction given a block and a transaction index.
@@ -1595,7 +1680,11 @@ func (s *PublicTransactionPoolAPI) GetTransactionCount(ctx context.Context, addr
 func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, hash common.Hash) *RPCTransaction {
 	// Try to return an already finalized transaction
 	if tx, blockHash, blockNumber, index := core.GetTransaction(s.b.ChainDb(), hash); tx != nil {
-		return newRPCTransaction(tx, blockHash, blockNumber, index)
+		var baseFee *big.Int
+		if block, _ := s.b.GetBlock(ctx, blockHash); block != nil {
+			baseFee = block.BaseFee()
+		}
+		return newRPCTransaction(tx, blockHash, blockNumber, index, baseFee)
 	}
 	// No finalized transaction, try to retrieve it from the pool
 	if tx := s.b.GetPoolTransaction(hash); tx != nil {
@@ -1637,7 +1726,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
-		signer = types.NewEIP155Signer(tx.ChainId())
+		signer = types.LatestSignerForChainID(tx.ChainId())
 	}
 	from, _ := types.Sender(signer, tx)
 
@@ -1653,6 +1742,12 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 		"contractAddress":   nil,
 		"logs":              receipt.Logs,
 		"logsBloom":         receipt.Bloom,
+		"type":              hexutil.Uint(tx.Type()),
+	}
+	// Report the gas price actually paid, which depends on the base fee for
+	// dynamic fee transactions.
+	if block, _ := s.b.GetBlock(ctx, blockHash); block != nil {
+		fields["effectiveGasPrice"] = (*hexutil.Big)(effectiveGasPrice(tx, block.BaseFee()))
 	}
 
 	// Assign receipt status or post state.
@@ -1700,6 +1795,14 @@ type SendTxArgs struct {
 	// newer name and should be preferred by clients.
 	Data  *hexutil.Bytes `json:"data"`
 	Input *hexutil.Bytes `json:"input"`
+
+	// For non-legacy transactions
+	AccessList *types.AccessList `json:"accessList,omitempty"`
+	ChainID    *hexutil.Big      `json:"chainId,omitempty"`
+
+	// For dynamic fee transactions
+	MaxFeePerGas         *hexutil.Big `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big `json:"maxPriorityFeePerGas,omitempty"`
 }
 
 // setDefaults is a helper function that fills in default values for unspecified tx fields.
@@ -1708,12 +1811,8 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 		args.Gas = new(hexutil.Uint64)
 		*(*uint64)(args.Gas) = 90000
 	}
-	if args.GasPrice == nil {
-		price, err := b.SuggestPrice(ctx)
-		if err != nil {
-			return err
-		}
-		args.GasPrice = (*hexutil.Big)(price)
+	if err := args.setFeeDefaults(ctx, b); err != nil {
+		return err
 	}
 	if args.Value == nil {
 		args.Value = new(hexutil.Big)
@@ -1740,6 +1839,58 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 			return errors.New(`contract creation without any data provided`)
 		}
 	}
+	if args.AccessList != nil {
+		if config := b.ChainConfig(); !config.IsEIP2718(b.CurrentBlock().Number()) {
+			return errors.New("access list transactions are not activated yet")
+		}
+		if args.ChainID == nil {
+			args.ChainID = (*hexutil.Big)(b.ChainConfig().ChainId)
+		}
+	}
+	return nil
+}
+
+// setFeeDefaults fills in the gas price of legacy transactions, or the fee cap
+// and tip of dynamic fee transactions, from the gas price oracle.
+func (args *SendTxArgs) setFeeDefaults(ctx context.Context, b Backend) error {
+	if args.MaxFeePerGas == nil && args.MaxPriorityFeePerGas == nil {
+		if args.GasPrice == nil {
+			price, err := b.SuggestPrice(ctx)
+			if err != nil {
+				return err
+			}
+			args.GasPrice = (*hexutil.Big)(price)
+		}
+		return nil
+	}
+	if args.GasPrice != nil {
+		return errors.New("both gasPrice and (maxFeePerGas or maxPriorityFeePerGas) specified")
+	}
+	head := b.CurrentBlock().Header()
+	if !b.ChainConfig().IsEIP1559(head.Number) {
+		return errors.New("dynamic fee transactions are not activated yet")
+	}
+	if args.MaxPriorityFeePerGas == nil {
+		tip, err := b.SuggestTipCap(ctx)
+		if err != nil {
+			return err
+		}
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tip)
+	}
+	if args.MaxFeePerGas == nil {
+		// Leave room for the base fee to double before the transaction is mined.
+		feeCap := new(big.Int).Add(
+			(*big.Int)(args.MaxPriorityFeePerGas),
+			new(big.Int).Mul(head.BaseFee, big.NewInt(2)),
+		)
+		args.MaxFeePerGas = (*hexutil.Big)(feeCap)
+	}
+	if args.MaxFeePerGas.ToInt().Cmp(args.MaxPriorityFeePerGas.ToInt()) < 0 {
+		return fmt.Errorf("maxFeePerGas (%v) < maxPriorityFeePerGas (%v)", args.MaxFeePerGas, args.MaxPriorityFeePerGas)
+	}
+	if args.ChainID == nil {
+		args.ChainID = (*hexutil.Big)(b.ChainConfig().ChainId)
+	}
 	return nil
 }
 
@@ -1750,6 +1901,35 @@ func (args *SendTxArgs) toTransaction() *types.Transaction {
 	} else if args.Input != nil {
 		input = *args.Input
 	}
+	if args.MaxFeePerGas != nil {
+		var al types.AccessList
+		if args.AccessList != nil {
+			al = *args.AccessList
+		}
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:    (*big.Int)(args.ChainID),
+			Nonce:      uint64(*args.Nonce),
+			GasTipCap:  (*big.Int)(args.MaxPriorityFeePerGas),
+			GasFeeCap:  (*big.Int)(args.MaxFeePerGas),
+			Gas:        uint64(*args.Gas),
+			To:         args.To,
+			Value:      (*big.Int)(args.Value),
+			Data:       input,
+			AccessList: al,
+		})
+	}
+	if args.AccessList != nil {
+		return types.NewTx(&types.AccessListTx{
+			ChainID:    (*big.Int)(args.ChainID),
+			Nonce:      uint64(*args.Nonce),
+			GasPrice:   (*big.Int)(args.GasPrice),
+			Gas:        uint64(*args.Gas),
+			To:         args.To,
+			Value:      (*big.Int)(args.Value),
+			Data:       input,
+			AccessList: *args.AccessList,
+		})
+	}
 	if args.To == nil {
 		return types.NewContractCreation(uint64(*args.Nonce), (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input)
 	}
@@ -1882,12 +2062,29 @@ func (s *PublicTomoXTransactionPoolAPI) GetOrderTxMatchByHash(ctx context.Contex
 		if err != nil {
 			return []*tradingstate.OrderItem{}, err
 		}
+		s.fillOrderRejectReason(hash, order)
 		orders = append(orders, order)
 	}
 	return orders, nil
 
 }
 
+// fillOrderRejectReason copies the reject reason of the order processed at the given txhash
+// from the SDK database. The reason is not a part of the tx data, it is only known by SDK nodes
+func (s *PublicTomoXTransactionPoolAPI) fillOrderRejectReason(txHash common.Hash, order *tradingstate.OrderItem) {
+	tomoxService := s.b.TomoxService()
+	if tomoxService == nil || !tomoxService.IsSDKNode() {
+		return
+	}
+	val, err := tomoxService.GetMongoDB().GetObject(order.Hash, &tradingstate.OrderItem{})
+	if err != nil || val == nil {
+		return
+	}
+	if stored := val.(*tradingstate.OrderItem); stored.TxHash == txHash && stored.Status == tradingstate.OrderStatusRejected {
+		order.RejectReason = stored.RejectReason
+	}
+}
+
 // GetOrderPoolContent return pending, queued content
 func (s *PublicTomoXTransactionPoolAPI) GetOrderPoolContent(ctx context.Context) interface{} {
 	pendingOrders := []*tradingstate.OrderItem{}
@@ -1910,6 +2107,7 @@ func (s *PublicTomoXTransactionPoolAPI) GetOrderPoolContent(ctx context.Context)
 				Type:            tx.Type(),
 				Hash:            tx.OrderHash(),
 				OrderID:         tx.OrderID(),
+				Route:           tx.Route(),
 				Signature: &tradingstate.Signature{
 					V: byte(V.Uint64()),
 					R: common.BigToHash(R),
@@ -1936,6 +2134,7 @@ func (s *PublicTomoXTransactionPoolAPI) GetOrderPoolContent(ctx context.Context)
 				Type:            tx.Type(),
 				Hash:            tx.OrderHash(),
 				OrderID:         tx.OrderID(),
+				Route:           tx.Route(),
 				Signature: &tradingstate.Signature{
 					V: byte(V.Uint64()),
 					R: common.BigToHash(R),
@@ -1974,6 +2173,8 @@ type OrderMsg struct {
 	Side            string         `json:"side,omitempty"`
 	Type            string         `json:"type,omitempty"`
 	OrderID         hexutil.Uint64 `json:"orderid,omitempty"`
+	// Route is the token path of a routed market order
+	Route []common.Address `json:"route,omitempty"`
 	// Signature values
 	V hexutil.Big `json:"v" gencodec:"required"`
 	R hexutil.Big `json:"r" gencodec:"required"`
@@ -2021,21 +2222,79 @@ type InterestVolume struct {
 }
 
 // SendOrder will add the signed transaction to the transaction pool.
-// The sender is responsible for signing the transaction and using the correct nonce.
+// The sender is responsible for using the correct nonce. If the message carries
+// no signature, the order is signed by the wallet holding the user address, which
+// may be a hardware wallet asking the user for confirmation.
 func (s *PublicTomoXTransactionPoolAPI) SendOrder(ctx context.Context, msg OrderMsg) (common.Hash, error) {
 	tx := types.NewOrderTransaction(uint64(msg.AccountNonce), msg.Quantity.ToInt(), msg.Price.ToInt(), msg.ExchangeAddress, msg.UserAddress, msg.BaseToken, msg.QuoteToken, msg.Status, msg.Side, msg.Type, msg.Hash, uint64(msg.OrderID))
-	tx = tx.ImportSignature(msg.V.ToInt(), msg.R.ToInt(), msg.S.ToInt())
+	if len(msg.Route) > 0 {
+		tx.SetRoute(msg.Route)
+	}
+	if isUnsigned(msg.V, msg.R, msg.S) {
+		hash := types.OrderTxSigner{}.Hash(tx)
+		if msg.Hash == (common.Hash{}) {
+			tx.SetOrderHash(hash)
+		} else if !tx.IsCancelledOrder() && msg.Hash != hash {
+			// Don't ask the user to confirm an order the pool would reject
+			return common.Hash{}, core.ErrInvalidOrderHash
+		}
+		sig, err := s.signText(msg.UserAddress, hash)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		if tx, err = tx.WithSignature(types.OrderTxSigner{}, sig); err != nil {
+			return common.Hash{}, err
+		}
+	} else {
+		tx = tx.ImportSignature(msg.V.ToInt(), msg.R.ToInt(), msg.S.ToInt())
+	}
 	return submitOrderTransaction(ctx, s.b, tx)
 }
 
 // SendLending will add the signed transaction to the transaction pool.
-// The sender is responsible for signing the transaction and using the correct nonce.
+// The sender is responsible for using the correct nonce. If the message carries
+// no signature, the lending is signed by the wallet holding the user address,
+// which may be a hardware wallet asking the user for confirmation.
 func (s *PublicTomoXTransactionPoolAPI) SendLending(ctx context.Context, msg LendingMsg) (common.Hash, error) {
 	tx := types.NewLendingTransaction(uint64(msg.AccountNonce), msg.Quantity.ToInt(), uint64(msg.Interest), uint64(msg.Term), msg.RelayerAddress, msg.UserAddress, msg.LendingToken, msg.CollateralToken, msg.AutoTopUp, msg.Status, msg.Side, msg.Type, msg.Hash, uint64(msg.LendingId), uint64(msg.LendingTradeId), msg.ExtraData)
-	tx = tx.ImportSignature(msg.V.ToInt(), msg.R.ToInt(), msg.S.ToInt())
+	if isUnsigned(msg.V, msg.R, msg.S) {
+		hash := types.LendingTxSigner{}.Hash(tx)
+		if msg.Hash == (common.Hash{}) {
+			tx.SetLendingHash(hash)
+		} else if tx.IsCreatedLending() && msg.Hash != hash {
+			// Don't ask the user to confirm a lending the pool would reject
+			return common.Hash{}, core.ErrInvalidLendingHash
+		}
+		sig, err := s.signText(msg.UserAddress, hash)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		if tx, err = tx.WithSignature(types.LendingTxSigner{}, sig); err != nil {
+			return common.Hash{}, err
+		}
+	} else {
+		tx = tx.ImportSignature(msg.V.ToInt(), msg.R.ToInt(), msg.S.ToInt())
+	}
 	return submitLendingTransaction(ctx, s.b, tx)
 }
 
+// isUnsigned reports whether an order or lending message carries no signature.
+func isUnsigned(V, R, S hexutil.Big) bool {
+	return V.ToInt().Sign() == 0 && R.ToInt().Sign() == 0 && S.ToInt().Sign() == 0
+}
+
+// signText signs the hash of an order or lending transaction as a personal
+// message with the wallet holding the user address.
+func (s *PublicTomoXTransactionPoolAPI) signText(user common.Address, hash common.Hash) ([]byte, error) {
+	account := accounts.Account{Address: user}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.SignText(account, hash.Bytes())
+}
+
 // GetOrderCount returns the number of transactions the given address has sent for the given block number
 func (s *PublicTomoXTransactionPoolAPI) GetOrderCount(ctx context.Context, addr common.Address) (*hexutil.Uint64, error) {
 
@@ -2608,9 +2867,28 @@ func (s *PublicTomoXTransactionPoolAPI) GetLendingTxMatchByHash(ctx context.Cont
 	if err != nil {
 		return []*lendingstate.LendingItem{}, err
 	}
+	for _, item := range batch.Data {
+		s.fillLendingRejectReason(hash, item)
+	}
 	return batch.Data, nil
 }
 
+// fillLendingRejectReason copies the reject reason of the lendingItem processed at the given txhash
+// from the SDK database. The reason is not a part of the tx data, it is only known by SDK nodes
+func (s *PublicTomoXTransactionPoolAPI) fillLendingRejectReason(txHash common.Hash, item *lendingstate.LendingItem) {
+	tomoxService := s.b.TomoxService()
+	if tomoxService == nil || !tomoxService.IsSDKNode() {
+		return
+	}
+	val, err := tomoxService.GetMongoDB().GetObject(item.Hash, &lendingstate.LendingItem{Type: item.Type})
+	if err != nil || val == nil {
+		return
+	}
+	if stored := val.(*lendingstate.LendingItem); stored.TxHash == txHash && stored.Status == lendingstate.LendingStatusReject {
+		item.RejectReason = stored.RejectReason
+	}
+}
+
 // GetLiquidatedTradesByTxHash returns trades which closed by TomoX protocol at the tx of the give hash
 func (s *PublicTomoXTransactionPoolAPI) GetLiquidatedTradesByTxHash(ctx context.Context, hash common.Hash) (lendingstate.FinalizedResult, error) {
 	var tx *types.Transaction
@@ -2682,6 +2960,277 @@ func (s *PublicTomoXTransactionPoolAPI) GetLendingTradeById(ctx context.Context,
 	return lendingItem, nil
 }
 
+// apiChainContext implements consensus.ChainContext on top of Backend
+type apiChainContext struct {
+	ctx context.Context
+	b   Backend
+}
+
+func (c *apiChainContext) Engine() consensus.Engine {
+	return c.b.GetEngine()
+}
+
+func (c *apiChainContext) GetHeader(hash common.Hash, number uint64) *types.Header {
+	header, err := c.b.HeaderByNumber(c.ctx, rpc.BlockNumber(number))
+	if err != nil || header == nil || header.Hash() != hash {
+		return nil
+	}
+	return header
+}
+
+func (c *apiChainContext) CurrentHeader() *types.Header {
+	return c.b.CurrentBlock().Header()
+}
+
+func (c *apiChainContext) Config() *params.ChainConfig {
+	return c.b.ChainConfig()
+}
+
+// CheckTokenCompatibility runs the checks of TomoX/TomoZ apply transactions against the token at the given block,
+// so that token issuers can find out incompatible tokens before paying for an apply transaction
+func (s *PublicTomoXTransactionPoolAPI) CheckTokenCompatibility(ctx context.Context, token common.Address, blockNr rpc.BlockNumber) (*core.TokenCompatibility, error) {
+	statedb, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	if len(statedb.GetCode(token)) == 0 {
+		return nil, fmt.Errorf("no contract code at %s", token.Hex())
+	}
+	return core.CheckTokenCompatibility(&apiChainContext{ctx: ctx, b: s.b}, header, statedb, token)
+}
+
+// GetRouteQuote finds the route with the best output to swap amount of fromToken to toToken
+// through at most 3 order books at the current block, without sending any order.
+// Only pairs listed by the exchange are used if it's given, otherwise pairs of all relayers are used without fee
+func (s *PublicTomoXTransactionPoolAPI) GetRouteQuote(ctx context.Context, fromToken, toToken common.Address, amount hexutil.Big, exchange *common.Address) (*tomox.RouteQuote, error) {
+	block := s.b.CurrentBlock()
+	if block == nil {
+		return nil, errors.New("Current block not found")
+	}
+	tomoxService := s.b.TomoxService()
+	if tomoxService == nil {
+		return nil, errors.New("TomoX service not found")
+	}
+	author, err := s.b.GetEngine().Author(block.Header())
+	if err != nil {
+		return nil, err
+	}
+	tomoxState, err := tomoxService.GetTradingState(block, author)
+	if err != nil {
+		return nil, err
+	}
+	statedb, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.BlockNumber(block.NumberU64()))
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	var relayer common.Address
+	if exchange != nil {
+		relayer = *exchange
+		if !tradingstate.IsValidRelayer(statedb, relayer) {
+			return nil, fmt.Errorf("invalid relayer: %s", relayer.Hex())
+		}
+	}
+	return tomoxService.GetRouteQuote(&apiChainContext{ctx: ctx, b: s.b}, statedb, tomoxState, relayer, fromToken, toToken, amount.ToInt())
+}
+
+// GetTWAP returns the time-weighted average price of a pair over window seconds before the current block,
+// with the volume and the number of trades recorded by the price oracle of the pair
+func (s *PublicTomoXTransactionPoolAPI) GetTWAP(ctx context.Context, baseToken, quoteToken common.Address, window hexutil.Uint64) (*tradingstate.PriceOracleResult, error) {
+	block := s.b.CurrentBlock()
+	if block == nil {
+		return nil, errors.New("Current block not found")
+	}
+	tomoxService := s.b.TomoxService()
+	if tomoxService == nil {
+		return nil, errors.New("TomoX service not found")
+	}
+	author, err := s.b.GetEngine().Author(block.Header())
+	if err != nil {
+		return nil, err
+	}
+	tomoxState, err := tomoxService.GetTradingState(block, author)
+	if err != nil {
+		return nil, err
+	}
+	oracle := tomoxState.GetPriceOracle(tradingstate.GetTradingOrderBookHash(baseToken, quoteToken))
+	return oracle.GetTWAP(block.Time().Uint64(), uint64(window))
+}
+
+const (
+	// relayerInfoEpochs is the number of epochs, including the current one, reported by GetRelayerInfo
+	relayerInfoEpochs = 4
+	// relayerLowFeeTrades warns relayer if its deposit can only pay fee for less than this number of trades
+	relayerLowFeeTrades = 10000
+)
+
+// RelayerPair is a trading pair listed by a relayer
+type RelayerPair struct {
+	BaseToken  common.Address `json:"baseToken"`
+	QuoteToken common.Address `json:"quoteToken"`
+}
+
+// RelayerLendingTerm is a lending token and term listed by a relayer
+type RelayerLendingTerm struct {
+	LendingToken common.Address `json:"lendingToken"`
+	Term         uint64         `json:"term"`
+}
+
+// RelayerPairVolume is the matched volume of a trading pair in an epoch
+type RelayerPairVolume struct {
+	BaseToken  common.Address `json:"baseToken"`
+	QuoteToken common.Address `json:"quoteToken"`
+	Volume     *big.Int       `json:"volume"`
+	Trades     uint64         `json:"trades"`
+}
+
+// RelayerEpochStats is the fee income and volume of a relayer in an epoch
+type RelayerEpochStats struct {
+	Epoch          uint64                      `json:"epoch"`
+	FromBlock      uint64                      `json:"fromBlock"`
+	ToBlock        uint64                      `json:"toBlock"`
+	FeeIncome      map[common.Address]*big.Int `json:"feeIncome"`
+	RelayerFeePaid *big.Int                    `json:"relayerFeePaid"`
+	Volumes        []*RelayerPairVolume        `json:"volumes"`
+}
+
+// RelayerInfo is the registration and fee accounting of a relayer
+type RelayerInfo struct {
+	Relayer       common.Address       `json:"relayer"`
+	Owner         common.Address       `json:"owner"`
+	Deposit       *big.Int             `json:"deposit"`
+	LockedFund    *big.Int             `json:"lockedFund"`
+	RemainingFee  *big.Int             `json:"remainingFee"`
+	TradingFee    *big.Int             `json:"tradingFee"`
+	Resigned      bool                 `json:"resigned"`
+	Pairs         []RelayerPair        `json:"pairs"`
+	LendingFee    *big.Int             `json:"lendingFee"`
+	LendingTerms  []RelayerLendingTerm `json:"lendingTerms"`
+	LowFeeWarning string               `json:"lowFeeWarning,omitempty"`
+	Epochs        []*RelayerEpochStats `json:"epochs,omitempty"`
+}
+
+// GetRelayerInfo returns owner, deposit, remaining fee balance, listed pairs and lending terms of the relayer.
+// On SDK nodes, fee income and volume of the latest epochs are also returned
+func (s *PublicTomoXTransactionPoolAPI) GetRelayerInfo(ctx context.Context, relayer common.Address) (*RelayerInfo, error) {
+	statedb, header, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	// resigned relayers and relayers running out of fee are still reported, so IsValidRelayer is not used here
+	owner := tradingstate.GetRelayerOwner(relayer, statedb)
+	if owner == (common.Address{}) {
+		return nil, errors.New("Relayer not found")
+	}
+	deposit := tradingstate.GetRelayerDeposit(relayer, statedb)
+	info := &RelayerInfo{
+		Relayer:      relayer,
+		Owner:        owner,
+		Deposit:      deposit,
+		LockedFund:   new(big.Int).Mul(common.BasePrice, common.RelayerLockedFund),
+		RemainingFee: tradingstate.GetRelayerSpareFee(deposit),
+		TradingFee:   tradingstate.GetExRelayerFee(relayer, statedb),
+		Resigned:     tradingstate.IsResignedRelayer(relayer, statedb),
+		Pairs:        []RelayerPair{},
+		LendingFee:   lendingstate.GetFee(statedb, relayer),
+		LendingTerms: []RelayerLendingTerm{},
+	}
+	baseLength := tradingstate.GetBaseTokenLength(relayer, statedb)
+	quoteLength := tradingstate.GetQuoteTokenLength(relayer, statedb)
+	if baseLength != quoteLength {
+		return nil, fmt.Errorf("Invalid length from token & to token : from :%d , to :%d ", baseLength, quoteLength)
+	}
+	for i := uint64(0); i < baseLength; i++ {
+		info.Pairs = append(info.Pairs, RelayerPair{
+			BaseToken:  tradingstate.GetBaseTokenAtIndex(relayer, statedb, i),
+			QuoteToken: tradingstate.GetQuoteTokenAtIndex(relayer, statedb, i),
+		})
+	}
+	bases := lendingstate.GetBaseList(statedb, relayer)
+	terms := lendingstate.GetTerms(statedb, relayer)
+	for i := 0; i < len(bases) && i < len(terms); i++ {
+		info.LendingTerms = append(info.LendingTerms, RelayerLendingTerm{LendingToken: bases[i], Term: terms[i]})
+	}
+	if tradingstate.IsRelayerFeeLow(deposit, relayerLowFeeTrades) {
+		info.LowFeeWarning = fmt.Sprintf("remaining fee %v can pay for less than %d trades, orders will be rejected when deposit goes below %v", info.RemainingFee, relayerLowFeeTrades, info.LockedFund)
+	}
+
+	tomoxService := s.b.TomoxService()
+	if tomoxService == nil || !tomoxService.IsSDKNode() || s.b.ChainConfig().Posv == nil {
+		return info, nil
+	}
+	epoch := s.b.ChainConfig().Posv.Epoch
+	current := header.Number.Uint64() / epoch
+	for i := uint64(0); i < relayerInfoEpochs && i <= current; i++ {
+		stats, err := s.getRelayerEpochStats(ctx, relayer, current-i, header)
+		if err != nil {
+			return nil, err
+		}
+		info.Epochs = append(info.Epochs, stats)
+	}
+	return info, nil
+}
+
+// getRelayerEpochStats sums fee income and volume of the trades matched by relayer in the given epoch.
+// Trades are read from the SDK database by their block time
+func (s *PublicTomoXTransactionPoolAPI) getRelayerEpochStats(ctx context.Context, relayer common.Address, epochNumber uint64, head *types.Header) (*RelayerEpochStats, error) {
+	epoch := s.b.ChainConfig().Posv.Epoch
+	stats := &RelayerEpochStats{
+		Epoch:          epochNumber,
+		FromBlock:      epochNumber * epoch,
+		ToBlock:        (epochNumber+1)*epoch - 1,
+		FeeIncome:      map[common.Address]*big.Int{},
+		RelayerFeePaid: new(big.Int),
+		Volumes:        []*RelayerPairVolume{},
+	}
+	fromHeader, err := s.b.HeaderByNumber(ctx, rpc.BlockNumber(stats.FromBlock))
+	if err != nil || fromHeader == nil {
+		return nil, fmt.Errorf("header %d not found", stats.FromBlock)
+	}
+	from := time.Unix(fromHeader.Time.Int64(), 0).UTC()
+	// the current epoch ends at the head block, trades of the head block are included
+	to := time.Unix(head.Time.Int64()+1, 0).UTC()
+	if stats.ToBlock < head.Number.Uint64() {
+		toHeader, err := s.b.HeaderByNumber(ctx, rpc.BlockNumber(stats.ToBlock+1))
+		if err != nil || toHeader == nil {
+			return nil, fmt.Errorf("header %d not found", stats.ToBlock+1)
+		}
+		to = time.Unix(toHeader.Time.Int64(), 0).UTC()
+	}
+	volumes := map[common.Hash]*RelayerPairVolume{}
+	for _, trade := range s.b.TomoxService().GetMongoDB().GetTradesByExchange(relayer, from, to) {
+		if trade.MakerExchange == relayer {
+			addRelayerFeeIncome(stats.FeeIncome, trade.QuoteToken, trade.MakeFee)
+			stats.RelayerFeePaid.Add(stats.RelayerFeePaid, common.RelayerFee)
+		}
+		if trade.TakerExchange == relayer {
+			addRelayerFeeIncome(stats.FeeIncome, trade.QuoteToken, trade.TakeFee)
+			stats.RelayerFeePaid.Add(stats.RelayerFeePaid, common.RelayerFee)
+		}
+		pairHash := tradingstate.GetTradingOrderBookHash(trade.BaseToken, trade.QuoteToken)
+		volume, ok := volumes[pairHash]
+		if !ok {
+			volume = &RelayerPairVolume{BaseToken: trade.BaseToken, QuoteToken: trade.QuoteToken, Volume: new(big.Int)}
+			volumes[pairHash] = volume
+			stats.Volumes = append(stats.Volumes, volume)
+		}
+		if trade.Amount != nil {
+			volume.Volume.Add(volume.Volume, trade.Amount)
+		}
+		volume.Trades++
+	}
+	return stats, nil
+}
+
+func addRelayerFeeIncome(income map[common.Address]*big.Int, token common.Address, fee *big.Int) {
+	if fee == nil {
+		return
+	}
+	if _, ok := income[token]; !ok {
+		income[token] = new(big.Int)
+	}
+	income[token].Add(income[token], fee)
+}
+
 // Sign calculates an ECDSA signature for:
 // keccack256("\x19Ethereum Signed Message:\n" + len(message) + message).
 //
@@ -2707,6 +3256,25 @@ func (s *PublicTransactionPoolAPI) Sign(addr common.Address, data hexutil.Bytes)
 	return signature, err
 }
 
+// SignTypedData calculates an ECDSA signature of the EIP-712 hash of the given
+// typed data, such as the typed data of an order or lending transaction. The
+// account associated with addr must be unlocked.
+func (s *PublicTransactionPoolAPI) SignTypedData(addr common.Address, data eip712.TypedData) (hexutil.Bytes, error) {
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	// Sign the typed data with the wallet
+	signature, err := wallet.SignTypedData(account, &data)
+	if err == nil {
+		signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	}
+	return signature, err
+}
+
 // SignTransactionResult represents a RLP encoded signed transaction.
 type SignTransactionResult struct {
 	Raw hexutil.Bytes      `json:"raw"`
@@ -2752,7 +3320,7 @@ func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, err
 	for _, tx := range pending {
 		var signer types.Signer = types.HomesteadSigner{}
 		if tx.Protected() {
-			signer = types.NewEIP155Signer(tx.ChainId())
+			signer = types.LatestSignerForChainID(tx.ChainId())
 		}
 		from, _ := types.Sender(signer, tx)
 		if _, err := s.b.AccountManager().Find(accounts.Account{Address: from}); err == nil {
@@ -2780,7 +3348,7 @@ func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs SendTxAr
 	for _, p := range pending {
 		var signer types.Signer = types.HomesteadSigner{}
 		if p.Protected() {
-			signer = types.NewEIP155Signer(p.ChainId())
+			signer = types.LatestSignerForChainID(p.ChainId())
 		}
 		wantSigHash := signer.Hash(matchTx)
 
@@ -0,0 +1,174 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/accounts"
+	"github.com/69th-byte/sdexchain/accounts/keystore"
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/common/hexutil"
+	"github.com/69th-byte/sdexchain/core"
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/params"
+	"github.com/69th-byte/sdexchain/tomox/tradingstate"
+	"github.com/69th-byte/sdexchain/tomoxlending/lendingstate"
+)
+
+// walletBackend is an API backend holding the accounts of a keystore and
+// collecting the order and lending transactions submitted to the pools.
+type walletBackend struct {
+	Backend
+	manager  *accounts.Manager
+	orders   []*types.OrderTransaction
+	lendings []*types.LendingTransaction
+}
+
+func (b *walletBackend) ChainConfig() *params.ChainConfig  { return params.TestChainConfig }
+func (b *walletBackend) AccountManager() *accounts.Manager { return b.manager }
+
+func (b *walletBackend) SendOrderTx(ctx context.Context, tx *types.OrderTransaction) error {
+	b.orders = append(b.orders, tx)
+	return nil
+}
+
+func (b *walletBackend) SendLendingTx(ctx context.Context, tx *types.LendingTransaction) error {
+	b.lendings = append(b.lendings, tx)
+	return nil
+}
+
+// newWalletBackend creates a backend with the maker's key imported into a keystore.
+func newWalletBackend(t *testing.T, dir string) (*walletBackend, *keystore.KeyStore) {
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	if _, err := ks.ImportECDSA(simMakerKey, ""); err != nil {
+		t.Fatalf("failed to import key: %v", err)
+	}
+	return &walletBackend{manager: accounts.NewManager(ks)}, ks
+}
+
+// Tests that unsigned orders are signed by the wallet of the user, the way the
+// order pool verifies them both before and after the typed data fork.
+func TestSendOrderWalletSigning(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ethapi-wallet")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	backend, ks := newWalletBackend(t, dir)
+	api := NewPublicTomoXTransactionPoolAPI(backend, nil)
+
+	msg := OrderMsg{
+		Quantity:        hexutil.Big(*common.BasePrice),
+		Price:           hexutil.Big(*common.BasePrice),
+		ExchangeAddress: simRelayer,
+		UserAddress:     simMaker,
+		BaseToken:       simBaseToken,
+		QuoteToken:      simQuoteToken,
+		Status:          tradingstate.OrderNew,
+		Side:            tradingstate.Ask,
+		Type:            tradingstate.Limit,
+	}
+	if _, err := api.SendOrder(context.Background(), msg); err != keystore.ErrLocked {
+		t.Fatalf("locked signing error mismatch: have %v, want %v", err, keystore.ErrLocked)
+	}
+	if err := ks.Unlock(accounts.Account{Address: simMaker}, ""); err != nil {
+		t.Fatalf("failed to unlock account: %v", err)
+	}
+	// A hash not matching the order must be rejected before the wallet is asked
+	forged := msg
+	forged.Hash = common.HexToHash("0x01")
+	if _, err := api.SendOrder(context.Background(), forged); err != core.ErrInvalidOrderHash {
+		t.Fatalf("forged hash error mismatch: have %v, want %v", err, core.ErrInvalidOrderHash)
+	}
+	if len(backend.orders) != 0 {
+		t.Fatalf("forged order submitted")
+	}
+	if _, err := api.SendOrder(context.Background(), msg); err != nil {
+		t.Fatalf("failed to send order: %v", err)
+	}
+	if len(backend.orders) != 1 {
+		t.Fatalf("submitted order count mismatch: have %d, want 1", len(backend.orders))
+	}
+	tx := backend.orders[0]
+	if hash := (types.OrderTxSigner{}).Hash(tx); tx.OrderHash() != hash {
+		t.Errorf("order hash mismatch: have %x, want %x", tx.OrderHash(), hash)
+	}
+	for _, number := range []*big.Int{common.Big0, common.TIPTomoXTypedData} {
+		signer := types.MakeOrderSigner(params.TestChainConfig, number)
+		if from, err := types.OrderSender(signer, tx); err != nil || from != simMaker {
+			t.Errorf("block %v: sender mismatch: have %x (%v), want %x", number, from, err, simMaker)
+		}
+	}
+}
+
+// Tests that unsigned lendings are signed by the wallet of the user, the way the
+// lending pool verifies them both before and after the typed data fork.
+func TestSendLendingWalletSigning(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ethapi-wallet")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	backend, ks := newWalletBackend(t, dir)
+	if err := ks.Unlock(accounts.Account{Address: simMaker}, ""); err != nil {
+		t.Fatalf("failed to unlock account: %v", err)
+	}
+	api := NewPublicTomoXTransactionPoolAPI(backend, nil)
+
+	msg := LendingMsg{
+		Quantity:       hexutil.Big(*common.BasePrice),
+		RelayerAddress: simRelayer,
+		UserAddress:    simMaker,
+		LendingToken:   simQuoteToken,
+		Term:           86400,
+		Interest:       10,
+		Status:         lendingstate.LendingStatusNew,
+		Side:           lendingstate.Investing,
+		Type:           lendingstate.Limit,
+	}
+	forged := msg
+	forged.Hash = common.HexToHash("0x01")
+	if _, err := api.SendLending(context.Background(), forged); err != core.ErrInvalidLendingHash {
+		t.Fatalf("forged hash error mismatch: have %v, want %v", err, core.ErrInvalidLendingHash)
+	}
+	if len(backend.lendings) != 0 {
+		t.Fatalf("forged lending submitted")
+	}
+	if _, err := api.SendLending(context.Background(), msg); err != nil {
+		t.Fatalf("failed to send lending: %v", err)
+	}
+	if len(backend.lendings) != 1 {
+		t.Fatalf("submitted lending count mismatch: have %d, want 1", len(backend.lendings))
+	}
+	tx := backend.lendings[0]
+	if hash := (types.LendingTxSigner{}).Hash(tx); tx.LendingHash() != hash {
+		t.Errorf("lending hash mismatch: have %x, want %x", tx.LendingHash(), hash)
+	}
+	for _, number := range []*big.Int{common.Big0, common.TIPTomoXTypedData} {
+		signer := types.MakeLendingSigner(params.TestChainConfig, number)
+		if from, err := types.LendingSender(signer, tx); err != nil || from != simMaker {
+			t.Errorf("block %v: sender mismatch: have %x (%v), want %x", number, from, err, simMaker)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/common/hexutil"
+	"github.com/69th-byte/sdexchain/core/state"
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/core/vm"
+	"github.com/69th-byte/sdexchain/params"
+	"github.com/69th-byte/sdexchain/rlp"
+	"github.com/69th-byte/sdexchain/rpc"
+	"github.com/69th-byte/sdexchain/tomox"
+	"github.com/69th-byte/sdexchain/tomox/tradingstate"
+	"github.com/69th-byte/sdexchain/tomoxlending"
+	"github.com/69th-byte/sdexchain/tomoxlending/lendingstate"
+)
+
+// SimulateArgs represents the arguments of a matching simulation: signed order
+// and lending transactions, RLP encoded, and an optional call executed after
+// they have been matched. If EstimateGas is set, the gas needed by the call is
+// estimated on top of the matched states as well.
+type SimulateArgs struct {
+	Orders      []hexutil.Bytes `json:"orders"`
+	Lendings    []hexutil.Bytes `json:"lendings"`
+	Call        *CallArgs       `json:"call"`
+	EstimateGas bool            `json:"estimateGas"`
+}
+
+// BalanceChange is the net change of a token balance caused by the simulated matching.
+type BalanceChange struct {
+	Account common.Address `json:"account"`
+	Token   common.Address `json:"token"`
+	Delta   *hexutil.Big   `json:"delta"`
+}
+
+// SimulateResult is the outcome of a matching simulation.
+type SimulateResult struct {
+	ReturnValue    hexutil.Bytes                `json:"returnValue"`
+	GasUsed        hexutil.Uint64               `json:"gasUsed"`
+	EstimatedGas   *hexutil.Uint64              `json:"estimatedGas,omitempty"`
+	Failed         bool                         `json:"failed"`
+	Trades         []map[string]string          `json:"trades"`
+	Rejects        []*tradingstate.OrderItem    `json:"rejects"`
+	LendingTrades  []*lendingstate.LendingTrade `json:"lendingTrades"`
+	LendingRejects []*lendingstate.LendingItem  `json:"lendingRejects"`
+	BalanceChanges []BalanceChange              `json:"balanceChanges"`
+}
+
+// balanceCollector is a matching tracer summing up the balances moved by the
+// matching engines. Fees are credited to their receivers.
+type balanceCollector struct {
+	deltas map[common.Address]map[common.Address]*big.Int // token -> account -> delta
+}
+
+func newBalanceCollector() *balanceCollector {
+	return &balanceCollector{deltas: make(map[common.Address]map[common.Address]*big.Int)}
+}
+
+func (c *balanceCollector) add(account, token common.Address, delta *big.Int) {
+	if delta == nil || delta.Sign() == 0 {
+		return
+	}
+	if c.deltas[token] == nil {
+		c.deltas[token] = make(map[common.Address]*big.Int)
+	}
+	if c.deltas[token][account] == nil {
+		c.deltas[token][account] = new(big.Int)
+	}
+	c.deltas[token][account].Add(c.deltas[token][account], delta)
+}
+
+// changes returns the non-zero balance changes sorted by account and token.
+func (c *balanceCollector) changes() []BalanceChange {
+	changes := []BalanceChange{}
+	for token, accounts := range c.deltas {
+		for account, delta := range accounts {
+			if delta.Sign() != 0 {
+				changes = append(changes, BalanceChange{Account: account, Token: token, Delta: (*hexutil.Big)(delta)})
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if cmp := bytes.Compare(changes[i].Account[:], changes[j].Account[:]); cmp != 0 {
+			return cmp < 0
+		}
+		return bytes.Compare(changes[i].Token[:], changes[j].Token[:]) < 0
+	})
+	return changes
+}
+
+func (c *balanceCollector) CaptureOrderStart(engine string, orderBook, hash common.Hash, user common.Address, side, orderType string, price, quantity *big.Int) {
+}
+
+func (c *balanceCollector) CaptureLevel(side string, price, remaining *big.Int) {}
+
+func (c *balanceCollector) CaptureTrade(makerHash common.Hash, maker common.Address, price, quantity *big.Int) {
+}
+
+func (c *balanceCollector) CaptureBalance(account, token common.Address, delta *big.Int) {
+	c.add(account, token, delta)
+}
+
+func (c *balanceCollector) CaptureFee(payer, receiver, token common.Address, amount *big.Int) {
+	c.add(receiver, token, amount)
+}
+
+func (c *balanceCollector) CaptureReject(hash common.Hash, reason string) {}
+
+func (c *balanceCollector) CaptureOrderEnd(err error) {}
+
+// Simulate matches the given order and lending transactions against copies of the
+// states at the given block, then executes the call on top of the result. Nothing
+// is written to the chain or the pools.
+func (s *PublicTomoXTransactionPoolAPI) Simulate(ctx context.Context, args SimulateArgs, blockNr rpc.BlockNumber) (*SimulateResult, error) {
+	tomoxService := s.b.TomoxService()
+	lendingService := s.b.LendingService()
+	if tomoxService == nil || lendingService == nil {
+		return nil, errors.New("TomoX service not found")
+	}
+	statedb, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	block, err := s.b.BlockByNumber(ctx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	author, err := s.b.GetEngine().Author(block.Header())
+	if err != nil {
+		return nil, err
+	}
+	tradingState, err := tomoxService.GetTradingState(block, author)
+	if err != nil {
+		return nil, err
+	}
+	lendingState, err := lendingService.GetLendingState(block, author)
+	if err != nil {
+		return nil, err
+	}
+	collector := newBalanceCollector()
+	tradingState.SetMatchingTracer(collector)
+	defer tradingState.SetMatchingTracer(nil)
+
+	var (
+		chain  = &apiChainContext{ctx: ctx, b: s.b}
+		result = &SimulateResult{
+			Trades:         []map[string]string{},
+			Rejects:        []*tradingstate.OrderItem{},
+			LendingTrades:  []*lendingstate.LendingTrade{},
+			LendingRejects: []*lendingstate.LendingItem{},
+		}
+	)
+	for i, encodedTx := range args.Orders {
+		tx := new(types.OrderTransaction)
+		if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+			return nil, fmt.Errorf("order %d: %v", i, err)
+		}
+		order, err := tomox.NewOrderItem(tx)
+		if err != nil {
+			return nil, fmt.Errorf("order %d: %v", i, err)
+		}
+		orderBook := tradingstate.GetTradingOrderBookHash(order.BaseToken, order.QuoteToken)
+		trades, rejects, err := tomoxService.CommitOrder(header, author, chain, statedb, tradingState, orderBook, order)
+		if err != nil {
+			return nil, fmt.Errorf("order %d: %v", i, err)
+		}
+		result.Trades = append(result.Trades, trades...)
+		result.Rejects = append(result.Rejects, rejects...)
+	}
+	for i, encodedTx := range args.Lendings {
+		tx := new(types.LendingTransaction)
+		if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+			return nil, fmt.Errorf("lending %d: %v", i, err)
+		}
+		item, err := tomoxlending.NewLendingItem(tx)
+		if err != nil {
+			return nil, fmt.Errorf("lending %d: %v", i, err)
+		}
+		lendingBook := lendingstate.GetLendingOrderBookHash(item.LendingToken, item.Term)
+		trades, rejects, err := lendingService.CommitOrder(header, author, chain, statedb, lendingState, tradingState, lendingBook, item)
+		if err != nil {
+			return nil, fmt.Errorf("lending %d: %v", i, err)
+		}
+		result.LendingTrades = append(result.LendingTrades, trades...)
+		result.LendingRejects = append(result.LendingRejects, rejects...)
+	}
+	result.BalanceChanges = collector.changes()
+
+	if args.Call != nil && args.EstimateGas {
+		gas, err := estimateGas(ctx, s.b, *args.Call, statedb, tradingState, header)
+		if err != nil {
+			return nil, err
+		}
+		result.EstimatedGas = (*hexutil.Uint64)(&gas)
+	}
+	if args.Call != nil {
+		ret, gas, failed, err := applyCall(ctx, s.b, *args.Call, statedb, tradingState, header, vm.Config{}, 5*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		result.ReturnValue, result.GasUsed, result.Failed = ret, hexutil.Uint64(gas), failed
+	}
+	return result, nil
+}
+
+// estimateGas binary searches the gas needed by the call on top of the given
+// states, the same way EstimateGas does against the latest block. Every attempt
+// runs on copies, leaving the states untouched.
+func estimateGas(ctx context.Context, b Backend, args CallArgs, statedb *state.StateDB, tradingState *tradingstate.TradingStateDB, header *types.Header) (uint64, error) {
+	var (
+		lo  uint64 = params.TxGas - 1
+		hi  uint64 = header.GasLimit
+		cap uint64
+	)
+	if uint64(args.Gas) >= params.TxGas {
+		hi = uint64(args.Gas)
+	}
+	cap = hi
+
+	executable := func(gas uint64) bool {
+		args.Gas = hexutil.Uint64(gas)
+
+		_, _, failed, err := applyCall(ctx, b, args, statedb.Copy(), tradingState.Copy(), header, vm.Config{}, 0)
+		if err != nil || failed {
+			return false
+		}
+		return true
+	}
+	for lo+1 < hi {
+		mid := (hi + lo) / 2
+		if !executable(mid) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	if hi == cap {
+		if !executable(hi) {
+			return 0, fmt.Errorf("gas required exceeds allowance or always failing transaction")
+		}
+	}
+	return hi, nil
+}
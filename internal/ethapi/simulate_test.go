@@ -0,0 +1,282 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/common/hexutil"
+	"github.com/69th-byte/sdexchain/common/math"
+	"github.com/69th-byte/sdexchain/consensus"
+	"github.com/69th-byte/sdexchain/consensus/ethash"
+	"github.com/69th-byte/sdexchain/core"
+	"github.com/69th-byte/sdexchain/core/rawdb"
+	"github.com/69th-byte/sdexchain/core/state"
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/core/vm"
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/params"
+	"github.com/69th-byte/sdexchain/rlp"
+	"github.com/69th-byte/sdexchain/rpc"
+	"github.com/69th-byte/sdexchain/tomox"
+	"github.com/69th-byte/sdexchain/tomox/tradingstate"
+	"github.com/69th-byte/sdexchain/tomoxlending"
+	"github.com/69th-byte/sdexchain/tomoxlending/lendingstate"
+)
+
+var (
+	simRelayer    = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	simOwner      = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	simBaseToken  = common.HexToAddress("0x00000000000000000000000000000000000000cc")
+	simQuoteToken = common.HexToAddress(common.TomoNativeAddress)
+
+	simMakerKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	simTakerKey, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+	simMaker       = crypto.PubkeyToAddress(simMakerKey.PublicKey)
+	simTaker       = crypto.PubkeyToAddress(simTakerKey.PublicKey)
+)
+
+// simBackend is an API backend serving the latest block of a test chain and the
+// TomoX services matching on top of it. Only the methods needed by the matching
+// simulation are implemented.
+type simBackend struct {
+	Backend
+	chain   *core.BlockChain
+	tomox   *tomox.TomoX
+	lending *tomoxlending.Lending
+}
+
+func (b *simBackend) ChainConfig() *params.ChainConfig      { return b.chain.Config() }
+func (b *simBackend) CurrentBlock() *types.Block            { return b.chain.CurrentBlock() }
+func (b *simBackend) GetEngine() consensus.Engine           { return b.chain.Engine() }
+func (b *simBackend) TomoxService() *tomox.TomoX            { return b.tomox }
+func (b *simBackend) LendingService() *tomoxlending.Lending { return b.lending }
+
+func (b *simBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+		return b.chain.CurrentHeader(), nil
+	}
+	return b.chain.GetHeaderByNumber(uint64(number)), nil
+}
+
+func (b *simBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+		return b.chain.CurrentBlock(), nil
+	}
+	return b.chain.GetBlockByNumber(uint64(number)), nil
+}
+
+func (b *simBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	header, err := b.HeaderByNumber(ctx, number)
+	if header == nil || err != nil {
+		return nil, nil, err
+	}
+	statedb, err := b.chain.StateAt(header.Root)
+	return statedb, header, err
+}
+
+func (b *simBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, tomoxState *tradingstate.TradingStateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
+	state.SetBalance(msg.From(), math.MaxBig256)
+	context := core.NewEVMContext(msg, header, b.chain, nil)
+	return vm.NewEVM(context, state, tomoxState, b.chain.Config(), vmCfg), func() error { return nil }, nil
+}
+
+// simRelayerStorage returns the storage of the relayer registration contract
+// listing simRelayer with the given fee rate and a single base/TOMO pair.
+func simRelayerStorage(feeRate int64) map[common.Hash]common.Hash {
+	loc := tradingstate.GetLocMappingAtKey(simRelayer.Hash(), tradingstate.RelayerMappingSlot["RELAYER_LIST"])
+	field := func(name string) common.Hash {
+		return common.BigToHash(new(big.Int).Add(loc, tradingstate.RelayerStructMappingSlot[name]))
+	}
+	deposit := new(big.Int).Mul(common.BasePrice, new(big.Int).Add(common.RelayerLockedFund, big.NewInt(100)))
+	return map[common.Hash]common.Hash{
+		field("_deposit"):    common.BigToHash(deposit),
+		field("_fee"):        common.BigToHash(big.NewInt(feeRate)),
+		field("_fromTokens"): common.BigToHash(common.Big1),
+		field("_toTokens"):   common.BigToHash(common.Big1),
+		field("_owner"):      simOwner.Hash(),
+		state.GetLocDynamicArrAtElement(field("_fromTokens"), 0, 1): simBaseToken.Hash(),
+		state.GetLocDynamicArrAtElement(field("_toTokens"), 0, 1):   simQuoteToken.Hash(),
+	}
+}
+
+// newSimBackend creates a test chain with a registered relayer, the maker holding
+// base tokens and the taker holding TOMO.
+func newSimBackend(t *testing.T, dir string) *simBackend {
+	makerBalance := tradingstate.GetLocMappingAtKey(simMaker.Hash(), tradingstate.TokenMappingSlot["balances"])
+	var (
+		db    = rawdb.NewMemoryDatabase()
+		gspec = &core.Genesis{
+			Config:   params.TestChainConfig,
+			GasLimit: 10000000,
+			Alloc: core.GenesisAlloc{
+				common.HexToAddress(common.RelayerRegistrationSMC): {
+					Balance: new(big.Int).Mul(common.BasePrice, big.NewInt(100000)),
+					Storage: simRelayerStorage(10),
+				},
+				simBaseToken: {
+					Balance: common.Big0,
+					Storage: map[common.Hash]common.Hash{
+						common.BigToHash(makerBalance): common.BigToHash(new(big.Int).Mul(common.BasePrice, big.NewInt(100))),
+					},
+				},
+				simTaker: {Balance: new(big.Int).Mul(common.BasePrice, big.NewInt(100))},
+			},
+		}
+	)
+	gspec.MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create test chain: %v", err)
+	}
+	tomoX := tomox.New(&tomox.Config{DataDir: dir})
+	tomoX.SetTokenDecimal(simBaseToken, common.BasePrice)
+	return &simBackend{chain: chain, tomox: tomoX, lending: tomoxlending.New(tomoX)}
+}
+
+// signOrder returns the RLP encoding of a new limit order of the key's owner.
+func signOrder(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, baseToken common.Address, side string, quantity, price *big.Int) hexutil.Bytes {
+	user := crypto.PubkeyToAddress(key.PublicKey)
+	tx := types.NewOrderTransaction(nonce, quantity, price, simRelayer, user, baseToken, simQuoteToken, tradingstate.OrderNew, side, tradingstate.Limit, common.Hash{}, 0)
+	tx = types.NewOrderTransaction(nonce, quantity, price, simRelayer, user, baseToken, simQuoteToken, tradingstate.OrderNew, side, tradingstate.Limit, types.OrderTxSigner{}.OrderCreateHash(tx), 0)
+	signed, err := types.OrderSignTx(tx, types.OrderTxSigner{}, key)
+	if err != nil {
+		t.Fatalf("failed to sign order: %v", err)
+	}
+	blob, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		t.Fatalf("failed to encode order: %v", err)
+	}
+	return blob
+}
+
+// signLending returns the RLP encoding of a new investing limit lending of the key's owner.
+func signLending(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, quantity *big.Int) hexutil.Bytes {
+	user := crypto.PubkeyToAddress(key.PublicKey)
+	tx := types.NewLendingTransaction(nonce, quantity, 10, 86400, simRelayer, user, simQuoteToken, common.Address{}, false, lendingstate.LendingStatusNew, lendingstate.Investing, lendingstate.Limit, common.Hash{}, 0, 0, "")
+	tx = types.NewLendingTransaction(nonce, quantity, 10, 86400, simRelayer, user, simQuoteToken, common.Address{}, false, lendingstate.LendingStatusNew, lendingstate.Investing, lendingstate.Limit, types.LendingTxSigner{}.LendingCreateHash(tx), 0, 0, "")
+	signed, err := types.LendingSignTx(tx, types.LendingTxSigner{}, key)
+	if err != nil {
+		t.Fatalf("failed to sign lending: %v", err)
+	}
+	blob, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		t.Fatalf("failed to encode lending: %v", err)
+	}
+	return blob
+}
+
+// Tests that the simulation matches the given orders, reports the moved balances
+// and the rejected orders and lendings, and estimates the call on top of them.
+func TestSimulate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ethapi-simulate")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		backend  = newSimBackend(t, dir)
+		api      = NewPublicTomoXTransactionPoolAPI(backend, nil)
+		quantity = new(big.Int).Mul(common.BasePrice, big.NewInt(10))
+		price    = common.BasePrice
+		unlisted = common.HexToAddress("0x00000000000000000000000000000000000000dd")
+		receiver = common.HexToAddress("0x00000000000000000000000000000000000000ee")
+	)
+	result, err := api.Simulate(context.Background(), SimulateArgs{
+		Orders: []hexutil.Bytes{
+			signOrder(t, simMakerKey, 0, simBaseToken, tradingstate.Ask, quantity, price),
+			signOrder(t, simTakerKey, 0, simBaseToken, tradingstate.Bid, quantity, price),
+			signOrder(t, simTakerKey, 1, unlisted, tradingstate.Bid, quantity, price),
+		},
+		Lendings: []hexutil.Bytes{
+			signLending(t, simTakerKey, 0, quantity),
+		},
+		Call:        &CallArgs{From: simTaker, To: &receiver},
+		EstimateGas: true,
+	}, rpc.LatestBlockNumber)
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if len(result.Trades) != 1 {
+		t.Fatalf("trade count mismatch: have %d, want 1", len(result.Trades))
+	}
+	if len(result.Rejects) != 1 || result.Rejects[0].BaseToken != unlisted || result.Rejects[0].RejectReason != tradingstate.RejectReasonInvalidPair {
+		t.Errorf("order rejects mismatch: have %v", result.Rejects)
+	}
+	if len(result.LendingRejects) != 1 || result.LendingRejects[0].RejectReason != lendingstate.RejectReasonInvalidOrder {
+		t.Errorf("lending rejects mismatch: have %v", result.LendingRejects)
+	}
+	// Both sides pay the relayer 0.1% of the 10 TOMO traded, the masternode owner
+	// is credited with the matching fee charged to the relayer for both sides
+	var (
+		tomo      = func(n int64) *big.Int { return new(big.Int).Mul(common.BasePrice, big.NewInt(n)) }
+		fee       = new(big.Int).Div(tomo(10), big.NewInt(1000))
+		masterFee = new(big.Int).Mul(common.RelayerFee, big.NewInt(2))
+	)
+	type key struct{ account, token common.Address }
+	want := map[key]*big.Int{
+		{common.Address{}, simQuoteToken}: masterFee,
+		{simOwner, simQuoteToken}:         new(big.Int).Mul(fee, big.NewInt(2)),
+		{simMaker, simQuoteToken}:         new(big.Int).Sub(tomo(10), fee),
+		{simMaker, simBaseToken}:          new(big.Int).Neg(quantity),
+		{simTaker, simQuoteToken}:         new(big.Int).Neg(new(big.Int).Add(tomo(10), fee)),
+		{simTaker, simBaseToken}:          quantity,
+	}
+	have := make(map[key]*big.Int)
+	for _, change := range result.BalanceChanges {
+		have[key{change.Account, change.Token}] = change.Delta.ToInt()
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("balance changes mismatch:\nhave %v\nwant %v", have, want)
+	}
+	if result.Failed {
+		t.Errorf("call failed")
+	}
+	if result.EstimatedGas == nil || uint64(*result.EstimatedGas) != params.TxGas {
+		t.Errorf("estimated gas mismatch: have %v, want %d", result.EstimatedGas, params.TxGas)
+	}
+	// Nothing may be persisted by the simulation
+	statedb, _, _ := backend.StateAndHeaderByNumber(context.Background(), rpc.LatestBlockNumber)
+	if balance := tradingstate.GetTokenBalance(simTaker, simBaseToken, statedb); balance.Sign() != 0 {
+		t.Errorf("simulated trade persisted: taker base balance %v", balance)
+	}
+}
+
+// Tests that orders which can't be applied at all fail the whole simulation.
+func TestSimulateNonceTooHigh(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ethapi-simulate")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	api := NewPublicTomoXTransactionPoolAPI(newSimBackend(t, dir), nil)
+	_, err = api.Simulate(context.Background(), SimulateArgs{
+		Orders: []hexutil.Bytes{signOrder(t, simMakerKey, 1, simBaseToken, tradingstate.Ask, common.BasePrice, common.BasePrice)},
+	}, rpc.LatestBlockNumber)
+	if err == nil || err.Error() != "order 0: "+tomox.ErrNonceTooHigh.Error() {
+		t.Fatalf("error mismatch: have %v, want %v", err, tomox.ErrNonceTooHigh)
+	}
+}
@@ -444,6 +444,12 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
 		}),
+		new web3._extend.Method({
+			name: 'signTypedData',
+			call: 'eth_signTypedData',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'resend',
 			call: 'eth_resend',
@@ -572,6 +578,12 @@ web3._extend({
 			params: 3,
 			inputFormatter: [null, web3._extend.formatters.inputAddressFormatter, null]
 		}),
+		new web3._extend.Method({
+			name: 'signTypedData',
+			call: 'personal_signTypedData',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputAddressFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'ecRecover',
 			call: 'personal_ecRecover',
@@ -684,6 +696,35 @@ web3._extend({
             params: 1
 		}),
 		new web3._extend.Method({
+            name: 'getRelayerInfo',
+            call: 'tomox_getRelayerInfo',
+            params: 1
+		}),
+		new web3._extend.Method({
+            name: 'checkTokenCompatibility',
+            call: 'tomox_checkTokenCompatibility',
+            params: 2,
+            inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+            name: 'getRouteQuote',
+            call: 'tomox_getRouteQuote',
+            params: 4,
+            inputFormatter: [null, null, null, null]
+		}),
+		new web3._extend.Method({
+            name: 'getTWAP',
+            call: 'tomox_getTWAP',
+            params: 3,
+            inputFormatter: [null, null, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+            name: 'simulate',
+            call: 'tomox_simulate',
+            params: 2,
+            inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
             name: 'getOrderPoolContent',
             call: 'tomox_getOrderPoolContent',
             params: 0
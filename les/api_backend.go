@@ -19,9 +19,9 @@ package les
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"github.com/69th-byte/sdexchain/tomox/tradingstate"
 	"github.com/69th-byte/sdexchain/tomoxlending"
+	"github.com/69th-byte/sdexchain/tomoxlending/lendingstate"
 	"io/ioutil"
 	"math/big"
 	"path/filepath"
@@ -115,10 +115,10 @@ func (b *LesApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction)
 	return b.eth.txPool.Add(ctx, signedTx)
 }
 func (b *LesApiBackend) SendOrderTx(ctx context.Context, signedTx *types.OrderTransaction) error {
-	return nil
+	return b.eth.orderRelay.SendOrders(types.OrderTransactions{signedTx})
 }
 func (b *LesApiBackend) SendLendingTx(ctx context.Context, signedTx *types.LendingTransaction) error {
-	return nil
+	return b.eth.orderRelay.SendLendings(types.LendingTransactions{signedTx})
 }
 
 func (b *LesApiBackend) RemoveTx(txHash common.Hash) {
@@ -188,6 +188,10 @@ func (b *LesApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+func (b *LesApiBackend) SuggestTipCap(ctx context.Context) (*big.Int, error) {
+	return b.gpo.SuggestTipCap(ctx)
+}
+
 func (b *LesApiBackend) ChainDb() ethdb.Database {
 	return b.eth.chainDb
 }
@@ -272,15 +276,48 @@ func (b *LesApiBackend) AreTwoBlockSamePath(bh1 common.Hash, bh2 common.Hash) bo
 	return true
 }
 
-// GetOrderNonce get order nonce
+// GetOrderNonce get order nonce, reading the trading state of the current
+// block through ODR
 func (b *LesApiBackend) GetOrderNonce(address common.Hash) (uint64, error) {
-	return 0, errors.New("cannot find tomox service")
+	header := b.eth.blockchain.CurrentHeader()
+	author, err := b.eth.engine.Author(header)
+	if err != nil {
+		return 0, err
+	}
+	tomoxState, err := b.tradingState(types.NewBlockWithHeader(header), author)
+	if err != nil {
+		return 0, err
+	}
+	nonce := tomoxState.GetNonce(address)
+	return nonce, tomoxState.Error()
+}
+
+// tradingState opens the trading state committed to by author in the given
+// block, retrieving the missing trie nodes from the network on demand.
+func (b *LesApiBackend) tradingState(block *types.Block, author common.Address) (*tradingstate.TradingStateDB, error) {
+	ctx := context.Background()
+	root, _, err := light.GetTomoXStateRoots(ctx, b.eth.odr, block.Header(), author)
+	if err != nil {
+		return nil, err
+	}
+	return light.NewTradingState(ctx, block.Header(), root, b.eth.odr), nil
+}
+
+// lendingState opens the lending state committed to by author in the given
+// block, retrieving the missing trie nodes from the network on demand.
+func (b *LesApiBackend) lendingState(block *types.Block, author common.Address) (*lendingstate.LendingStateDB, error) {
+	ctx := context.Background()
+	_, root, err := light.GetTomoXStateRoots(ctx, b.eth.odr, block.Header(), author)
+	if err != nil {
+		return nil, err
+	}
+	return light.NewLendingState(ctx, block.Header(), root, b.eth.odr), nil
 }
 
 func (b *LesApiBackend) TomoxService() *tomox.TomoX {
-	return nil
+	return b.eth.tomox
 }
 
 func (b *LesApiBackend) LendingService() *tomoxlending.Lending {
-	return nil
+	return b.eth.lending
 }
@@ -26,6 +26,7 @@ import (
 	"github.com/69th-byte/sdexchain/common"
 	"github.com/69th-byte/sdexchain/common/hexutil"
 	"github.com/69th-byte/sdexchain/consensus"
+	"github.com/69th-byte/sdexchain/consensus/posv"
 	"github.com/69th-byte/sdexchain/core"
 	"github.com/69th-byte/sdexchain/core/bloombits"
 	"github.com/69th-byte/sdexchain/core/types"
@@ -43,6 +44,8 @@ import (
 	"github.com/69th-byte/sdexchain/p2p/discv5"
 	"github.com/69th-byte/sdexchain/params"
 	rpc "github.com/69th-byte/sdexchain/rpc"
+	"github.com/69th-byte/sdexchain/tomox"
+	"github.com/69th-byte/sdexchain/tomoxlending"
 )
 
 type LightEthereum struct {
@@ -50,6 +53,10 @@ type LightEthereum struct {
 
 	odr         *LesOdr
 	relay       *LesTxRelay
+	orderRelay  *LesOrderRelay
+	posv        *posvTracker // nil unless the chain runs PoSV
+	tomox       *tomox.TomoX
+	lending     *tomoxlending.Lending
 	chainConfig *params.ChainConfig
 	// Channel for shutting down the service
 	shutdownChan chan bool
@@ -111,12 +118,16 @@ func New(ctx *node.ServiceContext, config *eth.Config) (*LightEthereum, error) {
 	}
 
 	leth.relay = NewLesTxRelay(peers, leth.reqDist)
+	leth.orderRelay = NewLesOrderRelay(peers, leth.reqDist)
 	leth.serverPool = newServerPool(chainDb, quitSync, &leth.wg)
 	leth.retriever = newRetrieveManager(peers, leth.reqDist, leth.serverPool)
 	leth.odr = NewLesOdr(chainDb, leth.chtIndexer, leth.bloomTrieIndexer, leth.bloomIndexer, leth.retriever)
 	if leth.blockchain, err = light.NewLightChain(leth.odr, leth.chainConfig, leth.engine); err != nil {
 		return nil, err
 	}
+	if engine, ok := leth.engine.(*posv.Posv); ok {
+		leth.posv = newPosvTracker(engine, leth.blockchain, leth.odr)
+	}
 	leth.bloomIndexer.Start(leth.blockchain)
 	// Rewind the chain in case of an incompatible config upgrade.
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
@@ -129,7 +140,10 @@ func New(ctx *node.ServiceContext, config *eth.Config) (*LightEthereum, error) {
 	if leth.protocolManager, err = NewProtocolManager(leth.chainConfig, true, ClientProtocolVersions, config.NetworkId, leth.eventMux, leth.engine, leth.peers, leth.blockchain, nil, chainDb, leth.odr, leth.relay, quitSync, &leth.wg); err != nil {
 		return nil, err
 	}
+	leth.protocolManager.posv = leth.posv
 	leth.ApiBackend = &LesApiBackend{leth, nil}
+	leth.tomox = tomox.NewLight(leth.ApiBackend.tradingState)
+	leth.lending = tomoxlending.NewLight(leth.tomox, leth.ApiBackend.lendingState)
 	gpoParams := config.GPO
 	if gpoParams.Default == nil {
 		gpoParams.Default = config.GasPrice
@@ -145,6 +159,8 @@ func lesTopic(genesisHash common.Hash, protocolVersion uint) discv5.Topic {
 		name = "LES"
 	case lpv2:
 		name = "LES2"
+	case lpv3:
+		name = "LES3"
 	default:
 		panic(nil)
 	}
@@ -248,6 +264,9 @@ func (s *LightEthereum) Stop() error {
 	if s.bloomTrieIndexer != nil {
 		s.bloomTrieIndexer.Close()
 	}
+	if s.posv != nil {
+		s.posv.stop()
+	}
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
 	s.txPool.Stop()
@@ -92,10 +92,22 @@ type txPool interface {
 	Status(hashes []common.Hash) []core.TxStatus
 }
 
+type orderPool interface {
+	AddRemotes(txs []*types.OrderTransaction) []error
+	Status(hashes []common.Hash) []core.TxStatus
+}
+
+type lendingPool interface {
+	AddRemotes(txs []*types.LendingTransaction) []error
+	Status(hashes []common.Hash) []core.TxStatus
+}
+
 type ProtocolManager struct {
 	lightSync   bool
 	txpool      txPool
 	txrelay     *LesTxRelay
+	orderpool   orderPool
+	lendingpool lendingPool
 	networkId   uint64
 	chainConfig *params.ChainConfig
 	blockchain  BlockChain
@@ -106,6 +118,10 @@ type ProtocolManager struct {
 	lesTopic    discv5.Topic
 	reqDist     *requestDistributor
 	retriever   *retrieveManager
+	posv        *posvTracker
+
+	// TomoX trie databases, nil if the server does not run TomoX
+	tradingTrieDb, lendingTrieDb *trie.Database
 
 	downloader *downloader.Downloader
 	fetcher    *lightFetcher
@@ -331,7 +347,7 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	}
 }
 
-var reqList = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, GetProofsV1Msg, SendTxMsg, SendTxV2Msg, GetTxStatusMsg, GetHeaderProofsMsg, GetProofsV2Msg, GetHelperTrieProofsMsg}
+var reqList = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, GetProofsV1Msg, SendTxMsg, SendTxV2Msg, GetTxStatusMsg, GetHeaderProofsMsg, GetProofsV2Msg, GetHelperTrieProofsMsg, SendOrderTxMsg, SendLendingTxMsg, GetTomoXProofsMsg}
 
 // handleMsg is invoked whenever an inbound message is received from a remote
 // peer. The remote connection is torn down upon returning any error.
@@ -1077,6 +1093,122 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 
 		p.fcServer.GotReply(resp.ReqID, resp.BV)
 
+	case SendOrderTxMsg:
+		if pm.orderpool == nil {
+			return errResp(ErrRequestRejected, "")
+		}
+		// Order transactions arrived, parse all of them and deliver to the pool
+		var req struct {
+			ReqID uint64
+			Txs   []*types.OrderTransaction
+		}
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		reqCnt := len(req.Txs)
+		if reject(uint64(reqCnt), MaxTxSend) {
+			return errResp(ErrRequestRejected, "")
+		}
+
+		hashes := make([]common.Hash, len(req.Txs))
+		for i, tx := range req.Txs {
+			hashes[i] = tx.Hash()
+		}
+		stats := make([]txStatus, len(req.Txs))
+		for i, status := range pm.orderpool.Status(hashes) {
+			stats[i].Status = status
+			if status == core.TxStatusUnknown {
+				if errs := pm.orderpool.AddRemotes([]*types.OrderTransaction{req.Txs[i]}); errs[0] != nil {
+					stats[i].Error = errs[0].Error()
+					continue
+				}
+				stats[i].Status = pm.orderpool.Status([]common.Hash{hashes[i]})[0]
+			}
+		}
+
+		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
+		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
+
+		return p.SendTxStatus(req.ReqID, bv, stats)
+
+	case SendLendingTxMsg:
+		if pm.lendingpool == nil {
+			return errResp(ErrRequestRejected, "")
+		}
+		// Lending transactions arrived, parse all of them and deliver to the pool
+		var req struct {
+			ReqID uint64
+			Txs   []*types.LendingTransaction
+		}
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		reqCnt := len(req.Txs)
+		if reject(uint64(reqCnt), MaxTxSend) {
+			return errResp(ErrRequestRejected, "")
+		}
+
+		hashes := make([]common.Hash, len(req.Txs))
+		for i, tx := range req.Txs {
+			hashes[i] = tx.Hash()
+		}
+		stats := make([]txStatus, len(req.Txs))
+		for i, status := range pm.lendingpool.Status(hashes) {
+			stats[i].Status = status
+			if status == core.TxStatusUnknown {
+				if errs := pm.lendingpool.AddRemotes([]*types.LendingTransaction{req.Txs[i]}); errs[0] != nil {
+					stats[i].Error = errs[0].Error()
+					continue
+				}
+				stats[i].Status = pm.lendingpool.Status([]common.Hash{hashes[i]})[0]
+			}
+		}
+
+		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
+		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
+
+		return p.SendTxStatus(req.ReqID, bv, stats)
+
+	case GetTomoXProofsMsg:
+		p.Log().Trace("Received TomoX proofs request")
+		// Decode the retrieval message
+		var req struct {
+			ReqID uint64
+			Reqs  []TomoXProofReq
+		}
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		reqCnt := len(req.Reqs)
+		if reject(uint64(reqCnt), MaxProofsFetch) {
+			return errResp(ErrRequestRejected, "")
+		}
+
+		nodes := light.NewNodeSet()
+
+		for _, req := range req.Reqs {
+			// The trie is identified by its root alone, the client verifies
+			// the proof against a root it already trusts
+			triedb := pm.tradingTrieDb
+			if req.Lending {
+				triedb = pm.lendingTrieDb
+			}
+			if triedb == nil {
+				continue
+			}
+			t, err := trie.New(req.Root, triedb)
+			if err != nil {
+				continue
+			}
+			t.Prove(req.Key, req.FromLevel, nodes)
+			if nodes.DataSize() >= softResponseLimit {
+				break
+			}
+		}
+		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
+		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
+		return p.SendProofsV2(req.ReqID, bv, nodes.NodeList())
+
 	default:
 		p.Log().Trace("Received unknown message", "code", msg.Code)
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
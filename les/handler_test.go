@@ -18,6 +18,7 @@ package les
 
 import (
 	"encoding/binary"
+	"errors"
 	"github.com/69th-byte/sdexchain/core/rawdb"
 	"math/big"
 	"math/rand"
@@ -578,3 +579,86 @@ func TestTransactionStatusLes2(t *testing.T) {
 	test(tx1, false, txStatus{Status: core.TxStatusPending})
 	test(tx2, false, txStatus{Status: core.TxStatusPending})
 }
+
+// Tests that TomoX trie proofs can be correctly retrieved.
+func TestGetTomoXProofsLes3(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	pm := newTestProtocolManagerMust(t, false, 0, nil, nil, nil, db)
+	pm.tradingTrieDb = trie.NewDatabase(db)
+	peer, _ := newTestPeer(t, "peer", 3, pm, true)
+	defer peer.close()
+
+	// Assemble a trading trie to prove keys from
+	tr, _ := trie.New(common.Hash{}, pm.tradingTrieDb)
+	for i := byte(0); i < 16; i++ {
+		tr.Update([]byte{i, i}, []byte{i + 1})
+	}
+	root, _ := tr.Commit(nil)
+
+	var reqs []TomoXProofReq
+	proofs := light.NewNodeSet()
+	for _, key := range [][]byte{{1, 1}, {7, 7}, {0xff}} {
+		reqs = append(reqs, TomoXProofReq{Root: root, Key: key})
+		tr.Prove(key, 0, proofs)
+	}
+	// The node runs no lending engine, so lending requests are skipped
+	reqs = append(reqs, TomoXProofReq{Lending: true, Root: root, Key: []byte{2, 2}})
+
+	cost := peer.GetRequestCost(GetTomoXProofsMsg, len(reqs))
+	sendRequest(peer.app, GetTomoXProofsMsg, 42, cost, reqs)
+	if err := expectResponse(peer.app, ProofsV2Msg, 42, testBufLimit, proofs.NodeList()); err != nil {
+		t.Errorf("proofs mismatch: %v", err)
+	}
+}
+
+// testOrderPool is an order pool accepting all orders with a positive quantity.
+type testOrderPool struct {
+	known map[common.Hash]bool
+}
+
+func (pool *testOrderPool) AddRemotes(txs []*types.OrderTransaction) []error {
+	errs := make([]error, len(txs))
+	for i, tx := range txs {
+		if tx.Quantity().Sign() <= 0 {
+			errs[i] = errors.New("invalid quantity")
+			continue
+		}
+		pool.known[tx.Hash()] = true
+	}
+	return errs
+}
+
+func (pool *testOrderPool) Status(hashes []common.Hash) []core.TxStatus {
+	status := make([]core.TxStatus, len(hashes))
+	for i, hash := range hashes {
+		if pool.known[hash] {
+			status[i] = core.TxStatusPending
+		}
+	}
+	return status
+}
+
+func TestSendOrderTxLes3(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	pm := newTestProtocolManagerMust(t, false, 0, nil, nil, nil, db)
+	pm.orderpool = &testOrderPool{known: make(map[common.Hash]bool)}
+	peer, _ := newTestPeer(t, "peer", 3, pm, true)
+	defer peer.close()
+
+	var reqID uint64
+
+	test := func(tx *types.OrderTransaction, expStatus txStatus) {
+		reqID++
+		cost := peer.GetRequestCost(SendOrderTxMsg, 1)
+		sendRequest(peer.app, SendOrderTxMsg, reqID, cost, types.OrderTransactions{tx})
+		if err := expectResponse(peer.app, TxStatusMsg, reqID, testBufLimit, []txStatus{expStatus}); err != nil {
+			t.Errorf("order status mismatch: %v", err)
+		}
+	}
+	order := func(quantity int64) *types.OrderTransaction {
+		return types.NewOrderTransaction(1, big.NewInt(quantity), big.NewInt(100), common.Address{1}, acc1Addr, common.Address{2}, common.Address{3}, "NEW", "BUY", "LO", common.Hash{}, 0)
+	}
+	test(order(0), txStatus{Status: core.TxStatusUnknown, Error: "invalid quantity"})
+	test(order(10), txStatus{Status: core.TxStatusPending})
+	test(order(10), txStatus{Status: core.TxStatusPending}) // adding it again should not return an error
+}
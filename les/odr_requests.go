@@ -62,6 +62,8 @@ func LesRequest(req light.OdrRequest) LesOdrRequest {
 		return (*ReceiptsRequest)(r)
 	case *light.TrieRequest:
 		return (*TrieRequest)(r)
+	case *light.TomoXTrieRequest:
+		return (*TomoXTrieRequest)(r)
 	case *light.CodeRequest:
 		return (*CodeRequest)(r)
 	case *light.ChtRequest:
@@ -193,7 +195,7 @@ func (r *TrieRequest) GetCost(peer *peer) uint64 {
 	switch peer.version {
 	case lpv1:
 		return peer.GetRequestCost(GetProofsV1Msg, 1)
-	case lpv2:
+	case lpv2, lpv3:
 		return peer.GetRequestCost(GetProofsV2Msg, 1)
 	default:
 		panic(nil)
@@ -256,6 +258,68 @@ func (r *TrieRequest) Validate(db ethdb.Database, msg *Msg) error {
 	}
 }
 
+// TomoXProofReq requests a Merkle proof of a key in a TomoX trading or lending
+// trie. The trie is identified by its root only, the client checks the proof
+// against a root it already trusts.
+type TomoXProofReq struct {
+	Lending   bool
+	Root      common.Hash
+	Key       []byte
+	FromLevel uint
+}
+
+// ODR request type for TomoX trading/lending trie entries, see LesOdrRequest interface
+type TomoXTrieRequest light.TomoXTrieRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *TomoXTrieRequest) GetCost(peer *peer) uint64 {
+	return peer.GetRequestCost(GetTomoXProofsMsg, 1)
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request
+func (r *TomoXTrieRequest) CanSend(peer *peer) bool {
+	if peer.version < lpv3 {
+		return false
+	}
+	return peer.HasBlock(r.Id.BlockHash, r.Id.BlockNumber)
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *TomoXTrieRequest) Request(reqID uint64, peer *peer) error {
+	peer.Log().Debug("Requesting TomoX trie proof", "lending", r.Lending, "root", r.Id.Root, "key", r.Key)
+	req := TomoXProofReq{
+		Lending: r.Lending,
+		Root:    r.Id.Root,
+		Key:     r.Key,
+	}
+	return peer.RequestTomoXProofs(reqID, r.GetCost(peer), []TomoXProofReq{req})
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *TomoXTrieRequest) Validate(db ethdb.Database, msg *Msg) error {
+	log.Debug("Validating TomoX trie proof", "lending", r.Lending, "root", r.Id.Root, "key", r.Key)
+
+	if msg.MsgType != MsgProofsV2 {
+		return errInvalidMessageType
+	}
+	proofs := msg.Obj.(light.NodeList)
+	// Verify the proof and store if checks out
+	nodeSet := proofs.NodeSet()
+	reads := &readTraceDB{db: nodeSet}
+	if _, err := trie.VerifyProof(r.Id.Root, r.Key, reads); err != nil {
+		return fmt.Errorf("merkle proof verification failed: %v", err)
+	}
+	// check if all nodes have been read by VerifyProof
+	if len(reads.reads) != nodeSet.KeyCount() {
+		return errUselessNodes
+	}
+	r.Proof = nodeSet
+	return nil
+}
+
 type CodeReq struct {
 	BHash  common.Hash
 	AccKey []byte
@@ -353,7 +417,7 @@ func (r *ChtRequest) GetCost(peer *peer) uint64 {
 	switch peer.version {
 	case lpv1:
 		return peer.GetRequestCost(GetHeaderProofsMsg, 1)
-	case lpv2:
+	case lpv2, lpv3:
 		return peer.GetRequestCost(GetHelperTrieProofsMsg, 1)
 	default:
 		panic(nil)
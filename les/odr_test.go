@@ -35,6 +35,7 @@ import (
 	"github.com/69th-byte/sdexchain/light"
 	"github.com/69th-byte/sdexchain/params"
 	"github.com/69th-byte/sdexchain/rlp"
+	"github.com/69th-byte/sdexchain/trie"
 )
 
 type odrTestFn func(ctx context.Context, db ethdb.Database, config *params.ChainConfig, bc *core.BlockChain, lc *light.LightChain, bhash common.Hash) []byte
@@ -133,7 +134,7 @@ func odrContractCall(ctx context.Context, db ethdb.Database, config *params.Chai
 				if value, ok := feeCapacity[testContractAddr]; ok {
 					balanceTokenFee = value
 				}
-				msg := callmsg{types.NewMessage(from.Address(), &testContractAddr, 0, new(big.Int), 100000, new(big.Int), data, false, balanceTokenFee)}
+				msg := callmsg{types.NewMessage(from.Address(), &testContractAddr, 0, new(big.Int), 100000, new(big.Int), data, nil, false, balanceTokenFee)}
 
 				context := core.NewEVMContext(msg, header, bc, nil)
 				vmenv := vm.NewEVM(context, statedb, nil, config, vm.Config{})
@@ -153,7 +154,7 @@ func odrContractCall(ctx context.Context, db ethdb.Database, config *params.Chai
 			if value, ok := feeCapacity[testContractAddr]; ok {
 				balanceTokenFee = value
 			}
-			msg := callmsg{types.NewMessage(testBankAddress, &testContractAddr, 0, new(big.Int), 100000, new(big.Int), data, false, balanceTokenFee)}
+			msg := callmsg{types.NewMessage(testBankAddress, &testContractAddr, 0, new(big.Int), 100000, new(big.Int), data, nil, false, balanceTokenFee)}
 			context := core.NewEVMContext(msg, header, lc, nil)
 			vmenv := vm.NewEVM(context, statedb, nil, config, vm.Config{})
 			gp := new(core.GasPool).AddGas(math.MaxUint64)
@@ -225,3 +226,46 @@ func testOdr(t *testing.T, protocol int, expFail uint64, fn odrTestFn) {
 	time.Sleep(time.Millisecond * 10) // ensure that all peerSetNotify callbacks are executed
 	test(5)
 }
+
+// Tests that TomoX trie proofs are validated for the prefix path keys light
+// clients request missing trie nodes with.
+func TestTomoXTrieRequestValidate(t *testing.T) {
+	tr, _ := trie.New(common.Hash{}, trie.NewDatabase(rawdb.NewMemoryDatabase()))
+	for _, key := range [][]byte{{0x12, 0x34}, {0x12, 0x35}, {0x13, 0x00}, {0x56, 0x78}} {
+		tr.Update(key, bytes.Repeat(key, 16))
+	}
+	root, _ := tr.Commit(nil)
+
+	prove := func(keys ...[]byte) light.NodeList {
+		proof := light.NewNodeSet()
+		for _, key := range keys {
+			tr.Prove(key, 0, proof)
+		}
+		return proof.NodeList()
+	}
+	// Nodes found missing at the paths 1, 12 and 123 are requested by the keys
+	// 0x10, 0x12 and 0x1230, next to the full keys of the leaves
+	for _, key := range [][]byte{{0x10}, {0x12}, {0x12, 0x30}, {0x12, 0x34}, {0x56}} {
+		req := &TomoXTrieRequest{Id: &light.TrieID{Root: root}, Key: key}
+		if err := req.Validate(nil, &Msg{MsgType: MsgProofsV2, Obj: prove(key)}); err != nil {
+			t.Errorf("key %x: proof rejected: %v", key, err)
+			continue
+		}
+		if req.Proof == nil || req.Proof.KeyCount() == 0 {
+			t.Errorf("key %x: proof not stored", key)
+		}
+	}
+	// Proofs against another root, with nodes of other paths or in another
+	// message are rejected
+	req := &TomoXTrieRequest{Id: &light.TrieID{Root: common.Hash{1}}, Key: []byte{0x12}}
+	if err := req.Validate(nil, &Msg{MsgType: MsgProofsV2, Obj: prove([]byte{0x12})}); err == nil {
+		t.Errorf("proof against unknown root accepted")
+	}
+	req = &TomoXTrieRequest{Id: &light.TrieID{Root: root}, Key: []byte{0x12}}
+	if err := req.Validate(nil, &Msg{MsgType: MsgProofsV2, Obj: prove([]byte{0x12}, []byte{0x56, 0x78})}); err != errUselessNodes {
+		t.Errorf("proof with useless nodes: have %v, want %v", err, errUselessNodes)
+	}
+	if err := req.Validate(nil, &Msg{MsgType: MsgProofsV1, Obj: prove([]byte{0x12})}); err != errInvalidMessageType {
+		t.Errorf("proof in wrong message: have %v, want %v", err, errInvalidMessageType)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/69th-byte/sdexchain/core/types"
+)
+
+// errNoOrderRelayPeers is returned if no connected server speaks a protocol
+// version able to relay order and lending transactions.
+var errNoOrderRelayPeers = errors.New("no LES servers available to relay TomoX transactions")
+
+// LesOrderRelay relays order and lending transactions to LES servers. Unlike
+// normal transactions they are not tracked until mined: the server's pools
+// take over as soon as they are accepted.
+type LesOrderRelay struct {
+	ps           *peerSet
+	peerList     []*peer
+	peerStartPos int
+	lock         sync.Mutex
+
+	reqDist *requestDistributor
+}
+
+func NewLesOrderRelay(ps *peerSet, reqDist *requestDistributor) *LesOrderRelay {
+	r := &LesOrderRelay{
+		ps:      ps,
+		reqDist: reqDist,
+	}
+	ps.notify(r)
+	return r
+}
+
+func (self *LesOrderRelay) registerPeer(p *peer) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.updatePeers()
+}
+
+func (self *LesOrderRelay) unregisterPeer(p *peer) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.updatePeers()
+}
+
+// updatePeers refreshes the list of peers able to relay TomoX transactions.
+func (self *LesOrderRelay) updatePeers() {
+	self.peerList = self.peerList[:0]
+	for _, p := range self.ps.AllPeers() {
+		if p.version >= lpv3 {
+			self.peerList = append(self.peerList, p)
+		}
+	}
+}
+
+// selectPeers returns at most count peers, rotating the starting position of
+// the peer list on every call.
+func (self *LesOrderRelay) selectPeers(count int) []*peer {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if len(self.peerList) == 0 {
+		return nil
+	}
+	self.peerStartPos++
+	if self.peerStartPos >= len(self.peerList) {
+		self.peerStartPos = 0
+	}
+	if count > len(self.peerList) {
+		count = len(self.peerList)
+	}
+	peers := make([]*peer, count)
+	for i := range peers {
+		peers[i] = self.peerList[(self.peerStartPos+i)%len(self.peerList)]
+	}
+	return peers
+}
+
+// send queues a request with the given message code to each of the selected
+// peers, the actual sending is done by the send callback.
+func (self *LesOrderRelay) send(msgcode uint64, amount int, send func(p *peer, reqID, cost uint64)) error {
+	peers := self.selectPeers(3)
+	if len(peers) == 0 {
+		return errNoOrderRelayPeers
+	}
+	for _, p := range peers {
+		pp := p
+
+		reqID := genReqID()
+		rq := &distReq{
+			getCost: func(dp distPeer) uint64 {
+				peer := dp.(*peer)
+				return peer.GetRequestCost(msgcode, amount)
+			},
+			canSend: func(dp distPeer) bool {
+				return dp.(*peer) == pp
+			},
+			request: func(dp distPeer) func() {
+				peer := dp.(*peer)
+				cost := peer.GetRequestCost(msgcode, amount)
+				peer.fcServer.QueueRequest(reqID, cost)
+				return func() { send(peer, reqID, cost) }
+			},
+		}
+		self.reqDist.queue(rq)
+	}
+	return nil
+}
+
+// SendOrders relays a batch of order transactions to a few LES servers.
+func (self *LesOrderRelay) SendOrders(txs types.OrderTransactions) error {
+	return self.send(SendOrderTxMsg, len(txs), func(p *peer, reqID, cost uint64) {
+		p.SendOrderTxs(reqID, cost, txs)
+	})
+}
+
+// SendLendings relays a batch of lending transactions to a few LES servers.
+func (self *LesOrderRelay) SendLendings(txs types.LendingTransactions) error {
+	return self.send(SendLendingTxMsg, len(txs), func(p *peer, reqID, cost uint64) {
+		p.SendLendingTxs(reqID, cost, txs)
+	})
+}
@@ -276,7 +276,7 @@ func (p *peer) RequestProofs(reqID, cost uint64, reqs []ProofReq) error {
 	switch p.version {
 	case lpv1:
 		return sendRequest(p.rw, GetProofsV1Msg, reqID, cost, reqs)
-	case lpv2:
+	case lpv2, lpv3:
 		return sendRequest(p.rw, GetProofsV2Msg, reqID, cost, reqs)
 	default:
 		panic(nil)
@@ -298,7 +298,7 @@ func (p *peer) RequestHelperTrieProofs(reqID, cost uint64, reqs []HelperTrieReq)
 			reqsV1[i] = ChtReq{ChtNum: (req.TrieIdx + 1) * (light.CHTFrequencyClient / light.CHTFrequencyServer), BlockNum: blockNum, FromLevel: req.FromLevel}
 		}
 		return sendRequest(p.rw, GetHeaderProofsMsg, reqID, cost, reqsV1)
-	case lpv2:
+	case lpv2, lpv3:
 		return sendRequest(p.rw, GetHelperTrieProofsMsg, reqID, cost, reqs)
 	default:
 		panic(nil)
@@ -317,13 +317,34 @@ func (p *peer) SendTxs(reqID, cost uint64, txs types.Transactions) error {
 	switch p.version {
 	case lpv1:
 		return p2p.Send(p.rw, SendTxMsg, txs) // old message format does not include reqID
-	case lpv2:
+	case lpv2, lpv3:
 		return sendRequest(p.rw, SendTxV2Msg, reqID, cost, txs)
 	default:
 		panic(nil)
 	}
 }
 
+// RequestTomoXProofs fetches a batch of TomoX trading or lending trie merkle
+// proofs from a remote node.
+func (p *peer) RequestTomoXProofs(reqID, cost uint64, reqs []TomoXProofReq) error {
+	p.Log().Debug("Fetching batch of TomoX proofs", "count", len(reqs))
+	return sendRequest(p.rw, GetTomoXProofsMsg, reqID, cost, reqs)
+}
+
+// SendOrderTxs sends a batch of order transactions to be added to the remote
+// order pool.
+func (p *peer) SendOrderTxs(reqID, cost uint64, txs types.OrderTransactions) error {
+	p.Log().Debug("Sending batch of order transactions", "count", len(txs))
+	return sendRequest(p.rw, SendOrderTxMsg, reqID, cost, txs)
+}
+
+// SendLendingTxs sends a batch of lending transactions to be added to the
+// remote lending pool.
+func (p *peer) SendLendingTxs(reqID, cost uint64, txs types.LendingTransactions) error {
+	p.Log().Debug("Sending batch of lending transactions", "count", len(txs))
+	return sendRequest(p.rw, SendLendingTxMsg, reqID, cost, txs)
+}
+
 type keyValueEntry struct {
 	Key   string
 	Value rlp.RawValue
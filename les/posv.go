@@ -0,0 +1,187 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/consensus/posv"
+	"github.com/69th-byte/sdexchain/core"
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/event"
+	"github.com/69th-byte/sdexchain/light"
+	"github.com/69th-byte/sdexchain/log"
+	"github.com/69th-byte/sdexchain/params"
+)
+
+const (
+	// chainEventChanSize is the size of channel listening to ChainEvent.
+	chainEventChanSize = 10
+
+	// posvRetrievalTimeout bounds the contract state retrievals done while
+	// verifying a single checkpoint.
+	posvRetrievalTimeout = 30 * time.Second
+)
+
+// posvTracker lets a light client verify PoSV headers without block bodies or
+// the full state. It wires the engine hooks the full node implements on top of
+// its local state to ODR retrievals of the validator and randomize contract
+// storage, and keeps the masternode set of the engine's snapshots up to date
+// epoch by epoch, starting from the genesis or a trusted CHT checkpoint.
+type posvTracker struct {
+	engine *posv.Posv
+	chain  *light.LightChain
+	odr    light.OdrBackend
+	config *params.PosvConfig
+
+	chainCh  chan core.ChainEvent
+	chainSub event.Subscription
+}
+
+func newPosvTracker(engine *posv.Posv, chain *light.LightChain, odr light.OdrBackend) *posvTracker {
+	t := &posvTracker{
+		engine:  engine,
+		chain:   chain,
+		odr:     odr,
+		config:  chain.Config().Posv,
+		chainCh: make(chan core.ChainEvent, chainEventChanSize),
+	}
+	engine.HookGetSignersFromContract = t.getSigners
+	engine.HookVerifyMNs = t.verifyValidators
+
+	t.chainSub = chain.SubscribeChainEvent(t.chainCh)
+	go t.loop()
+	return t
+}
+
+func (t *posvTracker) stop() {
+	t.chainSub.Unsubscribe()
+}
+
+// loop updates the masternodes of the engine's snapshot at every gap block,
+// like the full node does after importing it.
+func (t *posvTracker) loop() {
+	for {
+		select {
+		case ev := <-t.chainCh:
+			header := ev.Block.Header()
+			if number := header.Number.Uint64(); number == 0 || (number+t.config.Gap)%t.config.Epoch != 0 {
+				continue
+			}
+			if err := t.updateMasternodes(header); err != nil {
+				log.Warn("Failed to update masternodes", "number", header.Number, "hash", ev.Hash, "err", err)
+			}
+		case <-t.chainSub.Err():
+			return
+		}
+	}
+}
+
+func (t *posvTracker) updateMasternodes(header *types.Header) error {
+	ctx, cancel := context.WithTimeout(context.Background(), posvRetrievalTimeout)
+	defer cancel()
+
+	masternodes, err := light.GetMasternodesFromContract(ctx, t.odr, header)
+	if err != nil {
+		return err
+	}
+	return t.engine.UpdateMasternodes(t.chain.HeaderChain(), header, masternodes)
+}
+
+// getSigners implements posv.HookGetSignersFromContract, reading the masternode
+// candidates at the given gap block with Merkle proofs.
+func (t *posvTracker) getSigners(header *types.Header) ([]common.Address, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), posvRetrievalTimeout)
+	defer cancel()
+
+	masternodes, err := light.GetMasternodesFromContract(ctx, t.odr, header)
+	if err != nil {
+		return nil, err
+	}
+	signers := make([]common.Address, len(masternodes))
+	for i, m := range masternodes {
+		signers[i] = m.Address
+	}
+	return signers, nil
+}
+
+// verifyValidators implements posv.HookVerifyMNs, checking the double validation
+// list of a randomize checkpoint against the randomize contract state it was
+// derived from.
+func (t *posvTracker) verifyValidators(header, parent *types.Header, signers []common.Address) error {
+	number := header.Number.Int64()
+	if number == 0 || number%common.EpocBlockRandomize != 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), posvRetrievalTimeout)
+	defer cancel()
+
+	validators, err := light.GetValidatorsFromContract(ctx, t.odr, parent, signers)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(header.Validators, validators) {
+		return posv.ErrInvalidCheckpointValidators
+	}
+	return nil
+}
+
+// trustHead makes sure the headers on top of the current head can be verified.
+// A light client that synced a CHT has no ancestors to build a snapshot from,
+// so the masternodes of the head's epoch are taken from the checkpoint header
+// (or, past the gap block, the validator contract) proven against the CHT, and
+// the checkpoint headers carrying the recent penalties are retrieved.
+func (t *posvTracker) trustHead(ctx context.Context) error {
+	head := t.chain.CurrentHeader()
+	if _, err := t.engine.GetSnapshot(t.chain.HeaderChain(), head); err == nil {
+		return nil
+	}
+	number := head.Number.Uint64()
+	checkpoint := number - number%t.config.Epoch
+
+	var masternodes []common.Address
+	if gap := checkpoint + t.config.Epoch - t.config.Gap; number >= gap {
+		header, err := t.chain.GetHeaderByNumberOdr(ctx, gap)
+		if err != nil {
+			return err
+		}
+		if masternodes, err = t.getSigners(header); err != nil {
+			return err
+		}
+	} else {
+		header, err := t.chain.GetHeaderByNumberOdr(ctx, checkpoint)
+		if err != nil {
+			return err
+		}
+		masternodes = posv.GetMasternodesFromCheckpointHeader(header)
+	}
+	// The next checkpoint drops the masternodes penalized by the previous ones,
+	// retrieve those headers so the engine finds them locally.
+	for i := uint64(0); i < common.LimitPenaltyEpoch; i++ {
+		if checkpoint <= i*t.config.Epoch {
+			break
+		}
+		if _, err := t.chain.GetHeaderByNumberOdr(ctx, checkpoint-i*t.config.Epoch); err != nil {
+			return err
+		}
+	}
+	log.Info("Trusting masternodes of checkpoint", "number", number, "hash", head.Hash(), "masternodes", len(masternodes))
+	return t.engine.SetTrustedSnapshot(number, head.Hash(), masternodes)
+}
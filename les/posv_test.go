@@ -0,0 +1,207 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/consensus"
+	"github.com/69th-byte/sdexchain/consensus/posv"
+	"github.com/69th-byte/sdexchain/core"
+	"github.com/69th-byte/sdexchain/core/rawdb"
+	"github.com/69th-byte/sdexchain/core/state"
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/ethdb"
+	"github.com/69th-byte/sdexchain/light"
+	"github.com/69th-byte/sdexchain/params"
+	"github.com/69th-byte/sdexchain/trie"
+)
+
+// posvTestOdr serves the contract storage of a full node database and has no
+// CHT, so headers missing locally cannot be retrieved.
+type posvTestOdr struct {
+	light.OdrBackend
+	sdb, ldb ethdb.Database
+}
+
+func (odr *posvTestOdr) Database() ethdb.Database       { return odr.ldb }
+func (odr *posvTestOdr) ChtIndexer() *core.ChainIndexer { return nil }
+
+func (odr *posvTestOdr) Retrieve(ctx context.Context, req light.OdrRequest) error {
+	switch req := req.(type) {
+	case *light.TrieRequest:
+		t, _ := trie.New(req.Id.Root, trie.NewDatabase(odr.sdb))
+		nodes := light.NewNodeSet()
+		t.Prove(req.Key, 0, nodes)
+		req.Proof = nodes
+	case *light.CodeRequest:
+		req.Data, _ = odr.sdb.Get(req.Hash[:])
+	}
+	req.StoreResult(odr.ldb)
+	return nil
+}
+
+// newPosvTestTracker creates a light chain whose local headers are the genesis
+// and the given ones, as after syncing a CHT, with the last one as head.
+func newPosvTestTracker(t *testing.T, odr *posvTestOdr, headers []*types.Header) *posvTracker {
+	config := *params.TestChainConfig
+	config.Posv = &params.PosvConfig{Period: 2, Epoch: 10, Gap: 5}
+
+	genesis := (&core.Genesis{Config: &config, ExtraData: make([]byte, 32+65)}).MustCommit(odr.ldb)
+	parent := genesis.Hash()
+	for _, header := range headers {
+		header.ParentHash = parent
+		core.WriteHeader(odr.ldb, header)
+		core.WriteTd(odr.ldb, header.Hash(), header.Number.Uint64(), header.Number)
+		core.WriteCanonicalHash(odr.ldb, header.Hash(), header.Number.Uint64())
+		parent = header.Hash()
+	}
+	core.WriteHeadHeaderHash(odr.ldb, parent)
+
+	engine := posv.New(config.Posv, rawdb.NewMemoryDatabase())
+	chain, err := light.NewLightChain(odr, &config, engine)
+	if err != nil {
+		t.Fatalf("failed to create light chain: %v", err)
+	}
+	return &posvTracker{engine: engine, chain: chain, odr: odr, config: config.Posv}
+}
+
+// posvTestCheckpoint creates a checkpoint header listing the given masternodes
+// and penalties.
+func posvTestCheckpoint(number int64, masternodes, penalties []common.Address) *types.Header {
+	extra := make([]byte, 32)
+	for _, m := range masternodes {
+		extra = append(extra, m[:]...)
+	}
+	return &types.Header{
+		Number:    big.NewInt(number),
+		Extra:     append(extra, make([]byte, 65)...),
+		Penalties: common.ExtractAddressToBytes(penalties),
+	}
+}
+
+// setPosvCandidate appends a candidate with the given cap to the validator contract.
+func setPosvCandidate(statedb *state.StateDB, candidate common.Address, cap int64) {
+	validator := common.HexToAddress(common.MasternodeVotingSMC)
+
+	slot := common.BigToHash(big.NewInt(3))
+	length := statedb.GetState(validator, slot).Big().Uint64()
+	statedb.SetState(validator, state.GetLocDynamicArrAtElement(slot, length, 1), candidate.Hash())
+	statedb.SetState(validator, slot, common.BigToHash(new(big.Int).SetUint64(length+1)))
+
+	loc := state.GetLocMappingAtKey(candidate.Hash(), 1)
+	statedb.SetState(validator, common.BigToHash(loc.Add(loc, common.Big1)), common.BigToHash(big.NewInt(cap)))
+}
+
+func checkPosvSigners(t *testing.T, tracker *posvTracker, want []common.Address) {
+	snap, err := tracker.engine.GetSnapshot(tracker.chain.HeaderChain(), tracker.chain.CurrentHeader())
+	if err != nil {
+		t.Fatalf("no snapshot of trusted head: %v", err)
+	}
+	if have := snap.GetSigners(); !bytes.Equal(common.ExtractAddressToBytes(have), common.ExtractAddressToBytes(want)) {
+		t.Errorf("signers mismatch: have %x, want %x", have, want)
+	}
+}
+
+func TestPosvTrustHeadCheckpoint(t *testing.T) {
+	var (
+		m1 = common.HexToAddress("0x01")
+		m2 = common.HexToAddress("0x02")
+		m3 = common.HexToAddress("0x03")
+	)
+	odr := &posvTestOdr{sdb: rawdb.NewMemoryDatabase(), ldb: rawdb.NewMemoryDatabase()}
+	tracker := newPosvTestTracker(t, odr, []*types.Header{
+		posvTestCheckpoint(10, []common.Address{m1, m2, m3}, nil),
+		posvTestCheckpoint(30, []common.Address{m1, m2}, []common.Address{m3}),
+		posvTestCheckpoint(40, []common.Address{m1, m2}, nil),
+		{Number: big.NewInt(41)},
+		{Number: big.NewInt(42)},
+	})
+
+	// The checkpoint at 20 may penalize masternodes of the next checkpoint and
+	// cannot be retrieved, so the head is not trusted.
+	if err := tracker.trustHead(context.Background()); err != light.ErrNoTrustedCht {
+		t.Fatalf("trusted head without penalty checkpoint: have %v, want %v", err, light.ErrNoTrustedCht)
+	}
+	if _, err := posv.RemovePenaltiesFromBlock(tracker.chain.HeaderChain(), []common.Address{m1}, 20); err != consensus.ErrUnknownAncestor {
+		t.Errorf("removed penalties of missing checkpoint: have %v, want %v", err, consensus.ErrUnknownAncestor)
+	}
+
+	checkpoint := posvTestCheckpoint(20, []common.Address{m1, m2, m3}, nil)
+	core.WriteHeader(odr.ldb, checkpoint)
+	core.WriteCanonicalHash(odr.ldb, checkpoint.Hash(), 20)
+
+	if err := tracker.trustHead(context.Background()); err != nil {
+		t.Fatalf("failed to trust head: %v", err)
+	}
+	checkPosvSigners(t, tracker, []common.Address{m1, m2})
+
+	masternodes, err := posv.RemovePenaltiesFromBlock(tracker.chain.HeaderChain(), []common.Address{m1, m2, m3}, 30)
+	if err != nil {
+		t.Fatalf("failed to remove penalties: %v", err)
+	}
+	if want := []common.Address{m1, m2}; !bytes.Equal(common.ExtractAddressToBytes(masternodes), common.ExtractAddressToBytes(want)) {
+		t.Errorf("masternodes mismatch: have %x, want %x", masternodes, want)
+	}
+}
+
+func TestPosvTrustHeadGap(t *testing.T) {
+	var (
+		m1 = common.HexToAddress("0x01")
+		m2 = common.HexToAddress("0x02")
+		m3 = common.HexToAddress("0x03")
+	)
+	sdb := rawdb.NewMemoryDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(sdb))
+	setPosvCandidate(statedb, m1, 10)
+	setPosvCandidate(statedb, m2, 30)
+	setPosvCandidate(statedb, m3, 30)
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := statedb.Database().TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to write state: %v", err)
+	}
+	odr := &posvTestOdr{sdb: sdb, ldb: rawdb.NewMemoryDatabase()}
+	tracker := newPosvTestTracker(t, odr, []*types.Header{
+		posvTestCheckpoint(10, []common.Address{m1}, nil),
+		posvTestCheckpoint(20, []common.Address{m1}, nil),
+		posvTestCheckpoint(30, []common.Address{m1}, nil),
+		posvTestCheckpoint(40, []common.Address{m1}, nil),
+		{Number: big.NewInt(45), Root: root},
+		{Number: big.NewInt(46)},
+	})
+
+	// Past the gap block the next masternodes are read from the contract, equal
+	// stakes ordered the way the full node orders them.
+	signers, err := tracker.getSigners(tracker.chain.GetHeaderByNumber(45))
+	if err != nil {
+		t.Fatalf("failed to retrieve signers: %v", err)
+	}
+	if want := []common.Address{m3, m2, m1}; !bytes.Equal(common.ExtractAddressToBytes(signers), common.ExtractAddressToBytes(want)) {
+		t.Errorf("signers mismatch: have %x, want %x", signers, want)
+	}
+	if err := tracker.trustHead(context.Background()); err != nil {
+		t.Fatalf("failed to trust head: %v", err)
+	}
+	checkPosvSigners(t, tracker, []common.Address{m1, m2, m3})
+}
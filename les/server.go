@@ -90,6 +90,19 @@ func NewLesServer(eth *eth.Ethereum, config *eth.Config) (*LesServer, error) {
 		logger.Info("Loaded bloom trie", "section", bloomTrieLastSection, "head", bloomTrieSectionHead, "root", bloomTrieRoot)
 	}
 
+	if orderPool := eth.OrderPool(); orderPool != nil {
+		pm.orderpool = orderPool
+	}
+	if lendingPool := eth.LendingPool(); lendingPool != nil {
+		pm.lendingpool = lendingPool
+	}
+	if tomoX := eth.GetTomoX(); tomoX != nil && tomoX.GetStateCache() != nil {
+		pm.tradingTrieDb = tomoX.GetStateCache().TrieDB()
+	}
+	if lending := eth.GetTomoXLending(); lending != nil && lending.GetStateCache() != nil {
+		pm.lendingTrieDb = lending.GetStateCache().TrieDB()
+	}
+
 	srv.chtIndexer.Start(eth.BlockChain())
 	pm.server = srv
 
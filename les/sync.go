@@ -23,6 +23,7 @@ import (
 	"github.com/69th-byte/sdexchain/core"
 	"github.com/69th-byte/sdexchain/eth/downloader"
 	"github.com/69th-byte/sdexchain/light"
+	"github.com/69th-byte/sdexchain/log"
 )
 
 const (
@@ -80,5 +81,11 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
 	pm.blockchain.(*light.LightChain).SyncCht(ctx)
+	if pm.posv != nil {
+		if err := pm.posv.trustHead(ctx); err != nil {
+			log.Warn("Failed to retrieve masternodes of the head", "err", err)
+			return
+		}
+	}
 	pm.downloader.Synchronise(peer.id, peer.Head(), peer.Td(), downloader.LightSync)
 }
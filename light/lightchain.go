@@ -205,6 +205,10 @@ func (bc *LightChain) ResetWithGenesisBlock(genesis *types.Block) {
 // Engine retrieves the light chain's consensus engine.
 func (bc *LightChain) Engine() consensus.Engine { return bc.engine }
 
+// HeaderChain returns the underlying header chain, which implements
+// consensus.ChainReader.
+func (bc *LightChain) HeaderChain() *core.HeaderChain { return bc.hc }
+
 // Genesis returns the genesis block
 func (bc *LightChain) Genesis() *types.Block {
 	return bc.genesisBlock
@@ -89,6 +89,22 @@ func (req *TrieRequest) StoreResult(db ethdb.Database) {
 	req.Proof.Store(db)
 }
 
+// TomoXTrieRequest is the ODR request type for TomoX trading and lending trie
+// entries. Id.Root is either the root committed to by the block's TomoX state
+// transaction or a sub-trie root read from an already verified parent trie.
+type TomoXTrieRequest struct {
+	OdrRequest
+	Id      *TrieID
+	Lending bool
+	Key     []byte
+	Proof   *NodeSet
+}
+
+// StoreResult stores the retrieved data in local database
+func (req *TomoXTrieRequest) StoreResult(db ethdb.Database) {
+	req.Proof.Store(db)
+}
+
 // CodeRequest is the ODR request type for retrieving contract code
 type CodeRequest struct {
 	OdrRequest
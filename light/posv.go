@@ -0,0 +1,74 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"context"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/consensus/posv"
+	"github.com/69th-byte/sdexchain/contracts"
+	"github.com/69th-byte/sdexchain/core"
+	"github.com/69th-byte/sdexchain/core/state"
+	"github.com/69th-byte/sdexchain/core/types"
+)
+
+// GetMasternodesFromContract reads the masternode candidates of the validator
+// contract at the given block, ordered and capped by posv.SortMasternodes. The
+// contract storage is retrieved with Merkle proofs
+// against the header's state root.
+func GetMasternodesFromContract(ctx context.Context, odr OdrBackend, header *types.Header) ([]posv.Masternode, error) {
+	statedb := NewState(ctx, header, odr)
+	var masternodes []posv.Masternode
+	for _, candidate := range state.GetCandidates(statedb) {
+		if candidate == (common.Address{}) {
+			continue
+		}
+		masternodes = append(masternodes, posv.Masternode{Address: candidate, Stake: state.GetCandidateCap(statedb, candidate)})
+	}
+	if err := statedb.Error(); err != nil {
+		return nil, err
+	}
+	return posv.SortMasternodes(masternodes), nil
+}
+
+// GetValidatorsFromContract computes the double validation list of the given
+// masternodes from the secrets and openings they revealed to the randomize
+// contract up to the given block.
+func GetValidatorsFromContract(ctx context.Context, odr OdrBackend, header *types.Header, masternodes []common.Address) ([]byte, error) {
+	if len(masternodes) == 0 {
+		return nil, core.ErrNotFoundM1
+	}
+	statedb := NewState(ctx, header, odr)
+	randomizes := make([]int64, 0, len(masternodes))
+	for _, addr := range masternodes {
+		secrets, opening := state.GetSecret(statedb, addr), state.GetOpening(statedb, addr)
+		if err := statedb.Error(); err != nil {
+			return nil, err
+		}
+		random, err := contracts.DecryptRandomizeFromSecretsAndOpening(secrets, opening)
+		if err != nil {
+			return nil, err
+		}
+		randomizes = append(randomizes, random)
+	}
+	m2, err := contracts.GenM2FromRandomize(randomizes, int64(len(masternodes)))
+	if err != nil {
+		return nil, err
+	}
+	return contracts.BuildValidatorFromM2(m2), nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"strconv"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/contracts"
+	"github.com/69th-byte/sdexchain/core"
+	"github.com/69th-byte/sdexchain/core/rawdb"
+	"github.com/69th-byte/sdexchain/core/state"
+	"github.com/69th-byte/sdexchain/core/types"
+)
+
+var (
+	posvValidator = common.HexToAddress(common.MasternodeVotingSMC)
+	posvRandomize = common.HexToAddress(common.RandomizeSMC)
+)
+
+// setCandidate appends a candidate with the given cap to the validator contract.
+func setCandidate(statedb *state.StateDB, candidate common.Address, cap int64) {
+	slot := common.BigToHash(big.NewInt(3))
+	length := statedb.GetState(posvValidator, slot).Big().Uint64()
+	statedb.SetState(posvValidator, state.GetLocDynamicArrAtElement(slot, length, 1), candidate.Hash())
+	statedb.SetState(posvValidator, slot, common.BigToHash(new(big.Int).SetUint64(length+1)))
+
+	loc := state.GetLocMappingAtKey(candidate.Hash(), 1)
+	statedb.SetState(posvValidator, common.BigToHash(loc.Add(loc, common.Big1)), common.BigToHash(big.NewInt(cap)))
+}
+
+// setRandomize reveals the secret random number of a masternode.
+func setRandomize(statedb *state.StateDB, masternode common.Address, random int) {
+	opening := masternode.Hash()
+	var secret common.Hash
+	copy(secret[:], common.LeftPadBytes([]byte(contracts.Encrypt(opening[:], strconv.Itoa(random))), 32))
+
+	loc := common.BigToHash(state.GetLocMappingAtKey(masternode.Hash(), 0))
+	statedb.SetState(posvRandomize, loc, common.BigToHash(common.Big1))
+	statedb.SetState(posvRandomize, state.GetLocDynamicArrAtElement(loc, 0, 1), secret)
+	statedb.SetState(posvRandomize, common.BigToHash(state.GetLocMappingAtKey(masternode.Hash(), 1)), opening)
+}
+
+func TestGetMasternodesFromContract(t *testing.T) {
+	sdb := rawdb.NewMemoryDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(sdb))
+
+	var (
+		low  = common.HexToAddress("0x01")
+		high = common.HexToAddress("0x02")
+		mid  = common.HexToAddress("0x03")
+	)
+	setCandidate(statedb, low, 10)
+	setCandidate(statedb, high, 30)
+	setCandidate(statedb, common.Address{}, 50)
+	setCandidate(statedb, mid, 20)
+	for i, m := range []common.Address{low, high, mid} {
+		setRandomize(statedb, m, i+1)
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := statedb.Database().TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to write state: %v", err)
+	}
+	header := &types.Header{Number: big.NewInt(1), Root: root}
+
+	odr := &testOdr{sdb: sdb, ldb: rawdb.NewMemoryDatabase()}
+	masternodes, err := GetMasternodesFromContract(context.Background(), odr, header)
+	if err != nil {
+		t.Fatalf("failed to retrieve masternodes: %v", err)
+	}
+	want := []common.Address{high, mid, low}
+	if len(masternodes) != len(want) {
+		t.Fatalf("masternode count mismatch: have %d, want %d", len(masternodes), len(want))
+	}
+	for i, m := range masternodes {
+		if m.Address != want[i] {
+			t.Errorf("masternode %d mismatch: have %x, want %x", i, m.Address, want[i])
+		}
+	}
+
+	validators, err := GetValidatorsFromContract(context.Background(), odr, header, want)
+	if err != nil {
+		t.Fatalf("failed to compute validators: %v", err)
+	}
+	m2, _ := contracts.GenM2FromRandomize([]int64{2, 3, 1}, int64(len(want)))
+	if exp := contracts.BuildValidatorFromM2(m2); !bytes.Equal(validators, exp) {
+		t.Errorf("validators mismatch: have %x, want %x", validators, exp)
+	}
+	if _, err := GetValidatorsFromContract(context.Background(), odr, header, nil); err != core.ErrNotFoundM1 {
+		t.Errorf("validators of no masternodes: have %v, want %v", err, core.ErrNotFoundM1)
+	}
+
+	// Without ODR the contract storage cannot be proven
+	odr = &testOdr{sdb: sdb, ldb: rawdb.NewMemoryDatabase(), disable: true}
+	if _, err := GetMasternodesFromContract(context.Background(), odr, header); err != ErrOdrDisabled {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrOdrDisabled)
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/ethdb"
+	"github.com/69th-byte/sdexchain/tomox/tradingstate"
+	"github.com/69th-byte/sdexchain/tomoxlending/lendingstate"
+	"github.com/69th-byte/sdexchain/trie"
+)
+
+// GetTomoXStateRoots retrieves the trading and lending state roots committed to
+// by the TomoX state transaction that author included in the given block. Both
+// roots are empty if the block carries no such transaction.
+func GetTomoXStateRoots(ctx context.Context, odr OdrBackend, header *types.Header, author common.Address) (trading common.Hash, lending common.Hash, err error) {
+	block, err := GetBlock(ctx, odr, header.Hash(), header.Number.Uint64())
+	if err != nil {
+		return common.Hash{}, common.Hash{}, err
+	}
+	trading, lending = tradingstate.EmptyRoot, lendingstate.EmptyRoot
+	for _, tx := range block.Transactions() {
+		if tx.To() == nil || tx.To().Hex() != common.TradingStateAddr {
+			continue
+		}
+		if from := tx.From(); from == nil || *from != author {
+			continue
+		}
+		if data := tx.Data(); len(data) >= 32 {
+			trading = common.BytesToHash(data[:32])
+			if len(data) >= 64 {
+				lending = common.BytesToHash(data[32:64])
+			}
+		}
+		break
+	}
+	return trading, lending, nil
+}
+
+// NewTradingState creates a trading state of the given block backed by ODR,
+// fetching missing trie nodes together with Merkle proofs against root.
+func NewTradingState(ctx context.Context, head *types.Header, root common.Hash, odr OdrBackend) *tradingstate.TradingStateDB {
+	state, _ := tradingstate.New(root, &odrTradingDatabase{odrTomoXDatabase{ctx, tomoXTrieID(head, root), odr, false}})
+	return state
+}
+
+// NewLendingState creates a lending state of the given block backed by ODR,
+// fetching missing trie nodes together with Merkle proofs against root.
+func NewLendingState(ctx context.Context, head *types.Header, root common.Hash, odr OdrBackend) *lendingstate.LendingStateDB {
+	state, _ := lendingstate.New(root, &odrLendingDatabase{odrTomoXDatabase{ctx, tomoXTrieID(head, root), odr, true}})
+	return state
+}
+
+func tomoXTrieID(head *types.Header, root common.Hash) *TrieID {
+	return &TrieID{
+		BlockHash:   head.Hash(),
+		BlockNumber: head.Number.Uint64(),
+		Root:        root,
+	}
+}
+
+// odrTomoXDatabase holds what the trading and lending databases share. TomoX
+// tries have no contract code, so only trie access needs ODR.
+type odrTomoXDatabase struct {
+	ctx     context.Context
+	id      *TrieID
+	backend OdrBackend
+	lending bool
+}
+
+func (db *odrTomoXDatabase) openTrie(root common.Hash) *odrTomoXTrie {
+	id := *db.id
+	id.Root = root
+	return &odrTomoXTrie{db: db, id: &id}
+}
+
+func (db *odrTomoXDatabase) openStorageTrie(addrHash, root common.Hash) *odrTomoXTrie {
+	return &odrTomoXTrie{db: db, id: StorageTrieID(db.id, addrHash, root)}
+}
+
+func (db *odrTomoXDatabase) copyTrie(t *odrTomoXTrie) *odrTomoXTrie {
+	cpy := &odrTomoXTrie{db: t.db, id: t.id}
+	if t.trie != nil {
+		cpytrie := *t.trie
+		cpy.trie = &cpytrie
+	}
+	return cpy
+}
+
+func (db *odrTomoXDatabase) ContractCode(addrHash, codeHash common.Hash) ([]byte, error) {
+	return nil, nil
+}
+
+func (db *odrTomoXDatabase) ContractCodeSize(addrHash, codeHash common.Hash) (int, error) {
+	return 0, nil
+}
+
+func (db *odrTomoXDatabase) TrieDB() *trie.Database {
+	return nil
+}
+
+type odrTradingDatabase struct {
+	odrTomoXDatabase
+}
+
+func (db *odrTradingDatabase) OpenTrie(root common.Hash) (tradingstate.Trie, error) {
+	return db.openTrie(root), nil
+}
+
+func (db *odrTradingDatabase) OpenStorageTrie(addrHash, root common.Hash) (tradingstate.Trie, error) {
+	return db.openStorageTrie(addrHash, root), nil
+}
+
+func (db *odrTradingDatabase) CopyTrie(t tradingstate.Trie) tradingstate.Trie {
+	switch t := t.(type) {
+	case *odrTomoXTrie:
+		return db.copyTrie(t)
+	default:
+		panic(fmt.Errorf("unknown trie type %T", t))
+	}
+}
+
+type odrLendingDatabase struct {
+	odrTomoXDatabase
+}
+
+func (db *odrLendingDatabase) OpenTrie(root common.Hash) (lendingstate.Trie, error) {
+	return db.openTrie(root), nil
+}
+
+func (db *odrLendingDatabase) OpenStorageTrie(addrHash, root common.Hash) (lendingstate.Trie, error) {
+	return db.openStorageTrie(addrHash, root), nil
+}
+
+func (db *odrLendingDatabase) CopyTrie(t lendingstate.Trie) lendingstate.Trie {
+	switch t := t.(type) {
+	case *odrTomoXTrie:
+		return db.copyTrie(t)
+	default:
+		panic(fmt.Errorf("unknown trie type %T", t))
+	}
+}
+
+// odrTomoXTrie is a TomoX trie which retrieves its missing nodes on demand.
+// Unlike the state trie its keys are not hashed, and the ordered lookups used
+// by the matching engine walk the trie without a key, so missing nodes are
+// requested by the path they were found missing at.
+type odrTomoXTrie struct {
+	db   *odrTomoXDatabase
+	id   *TrieID
+	trie *trie.Trie
+}
+
+func (t *odrTomoXTrie) TryGet(key []byte) ([]byte, error) {
+	var res []byte
+	err := t.do(func() (err error) {
+		res, err = t.trie.TryGet(key)
+		return err
+	})
+	return res, err
+}
+
+func (t *odrTomoXTrie) TryGetBestLeftKeyAndValue() ([]byte, []byte, error) {
+	var key, value []byte
+	err := t.do(func() (err error) {
+		key, value, err = t.trie.TryGetBestLeftKeyAndValue()
+		return err
+	})
+	return key, value, err
+}
+
+func (t *odrTomoXTrie) TryGetAllLeftKeyAndValue(limit []byte) ([][]byte, [][]byte, error) {
+	var keys, values [][]byte
+	err := t.do(func() (err error) {
+		keys, values, err = t.trie.TryGetAllLeftKeyAndValue(limit)
+		return err
+	})
+	return keys, values, err
+}
+
+func (t *odrTomoXTrie) TryGetBestRightKeyAndValue() ([]byte, []byte, error) {
+	var key, value []byte
+	err := t.do(func() (err error) {
+		key, value, err = t.trie.TryGetBestRightKeyAndValue()
+		return err
+	})
+	return key, value, err
+}
+
+func (t *odrTomoXTrie) TryUpdate(key, value []byte) error {
+	return t.do(func() error {
+		return t.trie.TryUpdate(key, value)
+	})
+}
+
+func (t *odrTomoXTrie) TryDelete(key []byte) error {
+	return t.do(func() error {
+		return t.trie.TryDelete(key)
+	})
+}
+
+func (t *odrTomoXTrie) Commit(onleaf trie.LeafCallback) (common.Hash, error) {
+	if t.trie == nil {
+		return t.id.Root, nil
+	}
+	return t.trie.Commit(onleaf)
+}
+
+func (t *odrTomoXTrie) Hash() common.Hash {
+	if t.trie == nil {
+		return t.id.Root
+	}
+	return t.trie.Hash()
+}
+
+// NodeIterator iterates over the locally available nodes only, it does not
+// retrieve the subtries it finds missing.
+func (t *odrTomoXTrie) NodeIterator(startkey []byte) trie.NodeIterator {
+	if err := t.do(func() error { return nil }); err != nil {
+		empty, _ := trie.New(common.Hash{}, trie.NewDatabase(t.db.backend.Database()))
+		return empty.NodeIterator(startkey)
+	}
+	return t.trie.NodeIterator(startkey)
+}
+
+func (t *odrTomoXTrie) GetKey(key []byte) []byte {
+	return key
+}
+
+func (t *odrTomoXTrie) Prove(key []byte, fromLevel uint, proofDb ethdb.KeyValueWriter) error {
+	return errors.New("not implemented, needs client/server interface split")
+}
+
+// do tries and retries to execute a function until it returns with no error or
+// an error type other than MissingNodeError
+func (t *odrTomoXTrie) do(fn func() error) error {
+	var lasthash common.Hash
+	for {
+		var err error
+		if t.trie == nil {
+			t.trie, err = trie.New(t.id.Root, trie.NewDatabase(t.db.backend.Database()))
+		}
+		if err == nil {
+			err = fn()
+		}
+		missing, ok := err.(*trie.MissingNodeError)
+		if !ok {
+			return err
+		}
+		if missing.NodeHash == lasthash {
+			return fmt.Errorf("retrieve loop for trie node %x", missing.NodeHash)
+		}
+		lasthash = missing.NodeHash
+		r := &TomoXTrieRequest{Id: t.id, Lending: t.db.lending, Key: nibblesToKey(missing.Path)}
+		if err := t.db.backend.Retrieve(t.db.ctx, r); err != nil {
+			return err
+		}
+	}
+}
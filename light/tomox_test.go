@@ -0,0 +1,155 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/core"
+	"github.com/69th-byte/sdexchain/core/rawdb"
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/params"
+	"github.com/69th-byte/sdexchain/tomox/tradingstate"
+	"github.com/69th-byte/sdexchain/tomoxlending/lendingstate"
+)
+
+func TestOdrTradingState(t *testing.T) {
+	sdb := rawdb.NewMemoryDatabase()
+	stateCache := tradingstate.NewDatabase(sdb)
+	statedb, _ := tradingstate.New(tradingstate.EmptyRoot, stateCache)
+
+	orderBook := common.StringToHash("BTC/TOMO")
+	relayer := common.BigToHash(big.NewInt(1))
+	statedb.SetNonce(relayer, 7)
+	for i := int64(1); i <= 10; i++ {
+		ask := tradingstate.OrderItem{OrderID: uint64(2 * i), Quantity: big.NewInt(i), Price: big.NewInt(100 + i), Side: tradingstate.Ask}
+		bid := tradingstate.OrderItem{OrderID: uint64(2*i + 1), Quantity: big.NewInt(i), Price: big.NewInt(100 - i), Side: tradingstate.Bid}
+		statedb.InsertOrderItem(orderBook, common.BigToHash(big.NewInt(2*i)), ask)
+		statedb.InsertOrderItem(orderBook, common.BigToHash(big.NewInt(2*i+1)), bid)
+	}
+	statedb.SetLastPrice(orderBook, big.NewInt(100))
+	root, err := statedb.Commit()
+	if err != nil {
+		t.Fatalf("failed to commit trading state: %v", err)
+	}
+	if err := stateCache.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to write trading state: %v", err)
+	}
+
+	odr := &testOdr{sdb: sdb, ldb: rawdb.NewMemoryDatabase()}
+	header := &types.Header{Number: big.NewInt(1)}
+	lstate := NewTradingState(context.Background(), header, root, odr)
+
+	if nonce := lstate.GetNonce(relayer); nonce != 7 {
+		t.Errorf("nonce mismatch: have %d, want 7", nonce)
+	}
+	if price := lstate.GetLastPrice(orderBook); price == nil || price.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("last price mismatch: have %v, want 100", price)
+	}
+	if price, volume := lstate.GetBestAskPrice(orderBook); price.Cmp(big.NewInt(101)) != 0 || volume.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("best ask mismatch: have %v/%v, want 101/1", price, volume)
+	}
+	if price, volume := lstate.GetBestBidPrice(orderBook); price.Cmp(big.NewInt(99)) != 0 || volume.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("best bid mismatch: have %v/%v, want 99/1", price, volume)
+	}
+	if err := lstate.Error(); err != nil {
+		t.Errorf("unexpected state error: %v", err)
+	}
+
+	// Without ODR the missing nodes cannot be resolved
+	odr = &testOdr{sdb: sdb, ldb: rawdb.NewMemoryDatabase(), disable: true}
+	lstate = NewTradingState(context.Background(), header, root, odr)
+	if nonce := lstate.GetNonce(relayer); nonce != 0 {
+		t.Errorf("nonce without ODR: have %d, want 0", nonce)
+	}
+	if err := lstate.Error(); err != ErrOdrDisabled {
+		t.Errorf("state error mismatch: have %v, want %v", err, ErrOdrDisabled)
+	}
+}
+
+func TestOdrLendingState(t *testing.T) {
+	sdb := rawdb.NewMemoryDatabase()
+	stateCache := lendingstate.NewDatabase(sdb)
+	statedb, _ := lendingstate.New(lendingstate.EmptyRoot, stateCache)
+
+	lendingBook := common.StringToHash("USDT/60")
+	for i := int64(1); i <= 5; i++ {
+		invest := lendingstate.LendingItem{LendingId: uint64(2 * i), Quantity: big.NewInt(i), Interest: big.NewInt(10 + i), Side: lendingstate.Investing}
+		borrow := lendingstate.LendingItem{LendingId: uint64(2*i + 1), Quantity: big.NewInt(i), Interest: big.NewInt(10 - i), Side: lendingstate.Borrowing}
+		statedb.InsertLendingItem(lendingBook, common.BigToHash(big.NewInt(2*i)), invest)
+		statedb.InsertLendingItem(lendingBook, common.BigToHash(big.NewInt(2*i+1)), borrow)
+	}
+	root, err := statedb.Commit()
+	if err != nil {
+		t.Fatalf("failed to commit lending state: %v", err)
+	}
+	if err := stateCache.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to write lending state: %v", err)
+	}
+
+	odr := &testOdr{sdb: sdb, ldb: rawdb.NewMemoryDatabase()}
+	lstate := NewLendingState(context.Background(), &types.Header{Number: big.NewInt(1)}, root, odr)
+
+	wantRate, wantVolume := statedb.GetBestInvestingRate(lendingBook)
+	if rate, volume := lstate.GetBestInvestingRate(lendingBook); rate.Cmp(wantRate) != 0 || volume.Cmp(wantVolume) != 0 {
+		t.Errorf("best investing rate mismatch: have %v/%v, want %v/%v", rate, volume, wantRate, wantVolume)
+	}
+	wantRate, wantVolume = statedb.GetBestBorrowRate(lendingBook)
+	if rate, volume := lstate.GetBestBorrowRate(lendingBook); rate.Cmp(wantRate) != 0 || volume.Cmp(wantVolume) != 0 {
+		t.Errorf("best borrow rate mismatch: have %v/%v, want %v/%v", rate, volume, wantRate, wantVolume)
+	}
+}
+
+func TestGetTomoXStateRoots(t *testing.T) {
+	var (
+		sdb     = rawdb.NewMemoryDatabase()
+		ldb     = rawdb.NewMemoryDatabase()
+		signer  = types.HomesteadSigner{}
+		to      = common.HexToAddress(common.TradingStateAddr)
+		trading = common.HexToHash("0x01")
+		lending = common.HexToHash("0x02")
+	)
+	// A forged state transaction from someone else precedes the author's one
+	data := append(common.HexToHash("0xdead").Bytes(), common.HexToHash("0xbeef").Bytes()...)
+	forged, _ := types.SignTx(types.NewTransaction(0, to, new(big.Int), params.TxGas, nil, data), signer, acc1Key)
+	data = append(trading.Bytes(), lending.Bytes()...)
+	valid, _ := types.SignTx(types.NewTransaction(0, to, new(big.Int), params.TxGas, nil, data), signer, testBankKey)
+
+	block := types.NewBlock(&types.Header{Number: big.NewInt(1)}, []*types.Transaction{forged, valid}, nil, nil)
+	core.WriteHeader(sdb, block.Header())
+	core.WriteHeader(ldb, block.Header())
+	core.WriteBody(sdb, block.Hash(), block.NumberU64(), block.Body())
+
+	odr := &testOdr{sdb: sdb, ldb: ldb}
+	haveTrading, haveLending, err := GetTomoXStateRoots(context.Background(), odr, block.Header(), testBankAddress)
+	if err != nil {
+		t.Fatalf("failed to retrieve state roots: %v", err)
+	}
+	if haveTrading != trading || haveLending != lending {
+		t.Errorf("state roots mismatch: have %x/%x, want %x/%x", haveTrading, haveLending, trading, lending)
+	}
+	haveTrading, haveLending, err = GetTomoXStateRoots(context.Background(), odr, block.Header(), acc2Addr)
+	if err != nil {
+		t.Fatalf("failed to retrieve state roots: %v", err)
+	}
+	if haveTrading != tradingstate.EmptyRoot || haveLending != lendingstate.EmptyRoot {
+		t.Errorf("state roots of a non author: have %x/%x, want empty", haveTrading, haveLending)
+	}
+}
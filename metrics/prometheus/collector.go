@@ -0,0 +1,138 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/69th-byte/sdexchain/metrics"
+)
+
+// contentType is the version 0.0.4 text exposition format.
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	typeGaugeTpl           = "# TYPE %s gauge\n"
+	typeCounterTpl         = "# TYPE %s counter\n"
+	typeSummaryTpl         = "# TYPE %s summary\n"
+	keyValueTpl            = "%s %v\n"
+	keyQuantileTagValueTpl = "%s{quantile=\"%s\"} %v\n"
+)
+
+// quantiles reported for histograms and timers.
+var quantiles = []float64{0.5, 0.75, 0.95, 0.99, 0.999, 0.9999}
+
+// collector aggregates the Prometheus report of different metric types into a
+// single byte buffer.
+type collector struct {
+	buff *bytes.Buffer
+}
+
+// newCollector creates a new Prometheus metric aggregator.
+func newCollector() *collector {
+	return &collector{
+		buff: &bytes.Buffer{},
+	}
+}
+
+func (c *collector) addCounter(name string, m metrics.Counter) {
+	c.writeCounter(name, m.Count())
+}
+
+func (c *collector) addGauge(name string, m metrics.Gauge) {
+	c.writeGauge(name, m.Value())
+}
+
+func (c *collector) addGaugeFloat64(name string, m metrics.GaugeFloat64) {
+	c.writeGauge(name, m.Value())
+}
+
+func (c *collector) addHistogram(name string, m metrics.Histogram) {
+	ps := m.Percentiles(quantiles)
+	values := make([]interface{}, len(ps))
+	for i, p := range ps {
+		values[i] = p
+	}
+	c.writeSummary(name, quantiles, values, m.Sum(), m.Count())
+}
+
+func (c *collector) addMeter(name string, m metrics.Meter) {
+	c.writeCounter(name, m.Count())
+}
+
+func (c *collector) addTimer(name string, m metrics.Timer) {
+	ps := m.Percentiles(quantiles)
+	values := make([]interface{}, len(ps))
+	for i, p := range ps {
+		values[i] = p
+	}
+	c.writeSummary(name, quantiles, values, m.Sum(), m.Count())
+}
+
+func (c *collector) addResettingTimer(name string, m metrics.ResettingTimer) {
+	if len(m.Values()) == 0 {
+		return
+	}
+	ps := m.Percentiles([]float64{50, 95, 99})
+	values := make([]interface{}, len(ps))
+	var sum int64
+	for i, p := range ps {
+		values[i] = p
+	}
+	for _, v := range m.Values() {
+		sum += v
+	}
+	c.writeSummary(name, []float64{0.5, 0.95, 0.99}, values, sum, len(m.Values()))
+}
+
+func (c *collector) writeGauge(name string, value interface{}) {
+	name = mutateKey(name)
+	c.buff.WriteString(fmt.Sprintf(typeGaugeTpl, name))
+	c.buff.WriteString(fmt.Sprintf(keyValueTpl, name, value))
+}
+
+func (c *collector) writeCounter(name string, value interface{}) {
+	name = mutateKey(name)
+	c.buff.WriteString(fmt.Sprintf(typeCounterTpl, name))
+	c.buff.WriteString(fmt.Sprintf(keyValueTpl, name, value))
+}
+
+func (c *collector) writeSummary(name string, qs []float64, values []interface{}, sum, count interface{}) {
+	name = mutateKey(name)
+	c.buff.WriteString(fmt.Sprintf(typeSummaryTpl, name))
+	for i, q := range qs {
+		c.buff.WriteString(fmt.Sprintf(keyQuantileTagValueTpl, name, strconv.FormatFloat(q, 'f', -1, 64), values[i]))
+	}
+	c.buff.WriteString(fmt.Sprintf(keyValueTpl, name+"_sum", sum))
+	c.buff.WriteString(fmt.Sprintf(keyValueTpl, name+"_count", count))
+}
+
+// mutateKey converts a go-metrics name into a valid Prometheus metric name,
+// replacing the path separators and any other invalid character with '_'.
+func mutateKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"github.com/69th-byte/sdexchain/core/rawdb"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -64,6 +65,10 @@ type Node struct {
 	httpListener  net.Listener // HTTP RPC listener socket to server API requests
 	httpHandler   *rpc.Server  // HTTP RPC request handler to process the API requests
 
+	httpMux      map[string]http.Handler // Additional handlers served on the HTTP endpoint, keyed by path
+	httpMuxNames map[string]string       // Descriptive names of the additional HTTP handlers
+	httpMuxLock  sync.Mutex              // Separate from lock, handlers are registered by service constructors during Start
+
 	wsEndpoint string       // Websocket endpoint (interface + port) to listen at (empty = websocket disabled)
 	wsListener net.Listener // Websocket RPC listener socket to server API requests
 	wsHandler  *rpc.Server  // Websocket RPC request handler to process the API requests
@@ -135,6 +140,21 @@ func (n *Node) Register(constructor ServiceConstructor) error {
 	return nil
 }
 
+// RegisterHandler mounts an additional HTTP handler on the given path of the
+// HTTP RPC endpoint. It is meant to be called by service constructors, the
+// handlers are only picked up when the HTTP endpoint is started.
+func (n *Node) RegisterHandler(name, path string, handler http.Handler) {
+	n.httpMuxLock.Lock()
+	defer n.httpMuxLock.Unlock()
+
+	if n.httpMux == nil {
+		n.httpMux = make(map[string]http.Handler)
+		n.httpMuxNames = make(map[string]string)
+	}
+	n.httpMux[path] = handler
+	n.httpMuxNames[path] = name
+}
+
 // Start create a live P2P node and starts running it.
 func (n *Node) Start() error {
 	n.lock.Lock()
@@ -394,8 +414,13 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	if listener, err = net.Listen("tcp", endpoint); err != nil {
 		return err
 	}
-	go rpc.NewHTTPServer(cors, vhosts, handler).Serve(listener)
+	go rpc.NewHTTPServer(cors, vhosts, n.httpServeMux(handler)).Serve(listener)
 	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s", endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","))
+	n.httpMuxLock.Lock()
+	for path, name := range n.httpMuxNames {
+		n.log.Info(fmt.Sprintf("%s endpoint opened", name), "url", fmt.Sprintf("http://%s%s", endpoint, path))
+	}
+	n.httpMuxLock.Unlock()
 	// All listeners booted successfully
 	n.httpEndpoint = endpoint
 	n.httpListener = listener
@@ -404,6 +429,24 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	return nil
 }
 
+// httpServeMux returns the handler serving the HTTP endpoint: the RPC server
+// itself if no additional handlers were registered, otherwise a multiplexer
+// dispatching the registered paths and falling back to the RPC server.
+func (n *Node) httpServeMux(rpcHandler *rpc.Server) http.Handler {
+	n.httpMuxLock.Lock()
+	defer n.httpMuxLock.Unlock()
+
+	if len(n.httpMux) == 0 {
+		return rpcHandler
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", rpcHandler)
+	for path, handler := range n.httpMux {
+		mux.Handle(path, handler)
+	}
+	return mux
+}
+
 // stopHTTP terminates the HTTP RPC endpoint.
 func (n *Node) stopHTTP() {
 	if n.httpListener != nil {
@@ -652,6 +695,25 @@ func (n *Node) OpenDatabase(name string, cache, handles int, namespace string) (
 	return rawdb.NewLevelDBDatabase(n.config.resolvePath(name), cache, handles, namespace)
 }
 
+// OpenDatabaseWithFreezer opens an existing database with the given name (or
+// creates one if no previous can be found) from within the node's instance
+// directory, also attaching a chain freezer to it that moves ancient chain data
+// from the database to immutable append-only files. If the node is ephemeral, a
+// memory database is returned.
+func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, freezer, namespace string, threshold uint64) (ethdb.Database, error) {
+	if n.config.DataDir == "" {
+		return rawdb.NewMemoryDatabase(), nil
+	}
+	root := n.config.resolvePath(name)
+	switch {
+	case freezer == "":
+		freezer = filepath.Join(root, "ancient")
+	case !filepath.IsAbs(freezer):
+		freezer = n.config.resolvePath(freezer)
+	}
+	return rawdb.NewLevelDBDatabaseWithFreezer(root, cache, handles, freezer, namespace, threshold)
+}
+
 // ResolvePath returns the absolute path of a resource in the instance directory.
 func (n *Node) ResolvePath(x string) string {
 	return n.config.resolvePath(x)
@@ -18,7 +18,10 @@ package node
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"reflect"
 	"testing"
@@ -573,3 +576,50 @@ func TestAPIGather(t *testing.T) {
 		}
 	}
 }
+
+// Tests that handlers registered by service constructors are served on the HTTP
+// endpoint, behind the same virtual host checks as the RPC server.
+func TestHTTPHandlerRegistration(t *testing.T) {
+	config := testNodeConfig()
+	config.HTTPHost = "127.0.0.1"
+	config.HTTPPort = 0
+
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	constructor := func(*ServiceContext) (Service, error) {
+		stack.RegisterHandler("Test", "/test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "test")
+		}))
+		return new(NoopService), nil
+	}
+	if err := stack.Register(constructor); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	url := fmt.Sprintf("http://%s/test", stack.httpListener.Addr())
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("failed to query handler: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "test" {
+		t.Fatalf("handler response mismatch: have %q, want %q", body, "test")
+	}
+	// Requests for unknown virtual hosts must be rejected
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Host = "example.com"
+	if resp, err = http.DefaultClient.Do(req); err != nil {
+		t.Fatalf("failed to query handler: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("status mismatch for unknown host: have %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
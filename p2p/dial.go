@@ -21,6 +21,7 @@ import (
 	"crypto/rand"
 	"errors"
 	"fmt"
+	mrand "math/rand"
 	"net"
 	"time"
 
@@ -42,6 +43,9 @@ const (
 	// attempted to be connected.
 	fallbackInterval = 20 * time.Second
 
+	// DNS node lists are queried at most this often.
+	dnsInterval = 30 * time.Second
+
 	// Endpoint resolution is throttled with bounded backoff.
 	initialResolveDelay = 60 * time.Second
 	maxResolveDelay     = time.Hour
@@ -82,6 +86,16 @@ type dialstate struct {
 
 	start     time.Time        // time when the dialer was first used
 	bootnodes []*discover.Node // default dials when there are no peers
+
+	dns        nodeSource // DNS node lists, nil if DNS discovery is disabled
+	dnsRunning bool
+	nextDNS    time.Time // earliest time of the next DNS query
+}
+
+// nodeSource is a source of dial candidates besides the discovery table, like
+// the node lists of DNS discovery.
+type nodeSource interface {
+	Nodes() []*discover.Node
 }
 
 type discoverTable interface {
@@ -121,6 +135,13 @@ type discoverTask struct {
 	results []*discover.Node
 }
 
+// dnsTask retrieves the nodes of the DNS node lists.
+// Only one dnsTask is active at any time.
+type dnsTask struct {
+	src     nodeSource
+	results []*discover.Node
+}
+
 // A waitExpireTask is generated if there are no other tasks
 // to keep the loop in Server.run ticking.
 type waitExpireTask struct {
@@ -235,6 +256,11 @@ func (s *dialstate) newTasks(nRunning int, peers map[discover.NodeID]*Peer, now
 		}
 	}
 	s.lookupBuf = s.lookupBuf[:copy(s.lookupBuf, s.lookupBuf[i:])]
+	// Query the DNS node lists if discovery doesn't yield enough candidates.
+	if s.dns != nil && len(s.lookupBuf) < needDynDials && !s.dnsRunning && !now.Before(s.nextDNS) {
+		s.dnsRunning = true
+		newtasks = append(newtasks, &dnsTask{src: s.dns})
+	}
 	// Launch a discovery lookup if more candidates are needed.
 	if len(s.lookupBuf) < needDynDials && !s.lookupRunning {
 		s.lookupRunning = true
@@ -288,6 +314,10 @@ func (s *dialstate) taskDone(t task, now time.Time) {
 	case *discoverTask:
 		s.lookupRunning = false
 		s.lookupBuf = append(s.lookupBuf, t.results...)
+	case *dnsTask:
+		s.dnsRunning = false
+		s.nextDNS = now.Add(dnsInterval)
+		s.lookupBuf = append(s.lookupBuf, t.results...)
 	}
 }
 
@@ -399,6 +429,18 @@ func (t *discoverTask) String() string {
 	return s
 }
 
+func (t *dnsTask) Do(srv *Server) {
+	// Shuffle the nodes so peers using the same lists dial different ones.
+	nodes := t.src.Nodes()
+	for _, i := range mrand.Perm(len(nodes)) {
+		t.results = append(t.results, nodes[i])
+	}
+}
+
+func (t *dnsTask) String() string {
+	return fmt.Sprintf("DNS node list query (%d results)", len(t.results))
+}
+
 func (t waitExpireTask) Do(*Server) {
 	time.Sleep(t.Duration)
 }
@@ -408,6 +408,63 @@ func TestDialStateDynDialFromTable(t *testing.T) {
 }
 
 // This test checks that candidates that do not match the netrestrict list are not dialed.
+type fakeNodeSource []*discover.Node
+
+func (s fakeNodeSource) Nodes() []*discover.Node { return s }
+
+// This test checks that dynamic dials are launched from DNS node lists and
+// that the lists are queried at most once per dnsInterval.
+func TestDialStateDNS(t *testing.T) {
+	src := fakeNodeSource{{ID: uintID(1)}, {ID: uintID(2)}, {ID: uintID(3)}}
+	state := newDialState(nil, nil, fakeTable{}, 5, nil)
+	state.dns = src
+
+	runDialTest(t, dialtest{
+		init: state,
+		rounds: []round{
+			// The DNS node lists are queried along with discovery.
+			{
+				new: []task{
+					&dnsTask{src: src},
+					&discoverTask{},
+				},
+			},
+			// Their nodes are dialed, but the lists aren't queried again yet.
+			{
+				done: []task{
+					&dnsTask{src: src, results: src},
+					&discoverTask{},
+				},
+				new: []task{
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(1)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(2)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(3)}},
+					&discoverTask{},
+				},
+			},
+			// Only discovery is retried while dnsInterval hasn't passed.
+			{
+				done: []task{
+					&discoverTask{},
+				},
+				new: []task{
+					&discoverTask{},
+				},
+			},
+			// Once it passed, the lists are queried again.
+			{
+				done: []task{
+					&discoverTask{},
+				},
+				new: []task{
+					&dnsTask{src: src},
+					&discoverTask{},
+				},
+			},
+		},
+	})
+}
+
 func TestDialStateNetRestrict(t *testing.T) {
 	// This table always returns the same random nodes
 	// in the order given below.
@@ -34,6 +34,7 @@ import (
 	"github.com/69th-byte/sdexchain/common"
 	"github.com/69th-byte/sdexchain/crypto"
 	"github.com/69th-byte/sdexchain/crypto/secp256k1"
+	"github.com/69th-byte/sdexchain/p2p/enr"
 )
 
 const NodeIDBits = 512
@@ -128,6 +129,10 @@ var incompleteNodeURL = regexp.MustCompile("(?i)^(?:enode://)?([0-9a-f]+)$")
 //    enode://<hex node id>
 //    <hex node id>
 //
+// Nodes can also be given as a signed node record in its textual form
+//
+//    enr:<base64 record>
+//
 // For complete nodes, the node ID is encoded in the username portion
 // of the URL, separated from the host by an @ sign. The hostname can
 // only be given as an IP address, DNS domain names are not allowed.
@@ -141,6 +146,13 @@ var incompleteNodeURL = regexp.MustCompile("(?i)^(?:enode://)?([0-9a-f]+)$")
 //
 //    enode://<hex node id>@10.3.58.6:30303?discport=30301
 func ParseNode(rawurl string) (*Node, error) {
+	if strings.HasPrefix(rawurl, "enr:") {
+		var r enr.Record
+		if err := r.UnmarshalText([]byte(rawurl)); err != nil {
+			return nil, fmt.Errorf("invalid node record (%v)", err)
+		}
+		return NodeFromRecord(&r)
+	}
 	if m := incompleteNodeURL.FindStringSubmatch(rawurl); m != nil {
 		id, err := HexID(m[1])
 		if err != nil {
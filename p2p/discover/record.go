@@ -0,0 +1,83 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"net"
+
+	"github.com/69th-byte/sdexchain/p2p/enr"
+)
+
+var errMissingIP = errors.New("node record has no IP address")
+
+// NodeFromRecord creates a node from the endpoint and public key contained in a
+// signed node record. The discovery port defaults to the TCP port if the record
+// doesn't hold one.
+func NodeFromRecord(r *enr.Record) (*Node, error) {
+	var (
+		pubkey enr.Secp256k1
+		ip4    enr.IP4
+		ip6    enr.IP6
+		tcp    enr.TCP
+		udp    enr.UDP
+		ip     net.IP
+	)
+	if err := r.Load(&pubkey); err != nil {
+		return nil, err
+	}
+	if err := r.Load(&ip4); err == nil {
+		ip = net.IP(ip4)
+	} else if err := r.Load(&ip6); err == nil {
+		ip = net.IP(ip6)
+	} else {
+		return nil, errMissingIP
+	}
+	if err := r.Load(&tcp); err != nil {
+		return nil, err
+	}
+	if err := r.Load(&udp); enr.IsNotFound(err) {
+		udp = enr.UDP(tcp)
+	} else if err != nil {
+		return nil, err
+	}
+	key := ecdsa.PublicKey(pubkey)
+	return NewNode(PubkeyID(&key), ip, uint16(udp), uint16(tcp)), nil
+}
+
+// SignNodeRecord creates a node record holding the endpoint of n and signs it
+// with the node's private key.
+func SignNodeRecord(n *Node, key *ecdsa.PrivateKey) (*enr.Record, error) {
+	if PubkeyID(&key.PublicKey) != n.ID {
+		return nil, errors.New("key doesn't match node ID")
+	}
+	var r enr.Record
+	if ip4 := n.IP.To4(); ip4 != nil {
+		r.Set(enr.IP4(ip4))
+	} else if n.IP != nil {
+		r.Set(enr.IP6(n.IP))
+	} else {
+		return nil, errMissingIP
+	}
+	r.Set(enr.TCP(n.TCP))
+	r.Set(enr.UDP(n.UDP))
+	if err := r.Sign(key); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
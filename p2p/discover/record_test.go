@@ -0,0 +1,64 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/p2p/enr"
+)
+
+func TestNodeRecord(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	n := NewNode(PubkeyID(&key.PublicKey), net.IP{10, 3, 58, 6}, 30301, 30303)
+
+	r, err := SignNodeRecord(n, key)
+	if err != nil {
+		t.Fatalf("failed to sign record: %v", err)
+	}
+	n2, err := NodeFromRecord(r)
+	if err != nil {
+		t.Fatalf("failed to create node from record: %v", err)
+	}
+	if !reflect.DeepEqual(n, n2) {
+		t.Errorf("node mismatch:\ngot:  %#v\nwant: %#v", n2, n)
+	}
+	// The textual form of the record is accepted as node designator
+	text, _ := r.MarshalText()
+	n3, err := ParseNode(string(text))
+	if err != nil {
+		t.Fatalf("failed to parse record: %v", err)
+	}
+	if !reflect.DeepEqual(n, n3) {
+		t.Errorf("parsed node mismatch:\ngot:  %#v\nwant: %#v", n3, n)
+	}
+	// Records signed by another key are rejected
+	other, _ := crypto.GenerateKey()
+	if _, err := SignNodeRecord(n, other); err == nil {
+		t.Error("signed record with foreign key")
+	}
+	// Records without an endpoint can't be dialed
+	var empty enr.Record
+	empty.Set(enr.TCP(30303))
+	empty.Sign(key)
+	if _, err := NodeFromRecord(&empty); err != errMissingIP {
+		t.Errorf("error mismatch: got %v, want %v", err, errMissingIP)
+	}
+}
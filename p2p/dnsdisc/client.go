@@ -0,0 +1,288 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/69th-byte/sdexchain/common/mclock"
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/log"
+	"github.com/69th-byte/sdexchain/p2p/discover"
+	"github.com/69th-byte/sdexchain/p2p/enr"
+)
+
+const (
+	defaultTimeout         = 5 * time.Second
+	defaultRecheckInterval = 30 * time.Minute
+	defaultMaxEntries      = 10000
+)
+
+// Resolver is a DNS resolver that can query TXT records.
+type Resolver interface {
+	LookupTXT(ctx context.Context, domain string) ([]string, error)
+}
+
+// Config holds the settings of a DNS discovery client.
+type Config struct {
+	Timeout         time.Duration // timeout used for each DNS lookup (default 5s)
+	RecheckInterval time.Duration // time between tree root update checks (default 30min)
+	Resolver        Resolver      // the DNS resolver (default net.DefaultResolver)
+	Clock           mclock.Clock  // clock used to schedule root checks (default mclock.System)
+	MaxEntries      int           // maximum number of entries walked per tree (default 10000)
+}
+
+func (cfg Config) withDefaults() Config {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.RecheckInterval == 0 {
+		cfg.RecheckInterval = defaultRecheckInterval
+	}
+	if cfg.Resolver == nil {
+		cfg.Resolver = new(net.Resolver)
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = mclock.System{}
+	}
+	if cfg.MaxEntries == 0 {
+		cfg.MaxEntries = defaultMaxEntries
+	}
+	return cfg
+}
+
+// Client discovers nodes by querying DNS servers. It keeps the trees it synced
+// in memory and only downloads the entries that changed when a tree is updated.
+type Client struct {
+	cfg   Config
+	urls  []*linkEntry
+	lock  sync.Mutex
+	trees map[string]*clientTree // synced trees by domain
+}
+
+// clientTree is the synced state of a single tree.
+type clientTree struct {
+	loc       *linkEntry
+	root      *rootEntry
+	lastCheck mclock.AbsTime
+	entries   map[string]entry // verified entries by hash
+	nodes     []*enr.Record
+	links     []*linkEntry
+}
+
+// NewClient creates a DNS discovery client for the node lists at the given
+// enrtree:// URLs.
+func NewClient(cfg Config, urls ...string) (*Client, error) {
+	c := &Client{cfg: cfg.withDefaults(), trees: make(map[string]*clientTree)}
+	for _, url := range urls {
+		loc, err := parseLink(url)
+		if err != nil {
+			return nil, fmt.Errorf("invalid enrtree URL %q: %v", url, err)
+		}
+		c.urls = append(c.urls, loc)
+	}
+	return c, nil
+}
+
+// SyncTree downloads the complete tree at the given enrtree:// URL. The trees it
+// links to are not retrieved.
+func (c *Client) SyncTree(url string) (*Tree, error) {
+	loc, err := parseLink(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid enrtree URL %q: %v", url, err)
+	}
+	ct := &clientTree{loc: loc, entries: make(map[string]entry)}
+	if err := c.syncTree(ct); err != nil {
+		return nil, err
+	}
+	t := &Tree{root: ct.root, entries: make(map[string]entry, len(ct.entries))}
+	for hash, e := range ct.entries {
+		t.entries[hash] = e
+	}
+	return t, nil
+}
+
+// Nodes returns the nodes of all lists the client was created with and of the
+// lists linked from them. Trees whose root wasn't checked for the recheck
+// interval are updated first; if that fails, their last known nodes are used.
+func (c *Client) Nodes() []*discover.Node {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var (
+		nodes   []*discover.Node
+		seen    = make(map[discover.NodeID]bool)
+		visited = make(map[string]bool)
+		queue   = append([]*linkEntry{}, c.urls...)
+	)
+	for len(queue) > 0 {
+		loc := queue[0]
+		queue = queue[1:]
+		if visited[loc.domain] {
+			continue
+		}
+		visited[loc.domain] = true
+
+		ct := c.trees[loc.domain]
+		if ct == nil {
+			ct = &clientTree{loc: loc, entries: make(map[string]entry)}
+			c.trees[loc.domain] = ct
+		}
+		if ct.root == nil || c.cfg.Clock.Now().Sub(ct.lastCheck) >= c.cfg.RecheckInterval {
+			if err := c.syncTree(ct); err != nil {
+				log.Debug("Failed to sync DNS node list", "domain", loc.domain, "err", err)
+			}
+		}
+		for _, r := range ct.nodes {
+			n, err := discover.NodeFromRecord(r)
+			if err != nil {
+				log.Trace("Skipping DNS node record", "domain", loc.domain, "err", err)
+				continue
+			}
+			if !seen[n.ID] {
+				seen[n.ID] = true
+				nodes = append(nodes, n)
+			}
+		}
+		queue = append(queue, ct.links...)
+	}
+	return nodes
+}
+
+// syncTree checks the root of the tree and downloads the entries of both
+// subtrees if the root changed.
+func (c *Client) syncTree(ct *clientTree) error {
+	ct.lastCheck = c.cfg.Clock.Now()
+	root, err := c.resolveRoot(ct.loc)
+	if err != nil {
+		return err
+	}
+	if ct.root != nil && ct.root.String() == root.String() {
+		return nil
+	}
+	if ct.root != nil && root.seq < ct.root.seq {
+		return fmt.Errorf("root sequence number went backwards (%d < %d)", root.seq, ct.root.seq)
+	}
+	// Walk both subtrees, keeping the entries that are still referenced.
+	entries := make(map[string]entry)
+	var nodes []*enr.Record
+	err = c.walk(ct, entries, root.eroot, false, func(e entry) {
+		nodes = append(nodes, e.(*enrEntry).node)
+	})
+	if err != nil {
+		return err
+	}
+	var links []*linkEntry
+	err = c.walk(ct, entries, root.lroot, true, func(e entry) {
+		links = append(links, e.(*linkEntry))
+	})
+	if err != nil {
+		return err
+	}
+	ct.root, ct.entries, ct.nodes, ct.links = root, entries, nodes, links
+	log.Debug("Synced DNS node list", "domain", ct.loc.domain, "seq", root.seq, "nodes", len(nodes), "links", len(links))
+	return nil
+}
+
+// walk visits all leaves below the entry with the given hash. Entries referenced
+// more than once are only visited the first time, and at most MaxEntries entries
+// are visited in total.
+func (c *Client) walk(ct *clientTree, entries map[string]entry, hash string, links bool, leaf func(entry)) error {
+	if _, ok := entries[hash]; ok {
+		return nil
+	}
+	if len(entries) >= c.cfg.MaxEntries {
+		return errTreeTooLarge
+	}
+	e, ok := ct.entries[hash]
+	if !ok {
+		var err error
+		if e, err = c.resolveEntry(ct.loc.domain, hash, links); err != nil {
+			return err
+		}
+	}
+	entries[hash] = e
+	if branch, ok := e.(*branchEntry); ok {
+		for _, child := range branch.children {
+			if err := c.walk(ct, entries, child, links, leaf); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	leaf(e)
+	return nil
+}
+
+// resolveRoot retrieves the root record of a tree and verifies its signature.
+func (c *Client) resolveRoot(loc *linkEntry) (*rootEntry, error) {
+	txts, err := c.lookupTXT(loc.domain)
+	if err != nil {
+		return nil, err
+	}
+	for _, txt := range txts {
+		if !strings.HasPrefix(txt, rootPrefix) {
+			continue
+		}
+		root, err := parseRoot(txt)
+		if err != nil {
+			return nil, err
+		}
+		if !root.verifySignature(loc.pubkey) {
+			return nil, errInvalidSig
+		}
+		return root, nil
+	}
+	return nil, fmt.Errorf("no root record found at %s", loc.domain)
+}
+
+// resolveEntry retrieves the entry with the given hash and checks that it
+// matches the hash.
+func (c *Client) resolveEntry(domain, hash string, links bool) (entry, error) {
+	name := hash + "." + domain
+	want, err := b32format.DecodeString(hash)
+	if err != nil {
+		return nil, errInvalidChild
+	}
+	txts, err := c.lookupTXT(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, txt := range txts {
+		if !bytes.HasPrefix(crypto.Keccak256([]byte(txt)), want) {
+			continue
+		}
+		e, err := parseEntry(txt, links)
+		if err != nil {
+			return nil, fmt.Errorf("invalid entry at %s: %v", name, err)
+		}
+		return e, nil
+	}
+	return nil, fmt.Errorf("no entry matching hash found at %s", name)
+}
+
+func (c *Client) lookupTXT(name string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+	return c.cfg.Resolver.LookupTXT(ctx, name)
+}
@@ -0,0 +1,205 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/69th-byte/sdexchain/common/mclock"
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/p2p/discover"
+	"github.com/69th-byte/sdexchain/p2p/enr"
+)
+
+// mapResolver is a DNS resolver serving the TXT records of a map.
+type mapResolver map[string]string
+
+func (mr mapResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if record, ok := mr[name]; ok {
+		return []string{record}, nil
+	}
+	return nil, fmt.Errorf("no such name %s", name)
+}
+
+// publish adds the records of the tree to the resolver.
+func (mr mapResolver) publish(t *testing.T, tree *Tree, domain string) {
+	records, err := tree.ToTXT(domain)
+	if err != nil {
+		t.Fatalf("failed to export tree: %v", err)
+	}
+	for name, txt := range records {
+		mr[name] = txt
+	}
+}
+
+func nodeIDs(t *testing.T, records []*enr.Record) map[discover.NodeID]bool {
+	ids := make(map[discover.NodeID]bool)
+	for _, r := range records {
+		n, err := discover.NodeFromRecord(r)
+		if err != nil {
+			t.Fatalf("invalid test record: %v", err)
+		}
+		ids[n.ID] = true
+	}
+	return ids
+}
+
+func checkNodes(t *testing.T, have []*discover.Node, want map[discover.NodeID]bool) {
+	t.Helper()
+	if len(have) != len(want) {
+		t.Fatalf("node count mismatch: got %d, want %d", len(have), len(want))
+	}
+	for _, n := range have {
+		if !want[n.ID] {
+			t.Errorf("unexpected node %x", n.ID[:8])
+		}
+	}
+}
+
+func TestClientSyncTree(t *testing.T) {
+	nodes := testNodes(t, 30)
+	tree, _ := MakeTree(1, nodes, nil)
+	url, _ := tree.Sign(testKey, "nodes.example.org")
+	resolver := make(mapResolver)
+	resolver.publish(t, tree, "nodes.example.org")
+
+	c, _ := NewClient(Config{Resolver: resolver})
+	synced, err := c.SyncTree(url)
+	if err != nil {
+		t.Fatalf("failed to sync tree: %v", err)
+	}
+	if synced.Seq() != 1 || synced.Signature() != tree.Signature() {
+		t.Errorf("synced root mismatch")
+	}
+	if len(synced.Nodes()) != len(nodes) {
+		t.Errorf("node count mismatch: got %d, want %d", len(synced.Nodes()), len(nodes))
+	}
+	// A tree signed by someone else must be rejected
+	other, _ := crypto.GenerateKey()
+	if _, err := c.SyncTree(testLink(other, "nodes.example.org")); err != errInvalidSig {
+		t.Errorf("foreign tree error mismatch: got %v, want %v", err, errInvalidSig)
+	}
+	// Tampered entries must be rejected
+	for name, txt := range resolver {
+		if name != "nodes.example.org" {
+			resolver[name] = txt + "x"
+			break
+		}
+	}
+	if _, err := c.SyncTree(url); err == nil {
+		t.Error("synced tree with tampered entry")
+	}
+}
+
+func TestClientNodesFollowLinksAndUpdates(t *testing.T) {
+	var (
+		clock    = new(mclock.Simulated)
+		resolver = make(mapResolver)
+		nodesA   = testNodes(t, 5)
+		nodesB   = testNodes(t, 3)
+	)
+	otherKey, _ := crypto.GenerateKey()
+
+	// Tree B is only reachable through a link from tree A.
+	treeB, _ := MakeTree(1, nodesB, nil)
+	linkB, _ := treeB.Sign(otherKey, "b.example.org")
+	resolver.publish(t, treeB, "b.example.org")
+
+	treeA, _ := MakeTree(1, nodesA, []string{linkB})
+	urlA, _ := treeA.Sign(testKey, "a.example.org")
+	resolver.publish(t, treeA, "a.example.org")
+
+	c, err := NewClient(Config{Resolver: resolver, Clock: clock, RecheckInterval: time.Minute}, urlA)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	want := nodeIDs(t, append(append([]*enr.Record{}, nodesA...), nodesB...))
+	checkNodes(t, c.Nodes(), want)
+
+	// An update of tree A isn't noticed before the recheck interval passed
+	nodesA2 := testNodes(t, 2)
+	treeA2, _ := MakeTree(2, nodesA2, nil)
+	treeA2.Sign(testKey, "a.example.org")
+	resolver.publish(t, treeA2, "a.example.org")
+
+	checkNodes(t, c.Nodes(), want)
+	clock.Run(time.Minute)
+	checkNodes(t, c.Nodes(), nodeIDs(t, nodesA2))
+
+	// Unreachable trees keep serving the last synced nodes
+	delete(resolver, "a.example.org")
+	clock.Run(time.Minute)
+	checkNodes(t, c.Nodes(), nodeIDs(t, nodesA2))
+}
+
+func TestClientSyncTreeSharedEntries(t *testing.T) {
+	// Every branch references its child maxChildren times, walking all the
+	// references would visit maxChildren^8 copies of the single node.
+	nodes := testNodes(t, 1)
+	tree := &Tree{entries: make(map[string]entry)}
+	var top entry = &enrEntry{node: nodes[0]}
+	for depth := 0; depth < 8; depth++ {
+		hash := subdomain(top)
+		tree.entries[hash] = top
+		children := make([]string, maxChildren)
+		for i := range children {
+			children[i] = hash
+		}
+		top = &branchEntry{children: children}
+	}
+	tree.entries[subdomain(top)] = top
+	links := &branchEntry{}
+	tree.entries[subdomain(links)] = links
+	tree.root = &rootEntry{eroot: subdomain(top), lroot: subdomain(links), seq: 1}
+	url, _ := tree.Sign(testKey, "nodes.example.org")
+	resolver := make(mapResolver)
+	resolver.publish(t, tree, "nodes.example.org")
+
+	c, _ := NewClient(Config{Resolver: resolver})
+	synced, err := c.SyncTree(url)
+	if err != nil {
+		t.Fatalf("failed to sync tree: %v", err)
+	}
+	if len(synced.Nodes()) != 1 {
+		t.Errorf("node count mismatch: got %d, want 1", len(synced.Nodes()))
+	}
+}
+
+func TestClientSyncTreeMaxEntries(t *testing.T) {
+	tree, _ := MakeTree(1, testNodes(t, 30), nil)
+	url, _ := tree.Sign(testKey, "nodes.example.org")
+	resolver := make(mapResolver)
+	resolver.publish(t, tree, "nodes.example.org")
+
+	c, _ := NewClient(Config{Resolver: resolver, MaxEntries: 20})
+	if _, err := c.SyncTree(url); err != errTreeTooLarge {
+		t.Errorf("error mismatch: got %v, want %v", err, errTreeTooLarge)
+	}
+	c, _ = NewClient(Config{Resolver: resolver, MaxEntries: 40})
+	if _, err := c.SyncTree(url); err != nil {
+		t.Errorf("failed to sync tree: %v", err)
+	}
+}
+
+func TestNewClientInvalidURL(t *testing.T) {
+	if _, err := NewClient(Config{}, "enrtree://nodes.example.org"); err == nil {
+		t.Error("created client with URL lacking public key")
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package dnsdisc implements node discovery via DNS (EIP-1459).
+//
+// A node list is published as a Merkle tree of TXT records below a domain. The
+// root record at the domain itself is signed by the list operator and points to
+// the subtree of node records and to the subtree of links to other lists:
+//
+//	enrtree-root:v1 e=<enr-root> l=<link-root> seq=<sequence-number> sig=<signature>
+//
+// Interior records list the hashes of their children, which are the subdomains
+// holding them, and leaf records hold a node record or a link:
+//
+//	enrtree-branch:<h₁>,<h₂>,...,<hₙ>
+//	enr:<node-record>
+//	enrtree://<key>@<fqdn>
+//
+// Lists are referred to by enrtree:// URLs carrying the base32 encoded
+// compressed public key of the operator and the domain of the tree.
+package dnsdisc
@@ -0,0 +1,438 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/p2p/enr"
+)
+
+const (
+	rootPrefix   = "enrtree-root:v1"
+	linkPrefix   = "enrtree://"
+	branchPrefix = "enrtree-branch:"
+	enrPrefix    = "enr:"
+
+	// maxChildren is the number of hashes that fit into a branch record without
+	// exceeding the size of a single TXT string.
+	maxChildren = 13
+
+	// hashAbbrev is the number of bytes of the keccak256 hash of an entry that
+	// make up its subdomain.
+	hashAbbrev = 16
+
+	// minHashLength is the minimum number of bytes accepted as child hash.
+	minHashLength = 12
+
+	// sigLength is the length of the root signature, including the recovery id.
+	sigLength = 65
+)
+
+var (
+	b32format = base32.StdEncoding.WithPadding(base32.NoPadding)
+	b64format = base64.RawURLEncoding
+)
+
+var (
+	errUnknownEntry = errors.New("unknown entry type")
+	errNoPubkey     = errors.New("missing public key")
+	errBadPubkey    = errors.New("invalid public key")
+	errInvalidENR   = errors.New("invalid node record")
+	errInvalidChild = errors.New("invalid child hash")
+	errInvalidSig   = errors.New("invalid base64 signature")
+	errSyntax       = errors.New("invalid syntax")
+	errNoSignature  = errors.New("tree is not signed")
+	errTreeTooLarge = errors.New("tree has too many entries")
+)
+
+// Tree is a Merkle tree of node records and links to other trees, ready to be
+// published as TXT records below a domain.
+type Tree struct {
+	root    *rootEntry
+	entries map[string]entry
+}
+
+// MakeTree creates a tree containing the given nodes and links. The tree is not
+// signed, use Sign before publishing it.
+func MakeTree(seq uint, nodes []*enr.Record, links []string) (*Tree, error) {
+	// Sort the records and links so the same input always yields the same tree.
+	records := make([]entry, 0, len(nodes))
+	for _, n := range nodes {
+		if !n.Signed() {
+			return nil, fmt.Errorf("can't add unsigned node record (seq %d)", n.Seq())
+		}
+		records = append(records, &enrEntry{node: n})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].String() < records[j].String()
+	})
+	linkEntries := make([]entry, 0, len(links))
+	for _, l := range links {
+		le, err := parseLink(l)
+		if err != nil {
+			return nil, fmt.Errorf("invalid link %q: %v", l, err)
+		}
+		linkEntries = append(linkEntries, le)
+	}
+	sort.Slice(linkEntries, func(i, j int) bool {
+		return linkEntries[i].String() < linkEntries[j].String()
+	})
+	// Build the subtrees and put the root on top.
+	t := &Tree{entries: make(map[string]entry)}
+	eroot := t.build(records)
+	t.entries[subdomain(eroot)] = eroot
+	lroot := t.build(linkEntries)
+	t.entries[subdomain(lroot)] = lroot
+	t.root = &rootEntry{eroot: subdomain(eroot), lroot: subdomain(lroot), seq: seq}
+	return t, nil
+}
+
+// build assembles the given leaves into a subtree of branches, adding all its
+// entries except the returned top one to the tree.
+func (t *Tree) build(entries []entry) entry {
+	if len(entries) == 1 {
+		return entries[0]
+	}
+	if len(entries) <= maxChildren {
+		hashes := make([]string, len(entries))
+		for i, e := range entries {
+			hashes[i] = subdomain(e)
+			t.entries[hashes[i]] = e
+		}
+		return &branchEntry{children: hashes}
+	}
+	var subtrees []entry
+	for len(entries) > 0 {
+		n := maxChildren
+		if len(entries) < n {
+			n = len(entries)
+		}
+		sub := t.build(entries[:n])
+		entries = entries[n:]
+		subtrees = append(subtrees, sub)
+		t.entries[subdomain(sub)] = sub
+	}
+	return t.build(subtrees)
+}
+
+// Sign signs the tree root with the given key and returns the enrtree:// URL
+// that refers to the tree once it is published at domain.
+func (t *Tree) Sign(key *ecdsa.PrivateKey, domain string) (string, error) {
+	root := *t.root
+	sig, err := crypto.Sign(root.sigHash(), key)
+	if err != nil {
+		return "", err
+	}
+	root.sig = sig
+	t.root = &root
+	return newLinkEntry(domain, &key.PublicKey).String(), nil
+}
+
+// SetSignature assigns a signature previously created by Sign to the root of an
+// identical tree, verifying it against the public key.
+func (t *Tree) SetSignature(pubkey *ecdsa.PublicKey, signature string) error {
+	sig, err := b64format.DecodeString(signature)
+	if err != nil || len(sig) != sigLength {
+		return errInvalidSig
+	}
+	root := *t.root
+	root.sig = sig
+	if !root.verifySignature(pubkey) {
+		return errInvalidSig
+	}
+	t.root = &root
+	return nil
+}
+
+// Seq returns the sequence number of the tree.
+func (t *Tree) Seq() uint {
+	return t.root.seq
+}
+
+// Signature returns the signature of the tree root, or the empty string if the
+// tree isn't signed.
+func (t *Tree) Signature() string {
+	if t.root.sig == nil {
+		return ""
+	}
+	return b64format.EncodeToString(t.root.sig)
+}
+
+// Links returns the enrtree:// URLs of the trees linked from this one.
+func (t *Tree) Links() []string {
+	var links []string
+	for _, e := range t.entries {
+		if le, ok := e.(*linkEntry); ok {
+			links = append(links, le.String())
+		}
+	}
+	sort.Strings(links)
+	return links
+}
+
+// Nodes returns the node records contained in the tree.
+func (t *Tree) Nodes() []*enr.Record {
+	var nodes []*enr.Record
+	for _, e := range t.entries {
+		if ee, ok := e.(*enrEntry); ok {
+			nodes = append(nodes, ee.node)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return bytes.Compare(nodes[i].NodeAddr(), nodes[j].NodeAddr()) < 0
+	})
+	return nodes
+}
+
+// ToTXT returns the TXT records of the signed tree keyed by the names they are
+// to be published at. The root record is published at the domain itself.
+func (t *Tree) ToTXT(domain string) (map[string]string, error) {
+	if t.root.sig == nil {
+		return nil, errNoSignature
+	}
+	records := map[string]string{domain: t.root.String()}
+	for hash, e := range t.entries {
+		name := hash
+		if domain != "" {
+			name = hash + "." + domain
+		}
+		records[name] = e.String()
+	}
+	return records, nil
+}
+
+// maxTXTString is the maximum length of a single character-string in a TXT
+// record. Longer records are split into several strings.
+const maxTXTString = 255
+
+// WriteZoneFile writes the records of the signed tree in the master file format
+// understood by most DNS servers.
+func (t *Tree) WriteZoneFile(w io.Writer, domain string, ttl uint) error {
+	records, err := t.ToTXT(domain)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(records))
+	for name := range records {
+		names = append(names, name)
+	}
+	// Put the root first, then the entries in lexical order.
+	sort.Slice(names, func(i, j int) bool {
+		if names[i] == domain || names[j] == domain {
+			return names[i] == domain
+		}
+		return names[i] < names[j]
+	})
+	fmt.Fprintf(w, "$ORIGIN %s.\n", domain)
+	for _, name := range names {
+		label := "@"
+		if name != domain {
+			label = strings.TrimSuffix(name, "."+domain)
+		}
+		txt := records[name]
+		var parts []string
+		for len(txt) > maxTXTString {
+			parts = append(parts, txt[:maxTXTString])
+			txt = txt[maxTXTString:]
+		}
+		parts = append(parts, txt)
+		if _, err := fmt.Fprintf(w, "%-27s %d IN TXT \"%s\"\n", label, ttl, strings.Join(parts, `" "`)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseURL parses an enrtree:// URL, returning the public key of the tree's
+// signer and the domain it is published at.
+func ParseURL(url string) (*ecdsa.PublicKey, string, error) {
+	le, err := parseLink(url)
+	if err != nil {
+		return nil, "", err
+	}
+	return le.pubkey, le.domain, nil
+}
+
+// Entry types.
+
+type entry interface {
+	fmt.Stringer
+}
+
+type (
+	rootEntry struct {
+		eroot string
+		lroot string
+		seq   uint
+		sig   []byte
+	}
+	branchEntry struct {
+		children []string
+	}
+	enrEntry struct {
+		node *enr.Record
+	}
+	linkEntry struct {
+		domain string
+		pubkey *ecdsa.PublicKey
+	}
+)
+
+func (e *rootEntry) sigHash() []byte {
+	return crypto.Keccak256([]byte(e.unsigned()))
+}
+
+func (e *rootEntry) unsigned() string {
+	return fmt.Sprintf(rootPrefix+" e=%s l=%s seq=%d", e.eroot, e.lroot, e.seq)
+}
+
+func (e *rootEntry) String() string {
+	return e.unsigned() + " sig=" + b64format.EncodeToString(e.sig)
+}
+
+func (e *rootEntry) verifySignature(pubkey *ecdsa.PublicKey) bool {
+	sig := e.sig[:sigLength-1] // remove recovery id
+	return crypto.VerifySignature(crypto.FromECDSAPub(pubkey), e.sigHash(), sig)
+}
+
+func (e *branchEntry) String() string {
+	return branchPrefix + strings.Join(e.children, ",")
+}
+
+func (e *enrEntry) String() string {
+	text, err := e.node.MarshalText()
+	if err != nil {
+		panic(fmt.Errorf("dnsdisc: can't encode node record: %v", err))
+	}
+	return string(text)
+}
+
+func newLinkEntry(domain string, pubkey *ecdsa.PublicKey) *linkEntry {
+	return &linkEntry{domain: domain, pubkey: pubkey}
+}
+
+func (e *linkEntry) String() string {
+	return linkPrefix + b32format.EncodeToString(crypto.CompressPubkey(e.pubkey)) + "@" + e.domain
+}
+
+// subdomain returns the name an entry is published at below the tree's domain.
+func subdomain(e entry) string {
+	h := crypto.Keccak256([]byte(e.String()))
+	return b32format.EncodeToString(h[:hashAbbrev])
+}
+
+// Entry parsing.
+
+func parseRoot(e string) (*rootEntry, error) {
+	var (
+		root rootEntry
+		sig  string
+	)
+	if _, err := fmt.Sscanf(e, rootPrefix+" e=%s l=%s seq=%d sig=%s", &root.eroot, &root.lroot, &root.seq, &sig); err != nil {
+		return nil, errSyntax
+	}
+	if !isValidHash(root.eroot) || !isValidHash(root.lroot) {
+		return nil, errInvalidChild
+	}
+	sigb, err := b64format.DecodeString(sig)
+	if err != nil || len(sigb) != sigLength {
+		return nil, errInvalidSig
+	}
+	root.sig = sigb
+	return &root, nil
+}
+
+// parseEntry parses a non-root entry. Links are accepted only if allowLinks is
+// set, node records only if it isn't: the two subtrees of a tree hold either.
+func parseEntry(e string, allowLinks bool) (entry, error) {
+	switch {
+	case strings.HasPrefix(e, branchPrefix):
+		return parseBranch(e)
+	case strings.HasPrefix(e, linkPrefix) && allowLinks:
+		return parseLink(e)
+	case strings.HasPrefix(e, enrPrefix) && !allowLinks:
+		return parseENR(e)
+	default:
+		return nil, errUnknownEntry
+	}
+}
+
+func parseBranch(e string) (entry, error) {
+	e = e[len(branchPrefix):]
+	if e == "" {
+		return &branchEntry{}, nil // empty entry is OK
+	}
+	hashes := strings.Split(e, ",")
+	for _, h := range hashes {
+		if !isValidHash(h) {
+			return nil, errInvalidChild
+		}
+	}
+	return &branchEntry{children: hashes}, nil
+}
+
+func parseENR(e string) (entry, error) {
+	var r enr.Record
+	if err := r.UnmarshalText([]byte(e)); err != nil {
+		return nil, errInvalidENR
+	}
+	return &enrEntry{node: &r}, nil
+}
+
+func parseLink(e string) (*linkEntry, error) {
+	if !strings.HasPrefix(e, linkPrefix) {
+		return nil, errSyntax
+	}
+	e = e[len(linkPrefix):]
+	pos := strings.IndexByte(e, '@')
+	if pos == -1 {
+		return nil, errNoPubkey
+	}
+	keystring, domain := e[:pos], e[pos+1:]
+	if domain == "" {
+		return nil, errSyntax
+	}
+	keybytes, err := b32format.DecodeString(keystring)
+	if err != nil {
+		return nil, errBadPubkey
+	}
+	key, err := crypto.DecompressPubkey(keybytes)
+	if err != nil {
+		return nil, errBadPubkey
+	}
+	return newLinkEntry(domain, key), nil
+}
+
+func isValidHash(s string) bool {
+	dlen := b32format.DecodedLen(len(s))
+	if dlen < minHashLength || dlen > 32 || strings.ContainsAny(s, "\n\r") {
+		return false
+	}
+	buf := make([]byte, 32)
+	_, err := b32format.Decode(buf, []byte(s))
+	return err == nil
+}
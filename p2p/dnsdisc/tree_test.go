@@ -0,0 +1,184 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/p2p/discover"
+	"github.com/69th-byte/sdexchain/p2p/enr"
+)
+
+var testKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+
+// testNodes creates n signed node records with distinct endpoints.
+func testNodes(t *testing.T, n int) []*enr.Record {
+	records := make([]*enr.Record, n)
+	for i := range records {
+		key, _ := crypto.GenerateKey()
+		node := discover.NewNode(discover.PubkeyID(&key.PublicKey), net.IP{10, 0, byte(i >> 8), byte(i)}, 30301, 30303)
+		r, err := discover.SignNodeRecord(node, key)
+		if err != nil {
+			t.Fatalf("failed to sign node record: %v", err)
+		}
+		records[i] = r
+	}
+	return records
+}
+
+func testLink(key *ecdsa.PrivateKey, domain string) string {
+	return newLinkEntry(domain, &key.PublicKey).String()
+}
+
+func TestTreeRoundTrip(t *testing.T) {
+	nodes := testNodes(t, 40)
+	other, _ := crypto.GenerateKey()
+	links := []string{testLink(other, "other.example.org")}
+
+	tree, err := MakeTree(3, nodes, links)
+	if err != nil {
+		t.Fatalf("failed to make tree: %v", err)
+	}
+	if _, err := tree.ToTXT("nodes.example.org"); err != errNoSignature {
+		t.Errorf("unsigned tree export error mismatch: got %v, want %v", err, errNoSignature)
+	}
+	url, err := tree.Sign(testKey, "nodes.example.org")
+	if err != nil {
+		t.Fatalf("failed to sign tree: %v", err)
+	}
+	if want := testLink(testKey, "nodes.example.org"); url != want {
+		t.Errorf("URL mismatch: got %s, want %s", url, want)
+	}
+	if tree.Seq() != 3 {
+		t.Errorf("sequence number mismatch: got %d, want 3", tree.Seq())
+	}
+	if !reflect.DeepEqual(tree.Links(), links) {
+		t.Errorf("links mismatch: got %v, want %v", tree.Links(), links)
+	}
+	if len(tree.Nodes()) != len(nodes) {
+		t.Errorf("node count mismatch: got %d, want %d", len(tree.Nodes()), len(nodes))
+	}
+	// Every record must parse back into the entry it was created from and fit
+	// into a TXT record.
+	records, err := tree.ToTXT("nodes.example.org")
+	if err != nil {
+		t.Fatalf("failed to export tree: %v", err)
+	}
+	for name, txt := range records {
+		if name == "nodes.example.org" {
+			root, err := parseRoot(txt)
+			if err != nil {
+				t.Fatalf("failed to parse root: %v", err)
+			}
+			if !root.verifySignature(&testKey.PublicKey) {
+				t.Error("root signature doesn't verify")
+			}
+			if root.verifySignature(&other.PublicKey) {
+				t.Error("root signature verifies with foreign key")
+			}
+			continue
+		}
+		if strings.HasPrefix(txt, branchPrefix) && len(txt) > 370 {
+			t.Errorf("branch record too long: %d bytes", len(txt))
+		}
+		e, err := parseEntry(txt, strings.HasPrefix(txt, linkPrefix))
+		if err != nil {
+			t.Fatalf("failed to parse entry %q: %v", txt, err)
+		}
+		if e.String() != txt {
+			t.Errorf("entry mismatch:\ngot:  %s\nwant: %s", e.String(), txt)
+		}
+		if hash := strings.TrimSuffix(name, ".nodes.example.org"); subdomain(e) != hash {
+			t.Errorf("entry published at wrong name %s", name)
+		}
+	}
+	// The signature can be restored on the same tree, but not on another one
+	sig := tree.Signature()
+	same, _ := MakeTree(3, nodes, links)
+	if err := same.SetSignature(&testKey.PublicKey, sig); err != nil {
+		t.Errorf("failed to restore signature: %v", err)
+	}
+	changed, _ := MakeTree(4, nodes, links)
+	if err := changed.SetSignature(&testKey.PublicKey, sig); err != errInvalidSig {
+		t.Errorf("signature of changed tree error mismatch: got %v, want %v", err, errInvalidSig)
+	}
+}
+
+func TestParseEntry(t *testing.T) {
+	tests := []struct {
+		input string
+		links bool
+		err   error
+	}{
+		{input: "enrtree-branch:", err: nil},
+		{input: "enrtree-branch:AAAAAAAAAAAAAAAAAAAA", err: nil},
+		{input: "enrtree-branch:AAAAAAAAAAAAAAAAAAAA,invalid", err: errInvalidChild},
+		{input: "enrtree-branch:AAAA", err: errInvalidChild},
+		{input: "enr:-invalid", err: errInvalidENR},
+		{input: "enrtree://AM5FCQLWIZX2QFPNJAP7VUERCCRNGRHWZG3YYHIUV7BVDQ5FDPRT2@nodes.example.org", links: true, err: nil},
+		{input: "enrtree://AM5FCQLWIZX2QFPNJAP7VUERCCRNGRHWZG3YYHIUV7BVDQ5FDPRT2@nodes.example.org", links: false, err: errUnknownEntry},
+		{input: "enrtree://nodes.example.org", links: true, err: errNoPubkey},
+		{input: "enrtree://AAAA@nodes.example.org", links: true, err: errBadPubkey},
+		{input: "foo:bar", err: errUnknownEntry},
+	}
+	for i, test := range tests {
+		if _, err := parseEntry(test.input, test.links); err != test.err {
+			t.Errorf("test %d: error mismatch: got %v, want %v", i, err, test.err)
+		}
+	}
+}
+
+func TestWriteZoneFile(t *testing.T) {
+	tree, _ := MakeTree(1, testNodes(t, 3), nil)
+	tree.Sign(testKey, "nodes.example.org")
+
+	var buf bytes.Buffer
+	if err := tree.WriteZoneFile(&buf, "nodes.example.org", 3600); err != nil {
+		t.Fatalf("failed to write zone file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	records, _ := tree.ToTXT("nodes.example.org")
+	if len(lines) != len(records)+1 {
+		t.Fatalf("line count mismatch: got %d, want %d", len(lines), len(records)+1)
+	}
+	if lines[0] != "$ORIGIN nodes.example.org." {
+		t.Errorf("origin mismatch: %s", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "@ ") || !strings.Contains(lines[1], `"`+rootPrefix) {
+		t.Errorf("root not published at the origin: %s", lines[1])
+	}
+	// Node records exceed a single TXT string and must be split
+	for _, line := range lines[2:] {
+		fields := strings.SplitN(line, ` IN TXT "`, 2)
+		txt := strings.TrimSuffix(fields[1], `"`)
+		for _, part := range strings.Split(txt, `" "`) {
+			if len(part) > maxTXTString {
+				t.Errorf("TXT string too long: %d bytes", len(part))
+			}
+		}
+		name := strings.Fields(fields[0])[0] + ".nodes.example.org"
+		if joined := strings.Replace(txt, `" "`, "", -1); joined != records[name] {
+			t.Errorf("record %s mismatch:\ngot:  %s\nwant: %s", name, joined, records[name])
+		}
+	}
+}
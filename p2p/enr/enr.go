@@ -30,6 +30,7 @@ package enr
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
@@ -44,6 +45,8 @@ const SizeLimit = 300 // maximum encoded size of a node record in bytes
 
 const ID_SECP256k1_KECCAK = ID("secp256k1-keccak") // the default identity scheme
 
+const textPrefix = "enr:" // prefix of the textual form of a node record
+
 var (
 	errNoID           = errors.New("unknown or unspecified identity scheme")
 	errInvalidSigsize = errors.New("invalid signature size")
@@ -54,6 +57,7 @@ var (
 	errTooBig         = fmt.Errorf("record bigger than %d bytes", SizeLimit)
 	errEncodeUnsigned = errors.New("can't encode unsigned record")
 	errNotFound       = errors.New("no such key in record")
+	errTextPrefix     = fmt.Errorf("record text doesn't start with %q", textPrefix)
 )
 
 // Record represents a node record. The zero value is an empty record.
@@ -205,6 +209,31 @@ func (r *Record) DecodeRLP(s *rlp.Stream) error {
 	return nil
 }
 
+// MarshalText implements encoding.TextMarshaler, returning the textual form of
+// the record: "enr:" followed by the URL-safe base64 encoding of the RLP record.
+func (r Record) MarshalText() ([]byte, error) {
+	if !r.Signed() {
+		return nil, errEncodeUnsigned
+	}
+	text := make([]byte, len(textPrefix)+base64.RawURLEncoding.EncodedLen(len(r.raw)))
+	copy(text, textPrefix)
+	base64.RawURLEncoding.Encode(text[len(textPrefix):], r.raw)
+	return text, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Decoding verifies the
+// signature.
+func (r *Record) UnmarshalText(text []byte) error {
+	if !bytes.HasPrefix(text, []byte(textPrefix)) {
+		return errTextPrefix
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(string(text[len(textPrefix):]))
+	if err != nil {
+		return err
+	}
+	return rlp.DecodeBytes(raw, r)
+}
+
 type s256raw []byte
 
 func (s256raw) ENRKey() string { return "secp256k1" }
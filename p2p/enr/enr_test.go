@@ -87,6 +87,20 @@ func TestGetSetDiscPort(t *testing.T) {
 	assert.Equal(t, port, port2)
 }
 
+// TestGetSetPorts tests encoding/decoding and setting/getting of the TCP and UDP keys.
+func TestGetSetPorts(t *testing.T) {
+	var r Record
+	r.Set(TCP(30303))
+	r.Set(UDP(30301))
+
+	var tcp TCP
+	var udp UDP
+	require.NoError(t, r.Load(&tcp))
+	require.NoError(t, r.Load(&udp))
+	assert.Equal(t, TCP(30303), tcp)
+	assert.Equal(t, UDP(30301), udp)
+}
+
 // TestGetSetSecp256k1 tests encoding/decoding and setting/getting of the Secp256k1 key.
 func TestGetSetSecp256k1(t *testing.T) {
 	var r Record
@@ -224,6 +238,26 @@ func TestSignEncodeAndDecode(t *testing.T) {
 	assert.Equal(t, blob, blob2)
 }
 
+// TestTextEncodeAndDecode tests the textual form of a record.
+func TestTextEncodeAndDecode(t *testing.T) {
+	var r Record
+	r.Set(TCP(30303))
+	r.Set(IP4{127, 0, 0, 1})
+	_, err := r.MarshalText()
+	assert.Equal(t, errEncodeUnsigned, err)
+	require.NoError(t, r.Sign(privkey))
+
+	text, err := r.MarshalText()
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(text, []byte("enr:")))
+
+	var r2 Record
+	require.NoError(t, r2.UnmarshalText(text))
+	assert.Equal(t, r, r2)
+
+	assert.Equal(t, errTextPrefix, r2.UnmarshalText(text[4:]))
+}
+
 func TestNodeAddr(t *testing.T) {
 	var r Record
 	if addr := r.NodeAddr(); addr != nil {
@@ -31,6 +31,7 @@ import (
 	"github.com/69th-byte/sdexchain/log"
 	"github.com/69th-byte/sdexchain/p2p/discover"
 	"github.com/69th-byte/sdexchain/p2p/discv5"
+	"github.com/69th-byte/sdexchain/p2p/dnsdisc"
 	"github.com/69th-byte/sdexchain/p2p/nat"
 	"github.com/69th-byte/sdexchain/p2p/netutil"
 )
@@ -93,6 +94,10 @@ type Config struct {
 	// protocol.
 	BootstrapNodesV5 []*discv5.Node `toml:",omitempty"`
 
+	// DNSDiscovery holds the enrtree:// URLs of DNS node lists (EIP-1459).
+	// Their nodes are dialed when discovery doesn't find enough peers.
+	DNSDiscovery []string `toml:",omitempty"`
+
 	// Static nodes are used as pre-configured connections which are always
 	// maintained and re-connected on disconnects.
 	StaticNodes []*discover.Node
@@ -485,6 +490,13 @@ func (srv *Server) Start() (err error) {
 
 	dynPeers := srv.maxDialedConns()
 	dialer := newDialState(srv.StaticNodes, srv.BootstrapNodes, srv.ntab, dynPeers, srv.NetRestrict)
+	if len(srv.DNSDiscovery) > 0 {
+		client, err := dnsdisc.NewClient(dnsdisc.Config{}, srv.DNSDiscovery...)
+		if err != nil {
+			return err
+		}
+		dialer.dns = client
+	}
 
 	// handshake
 	srv.ourHandshake = &protoHandshake{Version: baseProtocolVersion, Name: srv.Name, ID: discover.PubkeyID(&srv.PrivateKey.PublicKey)}
@@ -921,6 +933,7 @@ type NodeInfo struct {
 	ID    string `json:"id"`    // Unique node identifier (also the encryption key)
 	Name  string `json:"name"`  // Name of the node, including client type, version, OS, custom data
 	Enode string `json:"enode"` // Enode URL for adding this peer from remote peers
+	ENR   string `json:"enr"`   // Signed node record of the endpoint, for DNS node lists
 	IP    string `json:"ip"`    // IP address of the node
 	Ports struct {
 		Discovery int `json:"discovery"` // UDP listening port for discovery protocol
@@ -945,6 +958,11 @@ func (srv *Server) NodeInfo() *NodeInfo {
 	}
 	info.Ports.Discovery = int(node.UDP)
 	info.Ports.Listener = int(node.TCP)
+	if r, err := discover.SignNodeRecord(node, srv.PrivateKey); err == nil && !node.IP.IsUnspecified() {
+		if text, err := r.MarshalText(); err == nil {
+			info.ENR = string(text)
+		}
+	}
 
 	// Gather all the running protocol infos (only once per protocol type)
 	for _, proto := range srv.Protocols {
@@ -102,16 +102,16 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil, nil}
+	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, big.NewInt(0), nil, new(EthashConfig), nil, nil}
 
 	// AllPosvProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Ethereum core developers into the Posv consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllPosvProtocolChanges   = &ChainConfig{big.NewInt(89), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, &PosvConfig{Period: 0, Epoch: 30000}}
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil}
-	TestChainConfig          = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil, nil}
+	AllPosvProtocolChanges   = &ChainConfig{big.NewInt(89), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, big.NewInt(0), nil, nil, nil, &PosvConfig{Period: 0, Epoch: 30000}}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, big.NewInt(0), nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil}
+	TestChainConfig          = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, big.NewInt(0), nil, new(EthashConfig), nil, nil}
 	TestRules                = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -137,6 +137,8 @@ type ChainConfig struct {
 
 	ByzantiumBlock      *big.Int `json:"byzantiumBlock,omitempty"`      // Byzantium switch block (nil = no fork, 0 = already on byzantium)
 	ConstantinopleBlock *big.Int `json:"constantinopleBlock,omitempty"` // Constantinople switch block (nil = no fork, 0 = already activated)
+	EIP2718Block        *big.Int `json:"eip2718Block,omitempty"`        // EIP2718 (typed transactions) switch block (nil = no fork, 0 = already activated)
+	EIP1559Block        *big.Int `json:"eip1559Block,omitempty"`        // EIP1559 (base fee market) switch block (nil = no fork, 0 = already activated)
 
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
@@ -165,12 +167,13 @@ func (c *CliqueConfig) String() string {
 
 // PosvConfig is the consensus engine configs for proof-of-stake-voting based sealing.
 type PosvConfig struct {
-	Period              uint64         `json:"period"`              // Number of seconds between blocks to enforce
-	Epoch               uint64         `json:"epoch"`               // Epoch length to reset votes and checkpoint
-	Reward              uint64         `json:"reward"`              // Block reward - unit Ether
-	RewardCheckpoint    uint64         `json:"rewardCheckpoint"`    // Checkpoint block for calculate rewards.
-	Gap                 uint64         `json:"gap"`                 // Gap time preparing for the next epoch
-	FoudationWalletAddr common.Address `json:"foudationWalletAddr"` // Foundation Address Wallet
+	Period              uint64         `json:"period"`                // Number of seconds between blocks to enforce
+	Epoch               uint64         `json:"epoch"`                 // Epoch length to reset votes and checkpoint
+	Reward              uint64         `json:"reward"`                // Block reward - unit Ether
+	RewardCheckpoint    uint64         `json:"rewardCheckpoint"`      // Checkpoint block for calculate rewards.
+	Gap                 uint64         `json:"gap"`                   // Gap time preparing for the next epoch
+	FoudationWalletAddr common.Address `json:"foudationWalletAddr"`   // Foundation Address Wallet
+	BurnBaseFee         bool           `json:"burnBaseFee,omitempty"` // Burn the EIP1559 base fee instead of paying it to the foundation wallet
 }
 
 // String implements the stringer interface, returning the consensus engine details.
@@ -189,7 +192,7 @@ func (c *ChainConfig) String() string {
 	default:
 		engine = "unknown"
 	}
-	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Engine: %v}",
+	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v EIP2718: %v EIP1559: %v Engine: %v}",
 		c.ChainId,
 		c.HomesteadBlock,
 		c.DAOForkBlock,
@@ -199,6 +202,8 @@ func (c *ChainConfig) String() string {
 		c.EIP158Block,
 		c.ByzantiumBlock,
 		c.ConstantinopleBlock,
+		c.EIP2718Block,
+		c.EIP1559Block,
 		engine,
 	)
 }
@@ -233,6 +238,18 @@ func (c *ChainConfig) IsConstantinople(num *big.Int) bool {
 	return isForked(c.ConstantinopleBlock, num)
 }
 
+// IsEIP2718 returns whether num is either equal to the EIP2718 fork block or
+// greater, enabling typed transactions.
+func (c *ChainConfig) IsEIP2718(num *big.Int) bool {
+	return isForked(c.EIP2718Block, num)
+}
+
+// IsEIP1559 returns whether num is either equal to the EIP1559 fork block or
+// greater, enabling the base fee market.
+func (c *ChainConfig) IsEIP1559(num *big.Int) bool {
+	return isForked(c.EIP1559Block, num)
+}
+
 // IsPetersburg returns whether num is either
 // - equal to or greater than the PetersburgBlock fork block,
 // - OR is nil, and Constantinople is active
@@ -273,6 +290,20 @@ func (c *ChainConfig) IsTIPTomoXCancellationFee(num *big.Int) bool {
 	return isForked(common.TIPTomoXCancellationFee, num)
 }
 
+func (c *ChainConfig) IsTIPTomoXRoutedOrder(num *big.Int) bool {
+	return isForked(common.TIPTomoXRoutedOrder, num)
+}
+
+func (c *ChainConfig) IsTIPTomoXPriceOracle(num *big.Int) bool {
+	return isForked(common.TIPTomoXPriceOracle, num)
+}
+
+// IsTIPTomoXTypedData returns whether num is past the fork accepting order and
+// lending transactions signed over their EIP-712 typed data.
+func (c *ChainConfig) IsTIPTomoXTypedData(num *big.Int) bool {
+	return isForked(common.TIPTomoXTypedData, num)
+}
+
 // GasTable returns the gas table corresponding to the current phase (homestead or homestead reprice).
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
@@ -336,6 +367,12 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.ConstantinopleBlock, newcfg.ConstantinopleBlock, head) {
 		return newCompatError("Constantinople fork block", c.ConstantinopleBlock, newcfg.ConstantinopleBlock)
 	}
+	if isForkIncompatible(c.EIP2718Block, newcfg.EIP2718Block, head) {
+		return newCompatError("EIP2718 fork block", c.EIP2718Block, newcfg.EIP2718Block)
+	}
+	if isForkIncompatible(c.EIP1559Block, newcfg.EIP1559Block, head) {
+		return newCompatError("EIP1559 fork block", c.EIP1559Block, newcfg.EIP1559Block)
+	}
 	return nil
 }
 
@@ -403,6 +440,7 @@ type Rules struct {
 	ChainId                                                 *big.Int
 	IsHomestead, IsEIP150, IsEIP155, IsEIP158               bool
 	IsByzantium, IsConstantinople, IsPetersburg, IsIstanbul bool
+	IsEIP2718, IsEIP1559, IsTIPTomoXPriceOracle             bool
 }
 
 func (c *ChainConfig) Rules(num *big.Int) Rules {
@@ -420,5 +458,9 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 		IsConstantinople: c.IsConstantinople(num),
 		IsPetersburg:     c.IsPetersburg(num),
 		IsIstanbul:       c.IsIstanbul(num),
+		IsEIP2718:        c.IsEIP2718(num),
+		IsEIP1559:        c.IsEIP1559(num),
+
+		IsTIPTomoXPriceOracle: c.IsTIPTomoXPriceOracle(num),
 	}
 }
@@ -23,4 +23,9 @@ const (
 	// BloomBitsBlocks is the number of blocks a single bloom bit section vector
 	// contains.
 	BloomBitsBlocks uint64 = 4096
+
+	// ImmutabilityThreshold is the number of blocks after which a chain segment is
+	// considered immutable (i.e. soft finality). It is used by the chain freezer
+	// as the number of recent blocks kept in the key-value database.
+	ImmutabilityThreshold = 90000
 )
@@ -531,9 +531,24 @@ func makeStructWriter(typ reflect.Type) (writer, error) {
 	if err != nil {
 		return nil, err
 	}
+	firstOptional := len(fields)
+	for i, f := range fields {
+		if f.optional {
+			firstOptional = i
+			break
+		}
+	}
 	writer := func(val reflect.Value, w *encbuf) error {
+		// Trailing optional fields are omitted as long as they and all
+		// optional fields after them are zero.
+		last := len(fields) - 1
+		for ; last >= firstOptional; last-- {
+			if !val.Field(fields[last].index).IsZero() {
+				break
+			}
+		}
 		lh := w.list()
-		for _, f := range fields {
+		for _, f := range fields[:last+1] {
 			if err := f.info.writer(val.Field(f.index), w); err != nil {
 				return err
 			}
@@ -29,7 +29,23 @@ func (tomox *TomoX) CommitOrder(header *types.Header, coinbase common.Address, c
 	return trades, rejects, err
 }
 
+// ApplyOrder applies an order to the order book, reporting the matching steps to
+// the tracer attached to tradingStateDB if any.
 func (tomox *TomoX) ApplyOrder(header *types.Header, coinbase common.Address, chain consensus.ChainContext, statedb *state.StateDB, tradingStateDB *tradingstate.TradingStateDB, orderBook common.Hash, order *tradingstate.OrderItem) ([]map[string]string, []*tradingstate.OrderItem, error) {
+	tracer := tradingStateDB.MatchingTracer()
+	if tracer == nil {
+		return tomox.applyOrder(header, coinbase, chain, statedb, tradingStateDB, orderBook, order)
+	}
+	tracer.CaptureOrderStart(tradingstate.TradingEngine, orderBook, order.Hash, order.UserAddress, order.Side, order.Type, order.Price, order.Quantity)
+	trades, rejects, err := tomox.applyOrder(header, coinbase, chain, statedb, tradingStateDB, orderBook, order)
+	for _, reject := range rejects {
+		tracer.CaptureReject(reject.Hash, reject.RejectReason)
+	}
+	tracer.CaptureOrderEnd(err)
+	return trades, rejects, err
+}
+
+func (tomox *TomoX) applyOrder(header *types.Header, coinbase common.Address, chain consensus.ChainContext, statedb *state.StateDB, tradingStateDB *tradingstate.TradingStateDB, orderBook common.Hash, order *tradingstate.OrderItem) ([]map[string]string, []*tradingstate.OrderItem, error) {
 	var (
 		rejects []*tradingstate.OrderItem
 		trades  []map[string]string
@@ -56,7 +72,8 @@ func (tomox *TomoX) ApplyOrder(header *types.Header, coinbase common.Address, ch
 		}
 	}()
 
-	if err := order.VerifyOrder(statedb); err != nil {
+	if err := order.VerifyOrder(statedb, types.MakeOrderSigner(chain.Config(), header.Number)); err != nil {
+		order.SetRejectReason(tradingstate.GetRejectReason(err))
 		rejects = append(rejects, order)
 		return trades, rejects, nil
 	}
@@ -64,19 +81,28 @@ func (tomox *TomoX) ApplyOrder(header *types.Header, coinbase common.Address, ch
 		err, reject := tomox.ProcessCancelOrder(header, tradingStateDB, statedb, chain, coinbase, orderBook, order)
 		if err != nil || reject {
 			log.Debug("Reject cancelled order", "err", err)
+			order.SetRejectReason(tradingstate.RejectReasonCancelFailed)
 			rejects = append(rejects, order)
 		}
 		return trades, rejects, nil
 	}
-	if order.Type != tradingstate.Market {
+	if order.Type == tradingstate.RoutedMarket && !chain.Config().IsTIPTomoXRoutedOrder(header.Number) {
+		log.Debug("Reject routed order before fork", "number", header.Number)
+		order.SetRejectReason(tradingstate.RejectReasonInvalidOrder)
+		rejects = append(rejects, order)
+		return trades, rejects, nil
+	}
+	if order.Type != tradingstate.Market && order.Type != tradingstate.RoutedMarket {
 		if order.Price.Sign() == 0 || common.BigToHash(order.Price).Big().Cmp(order.Price) != 0 {
 			log.Debug("Reject order price invalid", "price", order.Price)
+			order.SetRejectReason(tradingstate.RejectReasonInvalidPrice)
 			rejects = append(rejects, order)
 			return trades, rejects, nil
 		}
 	}
 	if order.Quantity.Sign() == 0 || common.BigToHash(order.Quantity).Big().Cmp(order.Quantity) != 0 {
 		log.Debug("Reject order quantity invalid", "quantity", order.Quantity)
+		order.SetRejectReason(tradingstate.RejectReasonInvalidQuantity)
 		rejects = append(rejects, order)
 		return trades, rejects, nil
 	}
@@ -88,6 +114,16 @@ func (tomox *TomoX) ApplyOrder(header *types.Header, coinbase common.Address, ch
 		if err != nil {
 			log.Debug("Reject market order", "err", err, "order", tradingstate.ToJSON(order))
 			trades = []map[string]string{}
+			order.SetRejectReason(tradingstate.RejectReasonMatchingFailed)
+			rejects = append(rejects, order)
+		}
+	} else if orderType == tradingstate.RoutedMarket {
+		log.Debug("Process routed order", "route", order.Route, "quantity", order.Quantity, "minAmountOut", order.Price)
+		trades, rejects, err = tomox.processRoutedOrder(coinbase, chain, statedb, tradingStateDB, order)
+		if err != nil {
+			log.Debug("Reject routed order", "err", err, "order", tradingstate.ToJSON(order))
+			trades = []map[string]string{}
+			order.SetRejectReason(tradingstate.RejectReasonMatchingFailed)
 			rejects = append(rejects, order)
 		}
 	} else {
@@ -96,13 +132,33 @@ func (tomox *TomoX) ApplyOrder(header *types.Header, coinbase common.Address, ch
 		if err != nil {
 			log.Debug("Reject limit order", "err", err, "order", tradingstate.ToJSON(order))
 			trades = []map[string]string{}
+			order.SetRejectReason(tradingstate.RejectReasonMatchingFailed)
 			rejects = append(rejects, order)
 		}
 	}
+	if chain.Config().IsTIPTomoXPriceOracle(header.Number) {
+		observePrices(header, tradingStateDB, trades)
+	}
 
 	return trades, rejects, nil
 }
 
+// observePrices records the trades of an order to the price oracles of their order books
+func observePrices(header *types.Header, tradingStateDB *tradingstate.TradingStateDB, trades []map[string]string) {
+	for _, trade := range trades {
+		price, ok := new(big.Int).SetString(trade[tradingstate.TradePrice], 10)
+		if !ok {
+			continue
+		}
+		quantity, ok := new(big.Int).SetString(trade[tradingstate.TradeQuantity], 10)
+		if !ok {
+			continue
+		}
+		orderBook := tradingstate.GetTradingOrderBookHash(common.HexToAddress(trade[tradingstate.TradeBaseToken]), common.HexToAddress(trade[tradingstate.TradeQuoteToken]))
+		tradingStateDB.ObservePrice(orderBook, header.Number.Uint64(), header.Time.Uint64(), price, quantity)
+	}
+}
+
 // processMarketOrder : process the market order
 func (tomox *TomoX) processMarketOrder(coinbase common.Address, chain consensus.ChainContext, statedb *state.StateDB, tradingStateDB *tradingstate.TradingStateDB, orderBook common.Hash, order *tradingstate.OrderItem) ([]map[string]string, []*tradingstate.OrderItem, error) {
 	var (
@@ -215,6 +271,10 @@ func (tomox *TomoX) processOrderList(coinbase common.Address, chain consensus.Ch
 
 		rejects []*tradingstate.OrderItem
 	)
+	tracer := tradingStateDB.MatchingTracer()
+	if tracer != nil {
+		tracer.CaptureLevel(side, price, quantityToTrade)
+	}
 	for quantityToTrade.Sign() > 0 {
 		orderId, amount, _ := tradingStateDB.GetBestOrderIdAndAmount(orderBook, price, side)
 		var oldestOrder tradingstate.OrderItem
@@ -254,12 +314,14 @@ func (tomox *TomoX) processOrderList(coinbase common.Address, chain consensus.Ch
 		} else {
 			quotePrice = common.BasePrice
 		}
-		tradedQuantity, rejectMaker, settleBalanceResult, err := tomox.getTradeQuantity(quotePrice, coinbase, chain, statedb, order, &oldestOrder, maxTradedQuantity)
+		tradedQuantity, rejectMaker, settleBalanceResult, err := tomox.getTradeQuantity(quotePrice, coinbase, chain, statedb, tracer, order, &oldestOrder, maxTradedQuantity)
 		if err != nil && err == tradingstate.ErrQuantityTradeTooSmall {
 			if tradedQuantity.Cmp(maxTradedQuantity) == 0 {
 				if quantityToTrade.Cmp(amount) == 0 { // reject Taker & maker
+					order.SetRejectReason(tradingstate.RejectReasonQuantityTooSmall)
 					rejects = append(rejects, order)
 					quantityToTrade = tradingstate.Zero
+					oldestOrder.SetRejectReason(tradingstate.RejectReasonQuantityTooSmall)
 					rejects = append(rejects, &oldestOrder)
 					err = tradingStateDB.CancelOrder(orderBook, &oldestOrder)
 					if err != nil {
@@ -267,10 +329,12 @@ func (tomox *TomoX) processOrderList(coinbase common.Address, chain consensus.Ch
 					}
 					break
 				} else if quantityToTrade.Cmp(amount) < 0 { // reject Taker
+					order.SetRejectReason(tradingstate.RejectReasonQuantityTooSmall)
 					rejects = append(rejects, order)
 					quantityToTrade = tradingstate.Zero
 					break
 				} else { // reject maker
+					oldestOrder.SetRejectReason(tradingstate.RejectReasonQuantityTooSmall)
 					rejects = append(rejects, &oldestOrder)
 					err = tradingStateDB.CancelOrder(orderBook, &oldestOrder)
 					if err != nil {
@@ -280,6 +344,7 @@ func (tomox *TomoX) processOrderList(coinbase common.Address, chain consensus.Ch
 				}
 			} else {
 				if rejectMaker { // reject maker
+					oldestOrder.SetRejectReason(tradingstate.RejectReasonQuantityTooSmall)
 					rejects = append(rejects, &oldestOrder)
 					err = tradingStateDB.CancelOrder(orderBook, &oldestOrder)
 					if err != nil {
@@ -287,6 +352,7 @@ func (tomox *TomoX) processOrderList(coinbase common.Address, chain consensus.Ch
 					}
 					continue
 				} else { // reject Taker
+					order.SetRejectReason(tradingstate.RejectReasonQuantityTooSmall)
 					rejects = append(rejects, order)
 					quantityToTrade = tradingstate.Zero
 					break
@@ -297,6 +363,7 @@ func (tomox *TomoX) processOrderList(coinbase common.Address, chain consensus.Ch
 		}
 		if tradedQuantity.Sign() == 0 && !rejectMaker {
 			log.Debug("Reject order Taker ", "tradedQuantity", tradedQuantity, "rejectMaker", rejectMaker)
+			order.SetRejectReason(tradingstate.RejectReasonInsufficientBalance)
 			rejects = append(rejects, order)
 			quantityToTrade = tradingstate.Zero
 			break
@@ -327,6 +394,9 @@ func (tomox *TomoX) processOrderList(coinbase common.Address, chain consensus.Ch
 			tradeRecord[tradingstate.TradePrice] = oldestOrder.Price.String()
 			tradeRecord[tradingstate.MakerOrderType] = oldestOrder.Type
 			trades = append(trades, tradeRecord)
+			if tracer != nil {
+				tracer.CaptureTrade(oldestOrder.Hash, oldestOrder.UserAddress, oldestOrder.Price, tradedQuantity)
+			}
 
 			oldAveragePrice, oldTotalQuantity := tradingStateDB.GetMediumPriceAndTotalAmount(orderBook)
 
@@ -351,6 +421,7 @@ func (tomox *TomoX) processOrderList(coinbase common.Address, chain consensus.Ch
 			tradingStateDB.SetMediumPrice(orderBook, newAveragePrice, newTotalQuantity)
 		}
 		if rejectMaker {
+			oldestOrder.SetRejectReason(tradingstate.RejectReasonInsufficientBalance)
 			rejects = append(rejects, &oldestOrder)
 			err := tradingStateDB.CancelOrder(orderBook, &oldestOrder)
 			if err != nil {
@@ -361,7 +432,7 @@ func (tomox *TomoX) processOrderList(coinbase common.Address, chain consensus.Ch
 	return quantityToTrade, trades, rejects, nil
 }
 
-func (tomox *TomoX) getTradeQuantity(quotePrice *big.Int, coinbase common.Address, chain consensus.ChainContext, statedb *state.StateDB, takerOrder *tradingstate.OrderItem, makerOrder *tradingstate.OrderItem, quantityToTrade *big.Int) (*big.Int, bool, *tradingstate.SettleBalance, error) {
+func (tomox *TomoX) getTradeQuantity(quotePrice *big.Int, coinbase common.Address, chain consensus.ChainContext, statedb *state.StateDB, tracer tradingstate.MatchingTracer, takerOrder *tradingstate.OrderItem, makerOrder *tradingstate.OrderItem, quantityToTrade *big.Int) (*big.Int, bool, *tradingstate.SettleBalance, error) {
 	baseTokenDecimal, err := tomox.GetTokenDecimal(chain, statedb, makerOrder.BaseToken)
 	if err != nil || baseTokenDecimal.Sign() == 0 {
 		return tradingstate.Zero, false, nil, fmt.Errorf("Fail to get tokenDecimal. Token: %v . Err: %v", makerOrder.BaseToken.String(), err)
@@ -376,15 +447,18 @@ func (tomox *TomoX) getTradeQuantity(quotePrice *big.Int, coinbase common.Addres
 	if takerOrder.ExchangeAddress.String() == makerOrder.ExchangeAddress.String() {
 		if err := tradingstate.CheckRelayerFee(takerOrder.ExchangeAddress, new(big.Int).Mul(common.RelayerFee, big.NewInt(2)), statedb); err != nil {
 			log.Debug("Reject order Taker Exchnage = Maker Exchange , relayer not enough fee ", "err", err)
+			takerOrder.SetRejectReason(tradingstate.RejectReasonRelayerFee)
 			return tradingstate.Zero, false, nil, nil
 		}
 	} else {
 		if err := tradingstate.CheckRelayerFee(takerOrder.ExchangeAddress, common.RelayerFee, statedb); err != nil {
 			log.Debug("Reject order Taker , relayer not enough fee ", "err", err)
+			takerOrder.SetRejectReason(tradingstate.RejectReasonRelayerFee)
 			return tradingstate.Zero, false, nil, nil
 		}
 		if err := tradingstate.CheckRelayerFee(makerOrder.ExchangeAddress, common.RelayerFee, statedb); err != nil {
 			log.Debug("Reject order maker , relayer not enough fee ", "err", err)
+			makerOrder.SetRejectReason(tradingstate.RejectReasonRelayerFee)
 			return tradingstate.Zero, true, nil, nil
 		}
 	}
@@ -410,7 +484,7 @@ func (tomox *TomoX) getTradeQuantity(quotePrice *big.Int, coinbase common.Addres
 		settleBalanceResult, err = tradingstate.GetSettleBalance(quotePrice, takerOrder.Side, takerFeeRate, makerOrder.BaseToken, makerOrder.QuoteToken, makerOrder.Price, makerFeeRate, baseTokenDecimal, quoteTokenDecimal, quantity)
 		log.Debug("GetSettleBalance", "settleBalanceResult", settleBalanceResult, "err", err)
 		if err == nil {
-			err = DoSettleBalance(coinbase, takerOrder, makerOrder, settleBalanceResult, statedb)
+			err = DoSettleBalance(coinbase, takerOrder, makerOrder, settleBalanceResult, statedb, tracer)
 		}
 		return quantity, rejectMaker, settleBalanceResult, err
 	}
@@ -513,7 +587,9 @@ func GetTradeQuantity(takerSide string, takerFeeRate *big.Int, takerBalance *big
 	}
 }
 
-func DoSettleBalance(coinbase common.Address, takerOrder, makerOrder *tradingstate.OrderItem, settleBalance *tradingstate.SettleBalance, statedb *state.StateDB) error {
+// DoSettleBalance moves the balances of a trade between the taker and the maker
+// and pays the relayer and masternode fees. The tracer may be nil.
+func DoSettleBalance(coinbase common.Address, takerOrder, makerOrder *tradingstate.OrderItem, settleBalance *tradingstate.SettleBalance, statedb *state.StateDB, tracer tradingstate.MatchingTracer) error {
 	takerExOwner := tradingstate.GetRelayerOwner(takerOrder.ExchangeAddress, statedb)
 	makerExOwner := tradingstate.GetRelayerOwner(makerOrder.ExchangeAddress, statedb)
 	matchingFee := big.NewInt(0)
@@ -604,12 +680,24 @@ func DoSettleBalance(coinbase common.Address, takerOrder, makerOrder *tradingsta
 	// takerFee
 	tradingstate.SetTokenBalance(takerExOwner, newTakerFee, makerOrder.QuoteToken, statedb)
 	tradingstate.SetTokenBalance(makerExOwner, newMakerFee, makerOrder.QuoteToken, statedb)
+
+	if tracer != nil {
+		tracer.CaptureBalance(takerOrder.UserAddress, settleBalance.Taker.InToken, settleBalance.Taker.InTotal)
+		tracer.CaptureBalance(takerOrder.UserAddress, settleBalance.Taker.OutToken, new(big.Int).Neg(settleBalance.Taker.OutTotal))
+		tracer.CaptureBalance(makerOrder.UserAddress, settleBalance.Maker.InToken, settleBalance.Maker.InTotal)
+		tracer.CaptureBalance(makerOrder.UserAddress, settleBalance.Maker.OutToken, new(big.Int).Neg(settleBalance.Maker.OutTotal))
+		tracer.CaptureFee(takerOrder.UserAddress, takerExOwner, makerOrder.QuoteToken, settleBalance.Taker.Fee)
+		tracer.CaptureFee(makerOrder.UserAddress, makerExOwner, makerOrder.QuoteToken, settleBalance.Maker.Fee)
+		tracer.CaptureFee(takerOrder.ExchangeAddress, masternodeOwner, common.HexToAddress(common.TomoNativeAddress), common.RelayerFee)
+		tracer.CaptureFee(makerOrder.ExchangeAddress, masternodeOwner, common.HexToAddress(common.TomoNativeAddress), common.RelayerFee)
+	}
 	return nil
 }
 
 func (tomox *TomoX) ProcessCancelOrder(header *types.Header, tradingStateDB *tradingstate.TradingStateDB, statedb *state.StateDB, chain consensus.ChainContext, coinbase common.Address, orderBook common.Hash, order *tradingstate.OrderItem) (error, bool) {
 	if err := tradingstate.CheckRelayerFee(order.ExchangeAddress, common.RelayerCancelFee, statedb); err != nil {
 		log.Debug("Relayer not enough fee when cancel order", "err", err)
+		order.SetRejectReason(tradingstate.RejectReasonRelayerFee)
 		return nil, true
 	}
 	baseTokenDecimal, err := tomox.GetTokenDecimal(chain, statedb, order.BaseToken)
@@ -620,7 +708,8 @@ func (tomox *TomoX) ProcessCancelOrder(header *types.Header, tradingStateDB *tra
 	// order: basic order information (includes orderId, orderHash, baseToken, quoteToken) which user send to tomox to cancel order
 	// originOrder: full order information getting from order trie
 	originOrder := tradingStateDB.GetOrder(orderBook, common.BigToHash(new(big.Int).SetUint64(order.OrderID)))
-	if originOrder == tradingstate.EmptyOrder {
+	if tradingstate.IsEmptyOrder(originOrder) {
+		order.SetRejectReason(tradingstate.RejectReasonOrderNotFound)
 		return fmt.Errorf("order not found. OrderId: %v. Base: %s. Quote: %s", order.OrderID, order.BaseToken.Hex(), order.QuoteToken.Hex()), false
 	}
 	var tokenBalance *big.Int
@@ -643,6 +732,7 @@ func (tomox *TomoX) ProcessCancelOrder(header *types.Header, tradingStateDB *tra
 	}
 	if tokenBalance.Cmp(tokenCancelFee) < 0 {
 		log.Debug("User not enough balance when cancel order", "Side", originOrder.Side, "balance", tokenBalance, "fee", tokenCancelFee)
+		order.SetRejectReason(tradingstate.RejectReasonInsufficientBalance)
 		return nil, true
 	}
 
@@ -669,6 +759,14 @@ func (tomox *TomoX) ProcessCancelOrder(header *types.Header, tradingStateDB *tra
 		tradingstate.AddTokenBalance(relayerOwner, tokenCancelFee, originOrder.QuoteToken, statedb)
 	default:
 	}
+	if tracer := tradingStateDB.MatchingTracer(); tracer != nil {
+		feeToken := originOrder.QuoteToken
+		if originOrder.Side == tradingstate.Ask {
+			feeToken = originOrder.BaseToken
+		}
+		tracer.CaptureFee(originOrder.ExchangeAddress, masternodeOwner, common.HexToAddress(common.TomoNativeAddress), common.RelayerCancelFee)
+		tracer.CaptureFee(originOrder.UserAddress, relayerOwner, feeToken, tokenCancelFee)
+	}
 	// update cancel fee
 	extraData, _ := json.Marshal(struct {
 		CancelFee        string
@@ -0,0 +1,236 @@
+package relay
+
+import (
+	"context"
+	"errors"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/common/hexutil"
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/tomox/tradingstate"
+)
+
+var (
+	ErrNoQuantity = errors.New("missing quantity")
+	ErrNoPrice    = errors.New("missing price")
+)
+
+// PublicRelayAPI provides the relay RPC service, for relayers to answer quote
+// requests and for their users to request and accept quotes.
+type PublicRelayAPI struct {
+	r *Relay
+}
+
+// NewPublicRelayAPI creates a new relay RPC service.
+func NewPublicRelayAPI(r *Relay) *PublicRelayAPI {
+	return &PublicRelayAPI{r: r}
+}
+
+// Version returns the relay sub-protocol version.
+func (api *PublicRelayAPI) Version(ctx context.Context) string {
+	return ProtocolVersionStr
+}
+
+// RPCOrder is the JSON representation of a quoted order, in the format of the
+// orders sent to tomox_sendOrder.
+type RPCOrder struct {
+	AccountNonce    hexutil.Uint64 `json:"nonce"`
+	Quantity        *hexutil.Big   `json:"quantity"`
+	Price           *hexutil.Big   `json:"price"`
+	ExchangeAddress common.Address `json:"exchangeAddress"`
+	UserAddress     common.Address `json:"userAddress"`
+	BaseToken       common.Address `json:"baseToken"`
+	QuoteToken      common.Address `json:"quoteToken"`
+	Status          string         `json:"status"`
+	Side            string         `json:"side"`
+	Type            string         `json:"type"`
+	Hash            common.Hash    `json:"hash"`
+}
+
+// RPCQuote is the JSON representation of a quote.
+type RPCQuote struct {
+	Request common.Hash    `json:"request"`
+	Order   *RPCOrder      `json:"order"`
+	Expiry  hexutil.Uint64 `json:"expiry"`
+}
+
+func newRPCQuote(q *Quote) *RPCQuote {
+	order := q.Order
+	return &RPCQuote{
+		Request: q.Request,
+		Order: &RPCOrder{
+			AccountNonce:    hexutil.Uint64(order.Nonce()),
+			Quantity:        (*hexutil.Big)(order.Quantity()),
+			Price:           (*hexutil.Big)(order.Price()),
+			ExchangeAddress: order.ExchangeAddress(),
+			UserAddress:     order.UserAddress(),
+			BaseToken:       order.BaseToken(),
+			QuoteToken:      order.QuoteToken(),
+			Status:          order.Status(),
+			Side:            order.Side(),
+			Type:            order.Type(),
+			Hash:            order.OrderHash(),
+		},
+		Expiry: hexutil.Uint64(q.Expiry),
+	}
+}
+
+// RPCRequest is the JSON representation of a quote request.
+type RPCRequest struct {
+	ID         common.Hash    `json:"id"`
+	Exchange   common.Address `json:"exchange"`
+	User       common.Address `json:"user"`
+	Nonce      hexutil.Uint64 `json:"nonce"`
+	BaseToken  common.Address `json:"baseToken"`
+	QuoteToken common.Address `json:"quoteToken"`
+	Side       string         `json:"side"`
+	Quantity   *hexutil.Big   `json:"quantity"`
+	Expiry     hexutil.Uint64 `json:"expiry"`
+}
+
+// RequestArgs are the arguments of a quote request.
+type RequestArgs struct {
+	Exchange   common.Address `json:"exchange"`
+	User       common.Address `json:"user"`
+	Nonce      hexutil.Uint64 `json:"nonce"`
+	BaseToken  common.Address `json:"baseToken"`
+	QuoteToken common.Address `json:"quoteToken"`
+	Side       string         `json:"side"`
+	Quantity   *hexutil.Big   `json:"quantity"`
+	TTL        uint32         `json:"ttl"`
+}
+
+// RequestQuote asks the relayers of a pair for a quote, returning the ID of the
+// request.
+func (api *PublicRelayAPI) RequestQuote(ctx context.Context, args RequestArgs) (common.Hash, error) {
+	if args.Quantity == nil || args.Quantity.ToInt().Sign() <= 0 {
+		return common.Hash{}, ErrNoQuantity
+	}
+	if args.TTL == 0 {
+		args.TTL = DefaultTTL
+	}
+	req := &Request{
+		Exchange:   args.Exchange,
+		User:       args.User,
+		Nonce:      uint64(args.Nonce),
+		BaseToken:  args.BaseToken,
+		QuoteToken: args.QuoteToken,
+		Side:       args.Side,
+		Quantity:   args.Quantity.ToInt(),
+	}
+	return api.r.RequestQuote(req, args.TTL)
+}
+
+// Subscribe starts tracking the requests and the indications of interest of a
+// pair.
+func (api *PublicRelayAPI) Subscribe(ctx context.Context, base, quote common.Address) error {
+	return api.r.Subscribe(base, quote)
+}
+
+// GetRequests returns the pending quote requests of a subscribed pair.
+func (api *PublicRelayAPI) GetRequests(ctx context.Context, base, quote common.Address) []*RPCRequest {
+	reqs := make([]*RPCRequest, 0)
+	for _, req := range api.r.Requests(base, quote) {
+		reqs = append(reqs, &RPCRequest{
+			ID:         req.ID,
+			Exchange:   req.Exchange,
+			User:       req.User,
+			Nonce:      hexutil.Uint64(req.Nonce),
+			BaseToken:  req.BaseToken,
+			QuoteToken: req.QuoteToken,
+			Side:       req.Side,
+			Quantity:   (*hexutil.Big)(req.Quantity),
+			Expiry:     hexutil.Uint64(req.Expiry),
+		})
+	}
+	return reqs
+}
+
+// QuoteArgs are the arguments of a quote. A quote answering a request takes the
+// trade of the request, an indication of interest (a zero request) is given the
+// pair, side and quantity of the order.
+type QuoteArgs struct {
+	Sig        string         `json:"sig"` // Whisper key ID of the exchange key
+	Request    common.Hash    `json:"request"`
+	BaseToken  common.Address `json:"baseToken"`
+	QuoteToken common.Address `json:"quoteToken"`
+	Side       string         `json:"side"`
+	Quantity   *hexutil.Big   `json:"quantity"`
+	Price      *hexutil.Big   `json:"price"`
+	TTL        uint32         `json:"ttl"`
+}
+
+// SendQuote answers a quote request, or posts an indication of interest to the
+// pair, signed with the exchange key loaded in Whisper.
+func (api *PublicRelayAPI) SendQuote(ctx context.Context, args QuoteArgs) (common.Hash, error) {
+	if args.Price == nil || args.Price.ToInt().Sign() <= 0 {
+		return common.Hash{}, ErrNoPrice
+	}
+	key, err := api.r.shh.GetPrivateKey(args.Sig)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if args.TTL == 0 {
+		args.TTL = DefaultTTL
+	}
+	exchange := crypto.PubkeyToAddress(key.PublicKey)
+
+	var order *types.OrderTransaction
+	if args.Request == (common.Hash{}) {
+		if args.Quantity == nil || args.Quantity.ToInt().Sign() <= 0 {
+			return common.Hash{}, ErrNoQuantity
+		}
+		if args.Side != tradingstate.Bid && args.Side != tradingstate.Ask {
+			return common.Hash{}, ErrInvalidSide
+		}
+		order = types.NewOrderTransaction(0, args.Quantity.ToInt(), args.Price.ToInt(), exchange, common.Address{}, args.BaseToken, args.QuoteToken, types.OrderStatusNew, args.Side, types.OrderTypeLo, common.Hash{}, 0)
+	} else {
+		req := api.r.request(args.Request)
+		if req == nil {
+			return common.Hash{}, ErrUnknownRequest
+		}
+		order = types.NewOrderTransaction(req.Nonce, req.Quantity, args.Price.ToInt(), exchange, req.User, req.BaseToken, req.QuoteToken, types.OrderStatusNew, req.Side, types.OrderTypeLo, common.Hash{}, 0)
+	}
+	order.SetOrderHash(types.OrderTxSigner{}.Hash(order))
+
+	quote := &Quote{Request: args.Request, Order: order}
+	if err := api.r.SendQuote(quote, key, args.TTL); err != nil {
+		return common.Hash{}, err
+	}
+	return quote.Hash(), nil
+}
+
+// GetQuotes returns the quotes received for a request of the local node.
+func (api *PublicRelayAPI) GetQuotes(ctx context.Context, id common.Hash) ([]*RPCQuote, error) {
+	quotes, err := api.r.Quotes(id)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*RPCQuote, 0, len(quotes))
+	for _, q := range quotes {
+		res = append(res, newRPCQuote(q))
+	}
+	return res, nil
+}
+
+// GetInterests returns the indications of interest posted to a subscribed pair.
+// Their orders have no user and can't be accepted, takers request a quote from
+// the exchange instead.
+func (api *PublicRelayAPI) GetInterests(ctx context.Context, base, quote common.Address) []*RPCQuote {
+	res := make([]*RPCQuote, 0)
+	for _, q := range api.r.Interests(base, quote) {
+		res = append(res, newRPCQuote(q))
+	}
+	return res
+}
+
+// AcceptQuote submits the order of a quote with the user's signature of its
+// hash, returning the hash of the order transaction.
+func (api *PublicRelayAPI) AcceptQuote(ctx context.Context, hash common.Hash, sig hexutil.Bytes) (common.Hash, error) {
+	tx, err := api.r.AcceptQuote(hash, sig)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return tx.Hash(), nil
+}
@@ -0,0 +1,540 @@
+// Package relay implements an off-chain channel over Whisper for relayers to
+// send quotes to their users before they become on-chain orders.
+//
+// Every trading pair has a Whisper topic and a symmetric key derived from the
+// pair, so that any node subscribed to the pair reads the quote requests and the
+// indications of interest posted to it. A relayer answers a request with a
+// quote: the order the requester would submit, in the OrderTransaction format,
+// posted in a Whisper message signed with the relayer's exchange key and
+// encrypted to the requester. The requester accepts the quote by signing the
+// order, which is then submitted unchanged to the order pool.
+package relay
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/log"
+	"github.com/69th-byte/sdexchain/p2p"
+	"github.com/69th-byte/sdexchain/rlp"
+	"github.com/69th-byte/sdexchain/rpc"
+	"github.com/69th-byte/sdexchain/tomox/tradingstate"
+	whisper "github.com/69th-byte/sdexchain/whisper/whisperv6"
+)
+
+const (
+	ProtocolName       = "relay"
+	ProtocolVersionStr = "1.0"
+
+	DefaultTTL = 60 // Default lifetime of requests and quotes, in seconds
+	MaxTTL     = 3600
+
+	maxPairEntries   = 256 // Maximum number of requests or indications of interest tracked per pair
+	maxRequestQuotes = 64  // Maximum number of quotes tracked per request of the local node
+
+	powTime = 2 // Seconds spent on the Whisper proof of work of a message
+)
+
+// Message types, the first byte of the Whisper payload.
+const (
+	requestMsg = 0x00
+	quoteMsg   = 0x01
+)
+
+var (
+	ErrUnknownRequest   = errors.New("unknown quote request")
+	ErrUnknownQuote     = errors.New("unknown quote")
+	ErrQuoteExpired     = errors.New("quote expired")
+	ErrInvalidSide      = errors.New("invalid order side")
+	ErrInvalidTTL       = errors.New("invalid time to live")
+	ErrNotRelayer       = errors.New("signing key is not the exchange's")
+	ErrWrongSigner      = errors.New("order not signed by its user")
+	ErrInvalidSignature = errors.New("invalid signature length")
+	ErrInvalidReplyKey  = errors.New("invalid reply key of request")
+)
+
+// OrderPool is the order transaction pool accepted quotes are submitted to.
+type OrderPool interface {
+	AddLocal(tx *types.OrderTransaction) error
+}
+
+// Request asks the relayers of a pair for a quote.
+type Request struct {
+	ID         common.Hash
+	Exchange   common.Address // Relayer asked for a quote, zero for any
+	User       common.Address // Address the requester trades from
+	Nonce      uint64         // Order nonce of the user
+	BaseToken  common.Address
+	QuoteToken common.Address
+	Side       string   // Side the requester takes
+	Quantity   *big.Int // Quantity the requester wants to trade
+	Expiry     uint64   // Unix time the request expires at
+	ReplyTo    []byte   // Public key quotes are encrypted to
+}
+
+// Quote is an order offered by a relayer. If it answers a request, the order is
+// the requester's, ready to be signed and submitted. Otherwise it's an
+// indication of interest, the order the relayer would take the other side of:
+// it has no user, so its UserAddress and Nonce are zero and it can't be
+// accepted. A taker interested in it requests a quote from its exchange.
+type Quote struct {
+	Request common.Hash // Request the quote answers, zero for an indication of interest
+	Order   *types.OrderTransaction
+	Expiry  uint64 // Unix time the quote expires at
+}
+
+// Hash returns the order hash the requester signs to accept the quote.
+func (q *Quote) Hash() common.Hash {
+	return q.Order.OrderHash()
+}
+
+type pair struct {
+	base, quote common.Address
+}
+
+// topic returns the Whisper topic of the pair.
+func (p pair) topic() whisper.TopicType {
+	return whisper.BytesToTopic(crypto.Keccak256([]byte("tomox relay topic"), p.base[:], p.quote[:]))
+}
+
+// key returns the symmetric key requests and indications of interest of the
+// pair are encrypted with.
+func (p pair) key() []byte {
+	return crypto.Keccak256([]byte("tomox relay key"), p.base[:], p.quote[:])
+}
+
+// Relay is the relayer messaging service. It tracks the requests and the
+// indications of interest of the subscribed pairs, and the quotes answering the
+// requests of the local node.
+type Relay struct {
+	shh  *whisper.Whisper
+	pool OrderPool
+
+	key     *ecdsa.PrivateKey // Key quotes to the local node are encrypted to
+	replies string            // Whisper filter of the quotes
+
+	pairs     map[pair]string                   // Whisper filters of the subscribed pairs
+	requests  map[pair]map[common.Hash]*Request // Requests of other nodes, by ID
+	own       map[common.Hash]*Request          // Requests of the local node, by ID
+	quotes    map[common.Hash][]*Quote          // Quotes of the local node's requests, by request ID
+	interests map[pair]map[common.Hash]*Quote   // Indications of interest, by order hash
+	lock      sync.Mutex
+}
+
+// New creates a relayer messaging service on top of the Whisper node, submitting
+// the accepted quotes to the order pool.
+func New(shh *whisper.Whisper, pool OrderPool) (*Relay, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	return &Relay{
+		shh:       shh,
+		pool:      pool,
+		key:       key,
+		pairs:     make(map[pair]string),
+		requests:  make(map[pair]map[common.Hash]*Request),
+		own:       make(map[common.Hash]*Request),
+		quotes:    make(map[common.Hash][]*Quote),
+		interests: make(map[pair]map[common.Hash]*Quote),
+	}, nil
+}
+
+// Protocols implements node.Service, the messages travel over Whisper.
+func (r *Relay) Protocols() []p2p.Protocol { return nil }
+
+// APIs implements node.Service, returning the relay RPC API.
+func (r *Relay) APIs() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: ProtocolName,
+			Version:   ProtocolVersionStr,
+			Service:   NewPublicRelayAPI(r),
+			Public:    true,
+		},
+	}
+}
+
+// Start implements node.Service, listening for the quotes to the local node.
+func (r *Relay) Start(server *p2p.Server) error {
+	id, err := r.shh.Subscribe(&whisper.Filter{KeyAsym: r.key})
+	if err != nil {
+		return err
+	}
+	r.replies = id
+	return nil
+}
+
+// SaveData implements node.Service, the relay keeps no persistent data.
+func (r *Relay) SaveData() {}
+
+// Stop implements node.Service, removing the Whisper filters.
+func (r *Relay) Stop() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, id := range r.pairs {
+		r.shh.Unsubscribe(id)
+	}
+	if r.replies != "" {
+		r.shh.Unsubscribe(r.replies)
+	}
+	return nil
+}
+
+// Subscribe starts tracking the requests and the indications of interest of a
+// pair.
+func (r *Relay) Subscribe(base, quote common.Address) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.subscribe(pair{base, quote})
+}
+
+// subscribe installs the Whisper filter of a pair. The caller must hold the lock.
+func (r *Relay) subscribe(p pair) error {
+	if _, ok := r.pairs[p]; ok {
+		return nil
+	}
+	topic := p.topic()
+	id, err := r.shh.Subscribe(&whisper.Filter{KeySym: p.key(), Topics: [][]byte{topic[:]}})
+	if err != nil {
+		return err
+	}
+	r.pairs[p] = id
+	r.requests[p] = make(map[common.Hash]*Request)
+	r.interests[p] = make(map[common.Hash]*Quote)
+	return nil
+}
+
+// RequestQuote posts a request for quotes to the relayers of its pair, returning
+// its ID.
+func (r *Relay) RequestQuote(req *Request, ttl uint32) (common.Hash, error) {
+	if req.Side != tradingstate.Bid && req.Side != tradingstate.Ask {
+		return common.Hash{}, ErrInvalidSide
+	}
+	if ttl == 0 || ttl > MaxTTL {
+		return common.Hash{}, ErrInvalidTTL
+	}
+	if _, err := rand.Read(req.ID[:]); err != nil {
+		return common.Hash{}, err
+	}
+	req.Expiry = uint64(time.Now().Unix()) + uint64(ttl)
+	req.ReplyTo = crypto.FromECDSAPub(&r.key.PublicKey)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	p := pair{req.BaseToken, req.QuoteToken}
+	if err := r.subscribe(p); err != nil {
+		return common.Hash{}, err
+	}
+	if err := r.post(requestMsg, req, p.topic(), ttl, nil, p.key(), nil); err != nil {
+		return common.Hash{}, err
+	}
+	r.own[req.ID] = req
+	return req.ID, nil
+}
+
+// SendQuote offers an order for a request, signed with the exchange key of the
+// relayer. The order of an indication of interest is posted to its pair.
+func (r *Relay) SendQuote(quote *Quote, key *ecdsa.PrivateKey, ttl uint32) error {
+	if ttl == 0 || ttl > MaxTTL {
+		return ErrInvalidTTL
+	}
+	if crypto.PubkeyToAddress(key.PublicKey) != quote.Order.ExchangeAddress() {
+		return ErrNotRelayer
+	}
+	quote.Expiry = uint64(time.Now().Unix()) + uint64(ttl)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	p := pair{quote.Order.BaseToken(), quote.Order.QuoteToken()}
+	if quote.Request == (common.Hash{}) {
+		return r.post(quoteMsg, quote, p.topic(), ttl, key, p.key(), nil)
+	}
+	r.sync()
+	req, ok := r.requests[p][quote.Request]
+	if !ok {
+		return ErrUnknownRequest
+	}
+	dst := crypto.ToECDSAPub(req.ReplyTo)
+	if dst == nil || dst.X == nil {
+		return ErrInvalidReplyKey
+	}
+	return r.post(quoteMsg, quote, p.topic(), ttl, key, nil, dst)
+}
+
+// Requests returns the pending requests of a subscribed pair.
+func (r *Relay) Requests(base, quote common.Address) []*Request {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.sync()
+	var reqs []*Request
+	for _, req := range r.requests[pair{base, quote}] {
+		reqs = append(reqs, req)
+	}
+	return reqs
+}
+
+// request returns a pending request of another node, nil if unknown.
+func (r *Relay) request(id common.Hash) *Request {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.sync()
+	for _, reqs := range r.requests {
+		if req, ok := reqs[id]; ok {
+			return req
+		}
+	}
+	return nil
+}
+
+// Quotes returns the quotes received for a request of the local node.
+func (r *Relay) Quotes(id common.Hash) ([]*Quote, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.sync()
+	if _, ok := r.own[id]; !ok {
+		return nil, ErrUnknownRequest
+	}
+	return r.quotes[id], nil
+}
+
+// Interests returns the indications of interest posted to a subscribed pair.
+func (r *Relay) Interests(base, quote common.Address) []*Quote {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.sync()
+	var quotes []*Quote
+	for _, q := range r.interests[pair{base, quote}] {
+		quotes = append(quotes, q)
+	}
+	return quotes
+}
+
+// AcceptQuote signs the order of a quote with the requester's signature and
+// submits it to the order pool.
+func (r *Relay) AcceptQuote(hash common.Hash, sig []byte) (*types.OrderTransaction, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.sync()
+	var quote *Quote
+	for _, quotes := range r.quotes {
+		for _, q := range quotes {
+			if q.Hash() == hash {
+				quote = q
+			}
+		}
+	}
+	if quote == nil {
+		return nil, ErrUnknownQuote
+	}
+	if quote.Expiry < uint64(time.Now().Unix()) {
+		return nil, ErrQuoteExpired
+	}
+	if len(sig) != 65 {
+		return nil, ErrInvalidSignature
+	}
+	// Accept signatures with the V of both the Ethereum and the raw format
+	sig = common.CopyBytes(sig)
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+	tx, err := quote.Order.WithSignature(types.OrderTxSigner{}, sig)
+	if err != nil {
+		return nil, err
+	}
+	if from, err := types.OrderSender(types.OrderTxSigner{}, tx); err != nil || from != tx.UserAddress() {
+		return nil, ErrWrongSigner
+	}
+	if err := r.pool.AddLocal(tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// post wraps a message into a Whisper envelope and sends it. The caller must
+// hold the lock.
+func (r *Relay) post(code byte, data interface{}, topic whisper.TopicType, ttl uint32, src *ecdsa.PrivateKey, keySym []byte, dst *ecdsa.PublicKey) error {
+	payload, err := rlp.EncodeToBytes(data)
+	if err != nil {
+		return err
+	}
+	params := &whisper.MessageParams{
+		TTL:      ttl,
+		Src:      src,
+		Dst:      dst,
+		KeySym:   keySym,
+		Topic:    topic,
+		WorkTime: powTime,
+		PoW:      r.shh.MinPow(),
+		Payload:  append([]byte{code}, payload...),
+	}
+	msg, err := whisper.NewSentMessage(params)
+	if err != nil {
+		return err
+	}
+	env, err := msg.Wrap(params)
+	if err != nil {
+		return err
+	}
+	return r.shh.Send(env)
+}
+
+// sync processes the messages received by the Whisper filters and drops the
+// expired requests and quotes. Requests of the local node are dropped with their
+// quotes once they and all their quotes expired. The caller must hold the lock.
+func (r *Relay) sync() {
+	for p, id := range r.pairs {
+		if filter := r.shh.GetFilter(id); filter != nil {
+			for _, msg := range filter.Retrieve() {
+				r.handle(p, msg)
+			}
+		}
+	}
+	if filter := r.shh.GetFilter(r.replies); filter != nil {
+		for _, msg := range filter.Retrieve() {
+			r.handle(pair{}, msg)
+		}
+	}
+	now := uint64(time.Now().Unix())
+	for _, reqs := range r.requests {
+		for id, req := range reqs {
+			if req.Expiry < now {
+				delete(reqs, id)
+			}
+		}
+	}
+	for id, req := range r.own {
+		var quotes []*Quote
+		for _, q := range r.quotes[id] {
+			if q.Expiry >= now {
+				quotes = append(quotes, q)
+			}
+		}
+		switch {
+		case len(quotes) > 0:
+			r.quotes[id] = quotes
+		case req.Expiry < now:
+			delete(r.own, id)
+			delete(r.quotes, id)
+		default:
+			delete(r.quotes, id)
+		}
+	}
+	for _, quotes := range r.interests {
+		for hash, q := range quotes {
+			if q.Expiry < now {
+				delete(quotes, hash)
+			}
+		}
+	}
+}
+
+// capExpiry bounds the expiry time of a received request or quote to the
+// longest lifetime a message can be given.
+func capExpiry(expiry uint64) uint64 {
+	if max := uint64(time.Now().Unix()) + MaxTTL; expiry > max {
+		return max
+	}
+	return expiry
+}
+
+// handle processes a message received on the topic of a pair, or encrypted to
+// the local node if the pair is zero. The number of requests and indications of
+// interest tracked per pair, and of quotes per request, is bounded: once full,
+// new ones are dropped until old ones expire.
+func (r *Relay) handle(p pair, msg *whisper.ReceivedMessage) {
+	if len(msg.Payload) == 0 {
+		return
+	}
+	switch msg.Payload[0] {
+	case requestMsg:
+		var req Request
+		if err := rlp.DecodeBytes(msg.Payload[1:], &req); err != nil {
+			log.Debug("Invalid quote request", "err", err)
+			return
+		}
+		if req.BaseToken != p.base || req.QuoteToken != p.quote {
+			return
+		}
+		reqs := r.requests[p]
+		if _, ok := r.own[req.ID]; ok || reqs == nil {
+			return
+		}
+		if _, ok := reqs[req.ID]; !ok && len(reqs) >= maxPairEntries {
+			log.Debug("Dropping quote request of full pair", "base", p.base, "quote", p.quote)
+			return
+		}
+		req.Expiry = capExpiry(req.Expiry)
+		reqs[req.ID] = &req
+
+	case quoteMsg:
+		var quote Quote
+		if err := rlp.DecodeBytes(msg.Payload[1:], &quote); err != nil {
+			log.Debug("Invalid quote", "err", err)
+			return
+		}
+		// Quotes must be signed by the exchange of the order
+		src := msg.SigToPubKey()
+		if src == nil || crypto.PubkeyToAddress(*src) != quote.Order.ExchangeAddress() {
+			log.Debug("Quote not signed by its exchange", "exchange", quote.Order.ExchangeAddress())
+			return
+		}
+
+		quote.Expiry = capExpiry(quote.Expiry)
+
+		if quote.Request == (common.Hash{}) {
+			interests, ok := r.interests[pair{quote.Order.BaseToken(), quote.Order.QuoteToken()}]
+			if !ok || p == (pair{}) {
+				return
+			}
+			if _, ok := interests[quote.Hash()]; !ok && len(interests) >= maxPairEntries {
+				log.Debug("Dropping indication of interest of full pair", "base", p.base, "quote", p.quote)
+				return
+			}
+			interests[quote.Hash()] = &quote
+			return
+		}
+		if req, ok := r.own[quote.Request]; ok && quote.answers(req) {
+			if len(r.quotes[req.ID]) >= maxRequestQuotes {
+				log.Debug("Dropping quote of full request", "id", req.ID)
+				return
+			}
+			r.quotes[req.ID] = append(r.quotes[req.ID], &quote)
+		}
+	}
+}
+
+// answers checks that a quote is an order the requester can submit: the
+// requested trade on the asked exchange, with the order hash to sign.
+func (q *Quote) answers(req *Request) bool {
+	order := q.Order
+	switch {
+	case req.Exchange != (common.Address{}) && order.ExchangeAddress() != req.Exchange:
+		return false
+	case order.UserAddress() != req.User || order.Nonce() != req.Nonce:
+		return false
+	case order.BaseToken() != req.BaseToken || order.QuoteToken() != req.QuoteToken:
+		return false
+	case order.Side() != req.Side || order.Quantity().Cmp(req.Quantity) != 0:
+		return false
+	case order.Status() != types.OrderStatusNew || order.Type() != types.OrderTypeLo:
+		return false
+	}
+	return order.OrderHash() == types.OrderTxSigner{}.Hash(order)
+}
@@ -0,0 +1,232 @@
+package relay
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/common/hexutil"
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/rlp"
+	"github.com/69th-byte/sdexchain/tomox/tradingstate"
+	whisper "github.com/69th-byte/sdexchain/whisper/whisperv6"
+)
+
+var (
+	testBase  = common.HexToAddress("0x0000000000000000000000000000000000000001")
+	testQuote = common.HexToAddress("0x0000000000000000000000000000000000000002")
+)
+
+// testOrderPool collects the orders submitted by the relay.
+type testOrderPool struct {
+	txs  []*types.OrderTransaction
+	lock sync.Mutex
+}
+
+func (p *testOrderPool) AddLocal(tx *types.OrderTransaction) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.txs = append(p.txs, tx)
+	return nil
+}
+
+// newTestRelays creates a relayer and a client relay on a shared Whisper node.
+func newTestRelays(t *testing.T) (*whisper.Whisper, *PublicRelayAPI, *PublicRelayAPI, *testOrderPool) {
+	shh := whisper.New(&whisper.Config{MaxMessageSize: whisper.DefaultMaxMessageSize, MinimumAcceptedPOW: 0})
+	if err := shh.Start(nil); err != nil {
+		t.Fatalf("failed to start whisper: %v", err)
+	}
+	pool := new(testOrderPool)
+
+	var apis []*PublicRelayAPI
+	for i := 0; i < 2; i++ {
+		r, err := New(shh, pool)
+		if err != nil {
+			t.Fatalf("failed to create relay: %v", err)
+		}
+		if err := r.Start(nil); err != nil {
+			t.Fatalf("failed to start relay: %v", err)
+		}
+		apis = append(apis, NewPublicRelayAPI(r))
+	}
+	return shh, apis[0], apis[1], pool
+}
+
+// waitFor polls a condition until it holds or times out.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		if cond() {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+// signOrder signs the hash of an order the way wallets sign order hashes.
+func signOrder(t *testing.T, hash common.Hash, key *ecdsa.PrivateKey) hexutil.Bytes {
+	sig, err := crypto.Sign(crypto.Keccak256([]byte("\x19Ethereum Signed Message:\n32"), hash.Bytes()), key)
+	if err != nil {
+		t.Fatalf("failed to sign order: %v", err)
+	}
+	sig[64] += 27
+	return sig
+}
+
+// Tests that a quote requested by a user is answered by the relayer, and that
+// the accepted quote is submitted to the order pool as the user's order.
+func TestRequestQuote(t *testing.T) {
+	shh, relayer, client, pool := newTestRelays(t)
+	defer shh.Stop()
+
+	exchangeKey, _ := crypto.GenerateKey()
+	exchange := crypto.PubkeyToAddress(exchangeKey.PublicKey)
+	sig, err := shh.AddKeyPair(exchangeKey)
+	if err != nil {
+		t.Fatalf("failed to add exchange key: %v", err)
+	}
+	userKey, _ := crypto.GenerateKey()
+	user := crypto.PubkeyToAddress(userKey.PublicKey)
+
+	ctx := context.Background()
+	if err := relayer.Subscribe(ctx, testBase, testQuote); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	id, err := client.RequestQuote(ctx, RequestArgs{
+		Exchange:   exchange,
+		User:       user,
+		Nonce:      3,
+		BaseToken:  testBase,
+		QuoteToken: testQuote,
+		Side:       tradingstate.Bid,
+		Quantity:   (*hexutil.Big)(big.NewInt(1000)),
+	})
+	if err != nil {
+		t.Fatalf("failed to request quote: %v", err)
+	}
+	waitFor(t, "request", func() bool { return len(relayer.GetRequests(ctx, testBase, testQuote)) == 1 })
+	if req := relayer.GetRequests(ctx, testBase, testQuote)[0]; req.ID != id || req.User != user {
+		t.Fatalf("request mismatch: have %x from %x, want %x from %x", req.ID, req.User, id, user)
+	}
+
+	hash, err := relayer.SendQuote(ctx, QuoteArgs{Sig: sig, Request: id, Price: (*hexutil.Big)(big.NewInt(50))})
+	if err != nil {
+		t.Fatalf("failed to send quote: %v", err)
+	}
+	var quotes []*RPCQuote
+	waitFor(t, "quote", func() bool {
+		quotes, _ = client.GetQuotes(ctx, id)
+		return len(quotes) == 1
+	})
+	order := quotes[0].Order
+	if order.Hash != hash || order.ExchangeAddress != exchange || order.UserAddress != user || uint64(order.AccountNonce) != 3 {
+		t.Fatalf("quoted order mismatch: %+v", order)
+	}
+
+	// A signature of another key must be rejected
+	otherKey, _ := crypto.GenerateKey()
+	if _, err := client.AcceptQuote(ctx, hash, signOrder(t, hash, otherKey)); err != ErrWrongSigner {
+		t.Fatalf("foreign signature error mismatch: have %v, want %v", err, ErrWrongSigner)
+	}
+	txHash, err := client.AcceptQuote(ctx, hash, signOrder(t, hash, userKey))
+	if err != nil {
+		t.Fatalf("failed to accept quote: %v", err)
+	}
+	if len(pool.txs) != 1 || pool.txs[0].Hash() != txHash {
+		t.Fatalf("order not submitted: %v", pool.txs)
+	}
+	if from, err := types.OrderSender(types.OrderTxSigner{}, pool.txs[0]); err != nil || from != user {
+		t.Fatalf("order sender mismatch: have %x (%v), want %x", from, err, user)
+	}
+}
+
+// Tests that indications of interest reach the subscribers of the pair, and that
+// quotes can only be signed with the exchange key of their order.
+func TestIndicationOfInterest(t *testing.T) {
+	shh, relayer, client, _ := newTestRelays(t)
+	defer shh.Stop()
+
+	exchangeKey, _ := crypto.GenerateKey()
+	sig, _ := shh.AddKeyPair(exchangeKey)
+
+	ctx := context.Background()
+	if err := client.Subscribe(ctx, testBase, testQuote); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	hash, err := relayer.SendQuote(ctx, QuoteArgs{
+		Sig:        sig,
+		BaseToken:  testBase,
+		QuoteToken: testQuote,
+		Side:       tradingstate.Ask,
+		Quantity:   (*hexutil.Big)(big.NewInt(500)),
+		Price:      (*hexutil.Big)(big.NewInt(60)),
+	})
+	if err != nil {
+		t.Fatalf("failed to send indication of interest: %v", err)
+	}
+	waitFor(t, "indication of interest", func() bool { return len(client.GetInterests(ctx, testBase, testQuote)) == 1 })
+	if have := client.GetInterests(ctx, testBase, testQuote)[0].Order.Hash; have != hash {
+		t.Fatalf("indication of interest mismatch: have %x, want %x", have, hash)
+	}
+
+	otherKey, _ := crypto.GenerateKey()
+	order := types.NewOrderTransaction(0, big.NewInt(500), big.NewInt(60), crypto.PubkeyToAddress(exchangeKey.PublicKey), common.Address{}, testBase, testQuote, types.OrderStatusNew, tradingstate.Ask, types.OrderTypeLo, common.Hash{}, 0)
+	if err := relayer.r.SendQuote(&Quote{Order: order}, otherKey, DefaultTTL); err != ErrNotRelayer {
+		t.Fatalf("foreign key error mismatch: have %v, want %v", err, ErrNotRelayer)
+	}
+}
+
+// Tests that received requests can't outlive the longest lifetime nor overfill
+// their pair, and that expired requests of the local node are dropped along with
+// their quotes.
+func TestRelayBounds(t *testing.T) {
+	shh, relayer, _, _ := newTestRelays(t)
+	defer shh.Stop()
+
+	r := relayer.r
+	if err := r.Subscribe(testBase, testQuote); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	p := pair{testBase, testQuote}
+	now := uint64(time.Now().Unix())
+	for i := 0; i < maxPairEntries+10; i++ {
+		req := &Request{BaseToken: testBase, QuoteToken: testQuote, Side: tradingstate.Bid, Quantity: big.NewInt(1), Expiry: now + 10*MaxTTL}
+		req.ID[0], req.ID[1] = byte(i), byte(i>>8)
+		payload, _ := rlp.EncodeToBytes(req)
+		r.handle(p, &whisper.ReceivedMessage{Payload: append([]byte{requestMsg}, payload...)})
+	}
+	reqs := r.Requests(testBase, testQuote)
+	if len(reqs) != maxPairEntries {
+		t.Fatalf("request count mismatch: have %d, want %d", len(reqs), maxPairEntries)
+	}
+	for _, req := range reqs {
+		if req.Expiry > uint64(time.Now().Unix())+MaxTTL {
+			t.Fatalf("request expiry not capped: %d", req.Expiry)
+		}
+	}
+
+	// A request of the local node is kept while one of its quotes is valid
+	expired := &Request{ID: common.Hash{0xff}, Expiry: now - 1}
+	r.lock.Lock()
+	r.own[expired.ID] = expired
+	r.quotes[expired.ID] = []*Quote{{Request: expired.ID, Expiry: now - 1}, {Request: expired.ID, Expiry: now + 10}}
+	r.lock.Unlock()
+	if quotes, err := r.Quotes(expired.ID); err != nil || len(quotes) != 1 {
+		t.Fatalf("quotes mismatch: have %d (%v), want 1", len(quotes), err)
+	}
+	r.lock.Lock()
+	r.quotes[expired.ID][0].Expiry = now - 1
+	r.lock.Unlock()
+	if _, err := r.Quotes(expired.ID); err != ErrUnknownRequest {
+		t.Fatalf("expired request error mismatch: have %v, want %v", err, ErrUnknownRequest)
+	}
+	if len(r.own) != 0 || len(r.quotes) != 0 {
+		t.Fatalf("expired request not dropped: %d requests, %d quoted", len(r.own), len(r.quotes))
+	}
+}
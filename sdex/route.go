@@ -0,0 +1,347 @@
+package tomox
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/consensus"
+	"github.com/69th-byte/sdexchain/core/state"
+	"github.com/69th-byte/sdexchain/log"
+	"github.com/69th-byte/sdexchain/tomox/tradingstate"
+)
+
+var (
+	ErrNoRouteFound   = errors.New("no route found")
+	ErrInvalidDecimal = errors.New("invalid token decimal")
+)
+
+// RouteHop is one swap of a route through an order book
+type RouteHop struct {
+	BaseToken  common.Address `json:"baseToken"`
+	QuoteToken common.Address `json:"quoteToken"`
+	Side       string         `json:"side"`
+	AmountIn   *big.Int       `json:"amountIn"`
+	AmountOut  *big.Int       `json:"amountOut"`
+}
+
+// RouteQuote is the result of simulating a route against the current order books
+type RouteQuote struct {
+	Route         []common.Address `json:"route"`
+	Hops          []RouteHop       `json:"hops"`
+	AmountIn      *big.Int         `json:"amountIn"`
+	AmountOut     *big.Int         `json:"amountOut"`
+	SpotAmountOut *big.Int         `json:"spotAmountOut"`
+	PriceImpact   float64          `json:"priceImpact"`
+	Filled        bool             `json:"filled"`
+}
+
+// routePairs is the set of listed pairs, keyed by order book hash
+type routePairs struct {
+	pairs map[common.Hash]bool
+	// neighbours of each token, in both directions
+	neighbours map[common.Address][]common.Address
+}
+
+func newRoutePairs() *routePairs {
+	return &routePairs{
+		pairs:      map[common.Hash]bool{},
+		neighbours: map[common.Address][]common.Address{},
+	}
+}
+
+func (r *routePairs) add(baseToken, quoteToken common.Address) {
+	orderBook := tradingstate.GetTradingOrderBookHash(baseToken, quoteToken)
+	if r.pairs[orderBook] {
+		return
+	}
+	r.pairs[orderBook] = true
+	r.neighbours[baseToken] = append(r.neighbours[baseToken], quoteToken)
+	r.neighbours[quoteToken] = append(r.neighbours[quoteToken], baseToken)
+}
+
+func (r *routePairs) listed(baseToken, quoteToken common.Address) bool {
+	return r.pairs[tradingstate.GetTradingOrderBookHash(baseToken, quoteToken)]
+}
+
+// getRoutePairs returns pairs listed by the exchange, or by all relayers if exchange is empty
+func getRoutePairs(statedb *state.StateDB, exchange common.Address) *routePairs {
+	relayers := []common.Address{exchange}
+	if exchange == (common.Address{}) {
+		relayers = tradingstate.GetAllCoinbases(statedb)
+	}
+	pairs := newRoutePairs()
+	for _, relayer := range relayers {
+		length := tradingstate.GetBaseTokenLength(relayer, statedb)
+		if length != tradingstate.GetQuoteTokenLength(relayer, statedb) {
+			continue
+		}
+		for i := uint64(0); i < length; i++ {
+			pairs.add(tradingstate.GetBaseTokenAtIndex(relayer, statedb, i), tradingstate.GetQuoteTokenAtIndex(relayer, statedb, i))
+		}
+	}
+	return pairs
+}
+
+// getRouteHop returns the pair and the taker side to swap token from to token to
+func getRouteHop(listed func(baseToken, quoteToken common.Address) bool, from, to common.Address) (common.Address, common.Address, string, bool) {
+	if listed(from, to) {
+		return from, to, tradingstate.Ask, true
+	}
+	if listed(to, from) {
+		return to, from, tradingstate.Bid, true
+	}
+	return common.Address{}, common.Address{}, "", false
+}
+
+// processRoutedOrder matches a routed market order hop by hop, the output of each hop is spent by the next one.
+// The order is rejected and all hops are reverted if any hop can't be fully filled
+// or the final output is less than the order price
+func (tomox *TomoX) processRoutedOrder(coinbase common.Address, chain consensus.ChainContext, statedb *state.StateDB, tradingStateDB *tradingstate.TradingStateDB, order *tradingstate.OrderItem) ([]map[string]string, []*tradingstate.OrderItem, error) {
+	var (
+		trades  []map[string]string
+		rejects []*tradingstate.OrderItem
+	)
+	tomoxSnap := tradingStateDB.Snapshot()
+	dbSnap := statedb.Snapshot()
+	reject := func(reason string) ([]map[string]string, []*tradingstate.OrderItem, error) {
+		log.Debug("Reject routed order", "reason", reason, "hash", order.Hash.Hex())
+		tradingStateDB.RevertToSnapshot(tomoxSnap)
+		statedb.RevertToSnapshot(dbSnap)
+		order.SetRejectReason(reason)
+		return []map[string]string{}, []*tradingstate.OrderItem{order}, nil
+	}
+	listed := func(baseToken, quoteToken common.Address) bool {
+		return tradingstate.VerifyPair(statedb, order.ExchangeAddress, baseToken, quoteToken) == nil
+	}
+	takerFeeRate := tradingstate.GetExRelayerFee(order.ExchangeAddress, statedb)
+	amountIn := tradingstate.CloneBigInt(order.Quantity)
+	for i := 0; i+1 < len(order.Route); i++ {
+		from, to := order.Route[i], order.Route[i+1]
+		baseToken, quoteToken, side, ok := getRouteHop(listed, from, to)
+		if !ok {
+			return reject(tradingstate.RejectReasonInvalidRoute)
+		}
+		orderBook := tradingstate.GetTradingOrderBookHash(baseToken, quoteToken)
+		quantity := amountIn
+		if side == tradingstate.Bid {
+			// spend amountIn of quote token: buy as much base token as the asks allow
+			baseTokenDecimal, err := tomox.GetTokenDecimal(chain, statedb, baseToken)
+			if err != nil || baseTokenDecimal.Sign() == 0 {
+				return nil, nil, fmt.Errorf("Fail to get tokenDecimal. Token: %v . Err: %v", baseToken.String(), err)
+			}
+			asks, err := tradingStateDB.GetAsks(orderBook)
+			if err != nil {
+				return reject(tradingstate.RejectReasonNoLiquidity)
+			}
+			quantity, _ = tradingstate.GetBuyQuote(tradingstate.SortPriceLevels(asks, tradingstate.Ask), amountIn, takerFeeRate, baseTokenDecimal)
+		}
+		if quantity.Sign() == 0 {
+			return reject(tradingstate.RejectReasonNoLiquidity)
+		}
+		hopOrder := &tradingstate.OrderItem{
+			Quantity:        quantity,
+			Price:           new(big.Int),
+			ExchangeAddress: order.ExchangeAddress,
+			UserAddress:     order.UserAddress,
+			BaseToken:       baseToken,
+			QuoteToken:      quoteToken,
+			Status:          order.Status,
+			Side:            side,
+			Type:            tradingstate.Market,
+			Hash:            order.Hash,
+			Signature:       order.Signature,
+			Nonce:           order.Nonce,
+		}
+		balanceBefore := tradingstate.GetTokenBalance(order.UserAddress, to, statedb)
+		hopTrades, hopRejects, err := tomox.processMarketOrder(coinbase, chain, statedb, tradingStateDB, orderBook, hopOrder)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, rejected := range hopRejects {
+			if rejected == hopOrder {
+				return reject(rejected.RejectReason)
+			}
+		}
+		filled := new(big.Int)
+		for _, trade := range hopTrades {
+			tradedQuantity, ok := new(big.Int).SetString(trade[tradingstate.TradeQuantity], 10)
+			if ok {
+				filled = new(big.Int).Add(filled, tradedQuantity)
+			}
+		}
+		if filled.Cmp(quantity) != 0 {
+			return reject(tradingstate.RejectReasonNoLiquidity)
+		}
+		amountOut := new(big.Int).Sub(tradingstate.GetTokenBalance(order.UserAddress, to, statedb), balanceBefore)
+		if amountOut.Sign() <= 0 {
+			return reject(tradingstate.RejectReasonNoLiquidity)
+		}
+		log.Debug("Routed order hop", "from", from.Hex(), "to", to.Hex(), "side", side, "amountIn", amountIn, "amountOut", amountOut)
+		trades = append(trades, hopTrades...)
+		rejects = append(rejects, hopRejects...)
+		amountIn = amountOut
+	}
+	// price of a routed order is the minimum amount of the last token of the route
+	if order.Price != nil && order.Price.Sign() > 0 && amountIn.Cmp(order.Price) < 0 {
+		return reject(tradingstate.RejectReasonRouteSlippage)
+	}
+	return trades, rejects, nil
+}
+
+// GetRouteQuote simulates swapping amountIn of token from to token to through at most tradingstate.MaxRouteHops
+// order books listed by the exchange (or by any relayer if exchange is empty), and returns the route with the best output
+func (tomox *TomoX) GetRouteQuote(chain consensus.ChainContext, statedb *state.StateDB, tradingStateDB *tradingstate.TradingStateDB, exchange, from, to common.Address, amountIn *big.Int) (*RouteQuote, error) {
+	if from == to {
+		return nil, tradingstate.ErrInvalidRoute
+	}
+	if amountIn == nil || amountIn.Sign() <= 0 {
+		return nil, tradingstate.ErrInvalidQuantity
+	}
+	takerFeeRate := new(big.Int)
+	if exchange != (common.Address{}) {
+		takerFeeRate = tradingstate.GetExRelayerFee(exchange, statedb)
+	}
+	pairs := getRoutePairs(statedb, exchange)
+
+	var best *RouteQuote
+	for _, route := range findRoutes(pairs, from, to, tradingstate.MaxRouteHops) {
+		quote, err := tomox.quoteRoute(chain, statedb, tradingStateDB, pairs, route, amountIn, takerFeeRate)
+		if err != nil {
+			log.Debug("Failed to quote route", "route", route, "err", err)
+			continue
+		}
+		if best == nil || (quote.Filled && !best.Filled) || (quote.Filled == best.Filled && quote.AmountOut.Cmp(best.AmountOut) > 0) {
+			best = quote
+		}
+	}
+	if best == nil || best.AmountOut.Sign() == 0 {
+		return nil, ErrNoRouteFound
+	}
+	return best, nil
+}
+
+// findRoutes returns all simple token paths from token from to token to with at most maxHops pairs
+func findRoutes(pairs *routePairs, from, to common.Address, maxHops int) [][]common.Address {
+	var (
+		routes  [][]common.Address
+		visited = map[common.Address]bool{from: true}
+		walk    func(path []common.Address)
+	)
+	walk = func(path []common.Address) {
+		last := path[len(path)-1]
+		if last == to {
+			routes = append(routes, append([]common.Address{}, path...))
+			return
+		}
+		if len(path) > maxHops {
+			return
+		}
+		for _, next := range pairs.neighbours[last] {
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+			walk(append(path, next))
+			visited[next] = false
+		}
+	}
+	walk([]common.Address{from})
+	return routes
+}
+
+// quoteRoute walks the order books of the route with amountIn of its first token
+func (tomox *TomoX) quoteRoute(chain consensus.ChainContext, statedb *state.StateDB, tradingStateDB *tradingstate.TradingStateDB, pairs *routePairs, route []common.Address, amountIn, takerFeeRate *big.Int) (*RouteQuote, error) {
+	quote := &RouteQuote{
+		Route:         route,
+		AmountIn:      amountIn,
+		SpotAmountOut: amountIn,
+		Filled:        true,
+	}
+	hopIn := amountIn
+	for i := 0; i+1 < len(route); i++ {
+		baseToken, quoteToken, side, ok := getRouteHop(pairs.listed, route[i], route[i+1])
+		if !ok {
+			return nil, tradingstate.ErrInvalidRoute
+		}
+		baseTokenDecimal, err := tomox.GetTokenDecimal(chain, statedb, baseToken)
+		if err != nil {
+			return nil, err
+		}
+		if baseTokenDecimal.Sign() == 0 {
+			return nil, ErrInvalidDecimal
+		}
+		orderBook := tradingstate.GetTradingOrderBookHash(baseToken, quoteToken)
+		var (
+			levels    []tradingstate.PriceLevel
+			hopOut    *big.Int
+			hopFilled *big.Int
+		)
+		if side == tradingstate.Ask {
+			bids, err := tradingStateDB.GetBids(orderBook)
+			if err == nil {
+				levels = tradingstate.SortPriceLevels(bids, tradingstate.Bid)
+			}
+			hopOut, hopFilled = tradingstate.GetSellQuote(levels, hopIn, takerFeeRate, baseTokenDecimal)
+		} else {
+			asks, err := tradingStateDB.GetAsks(orderBook)
+			if err == nil {
+				levels = tradingstate.SortPriceLevels(asks, tradingstate.Ask)
+			}
+			hopOut, _ = tradingstate.GetBuyQuote(levels, hopIn, takerFeeRate, baseTokenDecimal)
+			// the whole amount is spent if the asks have volume left after the bought quantity
+			hopFilled = hopIn
+			if hopOut.Cmp(totalVolume(levels)) >= 0 {
+				hopFilled = new(big.Int)
+			}
+		}
+		if hopFilled.Cmp(hopIn) < 0 {
+			quote.Filled = false
+		}
+		if len(levels) > 0 {
+			quote.SpotAmountOut = spotAmountOut(side, levels[0].Price, quote.SpotAmountOut, takerFeeRate, baseTokenDecimal)
+		} else {
+			quote.SpotAmountOut = new(big.Int)
+		}
+		quote.Hops = append(quote.Hops, RouteHop{
+			BaseToken:  baseToken,
+			QuoteToken: quoteToken,
+			Side:       side,
+			AmountIn:   hopIn,
+			AmountOut:  hopOut,
+		})
+		hopIn = hopOut
+	}
+	quote.AmountOut = hopIn
+	if quote.SpotAmountOut.Sign() > 0 {
+		// priceImpact = (spotAmountOut - amountOut) * 100 / spotAmountOut
+		impact := new(big.Float).SetInt(new(big.Int).Sub(quote.SpotAmountOut, quote.AmountOut))
+		impact = impact.Mul(impact, big.NewFloat(100))
+		impact = impact.Quo(impact, new(big.Float).SetInt(quote.SpotAmountOut))
+		quote.PriceImpact, _ = impact.Float64()
+	}
+	return quote, nil
+}
+
+// spotAmountOut returns the output of a swap at the best price of the order book, as if it had unlimited volume
+func spotAmountOut(side string, bestPrice, amountIn, takerFeeRate, baseTokenDecimal *big.Int) *big.Int {
+	if side == tradingstate.Ask {
+		// amountOut = amountIn * bestPrice * (baseFee - takerFeeRate) / (baseTokenDecimal * baseFee)
+		amountOut := new(big.Int).Mul(amountIn, bestPrice)
+		amountOut = new(big.Int).Mul(amountOut, new(big.Int).Sub(common.TomoXBaseFee, takerFeeRate))
+		return new(big.Int).Div(amountOut, new(big.Int).Mul(baseTokenDecimal, common.TomoXBaseFee))
+	}
+	// amountOut = amountIn * baseTokenDecimal * baseFee / (bestPrice * (baseFee + takerFeeRate))
+	amountOut := new(big.Int).Mul(amountIn, baseTokenDecimal)
+	amountOut = new(big.Int).Mul(amountOut, common.TomoXBaseFee)
+	return new(big.Int).Div(amountOut, new(big.Int).Mul(bestPrice, new(big.Int).Add(common.TomoXBaseFee, takerFeeRate)))
+}
+
+func totalVolume(levels []tradingstate.PriceLevel) *big.Int {
+	total := new(big.Int)
+	for _, level := range levels {
+		total = new(big.Int).Add(total, level.Volume)
+	}
+	return total
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/69th-byte/sdexchain/common"
 	"github.com/69th-byte/sdexchain/core/state"
 	"github.com/69th-byte/sdexchain/log"
+	"github.com/69th-byte/sdexchain/metrics"
 	"github.com/69th-byte/sdexchain/rpc"
 	lru "github.com/hashicorp/golang-lru"
 	"golang.org/x/sync/syncmap"
@@ -36,6 +37,9 @@ var (
 	ErrNonceTooLow  = errors.New("nonce too low")
 )
 
+// matchingTimer measures the time spent matching the pending orders of a block.
+var matchingTimer = metrics.NewRegisteredTimer("tomox/matching", nil)
+
 type Config struct {
 	DataDir        string `toml:",omitempty"`
 	DBEngine       string `toml:",omitempty"`
@@ -62,6 +66,10 @@ type TomoX struct {
 	settings          syncmap.Map // holds configuration settings that can be dynamically changed
 	tokenDecimalCache *lru.Cache
 	orderCache        *lru.Cache
+
+	// openState opens the trading states of light clients, which keep no
+	// trading database of their own
+	openState func(block *types.Block, author common.Address) (*tradingstate.TradingStateDB, error)
 }
 
 func (tomox *TomoX) Protocols() []p2p.Protocol {
@@ -119,6 +127,23 @@ func New(cfg *Config) *TomoX {
 	return tomoX
 }
 
+// NewLight creates a TomoX instance for light clients. It keeps no trading
+// database, the trading states are opened by openState instead.
+func NewLight(openState func(block *types.Block, author common.Address) (*tradingstate.TradingStateDB, error)) *TomoX {
+	tokenDecimalCache, _ := lru.New(defaultCacheLimit)
+	orderCache, _ := lru.New(tradingstate.OrderCacheLimit)
+	tomoX := &TomoX{
+		orderNonce:        make(map[common.Address]*big.Int),
+		Triegc:            prque.New(),
+		tokenDecimalCache: tokenDecimalCache,
+		orderCache:        orderCache,
+		openState:         openState,
+	}
+	tomoX.settings.Store(overflowIdx, false)
+
+	return tomoX
+}
+
 // Overflow returns an indication if the message queue is full.
 func (tomox *TomoX) Overflow() bool {
 	val, _ := tomox.settings.Load(overflowIdx)
@@ -154,11 +179,43 @@ func (tomox *TomoX) Version() uint64 {
 	return ProtocolVersion
 }
 
+// NewOrderItem converts a signed order transaction into the order item the
+// matching engine works on.
+func NewOrderItem(tx *types.OrderTransaction) (*tradingstate.OrderItem, error) {
+	V, R, S := tx.Signature()
+	v, err := strconv.ParseInt(V.String(), 10, 8)
+	if err != nil {
+		return nil, err
+	}
+	return &tradingstate.OrderItem{
+		Nonce:           big.NewInt(int64(tx.Nonce())),
+		Quantity:        tx.Quantity(),
+		Price:           tx.Price(),
+		ExchangeAddress: tx.ExchangeAddress(),
+		UserAddress:     tx.UserAddress(),
+		BaseToken:       tx.BaseToken(),
+		QuoteToken:      tx.QuoteToken(),
+		Status:          tx.Status(),
+		Side:            tx.Side(),
+		Type:            tx.Type(),
+		Hash:            tx.OrderHash(),
+		OrderID:         tx.OrderID(),
+		Route:           tx.Route(),
+		Signature: &tradingstate.Signature{
+			V: byte(v),
+			R: common.BigToHash(R),
+			S: common.BigToHash(S),
+		},
+	}, nil
+}
+
 func (tomox *TomoX) ProcessOrderPending(header *types.Header, coinbase common.Address, chain consensus.ChainContext, pending map[common.Address]types.OrderTransactions, statedb *state.StateDB, tomoXstatedb *tradingstate.TradingStateDB) ([]tradingstate.TxDataMatch, map[common.Hash]tradingstate.MatchingResult) {
+	defer matchingTimer.UpdateSince(time.Now())
+
 	txMatches := []tradingstate.TxDataMatch{}
 	matchingResults := map[common.Hash]tradingstate.MatchingResult{}
 
-	txs := types.NewOrderTransactionByNonce(types.OrderTxSigner{}, pending)
+	txs := types.NewOrderTransactionByNonce(types.MakeOrderSigner(chain.Config(), header.Number), pending)
 	numberTx := 0
 	for {
 		tx := txs.Peek()
@@ -171,33 +228,10 @@ func (tomox *TomoX) ProcessOrderPending(header *types.Header, coinbase common.Ad
 		numberTx++
 		log.Debug("ProcessOrderPending start", "len", len(pending))
 		log.Debug("Get pending orders to process", "address", tx.UserAddress(), "nonce", tx.Nonce())
-		V, R, S := tx.Signature()
-
-		bigstr := V.String()
-		n, e := strconv.ParseInt(bigstr, 10, 8)
-		if e != nil {
+		order, err := NewOrderItem(tx)
+		if err != nil {
 			continue
 		}
-
-		order := &tradingstate.OrderItem{
-			Nonce:           big.NewInt(int64(tx.Nonce())),
-			Quantity:        tx.Quantity(),
-			Price:           tx.Price(),
-			ExchangeAddress: tx.ExchangeAddress(),
-			UserAddress:     tx.UserAddress(),
-			BaseToken:       tx.BaseToken(),
-			QuoteToken:      tx.QuoteToken(),
-			Status:          tx.Status(),
-			Side:            tx.Side(),
-			Type:            tx.Type(),
-			Hash:            tx.OrderHash(),
-			OrderID:         tx.OrderID(),
-			Signature: &tradingstate.Signature{
-				V: byte(n),
-				R: common.BigToHash(R),
-				S: common.BigToHash(S),
-			},
-		}
 		cancel := false
 		if order.Status == tradingstate.OrderStatusCancelled {
 			cancel = true
@@ -446,6 +480,7 @@ func (tomox *TomoX) SyncDataToSDKNode(takerOrderInTx *tradingstate.OrderItem, tx
 			updatedTakerOrder.Status = tradingstate.OrderStatusFilled
 		} else {
 			updatedTakerOrder.Status = tradingstate.OrderStatusRejected
+			updatedTakerOrder.SetRejectReason(tradingstate.RejectReasonNoLiquidity)
 		}
 	}
 	log.Debug("PutObject processed takerOrder",
@@ -494,9 +529,11 @@ func (tomox *TomoX) SyncDataToSDKNode(takerOrderInTx *tradingstate.OrderItem, tx
 
 	if len(rejectedOrders) > 0 {
 		var rejectedHashes []string
+		rejectReasons := make(map[string]string)
 		// updateRejectedOrders
 		for _, rejectedOrder := range rejectedOrders {
 			rejectedHashes = append(rejectedHashes, rejectedOrder.Hash.Hex())
+			rejectReasons[rejectedOrder.Hash.Hex()] = rejectedOrder.RejectReason
 			if updatedTakerOrder.Hash == rejectedOrder.Hash && !txMatchTime.Before(updatedTakerOrder.UpdatedAt) {
 				// cache order history for handling reorg
 				orderHistoryRecord := tradingstate.OrderHistoryItem{
@@ -513,6 +550,7 @@ func (tomox *TomoX) SyncDataToSDKNode(takerOrderInTx *tradingstate.OrderItem, tx
 				} else {
 					updatedTakerOrder.Status = tradingstate.OrderStatusRejected
 				}
+				updatedTakerOrder.RejectReason = rejectedOrder.RejectReason
 				updatedTakerOrder.TxHash = txHash
 				updatedTakerOrder.UpdatedAt = txMatchTime
 				if err := db.PutObject(updatedTakerOrder.Hash, updatedTakerOrder); err != nil {
@@ -547,6 +585,7 @@ func (tomox *TomoX) SyncDataToSDKNode(takerOrderInTx *tradingstate.OrderItem, tx
 				} else {
 					order.Status = tradingstate.OrderStatusRejected
 				}
+				order.RejectReason = rejectReasons[order.Hash.Hex()]
 				order.TxHash = txHash
 				order.UpdatedAt = txMatchTime
 				if err = db.PutObject(order.Hash, order); err != nil {
@@ -563,6 +602,9 @@ func (tomox *TomoX) SyncDataToSDKNode(takerOrderInTx *tradingstate.OrderItem, tx
 }
 
 func (tomox *TomoX) GetTradingState(block *types.Block, author common.Address) (*tradingstate.TradingStateDB, error) {
+	if tomox.openState != nil {
+		return tomox.openState(block, author)
+	}
 	root, err := tomox.GetTradingStateRoot(block, author)
 	if err != nil {
 		return nil, err
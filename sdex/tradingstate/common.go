@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/69th-byte/sdexchain/crypto"
 	"math/big"
+	"reflect"
 	"time"
 
 	"github.com/69th-byte/sdexchain/common"
@@ -12,6 +13,7 @@ import (
 
 const (
 	OrderCacheLimit = 10000
+	MaxRouteHops    = 3
 )
 
 var (
@@ -22,6 +24,9 @@ var (
 	Limit     = "LO"
 	Cancel    = "CANCELLED"
 	OrderNew  = "NEW"
+
+	// RoutedMarket is a market order executed through up to MaxRouteHops pairs
+	RoutedMarket = "RMO"
 )
 
 var EmptyHash = common.Hash{}
@@ -41,6 +46,11 @@ var EmptyOrder = OrderItem{
 	Quantity: Zero,
 }
 
+// IsEmptyOrder reports whether the order is EmptyOrder, which is returned by GetOrder if the order is not found
+func IsEmptyOrder(order OrderItem) bool {
+	return reflect.DeepEqual(order, EmptyOrder)
+}
+
 var (
 	ErrInvalidSignature = errors.New("verify order: invalid signature")
 	ErrInvalidPrice     = errors.New("verify order: invalid price")
@@ -49,11 +59,14 @@ var (
 	ErrInvalidOrderType = errors.New("verify order: unsupported order type")
 	ErrInvalidOrderSide = errors.New("verify order: invalid order side")
 	ErrInvalidStatus    = errors.New("verify order: invalid status")
+	ErrInvalidRoute     = errors.New("verify order: invalid route")
+	ErrInvalidPair      = errors.New("verify order: invalid pair")
 
 	// supported order types
 	MatchingOrderType = map[string]bool{
-		Market: true,
-		Limit:  true,
+		Market:       true,
+		Limit:        true,
+		RoutedMarket: true,
 	}
 )
 
@@ -77,6 +90,8 @@ type tradingExchangeObject struct {
 	BidRoot                common.Hash // merkle root of the storage trie
 	OrderRoot              common.Hash
 	LiquidationPriceRoot   common.Hash
+	// PriceOracle holds at most one item, it's a tail list to keep the encoding of pairs without oracle unchanged
+	PriceOracle []PriceOracle `rlp:"tail"`
 }
 
 var (
@@ -1,6 +1,9 @@
 package tradingstate
 
 import (
+	"bytes"
+	"errors"
+	"math/big"
 	"reflect"
 	"testing"
 )
@@ -41,3 +44,57 @@ func TestTxMatchesBatch(t *testing.T) {
 		t.Error("txMatchesBatch is different from originalTxMatchesBatch", "txMatchesBatch", txMatchesBatch, "originalTxMatchesBatch", originalTxMatchesBatch)
 	}
 }
+
+// RejectReason must neither change the order trie nor the payload of matching txs
+func TestRejectReasonNotEncoded(t *testing.T) {
+	order := &OrderItem{
+		Quantity:  big.NewInt(100),
+		Price:     big.NewInt(5),
+		Status:    OrderStatusNew,
+		Nonce:     big.NewInt(1),
+		Signature: &Signature{},
+	}
+	encoded, err := EncodeBytesItem(order)
+	if err != nil {
+		t.Fatal("Failed to encode", err)
+	}
+	order.SetRejectReason(RejectReasonInsufficientBalance)
+	order.SetRejectReason(RejectReasonMatchingFailed)
+	if order.RejectReason != RejectReasonInsufficientBalance {
+		t.Errorf("RejectReason overridden. Got: %s. Expected: %s", order.RejectReason, RejectReasonInsufficientBalance)
+	}
+	encodedWithReason, err := EncodeBytesItem(order)
+	if err != nil {
+		t.Fatal("Failed to encode", err)
+	}
+	if !bytes.Equal(encoded, encodedWithReason) {
+		t.Error("RejectReason changes rlp encoding of order")
+	}
+	decoded := &OrderItem{}
+	if err := DecodeBytesItem(encodedWithReason, decoded); err != nil {
+		t.Fatal("Failed to decode", err)
+	}
+	if decoded.RejectReason != "" {
+		t.Error("RejectReason should not be decoded from rlp", "rejectReason", decoded.RejectReason)
+	}
+}
+
+func TestGetRejectReason(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{ErrInvalidSignature, RejectReasonInvalidSignature},
+		{ErrInvalidRelayer, RejectReasonInvalidRelayer},
+		{ErrInvalidPrice, RejectReasonInvalidPrice},
+		{ErrInvalidQuantity, RejectReasonInvalidQuantity},
+		{ErrInvalidOrderSide, RejectReasonInvalidOrder},
+		{ErrInvalidPair, RejectReasonInvalidPair},
+		{errors.New("unexpected failure"), RejectReasonInvalidOrder},
+	}
+	for _, tt := range tests {
+		if got := GetRejectReason(tt.err); got != tt.want {
+			t.Errorf("GetRejectReason(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -62,6 +62,10 @@ type (
 		hash      common.Hash
 		prevPrice *big.Int
 	}
+	priceOracleChange struct {
+		hash common.Hash
+		prev []PriceOracle
+	}
 	insertLiquidationPrice struct {
 		orderBook   common.Hash
 		price       *big.Int
@@ -118,3 +122,8 @@ func (ch mediumPriceChange) undo(s *TradingStateDB) {
 func (ch mediumPriceBeforeEpochChange) undo(s *TradingStateDB) {
 	s.SetMediumPriceBeforeEpoch(ch.hash, ch.prevPrice)
 }
+func (ch priceOracleChange) undo(s *TradingStateDB) {
+	if stateObject := s.getStateExchangeObject(ch.hash); stateObject != nil {
+		stateObject.setPriceOracle(ch.prev)
+	}
+}
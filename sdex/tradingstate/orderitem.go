@@ -23,6 +23,25 @@ const (
 	OrderStatusRejected      = "REJECTED"
 )
 
+// reasons why an order ends up in the rejected list of ApplyOrder
+const (
+	RejectReasonInvalidOrder        = "INVALID_ORDER"
+	RejectReasonInvalidSignature    = "INVALID_SIGNATURE"
+	RejectReasonInvalidRelayer      = "INVALID_RELAYER"
+	RejectReasonInvalidPair         = "INVALID_PAIR"
+	RejectReasonInvalidPrice        = "INVALID_PRICE"
+	RejectReasonInvalidQuantity     = "INVALID_QUANTITY"
+	RejectReasonInsufficientBalance = "INSUFFICIENT_BALANCE"
+	RejectReasonRelayerFee          = "INSUFFICIENT_RELAYER_FEE"
+	RejectReasonQuantityTooSmall    = "QUANTITY_TOO_SMALL"
+	RejectReasonOrderNotFound       = "ORDER_NOT_FOUND"
+	RejectReasonCancelFailed        = "CANCEL_FAILED"
+	RejectReasonMatchingFailed      = "MATCHING_FAILED"
+	RejectReasonNoLiquidity         = "NO_LIQUIDITY"
+	RejectReasonInvalidRoute        = "INVALID_ROUTE"
+	RejectReasonRouteSlippage       = "ROUTE_SLIPPAGE"
+)
+
 // OrderItem : info that will be store in database
 type OrderItem struct {
 	Quantity        *big.Int       `json:"quantity,omitempty"`
@@ -43,6 +62,11 @@ type OrderItem struct {
 	UpdatedAt       time.Time      `json:"updatedAt,omitempty"`
 	OrderID         uint64         `json:"orderID,omitempty"`
 	ExtraData       string         `json:"extraData,omitempty"`
+	// RejectReason is filled by the matching engine, it is not a part of the order trie
+	RejectReason string `json:"rejectReason,omitempty" rlp:"-"`
+	// Route is the token path of routed market order, it is empty for other orders
+	// so that their encoding doesn't change
+	Route []common.Address `json:"route,omitempty" rlp:"tail"`
 }
 
 // Signature struct
@@ -77,6 +101,8 @@ type OrderItemBSON struct {
 	UpdatedAt       time.Time        `json:"updatedAt,omitempty" bson:"updatedAt"`
 	OrderID         string           `json:"orderID,omitempty" bson:"orderID"`
 	ExtraData       string           `json:"extraData,omitempty" bson:"extraData"`
+	RejectReason    string           `json:"rejectReason,omitempty" bson:"rejectReason,omitempty"`
+	Route           []string         `json:"route,omitempty" bson:"route,omitempty"`
 }
 
 func (o *OrderItem) GetBSON() (interface{}, error) {
@@ -97,6 +123,11 @@ func (o *OrderItem) GetBSON() (interface{}, error) {
 		UpdatedAt:       o.UpdatedAt,
 		OrderID:         strconv.FormatUint(o.OrderID, 10),
 		ExtraData:       o.ExtraData,
+		RejectReason:    o.RejectReason,
+	}
+
+	for _, token := range o.Route {
+		or.Route = append(or.Route, token.Hex())
 	}
 
 	if o.FilledAmount != nil {
@@ -137,6 +168,8 @@ func (o *OrderItem) SetBSON(raw bson.Raw) error {
 		UpdatedAt       time.Time        `json:"updatedAt" bson:"updatedAt"`
 		OrderID         string           `json:"orderID" bson:"orderID"`
 		ExtraData       string           `json:"extraData,omitempty" bson:"extraData"`
+		RejectReason    string           `json:"rejectReason,omitempty" bson:"rejectReason"`
+		Route           []string         `json:"route,omitempty" bson:"route"`
 	})
 
 	err := raw.Unmarshal(decoded)
@@ -184,12 +217,47 @@ func (o *OrderItem) SetBSON(raw bson.Raw) error {
 	}
 	o.OrderID = uint64(orderID)
 	o.ExtraData = decoded.ExtraData
+	o.RejectReason = decoded.RejectReason
+	o.Route = nil
+	for _, token := range decoded.Route {
+		o.Route = append(o.Route, common.HexToAddress(token))
+	}
 	return nil
 }
 
-// VerifyOrder verify orderItem
-func (o *OrderItem) VerifyOrder(state *state.StateDB) error {
-	if err := o.VerifyBasicOrderInfo(); err != nil {
+// SetRejectReason keeps the first reason recorded for the order,
+// a generic fallback never overrides a more specific cause
+func (o *OrderItem) SetRejectReason(reason string) {
+	if o.RejectReason == "" {
+		o.RejectReason = reason
+	}
+}
+
+// GetRejectReason maps an error returned by VerifyOrder to its reject reason
+func GetRejectReason(err error) string {
+	switch err {
+	case ErrInvalidSignature:
+		return RejectReasonInvalidSignature
+	case ErrInvalidRelayer:
+		return RejectReasonInvalidRelayer
+	case ErrInvalidPrice:
+		return RejectReasonInvalidPrice
+	case ErrInvalidQuantity:
+		return RejectReasonInvalidQuantity
+	case ErrInvalidOrderType, ErrInvalidOrderSide, ErrInvalidStatus:
+		return RejectReasonInvalidOrder
+	case ErrInvalidRoute:
+		return RejectReasonInvalidRoute
+	case ErrInvalidPair:
+		return RejectReasonInvalidPair
+	default:
+		return RejectReasonInvalidOrder
+	}
+}
+
+// VerifyOrder verify orderItem, accepting the signature schemes of the signer
+func (o *OrderItem) VerifyOrder(state *state.StateDB, signer types.OrderSigner) error {
+	if err := o.VerifyBasicOrderInfo(signer); err != nil {
 		return err
 	}
 	if err := o.verifyRelayer(state); err != nil {
@@ -197,14 +265,15 @@ func (o *OrderItem) VerifyOrder(state *state.StateDB) error {
 	}
 	if o.Status == OrderNew {
 		if err := VerifyPair(state, o.ExchangeAddress, o.BaseToken, o.QuoteToken); err != nil {
-			return err
+			log.Debug("Order pair is not listed by the relayer", "err", err)
+			return ErrInvalidPair
 		}
 	}
 	return nil
 }
 
 // VerifyBasicOrderInfo verify basic info
-func (o *OrderItem) VerifyBasicOrderInfo() error {
+func (o *OrderItem) VerifyBasicOrderInfo(signer types.OrderSigner) error {
 
 	if o.Status == OrderNew {
 		if o.Type == Limit {
@@ -221,11 +290,16 @@ func (o *OrderItem) VerifyBasicOrderInfo() error {
 		if err := o.verifyOrderType(); err != nil {
 			return err
 		}
+		if o.Type == RoutedMarket {
+			if err := o.verifyRoute(); err != nil {
+				return err
+			}
+		}
 	}
 	if err := o.verifyStatus(); err != nil {
 		return err
 	}
-	if err := o.verifySignature(); err != nil {
+	if err := o.verifySignature(signer); err != nil {
 		return err
 	}
 	return nil
@@ -240,7 +314,7 @@ func (o *OrderItem) verifyRelayer(state *state.StateDB) error {
 }
 
 //verify signatures
-func (o *OrderItem) verifySignature() error {
+func (o *OrderItem) verifySignature(signer types.OrderSigner) error {
 	bigstr := o.Nonce.String()
 	n, err := strconv.ParseInt(bigstr, 10, 64)
 	if err != nil {
@@ -252,8 +326,11 @@ func (o *OrderItem) verifySignature() error {
 
 	tx := types.NewOrderTransaction(uint64(n), o.Quantity, o.Price, o.ExchangeAddress, o.UserAddress,
 		o.BaseToken, o.QuoteToken, o.Status, o.Side, o.Type, o.Hash, o.OrderID)
+	if len(o.Route) > 0 {
+		tx.SetRoute(o.Route)
+	}
 	tx.ImportSignature(V, R, S)
-	from, _ := types.OrderSender(types.OrderTxSigner{}, tx)
+	from, _ := types.OrderSender(signer, tx)
 	if from != tx.UserAddress() {
 		return ErrInvalidSignature
 	}
@@ -269,6 +346,40 @@ func (o *OrderItem) verifyOrderType() error {
 	return nil
 }
 
+// verify route of routed market order
+func (o *OrderItem) verifyRoute() error {
+	return VerifyRoute(o.Route, o.BaseToken, o.QuoteToken, o.Side)
+}
+
+// VerifyRoute checks the route of a routed market order.
+// route is the token path from the token to spend to the token to receive, tokens must not repeat.
+// baseToken, quoteToken and side must describe the first hop of the route
+func VerifyRoute(route []common.Address, baseToken, quoteToken common.Address, side string) error {
+	if len(route) < 2 || len(route) > MaxRouteHops+1 {
+		return ErrInvalidRoute
+	}
+	seen := map[common.Address]bool{}
+	for _, token := range route {
+		if seen[token] {
+			return ErrInvalidRoute
+		}
+		seen[token] = true
+	}
+	switch side {
+	case Ask:
+		if baseToken != route[0] || quoteToken != route[1] {
+			return ErrInvalidRoute
+		}
+	case Bid:
+		if baseToken != route[1] || quoteToken != route[0] {
+			return ErrInvalidRoute
+		}
+	default:
+		return ErrInvalidOrderSide
+	}
+	return nil
+}
+
 //verify order side
 func (o *OrderItem) verifyOrderSide() error {
 
@@ -0,0 +1,183 @@
+package tradingstate
+
+import (
+	"errors"
+	"math/big"
+)
+
+const (
+	// PriceOracleSize is the number of observations kept in the ring buffer of a pair
+	PriceOracleSize = 128
+	// PriceOracleInterval is the minimum number of seconds between two observations in the ring buffer
+	PriceOracleInterval = 60
+)
+
+var (
+	ErrPriceOracleEmpty  = errors.New("price oracle: no observation")
+	ErrPriceOracleWindow = errors.New("price oracle: invalid window")
+	ErrPriceOracleTime   = errors.New("price oracle: time is before the latest observation")
+)
+
+// PriceObservation is the cumulative price, volume and number of trades of a pair at a block
+type PriceObservation struct {
+	Number           uint64
+	Time             uint64
+	Price            *big.Int // last price at the end of the block
+	CumulativePrice  *big.Int // sum of price * seconds the price lasted
+	CumulativeVolume *big.Int // sum of traded quantity
+	CumulativeTrades uint64
+}
+
+// PriceOracle keeps the latest observation of a pair, updated at every trade,
+// and a ring buffer of observations sampled at most every PriceOracleInterval seconds
+type PriceOracle struct {
+	Latest       PriceObservation
+	Cursor       uint64 // index of the newest observation in Observations
+	Observations []PriceObservation
+}
+
+// PriceOracleResult is the time-weighted average price of a pair over a window
+type PriceOracleResult struct {
+	TWAP   *big.Int `json:"twap"`
+	Volume *big.Int `json:"volume"`
+	Trades uint64   `json:"trades"`
+	Window uint64   `json:"window"` // seconds, less than the requested window if the oracle doesn't cover it
+}
+
+func (obs PriceObservation) copy() PriceObservation {
+	return PriceObservation{
+		Number:           obs.Number,
+		Time:             obs.Time,
+		Price:            CloneBigInt(obs.Price),
+		CumulativePrice:  CloneBigInt(obs.CumulativePrice),
+		CumulativeVolume: CloneBigInt(obs.CumulativeVolume),
+		CumulativeTrades: obs.CumulativeTrades,
+	}
+}
+
+// cumulativePriceAt extrapolates the cumulative price to the given time with the last price of the observation
+func (obs PriceObservation) cumulativePriceAt(time uint64) *big.Int {
+	elapsed := new(big.Int).SetUint64(time - obs.Time)
+	return new(big.Int).Add(obs.CumulativePrice, new(big.Int).Mul(obs.Price, elapsed))
+}
+
+// observe returns a copy of the oracle updated with a trade of quantity at price in the given block
+func (o *PriceOracle) observe(number, time uint64, price, quantity *big.Int) *PriceOracle {
+	oracle := &PriceOracle{Cursor: o.Cursor}
+	latest := o.Latest
+	switch {
+	case latest.Price == nil:
+		oracle.Latest = PriceObservation{
+			Number:           number,
+			Time:             time,
+			Price:            CloneBigInt(price),
+			CumulativePrice:  new(big.Int),
+			CumulativeVolume: CloneBigInt(quantity),
+			CumulativeTrades: 1,
+		}
+	case latest.Number == number:
+		oracle.Latest = latest.copy()
+		oracle.Latest.Price = CloneBigInt(price)
+		oracle.Latest.CumulativeVolume = new(big.Int).Add(latest.CumulativeVolume, quantity)
+		oracle.Latest.CumulativeTrades++
+	default:
+		oracle.Latest = PriceObservation{
+			Number:           number,
+			Time:             time,
+			Price:            CloneBigInt(price),
+			CumulativePrice:  latest.cumulativePriceAt(time),
+			CumulativeVolume: new(big.Int).Add(latest.CumulativeVolume, quantity),
+			CumulativeTrades: latest.CumulativeTrades + 1,
+		}
+	}
+	// copy on write, the observations may be shared with copies of the trading state
+	oracle.Observations = make([]PriceObservation, len(o.Observations), PriceOracleSize)
+	copy(oracle.Observations, o.Observations)
+	switch {
+	case len(oracle.Observations) == 0:
+		oracle.Observations = append(oracle.Observations, oracle.Latest)
+		oracle.Cursor = 0
+	case oracle.Observations[oracle.Cursor].Number == number:
+		oracle.Observations[oracle.Cursor] = oracle.Latest
+	case time >= oracle.Observations[oracle.Cursor].Time+PriceOracleInterval:
+		if len(oracle.Observations) < PriceOracleSize {
+			oracle.Observations = append(oracle.Observations, oracle.Latest)
+			oracle.Cursor = uint64(len(oracle.Observations) - 1)
+		} else {
+			oracle.Cursor = (oracle.Cursor + 1) % PriceOracleSize
+			oracle.Observations[oracle.Cursor] = oracle.Latest
+		}
+	}
+	return oracle
+}
+
+// observations returns the observations from the oldest to the newest, followed by the latest one
+func (o *PriceOracle) observations() []PriceObservation {
+	size := uint64(len(o.Observations))
+	result := make([]PriceObservation, 0, size+1)
+	for i := uint64(1); i <= size; i++ {
+		result = append(result, o.Observations[(o.Cursor+i)%size])
+	}
+	if size == 0 || result[size-1].Number != o.Latest.Number {
+		result = append(result, o.Latest)
+	}
+	return result
+}
+
+// GetTWAP returns the time-weighted average price over window seconds before time,
+// and the volume and number of trades since the newest observation at or before the beginning of the window.
+// The window is shortened to the oldest observation if the oracle doesn't cover it
+func (o *PriceOracle) GetTWAP(time, window uint64) (*PriceOracleResult, error) {
+	if o == nil || o.Latest.Price == nil {
+		return nil, ErrPriceOracleEmpty
+	}
+	if window == 0 {
+		return nil, ErrPriceOracleWindow
+	}
+	if time < o.Latest.Time {
+		return nil, ErrPriceOracleTime
+	}
+	observations := o.observations()
+	oldest := observations[0]
+	if time-oldest.Time < window {
+		window = time - oldest.Time
+	}
+	if window == 0 {
+		// all observations are at the current block, no time has passed since
+		return &PriceOracleResult{
+			TWAP:   CloneBigInt(o.Latest.Price),
+			Volume: new(big.Int).Sub(o.Latest.CumulativeVolume, oldest.CumulativeVolume),
+			Trades: o.Latest.CumulativeTrades - oldest.CumulativeTrades,
+		}, nil
+	}
+	start := time - window
+	// find the newest observation at or before start, interpolate the cumulative price with the next one
+	index := 0
+	for i := range observations {
+		if observations[i].Time > start {
+			break
+		}
+		index = i
+	}
+	before := observations[index]
+	cumulativeStart := before.cumulativePriceAt(start)
+	if index+1 < len(observations) {
+		after := observations[index+1]
+		if after.Time > before.Time {
+			// cumulativeStart = before.cumulative + (after.cumulative - before.cumulative) * (start - before.Time) / (after.Time - before.Time)
+			delta := new(big.Int).Sub(after.CumulativePrice, before.CumulativePrice)
+			delta = new(big.Int).Mul(delta, new(big.Int).SetUint64(start-before.Time))
+			delta = new(big.Int).Div(delta, new(big.Int).SetUint64(after.Time-before.Time))
+			cumulativeStart = new(big.Int).Add(before.CumulativePrice, delta)
+		}
+	}
+	cumulativeEnd := o.Latest.cumulativePriceAt(time)
+	twap := new(big.Int).Sub(cumulativeEnd, cumulativeStart)
+	twap = new(big.Int).Div(twap, new(big.Int).SetUint64(window))
+	return &PriceOracleResult{
+		TWAP:   twap,
+		Volume: new(big.Int).Sub(o.Latest.CumulativeVolume, before.CumulativeVolume),
+		Trades: o.Latest.CumulativeTrades - before.CumulativeTrades,
+		Window: window,
+	}, nil
+}
@@ -0,0 +1,126 @@
+package tradingstate
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/core/rawdb"
+	"github.com/69th-byte/sdexchain/rlp"
+)
+
+func TestPriceOracleTWAP(t *testing.T) {
+	oracle := &PriceOracle{}
+	// price 100 from time 1000, 200 from time 1100 (two trades in the block), 400 from time 1400
+	oracle = oracle.observe(1, 1000, big.NewInt(100), big.NewInt(1))
+	oracle = oracle.observe(2, 1100, big.NewInt(150), big.NewInt(2))
+	oracle = oracle.observe(2, 1100, big.NewInt(200), big.NewInt(3))
+	oracle = oracle.observe(3, 1400, big.NewInt(400), big.NewInt(4))
+	if len(oracle.Observations) != 3 || oracle.Cursor != 2 {
+		t.Fatalf("invalid observations: %d, cursor %d", len(oracle.Observations), oracle.Cursor)
+	}
+	tests := []struct {
+		time, window uint64
+		twap         int64
+		volume       int64
+		trades       uint64
+		actualWindow uint64
+	}{
+		// (100 * 100 + 200 * 300 + 400 * 100) / 500
+		{1500, 500, 220, 9, 3, 500},
+		// window is shortened to the oldest observation
+		{1500, 1000, 220, 9, 3, 500},
+		// (200 * 100 + 400 * 100) / 200
+		{1500, 200, 300, 4, 1, 200},
+		// the start of the window is interpolated between observations: (200 * 200) / 200
+		{1400, 200, 200, 4, 1, 200},
+	}
+	for i, test := range tests {
+		result, err := oracle.GetTWAP(test.time, test.window)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if result.TWAP.Int64() != test.twap || result.Volume.Int64() != test.volume || result.Trades != test.trades || result.Window != test.actualWindow {
+			t.Errorf("test %d: have twap %v volume %v trades %d window %d, want %d %d %d %d", i,
+				result.TWAP, result.Volume, result.Trades, result.Window, test.twap, test.volume, test.trades, test.actualWindow)
+		}
+	}
+	if _, err := oracle.GetTWAP(1300, 100); err != ErrPriceOracleTime {
+		t.Errorf("expected error %v, have %v", ErrPriceOracleTime, err)
+	}
+	var empty *PriceOracle
+	if _, err := empty.GetTWAP(1500, 100); err != ErrPriceOracleEmpty {
+		t.Errorf("expected error %v, have %v", ErrPriceOracleEmpty, err)
+	}
+}
+
+func TestPriceOracleRingBuffer(t *testing.T) {
+	oracle := &PriceOracle{}
+	for i := uint64(0); i < PriceOracleSize+10; i++ {
+		oracle = oracle.observe(i, i*PriceOracleInterval, big.NewInt(100), big.NewInt(1))
+		// observations within the interval update the latest one only
+		oracle = oracle.observe(i, i*PriceOracleInterval+1, big.NewInt(100), big.NewInt(1))
+	}
+	if len(oracle.Observations) != PriceOracleSize {
+		t.Fatalf("invalid number of observations: %d", len(oracle.Observations))
+	}
+	observations := oracle.observations()
+	if observations[0].Number != 10 || observations[len(observations)-1].Number != PriceOracleSize+9 {
+		t.Errorf("invalid ring buffer: oldest %d, newest %d", observations[0].Number, observations[len(observations)-1].Number)
+	}
+	for i := 1; i < len(observations); i++ {
+		if observations[i].Time <= observations[i-1].Time {
+			t.Fatalf("observations are not ordered at %d", i)
+		}
+	}
+}
+
+func TestPriceOracleState(t *testing.T) {
+	orderBook := GetTradingOrderBookHash(common.HexToAddress("0x01"), common.HexToAddress("0x02"))
+	tradingStateDB, _ := New(EmptyRoot, NewDatabase(rawdb.NewMemoryDatabase()))
+	tradingStateDB.SetLastPrice(orderBook, big.NewInt(100))
+	withoutOracle := tradingStateDB.GetOrNewStateExchangeObject(orderBook).data
+	encoded, err := rlp.EncodeToBytes(&withoutOracle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var legacy struct {
+		Nonce                  uint64
+		LastPrice              *big.Int
+		MediumPriceBeforeEpoch *big.Int
+		MediumPrice            *big.Int
+		TotalQuantity          *big.Int
+		LendingCount           *big.Int
+		AskRoot                common.Hash
+		BidRoot                common.Hash
+		OrderRoot              common.Hash
+		LiquidationPriceRoot   common.Hash
+	}
+	if err := rlp.DecodeBytes(encoded, &legacy); err != nil {
+		t.Fatal("encoding of pair without price oracle changed:", err)
+	}
+	legacyEncoded, _ := rlp.EncodeToBytes(&legacy)
+	if !bytes.Equal(encoded, legacyEncoded) {
+		t.Error("encoding of pair without price oracle changed")
+	}
+
+	snap := tradingStateDB.Snapshot()
+	tradingStateDB.ObservePrice(orderBook, 1, 1000, big.NewInt(100), big.NewInt(1))
+	if oracle := tradingStateDB.GetPriceOracle(orderBook); oracle == nil || oracle.Latest.Number != 1 {
+		t.Fatalf("price oracle is not updated: %v", oracle)
+	}
+	tradingStateDB.RevertToSnapshot(snap)
+	if oracle := tradingStateDB.GetPriceOracle(orderBook); oracle != nil {
+		t.Errorf("price oracle is not reverted: %v", oracle)
+	}
+	tradingStateDB.ObservePrice(orderBook, 2, 1100, big.NewInt(200), big.NewInt(1))
+	root, err := tradingStateDB.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	committed, _ := New(root, tradingStateDB.db)
+	if oracle := committed.GetPriceOracle(orderBook); oracle == nil || oracle.Latest.Price.Int64() != 200 {
+		t.Fatalf("price oracle is not committed: %v", oracle)
+	}
+}
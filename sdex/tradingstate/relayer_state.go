@@ -36,6 +36,32 @@ func GetRelayerOwner(relayer common.Address, statedb *state.StateDB) common.Addr
 	return common.BytesToAddress(statedb.GetState(common.HexToAddress(common.RelayerRegistrationSMC), locHash).Bytes())
 }
 
+// return the deposit of relayer, relayer fees are deducted from this deposit
+func GetRelayerDeposit(relayer common.Address, statedb *state.StateDB) *big.Int {
+	slot := RelayerMappingSlot["RELAYER_LIST"]
+	locBig := GetLocMappingAtKey(relayer.Hash(), slot)
+	locBig = new(big.Int).Add(locBig, RelayerStructMappingSlot["_deposit"])
+	locHash := common.BigToHash(locBig)
+	return statedb.GetState(common.HexToAddress(common.RelayerRegistrationSMC), locHash).Big()
+}
+
+// return the part of deposit which can still be used to pay fee before CheckRelayerFee starts rejecting orders
+func GetRelayerSpareFee(deposit *big.Int) *big.Int {
+	spare := new(big.Int).Sub(deposit, new(big.Int).Mul(common.BasePrice, common.RelayerLockedFund))
+	if spare.Sign() < 0 {
+		return new(big.Int)
+	}
+	return spare
+}
+
+// return true if relayer deposit can only pay fee for less than minTrades matched trades
+func IsRelayerFeeLow(deposit *big.Int, minTrades uint64) bool {
+	// each matched trade charges relayer fee for both taker and maker
+	feePerTrade := new(big.Int).Mul(common.RelayerFee, big.NewInt(2))
+	threshold := new(big.Int).Mul(feePerTrade, new(big.Int).SetUint64(minTrades))
+	return GetRelayerSpareFee(deposit).Cmp(threshold) < 0
+}
+
 // return true if relayer request to resign and have not withdraw locked fund
 func IsResignedRelayer(relayer common.Address, statedb *state.StateDB) bool {
 	slot := RelayerMappingSlot["RESIGN_REQUESTS"]
@@ -149,12 +175,7 @@ func SubRelayerFee(relayer common.Address, fee *big.Int, statedb *state.StateDB)
 }
 
 func CheckRelayerFee(relayer common.Address, fee *big.Int, statedb *state.StateDB) error {
-	slot := RelayerMappingSlot["RELAYER_LIST"]
-	locBig := GetLocMappingAtKey(relayer.Hash(), slot)
-
-	locBigDeposit := new(big.Int).SetUint64(uint64(0)).Add(locBig, RelayerStructMappingSlot["_deposit"])
-	locHashDeposit := common.BigToHash(locBigDeposit)
-	balance := statedb.GetState(common.HexToAddress(common.RelayerRegistrationSMC), locHashDeposit).Big()
+	balance := GetRelayerDeposit(relayer, statedb)
 	if new(big.Int).Sub(balance, fee).Cmp(new(big.Int).Mul(common.BasePrice, common.RelayerLockedFund)) < 0 {
 		return errors.Errorf("relayer %s isn't enough tomo fee : balance %d , fee : %d ", relayer.Hex(), balance.Uint64(), fee.Uint64())
 	}
@@ -0,0 +1,98 @@
+package tradingstate
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/69th-byte/sdexchain/common"
+)
+
+// PriceLevel is the total volume of one side of an order book at a price
+type PriceLevel struct {
+	Price  *big.Int
+	Volume *big.Int
+}
+
+// SortPriceLevels returns price levels of the given side ordered from the best price:
+// ascending for asks, descending for bids
+func SortPriceLevels(levels map[*big.Int]*big.Int, side string) []PriceLevel {
+	result := make([]PriceLevel, 0, len(levels))
+	for price, volume := range levels {
+		if price == nil || price.Sign() <= 0 || volume == nil || volume.Sign() <= 0 {
+			continue
+		}
+		result = append(result, PriceLevel{Price: price, Volume: volume})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if side == Bid {
+			return result[i].Price.Cmp(result[j].Price) > 0
+		}
+		return result[i].Price.Cmp(result[j].Price) < 0
+	})
+	return result
+}
+
+// GetSellQuote walks bids from the best price and returns the amount of quote token received
+// by selling quantity of base token after taker fee, and the quantity of base token which can be filled
+func GetSellQuote(bids []PriceLevel, quantity, takerFeeRate, baseTokenDecimal *big.Int) (*big.Int, *big.Int) {
+	remaining := CloneBigInt(quantity)
+	amountOut := new(big.Int)
+	filled := new(big.Int)
+	for _, level := range bids {
+		if remaining.Sign() <= 0 {
+			break
+		}
+		tradedQuantity := level.Volume
+		if remaining.Cmp(tradedQuantity) < 0 {
+			tradedQuantity = remaining
+		}
+		// quoteTokenQuantity = tradedQuantity * price / baseTokenDecimal
+		quoteTokenQuantity := new(big.Int).Mul(tradedQuantity, level.Price)
+		quoteTokenQuantity = new(big.Int).Div(quoteTokenQuantity, baseTokenDecimal)
+		takerFee := new(big.Int).Mul(quoteTokenQuantity, takerFeeRate)
+		takerFee = new(big.Int).Div(takerFee, common.TomoXBaseFee)
+
+		amountOut = new(big.Int).Add(amountOut, new(big.Int).Sub(quoteTokenQuantity, takerFee))
+		filled = new(big.Int).Add(filled, tradedQuantity)
+		remaining = new(big.Int).Sub(remaining, tradedQuantity)
+	}
+	return amountOut, filled
+}
+
+// GetBuyQuote walks asks from the best price and returns the quantity of base token which can be bought
+// by spending at most amountIn of quote token with taker fee included, and the amount of quote token spent
+func GetBuyQuote(asks []PriceLevel, amountIn, takerFeeRate, baseTokenDecimal *big.Int) (*big.Int, *big.Int) {
+	remaining := CloneBigInt(amountIn)
+	quantity := new(big.Int)
+	spent := new(big.Int)
+	feeBase := new(big.Int).Add(common.TomoXBaseFee, takerFeeRate)
+	for _, level := range asks {
+		if remaining.Sign() <= 0 {
+			break
+		}
+		// cost of q = q * price * (baseFee + takerFeeRate) / (baseTokenDecimal * baseFee)
+		maxQuantity := new(big.Int).Mul(remaining, baseTokenDecimal)
+		maxQuantity = new(big.Int).Mul(maxQuantity, common.TomoXBaseFee)
+		maxQuantity = new(big.Int).Div(maxQuantity, new(big.Int).Mul(feeBase, level.Price))
+		tradedQuantity := level.Volume
+		if maxQuantity.Cmp(tradedQuantity) < 0 {
+			tradedQuantity = maxQuantity
+		}
+		if tradedQuantity.Sign() == 0 {
+			break
+		}
+		quoteTokenQuantity := new(big.Int).Mul(tradedQuantity, level.Price)
+		quoteTokenQuantity = new(big.Int).Div(quoteTokenQuantity, baseTokenDecimal)
+		takerFee := new(big.Int).Mul(quoteTokenQuantity, takerFeeRate)
+		takerFee = new(big.Int).Div(takerFee, common.TomoXBaseFee)
+		cost := new(big.Int).Add(quoteTokenQuantity, takerFee)
+
+		quantity = new(big.Int).Add(quantity, tradedQuantity)
+		spent = new(big.Int).Add(spent, cost)
+		remaining = new(big.Int).Sub(remaining, cost)
+		if tradedQuantity.Cmp(level.Volume) < 0 {
+			break
+		}
+	}
+	return quantity, spent
+}
@@ -0,0 +1,128 @@
+package tradingstate
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/rlp"
+)
+
+func TestVerifyRoute(t *testing.T) {
+	var (
+		a = common.HexToAddress("0x01")
+		b = common.HexToAddress("0x02")
+		c = common.HexToAddress("0x03")
+		d = common.HexToAddress("0x04")
+		e = common.HexToAddress("0x05")
+	)
+	tests := []struct {
+		route   []common.Address
+		base    common.Address
+		quote   common.Address
+		side    string
+		wantErr error
+	}{
+		{[]common.Address{a, b}, a, b, Ask, nil},
+		{[]common.Address{a, b, c}, b, a, Bid, nil},
+		{[]common.Address{a, b, c, d}, a, b, Ask, nil},
+		{[]common.Address{a}, a, b, Ask, ErrInvalidRoute},
+		{[]common.Address{a, b, c, d, e}, a, b, Ask, ErrInvalidRoute},
+		{[]common.Address{a, b, a}, a, b, Ask, ErrInvalidRoute},
+		{[]common.Address{a, b, c}, a, b, Bid, ErrInvalidRoute},
+		{[]common.Address{a, b, c}, b, c, Ask, ErrInvalidRoute},
+		{[]common.Address{a, b, c}, a, b, "", ErrInvalidOrderSide},
+	}
+	for i, test := range tests {
+		if err := VerifyRoute(test.route, test.base, test.quote, test.side); err != test.wantErr {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, test.wantErr)
+		}
+	}
+}
+
+func TestRouteQuote(t *testing.T) {
+	decimal := big.NewInt(1000)
+	feeRate := big.NewInt(10) // 0.1%
+	bids := SortPriceLevels(map[*big.Int]*big.Int{
+		big.NewInt(90):  big.NewInt(1000),
+		big.NewInt(100): big.NewInt(500),
+		big.NewInt(0):   big.NewInt(100),
+	}, Bid)
+	if len(bids) != 2 || bids[0].Price.Int64() != 100 {
+		t.Fatalf("invalid sorted bids: %v", bids)
+	}
+	// 500 * 100 / 1000 + 300 * 90 / 1000 = 50 + 27, fee: 0.05 + 0.027 rounded down per level
+	amountOut, filled := GetSellQuote(bids, big.NewInt(800), feeRate, decimal)
+	if amountOut.Int64() != 77 || filled.Int64() != 800 {
+		t.Errorf("invalid sell quote: amountOut %v, filled %v", amountOut, filled)
+	}
+	amountOut, filled = GetSellQuote(bids, big.NewInt(2000), feeRate, decimal)
+	if filled.Int64() != 1500 {
+		t.Errorf("sell quote should be limited by volume: filled %v", filled)
+	}
+
+	asks := SortPriceLevels(map[*big.Int]*big.Int{
+		big.NewInt(2000): big.NewInt(1000),
+		big.NewInt(1000): big.NewInt(1000),
+	}, Ask)
+	if asks[0].Price.Int64() != 1000 {
+		t.Fatalf("invalid sorted asks: %v", asks)
+	}
+	// 1000 at price 1000 costs 1000 + 1 fee, the rest 2000 buys 999 at price 2000 with fee 1
+	quantity, spent := GetBuyQuote(asks, big.NewInt(3001), feeRate, decimal)
+	if quantity.Int64() != 1999 || spent.Cmp(big.NewInt(3001)) > 0 {
+		t.Errorf("invalid buy quote: quantity %v, spent %v", quantity, spent)
+	}
+	quantity, spent = GetBuyQuote(asks, big.NewInt(100000), feeRate, decimal)
+	if quantity.Int64() != 2000 || spent.Int64() != 3003 {
+		t.Errorf("buy quote should be limited by volume: quantity %v, spent %v", quantity, spent)
+	}
+}
+
+func TestOrderRouteEncoding(t *testing.T) {
+	order := &OrderItem{
+		Quantity:  big.NewInt(100),
+		Price:     big.NewInt(5),
+		Status:    OrderStatusNew,
+		Nonce:     big.NewInt(1),
+		Signature: &Signature{},
+	}
+	encoded, err := EncodeBytesItem(order)
+	if err != nil {
+		t.Fatal("Failed to encode", err)
+	}
+	order.Route = []common.Address{common.HexToAddress("0x01"), common.HexToAddress("0x02")}
+	encodedWithRoute, err := EncodeBytesItem(order)
+	if err != nil {
+		t.Fatal("Failed to encode", err)
+	}
+	// an empty route must not change the encoding of orders without route
+	content, _, err := rlp.SplitList(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contentWithRoute, _, err := rlp.SplitList(encodedWithRoute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, _ := rlp.CountValues(content)
+	countWithRoute, _ := rlp.CountValues(contentWithRoute)
+	if countWithRoute != count+len(order.Route) {
+		t.Errorf("route is not encoded as tail: %d values without route, %d with route", count, countWithRoute)
+	}
+	decoded := &OrderItem{}
+	if err := DecodeBytesItem(encodedWithRoute, decoded); err != nil {
+		t.Fatal("Failed to decode", err)
+	}
+	if !reflect.DeepEqual(decoded.Route, order.Route) {
+		t.Errorf("route mismatch: have %v, want %v", decoded.Route, order.Route)
+	}
+	decoded = &OrderItem{}
+	if err := DecodeBytesItem(encoded, decoded); err != nil {
+		t.Fatal("Failed to decode", err)
+	}
+	if len(decoded.Route) != 0 {
+		t.Errorf("route should be empty, have %v", decoded.Route)
+	}
+}
@@ -261,3 +261,27 @@ func TestGetSettleBalance(t *testing.T) {
 		})
 	}
 }
+
+func TestIsRelayerFeeLow(t *testing.T) {
+	lockedFund := new(big.Int).Mul(common.BasePrice, common.RelayerLockedFund)
+	feePerTrade := new(big.Int).Mul(common.RelayerFee, big.NewInt(2))
+	tests := []struct {
+		name    string
+		deposit *big.Int
+		spare   *big.Int
+		low     bool
+	}{
+		{"below locked fund", new(big.Int).Sub(lockedFund, big.NewInt(1)), big.NewInt(0), true},
+		{"equal locked fund", lockedFund, big.NewInt(0), true},
+		{"fee for 9 trades", new(big.Int).Add(lockedFund, new(big.Int).Mul(feePerTrade, big.NewInt(9))), new(big.Int).Mul(feePerTrade, big.NewInt(9)), true},
+		{"fee for 10 trades", new(big.Int).Add(lockedFund, new(big.Int).Mul(feePerTrade, big.NewInt(10))), new(big.Int).Mul(feePerTrade, big.NewInt(10)), false},
+	}
+	for _, tt := range tests {
+		if got := GetRelayerSpareFee(tt.deposit); got.Cmp(tt.spare) != 0 {
+			t.Errorf("%s: GetRelayerSpareFee = %v, want %v", tt.name, got, tt.spare)
+		}
+		if got := IsRelayerFeeLow(tt.deposit, 10); got != tt.low {
+			t.Errorf("%s: IsRelayerFeeLow = %v, want %v", tt.name, got, tt.low)
+		}
+	}
+}
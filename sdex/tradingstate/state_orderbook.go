@@ -96,6 +96,9 @@ func (s *tradingExchanges) empty() bool {
 	if !common.EmptyHash(s.data.LiquidationPriceRoot) {
 		return false
 	}
+	if len(s.data.PriceOracle) > 0 {
+		return false
+	}
 	return true
 }
 
@@ -390,6 +393,14 @@ func (self *tradingExchanges) setMediumPriceBeforeEpoch(price *big.Int) {
 	}
 }
 
+func (self *tradingExchanges) setPriceOracle(oracle []PriceOracle) {
+	self.data.PriceOracle = oracle
+	if self.onDirty != nil {
+		self.onDirty(self.Hash())
+		self.onDirty = nil
+	}
+}
+
 func (self *tradingExchanges) setMediumPrice(price *big.Int, quantity *big.Int) {
 	self.data.MediumPrice = price
 	self.data.TotalQuantity = quantity
@@ -59,6 +59,9 @@ type TradingStateDB struct {
 	validRevisions []revision
 	nextRevisionId int
 
+	// Tracer following the matching engines, see SetMatchingTracer.
+	tracer MatchingTracer
+
 	lock sync.Mutex
 }
 
@@ -183,6 +186,31 @@ func (self *TradingStateDB) SetMediumPriceBeforeEpoch(addr common.Hash, price *b
 	}
 }
 
+// GetPriceOracle returns the price oracle of the order book, nil if no trade has been observed
+func (self *TradingStateDB) GetPriceOracle(orderBook common.Hash) *PriceOracle {
+	stateObject := self.getStateExchangeObject(orderBook)
+	if stateObject != nil && len(stateObject.data.PriceOracle) > 0 {
+		return &stateObject.data.PriceOracle[0]
+	}
+	return nil
+}
+
+// ObservePrice records a trade of quantity at price in the given block to the price oracle of the order book
+func (self *TradingStateDB) ObservePrice(orderBook common.Hash, number, time uint64, price, quantity *big.Int) {
+	stateObject := self.GetOrNewStateExchangeObject(orderBook)
+	if stateObject != nil {
+		self.journal = append(self.journal, priceOracleChange{
+			hash: orderBook,
+			prev: stateObject.data.PriceOracle,
+		})
+		oracle := &PriceOracle{}
+		if len(stateObject.data.PriceOracle) > 0 {
+			oracle = &stateObject.data.PriceOracle[0]
+		}
+		stateObject.setPriceOracle([]PriceOracle{*oracle.observe(number, time, price, quantity)})
+	}
+}
+
 func (self *TradingStateDB) InsertOrderItem(orderBook common.Hash, orderId common.Hash, order OrderItem) {
 	priceHash := common.BigToHash(order.Price)
 	stateExchange := self.getStateExchangeObject(orderBook)
@@ -0,0 +1,67 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tradingstate
+
+import (
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/ethdb"
+	"github.com/69th-byte/sdexchain/rlp"
+	"github.com/69th-byte/sdexchain/trie"
+)
+
+// NewStateSync creates a new trading state trie download scheduler. Besides the
+// exchange trie itself it schedules the ask, bid, order and liquidation price
+// tries of every exchange, along with the order list and lending book tries
+// referenced from their leaves.
+func NewStateSync(root common.Hash, database ethdb.KeyValueReader, bloom *trie.SyncBloom) *trie.Sync {
+	var syncer *trie.Sync
+
+	// addSubTrie schedules a nested trie, skipping the roots of the tries which
+	// were never written.
+	addSubTrie := func(root common.Hash, parent common.Hash, callback trie.LeafCallback) {
+		if root != (common.Hash{}) {
+			syncer.AddSubTrie(root, 64, parent, callback)
+		}
+	}
+	// orderLists returns a callback scheduling the trie referenced from an order
+	// list leaf, whose own leaves are handled by the given callback.
+	orderLists := func(nested trie.LeafCallback) trie.LeafCallback {
+		return func(leaf []byte, parent common.Hash) error {
+			var obj orderList
+			if err := rlp.DecodeBytes(leaf, &obj); err != nil {
+				return err
+			}
+			addSubTrie(obj.Root, parent, nested)
+			return nil
+		}
+	}
+	callback := func(leaf []byte, parent common.Hash) error {
+		var obj tradingExchangeObject
+		if err := rlp.DecodeBytes(leaf, &obj); err != nil {
+			return err
+		}
+		addSubTrie(obj.AskRoot, parent, orderLists(nil))
+		addSubTrie(obj.BidRoot, parent, orderLists(nil))
+		addSubTrie(obj.OrderRoot, parent, nil)
+		// Liquidation prices reference a trie of lending books, each of them
+		// referencing the trie of its lending trades
+		addSubTrie(obj.LiquidationPriceRoot, parent, orderLists(orderLists(nil)))
+		return nil
+	}
+	syncer = trie.NewSync(root, database, callback, bloom)
+	return syncer
+}
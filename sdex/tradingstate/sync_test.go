@@ -0,0 +1,116 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tradingstate
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/core/rawdb"
+	"github.com/69th-byte/sdexchain/ethdb"
+	"github.com/69th-byte/sdexchain/ethdb/memorydb"
+	"github.com/69th-byte/sdexchain/trie"
+)
+
+// makeTestTradingState creates a trading state with orders on both sides of an
+// order book and some liquidation prices, committed into a fresh database.
+func makeTestTradingState(t *testing.T) (ethdb.Database, common.Hash, common.Hash) {
+	db := rawdb.NewMemoryDatabase()
+	stateCache := NewDatabase(db)
+	statedb, _ := New(common.Hash{}, stateCache)
+
+	orderBook := common.StringToHash("BTC/TOMO")
+	for i := 0; i < 32; i++ {
+		id := common.BigToHash(big.NewInt(int64(i) + 1))
+		side := Ask
+		if i%2 == 1 {
+			side = Bid
+		}
+		statedb.InsertOrderItem(orderBook, id, OrderItem{OrderID: uint64(i) + 1, Quantity: big.NewInt(int64(i) + 1), Price: big.NewInt(int64(i%8) + 1), Side: side, Signature: &Signature{V: 1, R: id, S: id}})
+	}
+	statedb.SetLastPrice(orderBook, big.NewInt(4))
+	for i := 0; i < 8; i++ {
+		statedb.InsertLiquidationPrice(orderBook, big.NewInt(int64(i%3)+1), common.BigToHash(big.NewInt(int64(i%2))), uint64(i))
+	}
+	root, err := statedb.Commit()
+	if err != nil {
+		t.Fatalf("failed to commit trading state: %v", err)
+	}
+	if err := stateCache.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to flush trading state: %v", err)
+	}
+	return db, root, orderBook
+}
+
+// Tests that an empty trading state is not scheduled for syncing.
+func TestEmptyTradingStateSync(t *testing.T) {
+	if req := NewStateSync(EmptyRoot, rawdb.NewMemoryDatabase(), trie.NewSyncBloom(1, memorydb.New())).Missing(1); len(req) != 0 {
+		t.Errorf("content requested for empty state: %v", req)
+	}
+}
+
+// Tests that a trading state is synced along with all its nested order book,
+// order list and liquidation price tries.
+func TestTradingStateSync(t *testing.T) {
+	srcDb, srcRoot, orderBook := makeTestTradingState(t)
+
+	dstDb := rawdb.NewMemoryDatabase()
+	sched := NewStateSync(srcRoot, dstDb, trie.NewSyncBloom(1, memorydb.New()))
+
+	queue := append([]common.Hash{}, sched.Missing(100)...)
+	for len(queue) > 0 {
+		results := make([]trie.SyncResult, len(queue))
+		for i, hash := range queue {
+			data, err := srcDb.Get(hash[:])
+			if err != nil {
+				t.Fatalf("failed to retrieve node data for %x", hash)
+			}
+			results[i] = trie.SyncResult{Hash: hash, Data: data}
+		}
+		if _, index, err := sched.Process(results); err != nil {
+			t.Fatalf("failed to process result #%d: %v", index, err)
+		}
+		batch := dstDb.NewBatch()
+		if err := sched.Commit(batch); err != nil {
+			t.Fatalf("failed to commit data: %v", err)
+		}
+		batch.Write()
+		queue = append(queue[:0], sched.Missing(100)...)
+	}
+	// Every node of the source database belongs to the state, all must be synced
+	it := srcDb.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		if len(it.Key()) != common.HashLength {
+			continue // Skip the preimages of the secure tries
+		}
+		if has, _ := dstDb.Has(it.Key()); !has {
+			t.Errorf("trie node %x not synced", it.Key())
+		}
+	}
+	statedb, err := New(srcRoot, NewDatabase(dstDb))
+	if err != nil {
+		t.Fatalf("failed to open synced state: %v", err)
+	}
+	if price, _ := statedb.GetBestAskPrice(orderBook); price.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("best ask price mismatch: have %v, want 1", price)
+	}
+	if price, _ := statedb.GetBestBidPrice(orderBook); price.Cmp(big.NewInt(8)) != 0 {
+		t.Errorf("best bid price mismatch: have %v, want 8", price)
+	}
+}
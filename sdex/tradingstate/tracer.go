@@ -0,0 +1,51 @@
+package tradingstate
+
+import (
+	"math/big"
+
+	"github.com/69th-byte/sdexchain/common"
+)
+
+// Matching engines reported through MatchingTracer.CaptureOrderStart.
+const (
+	TradingEngine = "trading"
+	LendingEngine = "lending"
+)
+
+// MatchingTracer is implemented by tracers which want to follow the steps the
+// trading and lending matching engines take while applying an order. The
+// tracer is attached to the TradingStateDB handed to the engines, both of
+// them share it.
+//
+// For lending orders the price is the interest rate and the order book is the
+// lending book.
+type MatchingTracer interface {
+	// CaptureOrderStart is called before an order is applied.
+	CaptureOrderStart(engine string, orderBook, hash common.Hash, user common.Address, side, orderType string, price, quantity *big.Int)
+	// CaptureLevel is called when the engine starts matching at a price level.
+	CaptureLevel(side string, price, remaining *big.Int)
+	// CaptureTrade is called for every trade created against a maker order.
+	CaptureTrade(makerHash common.Hash, maker common.Address, price, quantity *big.Int)
+	// CaptureBalance is called for every token balance moved by the settlement.
+	CaptureBalance(account, token common.Address, delta *big.Int)
+	// CaptureFee is called for every fee charged by the settlement.
+	CaptureFee(payer, receiver, token common.Address, amount *big.Int)
+	// CaptureReject is called for every order rejected while applying the order.
+	CaptureReject(hash common.Hash, reason string)
+	// CaptureOrderEnd is called after the order has been applied.
+	CaptureOrderEnd(err error)
+}
+
+// SetMatchingTracer attaches a tracer to the matching engines working on this
+// state. The tracer is not carried over by Copy.
+func (self *TradingStateDB) SetMatchingTracer(tracer MatchingTracer) {
+	self.tracer = tracer
+}
+
+// MatchingTracer returns the tracer attached to the state, nil if none.
+func (self *TradingStateDB) MatchingTracer() MatchingTracer {
+	if self == nil {
+		return nil
+	}
+	return self.tracer
+}
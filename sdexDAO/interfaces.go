@@ -5,8 +5,11 @@
 package tomoxDAO
 
 import (
+	"time"
+
 	"github.com/69th-byte/sdexchain/common"
 	"github.com/69th-byte/sdexchain/ethdb"
+	"github.com/69th-byte/sdexchain/tomox/tradingstate"
 )
 
 const defaultCacheLimit = 1024
@@ -24,6 +27,7 @@ type TomoXDAO interface {
 	GetListItemByTxHash(txhash common.Hash, val interface{}) interface{}
 	GetListItemByHashes(hashes []string, val interface{}) interface{}
 	DeleteItemByTxHash(txhash common.Hash, val interface{})
+	GetTradesByExchange(exchange common.Address, from, to time.Time) []*tradingstate.Trade
 
 	// basic tomox
 	InitBulk()
@@ -7,9 +7,11 @@ import (
 	"github.com/69th-byte/sdexchain/common"
 	"github.com/69th-byte/sdexchain/core/rawdb"
 	"sync"
+	"time"
 
 	"github.com/69th-byte/sdexchain/ethdb"
 	"github.com/69th-byte/sdexchain/log"
+	"github.com/69th-byte/sdexchain/tomox/tradingstate"
 	lru "github.com/hashicorp/golang-lru"
 )
 
@@ -119,6 +121,10 @@ func (db *BatchDatabase) GetListItemByHashes(hashes []string, val interface{}) i
 	return []interface{}{}
 }
 
+func (db *BatchDatabase) GetTradesByExchange(exchange common.Address, from, to time.Time) []*tradingstate.Trade {
+	return []*tradingstate.Trade{}
+}
+
 func (db *BatchDatabase) InitBulk() {
 }
 
@@ -522,6 +522,26 @@ func (db *MongoDatabase) GetListItemByTxHash(txhash common.Hash, val interface{}
 	return nil
 }
 
+// GetTradesByExchange returns trades in which the given relayer is taker exchange or maker exchange
+// and which are created in [from, to)
+func (db *MongoDatabase) GetTradesByExchange(exchange common.Address, from, to time.Time) []*tradingstate.Trade {
+	sc := db.Session.Copy()
+	defer sc.Close()
+
+	query := bson.M{
+		"$or": []bson.M{
+			{"takerExchange": exchange.Hex()},
+			{"makerExchange": exchange.Hex()},
+		},
+		"createdAt": bson.M{"$gte": from, "$lt": to},
+	}
+	result := []*tradingstate.Trade{}
+	if err := sc.DB(db.dbName).C(tradesCollection).Find(query).All(&result); err != nil && err != mgo.ErrNotFound {
+		log.Error("failed to GetTradesByExchange", "err", err, "exchange", exchange.Hex(), "from", from, "to", to)
+	}
+	return result
+}
+
 func (db *MongoDatabase) GetListItemByHashes(hashes []string, val interface{}) interface{} {
 	sc := db.Session.Copy()
 	defer sc.Close()
@@ -28,6 +28,23 @@ const (
 	Limit                      = "LO"
 )
 
+// reasons why a lendingItem ends up in the rejected list of ApplyOrder
+const (
+	RejectReasonInvalidOrder        = "INVALID_ORDER"
+	RejectReasonInvalidInterest     = "INVALID_INTEREST"
+	RejectReasonInvalidQuantity     = "INVALID_QUANTITY"
+	RejectReasonInsufficientBalance = "INSUFFICIENT_BALANCE"
+	RejectReasonRelayerFee          = "INSUFFICIENT_RELAYER_FEE"
+	RejectReasonQuantityTooSmall    = "QUANTITY_TOO_SMALL"
+	RejectReasonCollateralPrice     = "COLLATERAL_PRICE_UNAVAILABLE"
+	RejectReasonOrderNotFound       = "ORDER_NOT_FOUND"
+	RejectReasonCancelFailed        = "CANCEL_FAILED"
+	RejectReasonTopUpFailed         = "TOPUP_FAILED"
+	RejectReasonRepayFailed         = "REPAY_FAILED"
+	RejectReasonMatchingFailed      = "MATCHING_FAILED"
+	RejectReasonNoLiquidity         = "NO_LIQUIDITY"
+)
+
 var ValidInputLendingStatus = map[string]bool{
 	LendingStatusNew:       true,
 	LendingStatusCancelled: true,
@@ -76,6 +93,8 @@ type LendingItem struct {
 	LendingId       uint64         `bson:"lendingId" json:"lendingId"`
 	LendingTradeId  uint64         `bson:"tradeId" json:"tradeId"`
 	ExtraData       string         `bson:"extraData" json:"extraData"`
+	// RejectReason is filled by the matching engine, it is not a part of the lending trie
+	RejectReason string `bson:"rejectReason" json:"rejectReason,omitempty" rlp:"-"`
 }
 
 type LendingItemBSON struct {
@@ -100,6 +119,7 @@ type LendingItemBSON struct {
 	LendingId       string           `bson:"lendingId" json:"lendingId"`
 	LendingTradeId  string           `bson:"tradeId" json:"tradeId"`
 	ExtraData       string           `bson:"extraData" json:"extraData"`
+	RejectReason    string           `bson:"rejectReason,omitempty" json:"rejectReason,omitempty"`
 }
 
 func (l *LendingItem) GetBSON() (interface{}, error) {
@@ -123,6 +143,7 @@ func (l *LendingItem) GetBSON() (interface{}, error) {
 		LendingId:       strconv.FormatUint(l.LendingId, 10),
 		LendingTradeId:  strconv.FormatUint(l.LendingTradeId, 10),
 		ExtraData:       l.ExtraData,
+		RejectReason:    l.RejectReason,
 	}
 
 	if l.FilledAmount != nil {
@@ -191,10 +212,20 @@ func (l *LendingItem) SetBSON(raw bson.Raw) error {
 	}
 	l.LendingTradeId = uint64(lendingTradeId)
 	l.ExtraData = decoded.ExtraData
+	l.RejectReason = decoded.RejectReason
 	return nil
 }
 
-func (l *LendingItem) VerifyLendingItem(state *state.StateDB) error {
+// SetRejectReason keeps the first reason recorded for the lendingItem,
+// a generic fallback never overrides a more specific cause
+func (l *LendingItem) SetRejectReason(reason string) {
+	if l.RejectReason == "" {
+		l.RejectReason = reason
+	}
+}
+
+// VerifyLendingItem verify lendingItem, accepting the signature schemes of the signer
+func (l *LendingItem) VerifyLendingItem(state *state.StateDB, signer types.LendingSigner) error {
 	if err := l.VerifyLendingStatus(); err != nil {
 		return err
 	}
@@ -229,7 +260,7 @@ func (l *LendingItem) VerifyLendingItem(state *state.StateDB) error {
 	if !IsValidRelayer(state, l.Relayer) {
 		return fmt.Errorf("VerifyLendingItem: invalid relayer. address: %s", l.Relayer.Hex())
 	}
-	if err := l.VerifyLendingSignature(); err != nil {
+	if err := l.VerifyLendingSignature(signer); err != nil {
 		return err
 	}
 	return nil
@@ -330,7 +361,7 @@ func (l *LendingItem) EncodedSide() *big.Int {
 }
 
 //verify signatures
-func (l *LendingItem) VerifyLendingSignature() error {
+func (l *LendingItem) VerifyLendingSignature(signer types.LendingSigner) error {
 	V := big.NewInt(int64(l.Signature.V))
 	R := l.Signature.R.Big()
 	S := l.Signature.S.Big()
@@ -339,7 +370,7 @@ func (l *LendingItem) VerifyLendingSignature() error {
 	tx := types.NewLendingTransaction(l.Nonce.Uint64(), l.Quantity, l.Interest.Uint64(), l.Term, l.Relayer, l.UserAddress,
 		l.LendingToken, l.CollateralToken, l.AutoTopUp, l.Status, l.Side, l.Type, l.Hash, l.LendingId, l.LendingTradeId, l.ExtraData)
 	tx.ImportSignature(V, R, S)
-	from, _ := types.LendingSender(types.LendingTxSigner{}, tx)
+	from, _ := types.LendingSender(signer, tx)
 	if from != tx.UserAddress() {
 		return fmt.Errorf("verify lending item: invalid signature")
 	}
@@ -1,12 +1,14 @@
 package lendingstate
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/69th-byte/sdexchain/common"
 	"github.com/69th-byte/sdexchain/core/rawdb"
 	"github.com/69th-byte/sdexchain/core/state"
 	"github.com/69th-byte/sdexchain/crypto"
 	"github.com/69th-byte/sdexchain/crypto/sha3"
+	"github.com/69th-byte/sdexchain/rlp"
 	"github.com/69th-byte/sdexchain/rpc"
 	"math/big"
 	"math/rand"
@@ -573,3 +575,36 @@ func computeHash(l *LendingOrderMsg) common.Hash {
 	return common.BytesToHash(sha.Sum(nil))
 
 }
+
+// RejectReason must neither change the lending trie nor the payload of lending txs
+func TestLendingItem_RejectReasonNotEncoded(t *testing.T) {
+	item := &LendingItem{
+		Quantity: big.NewInt(100),
+		Interest: big.NewInt(10),
+		Status:   LendingStatusNew,
+		Nonce:    big.NewInt(1),
+	}
+	encoded, err := rlp.EncodeToBytes(item)
+	if err != nil {
+		t.Fatal("Failed to encode", err)
+	}
+	batch, err := EncodeTxLendingBatch(TxLendingBatch{Data: []*LendingItem{item}})
+	if err != nil {
+		t.Fatal("Failed to encode batch", err)
+	}
+	if bytes.Contains(batch, []byte("rejectReason")) {
+		t.Error("empty RejectReason changes the payload of lending txs")
+	}
+	item.SetRejectReason(RejectReasonCollateralPrice)
+	item.SetRejectReason(RejectReasonInsufficientBalance)
+	if item.RejectReason != RejectReasonCollateralPrice {
+		t.Errorf("RejectReason overridden. Got: %s. Expected: %s", item.RejectReason, RejectReasonCollateralPrice)
+	}
+	encodedWithReason, err := rlp.EncodeToBytes(item)
+	if err != nil {
+		t.Fatal("Failed to encode", err)
+	}
+	if !bytes.Equal(encoded, encodedWithReason) {
+		t.Error("RejectReason changes rlp encoding of lendingItem")
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package lendingstate
+
+import (
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/ethdb"
+	"github.com/69th-byte/sdexchain/rlp"
+	"github.com/69th-byte/sdexchain/trie"
+)
+
+// NewStateSync creates a new lending state trie download scheduler. Besides the
+// lending book trie itself it schedules the investing, borrowing, liquidation
+// time, lending item and lending trade tries of every lending book, along with
+// the item list tries referenced from their leaves.
+func NewStateSync(root common.Hash, database ethdb.KeyValueReader, bloom *trie.SyncBloom) *trie.Sync {
+	var syncer *trie.Sync
+
+	// addSubTrie schedules a nested trie, skipping the roots of the tries which
+	// were never written.
+	addSubTrie := func(root common.Hash, parent common.Hash, callback trie.LeafCallback) {
+		if root != (common.Hash{}) {
+			syncer.AddSubTrie(root, 64, parent, callback)
+		}
+	}
+	itemLists := func(leaf []byte, parent common.Hash) error {
+		var obj itemList
+		if err := rlp.DecodeBytes(leaf, &obj); err != nil {
+			return err
+		}
+		addSubTrie(obj.Root, parent, nil)
+		return nil
+	}
+	callback := func(leaf []byte, parent common.Hash) error {
+		var obj lendingObject
+		if err := rlp.DecodeBytes(leaf, &obj); err != nil {
+			return err
+		}
+		addSubTrie(obj.InvestingRoot, parent, itemLists)
+		addSubTrie(obj.BorrowingRoot, parent, itemLists)
+		addSubTrie(obj.LiquidationTimeRoot, parent, itemLists)
+		addSubTrie(obj.LendingItemRoot, parent, nil)
+		addSubTrie(obj.LendingTradeRoot, parent, nil)
+		return nil
+	}
+	syncer = trie.NewSync(root, database, callback, bloom)
+	return syncer
+}
@@ -0,0 +1,96 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package lendingstate
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/core/rawdb"
+	"github.com/69th-byte/sdexchain/ethdb/memorydb"
+	"github.com/69th-byte/sdexchain/trie"
+)
+
+// Tests that a lending state is synced along with all its nested investing,
+// borrowing, liquidation time, lending item and lending trade tries.
+func TestLendingStateSync(t *testing.T) {
+	srcDb := rawdb.NewMemoryDatabase()
+	stateCache := NewDatabase(srcDb)
+	statedb, _ := New(common.Hash{}, stateCache)
+
+	lendingBook := common.StringToHash("USDT/30")
+	for i := 0; i < 32; i++ {
+		id := common.BigToHash(big.NewInt(int64(i) + 1))
+		side := Investing
+		if i%2 == 1 {
+			side = Borrowing
+		}
+		statedb.InsertLendingItem(lendingBook, id, LendingItem{LendingId: uint64(i) + 1, Quantity: big.NewInt(int64(i) + 1), Interest: big.NewInt(int64(i%8) + 1), Side: side, Signature: &Signature{V: 1, R: id, S: id}})
+		statedb.InsertLiquidationTime(lendingBook, big.NewInt(int64(i%4)), uint64(i))
+		statedb.InsertTradingItem(lendingBook, uint64(i), LendingTrade{TradeId: uint64(i), Amount: big.NewInt(int64(i))})
+	}
+	root, err := statedb.Commit()
+	if err != nil {
+		t.Fatalf("failed to commit lending state: %v", err)
+	}
+	if err := stateCache.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to flush lending state: %v", err)
+	}
+	// Sync the state into an empty database
+	dstDb := rawdb.NewMemoryDatabase()
+	sched := NewStateSync(root, dstDb, trie.NewSyncBloom(1, memorydb.New()))
+
+	queue := append([]common.Hash{}, sched.Missing(100)...)
+	for len(queue) > 0 {
+		results := make([]trie.SyncResult, len(queue))
+		for i, hash := range queue {
+			data, err := srcDb.Get(hash[:])
+			if err != nil {
+				t.Fatalf("failed to retrieve node data for %x", hash)
+			}
+			results[i] = trie.SyncResult{Hash: hash, Data: data}
+		}
+		if _, index, err := sched.Process(results); err != nil {
+			t.Fatalf("failed to process result #%d: %v", index, err)
+		}
+		batch := dstDb.NewBatch()
+		if err := sched.Commit(batch); err != nil {
+			t.Fatalf("failed to commit data: %v", err)
+		}
+		batch.Write()
+		queue = append(queue[:0], sched.Missing(100)...)
+	}
+	// Every node of the source database belongs to the state, all must be synced
+	it := srcDb.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		if len(it.Key()) != common.HashLength {
+			continue // Skip the preimages of the secure tries
+		}
+		if has, _ := dstDb.Has(it.Key()); !has {
+			t.Errorf("trie node %x not synced", it.Key())
+		}
+	}
+	synced, err := New(root, NewDatabase(dstDb))
+	if err != nil {
+		t.Fatalf("failed to open synced state: %v", err)
+	}
+	if rate, _ := synced.GetBestInvestingRate(lendingBook); rate.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("best investing rate mismatch: have %v, want 1", rate)
+	}
+}
@@ -27,7 +27,23 @@ func (l *Lending) CommitOrder(header *types.Header, coinbase common.Address, cha
 	return trades, rejects, err
 }
 
+// ApplyOrder applies a lending order to the lending book, reporting the matching
+// steps to the tracer attached to tradingStateDb if any.
 func (l *Lending) ApplyOrder(header *types.Header, coinbase common.Address, chain consensus.ChainContext, statedb *state.StateDB, lendingStateDB *lendingstate.LendingStateDB, tradingStateDb *tradingstate.TradingStateDB, lendingOrderBook common.Hash, order *lendingstate.LendingItem) ([]*lendingstate.LendingTrade, []*lendingstate.LendingItem, error) {
+	tracer := tradingStateDb.MatchingTracer()
+	if tracer == nil {
+		return l.applyOrder(header, coinbase, chain, statedb, lendingStateDB, tradingStateDb, lendingOrderBook, order)
+	}
+	tracer.CaptureOrderStart(tradingstate.LendingEngine, lendingOrderBook, order.Hash, order.UserAddress, order.Side, order.Type, order.Interest, order.Quantity)
+	trades, rejects, err := l.applyOrder(header, coinbase, chain, statedb, lendingStateDB, tradingStateDb, lendingOrderBook, order)
+	for _, reject := range rejects {
+		tracer.CaptureReject(reject.Hash, reject.RejectReason)
+	}
+	tracer.CaptureOrderEnd(err)
+	return trades, rejects, err
+}
+
+func (l *Lending) applyOrder(header *types.Header, coinbase common.Address, chain consensus.ChainContext, statedb *state.StateDB, lendingStateDB *lendingstate.LendingStateDB, tradingStateDb *tradingstate.TradingStateDB, lendingOrderBook common.Hash, order *lendingstate.LendingItem) ([]*lendingstate.LendingTrade, []*lendingstate.LendingItem, error) {
 	var (
 		rejects []*lendingstate.LendingItem
 		trades  []*lendingstate.LendingTrade
@@ -56,8 +72,9 @@ func (l *Lending) ApplyOrder(header *types.Header, coinbase common.Address, chai
 		}
 	}()
 
-	if err := order.VerifyLendingItem(statedb); err != nil {
+	if err := order.VerifyLendingItem(statedb, types.MakeLendingSigner(chain.Config(), header.Number)); err != nil {
 		log.Debug("invalid lending order", "order", lendingstate.ToJSON(order), "err", err)
+		order.SetRejectReason(lendingstate.RejectReasonInvalidOrder)
 		rejects = append(rejects, order)
 		return trades, rejects, nil
 	}
@@ -66,6 +83,7 @@ func (l *Lending) ApplyOrder(header *types.Header, coinbase common.Address, chai
 	case lendingstate.TopUp:
 		err, reject, newLendingTrade := l.ProcessTopUp(lendingStateDB, statedb, tradingStateDb, order)
 		if err != nil || reject {
+			order.SetRejectReason(lendingstate.RejectReasonTopUpFailed)
 			rejects = append(rejects, order)
 		}
 		trades = append(trades, newLendingTrade)
@@ -74,6 +92,7 @@ func (l *Lending) ApplyOrder(header *types.Header, coinbase common.Address, chai
 		lendingTrade, err := l.ProcessRepay(header, chain, lendingStateDB, statedb, tradingStateDb, lendingOrderBook, order)
 		if err != nil {
 			log.Debug("Can not process payment", "err", err)
+			order.SetRejectReason(lendingstate.RejectReasonRepayFailed)
 			rejects = append(rejects, order)
 		}
 		trades = append(trades, lendingTrade)
@@ -84,6 +103,7 @@ func (l *Lending) ApplyOrder(header *types.Header, coinbase common.Address, chai
 	if order.Status == lendingstate.LendingStatusCancelled {
 		err, reject := l.ProcessCancelOrder(header, lendingStateDB, statedb, tradingStateDb, chain, coinbase, lendingOrderBook, order)
 		if err != nil || reject {
+			order.SetRejectReason(lendingstate.RejectReasonCancelFailed)
 			rejects = append(rejects, order)
 		}
 		return trades, rejects, nil
@@ -92,12 +112,14 @@ func (l *Lending) ApplyOrder(header *types.Header, coinbase common.Address, chai
 	if order.Type != lendingstate.Market {
 		if order.Interest.Sign() == 0 || common.BigToHash(order.Interest).Big().Cmp(order.Interest) != 0 {
 			log.Debug("Reject order Interest invalid", "Interest", order.Interest)
+			order.SetRejectReason(lendingstate.RejectReasonInvalidInterest)
 			rejects = append(rejects, order)
 			return trades, rejects, nil
 		}
 	}
 	if order.Quantity.Sign() == 0 || common.BigToHash(order.Quantity).Big().Cmp(order.Quantity) != 0 {
 		log.Debug("Reject order quantity invalid", "quantity", order.Quantity)
+		order.SetRejectReason(lendingstate.RejectReasonInvalidQuantity)
 		rejects = append(rejects, order)
 		return trades, rejects, nil
 	}
@@ -108,6 +130,7 @@ func (l *Lending) ApplyOrder(header *types.Header, coinbase common.Address, chai
 		trades, rejects, err = l.processMarketOrder(header, coinbase, chain, statedb, lendingStateDB, tradingStateDb, lendingOrderBook, order)
 		if err != nil {
 			trades = []*lendingstate.LendingTrade{}
+			order.SetRejectReason(lendingstate.RejectReasonMatchingFailed)
 			rejects = append(rejects, order)
 		}
 	} else {
@@ -115,6 +138,7 @@ func (l *Lending) ApplyOrder(header *types.Header, coinbase common.Address, chai
 		trades, rejects, err = l.processLimitOrder(header, coinbase, chain, statedb, lendingStateDB, tradingStateDb, lendingOrderBook, order)
 		if err != nil {
 			trades = []*lendingstate.LendingTrade{}
+			order.SetRejectReason(lendingstate.RejectReasonMatchingFailed)
 			rejects = append(rejects, order)
 		}
 	}
@@ -234,6 +258,10 @@ func (l *Lending) processOrderList(header *types.Header, coinbase common.Address
 		trades  []*lendingstate.LendingTrade
 		rejects []*lendingstate.LendingItem
 	)
+	tracer := tradingStateDb.MatchingTracer()
+	if tracer != nil {
+		tracer.CaptureLevel(side, Interest, quantityToTrade)
+	}
 	for quantityToTrade.Sign() > 0 {
 		orderId, amount, err := lendingStateDB.GetBestLendingIdAndAmount(lendingOrderBook, Interest, side)
 		if err != nil {
@@ -287,12 +315,14 @@ func (l *Lending) processOrderList(header *types.Header, coinbase common.Address
 		if collateralPrice == nil || collateralPrice.Sign() <= 0 {
 			return nil, nil, nil, fmt.Errorf("invalid collateral price")
 		}
-		tradedQuantity, collateralLockedAmount, rejectMaker, settleBalanceResult, err := l.getLendQuantity(lendTokenTOMOPrice, collateralPrice, depositRate, borrowFee, coinbase, chain, header, statedb, order, &oldestOrder, maxTradedQuantity)
+		tradedQuantity, collateralLockedAmount, rejectMaker, settleBalanceResult, err := l.getLendQuantity(lendTokenTOMOPrice, collateralPrice, depositRate, borrowFee, coinbase, chain, header, statedb, tracer, order, &oldestOrder, maxTradedQuantity)
 		if err != nil && err == lendingstate.ErrQuantityTradeTooSmall && tradedQuantity != nil && tradedQuantity.Sign() >= 0 {
 			if tradedQuantity.Cmp(maxTradedQuantity) == 0 {
 				if quantityToTrade.Cmp(amount) == 0 { // reject Taker & maker
+					order.SetRejectReason(lendingstate.RejectReasonQuantityTooSmall)
 					rejects = append(rejects, order)
 					quantityToTrade = lendingstate.Zero
+					oldestOrder.SetRejectReason(lendingstate.RejectReasonQuantityTooSmall)
 					rejects = append(rejects, &oldestOrder)
 					err = lendingStateDB.CancelLendingOrder(lendingOrderBook, &oldestOrder)
 					log.Debug("Reject order maker", "lending id ", oldestOrder.LendingId, "err", err)
@@ -301,10 +331,12 @@ func (l *Lending) processOrderList(header *types.Header, coinbase common.Address
 					}
 					break
 				} else if quantityToTrade.Cmp(amount) < 0 { // reject Taker
+					order.SetRejectReason(lendingstate.RejectReasonQuantityTooSmall)
 					rejects = append(rejects, order)
 					quantityToTrade = lendingstate.Zero
 					break
 				} else { // reject maker
+					oldestOrder.SetRejectReason(lendingstate.RejectReasonQuantityTooSmall)
 					rejects = append(rejects, &oldestOrder)
 					err = lendingStateDB.CancelLendingOrder(lendingOrderBook, &oldestOrder)
 					log.Debug("Reject order maker", "lending id ", oldestOrder.LendingId, "err", err)
@@ -315,6 +347,7 @@ func (l *Lending) processOrderList(header *types.Header, coinbase common.Address
 				}
 			} else {
 				if rejectMaker { // reject maker
+					oldestOrder.SetRejectReason(lendingstate.RejectReasonQuantityTooSmall)
 					rejects = append(rejects, &oldestOrder)
 					err = lendingStateDB.CancelLendingOrder(lendingOrderBook, &oldestOrder)
 					log.Debug("Reject order maker", "lending id ", oldestOrder.LendingId, "err", err)
@@ -323,6 +356,7 @@ func (l *Lending) processOrderList(header *types.Header, coinbase common.Address
 					}
 					continue
 				} else { // reject Taker
+					order.SetRejectReason(lendingstate.RejectReasonQuantityTooSmall)
 					rejects = append(rejects, order)
 					quantityToTrade = lendingstate.Zero
 					break
@@ -333,6 +367,7 @@ func (l *Lending) processOrderList(header *types.Header, coinbase common.Address
 		}
 		if tradedQuantity.Sign() == 0 && !rejectMaker {
 			log.Debug("Reject order Taker ", "tradedQuantity", tradedQuantity, "rejectMaker", rejectMaker)
+			order.SetRejectReason(lendingstate.RejectReasonInsufficientBalance)
 			rejects = append(rejects, order)
 			quantityToTrade = lendingstate.Zero
 			break
@@ -405,8 +440,12 @@ func (l *Lending) processOrderList(header *types.Header, coinbase common.Address
 			log.Debug("InsertLiquidationPrice", "TradingOrderBookHash", tradingstate.GetTradingOrderBookHash(collateralToken, order.LendingToken).Hex(), "tradingId", tradingId, "lendingOrderBook", lendingOrderBook.Hex(), "liquidationPrice", liquidationPrice)
 			tradingStateDb.InsertLiquidationPrice(tradingstate.GetTradingOrderBookHash(collateralToken, order.LendingToken), liquidationPrice, lendingOrderBook, tradingId)
 			trades = append(trades, &lendingTrade)
+			if tracer != nil {
+				tracer.CaptureTrade(oldestOrder.Hash, oldestOrder.UserAddress, oldestOrder.Interest, tradedQuantity)
+			}
 		}
 		if rejectMaker {
+			oldestOrder.SetRejectReason(lendingstate.RejectReasonInsufficientBalance)
 			rejects = append(rejects, &oldestOrder)
 			err := lendingStateDB.CancelLendingOrder(lendingOrderBook, &oldestOrder)
 			if err != nil {
@@ -422,13 +461,15 @@ func (l *Lending) getLendQuantity(
 	collateralPrice,
 	depositRate,
 	borrowFee *big.Int,
-	coinbase common.Address, chain consensus.ChainContext, header *types.Header, statedb *state.StateDB, takerOrder *lendingstate.LendingItem, makerOrder *lendingstate.LendingItem, quantityToTrade *big.Int) (*big.Int, *big.Int, bool, *lendingstate.LendingSettleBalance, error) {
+	coinbase common.Address, chain consensus.ChainContext, header *types.Header, statedb *state.StateDB, tracer tradingstate.MatchingTracer, takerOrder *lendingstate.LendingItem, makerOrder *lendingstate.LendingItem, quantityToTrade *big.Int) (*big.Int, *big.Int, bool, *lendingstate.LendingSettleBalance, error) {
 	if collateralPrice == nil || collateralPrice.Sign() == 0 {
 		if takerOrder.Side == lendingstate.Borrowing {
 			log.Debug("Reject lending order taker , can not found  collateral price ")
+			takerOrder.SetRejectReason(lendingstate.RejectReasonCollateralPrice)
 			return lendingstate.Zero, lendingstate.Zero, false, nil, nil
 		} else {
 			log.Debug("Reject lending order maker , can not found  collateral price ")
+			makerOrder.SetRejectReason(lendingstate.RejectReasonCollateralPrice)
 			return lendingstate.Zero, lendingstate.Zero, true, nil, nil
 		}
 	}
@@ -447,15 +488,18 @@ func (l *Lending) getLendQuantity(
 	if takerOrder.Relayer.String() == makerOrder.Relayer.String() {
 		if err := lendingstate.CheckRelayerFee(takerOrder.Relayer, new(big.Int).Mul(common.RelayerLendingFee, big.NewInt(2)), statedb); err != nil {
 			log.Debug("Reject order Taker Exchnage = Maker Exchange , relayer not enough fee ", "err", err)
+			takerOrder.SetRejectReason(lendingstate.RejectReasonRelayerFee)
 			return lendingstate.Zero, lendingstate.Zero, false, nil, nil
 		}
 	} else {
 		if err := lendingstate.CheckRelayerFee(takerOrder.Relayer, common.RelayerLendingFee, statedb); err != nil {
 			log.Debug("Reject order Taker , relayer not enough fee ", "err", err)
+			takerOrder.SetRejectReason(lendingstate.RejectReasonRelayerFee)
 			return lendingstate.Zero, lendingstate.Zero, false, nil, nil
 		}
 		if err := lendingstate.CheckRelayerFee(makerOrder.Relayer, common.RelayerLendingFee, statedb); err != nil {
 			log.Debug("Reject order maker , relayer not enough fee ", "err", err)
+			makerOrder.SetRejectReason(lendingstate.RejectReasonRelayerFee)
 			return lendingstate.Zero, lendingstate.Zero, true, nil, nil
 		}
 	}
@@ -482,7 +526,7 @@ func (l *Lending) getLendQuantity(
 		settleBalanceResult, err := lendingstate.GetSettleBalance(isTomoXLendingFork, takerOrder.Side, lendTokenTOMOPrice, collateralPrice, depositRate, borrowFee, lendToken, collateralToken, LendingTokenDecimal, collateralTokenDecimal, quantity)
 		log.Debug("GetSettleBalance", "settleBalanceResult", settleBalanceResult, "err", err)
 		if err == nil {
-			err = DoSettleBalance(coinbase, takerOrder, makerOrder, settleBalanceResult, statedb)
+			err = DoSettleBalance(coinbase, takerOrder, makerOrder, settleBalanceResult, statedb, tracer)
 		}
 		if err != nil {
 			return quantity, lendingstate.Zero, rejectMaker, nil, err
@@ -570,7 +614,9 @@ func GetLendQuantity(takerSide string, collateralTokenDecimal *big.Int, depositR
 	}
 }
 
-func DoSettleBalance(coinbase common.Address, takerOrder, makerOrder *lendingstate.LendingItem, settleBalance *lendingstate.LendingSettleBalance, statedb *state.StateDB) error {
+// DoSettleBalance moves the lent tokens and the collateral of a lending trade and
+// pays the relayer and masternode fees. The tracer may be nil.
+func DoSettleBalance(coinbase common.Address, takerOrder, makerOrder *lendingstate.LendingItem, settleBalance *lendingstate.LendingSettleBalance, statedb *state.StateDB, tracer tradingstate.MatchingTracer) error {
 	takerExOwner := lendingstate.GetRelayerOwner(takerOrder.Relayer, statedb)
 	makerExOwner := lendingstate.GetRelayerOwner(makerOrder.Relayer, statedb)
 	matchingFee := big.NewInt(0)
@@ -672,12 +718,31 @@ func DoSettleBalance(coinbase common.Address, takerOrder, makerOrder *lendingsta
 			lendingstate.SetTokenBalance(adrr, value, token, statedb)
 		}
 	}
+	if tracer != nil {
+		lockAddress := common.HexToAddress(common.LendingLockAddress)
+		if takerOrder.Side == lendingstate.Borrowing {
+			tracer.CaptureBalance(takerOrder.UserAddress, settleBalance.Taker.InToken, settleBalance.Taker.InTotal)
+			tracer.CaptureBalance(takerOrder.UserAddress, settleBalance.Taker.OutToken, new(big.Int).Neg(settleBalance.Taker.OutTotal))
+			tracer.CaptureBalance(makerOrder.UserAddress, settleBalance.Maker.OutToken, new(big.Int).Neg(settleBalance.Maker.OutTotal))
+			tracer.CaptureBalance(lockAddress, settleBalance.Taker.OutToken, settleBalance.Taker.OutTotal)
+			tracer.CaptureFee(takerOrder.UserAddress, takerExOwner, settleBalance.Taker.InToken, settleBalance.Taker.Fee)
+			tracer.CaptureFee(takerOrder.Relayer, masternodeOwner, common.HexToAddress(common.TomoNativeAddress), matchingFee)
+		} else {
+			tracer.CaptureBalance(takerOrder.UserAddress, settleBalance.Taker.OutToken, new(big.Int).Neg(settleBalance.Taker.OutTotal))
+			tracer.CaptureBalance(makerOrder.UserAddress, settleBalance.Maker.InToken, settleBalance.Maker.InTotal)
+			tracer.CaptureBalance(makerOrder.UserAddress, settleBalance.Maker.OutToken, new(big.Int).Neg(settleBalance.Maker.OutTotal))
+			tracer.CaptureBalance(lockAddress, settleBalance.Maker.OutToken, settleBalance.Maker.OutTotal)
+			tracer.CaptureFee(makerOrder.UserAddress, makerExOwner, settleBalance.Maker.InToken, settleBalance.Maker.Fee)
+			tracer.CaptureFee(makerOrder.Relayer, masternodeOwner, common.HexToAddress(common.TomoNativeAddress), matchingFee)
+		}
+	}
 	return nil
 }
 
 func (l *Lending) ProcessCancelOrder(header *types.Header, lendingStateDB *lendingstate.LendingStateDB, statedb *state.StateDB, tradingStateDb *tradingstate.TradingStateDB, chain consensus.ChainContext, coinbase common.Address, lendingOrderBook common.Hash, order *lendingstate.LendingItem) (error, bool) {
 	originOrder := lendingStateDB.GetLendingOrder(lendingOrderBook, common.BigToHash(new(big.Int).SetUint64(order.LendingId)))
 	if originOrder == lendingstate.EmptyLendingOrder {
+		order.SetRejectReason(lendingstate.RejectReasonOrderNotFound)
 		return fmt.Errorf("lendingOrder not found. Id: %v. LendToken: %s . Term: %v. CollateralToken: %v", order.LendingId, order.LendingToken.Hex(), order.Term, order.CollateralToken.Hex()), false
 	}
 	if originOrder.Hash != order.Hash {
@@ -688,6 +753,7 @@ func (l *Lending) ProcessCancelOrder(header *types.Header, lendingStateDB *lendi
 	}
 	if err := lendingstate.CheckRelayerFee(originOrder.Relayer, common.RelayerLendingCancelFee, statedb); err != nil {
 		log.Debug("Relayer not enough fee when cancel order", "err", err)
+		order.SetRejectReason(lendingstate.RejectReasonRelayerFee)
 		return nil, true
 	}
 	lendTokenDecimal, err := l.tomox.GetTokenDecimal(chain, statedb, originOrder.LendingToken)
@@ -729,6 +795,7 @@ func (l *Lending) ProcessCancelOrder(header *types.Header, lendingStateDB *lendi
 
 	if tokenBalance.Cmp(tokenCancelFee) < 0 {
 		log.Debug("User not enough balance when cancel order", "Side", originOrder.Side, "Interest", originOrder.Interest, "Quantity", originOrder.Quantity, "balance", tokenBalance, "fee", tokenCancelFee)
+		order.SetRejectReason(lendingstate.RejectReasonInsufficientBalance)
 		return nil, true
 	}
 	err = lendingStateDB.CancelLendingOrder(lendingOrderBook, &originOrder)
@@ -752,6 +819,14 @@ func (l *Lending) ProcessCancelOrder(header *types.Header, lendingStateDB *lendi
 		lendingstate.AddTokenBalance(relayerOwner, tokenCancelFee, originOrder.CollateralToken, statedb)
 	default:
 	}
+	if tracer := tradingStateDb.MatchingTracer(); tracer != nil {
+		feeToken := originOrder.LendingToken
+		if originOrder.Side == lendingstate.Borrowing {
+			feeToken = originOrder.CollateralToken
+		}
+		tracer.CaptureFee(originOrder.Relayer, masternodeOwner, common.HexToAddress(common.TomoNativeAddress), common.RelayerLendingCancelFee)
+		tracer.CaptureFee(originOrder.UserAddress, relayerOwner, feeToken, tokenCancelFee)
+	}
 	extraData, _ := json.Marshal(struct {
 		CancelFee        string
 		TokenPriceInTOMO string
@@ -953,6 +1028,17 @@ func (l *Lending) GetMediumTradePriceBeforeEpoch(chain consensus.ChainContext, s
 	return nil, nil
 }
 
+// GetTWAPCollateralPrice returns the time-weighted average price of the pair collateralToken/lendingToken
+// over common.CollateralPriceTWAPWindow, nil if the price oracle of the pair doesn't cover the window
+func GetTWAPCollateralPrice(header *types.Header, tradingStateDb *tradingstate.TradingStateDB, collateralToken common.Address, lendingToken common.Address) *big.Int {
+	oracle := tradingStateDb.GetPriceOracle(tradingstate.GetTradingOrderBookHash(collateralToken, lendingToken))
+	result, err := oracle.GetTWAP(header.Time.Uint64(), common.CollateralPriceTWAPWindow)
+	if err != nil || result.Window < common.CollateralPriceTWAPWindow || result.TWAP.Sign() <= 0 {
+		return nil
+	}
+	return result.TWAP
+}
+
 //LendToken and CollateralToken must meet at least one of following conditions
 //- Have direct pair in TomoX: lendToken/CollateralToken or CollateralToken/LendToken
 //- Have pairs with TOMO:
@@ -993,6 +1079,14 @@ func (l *Lending) GetCollateralPrices(header *types.Header, chain consensus.Chai
 		collateralPrice = new(big.Int).Div(collateralPrice, inverseCollateralPriceFromContract)
 		return lendTokenTOMOPrice, collateralPrice, nil
 	}
+	// since the price oracle fork, the time-weighted average price of the direct pair is preferred
+	// because it's harder to manipulate than the average price of the last epoch
+	if chain.Config().IsTIPTomoXPriceOracle(header.Number) {
+		if twap := GetTWAPCollateralPrice(header, tradingStateDb, collateralToken, lendingToken); twap != nil {
+			log.Debug("Getting collateral/lending from price oracle in tomox", "lendToken", lendingToken.Hex(), "collateralToken", collateralToken.Hex(), "price", twap)
+			return lendTokenTOMOPrice, twap, nil
+		}
+	}
 	// if contract doesn't provide any price information
 	// getting price from pair in tomox
 	lastAveragePrice, err := l.GetMediumTradePriceBeforeEpoch(chain, statedb, tradingStateDb, collateralToken, lendingToken)
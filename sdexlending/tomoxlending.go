@@ -19,6 +19,7 @@ import (
 	"github.com/69th-byte/sdexchain/common"
 	"github.com/69th-byte/sdexchain/core/state"
 	"github.com/69th-byte/sdexchain/log"
+	"github.com/69th-byte/sdexchain/metrics"
 	"github.com/69th-byte/sdexchain/rpc"
 	lru "github.com/hashicorp/golang-lru"
 )
@@ -35,6 +36,10 @@ var (
 	ErrNonceTooLow  = errors.New("nonce too low")
 )
 
+// matchingTimer measures the time spent matching the pending lending orders of
+// a block.
+var matchingTimer = metrics.NewRegisteredTimer("lending/matching", nil)
+
 type Lending struct {
 	Triegc     *prque.Prque          // Priority queue mapping block numbers to tries to gc
 	StateCache lendingstate.Database // State database to reuse between imports (contains state cache)    *lendingstate.TradingStateDB
@@ -44,6 +49,10 @@ type Lending struct {
 	tomox               *tomox.TomoX
 	lendingItemHistory  *lru.Cache
 	lendingTradeHistory *lru.Cache
+
+	// openState opens the lending states of light clients, which keep no
+	// lending database of their own
+	openState func(block *types.Block, author common.Address) (*lendingstate.LendingStateDB, error)
 }
 
 func (l *Lending) Protocols() []p2p.Protocol {
@@ -75,6 +84,21 @@ func New(tomox *tomox.TomoX) *Lending {
 	return lending
 }
 
+// NewLight creates a lending instance for light clients. It keeps no lending
+// database, the lending states are opened by openState instead.
+func NewLight(tomox *tomox.TomoX, openState func(block *types.Block, author common.Address) (*lendingstate.LendingStateDB, error)) *Lending {
+	itemCache, _ := lru.New(defaultCacheLimit)
+	lendingTradeCache, _ := lru.New(defaultCacheLimit)
+	return &Lending{
+		orderNonce:          make(map[common.Address]*big.Int),
+		Triegc:              prque.New(),
+		lendingItemHistory:  itemCache,
+		lendingTradeHistory: lendingTradeCache,
+		tomox:               tomox,
+		openState:           openState,
+	}
+}
+
 func (l *Lending) GetLevelDB() tomoxDAO.TomoXDAO {
 	return l.tomox.GetLevelDB()
 }
@@ -100,11 +124,46 @@ func (l *Lending) Version() uint64 {
 	return ProtocolVersion
 }
 
+// NewLendingItem converts a signed lending transaction into the lending item
+// the lending engine works on.
+func NewLendingItem(tx *types.LendingTransaction) (*lendingstate.LendingItem, error) {
+	V, R, S := tx.Signature()
+	v, err := strconv.ParseInt(V.String(), 10, 8)
+	if err != nil {
+		return nil, err
+	}
+	return &lendingstate.LendingItem{
+		Nonce:           big.NewInt(int64(tx.Nonce())),
+		Quantity:        tx.Quantity(),
+		Interest:        new(big.Int).SetUint64(tx.Interest()),
+		Relayer:         tx.RelayerAddress(),
+		Term:            tx.Term(),
+		UserAddress:     tx.UserAddress(),
+		LendingToken:    tx.LendingToken(),
+		CollateralToken: tx.CollateralToken(),
+		AutoTopUp:       tx.AutoTopUp(),
+		Status:          tx.Status(),
+		Side:            tx.Side(),
+		Type:            tx.Type(),
+		Hash:            tx.LendingHash(),
+		LendingId:       tx.LendingId(),
+		LendingTradeId:  tx.LendingTradeId(),
+		ExtraData:       tx.ExtraData(),
+		Signature: &lendingstate.Signature{
+			V: byte(v),
+			R: common.BigToHash(R),
+			S: common.BigToHash(S),
+		},
+	}, nil
+}
+
 func (l *Lending) ProcessOrderPending(header *types.Header, coinbase common.Address, chain consensus.ChainContext, pending map[common.Address]types.LendingTransactions, statedb *state.StateDB, lendingStatedb *lendingstate.LendingStateDB, tradingStateDb *tradingstate.TradingStateDB) ([]*lendingstate.LendingItem, map[common.Hash]lendingstate.MatchingResult) {
+	defer matchingTimer.UpdateSince(time.Now())
+
 	lendingItems := []*lendingstate.LendingItem{}
 	matchingResults := map[common.Hash]lendingstate.MatchingResult{}
 
-	txs := types.NewLendingTransactionByNonce(types.LendingTxSigner{}, pending)
+	txs := types.NewLendingTransactionByNonce(types.MakeLendingSigner(chain.Config(), header.Number), pending)
 	for {
 		tx := txs.Peek()
 		if tx == nil {
@@ -112,37 +171,10 @@ func (l *Lending) ProcessOrderPending(header *types.Header, coinbase common.Addr
 		}
 		log.Debug("ProcessOrderPending start", "len", len(pending))
 		log.Debug("Get pending orders to process", "address", tx.UserAddress(), "nonce", tx.Nonce())
-		V, R, S := tx.Signature()
-
-		bigstr := V.String()
-		n, e := strconv.ParseInt(bigstr, 10, 8)
-		if e != nil {
+		order, err := NewLendingItem(tx)
+		if err != nil {
 			continue
 		}
-
-		order := &lendingstate.LendingItem{
-			Nonce:           big.NewInt(int64(tx.Nonce())),
-			Quantity:        tx.Quantity(),
-			Interest:        new(big.Int).SetUint64(tx.Interest()),
-			Relayer:         tx.RelayerAddress(),
-			Term:            tx.Term(),
-			UserAddress:     tx.UserAddress(),
-			LendingToken:    tx.LendingToken(),
-			CollateralToken: tx.CollateralToken(),
-			AutoTopUp:       tx.AutoTopUp(),
-			Status:          tx.Status(),
-			Side:            tx.Side(),
-			Type:            tx.Type(),
-			Hash:            tx.LendingHash(),
-			LendingId:       tx.LendingId(),
-			LendingTradeId:  tx.LendingTradeId(),
-			ExtraData:       tx.ExtraData(),
-			Signature: &lendingstate.Signature{
-				V: byte(n),
-				R: common.BigToHash(R),
-				S: common.BigToHash(S),
-			},
-		}
 		cancel := false
 		if order.Status == lendingstate.LendingStatusCancelled {
 			cancel = true
@@ -355,6 +387,7 @@ func (l *Lending) SyncDataToSDKNode(chain consensus.ChainContext, statedb *state
 			updatedTakerLendingItem.Status = lendingstate.LendingStatusFilled
 		} else {
 			updatedTakerLendingItem.Status = lendingstate.LendingStatusReject
+			updatedTakerLendingItem.SetRejectReason(lendingstate.RejectReasonNoLiquidity)
 		}
 	}
 
@@ -408,9 +441,11 @@ func (l *Lending) SyncDataToSDKNode(chain consensus.ChainContext, statedb *state
 
 	if len(rejectedItems) > 0 {
 		var rejectedHashes []string
+		rejectReasons := make(map[string]string)
 		// updateRejectedOrders
 		for _, r := range rejectedItems {
 			rejectedHashes = append(rejectedHashes, r.Hash.Hex())
+			rejectReasons[r.Hash.Hex()] = r.RejectReason
 			if updatedTakerLendingItem.Hash == r.Hash && !txMatchTime.Before(r.UpdatedAt) {
 				// cache r history for handling reorg
 				historyRecord := lendingstate.LendingItemHistoryItem{
@@ -427,6 +462,7 @@ func (l *Lending) SyncDataToSDKNode(chain consensus.ChainContext, statedb *state
 				} else {
 					updatedTakerLendingItem.Status = lendingstate.LendingStatusReject
 				}
+				updatedTakerLendingItem.RejectReason = r.RejectReason
 				updatedTakerLendingItem.TxHash = txHash
 				updatedTakerLendingItem.UpdatedAt = txMatchTime
 				if err := db.PutObject(updatedTakerLendingItem.Hash, updatedTakerLendingItem); err != nil {
@@ -461,6 +497,7 @@ func (l *Lending) SyncDataToSDKNode(chain consensus.ChainContext, statedb *state
 				} else {
 					r.Status = lendingstate.LendingStatusReject
 				}
+				r.RejectReason = rejectReasons[r.Hash.Hex()]
 				r.TxHash = txHash
 				r.UpdatedAt = txMatchTime
 				if err = db.PutObject(r.Hash, r); err != nil {
@@ -668,6 +705,9 @@ func (l *Lending) UpdateLendingTrade(trades map[common.Hash]*lendingstate.Lendin
 }
 
 func (l *Lending) GetLendingState(block *types.Block, author common.Address) (*lendingstate.LendingStateDB, error) {
+	if l.openState != nil {
+		return l.openState(block, author)
+	}
 	root, err := l.GetLendingStateRoot(block, author)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,417 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// Package tomoxclient provides a client for the TomoX and TomoX lending RPC API.
+package tomoxclient
+
+import (
+	"context"
+	"math/big"
+	"sync"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/common/hexutil"
+	"github.com/69th-byte/sdexchain/core"
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/rlp"
+	"github.com/69th-byte/sdexchain/rpc"
+	"github.com/69th-byte/sdexchain/tomox"
+	"github.com/69th-byte/sdexchain/tomox/tradingstate"
+	"github.com/69th-byte/sdexchain/tomoxlending/lendingstate"
+)
+
+// Client defines typed wrappers for the TomoX RPC API.
+type Client struct {
+	c *rpc.Client
+
+	lock          sync.Mutex
+	orderNonces   map[common.Address]uint64 // next order nonce of accounts sent through this client
+	lendingNonces map[common.Address]uint64 // next lending nonce of accounts sent through this client
+}
+
+// Dial connects a client to the given URL.
+func Dial(rawurl string) (*Client, error) {
+	return DialContext(context.Background(), rawurl)
+}
+
+// DialContext connects a client to the given URL with the given context.
+func DialContext(ctx context.Context, rawurl string) (*Client, error) {
+	c, err := rpc.DialContext(ctx, rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(c), nil
+}
+
+// NewClient creates a client that uses the given RPC client.
+func NewClient(c *rpc.Client) *Client {
+	return &Client{
+		c:             c,
+		orderNonces:   make(map[common.Address]uint64),
+		lendingNonces: make(map[common.Address]uint64),
+	}
+}
+
+// Close closes the underlying RPC connection.
+func (tc *Client) Close() {
+	tc.c.Close()
+}
+
+// PriceVolume is the price and total volume of a price level of an order book.
+type PriceVolume struct {
+	Price  *big.Int `json:"price,omitempty"`
+	Volume *big.Int `json:"volume,omitempty"`
+}
+
+// InterestVolume is the interest and total volume of an interest level of a lending book.
+type InterestVolume struct {
+	Interest *big.Int `json:"interest,omitempty"`
+	Volume   *big.Int `json:"volume,omitempty"`
+}
+
+// PoolContent is the content of the order pool.
+type PoolContent struct {
+	Pending []*tradingstate.OrderItem `json:"pending"`
+	Queued  []*tradingstate.OrderItem `json:"queued"`
+}
+
+// PoolStats is the number of pending and queued orders of the order pool.
+type PoolStats struct {
+	Pending int `json:"pending"`
+	Queued  int `json:"queued"`
+}
+
+// RelayerPair is a trading pair listed by a relayer.
+type RelayerPair struct {
+	BaseToken  common.Address `json:"baseToken"`
+	QuoteToken common.Address `json:"quoteToken"`
+}
+
+// RelayerLendingTerm is a lending token and term listed by a relayer.
+type RelayerLendingTerm struct {
+	LendingToken common.Address `json:"lendingToken"`
+	Term         uint64         `json:"term"`
+}
+
+// RelayerPairVolume is the matched volume of a trading pair in an epoch.
+type RelayerPairVolume struct {
+	BaseToken  common.Address `json:"baseToken"`
+	QuoteToken common.Address `json:"quoteToken"`
+	Volume     *big.Int       `json:"volume"`
+	Trades     uint64         `json:"trades"`
+}
+
+// RelayerEpochStats is the fee income and volume of a relayer in an epoch.
+type RelayerEpochStats struct {
+	Epoch          uint64                      `json:"epoch"`
+	FromBlock      uint64                      `json:"fromBlock"`
+	ToBlock        uint64                      `json:"toBlock"`
+	FeeIncome      map[common.Address]*big.Int `json:"feeIncome"`
+	RelayerFeePaid *big.Int                    `json:"relayerFeePaid"`
+	Volumes        []*RelayerPairVolume        `json:"volumes"`
+}
+
+// RelayerInfo is the registration and fee accounting of a relayer.
+type RelayerInfo struct {
+	Relayer       common.Address       `json:"relayer"`
+	Owner         common.Address       `json:"owner"`
+	Deposit       *big.Int             `json:"deposit"`
+	LockedFund    *big.Int             `json:"lockedFund"`
+	RemainingFee  *big.Int             `json:"remainingFee"`
+	TradingFee    *big.Int             `json:"tradingFee"`
+	Resigned      bool                 `json:"resigned"`
+	Pairs         []RelayerPair        `json:"pairs"`
+	LendingFee    *big.Int             `json:"lendingFee"`
+	LendingTerms  []RelayerLendingTerm `json:"lendingTerms"`
+	LowFeeWarning string               `json:"lowFeeWarning,omitempty"`
+	Epochs        []*RelayerEpochStats `json:"epochs,omitempty"`
+}
+
+// Order pool
+
+// SendOrderTransaction injects a signed order transaction into the order pool for execution.
+func (tc *Client) SendOrderTransaction(ctx context.Context, tx *types.OrderTransaction) (common.Hash, error) {
+	data, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	var hash common.Hash
+	err = tc.c.CallContext(ctx, &hash, "tomox_sendOrderRawTransaction", common.ToHex(data))
+	return hash, err
+}
+
+// SendLendingTransaction injects a signed lending transaction into the lending pool for execution.
+func (tc *Client) SendLendingTransaction(ctx context.Context, tx *types.LendingTransaction) (common.Hash, error) {
+	data, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	var hash common.Hash
+	err = tc.c.CallContext(ctx, &hash, "tomox_sendLendingRawTransaction", common.ToHex(data))
+	return hash, err
+}
+
+// OrderCount returns the order nonce of the account in the trading state of the current block.
+func (tc *Client) OrderCount(ctx context.Context, account common.Address) (uint64, error) {
+	var result hexutil.Uint64
+	err := tc.c.CallContext(ctx, &result, "tomox_getOrderCount", account)
+	return uint64(result), err
+}
+
+// LendingOrderCount returns the lending nonce of the account in the lending state of the current block.
+func (tc *Client) LendingOrderCount(ctx context.Context, account common.Address) (uint64, error) {
+	var result hexutil.Uint64
+	err := tc.c.CallContext(ctx, &result, "tomox_getLendingOrderCount", account)
+	return uint64(result), err
+}
+
+// OrderPoolContent returns the pending and queued orders of the order pool.
+func (tc *Client) OrderPoolContent(ctx context.Context) (*PoolContent, error) {
+	var result PoolContent
+	err := tc.c.CallContext(ctx, &result, "tomox_getOrderPoolContent")
+	return &result, err
+}
+
+// OrderStats returns the number of pending and queued orders of the order pool.
+func (tc *Client) OrderStats(ctx context.Context) (*PoolStats, error) {
+	var result PoolStats
+	err := tc.c.CallContext(ctx, &result, "tomox_getOrderStats")
+	return &result, err
+}
+
+// OrderTxMatchByHash returns the orders matched by the trading transaction with the given hash.
+func (tc *Client) OrderTxMatchByHash(ctx context.Context, hash common.Hash) ([]*tradingstate.OrderItem, error) {
+	var result []*tradingstate.OrderItem
+	err := tc.c.CallContext(ctx, &result, "tomox_getOrderTxMatchByHash", hash)
+	return result, err
+}
+
+// LendingTxMatchByHash returns the lending items matched by the lending transaction with the given hash.
+func (tc *Client) LendingTxMatchByHash(ctx context.Context, hash common.Hash) ([]*lendingstate.LendingItem, error) {
+	var result []*lendingstate.LendingItem
+	err := tc.c.CallContext(ctx, &result, "tomox_getLendingTxMatchByHash", hash)
+	return result, err
+}
+
+// LiquidatedTradesByTxHash returns the lending trades finalized by the lending finalized transaction with the given hash.
+func (tc *Client) LiquidatedTradesByTxHash(ctx context.Context, hash common.Hash) (*lendingstate.FinalizedResult, error) {
+	var result lendingstate.FinalizedResult
+	err := tc.c.CallContext(ctx, &result, "tomox_getLiquidatedTradesByTxHash", hash)
+	return &result, err
+}
+
+// Trading books
+
+// BestBid returns the best bid of the order book of a pair.
+func (tc *Client) BestBid(ctx context.Context, baseToken, quoteToken common.Address) (*PriceVolume, error) {
+	var result PriceVolume
+	err := tc.c.CallContext(ctx, &result, "tomox_getBestBid", baseToken, quoteToken)
+	return &result, err
+}
+
+// BestAsk returns the best ask of the order book of a pair.
+func (tc *Client) BestAsk(ctx context.Context, baseToken, quoteToken common.Address) (*PriceVolume, error) {
+	var result PriceVolume
+	err := tc.c.CallContext(ctx, &result, "tomox_getBestAsk", baseToken, quoteToken)
+	return &result, err
+}
+
+// Bids returns the total volume of each bid price of the order book of a pair.
+func (tc *Client) Bids(ctx context.Context, baseToken, quoteToken common.Address) (map[*big.Int]*big.Int, error) {
+	var result map[*big.Int]*big.Int
+	err := tc.c.CallContext(ctx, &result, "tomox_getBids", baseToken, quoteToken)
+	return result, err
+}
+
+// Asks returns the total volume of each ask price of the order book of a pair.
+func (tc *Client) Asks(ctx context.Context, baseToken, quoteToken common.Address) (map[*big.Int]*big.Int, error) {
+	var result map[*big.Int]*big.Int
+	err := tc.c.CallContext(ctx, &result, "tomox_getAsks", baseToken, quoteToken)
+	return result, err
+}
+
+// BidTree returns the orders of each bid price of the order book of a pair.
+func (tc *Client) BidTree(ctx context.Context, baseToken, quoteToken common.Address) (map[*big.Int]tradingstate.DumpOrderList, error) {
+	var result map[*big.Int]tradingstate.DumpOrderList
+	err := tc.c.CallContext(ctx, &result, "tomox_getBidTree", baseToken, quoteToken)
+	return result, err
+}
+
+// AskTree returns the orders of each ask price of the order book of a pair.
+func (tc *Client) AskTree(ctx context.Context, baseToken, quoteToken common.Address) (map[*big.Int]tradingstate.DumpOrderList, error) {
+	var result map[*big.Int]tradingstate.DumpOrderList
+	err := tc.c.CallContext(ctx, &result, "tomox_getAskTree", baseToken, quoteToken)
+	return result, err
+}
+
+// Price returns the last traded price of a pair.
+func (tc *Client) Price(ctx context.Context, baseToken, quoteToken common.Address) (*big.Int, error) {
+	var result *big.Int
+	err := tc.c.CallContext(ctx, &result, "tomox_getPrice", baseToken, quoteToken)
+	return result, err
+}
+
+// LastEpochPrice returns the average price of a pair in the last epoch.
+func (tc *Client) LastEpochPrice(ctx context.Context, baseToken, quoteToken common.Address) (*big.Int, error) {
+	var result *big.Int
+	err := tc.c.CallContext(ctx, &result, "tomox_getLastEpochPrice", baseToken, quoteToken)
+	return result, err
+}
+
+// CurrentEpochPrice returns the average price of a pair in the current epoch.
+func (tc *Client) CurrentEpochPrice(ctx context.Context, baseToken, quoteToken common.Address) (*big.Int, error) {
+	var result *big.Int
+	err := tc.c.CallContext(ctx, &result, "tomox_getCurrentEpochPrice", baseToken, quoteToken)
+	return result, err
+}
+
+// OrderById returns the order of a pair with the given id.
+func (tc *Client) OrderById(ctx context.Context, baseToken, quoteToken common.Address, orderId uint64) (*tradingstate.OrderItem, error) {
+	var result tradingstate.OrderItem
+	err := tc.c.CallContext(ctx, &result, "tomox_getOrderById", baseToken, quoteToken, orderId)
+	return &result, err
+}
+
+// TradingOrderBookInfo returns the state of the order book of a pair.
+func (tc *Client) TradingOrderBookInfo(ctx context.Context, baseToken, quoteToken common.Address) (*tradingstate.DumpOrderBookInfo, error) {
+	var result tradingstate.DumpOrderBookInfo
+	err := tc.c.CallContext(ctx, &result, "tomox_getTradingOrderBookInfo", baseToken, quoteToken)
+	return &result, err
+}
+
+// LiquidationPriceTree returns the lending trades of each liquidation price of a pair.
+func (tc *Client) LiquidationPriceTree(ctx context.Context, baseToken, quoteToken common.Address) (map[*big.Int]tradingstate.DumpLendingBook, error) {
+	var result map[*big.Int]tradingstate.DumpLendingBook
+	err := tc.c.CallContext(ctx, &result, "tomox_getLiquidationPriceTree", baseToken, quoteToken)
+	return result, err
+}
+
+// Lending books
+
+// BestInvesting returns the best investing of the lending book of a token and term.
+func (tc *Client) BestInvesting(ctx context.Context, lendingToken common.Address, term uint64) (*InterestVolume, error) {
+	var result InterestVolume
+	err := tc.c.CallContext(ctx, &result, "tomox_getBestInvesting", lendingToken, term)
+	return &result, err
+}
+
+// BestBorrowing returns the best borrowing of the lending book of a token and term.
+func (tc *Client) BestBorrowing(ctx context.Context, lendingToken common.Address, term uint64) (*InterestVolume, error) {
+	var result InterestVolume
+	err := tc.c.CallContext(ctx, &result, "tomox_getBestBorrowing", lendingToken, term)
+	return &result, err
+}
+
+// Invests returns the total volume of each investing interest of the lending book of a token and term.
+func (tc *Client) Invests(ctx context.Context, lendingToken common.Address, term uint64) (map[*big.Int]*big.Int, error) {
+	var result map[*big.Int]*big.Int
+	err := tc.c.CallContext(ctx, &result, "tomox_getInvests", lendingToken, term)
+	return result, err
+}
+
+// Borrows returns the total volume of each borrowing interest of the lending book of a token and term.
+func (tc *Client) Borrows(ctx context.Context, lendingToken common.Address, term uint64) (map[*big.Int]*big.Int, error) {
+	var result map[*big.Int]*big.Int
+	err := tc.c.CallContext(ctx, &result, "tomox_getBorrows", lendingToken, term)
+	return result, err
+}
+
+// InvestingTree returns the lending items of each investing interest of the lending book of a token and term.
+func (tc *Client) InvestingTree(ctx context.Context, lendingToken common.Address, term uint64) (map[*big.Int]lendingstate.DumpOrderList, error) {
+	var result map[*big.Int]lendingstate.DumpOrderList
+	err := tc.c.CallContext(ctx, &result, "tomox_getInvestingTree", lendingToken, term)
+	return result, err
+}
+
+// BorrowingTree returns the lending items of each borrowing interest of the lending book of a token and term.
+func (tc *Client) BorrowingTree(ctx context.Context, lendingToken common.Address, term uint64) (map[*big.Int]lendingstate.DumpOrderList, error) {
+	var result map[*big.Int]lendingstate.DumpOrderList
+	err := tc.c.CallContext(ctx, &result, "tomox_getBorrowingTree", lendingToken, term)
+	return result, err
+}
+
+// LendingOrderBookInfo returns the state of the lending book of a token and term.
+func (tc *Client) LendingOrderBookInfo(ctx context.Context, lendingToken common.Address, term uint64) (*lendingstate.DumpOrderBookInfo, error) {
+	var result lendingstate.DumpOrderBookInfo
+	err := tc.c.CallContext(ctx, &result, "tomox_getLendingOrderBookInfo", lendingToken, term)
+	return &result, err
+}
+
+// LendingTradeTree returns the lending trades of the lending book of a token and term by trade id.
+func (tc *Client) LendingTradeTree(ctx context.Context, lendingToken common.Address, term uint64) (map[*big.Int]lendingstate.LendingTrade, error) {
+	var result map[*big.Int]lendingstate.LendingTrade
+	err := tc.c.CallContext(ctx, &result, "tomox_getLendingTradeTree", lendingToken, term)
+	return result, err
+}
+
+// LiquidationTimeTree returns the lending trades of each liquidation time of the lending book of a token and term.
+func (tc *Client) LiquidationTimeTree(ctx context.Context, lendingToken common.Address, term uint64) (map[*big.Int]lendingstate.DumpOrderList, error) {
+	var result map[*big.Int]lendingstate.DumpOrderList
+	err := tc.c.CallContext(ctx, &result, "tomox_getLiquidationTimeTree", lendingToken, term)
+	return result, err
+}
+
+// LendingOrderById returns the lending item of a token and term with the given id.
+func (tc *Client) LendingOrderById(ctx context.Context, lendingToken common.Address, term uint64, orderId uint64) (*lendingstate.LendingItem, error) {
+	var result lendingstate.LendingItem
+	err := tc.c.CallContext(ctx, &result, "tomox_getLendingOrderById", lendingToken, term, orderId)
+	return &result, err
+}
+
+// LendingTradeById returns the lending trade of a token and term with the given id.
+func (tc *Client) LendingTradeById(ctx context.Context, lendingToken common.Address, term uint64, tradeId uint64) (*lendingstate.LendingTrade, error) {
+	var result lendingstate.LendingTrade
+	err := tc.c.CallContext(ctx, &result, "tomox_getLendingTradeById", lendingToken, term, tradeId)
+	return &result, err
+}
+
+// Tokens, routes, prices and relayers
+
+// CheckTokenCompatibility checks whether a token can be listed on TomoX and TomoZ at the given block,
+// nil is the latest block.
+func (tc *Client) CheckTokenCompatibility(ctx context.Context, token common.Address, blockNumber *big.Int) (*core.TokenCompatibility, error) {
+	var result core.TokenCompatibility
+	err := tc.c.CallContext(ctx, &result, "tomox_checkTokenCompatibility", token, toBlockNumArg(blockNumber))
+	return &result, err
+}
+
+// RouteQuote returns the best route to swap amount of fromToken into toToken over the listed pairs
+// of the given relayer, or of all relayers if exchange is nil.
+func (tc *Client) RouteQuote(ctx context.Context, fromToken, toToken common.Address, amount *big.Int, exchange *common.Address) (*tomox.RouteQuote, error) {
+	var result tomox.RouteQuote
+	err := tc.c.CallContext(ctx, &result, "tomox_getRouteQuote", fromToken, toToken, (*hexutil.Big)(amount), exchange)
+	return &result, err
+}
+
+// TWAP returns the time-weighted average price of a pair over window seconds.
+func (tc *Client) TWAP(ctx context.Context, baseToken, quoteToken common.Address, window uint64) (*tradingstate.PriceOracleResult, error) {
+	var result tradingstate.PriceOracleResult
+	err := tc.c.CallContext(ctx, &result, "tomox_getTWAP", baseToken, quoteToken, hexutil.Uint64(window))
+	return &result, err
+}
+
+// RelayerInfo returns the registration and fee accounting of a relayer.
+func (tc *Client) RelayerInfo(ctx context.Context, relayer common.Address) (*RelayerInfo, error) {
+	var result RelayerInfo
+	err := tc.c.CallContext(ctx, &result, "tomox_getRelayerInfo", relayer)
+	return &result, err
+}
+
+func toBlockNumArg(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	return hexutil.EncodeBig(number)
+}
@@ -0,0 +1,320 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package tomoxclient
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/common/hexutil"
+	"github.com/69th-byte/sdexchain/consensus/ethash"
+	"github.com/69th-byte/sdexchain/core"
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/crypto"
+	"github.com/69th-byte/sdexchain/eth"
+	"github.com/69th-byte/sdexchain/node"
+	"github.com/69th-byte/sdexchain/params"
+	"github.com/69th-byte/sdexchain/rlp"
+	"github.com/69th-byte/sdexchain/rpc"
+	"github.com/69th-byte/sdexchain/tomox"
+	"github.com/69th-byte/sdexchain/tomox/tradingstate"
+	"github.com/69th-byte/sdexchain/tomoxlending"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testBase    = common.HexToAddress(common.TomoNativeAddress)
+	testQuote   = common.HexToAddress("0x4d7eA2cE949216D6b120f3AA10164173615A2b6C")
+	testRelayer = common.HexToAddress("0x0D3ab14BBaD3D99F4203bd7a11aCB94882050E7e")
+)
+
+// newTestNode starts a networkless node with the TomoX and lending services and
+// returns a client attached to it.
+func newTestNode(t *testing.T) (*node.Node, *Client, string) {
+	workspace, err := ioutil.TempDir("", "tomoxclient-tester-")
+	if err != nil {
+		t.Fatalf("failed to create temporary datadir: %v", err)
+	}
+	stack, err := node.New(&node.Config{DataDir: workspace, UseLightweightKDF: true, Name: "tomoxclient-tester"})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	ethConf := &eth.Config{
+		// blocks are authored by the coinbase with ethash, the trading and lending states of the
+		// genesis block are then available without sealing a block
+		Genesis: &core.Genesis{
+			Config:     params.AllEthashProtocolChanges,
+			GasLimit:   6283185,
+			Difficulty: big.NewInt(1),
+			Alloc:      core.GenesisAlloc{},
+		},
+		Etherbase: testAddr,
+		Ethash: ethash.Config{
+			PowMode: ethash.ModeTest,
+		},
+	}
+	tomoX := tomox.New(&tomox.Config{DataDir: filepath.Join(workspace, "tomox")})
+	lending := tomoxlending.New(tomoX)
+	if err = stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		return eth.New(ctx, ethConf, tomoX, lending)
+	}); err != nil {
+		t.Fatalf("failed to register Ethereum protocol: %v", err)
+	}
+	if err = stack.Start(); err != nil {
+		t.Fatalf("failed to start test stack: %v", err)
+	}
+	client, err := stack.Attach()
+	if err != nil {
+		t.Fatalf("failed to attach to node: %v", err)
+	}
+	return stack, NewClient(client), workspace
+}
+
+func TestClientOrderBooks(t *testing.T) {
+	stack, client, workspace := newTestNode(t)
+	defer os.RemoveAll(workspace)
+	defer stack.Stop()
+	defer client.Close()
+
+	ctx := context.Background()
+	if nonce, err := client.OrderCount(ctx, testAddr); err != nil || nonce != 0 {
+		t.Fatalf("order count mismatch: have %d, %v, want 0", nonce, err)
+	}
+	if nonce, err := client.LendingOrderCount(ctx, testAddr); err != nil || nonce != 0 {
+		t.Fatalf("lending order count mismatch: have %d, %v, want 0", nonce, err)
+	}
+	if stats, err := client.OrderStats(ctx); err != nil || stats.Pending != 0 || stats.Queued != 0 {
+		t.Fatalf("order stats mismatch: have %v, %v", stats, err)
+	}
+	if content, err := client.OrderPoolContent(ctx); err != nil || len(content.Pending) != 0 || len(content.Queued) != 0 {
+		t.Fatalf("order pool content mismatch: have %v, %v", content, err)
+	}
+	if _, err := client.Bids(ctx, testBase, testQuote); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("bids of an unknown order book: have %v, want not found", err)
+	}
+	if _, err := client.LendingOrderBookInfo(ctx, testQuote, 86400); err == nil {
+		t.Fatal("info of an unknown lending book is returned")
+	}
+	if matches, err := client.OrderTxMatchByHash(ctx, common.Hash{}); err != nil || len(matches) != 0 {
+		t.Fatalf("order matches mismatch: have %v, %v", matches, err)
+	}
+
+	// the order pool accepts and drops orders before TomoX is enabled, the nonce fetched
+	// from the trading state is then tracked by the client for the next orders
+	opts := NewKeyedTransactor(testKey)
+	params := OrderParams{
+		ExchangeAddress: testRelayer,
+		BaseToken:       testBase,
+		QuoteToken:      testQuote,
+		Quantity:        big.NewInt(1000),
+		Price:           big.NewInt(1),
+		Side:            tradingstate.Bid,
+		Type:            types.OrderTypeLo,
+	}
+	for i := uint64(0); i < 2; i++ {
+		tx, err := client.CreateOrder(opts, params)
+		if err != nil {
+			t.Fatalf("order %d: %v", i, err)
+		}
+		if tx.Nonce() != i {
+			t.Errorf("order %d: nonce mismatch: have %d, want %d", i, tx.Nonce(), i)
+		}
+	}
+}
+
+// FakePool is a minimal tomox RPC service recording the transactions sent by the client.
+type FakePool struct {
+	orderNonce   uint64
+	lendingNonce uint64
+	orders       []*types.OrderTransaction
+	lendings     []*types.LendingTransaction
+	reject       bool
+}
+
+func (s *FakePool) GetOrderCount(addr common.Address) hexutil.Uint64 {
+	return hexutil.Uint64(s.orderNonce)
+}
+
+func (s *FakePool) GetLendingOrderCount(addr common.Address) hexutil.Uint64 {
+	return hexutil.Uint64(s.lendingNonce)
+}
+
+func (s *FakePool) SendOrderRawTransaction(encodedTx hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.OrderTransaction)
+	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+		return common.Hash{}, err
+	}
+	if s.reject {
+		return common.Hash{}, errors.New("rejected")
+	}
+	s.orders = append(s.orders, tx)
+	return tx.Hash(), nil
+}
+
+func (s *FakePool) SendLendingRawTransaction(encodedTx hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.LendingTransaction)
+	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+		return common.Hash{}, err
+	}
+	if s.reject {
+		return common.Hash{}, errors.New("rejected")
+	}
+	s.lendings = append(s.lendings, tx)
+	return tx.Hash(), nil
+}
+
+func newTestPool(t *testing.T) (*FakePool, *Client) {
+	service := new(FakePool)
+	server := rpc.NewServer()
+	if err := server.RegisterName("tomox", service); err != nil {
+		t.Fatal(err)
+	}
+	return service, NewClient(rpc.DialInProc(server))
+}
+
+func TestClientOrderNonces(t *testing.T) {
+	service, client := newTestPool(t)
+	defer client.Close()
+
+	service.orderNonce = 5
+	opts := NewKeyedTransactor(testKey)
+	params := OrderParams{
+		ExchangeAddress: testRelayer,
+		BaseToken:       testBase,
+		QuoteToken:      testQuote,
+		Quantity:        big.NewInt(1000),
+		Price:           big.NewInt(1),
+		Side:            tradingstate.Ask,
+		Type:            types.OrderTypeLo,
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := client.CreateOrder(opts, params); err != nil {
+			t.Fatalf("order %d: %v", i, err)
+		}
+	}
+	cancel, err := client.CancelOrder(opts, testRelayer, testBase, testQuote, service.orders[0].OrderHash(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancel.Status() != types.OrderStatusCancelled || cancel.OrderHash() != service.orders[0].OrderHash() {
+		t.Errorf("invalid cancelled order: status %s, hash %x", cancel.Status(), cancel.OrderHash())
+	}
+	for i, tx := range service.orders {
+		if tx.Nonce() != uint64(5+i) {
+			t.Errorf("order %d: nonce mismatch: have %d, want %d", i, tx.Nonce(), 5+i)
+		}
+		from, err := types.OrderSender(types.OrderTxSigner{}, tx)
+		if err != nil || from != testAddr {
+			t.Errorf("order %d: sender mismatch: have %x, %v, want %x", i, from, err, testAddr)
+		}
+		if !tx.IsCancelledOrder() && tx.OrderHash() != (types.OrderTxSigner{}).Hash(tx) {
+			t.Errorf("order %d: order hash mismatch", i)
+		}
+	}
+	// a rejected order drops the tracked nonce, the next order fetches it from the node again
+	service.reject = true
+	if _, err := client.CreateOrder(opts, params); err == nil {
+		t.Fatal("rejected order is sent")
+	}
+	service.reject = false
+	service.orderNonce = 20
+	tx, err := client.CreateOrder(opts, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Nonce() != 20 {
+		t.Errorf("nonce mismatch after rejection: have %d, want 20", tx.Nonce())
+	}
+	// an explicit nonce overrides the tracked one
+	opts.Nonce = big.NewInt(100)
+	if tx, err = client.CreateOrder(opts, params); err != nil || tx.Nonce() != 100 {
+		t.Errorf("explicit nonce mismatch: have %v, %v, want 100", tx, err)
+	}
+}
+
+func TestClientLendingNonces(t *testing.T) {
+	service, client := newTestPool(t)
+	defer client.Close()
+
+	service.lendingNonce = 2
+	service.orderNonce = 7
+	opts := NewKeyedTransactor(testKey)
+	if _, err := client.CreateLending(opts, LendingParams{
+		RelayerAddress:  testRelayer,
+		LendingToken:    testQuote,
+		CollateralToken: testBase,
+		Quantity:        big.NewInt(1000),
+		Interest:        100,
+		Term:            86400,
+		Side:            types.LendingSideBorrow,
+		Type:            types.LendingTypeLo,
+		AutoTopUp:       true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.TopUpLending(opts, testRelayer, testQuote, 86400, 1, big.NewInt(10)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.RepayLending(opts, testRelayer, testQuote, 86400, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CancelLending(opts, testRelayer, testQuote, 86400, service.lendings[0].LendingHash(), 1); err != nil {
+		t.Fatal(err)
+	}
+	kinds := []func(*types.LendingTransaction) bool{
+		(*types.LendingTransaction).IsCreatedLending,
+		(*types.LendingTransaction).IsTopupLending,
+		(*types.LendingTransaction).IsRepayLending,
+		(*types.LendingTransaction).IsCancelledLending,
+	}
+	for i, tx := range service.lendings {
+		if tx.Nonce() != uint64(2+i) {
+			t.Errorf("lending %d: nonce mismatch: have %d, want %d", i, tx.Nonce(), 2+i)
+		}
+		if !kinds[i](tx) {
+			t.Errorf("lending %d: unexpected kind: status %s, type %s", i, tx.Status(), tx.Type())
+		}
+		from, err := types.LendingSender(types.LendingTxSigner{}, tx)
+		if err != nil || from != testAddr {
+			t.Errorf("lending %d: sender mismatch: have %x, %v, want %x", i, from, err, testAddr)
+		}
+	}
+	// lending and order nonces are tracked separately
+	if tx, err := client.CreateOrder(opts, OrderParams{
+		ExchangeAddress: testRelayer,
+		BaseToken:       testBase,
+		QuoteToken:      testQuote,
+		Quantity:        big.NewInt(1000),
+		Price:           big.NewInt(1),
+		Side:            tradingstate.Ask,
+		Type:            types.OrderTypeLo,
+	}); err != nil || tx.Nonce() != 7 {
+		t.Errorf("order nonce mismatch: have %v, %v, want 7", tx, err)
+	}
+	// signing for another account is refused
+	opts.From = testRelayer
+	if _, err := client.RepayLending(opts, testRelayer, testQuote, 86400, 1); err == nil || !strings.Contains(err.Error(), "not authorized") {
+		t.Errorf("signing for another account: have %v, want %v", err, errNotAuthorized)
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package tomoxclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+
+	"github.com/69th-byte/sdexchain/common"
+	"github.com/69th-byte/sdexchain/core/types"
+	"github.com/69th-byte/sdexchain/crypto"
+)
+
+var (
+	errNotAuthorized = errors.New("not authorized to sign this account")
+	errNoSigner      = errors.New("no signer to authorize the transaction with")
+)
+
+// OrderSignerFn is a signer function callback when an order transaction requires a signature.
+type OrderSignerFn func(signer types.OrderSigner, address common.Address, tx *types.OrderTransaction) (*types.OrderTransaction, error)
+
+// LendingSignerFn is a signer function callback when a lending transaction requires a signature.
+type LendingSignerFn func(signer types.LendingSigner, address common.Address, tx *types.LendingTransaction) (*types.LendingTransaction, error)
+
+// TransactOpts is the collection of authorization data required to create a
+// valid order or lending transaction.
+type TransactOpts struct {
+	From          common.Address  // Ethereum account to send the transaction from
+	Nonce         *big.Int        // Nonce to use for the transaction execution (nil = use the nonce tracked by the client)
+	OrderSigner   OrderSignerFn   // Method to use for signing order transactions (mandatory for orders)
+	LendingSigner LendingSignerFn // Method to use for signing lending transactions (mandatory for lendings)
+
+	Context context.Context // Network context to support cancellation and timeouts (nil = no timeout)
+}
+
+// NewKeyedTransactor is a utility method to easily create an order and lending
+// transaction signer from a single private key.
+func NewKeyedTransactor(key *ecdsa.PrivateKey) *TransactOpts {
+	keyAddr := crypto.PubkeyToAddress(key.PublicKey)
+	return &TransactOpts{
+		From: keyAddr,
+		OrderSigner: func(signer types.OrderSigner, address common.Address, tx *types.OrderTransaction) (*types.OrderTransaction, error) {
+			if address != keyAddr {
+				return nil, errNotAuthorized
+			}
+			return types.OrderSignTx(tx, signer, key)
+		},
+		LendingSigner: func(signer types.LendingSigner, address common.Address, tx *types.LendingTransaction) (*types.LendingTransaction, error) {
+			if address != keyAddr {
+				return nil, errNotAuthorized
+			}
+			return types.LendingSignTx(tx, signer, key)
+		},
+	}
+}
+
+// OrderParams are the fields of a new order.
+type OrderParams struct {
+	ExchangeAddress common.Address
+	BaseToken       common.Address
+	QuoteToken      common.Address
+	Quantity        *big.Int
+	Price           *big.Int // limit price, or the minimum amount of the last token of Route for routed market orders
+	Side            string   // BUY or SELL
+	Type            string   // LO, MO or RMO
+	Route           []common.Address
+}
+
+// LendingParams are the fields of a new lending item.
+type LendingParams struct {
+	RelayerAddress  common.Address
+	LendingToken    common.Address
+	CollateralToken common.Address // borrowing only
+	Quantity        *big.Int
+	Interest        uint64
+	Term            uint64
+	Side            string // INVEST or BORROW
+	Type            string // LO or MO
+	AutoTopUp       bool   // borrowing only
+}
+
+// CreateOrder signs and sends a new order to the order pool.
+func (tc *Client) CreateOrder(opts *TransactOpts, params OrderParams) (*types.OrderTransaction, error) {
+	return tc.transactOrder(opts, func(nonce uint64) *types.OrderTransaction {
+		tx := types.NewOrderTransaction(nonce, params.Quantity, params.Price, params.ExchangeAddress, opts.From,
+			params.BaseToken, params.QuoteToken, types.OrderStatusNew, params.Side, params.Type, common.Hash{}, 0)
+		if len(params.Route) > 0 {
+			tx.SetRoute(params.Route)
+		}
+		tx.SetOrderHash(types.OrderTxSigner{}.Hash(tx))
+		return tx
+	})
+}
+
+// CancelOrder signs and sends the cancellation of the order with the given hash and id.
+func (tc *Client) CancelOrder(opts *TransactOpts, exchangeAddress, baseToken, quoteToken common.Address, orderHash common.Hash, orderId uint64) (*types.OrderTransaction, error) {
+	return tc.transactOrder(opts, func(nonce uint64) *types.OrderTransaction {
+		return types.NewOrderTransaction(nonce, new(big.Int), new(big.Int), exchangeAddress, opts.From,
+			baseToken, quoteToken, types.OrderStatusCancelled, "", types.OrderTypeLo, orderHash, orderId)
+	})
+}
+
+// CreateLending signs and sends a new lending item to the lending pool.
+func (tc *Client) CreateLending(opts *TransactOpts, params LendingParams) (*types.LendingTransaction, error) {
+	return tc.transactLending(opts, func(nonce uint64) *types.LendingTransaction {
+		tx := types.NewLendingTransaction(nonce, params.Quantity, params.Interest, params.Term, params.RelayerAddress, opts.From,
+			params.LendingToken, params.CollateralToken, params.AutoTopUp, types.LendingStatusNew, params.Side, params.Type, common.Hash{}, 0, 0, "")
+		tx.SetLendingHash(types.LendingTxSigner{}.Hash(tx))
+		return tx
+	})
+}
+
+// CancelLending signs and sends the cancellation of the lending item with the given hash and id.
+func (tc *Client) CancelLending(opts *TransactOpts, relayerAddress, lendingToken common.Address, term uint64, lendingHash common.Hash, lendingId uint64) (*types.LendingTransaction, error) {
+	return tc.transactLending(opts, func(nonce uint64) *types.LendingTransaction {
+		return types.NewLendingTransaction(nonce, new(big.Int), 0, term, relayerAddress, opts.From,
+			lendingToken, common.Address{}, false, types.LendingStatusCancelled, "", types.LendingTypeLo, lendingHash, lendingId, 0, "")
+	})
+}
+
+// RepayLending signs and sends the repayment of the lending trade with the given id.
+func (tc *Client) RepayLending(opts *TransactOpts, relayerAddress, lendingToken common.Address, term uint64, tradeId uint64) (*types.LendingTransaction, error) {
+	return tc.transactLending(opts, func(nonce uint64) *types.LendingTransaction {
+		tx := types.NewLendingTransaction(nonce, new(big.Int), 0, term, relayerAddress, opts.From,
+			lendingToken, common.Address{}, false, types.LendingStatusNew, types.LendingSideBorrow, types.LendingRePay, common.Hash{}, 0, tradeId, "")
+		tx.SetLendingHash(types.LendingTxSigner{}.Hash(tx))
+		return tx
+	})
+}
+
+// TopUpLending signs and sends a top up of quantity collateral to the lending trade with the given id.
+func (tc *Client) TopUpLending(opts *TransactOpts, relayerAddress, lendingToken common.Address, term uint64, tradeId uint64, quantity *big.Int) (*types.LendingTransaction, error) {
+	return tc.transactLending(opts, func(nonce uint64) *types.LendingTransaction {
+		tx := types.NewLendingTransaction(nonce, quantity, 0, term, relayerAddress, opts.From,
+			lendingToken, common.Address{}, false, types.LendingStatusNew, types.LendingSideBorrow, types.LendingTopup, common.Hash{}, 0, tradeId, "")
+		tx.SetLendingHash(types.LendingTxSigner{}.Hash(tx))
+		return tx
+	})
+}
+
+// ResetNonces drops the order and lending nonces tracked for the account, the
+// next transaction fetches them from the node again.
+func (tc *Client) ResetNonces(account common.Address) {
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+
+	delete(tc.orderNonces, account)
+	delete(tc.lendingNonces, account)
+}
+
+// transactOrder builds an order transaction with the next nonce of the sender, signs and sends it.
+func (tc *Client) transactOrder(opts *TransactOpts, build func(nonce uint64) *types.OrderTransaction) (*types.OrderTransaction, error) {
+	if opts.OrderSigner == nil {
+		return nil, errNoSigner
+	}
+	ctx := ensureContext(opts.Context)
+
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+
+	nonce, err := tc.nextNonce(ctx, opts, tc.orderNonces, tc.OrderCount)
+	if err != nil {
+		return nil, err
+	}
+	signedTx, err := opts.OrderSigner(types.OrderTxSigner{}, opts.From, build(nonce))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tc.SendOrderTransaction(ctx, signedTx); err != nil {
+		// the node may know a different nonce, fetch it again on the next transaction
+		delete(tc.orderNonces, opts.From)
+		return nil, err
+	}
+	tc.orderNonces[opts.From] = nonce + 1
+	return signedTx, nil
+}
+
+// transactLending builds a lending transaction with the next nonce of the sender, signs and sends it.
+func (tc *Client) transactLending(opts *TransactOpts, build func(nonce uint64) *types.LendingTransaction) (*types.LendingTransaction, error) {
+	if opts.LendingSigner == nil {
+		return nil, errNoSigner
+	}
+	ctx := ensureContext(opts.Context)
+
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+
+	nonce, err := tc.nextNonce(ctx, opts, tc.lendingNonces, tc.LendingOrderCount)
+	if err != nil {
+		return nil, err
+	}
+	signedTx, err := opts.LendingSigner(types.LendingTxSigner{}, opts.From, build(nonce))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tc.SendLendingTransaction(ctx, signedTx); err != nil {
+		delete(tc.lendingNonces, opts.From)
+		return nil, err
+	}
+	tc.lendingNonces[opts.From] = nonce + 1
+	return signedTx, nil
+}
+
+// nextNonce returns the nonce of opts if set, otherwise the nonce tracked for the sender,
+// fetched from the node if the sender has no transaction sent through this client yet.
+func (tc *Client) nextNonce(ctx context.Context, opts *TransactOpts, nonces map[common.Address]uint64, fetch func(context.Context, common.Address) (uint64, error)) (uint64, error) {
+	if opts.Nonce != nil {
+		return opts.Nonce.Uint64(), nil
+	}
+	if nonce, ok := nonces[opts.From]; ok {
+		return nonce, nil
+	}
+	return fetch(ctx, opts.From)
+}
+
+// ensureContext is a helper method to ensure a context is not nil, even if the
+// user specified it as such.
+func ensureContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.TODO()
+	}
+	return ctx
+}
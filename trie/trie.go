@@ -183,7 +183,7 @@ func (t *Trie) tryGetBestLeftKeyAndValue(origNode Node, prefix []byte) (key []by
 			return key, value, n, didResolve, err
 		}
 	case HashNode:
-		child, err := t.resolveHash(n, nil)
+		child, err := t.resolveHash(n, prefix)
 		if err != nil {
 			return nil, nil, n, true, err
 		}
@@ -250,7 +250,7 @@ func (t *Trie) tryGetAllLeftKeyAndValue(origNode Node, prefix []byte, limit []by
 		}
 		return keys, values, n, didResolve, err
 	case HashNode:
-		child, err := t.resolveHash(n, nil)
+		child, err := t.resolveHash(n, prefix)
 		if err != nil {
 			return nil, nil, n, true, err
 		}
@@ -298,7 +298,7 @@ func (t *Trie) tryGetBestRightKeyAndValue(origNode Node, prefix []byte) (key []b
 			return key, value, n, didResolve, err
 		}
 	case HashNode:
-		child, err := t.resolveHash(n, nil)
+		child, err := t.resolveHash(n, prefix)
 		if err != nil {
 			return nil, nil, n, true, err
 		}